@@ -0,0 +1,256 @@
+package benchmark
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	quic "github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+)
+
+const benchmarkALPN = "benchmark"
+
+var networks = []struct {
+	name       string
+	newNetwork func() Network
+}{
+	{name: "memory", newNetwork: func() Network { return NewMemoryNetwork() }},
+	{name: "loopback", newNetwork: func() Network { return LoopbackNetwork{} }},
+}
+
+func runWithNetworks(b *testing.B, f func(*testing.B, Network)) {
+	for _, n := range networks {
+		n := n
+		b.Run(n.name, func(b *testing.B) { f(b, n.newNetwork()) })
+	}
+}
+
+func serverTLSConfig() *tls.Config {
+	tlsConf := testdata.GetTLSConfig()
+	tlsConf.NextProtos = []string{benchmarkALPN}
+	return tlsConf
+}
+
+func clientTLSConfig() *tls.Config {
+	return &tls.Config{
+		RootCAs:    testdata.GetRootCA(),
+		ServerName: "localhost",
+		NextProtos: []string{benchmarkALPN},
+	}
+}
+
+// listen starts a server. The packet conn is closed when the benchmark ends.
+func listen(b *testing.B, n Network, conf *quic.Config) quic.Listener {
+	conn, err := n.ListenPacket()
+	if err != nil {
+		b.Fatal(err)
+	}
+	ln, err := quic.Listen(conn, serverTLSConfig(), conf)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		ln.Close()
+		conn.Close()
+	})
+	return ln
+}
+
+// dialer creates a function to dial the server. All connections use the same packet conn.
+func dialer(b *testing.B, n Network, ln quic.Listener, conf *quic.Config) func() quic.Session {
+	conn, err := n.ListenPacket()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { conn.Close() })
+	return func() quic.Session {
+		sess, err := quic.Dial(conn, ln.Addr(), "localhost", clientTLSConfig(), conf)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return sess
+	}
+}
+
+// acceptStreams accepts all unidirectional streams opened by the client, and reads them until the end.
+// It returns a channel that receives the number of bytes read on every stream.
+func acceptStreams(ln quic.Listener) <-chan int64 {
+	received := make(chan int64, 1000)
+	go func() {
+		sess, err := ln.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		for {
+			str, err := sess.AcceptUniStream(context.Background())
+			if err != nil {
+				return
+			}
+			go func() {
+				n, _ := io.Copy(ioutil.Discard, str)
+				received <- n
+			}()
+		}
+	}()
+	return received
+}
+
+// BenchmarkThroughputSingleStream measures the throughput of a single stream.
+func BenchmarkThroughputSingleStream(b *testing.B) {
+	runWithNetworks(b, func(b *testing.B, n Network) {
+		const chunkSize = 32 << 10
+		ln := listen(b, n, nil)
+		received := acceptStreams(ln)
+		sess := dialer(b, n, ln, nil)()
+		defer sess.CloseWithError(0, "")
+		chunk := make([]byte, chunkSize)
+
+		b.SetBytes(chunkSize)
+		b.ResetTimer()
+		str, err := sess.OpenUniStream()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < b.N; i++ {
+			if _, err := str.Write(chunk); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := str.Close(); err != nil {
+			b.Fatal(err)
+		}
+		if n := <-received; n != int64(b.N)*chunkSize {
+			b.Fatalf("received %d bytes, expected %d", n, b.N*chunkSize)
+		}
+	})
+}
+
+// BenchmarkThroughputManyStreams measures the throughput when transferring data on many concurrent streams.
+// Every iteration transfers the data on a new stream.
+func BenchmarkThroughputManyStreams(b *testing.B) {
+	runWithNetworks(b, func(b *testing.B, n Network) {
+		const (
+			streamSize        = 16 << 10
+			concurrentStreams = 100
+		)
+		conf := &quic.Config{MaxIncomingUniStreams: concurrentStreams}
+		ln := listen(b, n, conf)
+		received := acceptStreams(ln)
+		sess := dialer(b, n, ln, conf)()
+		defer sess.CloseWithError(0, "")
+		data := make([]byte, streamSize)
+
+		b.SetBytes(streamSize)
+		b.ResetTimer()
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrentStreams)
+		for i := 0; i < b.N; i++ {
+			str, err := sess.OpenUniStreamSync(context.Background())
+			if err != nil {
+				b.Fatal(err)
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if _, err := str.Write(data); err != nil {
+					b.Error(err)
+				}
+				str.Close()
+			}()
+		}
+		wg.Wait()
+		for i := 0; i < b.N; i++ {
+			if n := <-received; n != streamSize {
+				b.Fatalf("received %d bytes, expected %d", n, streamSize)
+			}
+		}
+	})
+}
+
+// BenchmarkHandshakes measures the number of handshakes per second.
+// The handshakes are run sequentially.
+func BenchmarkHandshakes(b *testing.B) {
+	runWithNetworks(b, func(b *testing.B, n Network) {
+		ln := listen(b, n, nil)
+		go func() {
+			for {
+				sess, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				sess.CloseWithError(0, "")
+			}
+		}()
+		dial := dialer(b, n, ln, nil)
+
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			dial().CloseWithError(0, "")
+		}
+		b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "handshakes/s")
+	})
+}
+
+// BenchmarkDatagrams measures the number of datagrams (RFC 9221) per second.
+func BenchmarkDatagrams(b *testing.B) {
+	runWithNetworks(b, func(b *testing.B, n Network) {
+		const datagramSize = 1000
+		conf := &quic.Config{EnableDatagrams: true}
+		ln := listen(b, n, conf)
+		var mutex sync.Mutex
+		var received int
+		go func() {
+			sess, err := ln.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			for {
+				if _, err := sess.ReceiveMessage(); err != nil {
+					return
+				}
+				mutex.Lock()
+				received++
+				mutex.Unlock()
+			}
+		}()
+		sess := dialer(b, n, ln, conf)()
+		defer sess.CloseWithError(0, "")
+		data := make([]byte, datagramSize)
+
+		b.SetBytes(datagramSize)
+		b.ResetTimer()
+		start := time.Now()
+		for i := 0; i < b.N; i++ {
+			if err := sess.SendMessage(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+		// Datagrams are not retransmitted, so some of them might be lost (when using the loopback network).
+		// Wait until all datagrams arrived, or no datagram arrived for a while.
+		var last int
+		for {
+			time.Sleep(10 * time.Millisecond)
+			mutex.Lock()
+			r := received
+			mutex.Unlock()
+			if r == b.N || r == last {
+				break
+			}
+			last = r
+		}
+		duration := time.Since(start)
+		b.StopTimer()
+		mutex.Lock()
+		defer mutex.Unlock()
+		b.ReportMetric(float64(received)/duration.Seconds(), "datagrams/s")
+		b.ReportMetric(float64(b.N-received)/float64(b.N), "loss")
+	})
+}
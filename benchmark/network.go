@@ -0,0 +1,151 @@
+// Package benchmark contains end-to-end benchmarks for quic-go.
+//
+// The benchmarks are run using go test -bench, for example:
+//
+//	go test -run=NONE -bench=. ./benchmark
+//
+// Every benchmark is run twice: once using an in-memory network, which measures the CPU cost of the
+// QUIC stack without any influence of the kernel, and once using UDP sockets on the loopback interface.
+package benchmark
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// A Network creates the packet conns used to run a benchmark.
+type Network interface {
+	ListenPacket() (net.PacketConn, error)
+}
+
+// LoopbackNetwork creates UDP sockets on the loopback interface.
+type LoopbackNetwork struct{}
+
+var _ Network = LoopbackNetwork{}
+
+// ListenPacket creates a UDP socket on 127.0.0.1, using a random port.
+func (LoopbackNetwork) ListenPacket() (net.PacketConn, error) {
+	return net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+}
+
+// memoryQueueLen is the number of packets that can be queued on a memory conn.
+// When the queue is full, writes block, so packets are never dropped.
+const memoryQueueLen = 1024
+
+type memoryAddr int
+
+func (a memoryAddr) Network() string { return "memory" }
+func (a memoryAddr) String() string  { return "memory:" + strconv.Itoa(int(a)) }
+
+type memoryPacket struct {
+	data []byte
+	from memoryAddr
+}
+
+// A MemoryNetwork is an in-memory network.
+// Packets are never dropped, reordered or delayed.
+type MemoryNetwork struct {
+	mutex    sync.Mutex
+	nextAddr memoryAddr
+	conns    map[memoryAddr]*memoryConn
+}
+
+var _ Network = &MemoryNetwork{}
+
+// NewMemoryNetwork creates a new in-memory network.
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{conns: make(map[memoryAddr]*memoryConn)}
+}
+
+// ListenPacket creates a new packet conn on the in-memory network.
+func (n *MemoryNetwork) ListenPacket() (net.PacketConn, error) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.nextAddr++
+	c := &memoryConn{
+		network: n,
+		addr:    n.nextAddr,
+		queue:   make(chan memoryPacket, memoryQueueLen),
+		closed:  make(chan struct{}),
+	}
+	n.conns[c.addr] = c
+	return c, nil
+}
+
+func (n *MemoryNetwork) get(addr net.Addr) (*memoryConn, bool) {
+	a, ok := addr.(memoryAddr)
+	if !ok {
+		return nil, false
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	c, ok := n.conns[a]
+	return c, ok
+}
+
+func (n *MemoryNetwork) remove(addr memoryAddr) {
+	n.mutex.Lock()
+	delete(n.conns, addr)
+	n.mutex.Unlock()
+}
+
+var errMemoryConnClosed = errors.New("use of closed memory conn")
+
+type memoryConn struct {
+	network *MemoryNetwork
+	addr    memoryAddr
+	queue   chan memoryPacket
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ net.PacketConn = &memoryConn{}
+
+func (c *memoryConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case p := <-c.queue:
+		return copy(b, p.data), p.from, nil
+	case <-c.closed:
+		return 0, nil, errMemoryConnClosed
+	}
+}
+
+func (c *memoryConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, errMemoryConnClosed
+	default:
+	}
+	dst, ok := c.network.get(addr)
+	if !ok {
+		// There's nobody listening on this address. Drop the packet, as the network would.
+		return len(b), nil
+	}
+	data := make([]byte, len(b))
+	copy(data, b)
+	select {
+	case dst.queue <- memoryPacket{data: data, from: c.addr}:
+	case <-dst.closed:
+	case <-c.closed:
+		return 0, errMemoryConnClosed
+	}
+	return len(b), nil
+}
+
+func (c *memoryConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.network.remove(c.addr)
+		close(c.closed)
+	})
+	return nil
+}
+
+func (c *memoryConn) LocalAddr() net.Addr                { return c.addr }
+func (c *memoryConn) SetDeadline(t time.Time) error      { return nil }
+func (c *memoryConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *memoryConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,64 @@
+package benchmark
+
+import (
+	"net"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory Network", func() {
+	var (
+		n      *MemoryNetwork
+		c1, c2 net.PacketConn
+	)
+
+	BeforeEach(func() {
+		n = NewMemoryNetwork()
+		var err error
+		c1, err = n.ListenPacket()
+		Expect(err).ToNot(HaveOccurred())
+		c2, err = n.ListenPacket()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c1.LocalAddr()).ToNot(Equal(c2.LocalAddr()))
+	})
+
+	AfterEach(func() {
+		c1.Close()
+		c2.Close()
+	})
+
+	It("sends packets", func() {
+		b := []byte("foobar")
+		_, err := c1.WriteTo(b, c2.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		b[0] = 'x' // the packet was copied
+		buf := make([]byte, 100)
+		l, addr, err := c2.ReadFrom(buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf[:l]).To(Equal([]byte("foobar")))
+		Expect(addr).To(Equal(c1.LocalAddr()))
+	})
+
+	It("drops packets sent to unknown addresses", func() {
+		c2.Close()
+		l, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(l).To(Equal(6))
+	})
+
+	It("unblocks ReadFrom when closed", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, _, err := c1.ReadFrom(make([]byte, 100))
+			Expect(err).To(MatchError("use of closed memory conn"))
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		Expect(c1.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		_, err := c1.WriteTo([]byte("foobar"), c2.LocalAddr())
+		Expect(err).To(MatchError("use of closed memory conn"))
+	})
+})
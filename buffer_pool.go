@@ -2,6 +2,7 @@ package quic
 
 import (
 	"sync"
+	"sync/atomic"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 )
@@ -13,6 +14,12 @@ type packetBuffer struct {
 	// It doesn't support concurrent use.
 	// It is > 1 when used for coalesced packet.
 	refCount int
+
+	allocator BufferAllocator // nil if the buffer was taken from the size class pools
+	raw       []byte          // the underlying buffer
+
+	// dscp is the DSCP that the packet is marked with when it is sent. It is not used if zero.
+	dscp uint8
 }
 
 // Split increases the refCount.
@@ -55,26 +62,105 @@ func (b *packetBuffer) Len() protocol.ByteCount {
 	return protocol.ByteCount(len(b.Data))
 }
 
+// shrink is called with the data of a packet that was read into the buffer.
+// Small packets are copied into a smaller buffer,
+// such that they don't tie up a full-sized buffer while they are queued.
+// It returns the buffer holding the packet, and the packet data.
+func (b *packetBuffer) shrink(data []byte) (*packetBuffer, []byte) {
+	if len(data) > smallPacketBufferSize || b.refCount != 1 {
+		return b, data
+	}
+	small := getPacketBufferWithSize(len(data))
+	small.Data = append(small.Data, data...)
+	b.Release()
+	return small, small.Data
+}
+
 func (b *packetBuffer) putBack() {
-	if cap(b.Data) != int(protocol.MaxPacketBufferSize) {
+	if cap(b.Data) != cap(b.raw) {
 		panic("putPacketBuffer called with packet of wrong size!")
 	}
-	bufferPool.Put(b)
+	if b.allocator != nil {
+		b.allocator.Put(b.raw)
+		b.allocator = nil
+		b.raw = nil
+		b.Data = nil
+		packetBufferPool.Put(b)
+		return
+	}
+	if class := sizeClass(cap(b.raw)); class >= 0 && sizeClasses[class] == cap(b.raw) {
+		sizeClassPools[class].Put(b)
+	}
+}
+
+// A BufferAllocator allocates the buffers that packets are read into and assembled in.
+type BufferAllocator interface {
+	// Get returns a buffer with a length of size bytes.
+	Get(size int) []byte
+	// Put is called with a buffer returned by Get, once it is not used any more.
+	Put([]byte)
 }
 
-var bufferPool sync.Pool
+type allocatorHolder struct{ BufferAllocator }
+
+var bufferAllocator atomic.Value // of type allocatorHolder
+
+// SetBufferAllocator sets the allocator used for packet buffers.
+// It should be called before any sessions are started.
+// Buffers that were allocated before are returned to the allocator they were obtained from.
+// Passing nil restores the default, which pools buffers of a few size classes.
+func SetBufferAllocator(a BufferAllocator) {
+	bufferAllocator.Store(allocatorHolder{a})
+}
+
+const smallPacketBufferSize = 512
+
+// sizeClasses are the buffer sizes used by default, in increasing order.
+var sizeClasses = [...]int{128, smallPacketBufferSize, int(protocol.MaxPacketBufferSize)}
+
+var sizeClassPools [len(sizeClasses)]sync.Pool
+
+// sizeClass returns the index of the smallest size class that can hold size bytes.
+// It returns -1 if size is larger than the largest size class.
+func sizeClass(size int) int {
+	for i, s := range sizeClasses {
+		if size <= s {
+			return i
+		}
+	}
+	return -1
+}
+
+var packetBufferPool = sync.Pool{New: func() interface{} { return &packetBuffer{} }}
 
 func getPacketBuffer() *packetBuffer {
-	buf := bufferPool.Get().(*packetBuffer)
+	return getPacketBufferWithSize(int(protocol.MaxPacketBufferSize))
+}
+
+// getPacketBufferWithSize gets a packet buffer with a capacity of (at least) size bytes.
+func getPacketBufferWithSize(size int) *packetBuffer {
+	var buf *packetBuffer
+	if a := bufferAllocator.Load().(allocatorHolder).BufferAllocator; a != nil {
+		buf = packetBufferPool.Get().(*packetBuffer)
+		buf.allocator = a
+		buf.raw = a.Get(size)
+	} else if class := sizeClass(size); class >= 0 {
+		buf = sizeClassPools[class].Get().(*packetBuffer)
+	} else {
+		buf = &packetBuffer{raw: make([]byte, size)}
+	}
+	buf.Data = buf.raw[:0]
 	buf.refCount = 1
-	buf.Data = buf.Data[:0]
+	buf.dscp = 0
 	return buf
 }
 
 func init() {
-	bufferPool.New = func() interface{} {
-		return &packetBuffer{
-			Data: make([]byte, 0, protocol.MaxPacketBufferSize),
+	for i := range sizeClassPools {
+		size := sizeClasses[i]
+		sizeClassPools[i].New = func() interface{} {
+			return &packetBuffer{raw: make([]byte, size)}
 		}
 	}
+	SetBufferAllocator(nil)
 }
@@ -42,6 +42,75 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(func() { buf.Decrement() }).To(Panic())
 	})
 
+	It("uses size classes", func() {
+		buf := getPacketBufferWithSize(100)
+		Expect(buf.Data).To(BeEmpty())
+		Expect(buf.Data).To(HaveCap(128))
+		buf.Release()
+		buf = getPacketBufferWithSize(200)
+		Expect(buf.Data).To(HaveCap(smallPacketBufferSize))
+		buf.Release()
+		buf = getPacketBufferWithSize(5000)
+		Expect(buf.Data).To(HaveCap(5000))
+		buf.Release()
+	})
+
+	It("copies small packets into a smaller buffer", func() {
+		buf := getPacketBuffer()
+		buf.Data = append(buf.Data, []byte("foobar")...)
+		small, data := buf.shrink(buf.Data)
+		Expect(data).To(Equal([]byte("foobar")))
+		Expect(small.Data).To(HaveCap(128))
+		small.Release()
+	})
+
+	It("doesn't copy large packets", func() {
+		buf := getPacketBuffer()
+		buf.Data = buf.Data[:1000]
+		b, data := buf.shrink(buf.Data)
+		Expect(b).To(Equal(buf))
+		Expect(data).To(HaveLen(1000))
+		b.Release()
+	})
+
+	Context("using a custom allocator", func() {
+		var allocated, released [][]byte
+
+		BeforeEach(func() {
+			allocated = nil
+			released = nil
+			SetBufferAllocator(&testAllocator{
+				get: func(size int) []byte {
+					b := make([]byte, size)
+					allocated = append(allocated, b)
+					return b
+				},
+				put: func(b []byte) { released = append(released, b) },
+			})
+		})
+
+		AfterEach(func() {
+			SetBufferAllocator(nil)
+		})
+
+		It("gets buffers from the allocator", func() {
+			buf := getPacketBuffer()
+			Expect(allocated).To(HaveLen(1))
+			Expect(allocated[0]).To(HaveLen(int(protocol.MaxPacketBufferSize)))
+			buf.Data = append(buf.Data, []byte("foobar")...)
+			buf.Release()
+			Expect(released).To(HaveLen(1))
+			Expect(&released[0][0]).To(Equal(&allocated[0][0]))
+		})
+
+		It("returns buffers to the allocator they were allocated from", func() {
+			buf := getPacketBuffer()
+			SetBufferAllocator(nil)
+			buf.Release()
+			Expect(released).To(HaveLen(1))
+		})
+	})
+
 	It("waits until all parts have been released", func() {
 		buf := getPacketBuffer()
 		buf.Split()
@@ -53,3 +122,11 @@ var _ = Describe("Buffer Pool", func() {
 		Expect(func() { buf.Decrement() }).To(Panic())
 	})
 })
+
+type testAllocator struct {
+	get func(int) []byte
+	put func([]byte)
+}
+
+func (a *testAllocator) Get(size int) []byte { return a.get(size) }
+func (a *testAllocator) Put(b []byte)        { a.put(b) }
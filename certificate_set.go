@@ -0,0 +1,137 @@
+package quic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// A CertificateSet holds multiple certificate chains (e.g. an RSA and an ECDSA chain,
+// or chains for different server names) and selects one of them for every handshake.
+// It is used by the server when set as Config.Certificates, such that a single tls.Config can be used for all cases.
+// Alternatively, its GetCertificate method can be used as the tls.Config.GetCertificate callback.
+// It is safe to modify a CertificateSet while it is in use.
+type CertificateSet struct {
+	// Select is called with all certificates that are supported by the client,
+	// in the order in which they were added.
+	// It can be used to implement a custom selection policy.
+	// If it returns neither a certificate nor an error, the handshake fails.
+	// If it is nil, the first supported certificate is used.
+	Select func(chi *tls.ClientHelloInfo, candidates []*tls.Certificate) (*tls.Certificate, error)
+
+	mutex   sync.RWMutex
+	certs   []*tls.Certificate
+	staples map[string][]byte // indexed by the (lowercase) server name
+}
+
+// AddCertificate adds a certificate chain to the set.
+// If the Leaf of the certificate is not set, it is parsed from the first certificate of the chain.
+func (s *CertificateSet) AddCertificate(cert *tls.Certificate) error {
+	if len(cert.Certificate) == 0 {
+		return errors.New("certificate chain is empty")
+	}
+	if cert.Leaf == nil {
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			return err
+		}
+		cert.Leaf = leaf
+	}
+	s.mutex.Lock()
+	s.certs = append(s.certs, cert)
+	s.mutex.Unlock()
+	return nil
+}
+
+// RemoveCertificate removes a certificate chain from the set.
+func (s *CertificateSet) RemoveCertificate(cert *tls.Certificate) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, c := range s.certs {
+		if c == cert {
+			s.certs = append(s.certs[:i], s.certs[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetOCSPStaple sets the OCSP staple that is sent for connections to serverName.
+// It takes precedence over the OCSPStaple of the selected certificate.
+// Passing a nil staple removes the staple for serverName.
+func (s *CertificateSet) SetOCSPStaple(serverName string, staple []byte) {
+	serverName = strings.ToLower(serverName)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if staple == nil {
+		delete(s.staples, serverName)
+		return
+	}
+	if s.staples == nil {
+		s.staples = make(map[string][]byte)
+	}
+	s.staples[serverName] = staple
+}
+
+// GetCertificate selects a certificate chain for the ClientHello.
+// It only considers certificates that are supported by the client,
+// i.e. that are valid for the requested server name and that can be used
+// with the signature schemes offered by the client.
+func (s *CertificateSet) GetCertificate(chi *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mutex.RLock()
+	var candidates []*tls.Certificate
+	for _, c := range s.certs {
+		if err := chi.SupportsCertificate(c); err == nil {
+			candidates = append(candidates, c)
+		}
+	}
+	staple, hasStaple := s.staples[strings.ToLower(chi.ServerName)]
+	s.mutex.RUnlock()
+
+	if len(candidates) == 0 {
+		return nil, errors.New("no certificate supported by the client")
+	}
+	cert := candidates[0]
+	if s.Select != nil {
+		var err error
+		cert, err = s.Select(chi, candidates)
+		if err != nil {
+			return nil, err
+		}
+		if cert == nil {
+			return nil, errors.New("no certificate selected")
+		}
+	}
+	if hasStaple {
+		c := *cert
+		c.OCSPStaple = staple
+		cert = &c
+	}
+	return cert, nil
+}
+
+// configureTLS returns a copy of the tls.Config that selects the certificate chain from the set.
+// Configs returned by the GetConfigForClient callback are modified in the same way.
+func (s *CertificateSet) configureTLS(conf *tls.Config) *tls.Config {
+	conf = s.applyTo(conf)
+	if getConfigForClient := conf.GetConfigForClient; getConfigForClient != nil {
+		conf.GetConfigForClient = func(chi *tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := getConfigForClient(chi)
+			if err != nil || c == nil {
+				return c, err
+			}
+			return s.applyTo(c), nil
+		}
+	}
+	return conf
+}
+
+func (s *CertificateSet) applyTo(conf *tls.Config) *tls.Config {
+	conf = conf.Clone()
+	conf.Certificates = nil
+	conf.GetCertificate = s.GetCertificate
+	return conf
+}
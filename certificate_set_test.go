@@ -0,0 +1,219 @@
+package quic
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Certificate Set", func() {
+	generateCert := func(priv crypto.Signer, serverNames ...string) *tls.Certificate {
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: serverNames[0]},
+			DNSNames:     serverNames,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, priv.Public(), priv)
+		Expect(err).ToNot(HaveOccurred())
+		return &tls.Certificate{
+			Certificate: [][]byte{certDER},
+			PrivateKey:  priv,
+		}
+	}
+
+	generateECDSACert := func(serverNames ...string) *tls.Certificate {
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		return generateCert(priv, serverNames...)
+	}
+
+	generateEd25519Cert := func(serverNames ...string) *tls.Certificate {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+		return generateCert(priv, serverNames...)
+	}
+
+	clientHello := func(serverName string, schemes ...tls.SignatureScheme) *tls.ClientHelloInfo {
+		return &tls.ClientHelloInfo{
+			ServerName:        serverName,
+			SupportedVersions: []uint16{tls.VersionTLS13},
+			SignatureSchemes:  schemes,
+		}
+	}
+
+	var set *CertificateSet
+
+	BeforeEach(func() {
+		set = &CertificateSet{}
+	})
+
+	It("errors when adding an empty certificate chain", func() {
+		Expect(set.AddCertificate(&tls.Certificate{})).To(MatchError("certificate chain is empty"))
+	})
+
+	It("parses the leaf certificate", func() {
+		cert := generateECDSACert("quic.clemente.io")
+		Expect(set.AddCertificate(cert)).To(Succeed())
+		Expect(cert.Leaf).ToNot(BeNil())
+		Expect(cert.Leaf.DNSNames).To(Equal([]string{"quic.clemente.io"}))
+	})
+
+	It("errors when no certificate is supported by the client", func() {
+		Expect(set.AddCertificate(generateECDSACert("quic.clemente.io"))).To(Succeed())
+		_, err := set.GetCertificate(clientHello("example.com", tls.ECDSAWithP256AndSHA256))
+		Expect(err).To(MatchError("no certificate supported by the client"))
+	})
+
+	It("selects the certificate based on the server name", func() {
+		cert1 := generateECDSACert("foo.clemente.io")
+		cert2 := generateECDSACert("bar.clemente.io")
+		Expect(set.AddCertificate(cert1)).To(Succeed())
+		Expect(set.AddCertificate(cert2)).To(Succeed())
+		cert, err := set.GetCertificate(clientHello("bar.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert).To(Equal(cert2))
+		cert, err = set.GetCertificate(clientHello("foo.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert).To(Equal(cert1))
+	})
+
+	It("selects the certificate based on the signature schemes supported by the client", func() {
+		ecdsaCert := generateECDSACert("quic.clemente.io")
+		ed25519Cert := generateEd25519Cert("quic.clemente.io")
+		Expect(set.AddCertificate(ed25519Cert)).To(Succeed())
+		Expect(set.AddCertificate(ecdsaCert)).To(Succeed())
+		cert, err := set.GetCertificate(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert).To(Equal(ecdsaCert))
+		cert, err = set.GetCertificate(clientHello("quic.clemente.io", tls.Ed25519, tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert).To(Equal(ed25519Cert))
+	})
+
+	It("removes certificates", func() {
+		cert1 := generateECDSACert("quic.clemente.io")
+		cert2 := generateECDSACert("quic.clemente.io")
+		Expect(set.AddCertificate(cert1)).To(Succeed())
+		Expect(set.AddCertificate(cert2)).To(Succeed())
+		set.RemoveCertificate(cert1)
+		cert, err := set.GetCertificate(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert).To(Equal(cert2))
+	})
+
+	It("uses the selection callback", func() {
+		ecdsaCert := generateECDSACert("quic.clemente.io")
+		ed25519Cert := generateEd25519Cert("quic.clemente.io")
+		otherCert := generateECDSACert("example.com")
+		Expect(set.AddCertificate(ecdsaCert)).To(Succeed())
+		Expect(set.AddCertificate(otherCert)).To(Succeed())
+		Expect(set.AddCertificate(ed25519Cert)).To(Succeed())
+		var candidates []*tls.Certificate
+		set.Select = func(_ *tls.ClientHelloInfo, certs []*tls.Certificate) (*tls.Certificate, error) {
+			candidates = certs
+			return certs[len(certs)-1], nil
+		}
+		cert, err := set.GetCertificate(clientHello("quic.clemente.io", tls.Ed25519, tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert).To(Equal(ed25519Cert))
+		Expect(candidates).To(Equal([]*tls.Certificate{ecdsaCert, ed25519Cert}))
+	})
+
+	It("returns errors from the selection callback", func() {
+		Expect(set.AddCertificate(generateECDSACert("quic.clemente.io"))).To(Succeed())
+		testErr := errors.New("test error")
+		set.Select = func(*tls.ClientHelloInfo, []*tls.Certificate) (*tls.Certificate, error) { return nil, testErr }
+		_, err := set.GetCertificate(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("errors when the selection callback doesn't select a certificate", func() {
+		Expect(set.AddCertificate(generateECDSACert("quic.clemente.io"))).To(Succeed())
+		set.Select = func(*tls.ClientHelloInfo, []*tls.Certificate) (*tls.Certificate, error) { return nil, nil }
+		_, err := set.GetCertificate(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).To(MatchError("no certificate selected"))
+	})
+
+	It("uses per-SNI OCSP staples", func() {
+		cert := generateECDSACert("foo.clemente.io", "bar.clemente.io")
+		cert.OCSPStaple = []byte("default")
+		Expect(set.AddCertificate(cert)).To(Succeed())
+		set.SetOCSPStaple("Foo.clemente.io", []byte("foo"))
+		c, err := set.GetCertificate(clientHello("foo.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.OCSPStaple).To(Equal([]byte("foo")))
+		Expect(c.Certificate).To(Equal(cert.Certificate))
+		Expect(cert.OCSPStaple).To(Equal([]byte("default")))
+		c, err = set.GetCertificate(clientHello("bar.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.OCSPStaple).To(Equal([]byte("default")))
+		// remove the staple
+		set.SetOCSPStaple("foo.clemente.io", nil)
+		c, err = set.GetCertificate(clientHello("foo.clemente.io", tls.ECDSAWithP256AndSHA256))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.OCSPStaple).To(Equal([]byte("default")))
+	})
+
+	Context("configuring a tls.Config", func() {
+		It("selects the certificate from the set", func() {
+			cert := generateECDSACert("quic.clemente.io")
+			Expect(set.AddCertificate(cert)).To(Succeed())
+			otherCert := generateECDSACert("foo.clemente.io")
+			tlsConf := &tls.Config{Certificates: []tls.Certificate{*otherCert}, NextProtos: []string{"proto"}}
+			conf := set.configureTLS(tlsConf)
+			Expect(conf.Certificates).To(BeEmpty())
+			Expect(conf.NextProtos).To(Equal([]string{"proto"}))
+			c, err := conf.GetCertificate(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c).To(Equal(cert))
+			// the original tls.Config is not modified
+			Expect(tlsConf.Certificates).To(HaveLen(1))
+			Expect(tlsConf.GetCertificate).To(BeNil())
+		})
+
+		It("selects the certificate from the set for configs returned by GetConfigForClient", func() {
+			cert := generateECDSACert("quic.clemente.io")
+			Expect(set.AddCertificate(cert)).To(Succeed())
+			otherCert := generateECDSACert("foo.clemente.io")
+			perClientConf := &tls.Config{Certificates: []tls.Certificate{*otherCert}}
+			conf := set.configureTLS(&tls.Config{
+				GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return perClientConf, nil },
+			})
+			c, err := conf.GetConfigForClient(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.Certificates).To(BeEmpty())
+			selected, err := c.GetCertificate(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selected).To(Equal(cert))
+			Expect(perClientConf.Certificates).To(HaveLen(1))
+		})
+
+		It("passes on nil configs and errors returned by GetConfigForClient", func() {
+			testErr := errors.New("test error")
+			conf := set.configureTLS(&tls.Config{
+				GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return nil, testErr },
+			})
+			_, err := conf.GetConfigForClient(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+			Expect(err).To(MatchError(testErr))
+			conf = set.configureTLS(&tls.Config{
+				GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return nil, nil },
+			})
+			c, err := conf.GetConfigForClient(clientHello("quic.clemente.io", tls.ECDSAWithP256AndSHA256))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,258 @@
+// Package cidaffinity encodes the index of a node, and of a socket on that node,
+// into QUIC connection IDs, and extracts it again.
+// This allows a load balancer to route packets to the node that owns a connection,
+// without keeping any per-connection state.
+//
+// The first byte of a connection ID carries the ID of the configuration in its two most significant bits,
+// which allows rotating keys without breaking existing connections.
+// The remaining bytes contain the node index, the socket index and a random nonce,
+// encrypted with a keyed permutation (a 4-round Feistel network using AES as its round function).
+// Without knowledge of the key, connection IDs can't be linked to each other, or to the node that issued them.
+//
+// Initial and 0-RTT packets use a connection ID that was chosen by the client,
+// which doesn't contain any routing information.
+// Load balancers need to route these packets by other means, for example by hashing the client's address.
+package cidaffinity
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+const (
+	// MaxConfigID is the maximum value of Config.ConfigID.
+	// The config ID 3 is reserved for connection IDs that don't contain routing information.
+	MaxConfigID = 2
+	// DefaultConnectionIDLen is the connection ID length used if Config.ConnectionIDLen is not set.
+	DefaultConnectionIDLen = 8
+
+	maxConnectionIDLen = 20
+	// minNonceLen is the minimum number of random bytes in every connection ID.
+	minNonceLen      = 4
+	numFeistelRounds = 4
+)
+
+var (
+	// ErrInvalidConnectionIDLength is returned when decoding a connection ID that doesn't have the configured length.
+	ErrInvalidConnectionIDLength = errors.New("cidaffinity: invalid connection ID length")
+	// ErrUnknownConfigID is returned when decoding a connection ID that was encoded using a different configuration.
+	ErrUnknownConfigID = errors.New("cidaffinity: unknown config ID")
+	// ErrNotRoutable is returned when decoding a packet that doesn't carry a connection ID chosen by the server,
+	// i.e. Initial and 0-RTT packets.
+	ErrNotRoutable = errors.New("cidaffinity: packet not routable")
+)
+
+// Config configures a Codec.
+// All nodes and load balancers need to use the same Config.
+type Config struct {
+	// ConfigID is encoded into the two most significant bits of the connection ID.
+	// It must not be larger than MaxConfigID.
+	ConfigID uint8
+	// Key is the AES key used to encrypt the routing information.
+	// It must be 16, 24 or 32 bytes long.
+	Key []byte
+	// ConnectionIDLen is the length of the connection IDs.
+	// If zero, DefaultConnectionIDLen is used.
+	// It must not be larger than 20 bytes, and it must leave at least 4 bytes for the random nonce.
+	ConnectionIDLen int
+	// NodeIndexLen is the number of bytes used to encode the node index.
+	// It must be between 1 and 8.
+	NodeIndexLen int
+	// SocketIndexLen is the number of bytes used to encode the socket index.
+	// It must be between 0 and 8.
+	SocketIndexLen int
+}
+
+// A Codec encodes routing information into connection IDs, and decodes it again.
+// It is safe for concurrent use.
+type Codec struct {
+	configID        uint8
+	connIDLen       int
+	nodeIndexLen    int
+	socketIndexLen  int
+	block           cipher.Block
+	leftLen         int // length of the left half of the Feistel network
+	encryptedLength int
+}
+
+// NewCodec creates a new Codec.
+func NewCodec(conf *Config) (*Codec, error) {
+	if conf.ConfigID > MaxConfigID {
+		return nil, fmt.Errorf("cidaffinity: invalid config ID %d", conf.ConfigID)
+	}
+	connIDLen := conf.ConnectionIDLen
+	if connIDLen == 0 {
+		connIDLen = DefaultConnectionIDLen
+	}
+	if connIDLen > maxConnectionIDLen {
+		return nil, fmt.Errorf("cidaffinity: connection ID length %d too large", connIDLen)
+	}
+	if conf.NodeIndexLen < 1 || conf.NodeIndexLen > 8 {
+		return nil, fmt.Errorf("cidaffinity: invalid node index length %d", conf.NodeIndexLen)
+	}
+	if conf.SocketIndexLen < 0 || conf.SocketIndexLen > 8 {
+		return nil, fmt.Errorf("cidaffinity: invalid socket index length %d", conf.SocketIndexLen)
+	}
+	if connIDLen-1-conf.NodeIndexLen-conf.SocketIndexLen < minNonceLen {
+		return nil, fmt.Errorf("cidaffinity: connection ID length %d too short for a %d byte node index and a %d byte socket index", connIDLen, conf.NodeIndexLen, conf.SocketIndexLen)
+	}
+	block, err := aes.NewCipher(conf.Key)
+	if err != nil {
+		return nil, err
+	}
+	n := connIDLen - 1
+	return &Codec{
+		configID:        conf.ConfigID,
+		connIDLen:       connIDLen,
+		nodeIndexLen:    conf.NodeIndexLen,
+		socketIndexLen:  conf.SocketIndexLen,
+		block:           block,
+		leftLen:         n / 2,
+		encryptedLength: n,
+	}, nil
+}
+
+// ConnectionIDLen is the length of the connection IDs.
+func (c *Codec) ConnectionIDLen() int { return c.connIDLen }
+
+// Encode generates a new connection ID for a node and a socket.
+// Every call returns a different connection ID.
+func (c *Codec) Encode(node, socket uint64) ([]byte, error) {
+	if err := c.checkIndices(node, socket); err != nil {
+		return nil, err
+	}
+	b := make([]byte, c.connIDLen)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	b[0] = c.configID<<6 | b[0]&0x3f
+	putUint(b[1:1+c.nodeIndexLen], node)
+	putUint(b[1+c.nodeIndexLen:1+c.nodeIndexLen+c.socketIndexLen], socket)
+	c.encrypt(b[1:])
+	return b, nil
+}
+
+// Decode extracts the node index and the socket index from a connection ID.
+func (c *Codec) Decode(connID []byte) (node, socket uint64, _ error) {
+	if len(connID) != c.connIDLen {
+		return 0, 0, ErrInvalidConnectionIDLength
+	}
+	if connID[0]>>6 != c.configID {
+		return 0, 0, ErrUnknownConfigID
+	}
+	b := make([]byte, c.encryptedLength)
+	copy(b, connID[1:])
+	c.decrypt(b)
+	return getUint(b[:c.nodeIndexLen]), getUint(b[c.nodeIndexLen : c.nodeIndexLen+c.socketIndexLen]), nil
+}
+
+// DecodePacket extracts the node index and the socket index from the destination connection ID of a QUIC packet.
+// For Initial and 0-RTT packets, it returns ErrNotRoutable.
+func (c *Codec) DecodePacket(packet []byte) (node, socket uint64, _ error) {
+	if len(packet) == 0 {
+		return 0, 0, ErrInvalidConnectionIDLength
+	}
+	if packet[0]&0x80 == 0 { // short header packet
+		if len(packet) < 1+c.connIDLen {
+			return 0, 0, ErrInvalidConnectionIDLength
+		}
+		return c.Decode(packet[1 : 1+c.connIDLen])
+	}
+	// long header packet: 1 byte type, 4 bytes version, 1 byte connection ID length
+	if len(packet) < 6 {
+		return 0, 0, ErrInvalidConnectionIDLength
+	}
+	switch (packet[0] & 0x30) >> 4 {
+	case 0x0, 0x1: // Initial and 0-RTT
+		return 0, 0, ErrNotRoutable
+	}
+	connIDLen := int(packet[5])
+	if len(packet) < 6+connIDLen {
+		return 0, 0, ErrInvalidConnectionIDLength
+	}
+	return c.Decode(packet[6 : 6+connIDLen])
+}
+
+// ConnectionIDGenerator returns a quic.ConnectionIDGenerator that generates connection IDs for a node and a socket.
+// It can be used as the quic.Config.ConnectionIDGenerator.
+func (c *Codec) ConnectionIDGenerator(node, socket uint64) (quic.ConnectionIDGenerator, error) {
+	if err := c.checkIndices(node, socket); err != nil {
+		return nil, err
+	}
+	return &generator{codec: c, node: node, socket: socket}, nil
+}
+
+func (c *Codec) checkIndices(node, socket uint64) error {
+	if c.nodeIndexLen < 8 && node >= 1<<(8*c.nodeIndexLen) {
+		return fmt.Errorf("cidaffinity: node index %d too large", node)
+	}
+	if c.socketIndexLen < 8 && socket >= 1<<(8*c.socketIndexLen) {
+		return fmt.Errorf("cidaffinity: socket index %d too large", socket)
+	}
+	return nil
+}
+
+func (c *Codec) encrypt(b []byte) {
+	left, right := b[:c.leftLen], b[c.leftLen:]
+	for r := 0; r < numFeistelRounds; r++ {
+		c.feistelRound(r, left, right)
+	}
+}
+
+func (c *Codec) decrypt(b []byte) {
+	left, right := b[:c.leftLen], b[c.leftLen:]
+	for r := numFeistelRounds - 1; r >= 0; r-- {
+		c.feistelRound(r, left, right)
+	}
+}
+
+// feistelRound applies a single round of the Feistel network.
+// Even rounds modify the right half, odd rounds modify the left half.
+// Applying the same round twice is a no-op.
+func (c *Codec) feistelRound(r int, left, right []byte) {
+	in, out := left, right
+	if r%2 == 1 {
+		in, out = right, left
+	}
+	var block [aes.BlockSize]byte
+	block[0] = byte(r)
+	block[1] = byte(c.encryptedLength)
+	copy(block[2:], in)
+	c.block.Encrypt(block[:], block[:])
+	for i := range out {
+		out[i] ^= block[i]
+	}
+}
+
+type generator struct {
+	codec        *Codec
+	node, socket uint64
+}
+
+var _ quic.ConnectionIDGenerator = &generator{}
+
+func (g *generator) GenerateConnectionID() ([]byte, error) {
+	return g.codec.Encode(g.node, g.socket)
+}
+
+func (g *generator) ConnectionIDLen() int { return g.codec.connIDLen }
+
+func putUint(b []byte, v uint64) {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+}
+
+func getUint(b []byte) uint64 {
+	var v uint64
+	for _, x := range b {
+		v = v<<8 | uint64(x)
+	}
+	return v
+}
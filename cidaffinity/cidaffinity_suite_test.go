@@ -0,0 +1,13 @@
+package cidaffinity
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCIDAffinity(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "cidaffinity Suite")
+}
@@ -0,0 +1,175 @@
+package cidaffinity
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Connection ID affinity", func() {
+	key := bytes.Repeat([]byte{0x42}, 16)
+
+	newCodec := func(conf *Config) *Codec {
+		c, err := NewCodec(conf)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return c
+	}
+
+	It("uses the default connection ID length", func() {
+		c := newCodec(&Config{Key: key, NodeIndexLen: 2})
+		Expect(c.ConnectionIDLen()).To(Equal(DefaultConnectionIDLen))
+		connID, err := c.Encode(1, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID).To(HaveLen(DefaultConnectionIDLen))
+	})
+
+	It("rejects invalid configs", func() {
+		_, err := NewCodec(&Config{Key: key, NodeIndexLen: 2, ConfigID: 3})
+		Expect(err).To(MatchError("cidaffinity: invalid config ID 3"))
+		_, err = NewCodec(&Config{Key: key, NodeIndexLen: 2, ConnectionIDLen: 21})
+		Expect(err).To(MatchError("cidaffinity: connection ID length 21 too large"))
+		_, err = NewCodec(&Config{Key: key})
+		Expect(err).To(MatchError("cidaffinity: invalid node index length 0"))
+		_, err = NewCodec(&Config{Key: key, NodeIndexLen: 9})
+		Expect(err).To(MatchError("cidaffinity: invalid node index length 9"))
+		_, err = NewCodec(&Config{Key: key, NodeIndexLen: 1, SocketIndexLen: 9})
+		Expect(err).To(MatchError("cidaffinity: invalid socket index length 9"))
+		_, err = NewCodec(&Config{Key: key, NodeIndexLen: 2, SocketIndexLen: 2})
+		Expect(err).To(MatchError("cidaffinity: connection ID length 8 too short for a 2 byte node index and a 2 byte socket index"))
+		_, err = NewCodec(&Config{Key: []byte("foobar"), NodeIndexLen: 2})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("encodes and decodes, for all connection ID lengths", func() {
+		for l := 7; l <= 20; l++ {
+			c := newCodec(&Config{Key: key, ConfigID: 1, ConnectionIDLen: l, NodeIndexLen: 1, SocketIndexLen: 1})
+			for node := uint64(0); node < 256; node += 15 {
+				connID, err := c.Encode(node, 255-node)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(connID).To(HaveLen(l))
+				Expect(connID[0] >> 6).To(BeEquivalentTo(1))
+				n, s, err := c.Decode(connID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(node))
+				Expect(s).To(Equal(255 - node))
+			}
+		}
+	})
+
+	It("encodes large indices", func() {
+		c := newCodec(&Config{Key: key, ConnectionIDLen: 20, NodeIndexLen: 8, SocketIndexLen: 7})
+		connID, err := c.Encode(0xdeadbeefdecafbad, 0xaabbccddeeff00)
+		Expect(err).ToNot(HaveOccurred())
+		n, s, err := c.Decode(connID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(Equal(uint64(0xdeadbeefdecafbad)))
+		Expect(s).To(Equal(uint64(0xaabbccddeeff00)))
+	})
+
+	It("refuses to encode indices that are too large", func() {
+		c := newCodec(&Config{Key: key, NodeIndexLen: 1, SocketIndexLen: 1})
+		_, err := c.Encode(256, 0)
+		Expect(err).To(MatchError("cidaffinity: node index 256 too large"))
+		_, err = c.Encode(0, 256)
+		Expect(err).To(MatchError("cidaffinity: socket index 256 too large"))
+		_, err = c.ConnectionIDGenerator(256, 0)
+		Expect(err).To(MatchError("cidaffinity: node index 256 too large"))
+	})
+
+	It("generates unlinkable connection IDs", func() {
+		c := newCodec(&Config{Key: key, NodeIndexLen: 2})
+		connID1, err := c.Encode(1337, 0)
+		Expect(err).ToNot(HaveOccurred())
+		connID2, err := c.Encode(1337, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(connID1).ToNot(Equal(connID2))
+		// the node index is not visible in the connection ID
+		Expect(connID1[1:3]).ToNot(Equal(connID2[1:3]))
+	})
+
+	It("doesn't decode connection IDs encoded with a different key", func() {
+		c1 := newCodec(&Config{Key: key, NodeIndexLen: 3})
+		c2 := newCodec(&Config{Key: bytes.Repeat([]byte{0x1}, 16), NodeIndexLen: 3})
+		connID, err := c1.Encode(1337, 0)
+		Expect(err).ToNot(HaveOccurred())
+		n, _, err := c2.Decode(connID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).ToNot(Equal(uint64(1337)))
+	})
+
+	It("rejects connection IDs with the wrong length or config ID", func() {
+		c1 := newCodec(&Config{Key: key, NodeIndexLen: 2, ConfigID: 1})
+		c2 := newCodec(&Config{Key: key, NodeIndexLen: 2, ConfigID: 2})
+		connID, err := c1.Encode(1, 0)
+		Expect(err).ToNot(HaveOccurred())
+		_, _, err = c2.Decode(connID)
+		Expect(err).To(MatchError(ErrUnknownConfigID))
+		_, _, err = c1.Decode(connID[:7])
+		Expect(err).To(MatchError(ErrInvalidConnectionIDLength))
+	})
+
+	Context("decoding packets", func() {
+		var (
+			c      *Codec
+			connID []byte
+		)
+
+		BeforeEach(func() {
+			c = newCodec(&Config{Key: key, NodeIndexLen: 2, SocketIndexLen: 1})
+			var err error
+			connID, err = c.Encode(1000, 3)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("decodes short header packets", func() {
+			packet := append(append([]byte{0x40}, connID...), []byte("payload")...)
+			n, s, err := c.DecodePacket(packet)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(1000))
+			Expect(s).To(BeEquivalentTo(3))
+			_, _, err = c.DecodePacket(packet[:len(connID)])
+			Expect(err).To(MatchError(ErrInvalidConnectionIDLength))
+		})
+
+		It("decodes Handshake packets", func() {
+			packet := []byte{0xc0 | 0x2<<4, 0, 0, 0, 1, byte(len(connID))}
+			packet = append(append(packet, connID...), []byte("payload")...)
+			n, s, err := c.DecodePacket(packet)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(1000))
+			Expect(s).To(BeEquivalentTo(3))
+		})
+
+		It("doesn't decode Initial and 0-RTT packets", func() {
+			for _, t := range []byte{0x0, 0x1} {
+				packet := []byte{0xc0 | t<<4, 0, 0, 0, 1, byte(len(connID))}
+				packet = append(packet, connID...)
+				_, _, err := c.DecodePacket(packet)
+				Expect(err).To(MatchError(ErrNotRoutable))
+			}
+		})
+
+		It("rejects truncated packets", func() {
+			_, _, err := c.DecodePacket(nil)
+			Expect(err).To(MatchError(ErrInvalidConnectionIDLength))
+			_, _, err = c.DecodePacket([]byte{0xc0 | 0x2<<4, 0, 0})
+			Expect(err).To(MatchError(ErrInvalidConnectionIDLength))
+			_, _, err = c.DecodePacket(append([]byte{0xc0 | 0x2<<4, 0, 0, 0, 1, byte(len(connID))}, connID[:4]...))
+			Expect(err).To(MatchError(ErrInvalidConnectionIDLength))
+		})
+	})
+
+	It("returns a quic.ConnectionIDGenerator", func() {
+		c := newCodec(&Config{Key: key, ConnectionIDLen: 12, NodeIndexLen: 4, SocketIndexLen: 2})
+		g, err := c.ConnectionIDGenerator(42, 7)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(g.ConnectionIDLen()).To(Equal(12))
+		connID, err := g.GenerateConnectionID()
+		Expect(err).ToNot(HaveOccurred())
+		n, s, err := c.Decode(connID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(42))
+		Expect(s).To(BeEquivalentTo(7))
+	})
+})
@@ -121,7 +121,8 @@ func dialAddrContext(
 // the PacketConn satisfies the OOBCapablePacketConn interface (as a net.UDPConn
 // does), ECN and packet info support will be enabled. In this case, ReadMsgUDP
 // and WriteMsgUDP will be used instead of ReadFrom and WriteTo to read/write
-// packets. The same PacketConn can be used for multiple calls to Dial and
+// packets. To use a PacketConn that is not a UDP socket, see NewGenericPacketConn.
+// The same PacketConn can be used for multiple calls to Dial and
 // Listen, QUIC connection IDs are used for demultiplexing the different
 // connections. The host parameter is used for SNI. The tls.Config must define
 // an application protocol (using NextProtos).
@@ -193,6 +194,9 @@ func dialContext(
 		return nil, err
 	}
 	config = populateClientConfig(config, createdPacketConn)
+	if isGenericPacketConn(pconn) {
+		config.DisablePathMTUDiscovery = true
+	}
 	packetHandlers, err := getMultiplexer().AddConn(pconn, config.ConnectionIDLength, config.StatelessResetKey, config.Tracer)
 	if err != nil {
 		return nil, err
@@ -254,7 +258,13 @@ func newClient(
 		}
 	}
 
-	srcConnID, err := generateConnectionID(config.ConnectionIDLength)
+	var srcConnID protocol.ConnectionID
+	var err error
+	if config.ConnectionIDGenerator != nil {
+		srcConnID, err = config.newConnectionID()
+	} else {
+		srcConnID, err = generateConnectionID(config.ConnectionIDLength)
+	}
 	if err != nil {
 		return nil, err
 	}
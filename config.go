@@ -2,6 +2,7 @@ package quic
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -19,16 +20,96 @@ func (c *Config) handshakeTimeout() time.Duration {
 	return utils.MaxDuration(protocol.DefaultHandshakeTimeout, 2*c.HandshakeIdleTimeout)
 }
 
+// newConnectionID generates a new connection ID, using the ConnectionIDGenerator if one is set.
+func (c *Config) newConnectionID() (protocol.ConnectionID, error) {
+	if c.ConnectionIDGenerator == nil {
+		return protocol.GenerateConnectionID(c.ConnectionIDLength)
+	}
+	b, err := c.ConnectionIDGenerator.GenerateConnectionID()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != c.ConnectionIDLength {
+		return nil, fmt.Errorf("ConnectionIDGenerator generated a connection ID of length %d, expected %d", len(b), c.ConnectionIDLength)
+	}
+	return protocol.ConnectionID(b), nil
+}
+
 func validateConfig(config *Config) error {
 	if config == nil {
 		return nil
 	}
+	if config.ConnectionIDGenerator != nil {
+		l := config.ConnectionIDGenerator.ConnectionIDLen()
+		if l < 4 || l > protocol.MaxConnIDLen {
+			return errors.New("invalid connection ID length for Config.ConnectionIDGenerator")
+		}
+		if config.ConnectionIDLength != 0 && config.ConnectionIDLength != l {
+			return errors.New("Config.ConnectionIDLength doesn't match the Config.ConnectionIDGenerator")
+		}
+	}
 	if config.MaxIncomingStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingStreams")
 	}
 	if config.MaxIncomingUniStreams > 1<<60 {
 		return errors.New("invalid value for Config.MaxIncomingUniStreams")
 	}
+	if config.MaxCryptoFrameFragments < 0 || config.MaxCryptoFrameFragments > protocol.MaxCryptoFrameFragments {
+		return errors.New("invalid value for Config.MaxCryptoFrameFragments")
+	}
+	if config.NumSessionTickets > protocol.MaxSessionTickets {
+		return errors.New("invalid value for Config.NumSessionTickets")
+	}
+	if config.MaxUnprocessedPackets < 0 {
+		return errors.New("invalid value for Config.MaxUnprocessedPackets")
+	}
+	if config.UnprocessedPacketsEvictionPolicy > EvictOldest {
+		return errors.New("invalid value for Config.UnprocessedPacketsEvictionPolicy")
+	}
+	if config.MaxUndecryptablePackets < 0 {
+		return errors.New("invalid value for Config.MaxUndecryptablePackets")
+	}
+	if config.ProbePolicy > ProbePing {
+		return errors.New("invalid value for Config.ProbePolicy")
+	}
+	if config.StreamScheduler > StreamSchedulerIncremental {
+		return errors.New("invalid value for Config.StreamScheduler")
+	}
+	for class, c := range config.TrafficClasses {
+		if class == TrafficClassDefault {
+			return errors.New("the default traffic class can't be configured")
+		}
+		if c.DSCP >= 64 {
+			return fmt.Errorf("invalid DSCP for traffic class %d", class)
+		}
+	}
+	if config.PersistentCongestionThreshold < 0 {
+		return errors.New("invalid value for Config.PersistentCongestionThreshold")
+	}
+	if config.TokenIPv4PrefixLength < 0 || config.TokenIPv4PrefixLength > 32 {
+		return errors.New("invalid value for Config.TokenIPv4PrefixLength")
+	}
+	if config.TokenIPv6PrefixLength < 0 || config.TokenIPv6PrefixLength > 128 {
+		return errors.New("invalid value for Config.TokenIPv6PrefixLength")
+	}
+	if t, ok := config.WindowUpdateStrategy.(windowUpdateThreshold); ok && !t.isValid() {
+		return errors.New("invalid window update threshold for Config.WindowUpdateStrategy")
+	}
+	if config.MaxStreamAcceptBacklog < 0 {
+		return errors.New("invalid value for Config.MaxStreamAcceptBacklog")
+	}
+	if config.MaxUniStreamAcceptBacklog < 0 {
+		return errors.New("invalid value for Config.MaxUniStreamAcceptBacklog")
+	}
+	if config.StreamIdleTimeout < 0 {
+		return errors.New("invalid value for Config.StreamIdleTimeout")
+	}
+	if config.CoalescingDelay < 0 {
+		return errors.New("invalid value for Config.CoalescingDelay")
+	}
+	if config.CoalescingMinFill < 0 || config.CoalescingMinFill > 1 {
+		return errors.New("invalid value for Config.CoalescingMinFill")
+	}
 	return nil
 }
 
@@ -99,12 +180,59 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxInitialCryptoData := config.MaxInitialCryptoData
+	if maxInitialCryptoData == 0 {
+		maxInitialCryptoData = protocol.DefaultMaxCryptoStreamOffset
+	}
+	maxHandshakeCryptoData := config.MaxHandshakeCryptoData
+	if maxHandshakeCryptoData == 0 {
+		maxHandshakeCryptoData = protocol.DefaultMaxCryptoStreamOffset
+	}
+	maxOneRTTCryptoData := config.MaxOneRTTCryptoData
+	if maxOneRTTCryptoData == 0 {
+		maxOneRTTCryptoData = protocol.DefaultMaxCryptoStreamOffset
+	}
+	maxCryptoFrameFragments := config.MaxCryptoFrameFragments
+	if maxCryptoFrameFragments == 0 {
+		maxCryptoFrameFragments = protocol.DefaultMaxCryptoFrameFragments
+	}
+	numSessionTickets := config.NumSessionTickets
+	if numSessionTickets == 0 {
+		numSessionTickets = 1
+	}
+
+	maxUnprocessedPackets := config.MaxUnprocessedPackets
+	if maxUnprocessedPackets == 0 {
+		maxUnprocessedPackets = protocol.MaxServerUnprocessedPackets
+	}
+	maxUndecryptablePackets := config.MaxUndecryptablePackets
+	if maxUndecryptablePackets == 0 {
+		maxUndecryptablePackets = protocol.MaxUndecryptablePackets
+	}
+	maxUndecryptableBytes := config.MaxUndecryptableBytes
+	if maxUndecryptableBytes == 0 {
+		maxUndecryptableBytes = uint64(protocol.MaxUndecryptableBytes)
+	}
+	connIDLen := config.ConnectionIDLength
+	if connIDLen == 0 && config.ConnectionIDGenerator != nil {
+		connIDLen = config.ConnectionIDGenerator.ConnectionIDLen()
+	}
+	persistentCongestionThreshold := config.PersistentCongestionThreshold
+	if persistentCongestionThreshold == 0 {
+		persistentCongestionThreshold = protocol.DefaultPersistentCongestionThreshold
+	}
+	coalescingMinFill := config.CoalescingMinFill
+	if coalescingMinFill == 0 {
+		coalescingMinFill = protocol.DefaultCoalescingMinFill
+	}
 
 	return &Config{
 		Versions:                         versions,
 		HandshakeIdleTimeout:             handshakeIdleTimeout,
 		MaxIdleTimeout:                   idleTimeout,
 		AcceptToken:                      config.AcceptToken,
+		TokenIPv4PrefixLength:            config.TokenIPv4PrefixLength,
+		TokenIPv6PrefixLength:            config.TokenIPv6PrefixLength,
 		KeepAlive:                        config.KeepAlive,
 		InitialStreamReceiveWindow:       initialStreamReceiveWindow,
 		MaxStreamReceiveWindow:           maxStreamReceiveWindow,
@@ -112,12 +240,61 @@ func populateConfig(config *Config) *Config {
 		MaxConnectionReceiveWindow:       maxConnectionReceiveWindow,
 		MaxIncomingStreams:               maxIncomingStreams,
 		MaxIncomingUniStreams:            maxIncomingUniStreams,
-		ConnectionIDLength:               config.ConnectionIDLength,
+		MaxInitialCryptoData:             maxInitialCryptoData,
+		MaxHandshakeCryptoData:           maxHandshakeCryptoData,
+		MaxCertificateChainSize:          config.MaxCertificateChainSize,
+		MaxOneRTTCryptoData:              maxOneRTTCryptoData,
+		MaxCryptoFrameFragments:          maxCryptoFrameFragments,
+		Certificates:                     config.Certificates,
+		NumSessionTickets:                numSessionTickets,
+		DiscardSessionTickets:            config.DiscardSessionTickets,
+		SessionTicketStored:              config.SessionTicketStored,
+		SessionResumed:                   config.SessionResumed,
+		ConnectionIDLength:               connIDLen,
+		ConnectionIDGenerator:            config.ConnectionIDGenerator,
+		ConnectionIDRotation:             config.ConnectionIDRotation,
 		StatelessResetKey:                config.StatelessResetKey,
 		TokenStore:                       config.TokenStore,
 		EnableDatagrams:                  config.EnableDatagrams,
 		DisablePathMTUDiscovery:          config.DisablePathMTUDiscovery,
 		DisableVersionNegotiationPackets: config.DisableVersionNegotiationPackets,
+		MaxUnprocessedPackets:            maxUnprocessedPackets,
+		UnprocessedPacketsEvictionPolicy: config.UnprocessedPacketsEvictionPolicy,
+		MaxUndecryptablePackets:          maxUndecryptablePackets,
+		MaxUndecryptableBytes:            maxUndecryptableBytes,
+		ProbePolicy:                      config.ProbePolicy,
+		StreamScheduler:                  config.StreamScheduler,
+		TrafficClasses:                   config.TrafficClasses,
+		PersistentCongestionThreshold:    persistentCongestionThreshold,
+		AdaptiveReorderingThreshold:      config.AdaptiveReorderingThreshold,
+		WindowUpdateStrategy:             config.WindowUpdateStrategy,
+		ManualStreamReceiveCredit:        config.ManualStreamReceiveCredit,
+		AllowIncomingStream:              config.AllowIncomingStream,
+		MaxStreamAcceptBacklog:           config.MaxStreamAcceptBacklog,
+		MaxUniStreamAcceptBacklog:        config.MaxUniStreamAcceptBacklog,
+		RejectedStreamErrorCode:          config.RejectedStreamErrorCode,
+		StreamIdleTimeout:                config.StreamIdleTimeout,
+		StreamIdleTimeoutErrorCode:       config.StreamIdleTimeoutErrorCode,
+		MaxStreamReassemblyMemory:        config.MaxStreamReassemblyMemory,
+		MaxConnectionReassemblyMemory:    config.MaxConnectionReassemblyMemory,
+		ReassemblyMemoryErrorCode:        config.ReassemblyMemoryErrorCode,
+		CoalescingDelay:                  config.CoalescingDelay,
+		CoalescingMinFill:                coalescingMinFill,
 		Tracer:                           config.Tracer,
+		MemoryBudget:                     config.MemoryBudget,
+		PanicHandler:                     config.PanicHandler,
+		faultInjector:                    config.faultInjector,
+		StreamObserver:                   config.StreamObserver,
+		DatagramPayloadSizeChanged:       config.DatagramPayloadSizeChanged,
+		InspectLongHeaderPacket:          config.InspectLongHeaderPacket,
+		EnableBDPFrames:                  config.EnableBDPFrames,
+		EnableResetStreamAt:              config.EnableResetStreamAt,
+		EnablePacketHistorySnapshots:     config.EnablePacketHistorySnapshots,
+		BDPFrameReceived:                 config.BDPFrameReceived,
+		PeerAddressChanged:               config.PeerAddressChanged,
+		CheckPeerAddressChange:           config.CheckPeerAddressChange,
+		AckRangeFilter:                   config.AckRangeFilter,
+		StreamTap:                        config.StreamTap,
+		KeysAvailable:                    config.KeysAvailable,
 	}
 }
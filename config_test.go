@@ -30,6 +30,83 @@ var _ = Describe("Config", func() {
 		It("errors on too large values for MaxIncomingUniStreams", func() {
 			Expect(validateConfig(&Config{MaxIncomingUniStreams: 1<<60 + 1})).To(MatchError("invalid value for Config.MaxIncomingUniStreams"))
 		})
+
+		It("errors on invalid values for MaxCryptoFrameFragments", func() {
+			Expect(validateConfig(&Config{MaxCryptoFrameFragments: protocol.MaxCryptoFrameFragments})).To(Succeed())
+			Expect(validateConfig(&Config{MaxCryptoFrameFragments: -1})).To(MatchError("invalid value for Config.MaxCryptoFrameFragments"))
+			Expect(validateConfig(&Config{MaxCryptoFrameFragments: protocol.MaxCryptoFrameFragments + 1})).To(MatchError("invalid value for Config.MaxCryptoFrameFragments"))
+		})
+
+		It("errors on too large values for NumSessionTickets", func() {
+			Expect(validateConfig(&Config{NumSessionTickets: protocol.MaxSessionTickets})).To(Succeed())
+			Expect(validateConfig(&Config{NumSessionTickets: -1})).To(Succeed())
+			Expect(validateConfig(&Config{NumSessionTickets: protocol.MaxSessionTickets + 1})).To(MatchError("invalid value for Config.NumSessionTickets"))
+		})
+
+		It("errors on negative values for MaxUnprocessedPackets", func() {
+			Expect(validateConfig(&Config{MaxUnprocessedPackets: -1})).To(MatchError("invalid value for Config.MaxUnprocessedPackets"))
+		})
+
+		It("errors on negative values for MaxUndecryptablePackets", func() {
+			Expect(validateConfig(&Config{MaxUndecryptablePackets: -1})).To(MatchError("invalid value for Config.MaxUndecryptablePackets"))
+		})
+
+		It("errors on invalid eviction policies", func() {
+			Expect(validateConfig(&Config{UnprocessedPacketsEvictionPolicy: EvictOldest})).To(Succeed())
+			Expect(validateConfig(&Config{UnprocessedPacketsEvictionPolicy: EvictOldest + 1})).To(MatchError("invalid value for Config.UnprocessedPacketsEvictionPolicy"))
+		})
+
+		It("errors on invalid probe policies", func() {
+			Expect(validateConfig(&Config{ProbePolicy: ProbePing})).To(Succeed())
+			Expect(validateConfig(&Config{ProbePolicy: ProbePing + 1})).To(MatchError("invalid value for Config.ProbePolicy"))
+		})
+
+		It("errors on invalid stream schedulers", func() {
+			Expect(validateConfig(&Config{StreamScheduler: StreamSchedulerIncremental})).To(Succeed())
+			Expect(validateConfig(&Config{StreamScheduler: StreamSchedulerIncremental + 1})).To(MatchError("invalid value for Config.StreamScheduler"))
+		})
+
+		It("errors on invalid traffic classes", func() {
+			Expect(validateConfig(&Config{TrafficClasses: map[TrafficClass]TrafficClassConfig{1: {DSCP: 63}}})).To(Succeed())
+			Expect(validateConfig(&Config{TrafficClasses: map[TrafficClass]TrafficClassConfig{1: {DSCP: 64}}})).To(MatchError("invalid DSCP for traffic class 1"))
+			Expect(validateConfig(&Config{TrafficClasses: map[TrafficClass]TrafficClassConfig{TrafficClassDefault: {DSCP: 46}}})).To(MatchError("the default traffic class can't be configured"))
+		})
+
+		It("errors on a negative persistent congestion threshold", func() {
+			Expect(validateConfig(&Config{PersistentCongestionThreshold: -1})).To(MatchError("invalid value for Config.PersistentCongestionThreshold"))
+		})
+
+		It("errors on invalid token prefix lengths", func() {
+			Expect(validateConfig(&Config{TokenIPv4PrefixLength: 24, TokenIPv6PrefixLength: 56})).To(Succeed())
+			Expect(validateConfig(&Config{TokenIPv4PrefixLength: -1})).To(MatchError("invalid value for Config.TokenIPv4PrefixLength"))
+			Expect(validateConfig(&Config{TokenIPv4PrefixLength: 33})).To(MatchError("invalid value for Config.TokenIPv4PrefixLength"))
+			Expect(validateConfig(&Config{TokenIPv6PrefixLength: -1})).To(MatchError("invalid value for Config.TokenIPv6PrefixLength"))
+			Expect(validateConfig(&Config{TokenIPv6PrefixLength: 129})).To(MatchError("invalid value for Config.TokenIPv6PrefixLength"))
+		})
+
+		It("errors on invalid stream accept backlogs", func() {
+			Expect(validateConfig(&Config{MaxStreamAcceptBacklog: -1})).To(MatchError("invalid value for Config.MaxStreamAcceptBacklog"))
+			Expect(validateConfig(&Config{MaxUniStreamAcceptBacklog: -1})).To(MatchError("invalid value for Config.MaxUniStreamAcceptBacklog"))
+		})
+
+		It("errors on an invalid stream idle timeout", func() {
+			Expect(validateConfig(&Config{StreamIdleTimeout: -1})).To(MatchError("invalid value for Config.StreamIdleTimeout"))
+		})
+
+		It("errors on invalid coalescing parameters", func() {
+			Expect(validateConfig(&Config{CoalescingDelay: time.Millisecond, CoalescingMinFill: 1})).To(Succeed())
+			Expect(validateConfig(&Config{CoalescingDelay: -1})).To(MatchError("invalid value for Config.CoalescingDelay"))
+			Expect(validateConfig(&Config{CoalescingMinFill: -0.1})).To(MatchError("invalid value for Config.CoalescingMinFill"))
+			Expect(validateConfig(&Config{CoalescingMinFill: 1.1})).To(MatchError("invalid value for Config.CoalescingMinFill"))
+		})
+
+		It("errors on invalid connection ID generators", func() {
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{}})).To(Succeed())
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{}, ConnectionIDLength: 8})).To(Succeed())
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{}, ConnectionIDLength: 4})).To(MatchError("Config.ConnectionIDLength doesn't match the Config.ConnectionIDGenerator"))
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{length: 3}})).To(MatchError("invalid connection ID length for Config.ConnectionIDGenerator"))
+			Expect(validateConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{length: 21}})).To(MatchError("invalid connection ID length for Config.ConnectionIDGenerator"))
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -45,12 +122,16 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "AcceptToken", "GetLogWriter":
+			case "AcceptToken", "GetLogWriter", "PanicHandler", "DatagramPayloadSizeChanged", "SessionTicketStored", "SessionResumed", "InspectLongHeaderPacket", "BDPFrameReceived", "PeerAddressChanged", "AllowIncomingStream", "CheckPeerAddressChange", "KeysAvailable", "AckRangeFilter", "StreamTap":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
 			case "ConnectionIDLength":
 				f.Set(reflect.ValueOf(8))
+			case "ConnectionIDGenerator":
+				f.Set(reflect.ValueOf(&connIDGenerator8{}))
+			case "ConnectionIDRotation":
+				f.Set(reflect.ValueOf(&ConnectionIDRotationPolicy{Packets: 100}))
 			case "HandshakeIdleTimeout":
 				f.Set(reflect.ValueOf(time.Second))
 			case "MaxIdleTimeout":
@@ -69,18 +150,88 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(int64(11)))
 			case "MaxIncomingUniStreams":
 				f.Set(reflect.ValueOf(int64(12)))
+			case "MaxInitialCryptoData":
+				f.Set(reflect.ValueOf(uint64(2000)))
+			case "MaxHandshakeCryptoData":
+				f.Set(reflect.ValueOf(uint64(3000)))
+			case "MaxCertificateChainSize":
+				f.Set(reflect.ValueOf(uint64(5000)))
+			case "MaxOneRTTCryptoData":
+				f.Set(reflect.ValueOf(uint64(4000)))
+			case "MaxCryptoFrameFragments":
+				f.Set(reflect.ValueOf(13))
+			case "Certificates":
+				f.Set(reflect.ValueOf(&CertificateSet{}))
+			case "NumSessionTickets":
+				f.Set(reflect.ValueOf(3))
+			case "DiscardSessionTickets":
+				f.Set(reflect.ValueOf(true))
 			case "StatelessResetKey":
 				f.Set(reflect.ValueOf([]byte{1, 2, 3, 4}))
 			case "KeepAlive":
 				f.Set(reflect.ValueOf(true))
 			case "EnableDatagrams":
 				f.Set(reflect.ValueOf(true))
+			case "EnableBDPFrames":
+				f.Set(reflect.ValueOf(true))
+			case "EnableResetStreamAt":
+				f.Set(reflect.ValueOf(true))
+			case "EnablePacketHistorySnapshots":
+				f.Set(reflect.ValueOf(true))
 			case "DisableVersionNegotiationPackets":
 				f.Set(reflect.ValueOf(true))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
+			case "MaxUnprocessedPackets":
+				f.Set(reflect.ValueOf(42))
+			case "UnprocessedPacketsEvictionPolicy":
+				f.Set(reflect.ValueOf(EvictOldest))
+			case "MaxUndecryptablePackets":
+				f.Set(reflect.ValueOf(7))
+			case "MaxUndecryptableBytes":
+				f.Set(reflect.ValueOf(uint64(5000)))
+			case "ProbePolicy":
+				f.Set(reflect.ValueOf(ProbeNewData))
+			case "StreamScheduler":
+				f.Set(reflect.ValueOf(StreamSchedulerWeighted))
+			case "TrafficClasses":
+				f.Set(reflect.ValueOf(map[TrafficClass]TrafficClassConfig{1: {DSCP: 46}}))
+			case "PersistentCongestionThreshold":
+				f.Set(reflect.ValueOf(5))
+			case "AdaptiveReorderingThreshold":
+				f.Set(reflect.ValueOf(true))
+			case "ManualStreamReceiveCredit":
+				f.Set(reflect.ValueOf(true))
+			case "TokenIPv4PrefixLength":
+				f.Set(reflect.ValueOf(24))
+			case "TokenIPv6PrefixLength":
+				f.Set(reflect.ValueOf(56))
+			case "MaxStreamAcceptBacklog", "MaxUniStreamAcceptBacklog":
+				f.Set(reflect.ValueOf(10))
+			case "RejectedStreamErrorCode":
+				f.Set(reflect.ValueOf(StreamErrorCode(0x1337)))
+			case "StreamIdleTimeout":
+				f.Set(reflect.ValueOf(time.Minute))
+			case "StreamIdleTimeoutErrorCode":
+				f.Set(reflect.ValueOf(StreamErrorCode(0x42)))
+			case "MaxStreamReassemblyMemory":
+				f.Set(reflect.ValueOf(uint64(1 << 20)))
+			case "MaxConnectionReassemblyMemory":
+				f.Set(reflect.ValueOf(uint64(1 << 24)))
+			case "ReassemblyMemoryErrorCode":
+				f.Set(reflect.ValueOf(StreamErrorCode(0x43)))
+			case "CoalescingDelay":
+				f.Set(reflect.ValueOf(time.Millisecond))
+			case "CoalescingMinFill":
+				f.Set(reflect.ValueOf(0.8))
+			case "WindowUpdateStrategy":
+				f.Set(reflect.ValueOf(WindowUpdateThreshold(0.5)))
 			case "Tracer":
 				f.Set(reflect.ValueOf(mocklogging.NewMockTracer(mockCtrl)))
+			case "StreamObserver":
+				f.Set(reflect.ValueOf(&noopStreamObserver{}))
+			case "MemoryBudget":
+				f.Set(reflect.ValueOf(NewMemoryBudget(1 << 20)))
 			default:
 				Fail(fmt.Sprintf("all fields must be accounted for, but saw unknown field %q", fn))
 			}
@@ -130,13 +281,55 @@ var _ = Describe("Config", func() {
 
 	Context("populating", func() {
 		It("populates function fields", func() {
-			var calledAcceptToken bool
+			var calledAcceptToken, calledPanicHandler, calledDatagramPayloadSizeChanged, calledSessionTicketStored, calledSessionResumed, calledInspectLongHeaderPacket, calledBDPFrameReceived, calledPeerAddressChanged, calledAllowIncomingStream, calledCheckPeerAddressChange, calledKeysAvailable, calledAckRangeFilter, calledStreamTap bool
 			c1 := &Config{
-				AcceptToken: func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
+				AcceptToken:                func(_ net.Addr, _ *Token) bool { calledAcceptToken = true; return true },
+				PanicHandler:               func(Session, interface{}, []byte) { calledPanicHandler = true },
+				DatagramPayloadSizeChanged: func(Session, int) { calledDatagramPayloadSizeChanged = true },
+				SessionTicketStored:        func(Session) { calledSessionTicketStored = true },
+				SessionResumed:             func(Session, ResumptionInfo) { calledSessionResumed = true },
+				InspectLongHeaderPacket:    func(*LongHeaderPacketInfo) { calledInspectLongHeaderPacket = true },
+				BDPFrameReceived:           func(Session, BDPInfo) { calledBDPFrameReceived = true },
+				PeerAddressChanged:         func(Session, AddressChange) { calledPeerAddressChanged = true },
+				AllowIncomingStream:        func(Session, StreamID) (bool, StreamErrorCode) { calledAllowIncomingStream = true; return true, 0 },
+				CheckPeerAddressChange: func(Session, AddressChange) PeerAddressChangeDecision {
+					calledCheckPeerAddressChange = true
+					return PeerAddressChangeAccept
+				},
+				KeysAvailable: func(Session, EncryptionLevel, bool) { calledKeysAvailable = true },
+				AckRangeFilter: func(_ Session, r []AckRange) []AckRange {
+					calledAckRangeFilter = true
+					return r
+				},
+				StreamTap: func(Session, StreamDataDirection, StreamID, uint64, []byte, bool) { calledStreamTap = true },
 			}
 			c2 := populateConfig(c1)
 			c2.AcceptToken(&net.UDPAddr{}, &Token{})
 			Expect(calledAcceptToken).To(BeTrue())
+			c2.PanicHandler(nil, nil, nil)
+			Expect(calledPanicHandler).To(BeTrue())
+			c2.DatagramPayloadSizeChanged(nil, 0)
+			Expect(calledDatagramPayloadSizeChanged).To(BeTrue())
+			c2.SessionTicketStored(nil)
+			Expect(calledSessionTicketStored).To(BeTrue())
+			c2.SessionResumed(nil, ResumptionInfo{})
+			Expect(calledSessionResumed).To(BeTrue())
+			c2.InspectLongHeaderPacket(&LongHeaderPacketInfo{})
+			Expect(calledInspectLongHeaderPacket).To(BeTrue())
+			c2.BDPFrameReceived(nil, BDPInfo{})
+			Expect(calledBDPFrameReceived).To(BeTrue())
+			c2.PeerAddressChanged(nil, AddressChange{})
+			Expect(calledPeerAddressChanged).To(BeTrue())
+			c2.AllowIncomingStream(nil, 0)
+			Expect(calledAllowIncomingStream).To(BeTrue())
+			c2.CheckPeerAddressChange(nil, AddressChange{})
+			Expect(calledCheckPeerAddressChange).To(BeTrue())
+			c2.KeysAvailable(nil, Encryption1RTT, true)
+			Expect(calledKeysAvailable).To(BeTrue())
+			c2.AckRangeFilter(nil, nil)
+			Expect(calledAckRangeFilter).To(BeTrue())
+			c2.StreamTap(nil, StreamDataSent, 0, 0, nil, false)
+			Expect(calledStreamTap).To(BeTrue())
 		})
 
 		It("copies non-function fields", func() {
@@ -144,6 +337,12 @@ var _ = Describe("Config", func() {
 			Expect(populateConfig(c)).To(Equal(c))
 		})
 
+		It("copies the fault injector", func() {
+			fi := faultInjectorFunc(func(faultInjectionPacket) packetFault { return packetFault{} })
+			c := populateConfig(&Config{faultInjector: fi})
+			Expect(c.faultInjector).ToNot(BeNil())
+		})
+
 		It("populates empty fields with default values", func() {
 			c := populateConfig(&Config{})
 			Expect(c.Versions).To(Equal(protocol.SupportedVersions))
@@ -154,8 +353,45 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxConnectionReceiveWindow).To(BeEquivalentTo(protocol.DefaultMaxReceiveConnectionFlowControlWindow))
 			Expect(c.MaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
+			Expect(c.MaxInitialCryptoData).To(BeEquivalentTo(protocol.DefaultMaxCryptoStreamOffset))
+			Expect(c.MaxHandshakeCryptoData).To(BeEquivalentTo(protocol.DefaultMaxCryptoStreamOffset))
+			Expect(c.MaxOneRTTCryptoData).To(BeEquivalentTo(protocol.DefaultMaxCryptoStreamOffset))
+			Expect(c.MaxCryptoFrameFragments).To(Equal(protocol.DefaultMaxCryptoFrameFragments))
+			Expect(c.NumSessionTickets).To(Equal(1))
+			Expect(c.DiscardSessionTickets).To(BeFalse())
 			Expect(c.DisableVersionNegotiationPackets).To(BeFalse())
 			Expect(c.DisablePathMTUDiscovery).To(BeFalse())
+			Expect(c.MaxUnprocessedPackets).To(Equal(protocol.MaxServerUnprocessedPackets))
+			Expect(c.UnprocessedPacketsEvictionPolicy).To(Equal(EvictNewest))
+			Expect(c.MaxUndecryptablePackets).To(Equal(protocol.MaxUndecryptablePackets))
+			Expect(c.MaxUndecryptableBytes).To(BeEquivalentTo(protocol.MaxUndecryptableBytes))
+			Expect(c.ProbePolicy).To(Equal(ProbeRetransmitOldest))
+			Expect(c.StreamScheduler).To(Equal(StreamSchedulerStrict))
+			Expect(c.PersistentCongestionThreshold).To(Equal(protocol.DefaultPersistentCongestionThreshold))
+			Expect(c.CoalescingDelay).To(BeZero())
+			Expect(c.CoalescingMinFill).To(Equal(protocol.DefaultCoalescingMinFill))
+		})
+
+		It("uses the length of the connection ID generator", func() {
+			Expect(populateServerConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{}}).ConnectionIDLength).To(Equal(8))
+			Expect(populateClientConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{}}, true).ConnectionIDLength).To(Equal(8))
+		})
+
+		It("generates connection IDs using the connection ID generator", func() {
+			c := populateServerConfig(&Config{ConnectionIDGenerator: &connIDGenerator8{}})
+			connID, err := c.newConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID).To(Equal(protocol.ConnectionID{1, 0, 0, 0, 0, 0, 0, 8}))
+			c.ConnectionIDLength = 4
+			_, err = c.newConnectionID()
+			Expect(err).To(MatchError("ConnectionIDGenerator generated a connection ID of length 8, expected 4"))
+		})
+
+		It("generates random connection IDs", func() {
+			c := populateServerConfig(&Config{})
+			connID, err := c.newConnectionID()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(connID.Len()).To(Equal(protocol.DefaultConnectionIDLength))
 		})
 
 		It("populates empty fields with default values, for the server", func() {
@@ -175,3 +411,28 @@ var _ = Describe("Config", func() {
 		})
 	})
 })
+
+type connIDGenerator8 struct {
+	length  int
+	counter byte
+}
+
+func (g *connIDGenerator8) ConnectionIDLen() int {
+	if g.length == 0 {
+		return 8
+	}
+	return g.length
+}
+
+func (g *connIDGenerator8) GenerateConnectionID() ([]byte, error) {
+	g.counter++
+	b := make([]byte, g.ConnectionIDLen())
+	b[0] = g.counter
+	b[len(b)-1] = byte(len(b))
+	return b, nil
+}
+
+type noopStreamObserver struct{}
+
+func (noopStreamObserver) OpenedStream(Session, StreamID)   {}
+func (noopStreamObserver) AcceptedStream(Session, StreamID) {}
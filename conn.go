@@ -52,10 +52,11 @@ func (c *basicConn) ReadPacket() (*receivedPacket, error) {
 	if err != nil {
 		return nil, err
 	}
+	buffer, data := buffer.shrink(buffer.Data[:n])
 	return &receivedPacket{
 		remoteAddr: addr,
 		rcvTime:    time.Now(),
-		data:       buffer.Data[:n],
+		data:       data,
 		buffer:     buffer,
 	}, nil
 }
@@ -14,3 +14,5 @@ func inspectReadBuffer(interface{}) (int, error) {
 }
 
 func (i *packetInfo) OOB() []byte { return nil }
+
+func dscpOOB(net.Addr, uint8) []byte { return nil }
@@ -7,6 +7,9 @@ import "golang.org/x/sys/unix"
 
 const msgTypeIPTOS = unix.IP_RECVTOS
 
+// Setting the DSCP of outgoing packets is not supported.
+const dscpSupported = false
+
 const (
 	ipv4RECVPKTINFO = 0x7
 	ipv6RECVPKTINFO = 0x24
@@ -7,6 +7,9 @@ import "golang.org/x/sys/unix"
 
 const msgTypeIPTOS = unix.IP_TOS
 
+// The DSCP of outgoing packets can be set using an IP_TOS / IPV6_TCLASS control message.
+const dscpSupported = true
+
 const (
 	ipv4RECVPKTINFO = unix.IP_PKTINFO
 	ipv6RECVPKTINFO = unix.IPV6_RECVPKTINFO
@@ -1,6 +1,7 @@
 package quic
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -18,6 +19,7 @@ var _ = Describe("Connection ID Generator", func() {
 		removedConnIDs     []protocol.ConnectionID
 		replacedWithClosed map[string]packetHandler
 		queuedFrames       []wire.Frame
+		generateConnID     func() (protocol.ConnectionID, error)
 		g                  *connIDGenerator
 	)
 	initialConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7}
@@ -33,9 +35,11 @@ var _ = Describe("Connection ID Generator", func() {
 		removedConnIDs = nil
 		queuedFrames = nil
 		replacedWithClosed = make(map[string]packetHandler)
+		generateConnID = func() (protocol.ConnectionID, error) { return protocol.GenerateConnectionID(7) }
 		g = newConnIDGenerator(
 			initialConnID,
 			initialClientDestConnID,
+			func() (protocol.ConnectionID, error) { return generateConnID() },
 			func(c protocol.ConnectionID) { addedConnIDs = append(addedConnIDs, c) },
 			connIDToToken,
 			func(c protocol.ConnectionID) { removedConnIDs = append(removedConnIDs, c) },
@@ -64,6 +68,21 @@ var _ = Describe("Connection ID Generator", func() {
 		}
 	})
 
+	It("uses the connection ID generation function", func() {
+		var counter byte
+		generateConnID = func() (protocol.ConnectionID, error) {
+			counter++
+			return protocol.ConnectionID{counter, 0, 0, 0, 0, 0, 0}, nil
+		}
+		Expect(g.SetMaxActiveConnIDs(3)).To(Succeed())
+		Expect(addedConnIDs).To(Equal([]protocol.ConnectionID{{1, 0, 0, 0, 0, 0, 0}, {2, 0, 0, 0, 0, 0, 0}}))
+	})
+
+	It("returns errors from the connection ID generation function", func() {
+		generateConnID = func() (protocol.ConnectionID, error) { return nil, errors.New("generation failed") }
+		Expect(g.SetMaxActiveConnIDs(3)).To(MatchError("generation failed"))
+	})
+
 	It("limits the number of connection IDs that it issues", func() {
 		Expect(g.SetMaxActiveConnIDs(9999999)).To(Succeed())
 		Expect(retiredConnIDs).To(BeEmpty())
@@ -2,6 +2,7 @@ package quic
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -21,9 +22,14 @@ type connIDManager struct {
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
 	// hide the packet loss rate from on-path observers.
+	// If a rotation policy is configured, it is used instead.
 	rand                   utils.Rand
 	packetsSinceLastChange uint32
 	packetsPerConnectionID uint32
+	rotationPolicy         *ConnectionIDRotationPolicy
+	bytesSinceLastChange   protocol.ByteCount
+	firstPacketSentTime    time.Time // time when the first packet was sent with the active connection ID
+	lastPacketSentTime     time.Time
 
 	addStatelessResetToken    func(protocol.StatelessResetToken)
 	removeStatelessResetToken func(protocol.StatelessResetToken)
@@ -32,12 +38,14 @@ type connIDManager struct {
 
 func newConnIDManager(
 	initialDestConnID protocol.ConnectionID,
+	rotationPolicy *ConnectionIDRotationPolicy,
 	addStatelessResetToken func(protocol.StatelessResetToken),
 	removeStatelessResetToken func(protocol.StatelessResetToken),
 	queueControlFrame func(wire.Frame),
 ) *connIDManager {
 	return &connIDManager{
 		activeConnectionID:        initialDestConnID,
+		rotationPolicy:            rotationPolicy,
 		addStatelessResetToken:    addStatelessResetToken,
 		removeStatelessResetToken: removeStatelessResetToken,
 		queueControlFrame:         queueControlFrame,
@@ -148,6 +156,8 @@ func (h *connIDManager) updateConnectionID() {
 	h.activeConnectionID = front.ConnectionID
 	h.activeStatelessResetToken = &front.StatelessResetToken
 	h.packetsSinceLastChange = 0
+	h.bytesSinceLastChange = 0
+	h.firstPacketSentTime = time.Time{}
 	h.packetsPerConnectionID = protocol.PacketsPerConnectionID/2 + uint32(h.rand.Int31n(protocol.PacketsPerConnectionID))
 	h.addStatelessResetToken(*h.activeStatelessResetToken)
 }
@@ -176,8 +186,13 @@ func (h *connIDManager) SetStatelessResetToken(token protocol.StatelessResetToke
 	h.addStatelessResetToken(token)
 }
 
-func (h *connIDManager) SentPacket() {
+func (h *connIDManager) SentPacket(now time.Time, size protocol.ByteCount) {
+	if h.packetsSinceLastChange == 0 {
+		h.firstPacketSentTime = now
+	}
 	h.packetsSinceLastChange++
+	h.bytesSinceLastChange += size
+	h.lastPacketSentTime = now
 }
 
 func (h *connIDManager) shouldUpdateConnID() bool {
@@ -188,6 +203,9 @@ func (h *connIDManager) shouldUpdateConnID() bool {
 	if h.queue.Len() > 0 && h.activeSequenceNumber == 0 {
 		return true
 	}
+	if h.rotationPolicy != nil {
+		return h.queue.Len() > 0 && h.rotationLimitReached()
+	}
 	// For later changes, only change if
 	// 1. The queue of connection IDs is filled more than 50%.
 	// 2. We sent at least PacketsPerConnectionID packets
@@ -195,6 +213,18 @@ func (h *connIDManager) shouldUpdateConnID() bool {
 		h.packetsSinceLastChange >= h.packetsPerConnectionID
 }
 
+// rotationLimitReached says if any of the limits of the rotation policy was reached.
+func (h *connIDManager) rotationLimitReached() bool {
+	p := h.rotationPolicy
+	if p.Packets > 0 && uint64(h.packetsSinceLastChange) >= p.Packets {
+		return true
+	}
+	if p.Bytes > 0 && uint64(h.bytesSinceLastChange) >= p.Bytes {
+		return true
+	}
+	return p.Interval > 0 && h.packetsSinceLastChange > 0 && h.lastPacketSentTime.Sub(h.firstPacketSentTime) >= p.Interval
+}
+
 func (h *connIDManager) Get() protocol.ConnectionID {
 	if h.shouldUpdateConnID() {
 		h.updateConnectionID()
@@ -202,6 +232,12 @@ func (h *connIDManager) Get() protocol.ConnectionID {
 	return h.activeConnectionID
 }
 
+// ActiveConnectionIDLen returns the length of the connection ID currently used.
+// Unlike Get, it never switches to a new connection ID.
+func (h *connIDManager) ActiveConnectionIDLen() int {
+	return h.activeConnectionID.Len()
+}
+
 func (h *connIDManager) SetHandshakeComplete() {
 	h.handshakeComplete = true
 }
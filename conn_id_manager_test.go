@@ -1,6 +1,8 @@
 package quic
 
 import (
+	"time"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -24,6 +26,7 @@ var _ = Describe("Connection ID Manager", func() {
 		removedTokens = nil
 		m = newConnIDManager(
 			initialConnID,
+			nil,
 			func(token protocol.StatelessResetToken) { tokenAdded = &token },
 			func(token protocol.StatelessResetToken) { removedTokens = append(removedTokens, token) },
 			func(f wire.Frame,
@@ -275,7 +278,7 @@ var _ = Describe("Connection ID Manager", func() {
 
 		var counter int
 		for i := 0; i < 50*protocol.PacketsPerConnectionID; i++ {
-			m.SentPacket()
+			m.SentPacket(time.Now(), 1200)
 
 			connID := m.Get()
 			if !connID.Equal(lastConnID) {
@@ -305,7 +308,7 @@ var _ = Describe("Connection ID Manager", func() {
 		m.SetHandshakeComplete()
 		Expect(m.Get()).To(Equal(protocol.ConnectionID{10, 10, 10, 10}))
 		for {
-			m.SentPacket()
+			m.SentPacket(time.Now(), 1200)
 			if m.Get().Equal(protocol.ConnectionID{11, 11, 11, 11}) {
 				break
 			}
@@ -335,7 +338,7 @@ var _ = Describe("Connection ID Manager", func() {
 		m.SetHandshakeComplete()
 		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
 		for i := 0; i < 2*protocol.PacketsPerConnectionID; i++ {
-			m.SentPacket()
+			m.SentPacket(time.Now(), 1200)
 		}
 		Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
 		Expect(m.Add(&wire.NewConnectionIDFrame{
@@ -347,6 +350,77 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens[0]).To(Equal(protocol.StatelessResetToken{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}))
 	})
 
+	Context("using a rotation policy", func() {
+		addConnIDs := func(n uint8) {
+			for i := uint8(1); i <= n; i++ {
+				Expect(m.Add(&wire.NewConnectionIDFrame{
+					SequenceNumber:      uint64(i),
+					ConnectionID:        protocol.ConnectionID{i, i, i, i},
+					StatelessResetToken: protocol.StatelessResetToken{i, i, i, i, i, i, i, i, i, i, i, i, i, i, i, i},
+				})).To(Succeed())
+			}
+			m.SetHandshakeComplete()
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+		}
+
+		It("changes the connection ID after the number of packets", func() {
+			m.rotationPolicy = &ConnectionIDRotationPolicy{Packets: 10}
+			addConnIDs(3)
+			for i := 0; i < 9; i++ {
+				m.SentPacket(time.Now(), 1200)
+				Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			}
+			m.SentPacket(time.Now(), 1200)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+			for i := 0; i < 10; i++ {
+				m.SentPacket(time.Now(), 1200)
+			}
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{3, 3, 3, 3}))
+		})
+
+		It("changes the connection ID after the number of bytes", func() {
+			m.rotationPolicy = &ConnectionIDRotationPolicy{Bytes: 3000}
+			addConnIDs(2)
+			m.SentPacket(time.Now(), 1500)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			m.SentPacket(time.Now(), 1499)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			m.SentPacket(time.Now(), 1)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+		})
+
+		It("changes the connection ID after the interval", func() {
+			m.rotationPolicy = &ConnectionIDRotationPolicy{Interval: time.Minute}
+			addConnIDs(3)
+			now := time.Now()
+			m.SentPacket(now, 1200)
+			m.SentPacket(now.Add(time.Minute-time.Nanosecond), 1200)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			m.SentPacket(now.Add(time.Minute), 1200)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+			// the interval starts with the first packet sent using the new connection ID
+			now = now.Add(time.Hour)
+			m.SentPacket(now, 1200)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+			m.SentPacket(now.Add(time.Minute), 1200)
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{3, 3, 3, 3}))
+		})
+
+		It("keeps the connection ID if the peer didn't provide any new ones", func() {
+			m.rotationPolicy = &ConnectionIDRotationPolicy{Packets: 1}
+			addConnIDs(1)
+			for i := 0; i < 10; i++ {
+				m.SentPacket(time.Now(), 1200)
+			}
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{1, 1, 1, 1}))
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: 2,
+				ConnectionID:   protocol.ConnectionID{2, 2, 2, 2},
+			})).To(Succeed())
+			Expect(m.Get()).To(Equal(protocol.ConnectionID{2, 2, 2, 2}))
+		})
+	})
+
 	It("removes the currently active stateless reset token when it is closed", func() {
 		m.Close()
 		Expect(removedTokens).To(BeEmpty())
@@ -10,6 +10,7 @@ import (
 	"net"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"golang.org/x/net/ipv4"
 	"golang.org/x/net/ipv6"
@@ -212,10 +213,11 @@ func (c *oobConn) ReadPacket() (*receivedPacket, error) {
 			ifIndex: ifIndex,
 		}
 	}
+	buffer, data := buffer.shrink(msg.Buffers[0][:msg.N])
 	return &receivedPacket{
 		remoteAddr: msg.Addr,
 		rcvTime:    time.Now(),
-		data:       msg.Buffers[0][:msg.N],
+		data:       data,
 		ecn:        ecn,
 		info:       info,
 		buffer:     buffer,
@@ -227,6 +229,27 @@ func (c *oobConn) WritePacket(b []byte, addr net.Addr, oob []byte) (n int, err e
 	return n, err
 }
 
+// dscpOOB returns the control message that sets the DSCP of a packet sent to addr.
+// It returns nil if this is not supported on this platform.
+func dscpOOB(addr net.Addr, dscp uint8) []byte {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !dscpSupported || !ok {
+		return nil
+	}
+	level, typ := unix.IPPROTO_IPV6, unix.IPV6_TCLASS
+	if udpAddr.IP.To4() != nil {
+		level, typ = unix.IPPROTO_IP, unix.IP_TOS
+	}
+	b := make([]byte, unix.CmsgSpace(4))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&b[0]))
+	h.Level = int32(level)
+	h.Type = int32(typ)
+	h.SetLen(unix.CmsgLen(4))
+	// The lower two bits of the TOS / Traffic Class byte are the ECN bits.
+	binary.LittleEndian.PutUint32(b[unix.CmsgLen(0):], uint32(dscp)<<2)
+	return b
+}
+
 func (info *packetInfo) OOB() []byte {
 	if info == nil {
 		return nil
@@ -131,6 +131,58 @@ var _ = Describe("OOB Conn Test", func() {
 		})
 	})
 
+	Context("DSCP", func() {
+		// sendWithDSCP sends a packet marked with a DSCP, and returns the TOS / Traffic Class byte that it was received with
+		sendWithDSCP := func(network string, addr *net.UDPAddr, dscp uint8) byte {
+			if !dscpSupported {
+				Skip("setting the DSCP is not supported on this platform")
+			}
+			receiver, err := net.ListenUDP(network, addr)
+			Expect(err).ToNot(HaveOccurred())
+			defer receiver.Close()
+			rawConn, err := receiver.SyscallConn()
+			Expect(err).ToNot(HaveOccurred())
+			level, opt, typ := unix.IPPROTO_IP, unix.IP_RECVTOS, unix.IP_TOS
+			if network == "udp6" {
+				level, opt, typ = unix.IPPROTO_IPV6, unix.IPV6_RECVTCLASS, unix.IPV6_TCLASS
+			}
+			Expect(rawConn.Control(func(fd uintptr) {
+				Expect(unix.SetsockoptInt(int(fd), level, opt, 1)).To(Succeed())
+			})).To(Succeed())
+
+			udpConn, err := net.ListenUDP(network, addr)
+			Expect(err).ToNot(HaveOccurred())
+			defer udpConn.Close()
+			oobConn, err := newConn(udpConn)
+			Expect(err).ToNot(HaveOccurred())
+			c := newSendConn(oobConn, receiver.LocalAddr(), nil)
+			Expect(c.WriteWithDSCP([]byte("foobar"), dscp)).To(Succeed())
+
+			b := make([]byte, 100)
+			oob := make([]byte, 128)
+			n, oobn, _, _, err := receiver.ReadMsgUDP(b, oob)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b[:n]).To(Equal([]byte("foobar")))
+			msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+			Expect(err).ToNot(HaveOccurred())
+			for _, msg := range msgs {
+				if msg.Header.Level == int32(level) && msg.Header.Type == int32(typ) {
+					return msg.Data[0]
+				}
+			}
+			Fail("didn't receive the TOS / Traffic Class")
+			return 0
+		}
+
+		It("marks packets on IPv4", func() {
+			Expect(sendWithDSCP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, 46)).To(BeEquivalentTo(46 << 2))
+		})
+
+		It("marks packets on IPv6", func() {
+			Expect(sendWithDSCP("udp6", &net.UDPAddr{IP: net.IPv6loopback}, 10)).To(BeEquivalentTo(10 << 2))
+		})
+	})
+
 	Context("Packet Info conn", func() {
 		sendPacket := func(network string, addr *net.UDPAddr) net.Addr {
 			conn, err := net.DialUDP(network, nil, addr)
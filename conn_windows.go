@@ -52,3 +52,5 @@ func inspectReadBuffer(c net.PacketConn) (int, error) {
 }
 
 func (i *packetInfo) OOB() []byte { return nil }
+
+func dscpOOB(net.Addr, uint8) []byte { return nil }
@@ -8,6 +8,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 type cryptoStream interface {
@@ -15,6 +16,8 @@ type cryptoStream interface {
 	HandleCryptoFrame(*wire.CryptoFrame) error
 	GetCryptoData() []byte
 	Finish() error
+	Stats() logging.CryptoStreamStats
+	BufferedBytes() protocol.ByteCount
 	// for sending data
 	io.Writer
 	HasData() bool
@@ -25,6 +28,12 @@ type cryptoStreamImpl struct {
 	queue  *frameSorter
 	msgBuf []byte
 
+	maxOffset    protocol.ByteCount
+	maxFragments int
+	// the maximum size of a TLS Certificate message, 0 if not limited
+	maxCertificateSize uint64
+	stats              logging.CryptoStreamStats
+
 	highestOffset protocol.ByteCount
 	finished      bool
 
@@ -32,18 +41,42 @@ type cryptoStreamImpl struct {
 	writeBuf    []byte
 }
 
-func newCryptoStream() cryptoStream {
-	return &cryptoStreamImpl{queue: newFrameSorter()}
+// newCryptoStream creates a new crypto stream.
+// maxOffset limits the amount of data that can be received on this stream,
+// maxFragments limits the number of CRYPTO frames that are buffered out of order.
+func newCryptoStream(maxOffset protocol.ByteCount, maxFragments int) cryptoStream {
+	return &cryptoStreamImpl{
+		queue:        newFrameSorter(),
+		maxOffset:    maxOffset,
+		maxFragments: maxFragments,
+	}
+}
+
+// newHandshakeCryptoStream creates the crypto stream for the Handshake encryption level.
+// In addition to the limits of newCryptoStream, it limits the size of the peer's certificate chain,
+// if maxCertificateSize is non-zero.
+func newHandshakeCryptoStream(maxOffset protocol.ByteCount, maxFragments int, maxCertificateSize uint64) cryptoStream {
+	return &cryptoStreamImpl{
+		queue:              newFrameSorter(),
+		maxOffset:          maxOffset,
+		maxFragments:       maxFragments,
+		maxCertificateSize: maxCertificateSize,
+	}
 }
 
 func (s *cryptoStreamImpl) HandleCryptoFrame(f *wire.CryptoFrame) error {
 	highestOffset := f.Offset + protocol.ByteCount(len(f.Data))
-	if maxOffset := highestOffset; maxOffset > protocol.MaxCryptoStreamOffset {
-		return &qerr.TransportError{
-			ErrorCode:    qerr.CryptoBufferExceeded,
-			ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", maxOffset, protocol.MaxCryptoStreamOffset),
+	if maxOffset := highestOffset; maxOffset > s.maxOffset {
+		return &CryptoBufferExceededError{
+			TransportError: qerr.TransportError{
+				ErrorCode:    qerr.CryptoBufferExceeded,
+				ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", maxOffset, s.maxOffset),
+			},
+			Offset: uint64(maxOffset),
+			Limit:  uint64(s.maxOffset),
 		}
 	}
+	s.stats.Frames++
 	if s.finished {
 		if highestOffset > s.highestOffset {
 			// reject crypto data received after this stream was already finished
@@ -57,16 +90,62 @@ func (s *cryptoStreamImpl) HandleCryptoFrame(f *wire.CryptoFrame) error {
 		return nil
 	}
 	s.highestOffset = utils.MaxByteCount(s.highestOffset, highestOffset)
+	if f.Offset > s.queue.readPos {
+		s.stats.OutOfOrderFrames++
+	}
 	if err := s.queue.Push(f.Data, f.Offset, nil); err != nil {
 		return err
 	}
 	for {
 		_, data, _ := s.queue.Pop()
 		if data == nil {
-			return nil
+			break
 		}
 		s.msgBuf = append(s.msgBuf, data...)
 	}
+	if s.maxCertificateSize > 0 {
+		if err := s.checkCertificateSize(); err != nil {
+			return err
+		}
+	}
+	if !s.queue.HasMoreData() {
+		return nil
+	}
+	fragments := s.queue.NumEntries()
+	if fragments > s.stats.MaxBufferedFragments {
+		s.stats.MaxBufferedFragments = fragments
+	}
+	if fragments > s.maxFragments {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.CryptoBufferExceeded,
+			ErrorMessage: fmt.Sprintf("too many out-of-order CRYPTO frames buffered on crypto stream (%d), maximum allowed %d", fragments, s.maxFragments),
+		}
+	}
+	return nil
+}
+
+// checkCertificateSize checks the length of all TLS Certificate messages in the message buffer.
+// This allows us to reject a certificate chain that is too large as soon as we received the message header,
+// without buffering the whole chain.
+func (s *cryptoStreamImpl) checkCertificateSize() error {
+	for b := s.msgBuf; len(b) >= 4; {
+		msgLen := uint64(b[1])<<16 + uint64(b[2])<<8 + uint64(b[3])
+		if b[0] == messageTypeCertificate && msgLen > s.maxCertificateSize {
+			return &CertificateChainTooLargeError{
+				TransportError: qerr.TransportError{
+					ErrorCode:    qerr.CryptoBufferExceeded,
+					ErrorMessage: fmt.Sprintf("certificate chain too large (%d bytes), maximum allowed %d", msgLen, s.maxCertificateSize),
+				},
+				Size:  msgLen,
+				Limit: s.maxCertificateSize,
+			}
+		}
+		if uint64(len(b)) < 4+msgLen {
+			break
+		}
+		b = b[4+msgLen:]
+	}
+	return nil
 }
 
 // GetCryptoData retrieves data that was received in CRYPTO frames
@@ -84,6 +163,17 @@ func (s *cryptoStreamImpl) GetCryptoData() []byte {
 	return msg
 }
 
+// Stats returns statistics about the reassembly of the data received on this stream
+func (s *cryptoStreamImpl) Stats() logging.CryptoStreamStats {
+	return s.stats
+}
+
+// BufferedBytes returns the number of bytes of received data that are buffered,
+// either because they were received out of order, or because the TLS message is not complete yet.
+func (s *cryptoStreamImpl) BufferedBytes() protocol.ByteCount {
+	return s.queue.Memory() + protocol.ByteCount(len(s.msgBuf))
+}
+
 func (s *cryptoStreamImpl) Finish() error {
 	if s.queue.HasMoreData() {
 		return &qerr.TransportError{
@@ -5,18 +5,46 @@ import (
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 )
 
 type cryptoDataHandler interface {
 	HandleMessage([]byte, protocol.EncryptionLevel) bool
 }
 
+// The message types of the TLS NewSessionTicket and Certificate messages.
+const (
+	messageTypeNewSessionTicket = 4
+	messageTypeCertificate      = 11
+)
+
+// sessionTicketFilter is used by the client to count the session tickets received after completion of the handshake.
+// If discard is set, session tickets are dropped instead of being passed to TLS.
+type sessionTicketFilter struct {
+	cryptoDataHandler
+
+	discard         bool
+	onSessionTicket func()
+}
+
+func (f *sessionTicketFilter) HandleMessage(data []byte, encLevel protocol.EncryptionLevel) bool {
+	if encLevel == protocol.Encryption1RTT && len(data) > 0 && data[0] == messageTypeNewSessionTicket {
+		f.onSessionTicket()
+		if f.discard {
+			return false
+		}
+	}
+	return f.cryptoDataHandler.HandleMessage(data, encLevel)
+}
+
 type cryptoStreamManager struct {
 	cryptoHandler cryptoDataHandler
 
 	initialStream   cryptoStream
 	handshakeStream cryptoStream
 	oneRTTStream    cryptoStream
+
+	tracer logging.ConnectionTracer
 }
 
 func newCryptoStreamManager(
@@ -24,12 +52,14 @@ func newCryptoStreamManager(
 	initialStream cryptoStream,
 	handshakeStream cryptoStream,
 	oneRTTStream cryptoStream,
+	tracer logging.ConnectionTracer,
 ) *cryptoStreamManager {
 	return &cryptoStreamManager{
 		cryptoHandler:   cryptoHandler,
 		initialStream:   initialStream,
 		handshakeStream: handshakeStream,
 		oneRTTStream:    oneRTTStream,
+		tracer:          tracer,
 	}
 }
 
@@ -55,7 +85,36 @@ func (m *cryptoStreamManager) HandleCryptoFrame(frame *wire.CryptoFrame, encLeve
 			return false, nil
 		}
 		if encLevelFinished := m.cryptoHandler.HandleMessage(data, encLevel); encLevelFinished {
-			return true, str.Finish()
+			if err := str.Finish(); err != nil {
+				return true, err
+			}
+			if m.tracer != nil {
+				m.tracer.ReassembledCryptoData(encLevel, str.Stats())
+			}
+			return true, nil
+		}
+	}
+}
+
+// Close is called when the session is closed.
+// The 1-RTT crypto stream is never finished, so the statistics about its reassembly are traced here,
+// if any CRYPTO frames were received after completion of the handshake.
+func (m *cryptoStreamManager) Close() {
+	if m.tracer == nil || m.oneRTTStream == nil {
+		return
+	}
+	if stats := m.oneRTTStream.Stats(); stats.Frames > 0 {
+		m.tracer.ReassembledCryptoData(protocol.Encryption1RTT, stats)
+	}
+}
+
+// BufferedBytes returns the number of bytes of received data that are buffered on all crypto streams.
+func (m *cryptoStreamManager) BufferedBytes() protocol.ByteCount {
+	var n protocol.ByteCount
+	for _, str := range []cryptoStream{m.initialStream, m.handshakeStream, m.oneRTTStream} {
+		if str != nil {
+			n += str.BufferedBytes()
 		}
 	}
+	return n
 }
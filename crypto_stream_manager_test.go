@@ -4,8 +4,10 @@ import (
 	"errors"
 
 	"github.com/golang/mock/gomock"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -13,8 +15,9 @@ import (
 
 var _ = Describe("Crypto Stream Manager", func() {
 	var (
-		csm *cryptoStreamManager
-		cs  *MockCryptoDataHandler
+		csm    *cryptoStreamManager
+		cs     *MockCryptoDataHandler
+		tracer *mocklogging.MockConnectionTracer
 
 		initialStream   *MockCryptoStream
 		handshakeStream *MockCryptoStream
@@ -26,7 +29,8 @@ var _ = Describe("Crypto Stream Manager", func() {
 		handshakeStream = NewMockCryptoStream(mockCtrl)
 		oneRTTStream = NewMockCryptoStream(mockCtrl)
 		cs = NewMockCryptoDataHandler(mockCtrl)
-		csm = newCryptoStreamManager(cs, initialStream, handshakeStream, oneRTTStream)
+		tracer = mocklogging.NewMockConnectionTracer(mockCtrl)
+		csm = newCryptoStreamManager(cs, initialStream, handshakeStream, oneRTTStream, tracer)
 	})
 
 	It("passes messages to the initial stream", func() {
@@ -92,12 +96,39 @@ var _ = Describe("Crypto Stream Manager", func() {
 			handshakeStream.EXPECT().GetCryptoData().Return([]byte("foobar")),
 			cs.EXPECT().HandleMessage([]byte("foobar"), protocol.EncryptionHandshake).Return(true),
 			handshakeStream.EXPECT().Finish(),
+			handshakeStream.EXPECT().Stats().Return(logging.CryptoStreamStats{Frames: 1}),
+			tracer.EXPECT().ReassembledCryptoData(protocol.EncryptionHandshake, logging.CryptoStreamStats{Frames: 1}),
 		)
 		encLevelChanged, err := csm.HandleCryptoFrame(cf, protocol.EncryptionHandshake)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(encLevelChanged).To(BeTrue())
 	})
 
+	It("doesn't trace statistics when no tracer is set", func() {
+		csm = newCryptoStreamManager(cs, initialStream, handshakeStream, oneRTTStream, nil)
+		cf := &wire.CryptoFrame{Data: []byte("foobar")}
+		gomock.InOrder(
+			initialStream.EXPECT().HandleCryptoFrame(cf),
+			initialStream.EXPECT().GetCryptoData().Return([]byte("foobar")),
+			cs.EXPECT().HandleMessage([]byte("foobar"), protocol.EncryptionInitial).Return(true),
+			initialStream.EXPECT().Finish(),
+		)
+		encLevelChanged, err := csm.HandleCryptoFrame(cf, protocol.EncryptionInitial)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(encLevelChanged).To(BeTrue())
+	})
+
+	It("traces statistics about the 1-RTT crypto stream when closed", func() {
+		oneRTTStream.EXPECT().Stats().Return(logging.CryptoStreamStats{Frames: 2, OutOfOrderFrames: 1})
+		tracer.EXPECT().ReassembledCryptoData(protocol.Encryption1RTT, logging.CryptoStreamStats{Frames: 2, OutOfOrderFrames: 1})
+		csm.Close()
+	})
+
+	It("doesn't trace statistics about the 1-RTT crypto stream if no CRYPTO frames were received", func() {
+		oneRTTStream.EXPECT().Stats()
+		csm.Close()
+	})
+
 	It("returns errors that occur when finishing a stream", func() {
 		testErr := errors.New("test error")
 		cf := &wire.CryptoFrame{Data: []byte("foobar")}
@@ -111,9 +142,67 @@ var _ = Describe("Crypto Stream Manager", func() {
 		Expect(err).To(MatchError(err))
 	})
 
+	It("reports the number of buffered bytes on all crypto streams", func() {
+		initialStream.EXPECT().BufferedBytes().Return(protocol.ByteCount(1))
+		handshakeStream.EXPECT().BufferedBytes().Return(protocol.ByteCount(20))
+		oneRTTStream.EXPECT().BufferedBytes().Return(protocol.ByteCount(300))
+		Expect(csm.BufferedBytes()).To(Equal(protocol.ByteCount(321)))
+	})
+
+	It("reports the number of buffered bytes without a 1-RTT stream", func() {
+		csm = newCryptoStreamManager(cs, initialStream, handshakeStream, nil, tracer)
+		initialStream.EXPECT().BufferedBytes().Return(protocol.ByteCount(1))
+		handshakeStream.EXPECT().BufferedBytes().Return(protocol.ByteCount(20))
+		Expect(csm.BufferedBytes()).To(Equal(protocol.ByteCount(21)))
+	})
+
 	It("errors for unknown encryption levels", func() {
 		_, err := csm.HandleCryptoFrame(&wire.CryptoFrame{}, 42)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("received CRYPTO frame with unexpected encryption level"))
 	})
 })
+
+var _ = Describe("Session Ticket Filter", func() {
+	var (
+		cs    *MockCryptoDataHandler
+		count int
+	)
+
+	BeforeEach(func() {
+		cs = NewMockCryptoDataHandler(mockCtrl)
+		count = 0
+	})
+
+	newFilter := func(discard bool) *sessionTicketFilter {
+		return &sessionTicketFilter{
+			cryptoDataHandler: cs,
+			discard:           discard,
+			onSessionTicket:   func() { count++ },
+		}
+	}
+
+	It("counts session tickets", func() {
+		f := newFilter(false)
+		ticket := []byte{messageTypeNewSessionTicket, 0, 0, 3, 1, 2, 3}
+		cs.EXPECT().HandleMessage(ticket, protocol.Encryption1RTT)
+		Expect(f.HandleMessage(ticket, protocol.Encryption1RTT)).To(BeFalse())
+		Expect(count).To(Equal(1))
+	})
+
+	It("discards session tickets", func() {
+		f := newFilter(true)
+		Expect(f.HandleMessage([]byte{messageTypeNewSessionTicket, 0, 0, 3, 1, 2, 3}, protocol.Encryption1RTT)).To(BeFalse())
+		Expect(count).To(Equal(1))
+	})
+
+	It("passes other messages to TLS", func() {
+		f := newFilter(true)
+		// a NewSessionTicket message at the Handshake encryption level is a protocol violation, which is detected by the crypto setup
+		cs.EXPECT().HandleMessage([]byte{messageTypeNewSessionTicket}, protocol.EncryptionHandshake).Return(true)
+		Expect(f.HandleMessage([]byte{messageTypeNewSessionTicket}, protocol.EncryptionHandshake)).To(BeTrue())
+		cs.EXPECT().HandleMessage([]byte{42}, protocol.Encryption1RTT)
+		Expect(f.HandleMessage([]byte{42}, protocol.Encryption1RTT)).To(BeFalse())
+		Expect(count).To(BeZero())
+	})
+})
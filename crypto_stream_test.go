@@ -2,11 +2,13 @@ package quic
 
 import (
 	"crypto/rand"
+	"errors"
 	"fmt"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -26,7 +28,7 @@ var _ = Describe("Crypto Stream", func() {
 	var str cryptoStream
 
 	BeforeEach(func() {
-		str = newCryptoStream()
+		str = newCryptoStream(protocol.DefaultMaxCryptoStreamOffset, protocol.DefaultMaxCryptoFrameFragments)
 	})
 
 	Context("handling incoming data", func() {
@@ -51,11 +53,136 @@ var _ = Describe("Crypto Stream", func() {
 
 		It("errors if the frame exceeds the maximum offset", func() {
 			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
-				Offset: protocol.MaxCryptoStreamOffset - 5,
+				Offset: protocol.DefaultMaxCryptoStreamOffset - 5,
 				Data:   []byte("foobar"),
+			})).To(MatchError(&CryptoBufferExceededError{
+				TransportError: qerr.TransportError{
+					ErrorCode:    qerr.CryptoBufferExceeded,
+					ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", protocol.DefaultMaxCryptoStreamOffset+1, protocol.DefaultMaxCryptoStreamOffset),
+				},
+				Offset: protocol.DefaultMaxCryptoStreamOffset + 1,
+				Limit:  protocol.DefaultMaxCryptoStreamOffset,
+			}))
+		})
+
+		It("uses the configured maximum offset", func() {
+			str = newCryptoStream(100, protocol.DefaultMaxCryptoFrameFragments)
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: 94,
+				Data:   []byte("foobar"),
+			})).To(Succeed())
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: 95,
+				Data:   []byte("foobar"),
+			})).To(MatchError(&CryptoBufferExceededError{
+				TransportError: qerr.TransportError{
+					ErrorCode:    qerr.CryptoBufferExceeded,
+					ErrorMessage: "received invalid offset 101 on crypto stream, maximum allowed 100",
+				},
+				Offset: 101,
+				Limit:  100,
+			}))
+		})
+
+		It("returns an error that can be unwrapped to a TransportError", func() {
+			str = newCryptoStream(100, protocol.DefaultMaxCryptoFrameFragments)
+			err := str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 95, Data: []byte("foobar")})
+			var transportErr *qerr.TransportError
+			Expect(errors.As(err, &transportErr)).To(BeTrue())
+			Expect(transportErr.ErrorCode).To(Equal(qerr.CryptoBufferExceeded))
+			Expect(err.Error()).To(Equal(transportErr.Error()))
+		})
+
+		Context("limiting the certificate chain size", func() {
+			createCertificateMessage := func(len int) []byte {
+				msg := createHandshakeMessage(len)
+				msg[0] = messageTypeCertificate
+				return msg
+			}
+
+			BeforeEach(func() {
+				str = newHandshakeCryptoStream(protocol.DefaultMaxCryptoStreamOffset, protocol.DefaultMaxCryptoFrameFragments, 1000)
+			})
+
+			It("accepts a certificate chain that doesn't exceed the limit", func() {
+				msg := createCertificateMessage(1000)
+				Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Data: msg})).To(Succeed())
+				Expect(str.GetCryptoData()).To(Equal(msg))
+			})
+
+			It("rejects a certificate chain as soon as the message header is received", func() {
+				msg := createCertificateMessage(1001)
+				Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Data: msg[:4]})).To(MatchError(&CertificateChainTooLargeError{
+					TransportError: qerr.TransportError{
+						ErrorCode:    qerr.CryptoBufferExceeded,
+						ErrorMessage: "certificate chain too large (1001 bytes), maximum allowed 1000",
+					},
+					Size:  1001,
+					Limit: 1000,
+				}))
+			})
+
+			It("rejects a certificate chain following other messages", func() {
+				msg1 := createHandshakeMessage(6)
+				msg1[0] = 8 // EncryptedExtensions
+				msg2 := createCertificateMessage(2000)
+				msg := append(append([]byte{}, msg1...), msg2[:10]...)
+				err := str.HandleCryptoFrame(&wire.CryptoFrame{Data: msg})
+				var certErr *CertificateChainTooLargeError
+				Expect(errors.As(err, &certErr)).To(BeTrue())
+				Expect(certErr.Size).To(BeEquivalentTo(2000))
+				Expect(certErr.Limit).To(BeEquivalentTo(1000))
+			})
+
+			It("doesn't limit other messages", func() {
+				msg := createHandshakeMessage(2000)
+				msg[0] = 15 // CertificateVerify
+				Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Data: msg})).To(Succeed())
+				Expect(str.GetCryptoData()).To(Equal(msg))
+			})
+		})
+
+		It("errors if too many out-of-order frames are buffered", func() {
+			str = newCryptoStream(protocol.DefaultMaxCryptoStreamOffset, 10)
+			for i := 0; i < 10; i++ {
+				Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+					Offset: protocol.ByteCount(10 + 2*i),
+					Data:   []byte("f"),
+				})).To(Succeed())
+			}
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+				Offset: 100,
+				Data:   []byte("f"),
 			})).To(MatchError(&qerr.TransportError{
 				ErrorCode:    qerr.CryptoBufferExceeded,
-				ErrorMessage: fmt.Sprintf("received invalid offset %d on crypto stream, maximum allowed %d", protocol.MaxCryptoStreamOffset+1, protocol.MaxCryptoStreamOffset),
+				ErrorMessage: "too many out-of-order CRYPTO frames buffered on crypto stream (11), maximum allowed 10",
+			}))
+		})
+
+		It("accepts many tiny frames, as long as they can be processed", func() {
+			msg := createHandshakeMessage(500)
+			for i := range msg {
+				Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
+					Offset: protocol.ByteCount(i),
+					Data:   msg[i : i+1],
+				})).To(Succeed())
+			}
+			Expect(str.GetCryptoData()).To(Equal(msg))
+			Expect(str.Stats()).To(Equal(logging.CryptoStreamStats{Frames: len(msg)}))
+		})
+
+		It("collects statistics about out-of-order frames", func() {
+			msg := createHandshakeMessage(6)
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 8, Data: msg[8:]})).To(Succeed())
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 4, Data: msg[4:6]})).To(Succeed())
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Data: msg[:4]})).To(Succeed())
+			Expect(str.GetCryptoData()).To(BeNil())
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 6, Data: msg[6:8]})).To(Succeed())
+			Expect(str.GetCryptoData()).To(Equal(msg))
+			Expect(str.Stats()).To(Equal(logging.CryptoStreamStats{
+				Frames:               4,
+				OutOfOrderFrames:     2,
+				MaxBufferedFragments: 2,
 			}))
 		})
 
@@ -91,6 +218,21 @@ var _ = Describe("Crypto Stream", func() {
 			Expect(str.GetCryptoData()).To(BeNil())
 		})
 
+		It("reports the number of buffered bytes", func() {
+			msg := createHandshakeMessage(6)
+			Expect(str.BufferedBytes()).To(BeZero())
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 8, Data: msg[8:]})).To(Succeed())
+			Expect(str.BufferedBytes()).To(Equal(entryMemory(msg[8:])))
+			// the message is not complete yet
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Data: msg[:6]})).To(Succeed())
+			Expect(str.GetCryptoData()).To(BeNil())
+			Expect(str.BufferedBytes()).To(Equal(entryMemory(msg[8:]) + 6))
+			Expect(str.HandleCryptoFrame(&wire.CryptoFrame{Offset: 6, Data: msg[6:8]})).To(Succeed())
+			Expect(str.BufferedBytes()).To(BeEquivalentTo(len(msg)))
+			Expect(str.GetCryptoData()).To(Equal(msg))
+			Expect(str.BufferedBytes()).To(BeZero())
+		})
+
 		Context("finishing", func() {
 			It("errors if there's still data to read after finishing", func() {
 				Expect(str.HandleCryptoFrame(&wire.CryptoFrame{
@@ -1,6 +1,8 @@
 package quic
 
 import (
+	"sync/atomic"
+
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -9,6 +11,7 @@ import (
 type datagramQueue struct {
 	sendQueue chan *wire.DatagramFrame
 	rcvQueue  chan []byte
+	rcvBytes  int64 // accessed atomically
 
 	closeErr error
 	closed   chan struct{}
@@ -66,6 +69,7 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 	copy(data, f.Data)
 	select {
 	case h.rcvQueue <- data:
+		atomic.AddInt64(&h.rcvBytes, int64(len(data)))
 	default:
 		h.logger.Debugf("Discarding DATAGRAM frame (%d bytes payload)", len(f.Data))
 	}
@@ -75,12 +79,18 @@ func (h *datagramQueue) HandleDatagramFrame(f *wire.DatagramFrame) {
 func (h *datagramQueue) Receive() ([]byte, error) {
 	select {
 	case data := <-h.rcvQueue:
+		atomic.AddInt64(&h.rcvBytes, -int64(len(data)))
 		return data, nil
 	case <-h.closed:
 		return nil, h.closeErr
 	}
 }
 
+// ReceivedBytes returns the number of bytes of received DATAGRAM frames that were not yet read by the application.
+func (h *datagramQueue) ReceivedBytes() protocol.ByteCount {
+	return protocol.ByteCount(atomic.LoadInt64(&h.rcvBytes))
+}
+
 func (h *datagramQueue) CloseWithError(e error) {
 	h.closeErr = e
 	close(h.closed)
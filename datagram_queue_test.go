@@ -3,6 +3,7 @@ package quic
 import (
 	"errors"
 
+	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
@@ -68,6 +69,23 @@ var _ = Describe("Datagram Queue", func() {
 			Expect(data).To(Equal([]byte("bar")))
 		})
 
+		It("counts the bytes that were not yet received by the application", func() {
+			Expect(queue.ReceivedBytes()).To(BeZero())
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foobar")})
+			Expect(queue.ReceivedBytes()).To(Equal(protocol.ByteCount(9)))
+			_, err := queue.Receive()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(queue.ReceivedBytes()).To(Equal(protocol.ByteCount(6)))
+		})
+
+		It("doesn't count dropped DATAGRAM frames", func() {
+			for i := 0; i < protocol.DatagramRcvQueueLen+10; i++ {
+				queue.HandleDatagramFrame(&wire.DatagramFrame{Data: []byte("foo")})
+			}
+			Expect(queue.ReceivedBytes()).To(Equal(protocol.ByteCount(3 * protocol.DatagramRcvQueueLen)))
+		})
+
 		It("blocks until a frame is received", func() {
 			c := make(chan []byte, 1)
 			go func() {
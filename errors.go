@@ -15,6 +15,22 @@ type (
 	HandshakeTimeoutError   = qerr.HandshakeTimeoutError
 )
 
+// The following errors can be used with errors.Is to find out why a session was closed.
+// The typed errors above can be used with errors.As to get more details.
+var (
+	// ErrIdleTimeout matches an IdleTimeoutError.
+	ErrIdleTimeout = qerr.ErrIdleTimeout
+	// ErrHandshakeTimeout matches a HandshakeTimeoutError.
+	ErrHandshakeTimeout = qerr.ErrHandshakeTimeout
+	// ErrStatelessReset matches a StatelessResetError.
+	ErrStatelessReset = qerr.ErrStatelessReset
+	// ErrVersionNegotiation matches a VersionNegotiationError.
+	ErrVersionNegotiation = qerr.ErrVersionNegotiation
+	// ErrApplicationClose matches an ApplicationError,
+	// no matter if the session was closed by the peer or locally.
+	ErrApplicationClose = qerr.ErrApplicationClose
+)
+
 type (
 	TransportErrorCode   = qerr.TransportErrorCode
 	ApplicationErrorCode = qerr.ApplicationErrorCode
@@ -46,6 +62,9 @@ const (
 type StreamError struct {
 	StreamID  StreamID
 	ErrorCode StreamErrorCode
+	// Err is the error registered for the ErrorCode with the session's StreamErrorRegistry.
+	// It is nil if no error was registered.
+	Err error
 }
 
 func (e *StreamError) Is(target error) bool {
@@ -53,6 +72,98 @@ func (e *StreamError) Is(target error) bool {
 	return ok
 }
 
+func (e *StreamError) Unwrap() error { return e.Err }
+
 func (e *StreamError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("stream %d canceled with error code %d: %s", e.StreamID, e.ErrorCode, e.Err)
+	}
 	return fmt.Sprintf("stream %d canceled with error code %d", e.StreamID, e.ErrorCode)
 }
+
+// A StopSendingError is returned from SendStream.Write if the peer sent a STOP_SENDING frame.
+// It wraps the StreamError carrying the peer's error code.
+type StopSendingError struct {
+	StreamError
+	// DeliveredOffset is the offset up to which all data written to the stream had been
+	// acknowledged by the peer when the STOP_SENDING frame was received.
+	// Data beyond this offset might or might not have been received by the peer.
+	DeliveredOffset uint64
+}
+
+func (e *StopSendingError) Unwrap() error { return &e.StreamError }
+
+func (e *StopSendingError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("stream %d: peer stopped reading with error code %d (data delivered up to offset %d): %s", e.StreamID, e.ErrorCode, e.DeliveredOffset, e.Err)
+	}
+	return fmt.Sprintf("stream %d: peer stopped reading with error code %d (data delivered up to offset %d)", e.StreamID, e.ErrorCode, e.DeliveredOffset)
+}
+
+// A CryptoBufferExceededError occurs when the peer sends more CRYPTO data than allowed at an encryption level,
+// see Config.MaxInitialCryptoData, Config.MaxHandshakeCryptoData and Config.MaxOneRTTCryptoData.
+// The session is closed with a CRYPTO_BUFFER_EXCEEDED error.
+type CryptoBufferExceededError struct {
+	TransportError
+	// Offset is the highest offset of the CRYPTO data received.
+	Offset uint64
+	// Limit is the limit configured for the encryption level.
+	Limit uint64
+}
+
+func (e *CryptoBufferExceededError) Unwrap() error { return &e.TransportError }
+
+// A CertificateChainTooLargeError occurs when the peer sends a certificate chain larger than Config.MaxCertificateChainSize.
+// Unlike for TLS errors, the reason is sent to the peer along with the CRYPTO_BUFFER_EXCEEDED error code,
+// so that peers with a very large certificate chain can find out why the handshake failed.
+type CertificateChainTooLargeError struct {
+	TransportError
+	// Size is the size of the TLS Certificate message.
+	Size uint64
+	// Limit is Config.MaxCertificateChainSize.
+	Limit uint64
+}
+
+func (e *CertificateChainTooLargeError) Unwrap() error { return &e.TransportError }
+
+// A ZeroRTTParameterError occurs when the server accepts 0-RTT, but its transport parameters don't allow the data
+// that the client might have sent in 0-RTT, based on the transport parameters remembered from the previous connection
+// (see Section 7.4.1 of RFC 9000). This is a PROTOCOL_VIOLATION.
+type ZeroRTTParameterError struct {
+	TransportError
+	// Parameter is the name of the first transport parameter that was reduced, as defined in RFC 9000,
+	// e.g. "initial_max_data".
+	Parameter string
+}
+
+func (e *ZeroRTTParameterError) Unwrap() error { return &e.TransportError }
+
+// A StreamReassemblyMemoryError is returned from ReceiveStream.Read if reading was canceled because the data
+// buffered for the stream held more memory than Config.MaxStreamReassemblyMemory.
+// It wraps the StreamError carrying the Config.ReassemblyMemoryErrorCode that was sent to the peer in the STOP_SENDING frame.
+type StreamReassemblyMemoryError struct {
+	StreamError
+	// Memory is the memory held by the stream's buffered data when the limit was exceeded.
+	Memory uint64
+	// Limit is Config.MaxStreamReassemblyMemory.
+	Limit uint64
+}
+
+func (e *StreamReassemblyMemoryError) Unwrap() error { return &e.StreamError }
+
+func (e *StreamReassemblyMemoryError) Error() string {
+	return fmt.Sprintf("stream %d: reassembly memory limit exceeded (%d > %d bytes), reading canceled with error code %d", e.StreamID, e.Memory, e.Limit, e.ErrorCode)
+}
+
+// A ConnectionReassemblyMemoryError occurs when the data buffered for all streams of a session
+// holds more memory than Config.MaxConnectionReassemblyMemory.
+// The peer didn't violate the protocol, so the session is closed with an INTERNAL_ERROR.
+type ConnectionReassemblyMemoryError struct {
+	TransportError
+	// Memory is the memory held by the buffered data of all streams when the limit was exceeded.
+	Memory uint64
+	// Limit is Config.MaxConnectionReassemblyMemory.
+	Limit uint64
+}
+
+func (e *ConnectionReassemblyMemoryError) Unwrap() error { return &e.TransportError }
@@ -2,15 +2,9 @@ package main
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/tls"
-	"crypto/x509"
-	"encoding/pem"
 	"fmt"
 	"io"
 	"log"
-	"math/big"
 
 	quic "github.com/lucas-clemente/quic-go"
 )
@@ -32,7 +26,11 @@ func main() {
 
 // Start a server that echos all data on the first stream opened by the client
 func echoServer() error {
-	listener, err := quic.ListenAddr(addr, generateTLSConfig(), nil)
+	tlsConf, err := quic.InsecureTestingTLSConfig("quic-echo-example")
+	if err != nil {
+		return err
+	}
+	listener, err := quic.ListenAddr(addr, tlsConf, nil)
 	if err != nil {
 		return err
 	}
@@ -50,9 +48,9 @@ func echoServer() error {
 }
 
 func clientMain() error {
-	tlsConf := &tls.Config{
-		InsecureSkipVerify: true,
-		NextProtos:         []string{"quic-echo-example"},
+	tlsConf, err := quic.InsecureTestingTLSConfig("quic-echo-example")
+	if err != nil {
+		return err
 	}
 	session, err := quic.DialAddr(addr, tlsConf, nil)
 	if err != nil {
@@ -87,27 +85,3 @@ func (w loggingWriter) Write(b []byte) (int, error) {
 	fmt.Printf("Server: Got '%s'\n", string(b))
 	return w.Writer.Write(b)
 }
-
-// Setup a bare-bones TLS config for the server
-func generateTLSConfig() *tls.Config {
-	key, err := rsa.GenerateKey(rand.Reader, 1024)
-	if err != nil {
-		panic(err)
-	}
-	template := x509.Certificate{SerialNumber: big.NewInt(1)}
-	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
-	if err != nil {
-		panic(err)
-	}
-	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
-	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
-
-	tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
-	if err != nil {
-		panic(err)
-	}
-	return &tls.Config{
-		Certificates: []tls.Certificate{tlsCert},
-		NextProtos:   []string{"quic-echo-example"},
-	}
-}
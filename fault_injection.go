@@ -0,0 +1,231 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// A faultInjectionPacket describes a packet that a session is about to send, or that it received.
+type faultInjectionPacket struct {
+	Type logging.PacketType
+	// PacketNumber is the packet number of a sent packet.
+	// Received packets are inspected before they are decrypted, so it is InvalidPacketNumber for them.
+	PacketNumber logging.PacketNumber
+	// Size is the size of the packet.
+	// For coalesced packets, this is the size of this packet, not the size of the datagram.
+	Size logging.ByteCount
+	// HasAck says if the packet contains an ACK frame. It is always false for received packets.
+	HasAck bool
+	// IsAckEliciting says if the packet contains any ack-eliciting frames. It is always false for received packets.
+	IsAckEliciting bool
+}
+
+// A packetFault is applied to a packet before it is sent, or before it is processed.
+// Faults can be combined, e.g. a packet can be corrupted and duplicated.
+type packetFault struct {
+	// Drop drops the packet. All other fields are ignored.
+	Drop bool
+	// Delay delays sending (or processing) of the packet.
+	Delay time.Duration
+	// Duplicate sends (or processes) the packet twice.
+	Duplicate bool
+	// Corrupt modifies the packet, such that decrypting it fails.
+	Corrupt bool
+}
+
+// A faultInjector injects faults into the packets sent and received by a session.
+// It allows writing targeted tests for the loss recovery logic,
+// e.g. by dropping all Handshake packets sent by the server, or every 10th packet containing an ACK.
+// It is only set in tests, see Config.faultInjector.
+//
+// The methods are called from the session's run loop, so they must not block.
+// Delayed and duplicated packets are sent (or processed) by the run loop as well.
+// They are discarded when the session is closed.
+type faultInjector interface {
+	// SendingPacket is called after the packet was passed to the loss detection logic,
+	// so the packet is declared lost (and retransmitted) if it is dropped.
+	SendingPacket(faultInjectionPacket) packetFault
+	// ReceivedPacket is called before the packet is decrypted.
+	ReceivedPacket(faultInjectionPacket) packetFault
+}
+
+// The faultInjectorFunc type is an adapter to allow the use of ordinary functions as a faultInjector.
+// It only injects faults into sent packets.
+type faultInjectorFunc func(faultInjectionPacket) packetFault
+
+// SendingPacket calls f(p).
+func (f faultInjectorFunc) SendingPacket(p faultInjectionPacket) packetFault {
+	return f(p)
+}
+
+// ReceivedPacket doesn't inject any faults.
+func (f faultInjectorFunc) ReceivedPacket(faultInjectionPacket) packetFault {
+	return packetFault{}
+}
+
+// A delayedPacket is a packet that was delayed or duplicated by the faultInjector.
+// Exactly one of sent and received is set.
+type delayedPacket struct {
+	time     time.Time
+	sent     *packetBuffer
+	received *receivedPacket
+}
+
+// sendDatagram sends a datagram, containing one or more (coalesced) packets.
+func (s *session) sendDatagram(buffer *packetBuffer, packets []*packetContents) {
+	if s.config.faultInjector == nil {
+		s.sendQueue.Send(buffer)
+		return
+	}
+	s.injectFaults(buffer, packets)
+}
+
+func (s *session) injectFaults(buffer *packetBuffer, packets []*packetContents) {
+	data := buffer.Data
+	var n, offset int
+	for _, p := range packets {
+		raw := data[offset : offset+int(p.length)]
+		offset += int(p.length)
+		fault := s.config.faultInjector.SendingPacket(faultInjectionPacket{
+			Type:           logging.PacketTypeFromHeader(&p.header.Header),
+			PacketNumber:   p.header.PacketNumber,
+			Size:           p.length,
+			HasAck:         p.ack != nil,
+			IsAckEliciting: p.IsAckEliciting(),
+		})
+		if fault.Drop {
+			s.logger.Debugf("fault injection: dropping packet %d (%s)", p.header.PacketNumber, p.EncryptionLevel())
+			continue
+		}
+		if fault.Corrupt {
+			s.logger.Debugf("fault injection: corrupting packet %d (%s)", p.header.PacketNumber, p.EncryptionLevel())
+			// The last byte is part of the AEAD tag.
+			raw[len(raw)-1] ^= 0xff
+		}
+		if fault.Duplicate {
+			s.logger.Debugf("fault injection: duplicating packet %d (%s)", p.header.PacketNumber, p.EncryptionLevel())
+			s.sendDelayed(raw, buffer.dscp, fault.Delay)
+		}
+		if fault.Delay > 0 {
+			s.logger.Debugf("fault injection: delaying packet %d (%s) by %s", p.header.PacketNumber, p.EncryptionLevel(), fault.Delay)
+			s.sendDelayed(raw, buffer.dscp, fault.Delay)
+			continue
+		}
+		n += copy(data[n:], raw)
+	}
+	if n == 0 {
+		buffer.Release()
+		return
+	}
+	buffer.Data = data[:n]
+	s.sendQueue.Send(buffer)
+}
+
+// sendDelayed queues a copy of a packet, which is sent by the run loop after the delay.
+func (s *session) sendDelayed(raw []byte, dscp uint8, delay time.Duration) {
+	buf := getPacketBufferWithSize(len(raw))
+	buf.Data = append(buf.Data, raw...)
+	buf.dscp = dscp
+	s.delayedPackets = append(s.delayedPackets, delayedPacket{time: time.Now().Add(delay), sent: buf})
+}
+
+// injectReceiveFaults applies the faults to a received packet (which might be part of a coalesced packet).
+// It returns false if the packet was dropped or delayed, and must not be processed now.
+func (s *session) injectReceiveFaults(p *receivedPacket, hdr *wire.Header) bool {
+	fault := s.config.faultInjector.ReceivedPacket(faultInjectionPacket{
+		Type:         logging.PacketTypeFromHeader(hdr),
+		PacketNumber: protocol.InvalidPacketNumber,
+		Size:         p.Size(),
+	})
+	if fault.Drop {
+		s.logger.Debugf("fault injection: dropping received %s packet (%d bytes)", hdr.PacketType(), p.Size())
+		return false
+	}
+	if fault.Corrupt {
+		s.logger.Debugf("fault injection: corrupting received %s packet (%d bytes)", hdr.PacketType(), p.Size())
+		// The last byte is part of the AEAD tag.
+		p.data[len(p.data)-1] ^= 0xff
+	}
+	if fault.Duplicate {
+		s.logger.Debugf("fault injection: duplicating received %s packet (%d bytes)", hdr.PacketType(), p.Size())
+		s.receiveDelayed(p, fault.Delay)
+	}
+	if fault.Delay > 0 {
+		s.logger.Debugf("fault injection: delaying received %s packet (%d bytes) by %s", hdr.PacketType(), p.Size(), fault.Delay)
+		s.receiveDelayed(p, fault.Delay)
+		return false
+	}
+	return true
+}
+
+// receiveDelayed queues a copy of a received packet, which is processed by the run loop after the delay.
+func (s *session) receiveDelayed(p *receivedPacket, delay time.Duration) {
+	c := p.Clone()
+	c.buffer = getPacketBufferWithSize(len(p.data))
+	c.buffer.Data = append(c.buffer.Data, p.data...)
+	c.data = c.buffer.Data
+	s.delayedPackets = append(s.delayedPackets, delayedPacket{time: time.Now().Add(delay), received: c})
+}
+
+// nextDelayedPacketTime returns the time when the next delayed packet is due.
+// Sent packets are not considered while the send queue is full.
+func (s *session) nextDelayedPacketTime() time.Time {
+	var t time.Time
+	if len(s.delayedPackets) == 0 {
+		return t
+	}
+	wouldBlock := s.sendQueue.WouldBlock()
+	for _, p := range s.delayedPackets {
+		if p.sent != nil && wouldBlock {
+			continue
+		}
+		if t.IsZero() || p.time.Before(t) {
+			t = p.time
+		}
+	}
+	return t
+}
+
+// handleDelayedPackets sends and processes the delayed packets that are due.
+// Sent packets remain queued while the send queue is full.
+func (s *session) handleDelayedPackets(now time.Time) {
+	if len(s.delayedPackets) == 0 {
+		return
+	}
+	remaining := s.delayedPackets[:0]
+	for _, p := range s.delayedPackets {
+		if p.time.After(now) {
+			remaining = append(remaining, p)
+			continue
+		}
+		if p.sent != nil {
+			if s.sendQueue.WouldBlock() {
+				remaining = append(remaining, p)
+				continue
+			}
+			s.sendQueue.Send(p.sent)
+			continue
+		}
+		p.received.rcvTime = now
+		s.handlePacketImpl(p.received)
+	}
+	for i := len(remaining); i < len(s.delayedPackets); i++ {
+		s.delayedPackets[i] = delayedPacket{}
+	}
+	s.delayedPackets = remaining
+}
+
+// discardDelayedPackets releases the buffers of the delayed packets when the session is closed.
+func (s *session) discardDelayedPackets() {
+	for _, p := range s.delayedPackets {
+		if p.sent != nil {
+			p.sent.Release()
+		} else {
+			p.received.buffer.Release()
+		}
+	}
+	s.delayedPackets = nil
+}
@@ -0,0 +1,41 @@
+package quic
+
+import "github.com/lucas-clemente/quic-go/internal/flowcontrol"
+
+// FlowControlState is a snapshot of the flow control state of a stream or of the connection.
+type FlowControlState struct {
+	// BytesSent is the number of bytes sent.
+	BytesSent uint64
+	// SendLimit is the flow control limit granted by the peer.
+	SendLimit uint64
+	// BlockedCount is the number of times that sending was blocked by this flow control limit,
+	// i.e. the number of STREAM_DATA_BLOCKED (or DATA_BLOCKED, for the connection) conditions.
+	BlockedCount uint64
+	// ConnectionBlockedCount is the number of times that a stream had data to send,
+	// but was blocked by connection-level flow control. It is always 0 for the connection.
+	// Together with BlockedCount, it allows distinguishing flow-control-limited from congestion-limited transfers.
+	ConnectionBlockedCount uint64
+	// BytesReceived is the highest offset received from the peer.
+	BytesReceived uint64
+	// BytesRead is the number of bytes read by the application.
+	BytesRead uint64
+	// ReceiveLimit is the flow control limit granted to the peer.
+	ReceiveLimit uint64
+}
+
+// SendBlocked says if sending is blocked by flow control,
+// i.e. if the limit granted by the peer has been reached.
+func (s FlowControlState) SendBlocked() bool {
+	return s.BytesSent >= s.SendLimit
+}
+
+func newFlowControlState(s flowcontrol.WindowState) FlowControlState {
+	return FlowControlState{
+		BytesSent:     uint64(s.BytesSent),
+		SendLimit:     uint64(s.SendWindow),
+		BlockedCount:  s.BlockedCount,
+		BytesReceived: uint64(s.HighestReceived),
+		BytesRead:     uint64(s.BytesRead),
+		ReceiveLimit:  uint64(s.ReceiveWindow),
+	}
+}
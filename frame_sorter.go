@@ -2,6 +2,7 @@ package quic
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/utils"
@@ -16,17 +17,40 @@ type frameSorter struct {
 	queue   map[protocol.ByteCount]frameSorterEntry
 	readPos protocol.ByteCount
 	gaps    *utils.ByteIntervalList
+
+	memory     protocol.ByteCount // memory held by the queued frames, see frameSorterEntryOverhead
+	connMemory *reassemblyMemory  // nil if the reassembly memory is not limited
 }
 
 var errDuplicateStreamData = errors.New("duplicate stream data")
 
-func newFrameSorter() *frameSorter {
-	s := frameSorter{
+// Frame sorters are reused when streams are completed,
+// since connections that use a lot of short-lived streams would otherwise allocate a new map and gap list for every stream.
+var frameSorterPool = sync.Pool{New: func() interface{} {
+	return &frameSorter{
 		gaps:  utils.NewByteIntervalList(),
 		queue: make(map[protocol.ByteCount]frameSorterEntry),
 	}
+}}
+
+func newFrameSorter() *frameSorter {
+	s := frameSorterPool.Get().(*frameSorter)
 	s.gaps.PushFront(utils.ByteInterval{Start: 0, End: protocol.MaxByteCount})
-	return &s
+	return s
+}
+
+// Release returns the frame sorter to the pool. It must not be used afterwards.
+// A frame sorter that still holds frames is not reused.
+func (s *frameSorter) Release() {
+	s.connMemory.Add(-s.memory)
+	s.connMemory = nil
+	if len(s.queue) > 0 {
+		return
+	}
+	s.memory = 0
+	s.readPos = 0
+	s.gaps.Init()
+	frameSorterPool.Put(s)
 }
 
 func (s *frameSorter) Push(data []byte, offset protocol.ByteCount, doneCb func()) error {
@@ -79,7 +103,7 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
 		if end-pos > oldEntryLen || (hasReplacedAtLeastOne && end-pos == oldEntryLen) {
 			// The existing frame is shorter than the new frame. Replace it.
-			delete(s.queue, pos)
+			s.delete(pos, oldEntry)
 			pos += oldEntryLen
 			hasReplacedAtLeastOne = true
 			if oldEntry.DoneCb != nil {
@@ -161,9 +185,25 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 	}
 
 	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb}
+	s.trackMemory(entryMemory(data))
 	return nil
 }
 
+func entryMemory(data []byte) protocol.ByteCount {
+	return protocol.ByteCount(cap(data)) + frameSorterEntryOverhead
+}
+
+func (s *frameSorter) trackMemory(n protocol.ByteCount) {
+	s.memory += n
+	s.connMemory.Add(n)
+}
+
+// delete removes the frame at pos from the queue. It doesn't call the DoneCb.
+func (s *frameSorter) delete(pos protocol.ByteCount, entry frameSorterEntry) {
+	delete(s.queue, pos)
+	s.trackMemory(-entryMemory(entry.Data))
+}
+
 func (s *frameSorter) findStartGap(offset protocol.ByteCount) (*utils.ByteIntervalElement, bool) {
 	for gap := s.gaps.Front(); gap != nil; gap = gap.Next() {
 		if offset >= gap.Value.Start && offset <= gap.Value.End {
@@ -196,7 +236,7 @@ func (s *frameSorter) deleteConsecutive(pos protocol.ByteCount) {
 			break
 		}
 		oldEntryLen := protocol.ByteCount(len(oldEntry.Data))
-		delete(s.queue, pos)
+		s.delete(pos, oldEntry)
 		if oldEntry.DoneCb != nil {
 			oldEntry.DoneCb()
 		}
@@ -209,7 +249,7 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	if !ok {
 		return s.readPos, nil, nil
 	}
-	delete(s.queue, s.readPos)
+	s.delete(s.readPos, entry)
 	offset := s.readPos
 	s.readPos += protocol.ByteCount(len(entry.Data))
 	if s.gaps.Front().Value.End <= s.readPos {
@@ -218,7 +258,49 @@ func (s *frameSorter) Pop() (protocol.ByteCount, []byte, func()) {
 	return offset, entry.Data, entry.DoneCb
 }
 
+// Peek appends up to n bytes of the data queued at the read position to b, without popping any frames.
+// Only contiguous data is returned.
+func (s *frameSorter) Peek(b []byte, n int) []byte {
+	pos := s.readPos
+	for len(b) < n {
+		entry, ok := s.queue[pos]
+		if !ok {
+			break
+		}
+		l := utils.Min(len(entry.Data), n-len(b))
+		b = append(b, entry.Data[:l]...)
+		pos += protocol.ByteCount(len(entry.Data))
+	}
+	return b
+}
+
 // HasMoreData says if there is any more data queued at *any* offset.
 func (s *frameSorter) HasMoreData() bool {
 	return len(s.queue) > 0
 }
+
+// Memory returns the memory held by the queued frames.
+func (s *frameSorter) Memory() protocol.ByteCount {
+	return s.memory
+}
+
+// DiscardQueued drops all queued frames, without changing the gaps.
+// It is used when reading was canceled, and the queued data will never be read.
+func (s *frameSorter) DiscardQueued() {
+	for pos, entry := range s.queue {
+		s.delete(pos, entry)
+		if entry.DoneCb != nil {
+			entry.DoneCb()
+		}
+	}
+}
+
+// NumEntries returns the number of frames that are currently queued.
+func (s *frameSorter) NumEntries() int {
+	return len(s.queue)
+}
+
+// ContiguousOffset returns the offset up to which all data has been received.
+func (s *frameSorter) ContiguousOffset() protocol.ByteCount {
+	return s.gaps.Front().Value.Start
+}
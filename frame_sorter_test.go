@@ -84,6 +84,23 @@ var _ = Describe("frame sorter", func() {
 		Expect(doneCb).To(BeNil())
 	})
 
+	It("resets released frame sorters", func() {
+		Expect(s.Push([]byte("foobar"), 0, nil)).To(Succeed())
+		_, data, _ := s.Pop()
+		Expect(data).To(Equal([]byte("foobar")))
+		s.Release()
+		// The pool might or might not return the frame sorter that was just released.
+		s = newFrameSorter()
+		Expect(s.readPos).To(BeZero())
+		Expect(s.queue).To(BeEmpty())
+		Expect(s.gaps.Len()).To(Equal(1))
+		Expect(s.gaps.Front().Value).To(Equal(utils.ByteInterval{Start: 0, End: protocol.MaxByteCount}))
+		Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+		offset, data, _ := s.Pop()
+		Expect(offset).To(BeZero())
+		Expect(data).To(Equal([]byte("foo")))
+	})
+
 	It("inserts and pops two consecutive frame", func() {
 		cb1, t1 := getCallback()
 		cb2, t2 := getCallback()
@@ -123,6 +140,73 @@ var _ = Describe("frame sorter", func() {
 		Expect(s.HasMoreData()).To(BeFalse())
 	})
 
+	It("says up to which offset data has been received", func() {
+		Expect(s.ContiguousOffset()).To(BeZero())
+		Expect(s.Push([]byte("bar"), 3, nil)).To(Succeed())
+		Expect(s.ContiguousOffset()).To(BeZero())
+		Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+		Expect(s.ContiguousOffset()).To(Equal(protocol.ByteCount(6)))
+		s.Pop()
+		Expect(s.ContiguousOffset()).To(Equal(protocol.ByteCount(6)))
+	})
+
+	It("peeks at contiguous data without popping it", func() {
+		Expect(s.Peek(nil, 10)).To(BeEmpty())
+		Expect(s.Push([]byte("foo"), 0, nil)).To(Succeed())
+		Expect(s.Push([]byte("bar"), 3, nil)).To(Succeed())
+		Expect(s.Push([]byte("baz"), 10, nil)).To(Succeed())
+		Expect(s.Peek(nil, 4)).To(Equal([]byte("foob")))
+		Expect(s.Peek([]byte("x"), 4)).To(Equal([]byte("xfoo")))
+		Expect(s.Peek(nil, 10)).To(Equal([]byte("foobar")))
+		_, data, _ := s.Pop()
+		Expect(data).To(Equal([]byte("foo")))
+		Expect(s.Peek(nil, 10)).To(Equal([]byte("bar")))
+	})
+
+	Context("memory accounting", func() {
+		It("accounts the capacity of the buffers of queued frames", func() {
+			Expect(s.Push(make([]byte, 10, 1000), 0, nil)).To(Succeed())
+			Expect(s.Push(make([]byte, 10, 500), 20, nil)).To(Succeed())
+			Expect(s.Memory()).To(Equal(1500 + 2*frameSorterEntryOverhead))
+			_, data, _ := s.Pop()
+			Expect(data).To(HaveLen(10))
+			Expect(s.Memory()).To(Equal(500 + frameSorterEntryOverhead))
+		})
+
+		It("accounts replaced frames", func() {
+			cb, t := getCallback()
+			Expect(s.Push(make([]byte, 5, 1000), 10, cb)).To(Succeed())
+			Expect(s.Push(make([]byte, 10, 500), 10, nil)).To(Succeed())
+			checkCallbackCalled(t)
+			Expect(s.Memory()).To(Equal(500 + frameSorterEntryOverhead))
+		})
+
+		It("updates the memory of the connection", func() {
+			m := newReassemblyMemory(0, 1<<20, 0)
+			s.connMemory = m
+			Expect(s.Push(make([]byte, 10, 1000), 0, nil)).To(Succeed())
+			Expect(s.Push(make([]byte, 10, 1000), 20, nil)).To(Succeed())
+			Expect(m.Used()).To(Equal(2000 + 2*frameSorterEntryOverhead))
+			s.Pop()
+			Expect(m.Used()).To(Equal(1000 + frameSorterEntryOverhead))
+			s.Release()
+			Expect(m.Used()).To(BeZero())
+		})
+
+		It("discards queued frames", func() {
+			cb1, t1 := getCallback()
+			cb2, t2 := getCallback()
+			Expect(s.Push([]byte("foo"), 0, cb1)).To(Succeed())
+			Expect(s.Push([]byte("bar"), 10, cb2)).To(Succeed())
+			s.DiscardQueued()
+			checkCallbackCalled(t1)
+			checkCallbackCalled(t2)
+			Expect(s.HasMoreData()).To(BeFalse())
+			Expect(s.Memory()).To(BeZero())
+			Expect(s.ContiguousOffset()).To(Equal(protocol.ByteCount(3)))
+		})
+	})
+
 	Context("Gap handling", func() {
 		var dataCounter uint8
 
@@ -18,6 +18,9 @@ type framer interface {
 
 	AddActiveStream(protocol.StreamID)
 	AppendStreamFrames([]ackhandler.Frame, protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount)
+	PendingStreamData(limit protocol.ByteCount) protocol.ByteCount
+	SetStreamPriority(protocol.StreamID, StreamPriority)
+	RemoveStream(protocol.StreamID)
 
 	Handle0RTTRejection() error
 }
@@ -30,6 +33,9 @@ type framerI struct {
 
 	activeStreams map[protocol.StreamID]struct{}
 	streamQueue   []protocol.StreamID
+	scheduler     *streamScheduler
+	// called for every STREAM frame popped from a stream, if non-nil
+	onStreamFrame func(*wire.StreamFrame)
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
@@ -39,11 +45,15 @@ var _ framer = &framerI{}
 
 func newFramer(
 	streamGetter streamGetter,
+	scheduler StreamScheduler,
 	v protocol.VersionNumber,
+	onStreamFrame func(*wire.StreamFrame),
 ) framer {
 	return &framerI{
 		streamGetter:  streamGetter,
 		activeStreams: make(map[protocol.StreamID]struct{}),
+		scheduler:     newStreamScheduler(scheduler),
+		onStreamFrame: onStreamFrame,
 		version:       v,
 	}
 }
@@ -96,6 +106,10 @@ func (f *framerI) AddActiveStream(id protocol.StreamID) {
 func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.ByteCount) ([]ackhandler.Frame, protocol.ByteCount) {
 	var length protocol.ByteCount
 	var lastFrame *ackhandler.Frame
+	startLen := len(frames)
+	// Streams that have more data, but didn't return a STREAM frame (e.g. because they're blocked by flow control).
+	// They are not considered again in this call, so that they don't starve the other streams.
+	var skipped []protocol.StreamID
 	f.mutex.Lock()
 	// pop STREAM frames, until less than MinStreamFrameSize bytes are left in the packet
 	numActiveStreams := len(f.streamQueue)
@@ -103,14 +117,20 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 		if protocol.MinStreamFrameSize+length > maxLen {
 			break
 		}
-		id := f.streamQueue[0]
-		f.streamQueue = f.streamQueue[1:]
+		next := f.scheduler.Next(f.streamQueue)
+		id := f.streamQueue[next]
+		if next == 0 {
+			f.streamQueue = f.streamQueue[1:]
+		} else {
+			f.streamQueue = append(f.streamQueue[:next], f.streamQueue[next+1:]...)
+		}
 		// This should never return an error. Better check it anyway.
 		// The stream will only be in the streamQueue, if it enqueued itself there.
 		str, err := f.streamGetter.GetOrOpenSendStream(id)
 		// The stream can be nil if it completed after it said it had data.
 		if str == nil || err != nil {
 			delete(f.activeStreams, id)
+			f.scheduler.Deactivated(id)
 			continue
 		}
 		remainingLen := maxLen - length
@@ -119,10 +139,13 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 		// the STREAM frame (which will always have the DataLen set).
 		remainingLen += quicvarint.Len(uint64(remainingLen))
 		frame, hasMoreData := str.popStreamFrame(remainingLen)
-		if hasMoreData { // put the stream back in the queue (at the end)
+		if hasMoreData && frame == nil {
+			skipped = append(skipped, id)
+		} else if hasMoreData { // put the stream back in the queue (at the end)
 			f.streamQueue = append(f.streamQueue, id)
 		} else { // no more data to send. Stream is not active any more
 			delete(f.activeStreams, id)
+			f.scheduler.Deactivated(id)
 		}
 		// The frame can be nil
 		// * if the receiveStream was canceled after it said it had data
@@ -131,10 +154,19 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 			continue
 		}
 		frames = append(frames, *frame)
-		length += frame.Length(f.version)
+		frameLen := frame.Length(f.version)
+		length += frameLen
+		f.scheduler.Sent(id, frameLen)
 		lastFrame = frame
 	}
+	f.streamQueue = append(f.streamQueue, skipped...)
 	f.mutex.Unlock()
+	// Call the callback after releasing the mutex, since it might call into the stream.
+	if f.onStreamFrame != nil {
+		for _, frame := range frames[startLen:] {
+			f.onStreamFrame(frame.Frame.(*wire.StreamFrame))
+		}
+	}
 	if lastFrame != nil {
 		lastFrameLen := lastFrame.Length(f.version)
 		// account for the smaller size of the last STREAM frame
@@ -144,6 +176,48 @@ func (f *framerI) AppendStreamFrames(frames []ackhandler.Frame, maxLen protocol.
 	return frames, length
 }
 
+// PendingStreamData returns the number of bytes of stream data waiting to be sent, up to limit.
+// If control frames are queued, or a stream has data that can't be delayed
+// (retransmissions, or the end of the stream), it returns limit.
+func (f *framerI) PendingStreamData(limit protocol.ByteCount) protocol.ByteCount {
+	f.controlFrameMutex.Lock()
+	hasControlFrames := len(f.controlFrames) > 0
+	f.controlFrameMutex.Unlock()
+	if hasControlFrames {
+		return limit
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var pending protocol.ByteCount
+	for _, id := range f.streamQueue {
+		str, err := f.streamGetter.GetOrOpenSendStream(id)
+		if str == nil || err != nil {
+			continue
+		}
+		n := str.pendingData()
+		if n >= limit-pending {
+			return limit
+		}
+		pending += n
+	}
+	return pending
+}
+
+// SetStreamPriority sets the priority that the stream scheduler uses for a stream.
+func (f *framerI) SetStreamPriority(id protocol.StreamID, prio StreamPriority) {
+	f.mutex.Lock()
+	f.scheduler.SetPriority(id, prio)
+	f.mutex.Unlock()
+}
+
+// RemoveStream removes the scheduling state kept for a stream that was completed.
+func (f *framerI) RemoveStream(id protocol.StreamID) {
+	f.mutex.Lock()
+	f.scheduler.Remove(id)
+	f.mutex.Unlock()
+}
+
 func (f *framerI) Handle0RTTRejection() error {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
@@ -152,6 +226,7 @@ func (f *framerI) Handle0RTTRejection() error {
 	f.streamQueue = f.streamQueue[:0]
 	for id := range f.activeStreams {
 		delete(f.activeStreams, id)
+		f.scheduler.Deactivated(id)
 	}
 	var j int
 	for i, frame := range f.controlFrames {
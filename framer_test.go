@@ -5,6 +5,7 @@ import (
 	"math/rand"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
@@ -33,7 +34,7 @@ var _ = Describe("Framer", func() {
 		stream1.EXPECT().StreamID().Return(protocol.StreamID(5)).AnyTimes()
 		stream2 = NewMockSendStreamI(mockCtrl)
 		stream2.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
-		framer = newFramer(streamGetter, version)
+		framer = newFramer(streamGetter, StreamSchedulerStrict, version, nil)
 	})
 
 	Context("handling control frames", func() {
@@ -108,6 +109,32 @@ var _ = Describe("Framer", func() {
 		})
 	})
 
+	Context("pending stream data", func() {
+		It("sums up the data pending on all active streams", func() {
+			Expect(framer.PendingStreamData(1000)).To(BeZero())
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			stream1.EXPECT().pendingData().Return(protocol.ByteCount(100))
+			stream2.EXPECT().pendingData().Return(protocol.ByteCount(200))
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			Expect(framer.PendingStreamData(1000)).To(Equal(protocol.ByteCount(300)))
+		})
+
+		It("returns the limit if more data is pending", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			stream1.EXPECT().pendingData().Return(protocol.MaxByteCount)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			Expect(framer.PendingStreamData(1000)).To(Equal(protocol.ByteCount(1000)))
+		})
+
+		It("returns the limit if control frames are queued", func() {
+			framer.QueueControlFrame(&wire.MaxDataFrame{MaximumData: 0x42})
+			Expect(framer.PendingStreamData(1000)).To(Equal(protocol.ByteCount(1000)))
+		})
+	})
+
 	Context("popping STREAM frames", func() {
 		It("returns nil when popping an empty framer", func() {
 			Expect(framer.AppendStreamFrames(nil, 1000)).To(BeEmpty())
@@ -167,6 +194,25 @@ var _ = Describe("Framer", func() {
 			Expect(length).To(Equal(f.Length(version)))
 		})
 
+		It("reports the STREAM frames it popped", func() {
+			var reported []*wire.StreamFrame
+			framer = newFramer(streamGetter, StreamSchedulerStrict, version, func(f *wire.StreamFrame) {
+				reported = append(reported, f)
+			})
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f1 := &wire.StreamFrame{StreamID: id1, Data: []byte("foo"), DataLenPresent: true}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("bar"), Fin: true, DataLenPresent: true}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			mdf := &wire.MaxDataFrame{MaximumData: 1337}
+			fs, _ := framer.AppendStreamFrames([]ackhandler.Frame{{Frame: mdf}}, 1000)
+			Expect(fs).To(HaveLen(3))
+			Expect(reported).To(Equal([]*wire.StreamFrame{f1, f2}))
+		})
+
 		It("skips a stream that was reported active, but was completed shortly after", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(nil, nil)
 			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
@@ -275,6 +321,94 @@ var _ = Describe("Framer", func() {
 			Expect(frames[1].Frame).To(Equal(f1))
 		})
 
+		It("returns frames of more urgent streams first", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f1 := &wire.StreamFrame{Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{Data: []byte("foobaz")}
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, false)
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id2, StreamPriority{Urgency: 0})
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(frames[1].Frame).To(Equal(f1))
+		})
+
+		It("doesn't let a stream that is blocked by flow control starve the other streams", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(2)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f1 := &wire.StreamFrame{StreamID: id2, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("foobaz")}
+			// stream 1 is more urgent, but blocked by flow control
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(nil, true).Times(2)
+			gomock.InOrder(
+				stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true),
+				stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, true),
+			)
+			framer.SetStreamPriority(id1, StreamPriority{Urgency: 0})
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f1))
+			frames, _ = framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(framer.HasData()).To(BeTrue())
+		})
+
+		It("doesn't let a stream that is blocked by flow control starve the other streams, when using weighted scheduling", func() {
+			framer = newFramer(streamGetter, StreamSchedulerWeighted, version, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(2)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f1 := &wire.StreamFrame{StreamID: id2, Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("foobaz")}
+			// stream 1 never sends any data, so its finish time stays the lowest
+			stream1.EXPECT().popStreamFrame(gomock.Any()).Return(nil, true).Times(2)
+			gomock.InOrder(
+				stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f1}, true),
+				stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, true),
+			)
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			frames, _ := framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f1))
+			frames, _ = framer.AppendStreamFrames(nil, 1000)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f2))
+		})
+
+		It("doesn't ask a stream that deferred a message for data again when packing the same packet", func() {
+			const id3 = protocol.StreamID(12)
+			mockSender := NewMockStreamSender(mockCtrl)
+			mockSender.EXPECT().onHasStreamData(id1).Times(2)
+			mockFC := mocks.NewMockStreamFlowController(mockCtrl)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+			str := newSendStream(id1, mockSender, mockFC, version)
+			_, err := str.WriteMessage(bytes.Repeat([]byte{'a'}, 10))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.WriteMessage(bytes.Repeat([]byte{'b'}, 500))
+			Expect(err).ToNot(HaveOccurred())
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(str, nil).Times(2)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil)
+			f2 := &wire.StreamFrame{StreamID: id2, Data: []byte("foobar")}
+			stream2.EXPECT().popStreamFrame(gomock.Any()).Return(&ackhandler.Frame{Frame: f2}, false)
+			framer.SetStreamPriority(id1, StreamPriority{Urgency: 0})
+			framer.AddActiveStream(id1)
+			framer.AddActiveStream(id2)
+			framer.AddActiveStream(id3)
+			// the second message doesn't fit into the rest of the packet, and must not be split
+			frames, _ := framer.AppendStreamFrames(nil, 400)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame.(*wire.StreamFrame).Data).To(Equal(bytes.Repeat([]byte{'a'}, 10)))
+			Expect(frames[1].Frame).To(Equal(f2))
+		})
+
 		It("only asks a stream for data once, even if it was reported active multiple times", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
 			f := &wire.StreamFrame{Data: []byte("foobar")}
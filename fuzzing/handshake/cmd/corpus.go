@@ -59,12 +59,14 @@ func newRunner(client, server *handshake.CryptoSetup) *runner {
 
 func (r *runner) OnReceivedParams(*wire.TransportParameters) {}
 func (r *runner) OnHandshakeComplete()                       {}
+func (r *runner) OnSessionTicketStored()                     {}
 func (r *runner) OnError(err error) {
 	log.Fatal("runner error:", err)
 	(*r.client).Close()
 	(*r.server).Close()
 }
-func (r *runner) DropKeys(protocol.EncryptionLevel) {}
+func (r *runner) OnKeysAvailable(protocol.EncryptionLevel, bool) {}
+func (r *runner) DropKeys(protocol.EncryptionLevel)              {}
 
 const alpn = "fuzz"
 
@@ -175,6 +175,7 @@ func newRunner(client, server *handshake.CryptoSetup) *runner {
 
 func (r *runner) OnReceivedParams(*wire.TransportParameters) {}
 func (r *runner) OnHandshakeComplete()                       {}
+func (r *runner) OnSessionTicketStored()                     {}
 func (r *runner) OnError(err error) {
 	r.Lock()
 	defer r.Unlock()
@@ -191,7 +192,8 @@ func (r *runner) Errored() bool {
 	defer r.Unlock()
 	return r.errored
 }
-func (r *runner) DropKeys(protocol.EncryptionLevel) {}
+func (r *runner) OnKeysAvailable(protocol.EncryptionLevel, bool) {}
+func (r *runner) DropKeys(protocol.EncryptionLevel)              {}
 
 const (
 	alpn      = "fuzzing"
@@ -0,0 +1,34 @@
+package quic
+
+import "net"
+
+// NewGenericPacketConn wraps a net.PacketConn, such that it is used without any UDP-specific assumptions.
+// This allows running QUIC over any transport that preserves datagram boundaries,
+// e.g. Unix domain sockets (using the "unixgram" network), in-memory pipes for in-process testing, or vsock.
+// Packets may be dropped, duplicated or reordered by the transport.
+//
+// When dialing or listening on a generic PacketConn, no OOB data is read or written,
+// even if the PacketConn satisfies the OOBCapablePacketConn interface.
+// This means that ECN and packet info are not used.
+// The size of the receive buffer is not changed.
+// Path MTU Discovery is disabled, as if Config.DisablePathMTUDiscovery was set:
+// Packets are at most 1252 (IPv4) / 1232 (IPv6) bytes in size for UDP addresses, and 1200 bytes for all other addresses.
+// Transports that support larger datagrams can use larger packets by implementing a MaxDatagramSize() int method
+// on their net.Addr (of the peer). Packets are never larger than 1452 bytes.
+//
+// Packets are sent to the address they were received from.
+// When using Unix domain sockets, the client's socket therefore needs to be bound to an address.
+func NewGenericPacketConn(c net.PacketConn) net.PacketConn {
+	return &genericPacketConn{PacketConn: c}
+}
+
+// genericPacketConn only exposes the methods of the net.PacketConn,
+// hiding the methods used for OOB data and for setting the receive buffer size.
+type genericPacketConn struct {
+	net.PacketConn
+}
+
+func isGenericPacketConn(c net.PacketConn) bool {
+	_, ok := c.(*genericPacketConn)
+	return ok
+}
@@ -0,0 +1,113 @@
+package quic
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generic PacketConn", func() {
+	// After the conn is closed, the packet handler map removes it from the multiplexer.
+	// Wait for this to happen, so that it doesn't interfere with the next test.
+	waitForConnRemoval := func(c net.PacketConn) {
+		connIndex := c.LocalAddr().Network() + " " + c.LocalAddr().String()
+		m := getMultiplexer().(*connMultiplexer)
+		Eventually(func() bool {
+			m.mutex.Lock()
+			defer m.mutex.Unlock()
+			_, ok := m.conns[connIndex]
+			return ok
+		}).Should(BeFalse())
+	}
+
+	It("doesn't use OOB data", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer udpConn.Close()
+		conn := NewGenericPacketConn(udpConn)
+		Expect(conn).ToNot(BeAssignableToTypeOf(&net.UDPConn{}))
+		_, ok := conn.(OOBCapablePacketConn)
+		Expect(ok).To(BeFalse())
+		_, ok = conn.(interface{ SetReadBuffer(int) error })
+		Expect(ok).To(BeFalse())
+		c, err := wrapConn(conn)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(BeAssignableToTypeOf(&basicConn{}))
+	})
+
+	It("disables Path MTU Discovery", func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		ln, err := Listen(NewGenericPacketConn(udpConn), testdata.GetTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ln.(*baseServer).config.DisablePathMTUDiscovery).To(BeTrue())
+		Expect(ln.Close()).To(Succeed())
+		Expect(udpConn.Close()).To(Succeed())
+		waitForConnRemoval(udpConn)
+	})
+
+	It("runs over Unix domain sockets", func() {
+		if runtime.GOOS == "windows" {
+			Skip("unixgram sockets are not supported on Windows")
+		}
+		dir, err := ioutil.TempDir("", "quic-go")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		serverConn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "server"), Net: "unixgram"})
+		Expect(err).ToNot(HaveOccurred())
+		defer serverConn.Close()
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"unix"}
+		ln, err := Listen(NewGenericPacketConn(serverConn), tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			sess.CloseWithError(0, "")
+		}()
+
+		clientConn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: filepath.Join(dir, "client"), Net: "unixgram"})
+		Expect(err).ToNot(HaveOccurred())
+		defer clientConn.Close()
+		sess, err := Dial(
+			NewGenericPacketConn(clientConn),
+			serverConn.LocalAddr(),
+			"localhost",
+			&tls.Config{RootCAs: testdata.GetRootCA(), NextProtos: []string{"unix"}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Eventually(sess.Context().Done()).Should(BeClosed())
+		Expect(ln.Close()).To(Succeed())
+		Expect(serverConn.Close()).To(Succeed())
+		Expect(clientConn.Close()).To(Succeed())
+		waitForConnRemoval(serverConn)
+		waitForConnRemoval(clientConn)
+	})
+})
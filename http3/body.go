@@ -1,6 +1,7 @@
 package http3
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -17,7 +18,8 @@ type body struct {
 	reqDone       chan<- struct{}
 	reqDoneClosed bool
 
-	onFrameError func()
+	onFrameError   func()
+	onUnknownFrame unknownFrameHandlerFunc
 
 	bytesRemainingInFrame uint64
 }
@@ -51,7 +53,7 @@ func (r *body) readImpl(b []byte) (int, error) {
 	if r.bytesRemainingInFrame == 0 {
 	parseLoop:
 		for {
-			frame, err := parseNextFrame(r.str)
+			frame, err := parseNextFrameWithHandler(r.str, r.onUnknownFrame)
 			if err != nil {
 				return 0, err
 			}
@@ -96,3 +98,45 @@ func (r *body) Close() error {
 	r.str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
 	return nil
 }
+
+// responseBody is the body of a http.Response.
+// Extension frames are written on the request stream.
+type responseBody struct {
+	*body
+	str *requestStream
+}
+
+var (
+	_ io.ReadCloser = &responseBody{}
+	_ FrameWriter   = &responseBody{}
+)
+
+func (r *responseBody) WriteFrame(ft FrameType, payload []byte) error {
+	return r.str.WriteFrame(ft, payload)
+}
+
+// datagramBody is the body of the http.Response to a CONNECT request, if datagrams are enabled.
+// Datagrams can be used until the body is closed.
+type datagramBody struct {
+	*responseBody
+	datagrams *streamDatagrams
+}
+
+var (
+	_ io.ReadCloser = &datagramBody{}
+	_ Datagrammer   = &datagramBody{}
+	_ FrameWriter   = &datagramBody{}
+)
+
+func (r *datagramBody) SendDatagram(b []byte) error {
+	return r.datagrams.SendDatagram(b)
+}
+
+func (r *datagramBody) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	return r.datagrams.ReceiveDatagram(ctx)
+}
+
+func (r *datagramBody) Close() error {
+	r.datagrams.close()
+	return r.body.Close()
+}
@@ -2,8 +2,10 @@ package http3
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go"
@@ -155,6 +157,34 @@ var _ = Describe("Body", func() {
 				Expect(errorCbCalled).To(BeTrue())
 			})
 
+			It("passes unknown frames to the handler", func() {
+				Expect(writeExtensionFrame(buf, 0x1337, []byte("metadata"))).To(Succeed())
+				buf.Write(getDataFrame([]byte("foobar")))
+				var frameType FrameType
+				var payload []byte
+				rb.onUnknownFrame = func(ft FrameType, r io.Reader) error {
+					frameType = ft
+					var err error
+					payload, err = ioutil.ReadAll(r)
+					return err
+				}
+				b := make([]byte, 6)
+				n, err := rb.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foobar")))
+				Expect(frameType).To(Equal(FrameType(0x1337)))
+				Expect(payload).To(Equal([]byte("metadata")))
+			})
+
+			It("returns the error returned by the unknown frame handler", func() {
+				Expect(writeExtensionFrame(buf, 0x1337, []byte("metadata"))).To(Succeed())
+				buf.Write(getDataFrame([]byte("foobar")))
+				testErr := errors.New("test error")
+				rb.onUnknownFrame = func(FrameType, io.Reader) error { return testErr }
+				_, err := rb.Read(make([]byte, 6))
+				Expect(err).To(MatchError(testErr))
+			})
+
 			if bodyType == bodyTypeResponse {
 				It("closes the reqDone channel when Read errors", func() {
 					buf.Write([]byte("invalid"))
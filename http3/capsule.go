@@ -0,0 +1,79 @@
+package http3
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// CapsuleType is the type of a capsule, see section 3.2 of RFC 9297.
+type CapsuleType uint64
+
+// ParseCapsule parses the header of a capsule.
+// The value of the capsule can be read from the returned io.Reader.
+// It returns io.EOF if r ends before the first byte of a capsule,
+// and io.ErrUnexpectedEOF if r ends in the middle of a capsule.
+func ParseCapsule(r quicvarint.Reader) (CapsuleType, io.Reader, error) {
+	ct, l, err := parseCapsuleHeader(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return ct, &exactReader{R: io.LimitReader(r, int64(l)).(*io.LimitedReader)}, nil
+}
+
+func parseCapsuleHeader(r quicvarint.Reader) (CapsuleType, uint64, error) {
+	cbr := &countingByteReader{Reader: r}
+	ct, err := quicvarint.Read(cbr)
+	if err != nil {
+		// If r ended without a single byte being read, there's no capsule.
+		if err == io.EOF && cbr.n > 0 {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	l, err := quicvarint.Read(r)
+	if err != nil {
+		if err == io.EOF {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	return CapsuleType(ct), l, nil
+}
+
+// WriteCapsule writes a capsule.
+func WriteCapsule(w quicvarint.Writer, ct CapsuleType, value []byte) error {
+	buf := &bytes.Buffer{}
+	quicvarint.Write(buf, uint64(ct))
+	quicvarint.Write(buf, uint64(len(value)))
+	buf.Write(value)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+type countingByteReader struct {
+	quicvarint.Reader
+	n int
+}
+
+func (r *countingByteReader) ReadByte() (byte, error) {
+	b, err := r.Reader.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+// exactReader returns io.ErrUnexpectedEOF if the underlying reader ends before the limit is reached.
+type exactReader struct {
+	R *io.LimitedReader
+}
+
+func (r *exactReader) Read(b []byte) (int, error) {
+	n, err := r.R.Read(b)
+	if err == io.EOF && r.R.N > 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
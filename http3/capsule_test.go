@@ -0,0 +1,71 @@
+package http3
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Capsule", func() {
+	appendVarInt := func(b []byte, val uint64) []byte {
+		buf := &bytes.Buffer{}
+		quicvarint.Write(buf, val)
+		return append(b, buf.Bytes()...)
+	}
+
+	It("parses Capsules", func() {
+		data := appendVarInt(nil, 1337)
+		data = appendVarInt(data, 6)
+		data = append(data, []byte("foobar")...)
+		ct, r, err := ParseCapsule(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ct).To(BeEquivalentTo(1337))
+		buf := make([]byte, 3)
+		_, err = io.ReadFull(r, buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(buf)).To(Equal("foo"))
+		_, err = io.ReadFull(r, buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(buf)).To(Equal("bar"))
+		_, err = io.ReadFull(r, buf)
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("writes Capsules", func() {
+		var buf bytes.Buffer
+		Expect(WriteCapsule(&buf, 1337, []byte("foobar"))).To(Succeed())
+		ct, r, err := ParseCapsule(&buf)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ct).To(BeEquivalentTo(1337))
+		val, err := ioutil.ReadAll(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(val)).To(Equal("foobar"))
+		Expect(buf.Len()).To(BeZero())
+	})
+
+	It("returns io.EOF if there's no Capsule", func() {
+		_, _, err := ParseCapsule(bytes.NewReader(nil))
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("errors on EOF", func() {
+		data := appendVarInt(nil, 1337)
+		data = appendVarInt(data, 6)
+		data = append(data, []byte("foobar")...)
+		for i := 1; i < len(data); i++ {
+			ct, r, err := ParseCapsule(bytes.NewReader(data[:i]))
+			if err != nil {
+				Expect(err).To(Equal(io.ErrUnexpectedEOF))
+				continue
+			}
+			Expect(ct).To(BeEquivalentTo(1337))
+			_, err = ioutil.ReadAll(r)
+			Expect(err).To(Equal(io.ErrUnexpectedEOF))
+		}
+	})
+})
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"strconv"
 	"sync"
 
@@ -26,6 +28,7 @@ const MethodGet0RTT = "GET_0RTT"
 const (
 	defaultUserAgent              = "quic-go HTTP/3"
 	defaultMaxResponseHeaderBytes = 10 * 1 << 20 // 10 MB
+	max1xxResponses               = 5            // same as the limit used by net/http
 )
 
 var defaultQuicConfig = &quic.Config{
@@ -37,9 +40,14 @@ var defaultQuicConfig = &quic.Config{
 var dialAddr = quic.DialAddrEarly
 
 type roundTripperOpts struct {
-	DisableCompression bool
-	EnableDatagram     bool
-	MaxHeaderBytes     int64
+	DisableCompression     bool
+	EnableDatagram         bool
+	MaxHeaderBytes         int64
+	AdditionalSettings     map[uint64]uint64
+	OnPeerSettings         func(quic.EarlySession, map[uint64]uint64)
+	UnknownFrameHandler    UnknownFrameHandler
+	StrictHeaderValidation bool
+	OnMalformedMessage     func(error)
 }
 
 // client is a HTTP3 client doing requests
@@ -56,8 +64,12 @@ type client struct {
 
 	decoder *qpack.Decoder
 
-	hostname string
-	session  quic.EarlySession
+	hostname  string
+	session   quic.EarlySession
+	datagrams *datagramDemultiplexer // nil if datagrams are not enabled
+
+	settingsReceived chan struct{}     // closed once the server's SETTINGS frame was received
+	peerSettings     map[uint64]uint64 // the server's settings, set before settingsReceived is closed
 
 	logger utils.Logger
 }
@@ -78,6 +90,12 @@ func newClient(
 	if len(quicConfig.Versions) != 1 {
 		return nil, errors.New("can only use a single QUIC version for dialing a HTTP/3 connection")
 	}
+	if err := validateUniStreamLimit(quicConfig); err != nil {
+		return nil, err
+	}
+	if err := validateAdditionalSettings(opts.AdditionalSettings); err != nil {
+		return nil, err
+	}
 	quicConfig.MaxIncomingStreams = -1 // don't allow any bidirectional streams
 	quicConfig.EnableDatagrams = opts.EnableDatagram
 	logger := utils.DefaultLogger.WithPrefix("h3 client")
@@ -99,6 +117,8 @@ func newClient(
 		opts:          opts,
 		dialer:        dialer,
 		logger:        logger,
+
+		settingsReceived: make(chan struct{}),
 	}, nil
 }
 
@@ -112,6 +132,9 @@ func (c *client) dial() error {
 	if err != nil {
 		return err
 	}
+	if c.opts.EnableDatagram {
+		c.datagrams = newDatagramDemultiplexer(c.session, c.logger)
+	}
 
 	// send the SETTINGs frame, using 0-RTT data, if possible
 	go func() {
@@ -134,12 +157,13 @@ func (c *client) setupSession() error {
 	buf := &bytes.Buffer{}
 	quicvarint.Write(buf, streamTypeControlStream)
 	// send the SETTINGS frame
-	(&settingsFrame{Datagram: c.opts.EnableDatagram}).Write(buf)
+	(&settingsFrame{Datagram: c.opts.EnableDatagram, other: c.opts.AdditionalSettings}).Write(buf)
 	_, err = str.Write(buf.Bytes())
 	return err
 }
 
 func (c *client) handleUnidirectionalStreams() {
+	var uniStreams uniStreamTracker
 	for {
 		str, err := c.session.AcceptUniStream(context.Background())
 		if err != nil {
@@ -153,16 +177,22 @@ func (c *client) handleUnidirectionalStreams() {
 				c.logger.Debugf("reading stream type on stream %d failed: %s", str.StreamID(), err)
 				return
 			}
-			// We're only interested in the control stream here.
+			if err := uniStreams.Open(streamType); err != nil {
+				c.session.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), err.Error())
+				return
+			}
+			closeSession := func(code errorCode, reason string) {
+				c.session.CloseWithError(quic.ApplicationErrorCode(code), reason)
+			}
 			switch streamType {
 			case streamTypeControlStream:
 			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
 				// Our QPACK implementation doesn't use the dynamic table yet.
-				// TODO: check that only one stream of each type is opened.
+				handleQPACKStream(str, closeSession)
 				return
 			case streamTypePushStream:
 				// We never increased the Push ID, so we don't expect any push streams.
-				c.session.CloseWithError(quic.ApplicationErrorCode(errorIDError), "")
+				c.session.CloseWithError(quic.ApplicationErrorCode(errorIDError), "server opened a push stream, but we never sent a MAX_PUSH_ID")
 				return
 			default:
 				str.CancelRead(quic.StreamErrorCode(errorStreamCreationError))
@@ -178,19 +208,45 @@ func (c *client) handleUnidirectionalStreams() {
 				c.session.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
+			c.handleSettings(sf)
+			if c.opts.OnPeerSettings != nil {
+				c.opts.OnPeerSettings(c.session, sf.Settings())
 			}
 			// If datagram support was enabled on our side as well as on the server side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if c.opts.EnableDatagram && !c.session.ConnectionState().SupportsDatagrams {
+			if sf.Datagram && c.opts.EnableDatagram && !c.session.ConnectionState().SupportsDatagrams {
 				c.session.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+				return
 			}
+			handleControlStreamFrames(str, closeSession)
 		}()
 	}
 }
 
+// handleSettings stores the server's settings.
+// It is only called once, since the server can only open a single control stream.
+func (c *client) handleSettings(sf *settingsFrame) {
+	c.peerSettings = sf.Settings()
+	close(c.settingsReceived)
+}
+
+// waitForExtendedConnect waits for the server's SETTINGS frame,
+// and returns an error if the server didn't enable extended CONNECT, see section 3 of RFC 9220.
+func (c *client) waitForExtendedConnect(ctx context.Context) error {
+	select {
+	case <-c.settingsReceived:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.session.Context().Done():
+		return errors.New("http3: session closed before receiving the server's SETTINGS")
+	}
+	if c.peerSettings[settingExtendedConnect] != 1 {
+		return errors.New("http3: server didn't enable extended CONNECT")
+	}
+	return nil
+}
+
 func (c *client) Close() error {
 	if c.session == nil {
 		return nil
@@ -231,6 +287,12 @@ func (c *client) RoundTrip(req *http.Request) (*http.Response, error) {
 		}
 	}
 
+	if isExtendedConnect(req) {
+		if err := c.waitForExtendedConnect(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
 	str, err := c.session.OpenStreamSync(req.Context())
 	if err != nil {
 		return nil, err
@@ -275,11 +337,93 @@ func (c *client) doRequest(
 	if !c.opts.DisableCompression && req.Method != "HEAD" && req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" {
 		requestGzip = true
 	}
-	if err := c.requestWriter.WriteRequest(str, req, requestGzip); err != nil {
+	// Register the stream before sending the request, since the server might send datagrams right after the response headers.
+	// If the request succeeds, the datagrams are used by the response body.
+	var datagrams *streamDatagrams
+	var bodyUsesDatagrams bool
+	if c.datagrams != nil && req.Method == http.MethodConnect {
+		datagrams = c.datagrams.register(str.StreamID())
+		defer func() {
+			if !bodyUsesDatagrams {
+				datagrams.close()
+			}
+		}()
+	}
+	var onUnknownFrame unknownFrameHandlerFunc
+	if c.opts.UnknownFrameHandler != nil {
+		onUnknownFrame = func(ft FrameType, r io.Reader) error { return c.opts.UnknownFrameHandler(req, ft, r) }
+	}
+	rstr := &requestStream{Stream: str}
+	if err := c.requestWriter.WriteRequest(rstr, req, requestGzip); err != nil {
 		return nil, newStreamError(errorInternalError, err)
 	}
 
-	frame, err := parseNextFrame(str)
+	trace := httptrace.ContextClientTrace(req.Context())
+	var num1xx int
+	var res *http.Response
+	for {
+		var rerr requestError
+		res, rerr = c.readResponseHeaders(str, onUnknownFrame)
+		if rerr.err != nil {
+			return nil, rerr
+		}
+		// Interim responses (e.g. 103 Early Hints) are passed to the client trace.
+		// 101 (Switching Protocols) is not an interim response, and not allowed in HTTP/3.
+		if res.StatusCode < 100 || res.StatusCode >= 200 || res.StatusCode == http.StatusSwitchingProtocols {
+			break
+		}
+		num1xx++
+		if num1xx > max1xxResponses {
+			return nil, newStreamError(errorExcessiveLoad, errors.New("http3: too many 1xx informational responses"))
+		}
+		if trace != nil && trace.Got1xxResponse != nil {
+			if err := trace.Got1xxResponse(res.StatusCode, textproto.MIMEHeader(res.Header)); err != nil {
+				return nil, newStreamError(errorRequestCanceled, err)
+			}
+		}
+	}
+
+	connState := qtls.ToTLSConnectionState(c.session.ConnectionState().TLS)
+	res.TLS = &connState
+	respBody := newResponseBody(str, reqDone, func() {
+		c.session.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
+	})
+	respBody.onUnknownFrame = onUnknownFrame
+
+	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
+	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
+	isInformational := res.StatusCode >= 100 && res.StatusCode < 200
+	isNoContent := res.StatusCode == 204
+	isSuccessfulConnect := req.Method == http.MethodConnect && res.StatusCode >= 200 && res.StatusCode < 300
+	if !hasTransferEncoding && !isInformational && !isNoContent && !isSuccessfulConnect {
+		res.ContentLength = -1
+		if clens, ok := res.Header["Content-Length"]; ok && len(clens) == 1 {
+			if clen64, err := strconv.ParseInt(clens[0], 10, 64); err == nil {
+				res.ContentLength = clen64
+			}
+		}
+	}
+
+	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Body = newGzipReader(respBody)
+		res.Uncompressed = true
+	} else if datagrams != nil && isSuccessfulConnect {
+		res.Body = &datagramBody{responseBody: &responseBody{body: respBody, str: rstr}, datagrams: datagrams}
+		bodyUsesDatagrams = true
+	} else {
+		res.Body = &responseBody{body: respBody, str: rstr}
+	}
+
+	return res, requestError{}
+}
+
+// readResponseHeaders reads a HEADERS frame, and parses the response status and header fields.
+// Frames of unknown types preceding the HEADERS frame are passed to onUnknownFrame.
+func (c *client) readResponseHeaders(str quic.Stream, onUnknownFrame unknownFrameHandlerFunc) (*http.Response, requestError) {
+	frame, err := parseNextFrameWithHandler(str, onUnknownFrame)
 	if err != nil {
 		return nil, newStreamError(errorFrameError, err)
 	}
@@ -299,13 +443,19 @@ func (c *client) doRequest(
 		// TODO: use the right error code
 		return nil, newConnError(errorGeneralProtocolError, err)
 	}
+	if c.opts.StrictHeaderValidation {
+		if err := validateResponseHeaders(hfs); err != nil {
+			if c.opts.OnMalformedMessage != nil {
+				c.opts.OnMalformedMessage(err)
+			}
+			return nil, newStreamError(errorMessageError, fmt.Errorf("http3: malformed response: %w", err))
+		}
+	}
 
-	connState := qtls.ToTLSConnectionState(c.session.ConnectionState().TLS)
 	res := &http.Response{
 		Proto:      "HTTP/3",
 		ProtoMajor: 3,
 		Header:     http.Header{},
-		TLS:        &connState,
 	}
 	for _, hf := range hfs {
 		switch hf.Name {
@@ -320,33 +470,5 @@ func (c *client) doRequest(
 			res.Header.Add(hf.Name, hf.Value)
 		}
 	}
-	respBody := newResponseBody(str, reqDone, func() {
-		c.session.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
-	})
-
-	// Rules for when to set Content-Length are defined in https://tools.ietf.org/html/rfc7230#section-3.3.2.
-	_, hasTransferEncoding := res.Header["Transfer-Encoding"]
-	isInformational := res.StatusCode >= 100 && res.StatusCode < 200
-	isNoContent := res.StatusCode == 204
-	isSuccessfulConnect := req.Method == http.MethodConnect && res.StatusCode >= 200 && res.StatusCode < 300
-	if !hasTransferEncoding && !isInformational && !isNoContent && !isSuccessfulConnect {
-		res.ContentLength = -1
-		if clens, ok := res.Header["Content-Length"]; ok && len(clens) == 1 {
-			if clen64, err := strconv.ParseInt(clens[0], 10, 64); err == nil {
-				res.ContentLength = clen64
-			}
-		}
-	}
-
-	if requestGzip && res.Header.Get("Content-Encoding") == "gzip" {
-		res.Header.Del("Content-Encoding")
-		res.Header.Del("Content-Length")
-		res.ContentLength = -1
-		res.Body = newGzipReader(respBody)
-		res.Uncompressed = true
-	} else {
-		res.Body = respBody
-	}
-
 	return res, requestError{}
 }
@@ -10,6 +10,9 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"sync"
 	"time"
 
 	"github.com/golang/mock/gomock"
@@ -61,6 +64,11 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError("can only use a single QUIC version for dialing a HTTP/3 connection"))
 	})
 
+	It("rejects quic.Configs that don't allow the server to open the control and QPACK streams", func() {
+		_, err := newClient("localhost:1337", nil, &roundTripperOpts{}, &quic.Config{MaxIncomingUniStreams: 2}, nil)
+		Expect(err).To(MatchError("http3: QuicConfig.MaxIncomingUniStreams must be at least 3 (for the control and the QPACK streams), is 2"))
+	})
+
 	It("uses the default QUIC and TLS config if none is give", func() {
 		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
 		Expect(err).ToNot(HaveOccurred())
@@ -150,6 +158,37 @@ var _ = Describe("Client", func() {
 		Expect(err).To(MatchError(testErr))
 	})
 
+	It("rejects invalid additional settings", func() {
+		_, err := newClient("localhost:1337", nil, &roundTripperOpts{AdditionalSettings: map[uint64]uint64{0x6: 1000}}, nil, nil)
+		Expect(err).To(MatchError("http3: setting 0x6 can't be used as an additional setting"))
+	})
+
+	It("sends additional settings", func() {
+		client, err := newClient("localhost:1337", nil, &roundTripperOpts{AdditionalSettings: map[uint64]uint64{0x1337: 42}}, nil, nil)
+		Expect(err).ToNot(HaveOccurred())
+		written := make(chan []byte, 1)
+		controlStr := mockquic.NewMockStream(mockCtrl)
+		controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) { written <- b })
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+		sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).MaxTimes(1)
+		sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, errors.New("done"))
+		dialAddr = func(string, *tls.Config, *quic.Config) (quic.EarlySession, error) { return sess, nil }
+		_, err = client.RoundTrip(req)
+		Expect(err).To(MatchError("done"))
+		var data []byte
+		Eventually(written).Should(Receive(&data))
+		r := bytes.NewReader(data)
+		streamType, err := quicvarint.Read(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+		f, err := parseNextFrame(r)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+		Expect(f.(*settingsFrame).other).To(Equal(map[uint64]uint64{0x1337: 42}))
+	})
+
 	It("errors when dialing fails", func() {
 		testErr := errors.New("handshake error")
 		client, err := newClient("localhost:1337", nil, &roundTripperOpts{}, nil, nil)
@@ -192,11 +231,13 @@ var _ = Describe("Client", func() {
 			request              *http.Request
 			sess                 *mockquic.MockEarlySession
 			settingsFrameWritten chan struct{}
+			streamsDone          chan struct{}
 		)
 		testDone := make(chan struct{})
 
 		BeforeEach(func() {
 			settingsFrameWritten = make(chan struct{})
+			streamsDone = make(chan struct{})
 			controlStr := mockquic.NewMockStream(mockCtrl)
 			controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) {
 				defer GinkgoRecover()
@@ -214,6 +255,7 @@ var _ = Describe("Client", func() {
 
 		AfterEach(func() {
 			testDone <- struct{}{}
+			close(streamsDone)
 			Eventually(settingsFrameWritten).Should(BeClosed())
 		})
 
@@ -222,7 +264,7 @@ var _ = Describe("Client", func() {
 			quicvarint.Write(buf, streamTypeControlStream)
 			(&settingsFrame{}).Write(buf)
 			controlStr := mockquic.NewMockStream(mockCtrl)
-			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
 			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 				return controlStr, nil
 			})
@@ -246,7 +288,7 @@ var _ = Describe("Client", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, streamType)
 				str := mockquic.NewMockStream(mockCtrl)
-				str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
 
 				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return str, nil
@@ -356,6 +398,72 @@ var _ = Describe("Client", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("calls the OnPeerSettings callback", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeControlStream)
+			(&settingsFrame{Datagram: true, other: map[uint64]uint64{0x1337: 42}}).Write(buf)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(controlStr, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			received := make(chan map[uint64]uint64, 1)
+			client.opts.OnPeerSettings = func(_ quic.EarlySession, settings map[uint64]uint64) { received <- settings }
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(received).Should(Receive(Equal(map[uint64]uint64{0x1337: 42, settingDatagram: 1})))
+			Expect(client.settingsReceived).To(BeClosed())
+			Expect(client.peerSettings).To(Equal(map[uint64]uint64{0x1337: 42, settingDatagram: 1}))
+		})
+
+		It("errors when the server closes a QPACK stream", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, streamTypeQPACKDecoderStream)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(str, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorClosedCriticalStream))
+				Expect(reason).To(Equal("QPACK stream closed"))
+				close(done)
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("errors when the server opens two QPACK encoder streams", func() {
+			for i := 0; i < 2; i++ {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, streamTypeQPACKEncoderStream)
+				str := mockquic.NewMockStream(mockCtrl)
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(str, nil)
+			}
+			sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+				<-testDone
+				return nil, errors.New("test done")
+			})
+			done := make(chan struct{})
+			sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+				defer GinkgoRecover()
+				Expect(code).To(BeEquivalentTo(errorStreamCreationError))
+				Expect(reason).To(Equal("duplicate QPACK encoder stream"))
+				close(done)
+			})
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("done"))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("errors when the server advertises datagram support (and we enabled support for it)", func() {
 			client.opts.EnableDatagram = true
 			buf := &bytes.Buffer{}
@@ -507,6 +615,297 @@ var _ = Describe("Client", func() {
 			Expect(rsp.StatusCode).To(Equal(418))
 		})
 
+		Context("extension frames", func() {
+			It("passes unknown frames received before the HEADERS frame to the UnknownFrameHandler", func() {
+				var frameTypes []FrameType
+				var payloads []string
+				client.opts.UnknownFrameHandler = func(r *http.Request, ft FrameType, rd io.Reader) error {
+					Expect(r).To(Equal(request))
+					frameTypes = append(frameTypes, ft)
+					data, err := ioutil.ReadAll(rd)
+					Expect(err).ToNot(HaveOccurred())
+					payloads = append(payloads, string(data))
+					return nil
+				}
+				rspBuf := &bytes.Buffer{}
+				Expect(writeExtensionFrame(rspBuf, 0x1337, []byte("foo"))).To(Succeed())
+				rspBuf.Write(getResponse(200))
+				gomock.InOrder(
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+					sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+					sess.EXPECT().ConnectionState().Return(quic.ConnectionState{}),
+				)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(frameTypes).To(Equal([]FrameType{0x1337}))
+				Expect(payloads).To(Equal([]string{"foo"}))
+			})
+
+			It("aborts the request if the UnknownFrameHandler errors before the HEADERS frame", func() {
+				testErr := errors.New("unknown frame error")
+				client.opts.UnknownFrameHandler = func(*http.Request, FrameType, io.Reader) error { return testErr }
+				rspBuf := &bytes.Buffer{}
+				Expect(writeExtensionFrame(rspBuf, 0x1337, []byte("foo"))).To(Succeed())
+				rspBuf.Write(getResponse(200))
+				gomock.InOrder(
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+					sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+				)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorFrameError))
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError(testErr))
+			})
+
+			It("writes extension frames on the request stream", func() {
+				pr, pw := io.Pipe()
+				request.Method = http.MethodPost
+				request.Body = pr
+				rspBuf := bytes.NewBuffer(getResponse(200))
+				gomock.InOrder(
+					sess.EXPECT().HandshakeComplete().Return(handshakeCtx),
+					sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil),
+					sess.EXPECT().ConnectionState().Return(quic.ConnectionState{}),
+				)
+				var mutex sync.Mutex
+				strBuf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					return strBuf.Write(p)
+				}).AnyTimes()
+				closed := make(chan struct{})
+				str.EXPECT().Close().Do(func() { close(closed) })
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.Body).To(BeAssignableToTypeOf(&responseBody{}))
+				Expect(rsp.Body.(FrameWriter).WriteFrame(0x1337, []byte("metadata"))).To(Succeed())
+				Expect(rsp.Body.(FrameWriter).WriteFrame(0x1, []byte("foobar"))).ToNot(Succeed())
+				_, err = pw.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pw.Close()).To(Succeed())
+				Eventually(closed).Should(BeClosed())
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				Expect(decodeHeader(strBuf)).To(HaveKeyWithValue(":method", "POST"))
+				var frameType FrameType
+				frame, err := parseNextFrameWithHandler(strBuf, func(ft FrameType, r io.Reader) error {
+					frameType = ft
+					data, err := ioutil.ReadAll(r)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("metadata")))
+					return nil
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frameType).To(BeEquivalentTo(0x1337))
+				Expect(frame).To(Equal(&dataFrame{Length: 6}))
+				Expect(strBuf.Bytes()).To(Equal([]byte("foobar")))
+			})
+		})
+
+		Context("CONNECT requests using datagrams", func() {
+			var sessClosed chan struct{}
+
+			BeforeEach(func() {
+				client.opts.EnableDatagram = true
+				closed := make(chan struct{})
+				sessClosed = closed
+				sess.EXPECT().ReceiveMessage().DoAndReturn(func() ([]byte, error) {
+					<-closed
+					return nil, errors.New("session closed")
+				}).MaxTimes(1)
+				var err error
+				request, err = http.NewRequest(http.MethodConnect, "https://quic.clemente.io:1337/masque", nil)
+				Expect(err).ToNot(HaveOccurred())
+				request.Proto = "connect-udp"
+			})
+
+			AfterEach(func() { close(sessClosed) })
+
+			It("fails extended CONNECT requests if the server didn't enable extended CONNECT", func() {
+				sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+				client.handleSettings(&settingsFrame{})
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				// don't EXPECT any calls to OpenStreamSync
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("http3: server didn't enable extended CONNECT"))
+			})
+
+			It("waits for the server's SETTINGS before sending an extended CONNECT request", func() {
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+				errChan := make(chan error, 1)
+				go func() {
+					_, err := client.RoundTrip(request)
+					errChan <- err
+				}()
+				Consistently(errChan).ShouldNot(Receive())
+				// don't EXPECT any calls to OpenStreamSync
+				client.handleSettings(&settingsFrame{})
+				Eventually(errChan).Should(Receive(MatchError("http3: server didn't enable extended CONNECT")))
+			})
+
+			It("stops waiting for the server's SETTINGS when the session is closed", func() {
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				sessCtx, sessCancel := context.WithCancel(context.Background())
+				sess.EXPECT().Context().Return(sessCtx).AnyTimes()
+				errChan := make(chan error, 1)
+				go func() {
+					_, err := client.RoundTrip(request)
+					errChan <- err
+				}()
+				Consistently(errChan).ShouldNot(Receive())
+				sessCancel()
+				Eventually(errChan).Should(Receive(MatchError("http3: session closed before receiving the server's SETTINGS")))
+			})
+
+			It("returns a response body that can be used for datagrams", func() {
+				sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+				client.handleSettings(&settingsFrame{other: map[uint64]uint64{settingExtendedConnect: 1}})
+				rspBuf := bytes.NewBuffer(getResponse(200))
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, 1)
+				buf.Write([]byte("foobar"))
+				sess.EXPECT().SendMessage(buf.Bytes())
+				str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				reqBuf := &bytes.Buffer{}
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(reqBuf.Write).AnyTimes()
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				headers := decodeHeader(reqBuf)
+				Expect(headers).To(HaveKeyWithValue(":method", "CONNECT"))
+				Expect(headers).To(HaveKeyWithValue(":protocol", "connect-udp"))
+				Expect(headers).To(HaveKeyWithValue(":path", "/masque"))
+				Expect(rsp.Body).To(BeAssignableToTypeOf(&datagramBody{}))
+				Expect(rsp.Body.(Datagrammer).SendDatagram([]byte("foobar"))).To(Succeed())
+				// the stream is unregistered when the body is closed
+				str.EXPECT().CancelRead(gomock.Any())
+				Expect(rsp.Body.Close()).To(Succeed())
+				client.datagrams.mutex.Lock()
+				Expect(client.datagrams.streams).To(BeEmpty())
+				client.datagrams.mutex.Unlock()
+			})
+
+			It("doesn't use datagrams if the CONNECT request fails", func() {
+				sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+				client.handleSettings(&settingsFrame{other: map[uint64]uint64{settingExtendedConnect: 1}})
+				rspBuf := bytes.NewBuffer(getResponse(404))
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				sess.EXPECT().OpenStreamSync(context.Background()).Return(str, nil)
+				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				str.EXPECT().StreamID().Return(quic.StreamID(4)).AnyTimes()
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(404))
+				Expect(rsp.Body).ToNot(BeAssignableToTypeOf(&datagramBody{}))
+				client.datagrams.mutex.Lock()
+				Expect(client.datagrams.streams).To(BeEmpty())
+				client.datagrams.mutex.Unlock()
+			})
+		})
+
+		It("rejects malformed responses, if strict header validation is enabled", func() {
+			client.opts.StrictHeaderValidation = true
+			var rejected []error
+			client.opts.OnMalformedMessage = func(err error) { rejected = append(rejected, err) }
+			rspBuf := bytes.NewBuffer(getHeadersFrame(map[string]string{":status": "200", "transfer-encoding": "chunked"}))
+			sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+			str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+			str.EXPECT().Close()
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+			str.EXPECT().CancelWrite(quic.StreamErrorCode(errorMessageError))
+			_, err := client.RoundTrip(request)
+			Expect(err).To(MatchError("http3: malformed response: connection-specific header field transfer-encoding"))
+			Expect(rejected).To(HaveLen(1))
+		})
+
+		Context("interim responses", func() {
+			BeforeEach(func() {
+				sess.EXPECT().HandshakeComplete().Return(handshakeCtx)
+				sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+				str.EXPECT().Write(gomock.Any()).AnyTimes().DoAndReturn(func(p []byte) (int, error) { return len(p), nil })
+				str.EXPECT().Close()
+			})
+
+			earlyHints := func() []byte {
+				return getHeadersFrame(map[string]string{":status": "103", "link": "</style.css>; rel=preload; as=style"})
+			}
+
+			It("passes 103 Early Hints to the client trace", func() {
+				rspBuf := bytes.NewBuffer(earlyHints())
+				rspBuf.Write(earlyHints())
+				rspBuf.Write(getResponse(200))
+				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				var codes []int
+				var links []string
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+						codes = append(codes, code)
+						links = append(links, header.Get("Link"))
+						return nil
+					},
+				}
+				rsp, err := client.RoundTrip(request.WithContext(httptrace.WithClientTrace(request.Context(), trace)))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+				Expect(rsp.Header.Get("Link")).To(BeEmpty())
+				Expect(codes).To(Equal([]int{103, 103}))
+				Expect(links).To(Equal([]string{"</style.css>; rel=preload; as=style", "</style.css>; rel=preload; as=style"}))
+			})
+
+			It("skips interim responses if no client trace is set", func() {
+				rspBuf := bytes.NewBuffer(earlyHints())
+				rspBuf.Write(getResponse(200))
+				sess.EXPECT().ConnectionState().Return(quic.ConnectionState{})
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				rsp, err := client.RoundTrip(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rsp.StatusCode).To(Equal(200))
+			})
+
+			It("aborts the request if the client trace returns an error", func() {
+				rspBuf := bytes.NewBuffer(earlyHints())
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+				testErr := errors.New("test error")
+				trace := &httptrace.ClientTrace{
+					Got1xxResponse: func(int, textproto.MIMEHeader) error { return testErr },
+				}
+				_, err := client.RoundTrip(request.WithContext(httptrace.WithClientTrace(request.Context(), trace)))
+				Expect(err).To(MatchError(testErr))
+			})
+
+			It("errors when the server sends too many interim responses", func() {
+				rspBuf := &bytes.Buffer{}
+				for i := 0; i <= max1xxResponses; i++ {
+					rspBuf.Write(earlyHints())
+				}
+				str.EXPECT().Read(gomock.Any()).DoAndReturn(rspBuf.Read).AnyTimes()
+				str.EXPECT().CancelWrite(quic.StreamErrorCode(errorExcessiveLoad))
+				_, err := client.RoundTrip(request)
+				Expect(err).To(MatchError("http3: too many 1xx informational responses"))
+			})
+		})
+
 		Context("requests containing a Body", func() {
 			var strBuf *bytes.Buffer
 
@@ -0,0 +1,139 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// A Datagrammer sends and receives HTTP datagrams associated with a request stream.
+// The payload of the QUIC DATAGRAM frame is prefixed with the quarter stream ID, see section 2.1 of RFC 9297.
+// Datagrams can only be used for CONNECT requests, and only if datagram support is enabled (EnableDatagrams).
+// The http.ResponseWriter passed to the handlers of a Server implements Datagrammer,
+// as does the http.Response.Body of a successful CONNECT request sent by a RoundTripper.
+type Datagrammer interface {
+	// SendDatagram sends a datagram on the request stream.
+	SendDatagram(b []byte) error
+	// ReceiveDatagram receives a datagram on the request stream.
+	// Datagrams that are not received in time are dropped.
+	ReceiveDatagram(ctx context.Context) ([]byte, error)
+}
+
+// maxQueuedDatagrams is the maximum number of datagrams queued for every request stream.
+const maxQueuedDatagrams = 32
+
+var errNoDatagrams = errors.New("http3: datagrams not enabled for this request")
+
+// datagramDemultiplexer dispatches the HTTP datagrams received on a session to the request streams.
+type datagramDemultiplexer struct {
+	sess quic.Session
+
+	startOnce sync.Once
+
+	mutex   sync.Mutex
+	streams map[quic.StreamID]chan []byte
+
+	closed   chan struct{} // closed when receiving from the session fails
+	closeErr error
+
+	logger utils.Logger
+}
+
+func newDatagramDemultiplexer(sess quic.Session, logger utils.Logger) *datagramDemultiplexer {
+	return &datagramDemultiplexer{
+		sess:    sess,
+		streams: make(map[quic.StreamID]chan []byte),
+		closed:  make(chan struct{}),
+		logger:  logger,
+	}
+}
+
+func (d *datagramDemultiplexer) run() {
+	for {
+		data, err := d.sess.ReceiveMessage()
+		if err != nil {
+			d.closeErr = err
+			close(d.closed)
+			return
+		}
+		r := bytes.NewReader(data)
+		quarterStreamID, err := quicvarint.Read(r)
+		if err != nil {
+			d.logger.Debugf("Dropping datagram: %s", err)
+			continue
+		}
+		d.mutex.Lock()
+		queue, ok := d.streams[quic.StreamID(quarterStreamID*4)]
+		d.mutex.Unlock()
+		if !ok {
+			d.logger.Debugf("Dropping datagram for unknown stream %d", quarterStreamID*4)
+			continue
+		}
+		select {
+		case queue <- data[len(data)-r.Len():]:
+		default:
+			d.logger.Debugf("Dropping datagram for stream %d, since the queue is full", quarterStreamID*4)
+		}
+	}
+}
+
+// register starts queueing the datagrams received for a stream.
+// It must be called before the request is sent (on the client side), or before it is handled (on the server side),
+// such that datagrams received right after the request headers are not dropped.
+func (d *datagramDemultiplexer) register(id quic.StreamID) *streamDatagrams {
+	d.startOnce.Do(func() { go d.run() })
+	queue := make(chan []byte, maxQueuedDatagrams)
+	d.mutex.Lock()
+	d.streams[id] = queue
+	d.mutex.Unlock()
+	return &streamDatagrams{demux: d, id: id, queue: queue}
+}
+
+func (d *datagramDemultiplexer) unregister(id quic.StreamID) {
+	d.mutex.Lock()
+	delete(d.streams, id)
+	d.mutex.Unlock()
+}
+
+// streamDatagrams sends and receives the datagrams of a single stream.
+// A nil streamDatagrams is used for streams that can't use datagrams.
+type streamDatagrams struct {
+	demux *datagramDemultiplexer
+	id    quic.StreamID
+	queue chan []byte
+}
+
+func (s *streamDatagrams) SendDatagram(b []byte) error {
+	if s == nil {
+		return errNoDatagrams
+	}
+	buf := &bytes.Buffer{}
+	quicvarint.Write(buf, uint64(s.id/4))
+	buf.Write(b)
+	return s.demux.sess.SendMessage(buf.Bytes())
+}
+
+func (s *streamDatagrams) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	if s == nil {
+		return nil, errNoDatagrams
+	}
+	select {
+	case data := <-s.queue:
+		return data, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.demux.closed:
+		return nil, s.demux.closeErr
+	}
+}
+
+func (s *streamDatagrams) close() {
+	if s != nil {
+		s.demux.unregister(s.id)
+	}
+}
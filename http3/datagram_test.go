@@ -0,0 +1,112 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Datagrams", func() {
+	appendVarInt := func(b []byte, val uint64) []byte {
+		buf := &bytes.Buffer{}
+		quicvarint.Write(buf, val)
+		return append(b, buf.Bytes()...)
+	}
+
+	var (
+		sess         *mockquic.MockEarlySession
+		demux        *datagramDemultiplexer
+		received     chan []byte
+		closeSession func()
+	)
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		// The receive loop might outlive the test, so it must not access the variables reset by the next test.
+		rcv := make(chan []byte, maxQueuedDatagrams+1)
+		received = rcv
+		var once sync.Once
+		closeSession = func() { once.Do(func() { close(rcv) }) }
+		sess.EXPECT().ReceiveMessage().DoAndReturn(func() ([]byte, error) {
+			data, ok := <-rcv
+			if !ok {
+				return nil, errors.New("session closed")
+			}
+			return data, nil
+		}).AnyTimes()
+		demux = newDatagramDemultiplexer(sess, utils.DefaultLogger)
+	})
+
+	AfterEach(func() { closeSession() })
+
+	It("sends datagrams, prefixed with the quarter stream ID", func() {
+		sess.EXPECT().SendMessage(append(appendVarInt(nil, 1337), []byte("foobar")...))
+		dgs := demux.register(1337 * 4)
+		Expect(dgs.SendDatagram([]byte("foobar"))).To(Succeed())
+	})
+
+	It("receives datagrams", func() {
+		dgs1 := demux.register(4)
+		dgs2 := demux.register(8)
+		received <- append(appendVarInt(nil, 2), []byte("foo")...)
+		received <- append(appendVarInt(nil, 1), []byte("bar")...)
+		data, err := dgs1.ReceiveDatagram(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("bar")))
+		data, err = dgs2.ReceiveDatagram(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foo")))
+	})
+
+	It("drops datagrams for unknown and unregistered streams", func() {
+		dgs := demux.register(4)
+		demux.register(8).close()
+		received <- append(appendVarInt(nil, 1337), []byte("foo")...)
+		received <- append(appendVarInt(nil, 2), []byte("bar")...)
+		received <- append(appendVarInt(nil, 1), []byte("baz")...)
+		data, err := dgs.ReceiveDatagram(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("baz")))
+	})
+
+	It("drops datagrams if the queue is full", func() {
+		dgs := demux.register(4)
+		for i := 0; i < maxQueuedDatagrams+1; i++ {
+			received <- append(appendVarInt(nil, 1), byte(i))
+		}
+		Eventually(received).Should(BeEmpty())
+		time.Sleep(scaleDuration(10 * time.Millisecond)) // wait for the last datagram to be dropped
+		for i := 0; i < maxQueuedDatagrams; i++ {
+			data, err := dgs.ReceiveDatagram(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte{byte(i)}))
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Millisecond))
+		defer cancel()
+		_, err := dgs.ReceiveDatagram(ctx)
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("returns the error of the session", func() {
+		dgs := demux.register(4)
+		closeSession()
+		_, err := dgs.ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError("session closed"))
+	})
+
+	It("errors if datagrams can't be used", func() {
+		var dgs *streamDatagrams
+		Expect(dgs.SendDatagram([]byte("foobar"))).To(MatchError(errNoDatagrams))
+		_, err := dgs.ReceiveDatagram(context.Background())
+		Expect(err).To(MatchError(errNoDatagrams))
+	})
+})
@@ -0,0 +1,77 @@
+package http3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// FrameType is the frame type of a HTTP/3 frame.
+type FrameType uint64
+
+// An UnknownFrameHandler is called for frames of unknown types received on the stream of req,
+// i.e. for frames used by HTTP/3 extensions.
+// The payload of the frame can be read from r. Data that is not read by the handler is skipped.
+// Frames are passed to the handler while the HEADERS frame and the body of the request (on the server side)
+// or of the response (on the client side) are read.
+// On the server side, req is nil for frames received before the HEADERS frame of the request.
+// If the handler returns an error, reading the body fails with this error.
+// Before the HEADERS frame, the error aborts the request.
+type UnknownFrameHandler func(req *http.Request, ft FrameType, r io.Reader) error
+
+// A FrameWriter sends frames of types that are not handled by this package, i.e. frames used by HTTP/3 extensions.
+// The http.ResponseWriter passed to the handlers of a Server implements FrameWriter.
+// On the client side, the body of the http.Response implements FrameWriter (unless the response was transparently decompressed).
+// The frames are written on the request stream, which is only possible until the request body has been sent completely.
+type FrameWriter interface {
+	// WriteFrame writes a frame on the stream of the request.
+	// It is invalid to write frames of the types defined by HTTP/3, and of the reserved HTTP/2 frame types.
+	WriteFrame(ft FrameType, payload []byte) error
+}
+
+// isReservedFrameType says if the frame type is defined by HTTP/3 or reserved, since it was used by HTTP/2.
+func isReservedFrameType(ft FrameType) bool {
+	return ft <= 0x9 || ft == 0xd || ft == 0xe
+}
+
+func writeExtensionFrame(w io.Writer, ft FrameType, payload []byte) error {
+	if isReservedFrameType(ft) {
+		return fmt.Errorf("http3: frame type %#x can't be used as an extension frame", uint64(ft))
+	}
+	if uint64(ft) > quicvarint.Max {
+		return fmt.Errorf("http3: frame type %#x exceeds the maximum varint value", uint64(ft))
+	}
+	buf := &bytes.Buffer{}
+	quicvarint.Write(buf, uint64(ft))
+	quicvarint.Write(buf, uint64(len(payload)))
+	buf.Write(payload)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// A requestStream is the stream of a request sent by the client.
+// Writes are serialized, such that extension frames don't interleave with the DATA frames of the request body.
+type requestStream struct {
+	quic.Stream
+
+	writeMutex sync.Mutex
+}
+
+var _ FrameWriter = &requestStream{}
+
+func (s *requestStream) Write(b []byte) (int, error) {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return s.Stream.Write(b)
+}
+
+func (s *requestStream) WriteFrame(ft FrameType, payload []byte) error {
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	return writeExtensionFrame(s.Stream, ft, payload)
+}
@@ -12,7 +12,17 @@ import (
 
 type frame interface{}
 
+// unknownFrameHandlerFunc is called for frames of unknown types.
+// It can read the frame payload from r.
+type unknownFrameHandlerFunc func(ft FrameType, r io.Reader) error
+
 func parseNextFrame(r io.Reader) (frame, error) {
+	return parseNextFrameWithHandler(r, nil)
+}
+
+// parseNextFrameWithHandler parses the next frame.
+// Frames of unknown types are passed to the unknownFrameHandler (if set), and skipped afterwards.
+func parseNextFrameWithHandler(r io.Reader, unknownFrameHandler unknownFrameHandlerFunc) (frame, error) {
 	qr := quicvarint.NewReader(r)
 	t, err := quicvarint.Read(qr)
 	if err != nil {
@@ -30,23 +40,25 @@ func parseNextFrame(r io.Reader) (frame, error) {
 		return &headersFrame{Length: l}, nil
 	case 0x4:
 		return parseSettingsFrame(r, l)
-	case 0x3: // CANCEL_PUSH
-		fallthrough
-	case 0x5: // PUSH_PROMISE
-		fallthrough
-	case 0x7: // GOAWAY
-		fallthrough
-	case 0xd: // MAX_PUSH_ID
-		fallthrough
-	case 0xe: // DUPLICATE_PUSH
-		fallthrough
+	case 0x3, // CANCEL_PUSH
+		0x5, // PUSH_PROMISE
+		0x7, // GOAWAY
+		0xd, // MAX_PUSH_ID
+		0xe: // DUPLICATE_PUSH
 	default:
-		// skip over unknown frames
-		if _, err := io.CopyN(ioutil.Discard, qr, int64(l)); err != nil {
-			return nil, err
+		if unknownFrameHandler != nil {
+			lr := &io.LimitedReader{R: qr, N: int64(l)}
+			if err := unknownFrameHandler(FrameType(t), lr); err != nil {
+				return nil, err
+			}
+			l = uint64(lr.N)
 		}
-		return parseNextFrame(qr)
 	}
+	// skip over frames that we don't handle (and the part of the payload the handler didn't read)
+	if _, err := io.CopyN(ioutil.Discard, qr, int64(l)); err != nil {
+		return nil, err
+	}
+	return parseNextFrameWithHandler(qr, unknownFrameHandler)
 }
 
 type dataFrame struct {
@@ -69,6 +81,28 @@ func (f *headersFrame) Write(b *bytes.Buffer) {
 
 const settingDatagram = 0x276
 
+// settingExtendedConnect is SETTINGS_ENABLE_CONNECT_PROTOCOL, see RFC 9220.
+const settingExtendedConnect = 0x8
+
+// validateAdditionalSettings checks that the settings don't contain any of the settings
+// defined by HTTP/3, QPACK and the HTTP/3 datagram draft, which are managed by this package.
+func validateAdditionalSettings(settings map[uint64]uint64) error {
+	for id, val := range settings {
+		// 0x1 (QPACK_MAX_TABLE_CAPACITY), 0x6 (MAX_FIELD_SECTION_SIZE) and 0x7 (QPACK_BLOCKED_STREAMS) are defined by HTTP/3 and QPACK,
+		// 0x2 to 0x5 are reserved, since they are used by HTTP/2.
+		if (id >= 0x1 && id <= 0x7) || id == settingDatagram {
+			return fmt.Errorf("http3: setting %#x can't be used as an additional setting", id)
+		}
+		if id > quicvarint.Max {
+			return fmt.Errorf("http3: setting identifier %#x exceeds the maximum varint value", id)
+		}
+		if val > quicvarint.Max {
+			return fmt.Errorf("http3: value %#x of setting %#x exceeds the maximum varint value", val, id)
+		}
+	}
+	return nil
+}
+
 type settingsFrame struct {
 	Datagram bool
 	other    map[uint64]uint64 // all settings that we don't explicitly recognize
@@ -140,3 +174,16 @@ func (f *settingsFrame) Write(b *bytes.Buffer) {
 		quicvarint.Write(b, val)
 	}
 }
+
+// Settings returns all settings contained in the frame.
+// H3_DATAGRAM is only contained if datagram support is enabled.
+func (f *settingsFrame) Settings() map[uint64]uint64 {
+	settings := make(map[uint64]uint64, len(f.other)+1)
+	for id, val := range f.other {
+		settings[id] = val
+	}
+	if f.Datagram {
+		settings[settingDatagram] = 1
+	}
+	return settings
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/lucas-clemente/quic-go/quicvarint"
 
@@ -30,6 +31,65 @@ var _ = Describe("Frames", func() {
 		Expect(frame.(*dataFrame).Length).To(Equal(uint64(0x1234)))
 	})
 
+	Context("unknown frames", func() {
+		It("passes unknown frames to the handler, and skips the part of the payload it didn't read", func() {
+			buf := &bytes.Buffer{}
+			Expect(writeExtensionFrame(buf, 0x1337, []byte("foobar"))).To(Succeed())
+			Expect(writeExtensionFrame(buf, 0x1338, []byte("raboof"))).To(Succeed())
+			(&dataFrame{Length: 0x1234}).Write(buf)
+			var types []FrameType
+			frame, err := parseNextFrameWithHandler(buf, func(ft FrameType, r io.Reader) error {
+				types = append(types, ft)
+				b := make([]byte, 3)
+				_, err := io.ReadFull(r, b)
+				Expect(err).ToNot(HaveOccurred())
+				if ft == 0x1337 {
+					Expect(b).To(Equal([]byte("foo")))
+				} else {
+					Expect(b).To(Equal([]byte("rab")))
+				}
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
+			Expect(frame.(*dataFrame).Length).To(Equal(uint64(0x1234)))
+			Expect(types).To(Equal([]FrameType{0x1337, 0x1338}))
+		})
+
+		It("limits the reader passed to the handler to the frame payload", func() {
+			buf := &bytes.Buffer{}
+			Expect(writeExtensionFrame(buf, 0x1337, []byte("foobar"))).To(Succeed())
+			(&dataFrame{Length: 0x1234}).Write(buf)
+			_, err := parseNextFrameWithHandler(buf, func(_ FrameType, r io.Reader) error {
+				data, err := ioutil.ReadAll(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("doesn't pass frames defined by HTTP/3 to the handler", func() {
+			data := appendVarInt(nil, 0x7) // GOAWAY
+			data = appendVarInt(data, 1)
+			data = append(data, 0)
+			buf := bytes.NewBuffer(data)
+			(&dataFrame{Length: 0x1234}).Write(buf)
+			frame, err := parseNextFrameWithHandler(buf, func(FrameType, io.Reader) error {
+				Fail("didn't expect the handler to be called")
+				return nil
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(frame).To(BeAssignableToTypeOf(&dataFrame{}))
+		})
+
+		It("refuses to write reserved frame types", func() {
+			for _, ft := range []FrameType{0x0, 0x1, 0x2, 0x4, 0x6, 0x9, 0xd, 0xe} {
+				Expect(writeExtensionFrame(&bytes.Buffer{}, ft, nil)).To(MatchError(fmt.Sprintf("http3: frame type %#x can't be used as an extension frame", uint64(ft))))
+			}
+		})
+	})
+
 	Context("DATA frames", func() {
 		It("parses", func() {
 			data := appendVarInt(nil, 0) // type byte
@@ -114,6 +174,23 @@ var _ = Describe("Frames", func() {
 			Expect(frame).To(Equal(sf))
 		})
 
+		It("validates additional settings", func() {
+			Expect(validateAdditionalSettings(nil)).To(Succeed())
+			Expect(validateAdditionalSettings(map[uint64]uint64{0x8: 1, 0x2b603742: 1})).To(Succeed())
+			for id := uint64(0x1); id <= 0x7; id++ {
+				Expect(validateAdditionalSettings(map[uint64]uint64{id: 1})).To(MatchError(fmt.Sprintf("http3: setting %#x can't be used as an additional setting", id)))
+			}
+			Expect(validateAdditionalSettings(map[uint64]uint64{settingDatagram: 1})).To(HaveOccurred())
+		})
+
+		It("rejects additional settings with an identifier that exceeds the maximum varint value", func() {
+			Expect(validateAdditionalSettings(map[uint64]uint64{quicvarint.Max + 1: 1})).To(MatchError("http3: setting identifier 0x4000000000000000 exceeds the maximum varint value"))
+		})
+
+		It("rejects additional settings with a value that exceeds the maximum varint value", func() {
+			Expect(validateAdditionalSettings(map[uint64]uint64{0x1337: quicvarint.Max + 1})).To(MatchError("http3: value 0x4000000000000000 of setting 0x1337 exceeds the maximum varint value"))
+		})
+
 		It("errors on EOF", func() {
 			sf := &settingsFrame{other: map[uint64]uint64{
 				13:         37,
@@ -179,5 +256,12 @@ var _ = Describe("Frames", func() {
 				Expect(frame).To(Equal(sf))
 			})
 		})
+
+		It("returns all settings", func() {
+			sf := &settingsFrame{other: map[uint64]uint64{0x1337: 42}}
+			Expect(sf.Settings()).To(Equal(map[uint64]uint64{0x1337: 42}))
+			sf.Datagram = true
+			Expect(sf.Settings()).To(Equal(map[uint64]uint64{0x1337: 42, settingDatagram: 1}))
+		})
 	})
 })
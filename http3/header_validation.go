@@ -0,0 +1,121 @@
+package http3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/marten-seemann/qpack"
+	"golang.org/x/net/http/httpguts"
+)
+
+// connectionSpecificHeaders are not allowed in HTTP/3, see section 4.2 of RFC 9114.
+var connectionSpecificHeaders = map[string]struct{}{
+	"connection":        {},
+	"keep-alive":        {},
+	"proxy-connection":  {},
+	"transfer-encoding": {},
+	"upgrade":           {},
+}
+
+var (
+	requestPseudoHeaders  = map[string]struct{}{":method": {}, ":scheme": {}, ":authority": {}, ":path": {}, ":protocol": {}}
+	responsePseudoHeaders = map[string]struct{}{":status": {}}
+)
+
+// validateRequestHeaders strictly validates the header fields of a request, according to section 4 of RFC 9114.
+func validateRequestHeaders(hfs []qpack.HeaderField) error {
+	pseudo, err := validateHeaderFields(hfs, requestPseudoHeaders)
+	if err != nil {
+		return err
+	}
+	if pseudo[":method"] == "" {
+		return errors.New("missing :method pseudo-header")
+	}
+	_, hasProtocol := pseudo[":protocol"]
+	if pseudo[":method"] == "CONNECT" {
+		if pseudo[":authority"] == "" {
+			return errors.New("missing :authority pseudo-header for CONNECT request")
+		}
+		// An extended CONNECT request (RFC 9220) contains the same pseudo-headers as other requests, plus :protocol.
+		if !hasProtocol {
+			if len(pseudo) != 2 {
+				return errors.New("CONNECT request must only contain the :method and :authority pseudo-headers")
+			}
+			return nil
+		}
+	} else if hasProtocol {
+		return errors.New(":protocol pseudo-header is only allowed for CONNECT requests")
+	}
+	if pseudo[":scheme"] == "" {
+		return errors.New("missing :scheme pseudo-header")
+	}
+	if pseudo[":path"] == "" {
+		return errors.New("missing :path pseudo-header")
+	}
+	return nil
+}
+
+// validateResponseHeaders strictly validates the header fields of a response, according to section 4 of RFC 9114.
+func validateResponseHeaders(hfs []qpack.HeaderField) error {
+	pseudo, err := validateHeaderFields(hfs, responsePseudoHeaders)
+	if err != nil {
+		return err
+	}
+	if pseudo[":status"] == "" {
+		return errors.New("missing :status pseudo-header")
+	}
+	return nil
+}
+
+// validateHeaderFields checks that:
+// * all pseudo-headers are allowed, appear at most once and precede all regular header fields
+// * all field names are lowercase tokens, and the field values don't contain forbidden characters
+// * no connection-specific header fields are used
+// * all Content-Length header fields have the same (numeric) value
+// It returns the values of the pseudo-headers.
+func validateHeaderFields(hfs []qpack.HeaderField, allowedPseudoHeaders map[string]struct{}) (map[string]string, error) {
+	pseudo := make(map[string]string, len(allowedPseudoHeaders))
+	var contentLength string
+	var seenRegular bool
+	for _, hf := range hfs {
+		if hf.IsPseudo() {
+			if seenRegular {
+				return nil, fmt.Errorf("pseudo-header %s after regular header field", hf.Name)
+			}
+			if _, ok := allowedPseudoHeaders[hf.Name]; !ok {
+				return nil, fmt.Errorf("invalid pseudo-header %s", hf.Name)
+			}
+			if _, ok := pseudo[hf.Name]; ok {
+				return nil, fmt.Errorf("duplicate pseudo-header %s", hf.Name)
+			}
+			pseudo[hf.Name] = hf.Value
+			continue
+		}
+		seenRegular = true
+		if !httpguts.ValidHeaderFieldName(hf.Name) || strings.ToLower(hf.Name) != hf.Name {
+			return nil, fmt.Errorf("invalid header field name %q", hf.Name)
+		}
+		if !httpguts.ValidHeaderFieldValue(hf.Value) || strings.TrimSpace(hf.Value) != hf.Value {
+			return nil, fmt.Errorf("invalid header field value for %s", hf.Name)
+		}
+		if _, ok := connectionSpecificHeaders[hf.Name]; ok {
+			return nil, fmt.Errorf("connection-specific header field %s", hf.Name)
+		}
+		switch hf.Name {
+		case "te":
+			if hf.Value != "trailers" {
+				return nil, fmt.Errorf("invalid value for TE header field: %q", hf.Value)
+			}
+		case "content-length":
+			if strings.TrimLeft(hf.Value, "0123456789") != "" || hf.Value == "" {
+				return nil, fmt.Errorf("invalid Content-Length: %q", hf.Value)
+			}
+			if contentLength != "" && contentLength != hf.Value {
+				return nil, fmt.Errorf("conflicting Content-Length header fields: %s and %s", contentLength, hf.Value)
+			}
+			contentLength = hf.Value
+		}
+	}
+	return pseudo, nil
+}
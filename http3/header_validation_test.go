@@ -0,0 +1,94 @@
+package http3
+
+import (
+	"github.com/marten-seemann/qpack"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Header Validation", func() {
+	validRequest := func(additional ...qpack.HeaderField) []qpack.HeaderField {
+		return append([]qpack.HeaderField{
+			{Name: ":method", Value: "GET"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":path", Value: "/foo"},
+		}, additional...)
+	}
+
+	It("accepts valid requests", func() {
+		Expect(validateRequestHeaders(validRequest(
+			qpack.HeaderField{Name: "content-length", Value: "42"},
+			qpack.HeaderField{Name: "content-length", Value: "42"},
+			qpack.HeaderField{Name: "te", Value: "trailers"},
+			qpack.HeaderField{Name: "cookie", Value: "foo=bar"},
+		))).To(Succeed())
+	})
+
+	It("accepts valid CONNECT requests", func() {
+		Expect(validateRequestHeaders([]qpack.HeaderField{
+			{Name: ":method", Value: "CONNECT"},
+			{Name: ":authority", Value: "quic.clemente.io:443"},
+		})).To(Succeed())
+	})
+
+	It("accepts valid extended CONNECT requests", func() {
+		Expect(validateRequestHeaders([]qpack.HeaderField{
+			{Name: ":method", Value: "CONNECT"},
+			{Name: ":protocol", Value: "connect-udp"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":authority", Value: "quic.clemente.io:443"},
+			{Name: ":path", Value: "/masque"},
+		})).To(Succeed())
+	})
+
+	It("accepts valid responses", func() {
+		Expect(validateResponseHeaders([]qpack.HeaderField{
+			{Name: ":status", Value: "200"},
+			{Name: "content-length", Value: "1337"},
+		})).To(Succeed())
+	})
+
+	DescribeTable("rejecting malformed requests",
+		func(hfs []qpack.HeaderField, expectedErr string) {
+			Expect(validateRequestHeaders(hfs)).To(MatchError(expectedErr))
+		},
+		Entry("missing :method", []qpack.HeaderField{{Name: ":scheme", Value: "https"}, {Name: ":path", Value: "/"}}, "missing :method pseudo-header"),
+		Entry("missing :scheme", []qpack.HeaderField{{Name: ":method", Value: "GET"}, {Name: ":path", Value: "/"}}, "missing :scheme pseudo-header"),
+		Entry("missing :path", []qpack.HeaderField{{Name: ":method", Value: "GET"}, {Name: ":scheme", Value: "https"}}, "missing :path pseudo-header"),
+		Entry("CONNECT with :path", []qpack.HeaderField{{Name: ":method", Value: "CONNECT"}, {Name: ":authority", Value: "quic.clemente.io:443"}, {Name: ":path", Value: "/"}}, "CONNECT request must only contain the :method and :authority pseudo-headers"),
+		Entry("extended CONNECT without :path", []qpack.HeaderField{{Name: ":method", Value: "CONNECT"}, {Name: ":protocol", Value: "connect-udp"}, {Name: ":authority", Value: "quic.clemente.io:443"}, {Name: ":scheme", Value: "https"}}, "missing :path pseudo-header"),
+		Entry(":protocol without CONNECT", validRequest(qpack.HeaderField{Name: ":protocol", Value: "connect-udp"}), ":protocol pseudo-header is only allowed for CONNECT requests"),
+		Entry("response pseudo-header", validRequest(qpack.HeaderField{Name: ":status", Value: "200"}), "invalid pseudo-header :status"),
+		Entry("unknown pseudo-header", validRequest(qpack.HeaderField{Name: ":foo", Value: "bar"}), "invalid pseudo-header :foo"),
+		Entry("duplicate pseudo-header", validRequest(qpack.HeaderField{Name: ":path", Value: "/bar"}), "duplicate pseudo-header :path"),
+		Entry("pseudo-header after regular header", append([]qpack.HeaderField{{Name: "foo", Value: "bar"}}, validRequest()...), "pseudo-header :method after regular header field"),
+		Entry("uppercase field name", validRequest(qpack.HeaderField{Name: "Foo", Value: "bar"}), `invalid header field name "Foo"`),
+		Entry("invalid field name", validRequest(qpack.HeaderField{Name: "foo bar", Value: "bar"}), `invalid header field name "foo bar"`),
+		Entry("CR in field value", validRequest(qpack.HeaderField{Name: "foo", Value: "bar\r\nx-injected: 1"}), "invalid header field value for foo"),
+		Entry("NUL in field value", validRequest(qpack.HeaderField{Name: "foo", Value: "bar\x00"}), "invalid header field value for foo"),
+		Entry("leading whitespace in field value", validRequest(qpack.HeaderField{Name: "foo", Value: " bar"}), "invalid header field value for foo"),
+		Entry("Transfer-Encoding", validRequest(qpack.HeaderField{Name: "transfer-encoding", Value: "chunked"}), "connection-specific header field transfer-encoding"),
+		Entry("Connection", validRequest(qpack.HeaderField{Name: "connection", Value: "close"}), "connection-specific header field connection"),
+		Entry("invalid TE", validRequest(qpack.HeaderField{Name: "te", Value: "gzip"}), `invalid value for TE header field: "gzip"`),
+		Entry("non-numeric Content-Length", validRequest(qpack.HeaderField{Name: "content-length", Value: "-1"}), `invalid Content-Length: "-1"`),
+		Entry("empty Content-Length", validRequest(qpack.HeaderField{Name: "content-length", Value: ""}), `invalid Content-Length: ""`),
+		Entry("conflicting Content-Length",
+			validRequest(qpack.HeaderField{Name: "content-length", Value: "42"}, qpack.HeaderField{Name: "content-length", Value: "43"}),
+			"conflicting Content-Length header fields: 42 and 43",
+		),
+	)
+
+	It("rejects responses without a :status", func() {
+		Expect(validateResponseHeaders([]qpack.HeaderField{{Name: "foo", Value: "bar"}})).To(MatchError("missing :status pseudo-header"))
+	})
+
+	It("rejects responses containing request pseudo-headers", func() {
+		Expect(validateResponseHeaders([]qpack.HeaderField{
+			{Name: ":status", Value: "200"},
+			{Name: ":path", Value: "/"},
+		})).To(MatchError("invalid pseudo-header :path"))
+	})
+})
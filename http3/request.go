@@ -12,7 +12,7 @@ import (
 )
 
 func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
-	var path, authority, method, contentLengthStr string
+	var path, authority, method, protocol, scheme, contentLengthStr string
 	httpHeaders := http.Header{}
 
 	for _, h := range headers {
@@ -23,6 +23,10 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 			method = h.Value
 		case ":authority":
 			authority = h.Value
+		case ":protocol":
+			protocol = h.Value
+		case ":scheme":
+			scheme = h.Value
 		case "content-length":
 			contentLengthStr = h.Value
 		default:
@@ -38,7 +42,15 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}
 
 	isConnect := method == http.MethodConnect
-	if isConnect {
+	// Extended CONNECT, see RFC 9220.
+	extendedConnect := isConnect && protocol != ""
+	if extendedConnect {
+		if path == "" || scheme == "" || authority == "" {
+			return nil, errors.New("extended CONNECT: :path, :scheme and :authority must not be empty")
+		}
+	} else if protocol != "" {
+		return nil, errors.New(":protocol must only be used for CONNECT requests")
+	} else if isConnect {
 		if path != "" || authority == "" {
 			return nil, errors.New(":path must be empty and :authority must not be empty")
 		}
@@ -50,7 +62,7 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	var requestURI string
 	var err error
 
-	if isConnect {
+	if isConnect && !extendedConnect {
 		u = &url.URL{Host: authority}
 		requestURI = authority
 	} else {
@@ -69,10 +81,15 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 		}
 	}
 
+	proto := "HTTP/3"
+	if extendedConnect {
+		proto = protocol
+	}
+
 	return &http.Request{
 		Method:        method,
 		URL:           u,
-		Proto:         "HTTP/3",
+		Proto:         proto,
 		ProtoMajor:    3,
 		ProtoMinor:    0,
 		Header:        httpHeaders,
@@ -84,6 +101,12 @@ func requestFromHeaders(headers []qpack.HeaderField) (*http.Request, error) {
 	}, nil
 }
 
+// isExtendedConnect says if req is an extended CONNECT request, see RFC 9220.
+// The protocol (the :protocol pseudo-header) is carried in req.Proto.
+func isExtendedConnect(req *http.Request) bool {
+	return req.Method == http.MethodConnect && req.Proto != "" && !strings.HasPrefix(req.Proto, "HTTP/")
+}
+
 func hostnameFromRequest(req *http.Request) string {
 	if req.URL != nil {
 		return req.URL.Host
@@ -92,6 +92,49 @@ var _ = Describe("Request", func() {
 		Expect(req.RequestURI).To(Equal("quic.clemente.io"))
 	})
 
+	It("handles extended CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":method", Value: http.MethodConnect},
+			{Name: ":protocol", Value: "connect-udp"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":path", Value: "/masque?h=example.com&p=443"},
+		}
+		req, err := requestFromHeaders(headers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(req.Method).To(Equal(http.MethodConnect))
+		Expect(req.Proto).To(Equal("connect-udp"))
+		Expect(req.ProtoMajor).To(Equal(3))
+		Expect(req.URL.Path).To(Equal("/masque"))
+		Expect(req.URL.RawQuery).To(Equal("h=example.com&p=443"))
+		Expect(req.Host).To(Equal("quic.clemente.io"))
+		Expect(req.RequestURI).To(Equal("/masque?h=example.com&p=443"))
+		Expect(isExtendedConnect(req)).To(BeTrue())
+	})
+
+	It("errors with missing path in extended CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":method", Value: http.MethodConnect},
+			{Name: ":protocol", Value: "connect-udp"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError("extended CONNECT: :path, :scheme and :authority must not be empty"))
+	})
+
+	It("errors with :protocol in methods other than CONNECT", func() {
+		headers := []qpack.HeaderField{
+			{Name: ":method", Value: http.MethodGet},
+			{Name: ":protocol", Value: "connect-udp"},
+			{Name: ":scheme", Value: "https"},
+			{Name: ":authority", Value: "quic.clemente.io"},
+			{Name: ":path", Value: "/foo"},
+		}
+		_, err := requestFromHeaders(headers)
+		Expect(err).To(MatchError(":protocol must only be used for CONNECT requests"))
+	})
+
 	It("errors with missing path", func() {
 		headers := []qpack.HeaderField{
 			{Name: ":authority", Value: "quic.clemente.io"},
@@ -137,6 +180,16 @@ var _ = Describe("Request", func() {
 		Expect(err).To(MatchError(":path must be empty and :authority must not be empty"))
 	})
 
+	It("detects extended CONNECT requests", func() {
+		req, err := http.NewRequest(http.MethodConnect, "https://quic.clemente.io/masque", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(isExtendedConnect(req)).To(BeFalse())
+		req.Proto = "connect-udp"
+		Expect(isExtendedConnect(req)).To(BeTrue())
+		req.Method = http.MethodGet
+		Expect(isExtendedConnect(req)).To(BeFalse())
+	})
+
 	Context("extracting the hostname from a request", func() {
 		var url *url.URL
 
@@ -65,16 +65,15 @@ func (w *requestWriter) WriteRequest(str quic.Stream, req *http.Request, gzip bo
 					break
 				}
 			}
+			// Write the frame header and the payload at once,
+			// such that extension frames written on the request stream can't end up in between.
 			buf := &bytes.Buffer{}
 			(&dataFrame{Length: uint64(n)}).Write(buf)
+			buf.Write(b[:n])
 			if _, err := str.Write(buf.Bytes()); err != nil {
 				w.logger.Errorf("Error writing request: %s", err)
 				return
 			}
-			if _, err := str.Write(b[:n]); err != nil {
-				w.logger.Errorf("Error writing request: %s", err)
-				return
-			}
 			if rerr != nil {
 				if rerr == io.EOF {
 					break
@@ -124,8 +123,9 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 		return err
 	}
 
+	extendedConnect := isExtendedConnect(req)
 	var path string
-	if req.Method != "CONNECT" {
+	if req.Method != "CONNECT" || extendedConnect {
 		path = req.URL.RequestURI()
 		if !validPseudoPath(path) {
 			orig := path
@@ -162,10 +162,13 @@ func (w *requestWriter) encodeHeaders(req *http.Request, addGzipHeader bool, tra
 		// [RFC3986]).
 		f(":authority", host)
 		f(":method", req.Method)
-		if req.Method != "CONNECT" {
+		if req.Method != "CONNECT" || extendedConnect {
 			f(":path", path)
 			f(":scheme", req.URL.Scheme)
 		}
+		if extendedConnect {
+			f(":protocol", req.Proto)
+		}
 		if trailers != "" {
 			f("trailer", trailers)
 		}
@@ -108,6 +108,33 @@ var _ = Describe("Request Writer", func() {
 		Expect(frame.(*dataFrame).Length).To(BeEquivalentTo(6))
 	})
 
+	It("writes a CONNECT request", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest(http.MethodConnect, "https://quic.clemente.io/", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":method", "CONNECT"))
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
+		Expect(headerFields).ToNot(HaveKey(":path"))
+		Expect(headerFields).ToNot(HaveKey(":scheme"))
+		Expect(headerFields).ToNot(HaveKey(":protocol"))
+	})
+
+	It("writes an extended CONNECT request", func() {
+		str.EXPECT().Close()
+		req, err := http.NewRequest(http.MethodConnect, "https://quic.clemente.io/masque?h=example.com&p=443", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Proto = "connect-udp"
+		Expect(rw.WriteRequest(str, req, false)).To(Succeed())
+		headerFields := decode(strBuf)
+		Expect(headerFields).To(HaveKeyWithValue(":method", "CONNECT"))
+		Expect(headerFields).To(HaveKeyWithValue(":protocol", "connect-udp"))
+		Expect(headerFields).To(HaveKeyWithValue(":authority", "quic.clemente.io"))
+		Expect(headerFields).To(HaveKeyWithValue(":path", "/masque?h=example.com&p=443"))
+		Expect(headerFields).To(HaveKeyWithValue(":scheme", "https"))
+	})
+
 	It("sends cookies", func() {
 		str.EXPECT().Close()
 		req, err := http.NewRequest("GET", "https://quic.clemente.io/", nil)
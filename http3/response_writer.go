@@ -3,6 +3,7 @@ package http3
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"net/http"
 	"strconv"
 	"strings"
@@ -31,6 +32,8 @@ type responseWriter struct {
 	headerWritten  bool
 	dataStreamUsed bool // set when DataSteam() is called
 
+	datagrams *streamDatagrams // nil if datagrams can't be used for this request
+
 	logger utils.Logger
 }
 
@@ -38,6 +41,8 @@ var (
 	_ http.ResponseWriter = &responseWriter{}
 	_ http.Flusher        = &responseWriter{}
 	_ DataStreamer        = &responseWriter{}
+	_ FrameWriter         = &responseWriter{}
+	_ Datagrammer         = &responseWriter{}
 )
 
 func newResponseWriter(stream quic.Stream, logger utils.Logger) *responseWriter {
@@ -103,6 +108,10 @@ func (w *responseWriter) Write(p []byte) (int, error) {
 	return w.bufferedStream.Write(p)
 }
 
+func (w *responseWriter) WriteFrame(ft FrameType, payload []byte) error {
+	return writeExtensionFrame(w.bufferedStream, ft, payload)
+}
+
 func (w *responseWriter) Flush() {
 	if err := w.bufferedStream.Flush(); err != nil {
 		w.logger.Errorf("could not flush to stream: %s", err.Error())
@@ -119,6 +128,18 @@ func (w *responseWriter) DataStream() quic.Stream {
 	return w.stream
 }
 
+// SendDatagram sends a datagram on the request stream.
+// It can only be used for CONNECT requests, and only until the handler returns.
+func (w *responseWriter) SendDatagram(b []byte) error {
+	return w.datagrams.SendDatagram(b)
+}
+
+// ReceiveDatagram receives a datagram on the request stream.
+// It can only be used for CONNECT requests, and only until the handler returns.
+func (w *responseWriter) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	return w.datagrams.ReceiveDatagram(ctx)
+}
+
 // copied from http2/http2.go
 // bodyAllowedForStatus reports whether a given response status code
 // permits a body. See RFC 2616, section 4.4.
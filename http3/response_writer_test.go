@@ -3,6 +3,7 @@ package http3
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"net/http"
 
 	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
@@ -147,4 +148,25 @@ var _ = Describe("Response Writer", func() {
 		Expect(n).To(BeZero())
 		Expect(err).To(MatchError(http.ErrBodyNotAllowed))
 	})
+
+	It("writes extension frames", func() {
+		rw.WriteHeader(http.StatusOK)
+		Expect(rw.WriteFrame(0x1337, []byte("metadata"))).To(Succeed())
+		rw.Write([]byte("foobar"))
+		decodeHeader(strBuf)
+		var payload []byte
+		frame, err := parseNextFrameWithHandler(strBuf, func(ft FrameType, r io.Reader) error {
+			Expect(ft).To(Equal(FrameType(0x1337)))
+			var err error
+			payload, err = ioutil.ReadAll(r)
+			return err
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(payload).To(Equal([]byte("metadata")))
+		Expect(frame).To(Equal(&dataFrame{Length: 6}))
+	})
+
+	It("refuses to write frames of types defined by HTTP/3", func() {
+		Expect(rw.WriteFrame(0x0, []byte("foobar"))).To(HaveOccurred())
+	})
 })
@@ -0,0 +1,219 @@
+package http3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// ReverseProxyOptions are options for NewSingleHostReverseProxy and NewSingleHostTunnelingProxy.
+type ReverseProxyOptions struct {
+	// RoundTripper is used to send requests to the backend.
+	// If nil, a new RoundTripper is used.
+	RoundTripper *RoundTripper
+	// Allow0RTT allows GET requests without a body to be forwarded using 0-RTT,
+	// if a session to the backend was resumed.
+	// Note that 0-RTT data doesn't provide replay protection,
+	// so this should only be used if GET requests don't have side effects on the backend.
+	Allow0RTT bool
+}
+
+// NewSingleHostReverseProxy returns a httputil.ReverseProxy that forwards requests to target using HTTP/3.
+// Requests are rewritten in the same way as by httputil.NewSingleHostReverseProxy.
+// The scheme of target has to be https.
+// Responses are flushed to the client immediately, such that streamed responses are not delayed by the proxy.
+// The returned proxy can be modified before it is used, e.g. to set an ErrorHandler or ModifyResponse.
+// To forward extended CONNECT requests, use NewSingleHostTunnelingProxy.
+func NewSingleHostReverseProxy(target *url.URL, opts *ReverseProxyOptions) *httputil.ReverseProxy {
+	if opts == nil {
+		opts = &ReverseProxyOptions{}
+	}
+	rt := opts.RoundTripper
+	if rt == nil {
+		rt = &RoundTripper{}
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		if opts.Allow0RTT && req.Method == http.MethodGet && req.ContentLength == 0 {
+			req.Method = MethodGet0RTT
+		}
+	}
+	proxy.Transport = rt
+	proxy.FlushInterval = -1
+	return proxy
+}
+
+// A ReverseProxy is a httputil.ReverseProxy that forwards requests using HTTP/3.
+//
+// In addition, it forwards extended CONNECT requests (RFC 9220), as used by MASQUE, which httputil.ReverseProxy can't handle.
+// After a successful response, the capsules (RFC 9297) sent on the request stream
+// are forwarded in both directions, until the backend ends its response.
+// HTTP datagrams are forwarded in both directions as well, if datagram support is enabled on both the Server
+// serving the proxy and the RoundTripper used to send requests to the backend (EnableDatagrams).
+// The Server has to enable extended CONNECT (EnableExtendedConnect), and the backend has to enable it as well.
+// For tunneled requests, the Director and ModifyResponse are used, but the response is not copied by httputil.ReverseProxy,
+// so its other hooks (e.g. FlushInterval and BufferPool) have no effect.
+type ReverseProxy struct {
+	*httputil.ReverseProxy
+}
+
+var _ http.Handler = &ReverseProxy{}
+
+// NewSingleHostTunnelingProxy returns a ReverseProxy that forwards requests to target using HTTP/3.
+// It is configured in the same way as the proxy returned by NewSingleHostReverseProxy,
+// and additionally forwards extended CONNECT requests.
+func NewSingleHostTunnelingProxy(target *url.URL, opts *ReverseProxyOptions) *ReverseProxy {
+	return &ReverseProxy{ReverseProxy: NewSingleHostReverseProxy(target, opts)}
+}
+
+// ServeHTTP forwards the request to the backend.
+func (p *ReverseProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !isExtendedConnect(req) {
+		p.ReverseProxy.ServeHTTP(w, req)
+		return
+	}
+	p.serveTunnel(w, req)
+}
+
+func (p *ReverseProxy) serveTunnel(w http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	outreq := req.Clone(ctx)
+	outreq.RequestURI = ""
+	p.Director(outreq)
+	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if prior := outreq.Header["X-Forwarded-For"]; len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		outreq.Header.Set("X-Forwarded-For", clientIP)
+	}
+	// The request body is sent while the response body is received.
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	outreq.Body = pr
+	outreq.ContentLength = 0
+
+	transport := p.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	rsp, err := transport.RoundTrip(outreq)
+	if err != nil {
+		p.handleError(w, req, err)
+		return
+	}
+	defer rsp.Body.Close()
+	if p.ModifyResponse != nil {
+		if err := p.ModifyResponse(rsp); err != nil {
+			p.handleError(w, req, err)
+			return
+		}
+	}
+
+	for k, vv := range rsp.Header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(rsp.StatusCode)
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		if _, err := io.Copy(w, rsp.Body); err != nil {
+			p.logf("http3: proxy error: %v", err)
+		}
+		return
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+
+	go func() { pw.CloseWithError(forwardCapsules(pw, req.Body)) }()
+	if dst, ok := w.(Datagrammer); ok {
+		if src, ok := rsp.Body.(Datagrammer); ok {
+			go forwardDatagrams(ctx, dst, src)
+			go forwardDatagrams(ctx, src, dst)
+		}
+	}
+	if err := forwardCapsules(w, rsp.Body); err != nil {
+		p.logf("http3: proxy error: %v", err)
+		// Reset the stream, such that the client doesn't mistake the truncated capsule stream for the end of the tunnel.
+		if ds, ok := w.(DataStreamer); ok {
+			str := ds.DataStream()
+			str.CancelWrite(quic.StreamErrorCode(errorRequestCanceled))
+			str.CancelRead(quic.StreamErrorCode(errorRequestCanceled))
+		}
+	}
+}
+
+func (p *ReverseProxy) handleError(w http.ResponseWriter, req *http.Request, err error) {
+	if p.ErrorHandler != nil {
+		p.ErrorHandler(w, req, err)
+		return
+	}
+	p.logf("http3: proxy error: %v", err)
+	w.WriteHeader(http.StatusBadGateway)
+}
+
+func (p *ReverseProxy) logf(format string, args ...interface{}) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// forwardCapsules copies the capsules read from src to dst, until src ends.
+// dst is flushed after every capsule, if it is a http.Flusher.
+func forwardCapsules(dst io.Writer, src io.Reader) error {
+	r := bufio.NewReader(src)
+	flusher, _ := dst.(http.Flusher)
+	buf := &bytes.Buffer{}
+	for {
+		ct, l, err := parseCapsuleHeader(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		buf.Reset()
+		quicvarint.Write(buf, uint64(ct))
+		quicvarint.Write(buf, l)
+		if _, err := dst.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(dst, r, int64(l)); err != nil {
+			if err == io.EOF {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// forwardDatagrams sends the datagrams received from src to dst, until receiving fails or ctx is canceled.
+func forwardDatagrams(ctx context.Context, dst, src Datagrammer) {
+	for {
+		b, err := src.ReceiveDatagram(ctx)
+		if err != nil {
+			return
+		}
+		// Datagrams are unreliable. A datagram that can't be sent (e.g. because it's too large) is dropped.
+		dst.SendDatagram(b)
+	}
+}
@@ -0,0 +1,280 @@
+package http3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingClient struct {
+	requests []*http.Request
+}
+
+func (c *recordingClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/plain"}},
+		Body:       ioutil.NopCloser(strings.NewReader("foobar")),
+		Request:    req,
+	}, nil
+}
+
+func (c *recordingClient) Close() error { return nil }
+
+type fakeDatagrammer struct {
+	received chan []byte
+	sent     chan []byte
+}
+
+func newFakeDatagrammer() *fakeDatagrammer {
+	return &fakeDatagrammer{received: make(chan []byte, 10), sent: make(chan []byte, 10)}
+}
+
+func (d *fakeDatagrammer) SendDatagram(b []byte) error {
+	d.sent <- b
+	return nil
+}
+
+func (d *fakeDatagrammer) ReceiveDatagram(ctx context.Context) ([]byte, error) {
+	select {
+	case b := <-d.received:
+		return b, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type tunnelResponseWriter struct {
+	*httptest.ResponseRecorder
+	*fakeDatagrammer
+}
+
+type tunnelResponseBody struct {
+	io.ReadCloser
+	*fakeDatagrammer
+}
+
+// tunnelClient responds to every request with the response returned by the respond callback.
+type tunnelClient struct {
+	requests chan *http.Request
+	respond  func(*http.Request) (*http.Response, error)
+}
+
+func (c *tunnelClient) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests <- req
+	return c.respond(req)
+}
+
+func (c *tunnelClient) Close() error { return nil }
+
+var _ = Describe("Reverse Proxy", func() {
+	var (
+		rt     *RoundTripper
+		client *recordingClient
+		target *url.URL
+	)
+
+	BeforeEach(func() {
+		var err error
+		target, err = url.Parse("https://backend.example.com/api")
+		Expect(err).ToNot(HaveOccurred())
+		client = &recordingClient{}
+		rt = &RoundTripper{clients: map[string]roundTripCloser{"backend.example.com:443": client}}
+	})
+
+	It("forwards requests to the target", func() {
+		proxy := NewSingleHostReverseProxy(target, &ReverseProxyOptions{RoundTripper: rt})
+		Expect(proxy.FlushInterval).To(BeNumerically("<", 0))
+		req := httptest.NewRequest(http.MethodPost, "https://proxy.example.com/foo?bar=baz", strings.NewReader("request"))
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(Equal("foobar"))
+		Expect(client.requests).To(HaveLen(1))
+		outreq := client.requests[0]
+		Expect(outreq.Method).To(Equal(http.MethodPost))
+		Expect(outreq.URL.String()).To(Equal("https://backend.example.com/api/foo?bar=baz"))
+		Expect(outreq.Header.Get("X-Forwarded-For")).ToNot(BeEmpty())
+	})
+
+	It("uses a new RoundTripper, if none is set", func() {
+		proxy := NewSingleHostReverseProxy(target, nil)
+		Expect(proxy.Transport).To(BeAssignableToTypeOf(&RoundTripper{}))
+		Expect(proxy.Transport).ToNot(BeIdenticalTo(rt))
+	})
+
+	It("doesn't use 0-RTT by default", func() {
+		proxy := NewSingleHostReverseProxy(target, &ReverseProxyOptions{RoundTripper: rt})
+		proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://proxy.example.com/foo", nil))
+		Expect(client.requests).To(HaveLen(1))
+		Expect(client.requests[0].Method).To(Equal(http.MethodGet))
+	})
+
+	It("forwards GET requests using 0-RTT, if enabled", func() {
+		proxy := NewSingleHostReverseProxy(target, &ReverseProxyOptions{RoundTripper: rt, Allow0RTT: true})
+		proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://proxy.example.com/foo", nil))
+		proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "https://proxy.example.com/foo", nil))
+		proxy.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "https://proxy.example.com/foo", strings.NewReader("body")))
+		Expect(client.requests).To(HaveLen(3))
+		Expect(client.requests[0].Method).To(Equal(MethodGet0RTT))
+		Expect(client.requests[1].Method).To(Equal(http.MethodPost))
+		Expect(client.requests[2].Method).To(Equal(http.MethodGet))
+	})
+	Context("tunneling extended CONNECT requests", func() {
+		var (
+			tunnel     *tunnelClient
+			backendDgs *fakeDatagrammer
+			backendR   *io.PipeReader
+			backendW   *io.PipeWriter
+			clientR    *io.PipeReader
+			clientW    *io.PipeWriter
+			req        *http.Request
+		)
+
+		BeforeEach(func() {
+			backendR, backendW = io.Pipe()
+			clientR, clientW = io.Pipe()
+			backendDgs = newFakeDatagrammer()
+			tunnel = &tunnelClient{
+				requests: make(chan *http.Request, 1),
+				respond: func(req *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: http.StatusOK,
+						Header:     http.Header{"Capsule-Protocol": []string{"?1"}},
+						Body:       &tunnelResponseBody{ReadCloser: backendR, fakeDatagrammer: backendDgs},
+						Request:    req,
+					}, nil
+				},
+			}
+			rt.clients["backend.example.com:443"] = tunnel
+			req = httptest.NewRequest(http.MethodGet, "https://proxy.example.com/masque?h=example.org&p=443", clientR)
+			req.Method = http.MethodConnect
+			req.Proto = "connect-udp"
+		})
+
+		It("forwards capsules and datagrams", func() {
+			proxy := NewSingleHostTunnelingProxy(target, &ReverseProxyOptions{RoundTripper: rt})
+			w := &tunnelResponseWriter{ResponseRecorder: httptest.NewRecorder(), fakeDatagrammer: newFakeDatagrammer()}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				proxy.ServeHTTP(w, req)
+			}()
+
+			var outreq *http.Request
+			Eventually(tunnel.requests).Should(Receive(&outreq))
+			Expect(outreq.Method).To(Equal(http.MethodConnect))
+			Expect(outreq.Proto).To(Equal("connect-udp"))
+			Expect(isExtendedConnect(outreq)).To(BeTrue())
+			Expect(outreq.URL.String()).To(Equal("https://backend.example.com/api/masque?h=example.org&p=443"))
+			Expect(outreq.Header.Get("X-Forwarded-For")).To(Equal("192.0.2.1"))
+
+			// capsules from the client to the backend
+			go func() {
+				defer GinkgoRecover()
+				Expect(WriteCapsule(quicvarint.NewWriter(clientW), 1337, []byte("foobar"))).To(Succeed())
+			}()
+			ct, r, err := ParseCapsule(quicvarint.NewReader(outreq.Body))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ct).To(BeEquivalentTo(1337))
+			val, err := ioutil.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(val)).To(Equal("foobar"))
+
+			// datagrams in both directions
+			w.received <- []byte("from client")
+			Eventually(backendDgs.sent).Should(Receive(Equal([]byte("from client"))))
+			backendDgs.received <- []byte("from backend")
+			Eventually(w.sent).Should(Receive(Equal([]byte("from backend"))))
+
+			// capsules from the backend to the client, until the backend ends the response
+			Expect(WriteCapsule(quicvarint.NewWriter(backendW), 42, []byte("raboof"))).To(Succeed())
+			Expect(backendW.Close()).To(Succeed())
+			Eventually(done).Should(BeClosed())
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Header().Get("Capsule-Protocol")).To(Equal("?1"))
+			Expect(w.Flushed).To(BeTrue())
+			ct, r, err = ParseCapsule(bytes.NewReader(w.Body.Bytes()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ct).To(BeEquivalentTo(42))
+			val, err = ioutil.ReadAll(r)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(val)).To(Equal("raboof"))
+			// the request stream to the backend is closed when the tunnel ends
+			_, err = outreq.Body.Read([]byte{0})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("forwards unsuccessful responses", func() {
+			tunnel.respond = func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusForbidden,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(strings.NewReader("forbidden")),
+					Request:    req,
+				}, nil
+			}
+			proxy := NewSingleHostTunnelingProxy(target, &ReverseProxyOptions{RoundTripper: rt})
+			w := httptest.NewRecorder()
+			proxy.ServeHTTP(w, req)
+			Expect(w.Code).To(Equal(http.StatusForbidden))
+			Expect(w.Body.String()).To(Equal("forbidden"))
+		})
+
+		It("calls the ErrorHandler if the request to the backend fails", func() {
+			tunnel.respond = func(*http.Request) (*http.Response, error) { return nil, errors.New("backend error") }
+			proxy := NewSingleHostTunnelingProxy(target, &ReverseProxyOptions{RoundTripper: rt})
+			var handledErr error
+			proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+				handledErr = err
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			w := httptest.NewRecorder()
+			proxy.ServeHTTP(w, req)
+			Expect(handledErr).To(MatchError("backend error"))
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		})
+
+		It("forwards CONNECT requests that are not extended CONNECT requests as other requests", func() {
+			proxy := NewSingleHostTunnelingProxy(target, &ReverseProxyOptions{RoundTripper: rt})
+			req.Proto = "HTTP/1.1"
+			Expect(backendW.Close()).To(Succeed())
+			w := httptest.NewRecorder()
+			proxy.ServeHTTP(w, req)
+			Expect(tunnel.requests).To(Receive())
+		})
+	})
+
+	Context("forwarding capsules", func() {
+		It("forwards Capsules", func() {
+			var src bytes.Buffer
+			Expect(WriteCapsule(&src, 1337, []byte("foo"))).To(Succeed())
+			Expect(WriteCapsule(&src, 42, nil)).To(Succeed())
+			Expect(WriteCapsule(&src, quicvarint.Max, bytes.Repeat([]byte("b"), 10000))).To(Succeed())
+			data := src.Bytes()
+			var dst bytes.Buffer
+			Expect(forwardCapsules(&dst, bytes.NewReader(data))).To(Succeed())
+			Expect(dst.Bytes()).To(Equal(data))
+		})
+
+		It("errors on truncated Capsules", func() {
+			var src bytes.Buffer
+			Expect(WriteCapsule(&src, 1337, []byte("foobar"))).To(Succeed())
+			data := src.Bytes()
+			Expect(forwardCapsules(&bytes.Buffer{}, bytes.NewReader(data[:len(data)-1]))).To(MatchError(io.ErrUnexpectedEOF))
+		})
+	})
+})
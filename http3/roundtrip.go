@@ -39,6 +39,8 @@ type RoundTripper struct {
 
 	// QuicConfig is the quic.Config used for dialing new connections.
 	// If nil, reasonable default values will be used.
+	// If MaxIncomingUniStreams is set, it must allow the server to open the control and the QPACK streams,
+	// i.e. it must be at least 3.
 	QuicConfig *quic.Config
 
 	// Enable support for HTTP/3 datagrams.
@@ -46,6 +48,30 @@ type RoundTripper struct {
 	// See https://www.ietf.org/archive/id/draft-schinazi-masque-h3-datagram-02.html.
 	EnableDatagrams bool
 
+	// AdditionalSettings specifies additional HTTP/3 settings that are sent in the SETTINGS frame.
+	// This can be used to negotiate HTTP/3 extensions.
+	// It is invalid to specify any of the settings defined by HTTP/3, QPACK and the HTTP/3 datagram draft.
+	AdditionalSettings map[uint64]uint64
+
+	// OnPeerSettings is called when the SETTINGS frame of the server is received.
+	// It is called with all settings contained in the frame, including the ones handled by this package.
+	// H3_DATAGRAM (0x276) is only contained if the server enabled datagram support.
+	OnPeerSettings func(sess quic.EarlySession, settings map[uint64]uint64)
+
+	// UnknownFrameHandler is called for frames of unknown types received on request streams.
+	// If nil, these frames are skipped.
+	UnknownFrameHandler UnknownFrameHandler
+
+	// StrictHeaderValidation enables strict validation of the header fields of responses, according to section 4 of RFC 9114.
+	// This checks for forbidden characters in field names and values, connection-specific header fields
+	// (including Transfer-Encoding), conflicting Content-Length header fields, and the presence and ordering of pseudo-headers.
+	// Malformed responses are rejected with an H3_MESSAGE_ERROR.
+	StrictHeaderValidation bool
+
+	// OnMalformedMessage is called for every response that is rejected because it is malformed.
+	// It can be used to collect metrics about rejected messages.
+	OnMalformedMessage func(err error)
+
 	// Dial specifies an optional dial function for creating QUIC
 	// connections for requests.
 	// If Dial is nil, quic.DialAddrEarly will be used.
@@ -141,9 +167,14 @@ func (r *RoundTripper) getClient(hostname string, onlyCached bool) (http.RoundTr
 			hostname,
 			r.TLSClientConfig,
 			&roundTripperOpts{
-				EnableDatagram:     r.EnableDatagrams,
-				DisableCompression: r.DisableCompression,
-				MaxHeaderBytes:     r.MaxResponseHeaderBytes,
+				EnableDatagram:         r.EnableDatagrams,
+				DisableCompression:     r.DisableCompression,
+				MaxHeaderBytes:         r.MaxResponseHeaderBytes,
+				AdditionalSettings:     r.AdditionalSettings,
+				OnPeerSettings:         r.OnPeerSettings,
+				UnknownFrameHandler:    r.UnknownFrameHandler,
+				StrictHeaderValidation: r.StrictHeaderValidation,
+				OnMalformedMessage:     r.OnMalformedMessage,
 			},
 			r.QuicConfig,
 			r.Dial,
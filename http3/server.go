@@ -85,6 +85,8 @@ type Server struct {
 
 	// By providing a quic.Config, it is possible to set parameters of the QUIC connection.
 	// If nil, it uses reasonable default values.
+	// If MaxIncomingUniStreams is set, it must allow the client to open the control and the QPACK streams,
+	// i.e. it must be at least 3.
 	QuicConfig *quic.Config
 
 	// Enable support for HTTP/3 datagrams.
@@ -92,6 +94,36 @@ type Server struct {
 	// See https://www.ietf.org/archive/id/draft-schinazi-masque-h3-datagram-02.html.
 	EnableDatagrams bool
 
+	// EnableExtendedConnect enables support for the extended CONNECT method, see RFC 9220.
+	// If set to true, SETTINGS_ENABLE_CONNECT_PROTOCOL (0x8) is sent in the SETTINGS frame.
+	// The protocol of an extended CONNECT request (the :protocol pseudo-header) is carried in Request.Proto.
+	// Extended CONNECT requests are rejected with an H3_MESSAGE_ERROR if this is not enabled.
+	EnableExtendedConnect bool
+
+	// AdditionalSettings specifies additional HTTP/3 settings that are sent in the SETTINGS frame.
+	// This can be used to negotiate HTTP/3 extensions.
+	// It is invalid to specify any of the settings defined by HTTP/3, QPACK and the HTTP/3 datagram draft.
+	AdditionalSettings map[uint64]uint64
+
+	// OnPeerSettings is called when the SETTINGS frame of the client is received.
+	// It is called with all settings contained in the frame, including the ones handled by this package.
+	// H3_DATAGRAM (0x276) is only contained if the client enabled datagram support.
+	OnPeerSettings func(sess quic.EarlySession, settings map[uint64]uint64)
+
+	// UnknownFrameHandler is called for frames of unknown types received on request streams.
+	// If nil, these frames are skipped.
+	UnknownFrameHandler UnknownFrameHandler
+
+	// StrictHeaderValidation enables strict validation of the header fields of requests, according to section 4 of RFC 9114.
+	// This checks for forbidden characters in field names and values, connection-specific header fields
+	// (including Transfer-Encoding), conflicting Content-Length header fields, and the presence and ordering of pseudo-headers.
+	// Malformed requests are rejected with an H3_MESSAGE_ERROR.
+	StrictHeaderValidation bool
+
+	// OnMalformedMessage is called for every request that is rejected because it is malformed.
+	// It can be used to collect metrics about rejected messages.
+	OnMalformedMessage func(err error)
+
 	port uint32 // used atomically
 
 	mutex     sync.Mutex
@@ -140,6 +172,12 @@ func (s *Server) serveImpl(tlsConf *tls.Config, conn net.PacketConn) error {
 	if s.Server == nil {
 		return errors.New("use of http3.Server without http.Server")
 	}
+	if err := validateUniStreamLimit(s.QuicConfig); err != nil {
+		return err
+	}
+	if err := validateAdditionalSettings(s.AdditionalSettings); err != nil {
+		return err
+	}
 	s.loggerOnce.Do(func() {
 		s.logger = utils.DefaultLogger.WithPrefix("server")
 	})
@@ -236,9 +274,14 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 	}
 	buf := &bytes.Buffer{}
 	quicvarint.Write(buf, streamTypeControlStream) // stream type
-	(&settingsFrame{Datagram: s.EnableDatagrams}).Write(buf)
+	(&settingsFrame{Datagram: s.EnableDatagrams, other: s.settings()}).Write(buf)
 	str.Write(buf.Bytes())
 
+	var datagrams *datagramDemultiplexer
+	if s.EnableDatagrams {
+		datagrams = newDatagramDemultiplexer(sess, s.logger)
+	}
+
 	go s.handleUnidirectionalStreams(sess)
 
 	// Process all requests immediately.
@@ -250,7 +293,7 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 			return
 		}
 		go func() {
-			rerr := s.handleRequest(sess, str, decoder, func() {
+			rerr := s.handleRequest(sess, str, datagrams, decoder, func() {
 				sess.CloseWithError(quic.ApplicationErrorCode(errorFrameUnexpected), "")
 			})
 			if rerr.err != nil || rerr.streamErr != 0 || rerr.connErr != 0 {
@@ -273,6 +316,7 @@ func (s *Server) handleConn(sess quic.EarlySession) {
 }
 
 func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
+	var uniStreams uniStreamTracker
 	for {
 		str, err := sess.AcceptUniStream(context.Background())
 		if err != nil {
@@ -286,15 +330,21 @@ func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
 				s.logger.Debugf("reading stream type on stream %d failed: %s", str.StreamID(), err)
 				return
 			}
-			// We're only interested in the control stream here.
+			if err := uniStreams.Open(streamType); err != nil {
+				sess.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), err.Error())
+				return
+			}
+			closeSession := func(code errorCode, reason string) {
+				sess.CloseWithError(quic.ApplicationErrorCode(code), reason)
+			}
 			switch streamType {
 			case streamTypeControlStream:
 			case streamTypeQPACKEncoderStream, streamTypeQPACKDecoderStream:
 				// Our QPACK implementation doesn't use the dynamic table yet.
-				// TODO: check that only one stream of each type is opened.
+				handleQPACKStream(str, closeSession)
 				return
 			case streamTypePushStream: // only the server can push
-				sess.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), "")
+				sess.CloseWithError(quic.ApplicationErrorCode(errorStreamCreationError), "client opened a push stream")
 				return
 			default:
 				str.CancelRead(quic.StreamErrorCode(errorStreamCreationError))
@@ -310,15 +360,17 @@ func (s *Server) handleUnidirectionalStreams(sess quic.EarlySession) {
 				sess.CloseWithError(quic.ApplicationErrorCode(errorMissingSettings), "")
 				return
 			}
-			if !sf.Datagram {
-				return
+			if s.OnPeerSettings != nil {
+				s.OnPeerSettings(sess, sf.Settings())
 			}
 			// If datagram support was enabled on our side as well as on the client side,
 			// we can expect it to have been negotiated both on the transport and on the HTTP/3 layer.
 			// Note: ConnectionState() will block until the handshake is complete (relevant when using 0-RTT).
-			if s.EnableDatagrams && !sess.ConnectionState().SupportsDatagrams {
+			if sf.Datagram && s.EnableDatagrams && !sess.ConnectionState().SupportsDatagrams {
 				sess.CloseWithError(quic.ApplicationErrorCode(errorSettingsError), "missing QUIC Datagram support")
+				return
 			}
+			handleControlStreamFrames(str, closeSession)
 		}(str)
 	}
 }
@@ -330,8 +382,26 @@ func (s *Server) maxHeaderBytes() uint64 {
 	return uint64(s.Server.MaxHeaderBytes)
 }
 
-func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpack.Decoder, onFrameError func()) requestError {
-	frame, err := parseNextFrame(str)
+// settings returns the settings sent in the SETTINGS frame, except for H3_DATAGRAM.
+func (s *Server) settings() map[uint64]uint64 {
+	if !s.EnableExtendedConnect {
+		return s.AdditionalSettings
+	}
+	settings := make(map[uint64]uint64, len(s.AdditionalSettings)+1)
+	for id, val := range s.AdditionalSettings {
+		settings[id] = val
+	}
+	settings[settingExtendedConnect] = 1
+	return settings
+}
+
+func (s *Server) handleRequest(sess quic.Session, str quic.Stream, datagrams *datagramDemultiplexer, decoder *qpack.Decoder, onFrameError func()) requestError {
+	var onUnknownFrame unknownFrameHandlerFunc
+	if s.UnknownFrameHandler != nil {
+		// the request is not known before the HEADERS frame is parsed
+		onUnknownFrame = func(ft FrameType, r io.Reader) error { return s.UnknownFrameHandler(nil, ft, r) }
+	}
+	frame, err := parseNextFrameWithHandler(str, onUnknownFrame)
 	if err != nil {
 		return newStreamError(errorRequestIncomplete, err)
 	}
@@ -351,14 +421,26 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 		// TODO: use the right error code
 		return newConnError(errorGeneralProtocolError, err)
 	}
+	if s.StrictHeaderValidation {
+		if err := validateRequestHeaders(hfs); err != nil {
+			if s.OnMalformedMessage != nil {
+				s.OnMalformedMessage(err)
+			}
+			return newStreamError(errorMessageError, err)
+		}
+	}
 	req, err := requestFromHeaders(hfs)
 	if err != nil {
 		// TODO: use the right error code
 		return newStreamError(errorGeneralProtocolError, err)
 	}
+	if isExtendedConnect(req) && !s.EnableExtendedConnect && s.AdditionalSettings[settingExtendedConnect] != 1 {
+		return newStreamError(errorMessageError, errors.New("http3: extended CONNECT not enabled"))
+	}
 
 	req.RemoteAddr = sess.RemoteAddr().String()
-	req.Body = newRequestBody(str, onFrameError)
+	body := newRequestBody(str, onFrameError)
+	req.Body = body
 
 	if s.logger.Debug() {
 		s.logger.Infof("%s %s%s, on stream %d", req.Method, req.Host, req.RequestURI, str.StreamID())
@@ -370,7 +452,14 @@ func (s *Server) handleRequest(sess quic.Session, str quic.Stream, decoder *qpac
 	ctx = context.WithValue(ctx, ServerContextKey, s)
 	ctx = context.WithValue(ctx, http.LocalAddrContextKey, sess.LocalAddr())
 	req = req.WithContext(ctx)
+	if s.UnknownFrameHandler != nil {
+		body.onUnknownFrame = func(ft FrameType, r io.Reader) error { return s.UnknownFrameHandler(req, ft, r) }
+	}
 	r := newResponseWriter(str, s.logger)
+	if datagrams != nil && req.Method == http.MethodConnect {
+		r.datagrams = datagrams.register(str.StreamID())
+		defer r.datagrams.close()
+	}
 	defer func() {
 		if !r.usedDataStream() {
 			r.Flush()
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"time"
@@ -138,7 +139,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			Expect(s.handleRequest(sess, str, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(s.handleRequest(sess, str, nil, qpackDecoder, nil)).To(Equal(requestError{}))
 			var req *http.Request
 			Eventually(requestChan).Should(Receive(&req))
 			Expect(req.Host).To(Equal("www.example.com"))
@@ -146,6 +147,72 @@ var _ = Describe("Server", func() {
 			Expect(req.Context().Value(ServerContextKey)).To(Equal(s))
 		})
 
+		It("passes unknown frames received before the HEADERS frame to the UnknownFrameHandler", func() {
+			var frameTypes []FrameType
+			s.UnknownFrameHandler = func(r *http.Request, ft FrameType, rd io.Reader) error {
+				Expect(r).To(BeNil())
+				frameTypes = append(frameTypes, ft)
+				data, err := ioutil.ReadAll(rd)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foo")))
+				return nil
+			}
+			s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+			buf := &bytes.Buffer{}
+			Expect(writeExtensionFrame(buf, 0x1337, []byte("foo"))).To(Succeed())
+			buf.Write(encodeRequest(exampleGetRequest))
+			setRequest(buf.Bytes())
+			str.EXPECT().Context().Return(reqContext)
+			str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+				return len(p), nil
+			}).AnyTimes()
+			str.EXPECT().CancelRead(gomock.Any())
+
+			Expect(s.handleRequest(sess, str, nil, qpackDecoder, nil)).To(Equal(requestError{}))
+			Expect(frameTypes).To(Equal([]FrameType{0x1337}))
+		})
+
+		It("resets the stream if the UnknownFrameHandler errors before the HEADERS frame", func() {
+			testErr := errors.New("unknown frame error")
+			s.UnknownFrameHandler = func(*http.Request, FrameType, io.Reader) error { return testErr }
+
+			buf := &bytes.Buffer{}
+			Expect(writeExtensionFrame(buf, 0x1337, []byte("foo"))).To(Succeed())
+			buf.Write(encodeRequest(exampleGetRequest))
+			setRequest(buf.Bytes())
+
+			Expect(s.handleRequest(sess, str, nil, qpackDecoder, nil)).To(Equal(newStreamError(errorRequestIncomplete, testErr)))
+		})
+
+		It("rejects malformed requests, if strict header validation is enabled", func() {
+			s.StrictHeaderValidation = true
+			var rejected []error
+			s.OnMalformedMessage = func(err error) { rejected = append(rejected, err) }
+			s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) { Fail("didn't expect the handler to be called") })
+			headerBuf := &bytes.Buffer{}
+			enc := qpack.NewEncoder(headerBuf)
+			for _, hf := range []qpack.HeaderField{
+				{Name: ":method", Value: "POST"},
+				{Name: ":scheme", Value: "https"},
+				{Name: ":authority", Value: "www.example.com"},
+				{Name: ":path", Value: "/"},
+				{Name: "content-length", Value: "6"},
+				{Name: "content-length", Value: "100"},
+			} {
+				Expect(enc.WriteField(hf)).To(Succeed())
+			}
+			buf := &bytes.Buffer{}
+			(&headersFrame{Length: uint64(headerBuf.Len())}).Write(buf)
+			buf.Write(headerBuf.Bytes())
+			setRequest(buf.Bytes())
+			rerr := s.handleRequest(sess, str, nil, qpackDecoder, nil)
+			Expect(rerr.streamErr).To(Equal(errorMessageError))
+			Expect(rerr.connErr).To(BeZero())
+			Expect(rejected).To(HaveLen(1))
+			Expect(rejected[0]).To(MatchError("conflicting Content-Length header fields: 6 and 100"))
+		})
+
 		It("returns 200 with an empty handler", func() {
 			s.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
 
@@ -155,7 +222,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"200"}))
@@ -172,7 +239,7 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write(gomock.Any()).DoAndReturn(responseBuf.Write).AnyTimes()
 			str.EXPECT().CancelRead(gomock.Any())
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			hfs := decodeHeader(responseBuf)
 			Expect(hfs).To(HaveKeyWithValue(":status", []string{"500"}))
@@ -189,15 +256,154 @@ var _ = Describe("Server", func() {
 			str.EXPECT().Write([]byte("foobar"))
 			// don't EXPECT CancelRead()
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 		})
 
+		It("sends additional settings", func() {
+			s.AdditionalSettings = map[uint64]uint64{0x1337: 42}
+			sess := mockquic.NewMockEarlySession(mockCtrl)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) {
+				defer GinkgoRecover()
+				r := bytes.NewReader(b)
+				streamType, err := quicvarint.Read(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+				f, err := parseNextFrame(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				Expect(f.(*settingsFrame).other).To(Equal(map[uint64]uint64{0x1337: 42}))
+			})
+			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).MaxTimes(1)
+			sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+			s.handleConn(sess)
+		})
+
+		It("sends SETTINGS_ENABLE_CONNECT_PROTOCOL, if extended CONNECT is enabled", func() {
+			s.AdditionalSettings = map[uint64]uint64{0x1337: 42}
+			s.EnableExtendedConnect = true
+			sess := mockquic.NewMockEarlySession(mockCtrl)
+			controlStr := mockquic.NewMockStream(mockCtrl)
+			controlStr.EXPECT().Write(gomock.Any()).Do(func(b []byte) {
+				defer GinkgoRecover()
+				r := bytes.NewReader(b)
+				streamType, err := quicvarint.Read(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(streamType).To(BeEquivalentTo(streamTypeControlStream))
+				f, err := parseNextFrame(r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(f).To(BeAssignableToTypeOf(&settingsFrame{}))
+				Expect(f.(*settingsFrame).other).To(Equal(map[uint64]uint64{0x1337: 42, settingExtendedConnect: 1}))
+			})
+			sess.EXPECT().OpenUniStream().Return(controlStr, nil)
+			sess.EXPECT().AcceptUniStream(gomock.Any()).Return(nil, errors.New("done")).MaxTimes(1)
+			sess.EXPECT().AcceptStream(gomock.Any()).Return(nil, errors.New("done"))
+			s.handleConn(sess)
+			Expect(s.AdditionalSettings).To(Equal(map[uint64]uint64{0x1337: 42}))
+		})
+
+		Context("extended CONNECT", func() {
+			var connectRequest *http.Request
+
+			BeforeEach(func() {
+				var err error
+				connectRequest, err = http.NewRequest(http.MethodConnect, "https://www.example.com/masque", nil)
+				Expect(err).ToNot(HaveOccurred())
+				connectRequest.Proto = "connect-udp"
+			})
+
+			It("rejects extended CONNECT requests, if extended CONNECT is not enabled", func() {
+				s.Handler = http.HandlerFunc(func(http.ResponseWriter, *http.Request) { Fail("didn't expect the handler to be called") })
+				setRequest(encodeRequest(connectRequest))
+				rerr := s.handleRequest(sess, str, nil, qpackDecoder, nil)
+				Expect(rerr.streamErr).To(Equal(errorMessageError))
+				Expect(rerr.err).To(MatchError("http3: extended CONNECT not enabled"))
+			})
+
+			It("handles extended CONNECT requests", func() {
+				s.EnableExtendedConnect = true
+				requestChan := make(chan *http.Request, 1)
+				s.Handler = http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+					requestChan <- r
+				})
+				setRequest(encodeRequest(connectRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+
+				Expect(s.handleRequest(sess, str, nil, qpackDecoder, nil)).To(Equal(requestError{}))
+				var req *http.Request
+				Eventually(requestChan).Should(Receive(&req))
+				Expect(req.Method).To(Equal(http.MethodConnect))
+				Expect(req.Proto).To(Equal("connect-udp"))
+				Expect(req.Host).To(Equal("www.example.com"))
+				Expect(req.URL.Path).To(Equal("/masque"))
+			})
+
+			It("allows the handler to use datagrams for CONNECT requests", func() {
+				s.EnableExtendedConnect = true
+				sessClosed := make(chan struct{})
+				defer close(sessClosed)
+				sess.EXPECT().ReceiveMessage().DoAndReturn(func() ([]byte, error) {
+					<-sessClosed
+					return nil, errors.New("session closed")
+				}).MaxTimes(1)
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, 2)
+				buf.Write([]byte("foobar"))
+				sess.EXPECT().SendMessage(buf.Bytes())
+				demux := newDatagramDemultiplexer(sess, utils.DefaultLogger)
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					defer GinkgoRecover()
+					Expect(w.(Datagrammer).SendDatagram([]byte("foobar"))).To(Succeed())
+				})
+				setRequest(encodeRequest(connectRequest))
+				str.EXPECT().StreamID().Return(quic.StreamID(8)).AnyTimes()
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+
+				Expect(s.handleRequest(sess, str, demux, qpackDecoder, nil)).To(Equal(requestError{}))
+				// the stream is unregistered when the handler returns
+				demux.mutex.Lock()
+				Expect(demux.streams).To(BeEmpty())
+				demux.mutex.Unlock()
+			})
+
+			It("doesn't allow the handler to use datagrams for other requests", func() {
+				done := make(chan struct{})
+				s.Handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					defer GinkgoRecover()
+					defer close(done)
+					Expect(w.(Datagrammer).SendDatagram([]byte("foobar"))).To(MatchError(errNoDatagrams))
+				})
+				setRequest(encodeRequest(exampleGetRequest))
+				str.EXPECT().Context().Return(reqContext)
+				str.EXPECT().Write(gomock.Any()).DoAndReturn(func(p []byte) (int, error) {
+					return len(p), nil
+				}).AnyTimes()
+				str.EXPECT().CancelRead(gomock.Any())
+
+				Expect(s.handleRequest(sess, str, newDatagramDemultiplexer(sess, utils.DefaultLogger), qpackDecoder, nil)).To(Equal(requestError{}))
+				Expect(done).To(BeClosed())
+			})
+		})
+
 		Context("control stream handling", func() {
-			var sess *mockquic.MockEarlySession
+			var (
+				sess        *mockquic.MockEarlySession
+				streamsDone chan struct{}
+			)
 			testDone := make(chan struct{})
 
 			BeforeEach(func() {
+				streamsDone = make(chan struct{})
 				sess = mockquic.NewMockEarlySession(mockCtrl)
 				controlStr := mockquic.NewMockStream(mockCtrl)
 				controlStr.EXPECT().Write(gomock.Any())
@@ -207,14 +413,17 @@ var _ = Describe("Server", func() {
 				sess.EXPECT().LocalAddr().AnyTimes()
 			})
 
-			AfterEach(func() { testDone <- struct{}{} })
+			AfterEach(func() {
+				testDone <- struct{}{}
+				close(streamsDone)
+			})
 
 			It("parses the SETTINGS frame", func() {
 				buf := &bytes.Buffer{}
 				quicvarint.Write(buf, streamTypeControlStream)
 				(&settingsFrame{}).Write(buf)
 				controlStr := mockquic.NewMockStream(mockCtrl)
-				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
 				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 					return controlStr, nil
 				})
@@ -237,7 +446,7 @@ var _ = Describe("Server", func() {
 					buf := &bytes.Buffer{}
 					quicvarint.Write(buf, streamType)
 					str := mockquic.NewMockStream(mockCtrl)
-					str.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+					str.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
 
 					sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
 						return str, nil
@@ -343,6 +552,73 @@ var _ = Describe("Server", func() {
 				Eventually(done).Should(BeClosed())
 			})
 
+			It("calls the OnPeerSettings callback", func() {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, streamTypeControlStream)
+				(&settingsFrame{Datagram: true, other: map[uint64]uint64{0x1337: 42}}).Write(buf)
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(controlStr, nil)
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				received := make(chan map[uint64]uint64, 1)
+				s.OnPeerSettings = func(ps quic.EarlySession, settings map[uint64]uint64) {
+					defer GinkgoRecover()
+					Expect(ps).To(Equal(sess))
+					received <- settings
+				}
+				s.handleConn(sess)
+				Eventually(received).Should(Receive(Equal(map[uint64]uint64{0x1337: 42, settingDatagram: 1})))
+			})
+
+			It("errors when the client closes the control stream", func() {
+				buf := &bytes.Buffer{}
+				quicvarint.Write(buf, streamTypeControlStream)
+				(&settingsFrame{}).Write(buf)
+				controlStr := mockquic.NewMockStream(mockCtrl)
+				controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(buf.Read).AnyTimes()
+				sess.EXPECT().AcceptUniStream(gomock.Any()).Return(controlStr, nil)
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				done := make(chan struct{})
+				sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorClosedCriticalStream))
+					Expect(reason).To(Equal("control stream closed"))
+					close(done)
+				})
+				s.handleConn(sess)
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("errors when the client opens two control streams", func() {
+				for i := 0; i < 2; i++ {
+					buf := &bytes.Buffer{}
+					quicvarint.Write(buf, streamTypeControlStream)
+					(&settingsFrame{}).Write(buf)
+					controlStr := mockquic.NewMockStream(mockCtrl)
+					controlStr.EXPECT().Read(gomock.Any()).DoAndReturn(blockingRead(buf, streamsDone)).AnyTimes()
+					sess.EXPECT().AcceptUniStream(gomock.Any()).Return(controlStr, nil)
+				}
+				sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+					<-testDone
+					return nil, errors.New("test done")
+				})
+				done := make(chan struct{})
+				sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(code quic.ApplicationErrorCode, reason string) {
+					defer GinkgoRecover()
+					Expect(code).To(BeEquivalentTo(errorStreamCreationError))
+					Expect(reason).To(Equal("duplicate control stream"))
+					close(done)
+				})
+				s.handleConn(sess)
+				Eventually(done).Should(BeClosed())
+			})
+
 			It("errors when the client advertises datagram support (and we enabled support for it)", func() {
 				s.EnableDatagrams = true
 				buf := &bytes.Buffer{}
@@ -515,7 +791,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -538,7 +814,7 @@ var _ = Describe("Server", func() {
 			}).AnyTimes()
 			str.EXPECT().CancelRead(quic.StreamErrorCode(errorNoError))
 
-			serr := s.handleRequest(sess, str, qpackDecoder, nil)
+			serr := s.handleRequest(sess, str, nil, qpackDecoder, nil)
 			Expect(serr.err).ToNot(HaveOccurred())
 			Eventually(handlerCalled).Should(BeClosed())
 		})
@@ -714,6 +990,16 @@ var _ = Describe("Server", func() {
 			ExpectWithOffset(1, c.NextProtos).To(Equal([]string{nextProtoH3}))
 		}
 
+		It("errors if the quic.Config doesn't allow the client to open the control and QPACK streams", func() {
+			s.QuicConfig = &quic.Config{MaxIncomingUniStreams: -1}
+			Expect(s.ListenAndServe()).To(MatchError("http3: QuicConfig.MaxIncomingUniStreams must be at least 3 (for the control and the QPACK streams), is -1"))
+		})
+
+		It("errors if the additional settings contain a setting defined by HTTP/3", func() {
+			s.AdditionalSettings = map[uint64]uint64{settingDatagram: 1}
+			Expect(s.ListenAndServe()).To(MatchError("http3: setting 0x276 can't be used as an additional setting"))
+		})
+
 		It("uses the quic.Config to start the QUIC server", func() {
 			conf := &quic.Config{HandshakeIdleTimeout: time.Nanosecond}
 			var receivedConf *quic.Config
@@ -0,0 +1,98 @@
+package http3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// numCriticalUniStreams is the number of unidirectional streams that every HTTP/3 endpoint opens:
+// the control stream, the QPACK encoder stream and the QPACK decoder stream.
+const numCriticalUniStreams = 3
+
+// validateUniStreamLimit checks that the quic.Config allows the peer to open
+// the unidirectional streams required by HTTP/3.
+func validateUniStreamLimit(conf *quic.Config) error {
+	if conf == nil || conf.MaxIncomingUniStreams == 0 {
+		return nil
+	}
+	if conf.MaxIncomingUniStreams < numCriticalUniStreams {
+		return fmt.Errorf("http3: QuicConfig.MaxIncomingUniStreams must be at least %d (for the control and the QPACK streams), is %d", numCriticalUniStreams, conf.MaxIncomingUniStreams)
+	}
+	return nil
+}
+
+// A uniStreamTracker makes sure that the peer opens every critical unidirectional stream at most once.
+type uniStreamTracker struct {
+	mutex  sync.Mutex
+	opened map[uint64]struct{}
+}
+
+// Open registers a unidirectional stream opened by the peer.
+// It returns an error if the peer already opened a control or QPACK stream of the same type.
+func (t *uniStreamTracker) Open(streamType uint64) error {
+	var name string
+	switch streamType {
+	case streamTypeControlStream:
+		name = "control stream"
+	case streamTypeQPACKEncoderStream:
+		name = "QPACK encoder stream"
+	case streamTypeQPACKDecoderStream:
+		name = "QPACK decoder stream"
+	default:
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.opened == nil {
+		t.opened = make(map[uint64]struct{})
+	}
+	if _, ok := t.opened[streamType]; ok {
+		return fmt.Errorf("duplicate %s", name)
+	}
+	t.opened[streamType] = struct{}{}
+	return nil
+}
+
+// isStreamClosed says if a read error means that the peer closed or reset the stream.
+// Other errors are returned when the session is closed.
+func isStreamClosed(err error) bool {
+	var streamErr *quic.StreamError
+	return err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &streamErr)
+}
+
+// handleControlStreamFrames reads the frames that follow the SETTINGS frame on the control stream.
+// The frames we don't handle (e.g. GOAWAY) are skipped, frames that must not be sent on the control stream are rejected.
+// The control stream is a critical stream: it must not be closed by the peer.
+func handleControlStreamFrames(str quic.ReceiveStream, closeSession func(errorCode, string)) {
+	// parseNextFrame skips the frames we don't handle
+	f, err := parseNextFrame(str)
+	if err != nil {
+		if isStreamClosed(err) {
+			closeSession(errorClosedCriticalStream, "control stream closed")
+		}
+		return
+	}
+	switch f.(type) {
+	case *settingsFrame:
+		closeSession(errorFrameUnexpected, "duplicate SETTINGS frame")
+	case *dataFrame:
+		closeSession(errorFrameUnexpected, "DATA frame on the control stream")
+	case *headersFrame:
+		closeSession(errorFrameUnexpected, "HEADERS frame on the control stream")
+	}
+}
+
+// handleQPACKStream discards the data received on a QPACK stream, since we don't use the dynamic table.
+// The QPACK streams are critical streams: they must not be closed by the peer.
+func handleQPACKStream(str quic.ReceiveStream, closeSession func(errorCode, string)) {
+	if _, err := io.Copy(ioutil.Discard, str); isStreamClosed(err) {
+		closeSession(errorClosedCriticalStream, "QPACK stream closed")
+	}
+}
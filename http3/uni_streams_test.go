@@ -0,0 +1,137 @@
+package http3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unidirectional Streams", func() {
+	It("validates the stream limit", func() {
+		Expect(validateUniStreamLimit(nil)).To(Succeed())
+		Expect(validateUniStreamLimit(&quic.Config{})).To(Succeed())
+		Expect(validateUniStreamLimit(&quic.Config{MaxIncomingUniStreams: 3})).To(Succeed())
+		Expect(validateUniStreamLimit(&quic.Config{MaxIncomingUniStreams: 2})).To(MatchError("http3: QuicConfig.MaxIncomingUniStreams must be at least 3 (for the control and the QPACK streams), is 2"))
+		Expect(validateUniStreamLimit(&quic.Config{MaxIncomingUniStreams: -1})).To(HaveOccurred())
+	})
+
+	It("allows the peer to open every critical stream once", func() {
+		var t uniStreamTracker
+		Expect(t.Open(streamTypeControlStream)).To(Succeed())
+		Expect(t.Open(streamTypeQPACKEncoderStream)).To(Succeed())
+		Expect(t.Open(streamTypeQPACKDecoderStream)).To(Succeed())
+		Expect(t.Open(streamTypeControlStream)).To(MatchError("duplicate control stream"))
+		Expect(t.Open(streamTypeQPACKEncoderStream)).To(MatchError("duplicate QPACK encoder stream"))
+		Expect(t.Open(streamTypeQPACKDecoderStream)).To(MatchError("duplicate QPACK decoder stream"))
+	})
+
+	It("ignores other stream types", func() {
+		var t uniStreamTracker
+		Expect(t.Open(streamTypePushStream)).To(Succeed())
+		Expect(t.Open(streamTypePushStream)).To(Succeed())
+		Expect(t.Open(1337)).To(Succeed())
+		Expect(t.Open(1337)).To(Succeed())
+	})
+
+	It("says if a stream was closed by the peer", func() {
+		Expect(isStreamClosed(nil)).To(BeTrue())
+		Expect(isStreamClosed(io.EOF)).To(BeTrue())
+		Expect(isStreamClosed(io.ErrUnexpectedEOF)).To(BeTrue())
+		Expect(isStreamClosed(&quic.StreamError{ErrorCode: 42})).To(BeTrue())
+		Expect(isStreamClosed(&quic.ApplicationError{ErrorCode: 42})).To(BeFalse())
+		Expect(isStreamClosed(errors.New("session closed"))).To(BeFalse())
+	})
+
+	Context("critical streams", func() {
+		type closeCall struct {
+			code   errorCode
+			reason string
+		}
+		var calls []closeCall
+		closeSession := func(code errorCode, reason string) { calls = append(calls, closeCall{code: code, reason: reason}) }
+
+		BeforeEach(func() { calls = nil })
+
+		newStream := func(data []byte, err error) quic.ReceiveStream {
+			buf := bytes.NewBuffer(data)
+			str := mockquic.NewMockStream(mockCtrl)
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(func(b []byte) (int, error) {
+				if buf.Len() > 0 {
+					return buf.Read(b)
+				}
+				return 0, err
+			}).AnyTimes()
+			return str
+		}
+
+		It("closes the session when the control stream is closed", func() {
+			handleControlStreamFrames(newStream(nil, io.EOF), closeSession)
+			Expect(calls).To(Equal([]closeCall{{code: errorClosedCriticalStream, reason: "control stream closed"}}))
+		})
+
+		It("closes the session when the control stream is reset", func() {
+			handleControlStreamFrames(newStream(nil, &quic.StreamError{ErrorCode: 42}), closeSession)
+			Expect(calls).To(Equal([]closeCall{{code: errorClosedCriticalStream, reason: "control stream closed"}}))
+		})
+
+		It("skips unknown frames on the control stream", func() {
+			buf := &bytes.Buffer{}
+			quicvarint.Write(buf, 0x7) // GOAWAY
+			quicvarint.Write(buf, 1)
+			quicvarint.Write(buf, 0)
+			handleControlStreamFrames(newStream(buf.Bytes(), errors.New("session closed")), closeSession)
+			Expect(calls).To(BeEmpty())
+		})
+
+		It("rejects a second SETTINGS frame", func() {
+			buf := &bytes.Buffer{}
+			(&settingsFrame{}).Write(buf)
+			handleControlStreamFrames(newStream(buf.Bytes(), nil), closeSession)
+			Expect(calls).To(Equal([]closeCall{{code: errorFrameUnexpected, reason: "duplicate SETTINGS frame"}}))
+		})
+
+		It("rejects DATA and HEADERS frames", func() {
+			buf := &bytes.Buffer{}
+			(&dataFrame{}).Write(buf)
+			handleControlStreamFrames(newStream(buf.Bytes(), nil), closeSession)
+			buf.Reset()
+			(&headersFrame{}).Write(buf)
+			handleControlStreamFrames(newStream(buf.Bytes(), nil), closeSession)
+			Expect(calls).To(Equal([]closeCall{
+				{code: errorFrameUnexpected, reason: "DATA frame on the control stream"},
+				{code: errorFrameUnexpected, reason: "HEADERS frame on the control stream"},
+			}))
+		})
+
+		It("closes the session when a QPACK stream is closed", func() {
+			handleQPACKStream(newStream([]byte("foobar"), io.EOF), closeSession)
+			Expect(calls).To(Equal([]closeCall{{code: errorClosedCriticalStream, reason: "QPACK stream closed"}}))
+		})
+
+		It("doesn't close the session when reading from a QPACK stream fails for other reasons", func() {
+			handleQPACKStream(newStream([]byte("foobar"), errors.New("session closed")), closeSession)
+			Expect(calls).To(BeEmpty())
+		})
+	})
+})
+
+// blockingRead returns a Read function that reads from buf.
+// Once all data was read, it blocks until done is closed.
+func blockingRead(buf *bytes.Buffer, done <-chan struct{}) func([]byte) (int, error) {
+	return func(b []byte) (int, error) {
+		if buf.Len() > 0 {
+			return buf.Read(b)
+		}
+		<-done
+		return 0, errors.New("test done")
+	}
+}
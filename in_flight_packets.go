@@ -0,0 +1,43 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/logutils"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// An InFlightPacket is a snapshot of a packet that was sent, but that was neither acknowledged nor declared lost yet.
+// It is intended for debugging, see Session.InFlightPackets.
+type InFlightPacket struct {
+	PacketNumber    logging.PacketNumber
+	EncryptionLevel EncryptionLevel
+	// Size is the size of the packet, in bytes.
+	Size     uint64
+	SendTime time.Time
+	// Frames are the frames contained in the packet, in the format used by the logging package.
+	// The payload of STREAM, CRYPTO and DATAGRAM frames is not included.
+	Frames []logging.Frame
+	// IsPathMTUProbePacket says if the packet was sent by Path MTU Discovery.
+	IsPathMTUProbePacket bool
+}
+
+func newInFlightPackets(packets []ackhandler.Packet) []InFlightPacket {
+	inFlight := make([]InFlightPacket, 0, len(packets))
+	for _, p := range packets {
+		frames := make([]logging.Frame, 0, len(p.Frames))
+		for _, f := range p.Frames {
+			frames = append(frames, logutils.ConvertFrame(f.Frame))
+		}
+		inFlight = append(inFlight, InFlightPacket{
+			PacketNumber:         p.PacketNumber,
+			EncryptionLevel:      p.EncryptionLevel,
+			Size:                 uint64(p.Length),
+			SendTime:             p.SendTime,
+			Frames:               frames,
+			IsPathMTUProbePacket: p.IsPathMTUProbePacket,
+		})
+	}
+	return inFlight
+}
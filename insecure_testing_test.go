@@ -0,0 +1,42 @@
+package quic
+
+import (
+	"crypto/tls"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Insecure Testing TLS Config", func() {
+	It("generates a config", func() {
+		conf, err := InsecureTestingTLSConfig("foo", "bar")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conf.InsecureSkipVerify).To(BeTrue())
+		Expect(conf.NextProtos).To(Equal([]string{"foo", "bar"}))
+		Expect(conf.Certificates).To(HaveLen(1))
+		leaf := conf.Certificates[0].Leaf
+		Expect(leaf).ToNot(BeNil())
+		Expect(leaf.VerifyHostname("localhost")).To(Succeed())
+		Expect(leaf.VerifyHostname("127.0.0.1")).To(Succeed())
+		Expect(leaf.VerifyHostname("::1")).To(Succeed())
+	})
+
+	It("generates a certificate that can be used for a handshake", func() {
+		conf, err := InsecureTestingTLSConfig()
+		Expect(err).ToNot(HaveOccurred())
+		chi := &tls.ClientHelloInfo{
+			ServerName:        "localhost",
+			SupportedVersions: []uint16{tls.VersionTLS13},
+			SignatureSchemes:  []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+		}
+		Expect(chi.SupportsCertificate(&conf.Certificates[0])).To(Succeed())
+	})
+
+	It("generates a new certificate every time", func() {
+		conf1, err := InsecureTestingTLSConfig()
+		Expect(err).ToNot(HaveOccurred())
+		conf2, err := InsecureTestingTLSConfig()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conf1.Certificates[0].Certificate).ToNot(Equal(conf2.Certificates[0].Certificate))
+	})
+})
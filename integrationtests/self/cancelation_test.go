@@ -2,6 +2,7 @@ package self_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
@@ -631,4 +632,131 @@ var _ = Describe("Stream Cancelations", func() {
 			Expect(server.Close()).To(Succeed())
 		})
 	})
+
+	It("delivers the data up to the reliable size, when canceling with RESET_STREAM_AT", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{EnableResetStreamAt: true}))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		const reliableSize = 10000
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRData[:reliableSize])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.CancelWriteAt(42, reliableSize)).To(Succeed())
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(&quic.Config{EnableResetStreamAt: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(str)
+		Expect(err).To(MatchError(&quic.StreamError{StreamID: str.StreamID(), ErrorCode: 42}))
+		Expect(data).To(Equal(PRData[:reliableSize]))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+	})
+
+	It("cancels all streams of a stream group", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		const numStreams = 3
+		groupClosed := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			group := sess.NewStreamGroup()
+			for i := 0; i < numStreams; i++ {
+				str, err := sess.OpenStreamSync(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(group.AddStream(str)).To(Succeed())
+				_, err = str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}
+			ustr, err := sess.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(group.AddSendStream(ustr)).To(Succeed())
+			_, err = ustr.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(group.Len()).To(Equal(numStreams + 1))
+			group.Close(42)
+			close(groupClosed)
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(groupClosed).Should(BeClosed())
+		for i := 0; i < numStreams; i++ {
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = io.ReadAll(str)
+			Expect(err).To(MatchError(&quic.StreamError{StreamID: str.StreamID(), ErrorCode: 42}))
+			// the server sent a STOP_SENDING frame
+			Eventually(str.Context().Done()).Should(BeClosed())
+		}
+		ustr, err := sess.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.ReadAll(ustr)
+		Expect(err).To(MatchError(&quic.StreamError{StreamID: ustr.StreamID(), ErrorCode: 42}))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+	})
+
+	It("translates stream error codes using the stream error registry", func() {
+		errServerCanceled := errors.New("server canceled the request")
+		errClientCanceled := errors.New("client canceled the request")
+
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(nil))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		serverErrChan := make(chan error, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.StreamErrors().Register(0x10c, errServerCanceled)).To(Succeed())
+			str, err := sess.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.StreamErrors().CancelWrite(str, fmt.Errorf("shutting down: %w", errServerCanceled))).To(BeTrue())
+			_, err = io.ReadAll(str)
+			serverErrChan <- err
+		}()
+
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(sess.StreamErrors().Register(0x10c, errClientCanceled)).To(Succeed())
+		str, err := sess.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = io.ReadAll(str)
+		Expect(err).To(MatchError(errClientCanceled))
+		var streamErr *quic.StreamError
+		Expect(errors.As(err, &streamErr)).To(BeTrue())
+		Expect(streamErr.ErrorCode).To(BeEquivalentTo(0x10c))
+		// cancel the server's read side, using the error code registered by the client
+		Expect(sess.StreamErrors().CancelWrite(str, errClientCanceled)).To(BeTrue())
+		var serverErr error
+		Eventually(serverErrChan).Should(Receive(&serverErr))
+		Expect(serverErr).To(MatchError(errServerCanceled))
+		Expect(sess.CloseWithError(0, "")).To(Succeed())
+	})
 })
@@ -87,4 +87,21 @@ var _ = Describe("Connection ID lengths tests", func() {
 		defer ln.Close()
 		runClient(ln.Addr(), clientConf)
 	})
+
+	It("downloads a file when both client and server change connection IDs frequently", func() {
+		serverConf := getQuicConfig(&quic.Config{
+			ConnectionIDLength:   randomConnIDLen(),
+			ConnectionIDRotation: &quic.ConnectionIDRotationPolicy{Packets: 3},
+			Versions:             []protocol.VersionNumber{protocol.VersionTLS},
+		})
+		clientConf := getQuicConfig(&quic.Config{
+			ConnectionIDLength:   randomConnIDLen(),
+			ConnectionIDRotation: &quic.ConnectionIDRotationPolicy{Packets: 3, Bytes: 5000},
+			Versions:             []protocol.VersionNumber{protocol.VersionTLS},
+		})
+
+		ln := runServer(serverConf)
+		defer ln.Close()
+		runClient(ln.Addr(), clientConf)
+	})
 })
@@ -2,9 +2,11 @@ package self_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"time"
 
 	"github.com/lucas-clemente/quic-go"
@@ -44,6 +46,22 @@ var _ = Describe("Stream deadline tests", func() {
 		return server, serverStr, clientStr
 	}
 
+	It("returns a net.Error that wraps os.ErrDeadlineExceeded", func() {
+		server, _, clientStr := setup()
+		defer server.Close()
+
+		clientStr.SetDeadline(time.Now().Add(-time.Second))
+		_, err := clientStr.Read([]byte{0})
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+		var nerr net.Error
+		Expect(errors.As(err, &nerr)).To(BeTrue())
+		Expect(nerr.Timeout()).To(BeTrue())
+		_, err = clientStr.Write([]byte("foobar"))
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, os.ErrDeadlineExceeded)).To(BeTrue())
+	})
+
 	Context("read deadlines", func() {
 		It("completes a transfer when the deadline is set", func() {
 			server, serverStr, clientStr := setup()
@@ -257,6 +257,24 @@ var _ = Describe("Handshake tests", func() {
 					Expect(err).ToNot(HaveOccurred())
 				})
 
+				It("rejects a certificate chain larger than the configured limit", func() {
+					runServer(getTLSConfigWithLongCertChain())
+					clientConfig.MaxCertificateChainSize = 500
+					_, err := quic.DialAddr(
+						fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+						getTLSClientConfig(),
+						clientConfig,
+					)
+					Expect(err).To(HaveOccurred())
+					var certErr *quic.CertificateChainTooLargeError
+					Expect(errors.As(err, &certErr)).To(BeTrue())
+					Expect(certErr.Limit).To(BeEquivalentTo(500))
+					Expect(certErr.Size).To(BeNumerically(">", 500))
+					var transportErr *quic.TransportError
+					Expect(errors.As(err, &transportErr)).To(BeTrue())
+					Expect(transportErr.ErrorCode).To(Equal(quic.CryptoBufferExceeded))
+				})
+
 				It("errors if the server name doesn't match", func() {
 					runServer(getTLSConfig())
 					conn, err := net.ListenUDP("udp", nil)
@@ -567,4 +585,89 @@ var _ = Describe("Handshake tests", func() {
 			Expect(token.IsRetryToken).To(BeTrue())
 		})
 	})
+
+	Context("inspecting long header packets", func() {
+		type inspectedPacket struct {
+			Type      logging.PacketType
+			Decrypted bool
+		}
+
+		inspect := func(c chan<- inspectedPacket) func(*quic.LongHeaderPacketInfo) {
+			return func(info *quic.LongHeaderPacketInfo) {
+				select {
+				case c <- inspectedPacket{Type: info.Type, Decrypted: info.Decrypted}:
+				default:
+				}
+			}
+		}
+
+		It("reports the long header packets sent during the handshake", func() {
+			serverPackets := make(chan inspectedPacket, 100)
+			serverConfig.InspectLongHeaderPacket = inspect(serverPackets)
+			serverConfig.AcceptToken = func(_ net.Addr, token *quic.Token) bool { return token != nil }
+			runServer(getTLSConfig())
+
+			clientPackets := make(chan inspectedPacket, 100)
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{InspectLongHeaderPacket: inspect(clientPackets)}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sess.CloseWithError(0, "")).To(Succeed())
+
+			// The first Initial is answered with a Retry, without creating a session.
+			Expect(serverPackets).To(Receive(Equal(inspectedPacket{Type: logging.PacketTypeInitial})))
+			Expect(serverPackets).To(Receive(Equal(inspectedPacket{Type: logging.PacketTypeInitial, Decrypted: true})))
+			Eventually(serverPackets).Should(Receive(Equal(inspectedPacket{Type: logging.PacketTypeHandshake, Decrypted: true})))
+
+			Expect(clientPackets).To(Receive(Equal(inspectedPacket{Type: logging.PacketTypeRetry})))
+			Expect(clientPackets).To(Receive(Equal(inspectedPacket{Type: logging.PacketTypeInitial, Decrypted: true})))
+			Eventually(clientPackets).Should(Receive(Equal(inspectedPacket{Type: logging.PacketTypeHandshake, Decrypted: true})))
+		})
+	})
+
+	Context("key availability", func() {
+		type keysEvent struct {
+			EncLevel   quic.EncryptionLevel
+			ForSending bool
+		}
+
+		recordKeys := func(c chan<- keysEvent) func(quic.Session, quic.EncryptionLevel, bool) {
+			return func(_ quic.Session, encLevel quic.EncryptionLevel, forSending bool) {
+				c <- keysEvent{EncLevel: encLevel, ForSending: forSending}
+			}
+		}
+
+		It("reports when the Handshake and 1-RTT keys become available", func() {
+			serverKeys := make(chan keysEvent, 10)
+			serverConfig.KeysAvailable = recordKeys(serverKeys)
+			runServer(getTLSConfig())
+
+			clientKeys := make(chan keysEvent, 10)
+			sess, err := quic.DialAddr(
+				fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+				getTLSClientConfig(),
+				getQuicConfig(&quic.Config{KeysAvailable: recordKeys(clientKeys)}),
+			)
+			Expect(err).ToNot(HaveOccurred())
+			defer sess.CloseWithError(0, "")
+
+			for _, c := range []chan keysEvent{serverKeys, clientKeys} {
+				var events []keysEvent
+				for i := 0; i < 4; i++ {
+					var e keysEvent
+					Eventually(c).Should(Receive(&e))
+					events = append(events, e)
+				}
+				Expect(events).To(ConsistOf(
+					keysEvent{EncLevel: quic.EncryptionHandshake, ForSending: true},
+					keysEvent{EncLevel: quic.EncryptionHandshake, ForSending: false},
+					keysEvent{EncLevel: quic.Encryption1RTT, ForSending: true},
+					keysEvent{EncLevel: quic.Encryption1RTT, ForSending: false},
+				))
+				Consistently(c).ShouldNot(Receive())
+			}
+		})
+	})
 })
@@ -0,0 +1,191 @@
+package self_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/integrationtests/tools/offpath"
+	quicproxy "github.com/lucas-clemente/quic-go/integrationtests/tools/proxy"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Off-path attacks", func() {
+	const (
+		connIDLen  = 6 // explicitly set the connection ID length, so the attacker can parse it
+		rtt        = 20 * time.Millisecond
+		numAttacks = 3
+	)
+
+	isShortHeaderPacket := func(raw []byte) bool { return raw[0]&0x80 == 0 }
+
+	for _, v := range protocol.SupportedVersions {
+		version := v
+
+		Context(fmt.Sprintf("with QUIC version %s", version), func() {
+			var (
+				serverConn, clientConn *net.UDPConn
+				proxy                  *quicproxy.QuicProxy
+				attacker               *offpath.Attacker
+				attack                 utils.AtomicBool
+				serverSessChan         chan quic.Session
+
+				addrChangesMutex sync.Mutex
+				addrChanges      []quic.AddressChange
+			)
+
+			BeforeEach(func() {
+				attack.Set(false)
+				addrChanges = nil
+				var err error
+				attacker, err = offpath.NewAttacker("localhost:0", connIDLen)
+				Expect(err).ToNot(HaveOccurred())
+				addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+				Expect(err).ToNot(HaveOccurred())
+				clientConn, err = net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+				serverConn, err = net.ListenUDP("udp", addr)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			AfterEach(func() {
+				Expect(attacker.Close()).To(Succeed())
+				Expect(proxy.Close()).To(Succeed())
+				Expect(clientConn.Close()).To(Succeed())
+				Expect(serverConn.Close()).To(Succeed())
+			})
+
+			runServer := func() {
+				ln, err := quic.Listen(serverConn, getTLSConfig(), getQuicConfig(&quic.Config{
+					Versions:           []protocol.VersionNumber{version},
+					ConnectionIDLength: connIDLen,
+					PeerAddressChanged: func(_ quic.Session, change quic.AddressChange) {
+						addrChangesMutex.Lock()
+						addrChanges = append(addrChanges, change)
+						addrChangesMutex.Unlock()
+					},
+				}))
+				Expect(err).ToNot(HaveOccurred())
+				serverSessChan = make(chan quic.Session, 1)
+				go func() {
+					defer GinkgoRecover()
+					sess, err := ln.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					serverSessChan <- sess
+					str, err := sess.AcceptUniStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal(PRData))
+					str2, err := sess.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					_, err = str2.Write(PRData)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str2.Close()).To(Succeed())
+				}()
+			}
+
+			runTest := func(delayCb quicproxy.DelayCallback) {
+				runServer()
+				var err error
+				proxy, err = quicproxy.NewQuicProxy("localhost:0", &quicproxy.Opts{
+					RemoteAddr:  serverConn.LocalAddr().String(),
+					DelayPacket: delayCb,
+				})
+				Expect(err).ToNot(HaveOccurred())
+				sess, err := quic.Dial(
+					clientConn,
+					proxy.LocalAddr(),
+					"localhost",
+					getTLSClientConfig(),
+					getQuicConfig(&quic.Config{
+						Versions:           []protocol.VersionNumber{version},
+						ConnectionIDLength: connIDLen,
+					}),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				var serverSess quic.Session
+				Eventually(serverSessChan).Should(Receive(&serverSess))
+				// Only attack the connection after the handshake completed.
+				// Before that, an attacker can tear down the connection, see the MITM tests.
+				attack.Set(true)
+
+				str, err := sess.OpenUniStream()
+				Expect(err).ToNot(HaveOccurred())
+				_, err = str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				rstr, err := sess.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := io.ReadAll(rstr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+
+				Expect(attacker.PacketsSent()).To(BeNumerically(">=", numAttacks))
+				Consistently(sess.Context().Done(), 2*rtt).ShouldNot(BeClosed())
+				Expect(serverSess.Context().Done()).ToNot(BeClosed())
+				Expect(sess.CloseWithError(0, "")).To(Succeed())
+				Eventually(serverSess.Context().Done()).Should(BeClosed())
+			}
+
+			// attackClient injects a packet towards the client for the first short header packets sent by the server
+			attackClient := func(inject func(target net.Addr, observed []byte) error) quicproxy.DelayCallback {
+				var numAttacked int32
+				return func(dir quicproxy.Direction, raw []byte) time.Duration {
+					defer GinkgoRecover()
+					if dir == quicproxy.DirectionOutgoing && attack.Get() && isShortHeaderPacket(raw) && atomic.AddInt32(&numAttacked, 1) <= numAttacks {
+						Expect(inject(clientConn.LocalAddr(), raw)).To(Succeed())
+					}
+					return rtt / 2
+				}
+			}
+
+			It("ignores spoofed Version Negotiation packets", func() {
+				runTest(attackClient(func(target net.Addr, observed []byte) error {
+					return attacker.SpoofVersionNegotiation(target, observed, []protocol.VersionNumber{0x1234})
+				}))
+			})
+
+			It("ignores spoofed Retry packets", func() {
+				runTest(attackClient(func(target net.Addr, observed []byte) error {
+					return attacker.SpoofRetry(target, observed, version)
+				}))
+			})
+
+			It("ignores spoofed stateless resets", func() {
+				runTest(attackClient(attacker.SpoofStatelessReset))
+			})
+
+			It("doesn't migrate to the attacker's address", func() {
+				var numAttacked int32
+				runTest(func(dir quicproxy.Direction, raw []byte) time.Duration {
+					defer GinkgoRecover()
+					// The copy sent by the attacker arrives before the original packet, which is delayed by the proxy.
+					if dir == quicproxy.DirectionIncoming && attack.Get() && isShortHeaderPacket(raw) && atomic.AddInt32(&numAttacked, 1) <= numAttacks {
+						Expect(attacker.ForgeMigration(serverConn.LocalAddr(), raw)).To(Succeed())
+					}
+					return rtt / 2
+				})
+				// the server tried to validate the attacker's address
+				Expect(attacker.PacketsReceived()).ToNot(BeZero())
+				addrChangesMutex.Lock()
+				defer addrChangesMutex.Unlock()
+				Expect(addrChanges).ToNot(BeEmpty())
+				for _, change := range addrChanges {
+					if change.NewAddr.String() == attacker.LocalAddr().String() {
+						Expect(change.State).ToNot(Equal(quic.AddressValidationSucceeded))
+					}
+				}
+			})
+		})
+	}
+})
@@ -122,4 +122,83 @@ var _ = Describe("TLS session resumption", func() {
 		Expect(err).ToNot(HaveOccurred())
 		Expect(serverSess.ConnectionState().TLS.DidResume).To(BeFalse())
 	})
+
+	It("sends multiple session tickets", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), getQuicConfig(&quic.Config{NumSessionTickets: 3}))
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		gets := make(chan string, 100)
+		puts := make(chan string, 100)
+		tlsConf := getTLSClientConfig()
+		tlsConf.ClientSessionCache = newClientSessionCache(gets, puts)
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			tlsConf,
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		for i := 0; i < 3; i++ {
+			Eventually(puts).Should(Receive())
+		}
+		Consistently(puts).ShouldNot(Receive())
+		Expect(sess.ConnectionStats().SessionTicketsReceived).To(BeEquivalentTo(3))
+
+		serverSess, err := server.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverSess.ConnectionStats().SessionTicketsSent).To(BeEquivalentTo(3))
+	})
+
+	It("discards session tickets, if the client config says so", func() {
+		server, err := quic.ListenAddr("localhost:0", getTLSConfig(), nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		gets := make(chan string, 100)
+		puts := make(chan string, 100)
+		tlsConf := getTLSClientConfig()
+		tlsConf.ClientSessionCache = newClientSessionCache(gets, puts)
+		sess, err := quic.DialAddr(
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			tlsConf,
+			getQuicConfig(&quic.Config{DiscardSessionTickets: true}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		Eventually(func() uint64 { return sess.ConnectionStats().SessionTicketsReceived }).Should(BeEquivalentTo(1))
+		Consistently(puts).ShouldNot(Receive())
+	})
+
+	It("calls the resumption callbacks", func() {
+		resumed := make(chan quic.ResumptionInfo, 1)
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{SessionResumed: func(_ quic.Session, info quic.ResumptionInfo) { resumed <- info }}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		stored := make(chan struct{}, 10)
+		tlsConf := getTLSClientConfig()
+		tlsConf.ClientSessionCache = newClientSessionCache(make(chan string, 100), make(chan string, 100))
+		conf := getQuicConfig(&quic.Config{SessionTicketStored: func(quic.Session) { stored <- struct{}{} }})
+		sess, err := quic.DialAddr(fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port), tlsConf, conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		Eventually(stored).Should(Receive())
+		_, err = server.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(resumed).ShouldNot(Receive())
+
+		sess, err = quic.DialAddr(fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port), tlsConf, conf)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		Expect(sess.ConnectionState().TLS.DidResume).To(BeTrue())
+		var info quic.ResumptionInfo
+		Eventually(resumed).Should(Receive(&info))
+		Expect(info.Used0RTT).To(BeFalse())
+		Eventually(stored).Should(Receive())
+	})
 })
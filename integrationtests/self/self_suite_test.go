@@ -344,10 +344,15 @@ func (t *connTracer) StartedConnection(local, remote net.Addr, srcConnID, destCo
 
 func (t *connTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
 }
-func (t *connTracer) ClosedConnection(error)                                   {}
-func (t *connTracer) SentTransportParameters(*logging.TransportParameters)     {}
-func (t *connTracer) ReceivedTransportParameters(*logging.TransportParameters) {}
-func (t *connTracer) RestoredTransportParameters(*logging.TransportParameters) {}
+func (t *connTracer) ClosedConnection(error)                                       {}
+func (t *connTracer) SentTransportParameters(*logging.TransportParameters)         {}
+func (t *connTracer) ReceivedTransportParameters(*logging.TransportParameters)     {}
+func (t *connTracer) RestoredTransportParameters(*logging.TransportParameters)     {}
+func (t *connTracer) NegotiatedIdleTimeout(local, remote, effective time.Duration) {}
+func (t *connTracer) StartedPathValidation(net.Addr, [8]byte)                      {}
+func (t *connTracer) ReceivedPathChallenge([8]byte)                                {}
+func (t *connTracer) FinishedPathValidation(net.Addr, bool, time.Duration)         {}
+func (t *connTracer) Rejected0RTT(parameter string)                                {}
 func (t *connTracer) SentPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
 }
 func (t *connTracer) ReceivedVersionNegotiationPacket(*logging.Header, []logging.VersionNumber) {}
@@ -358,21 +363,30 @@ func (t *connTracer) BufferedPacket(logging.PacketType)
 func (t *connTracer) DroppedPacket(logging.PacketType, logging.ByteCount, logging.PacketDropReason) {}
 func (t *connTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, bytesInFlight logging.ByteCount, packetsInFlight int) {
 }
+func (t *connTracer) UndecryptablePacketQueueFull(logging.PacketType, logging.ByteCount, int, logging.ByteCount) {
+}
 
+func (t *connTracer) SuspectedStatelessReset(logging.StatelessResetToken, int)         {}
+func (t *connTracer) DetectedPeerAnomaly(logging.PeerAnomaly, string)                  {}
 func (t *connTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketNumber) {}
 func (t *connTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
 }
-func (t *connTracer) UpdatedCongestionState(logging.CongestionState)                     {}
-func (t *connTracer) UpdatedPTOCount(value uint32)                                       {}
-func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
-func (t *connTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
-func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
-func (t *connTracer) DroppedKey(logging.KeyPhase)                                        {}
-func (t *connTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time) {}
-func (t *connTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)        {}
-func (t *connTracer) LossTimerCanceled()                                                 {}
-func (t *connTracer) Debug(string, string)                                               {}
-func (t *connTracer) Close()                                                             {}
+func (t *connTracer) UpdatedCongestionState(logging.CongestionState) {}
+func (t *connTracer) UpdatedPTOCount(value uint32)                   {}
+func (t *connTracer) DetectedPersistentCongestion(logging.EncryptionLevel, logging.PersistentCongestion) {
+}
+func (t *connTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)           {}
+func (t *connTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                      {}
+func (t *connTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                           {}
+func (t *connTracer) ReassembledCryptoData(logging.EncryptionLevel, logging.CryptoStreamStats) {}
+func (t *connTracer) BlockedByFlowControl(logging.StreamID, bool)                              {}
+func (t *connTracer) LabeledStream(logging.StreamID, string)                                   {}
+func (t *connTracer) DroppedKey(logging.KeyPhase)                                              {}
+func (t *connTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time)       {}
+func (t *connTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)              {}
+func (t *connTracer) LossTimerCanceled()                                                       {}
+func (t *connTracer) Debug(string, string)                                                     {}
+func (t *connTracer) Close()                                                                   {}
 
 type packet struct {
 	time   time.Time
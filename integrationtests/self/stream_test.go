@@ -158,6 +158,120 @@ var _ = Describe("Bidirectional streams", func() {
 				<-done1
 				<-done2
 			})
+
+			It("notifies when the data written to a stream was delivered", func() {
+				go func() {
+					defer GinkgoRecover()
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal(PRData))
+				}()
+
+				client, err := quic.DialAddr(
+					serverAddr,
+					getTLSClientConfig(),
+					getQuicConfig(qconf),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer client.CloseWithError(0, "")
+				str, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				n, err := str.Write(PRData)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				Eventually(str.Delivered(uint64(n))).Should(BeClosed())
+				Expect(str.DeliveredOffset()).To(BeEquivalentTo(len(PRData)))
+			})
+
+			It("splices streams", func() {
+				go func() {
+					defer GinkgoRecover()
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					src, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					dst, err := sess.OpenUniStreamSync(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					n, err := quic.Splice(dst, src)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(BeEquivalentTo(len(PRData)))
+				}()
+
+				client, err := quic.DialAddr(
+					serverAddr,
+					getTLSClientConfig(),
+					getQuicConfig(qconf),
+				)
+				Expect(err).ToNot(HaveOccurred())
+				defer client.CloseWithError(0, "")
+				str, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Write(PRData)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+				rstr, err := client.AcceptUniStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				data, err := io.ReadAll(rstr)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+			})
+
+			It("taps the stream data", func() {
+				go func() {
+					defer GinkgoRecover()
+					sess, err := server.Accept(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					str, err := sess.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					_, err = io.Copy(str, str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+
+				var mutex sync.Mutex
+				sent := make([]byte, len(PRData))
+				received := make([]byte, len(PRData))
+				var sentFin, receivedFin bool
+				conf := getQuicConfig(qconf)
+				conf.StreamTap = func(_ quic.Session, dir quic.StreamDataDirection, _ quic.StreamID, offset uint64, data []byte, fin bool) {
+					mutex.Lock()
+					defer mutex.Unlock()
+					if dir == quic.StreamDataSent {
+						copy(sent[offset:], data)
+						sentFin = sentFin || fin
+					} else {
+						copy(received[offset:], data)
+						receivedFin = receivedFin || fin
+					}
+				}
+				client, err := quic.DialAddr(serverAddr, getTLSClientConfig(), conf)
+				Expect(err).ToNot(HaveOccurred())
+				defer client.CloseWithError(0, "")
+				str, err := client.OpenStream()
+				Expect(err).ToNot(HaveOccurred())
+				go func() {
+					defer GinkgoRecover()
+					_, err := str.Write(PRData)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.Close()).To(Succeed())
+				}()
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal(PRData))
+				mutex.Lock()
+				defer mutex.Unlock()
+				Expect(sent).To(Equal(PRData))
+				Expect(sentFin).To(BeTrue())
+				Expect(received).To(Equal(PRData))
+				Expect(receivedFin).To(BeTrue())
+			})
 		})
 	}
 })
@@ -41,10 +41,15 @@ func (t *customConnTracer) StartedConnection(local, remote net.Addr, srcConnID,
 
 func (t *customConnTracer) NegotiatedVersion(chosen logging.VersionNumber, clientVersions, serverVersions []logging.VersionNumber) {
 }
-func (t *customConnTracer) ClosedConnection(error)                                   {}
-func (t *customConnTracer) SentTransportParameters(*logging.TransportParameters)     {}
-func (t *customConnTracer) ReceivedTransportParameters(*logging.TransportParameters) {}
-func (t *customConnTracer) RestoredTransportParameters(*logging.TransportParameters) {}
+func (t *customConnTracer) ClosedConnection(error)                                       {}
+func (t *customConnTracer) SentTransportParameters(*logging.TransportParameters)         {}
+func (t *customConnTracer) ReceivedTransportParameters(*logging.TransportParameters)     {}
+func (t *customConnTracer) RestoredTransportParameters(*logging.TransportParameters)     {}
+func (t *customConnTracer) NegotiatedIdleTimeout(local, remote, effective time.Duration) {}
+func (t *customConnTracer) StartedPathValidation(net.Addr, [8]byte)                      {}
+func (t *customConnTracer) ReceivedPathChallenge([8]byte)                                {}
+func (t *customConnTracer) FinishedPathValidation(net.Addr, bool, time.Duration)         {}
+func (t *customConnTracer) Rejected0RTT(parameter string)                                {}
 func (t *customConnTracer) SentPacket(hdr *logging.ExtendedHeader, size logging.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
 }
 
@@ -61,13 +66,23 @@ func (t *customConnTracer) UpdatedMetrics(rttStats *logging.RTTStats, cwnd, byte
 }
 
 func (t *customConnTracer) AcknowledgedPacket(logging.EncryptionLevel, logging.PacketNumber) {}
+func (t *customConnTracer) SuspectedStatelessReset(logging.StatelessResetToken, int)         {}
+func (t *customConnTracer) DetectedPeerAnomaly(logging.PeerAnomaly, string)                  {}
+func (t *customConnTracer) UndecryptablePacketQueueFull(logging.PacketType, logging.ByteCount, int, logging.ByteCount) {
+}
 func (t *customConnTracer) LostPacket(logging.EncryptionLevel, logging.PacketNumber, logging.PacketLossReason) {
 }
-func (t *customConnTracer) UpdatedCongestionState(logging.CongestionState)                     {}
-func (t *customConnTracer) UpdatedPTOCount(value uint32)                                       {}
-func (t *customConnTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective)     {}
-func (t *customConnTracer) UpdatedKey(generation logging.KeyPhase, remote bool)                {}
-func (t *customConnTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                     {}
+func (t *customConnTracer) UpdatedCongestionState(logging.CongestionState) {}
+func (t *customConnTracer) UpdatedPTOCount(value uint32)                   {}
+func (t *customConnTracer) DetectedPersistentCongestion(logging.EncryptionLevel, logging.PersistentCongestion) {
+}
+func (t *customConnTracer) UpdatedKeyFromTLS(logging.EncryptionLevel, logging.Perspective) {}
+func (t *customConnTracer) UpdatedKey(generation logging.KeyPhase, remote bool)            {}
+func (t *customConnTracer) DroppedEncryptionLevel(logging.EncryptionLevel)                 {}
+func (t *customConnTracer) ReassembledCryptoData(logging.EncryptionLevel, logging.CryptoStreamStats) {
+}
+func (t *customConnTracer) BlockedByFlowControl(logging.StreamID, bool)                        {}
+func (t *customConnTracer) LabeledStream(logging.StreamID, string)                             {}
 func (t *customConnTracer) DroppedKey(logging.KeyPhase)                                        {}
 func (t *customConnTracer) SetLossTimer(logging.TimerType, logging.EncryptionLevel, time.Time) {}
 func (t *customConnTracer) LossTimerExpired(logging.TimerType, logging.EncryptionLevel)        {}
@@ -1,10 +1,13 @@
 package self_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
+	"os"
 	"sync"
 
 	quic "github.com/lucas-clemente/quic-go"
@@ -61,7 +64,7 @@ var _ = Describe("Unidirectional Streams", func() {
 			go func() {
 				defer GinkgoRecover()
 				defer wg.Done()
-				data, err := io.ReadAll(str)
+				data, err := ioutil.ReadAll(str)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(data).To(Equal(dataForStream(str.StreamID())))
 			}()
@@ -138,4 +141,119 @@ var _ = Describe("Unidirectional Streams", func() {
 		<-done1
 		<-done2
 	})
+
+	It("sends a file using io.Copy", func() {
+		f, err := ioutil.TempFile("", "quic-go")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.Remove(f.Name())
+		defer f.Close()
+		_, err = f.Write(PRDataLong)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.Seek(0, io.SeekStart)
+		Expect(err).ToNot(HaveOccurred())
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal(PRDataLong))
+		}()
+
+		client, err := quic.DialAddr(serverAddr, getTLSClientConfig(), getQuicConfig(qconf))
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+		str, err := client.OpenUniStreamSync(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		n, err := io.Copy(str, f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(len(PRDataLong)))
+		Expect(str.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("receives data using io.Copy", func() {
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRDataLong)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		client, err := quic.DialAddr(serverAddr, getTLSClientConfig(), getQuicConfig(qconf))
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+		str, err := client.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		b := &bytes.Buffer{}
+		n, err := io.Copy(b, str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(len(PRDataLong)))
+		Expect(b.Bytes()).To(Equal(PRDataLong))
+	})
+
+	It("receives data using borrowed buffers", func() {
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.Write(PRDataLong)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		client, err := quic.DialAddr(serverAddr, getTLSClientConfig(), getQuicConfig(qconf))
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+		str, err := client.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		b := &bytes.Buffer{}
+		for {
+			bufs, release, err := str.ReadBuffers()
+			for _, buf := range bufs {
+				b.Write(buf)
+			}
+			release()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(b.Bytes()).To(Equal(PRDataLong))
+	})
+
+	It("sends data using vectored writes", func() {
+		go func() {
+			defer GinkgoRecover()
+			sess, err := server.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			bufs := net.Buffers{PRDataLong[:10], PRDataLong[10:5000], PRDataLong[5000:]}
+			n, err := str.WriteBuffers(bufs)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(len(PRDataLong)))
+			Expect(str.Close()).To(Succeed())
+		}()
+
+		client, err := quic.DialAddr(serverAddr, getTLSClientConfig(), getQuicConfig(qconf))
+		Expect(err).ToNot(HaveOccurred())
+		defer client.CloseWithError(0, "")
+		str, err := client.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRDataLong))
+	})
 })
@@ -121,6 +121,8 @@ var _ = Describe("0-RTT", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(data).To(Equal(testdata))
 					Expect(sess.ConnectionState().TLS.Used0RTT).To(BeTrue())
+					Expect(sess.ConnectionState().Used0RTT).To(BeTrue())
+					Expect(sess.ConnectionState().DidResume).To(BeTrue())
 					Expect(sess.CloseWithError(0, "")).To(Succeed())
 					close(done)
 				}()
@@ -141,6 +143,7 @@ var _ = Describe("0-RTT", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(str.Close()).To(Succeed())
 				Expect(sess.ConnectionState().TLS.Used0RTT).To(BeTrue())
+				Expect(sess.ConnectionState().Used0RTT).To(BeTrue())
 				Eventually(done).Should(BeClosed())
 				Eventually(sess.Context().Done()).Should(BeClosed())
 			}
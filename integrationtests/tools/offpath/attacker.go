@@ -0,0 +1,177 @@
+package offpath
+
+import (
+	"crypto/rand"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testutils"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// An Attacker simulates an off-path attacker.
+// It can observe packets exchanged between two endpoints (e.g. using the callbacks of the quicproxy),
+// but it can neither drop nor modify them.
+// All it can do is inject packets of its own, sent from its own address.
+type Attacker struct {
+	conn      *net.UDPConn
+	connIDLen int
+
+	numSent     uint32
+	numReceived uint32
+
+	closeOnce sync.Once
+	runDone   chan struct{}
+
+	logger utils.Logger
+}
+
+// NewAttacker creates a new attacker listening on the local address.
+// The connection ID length is needed to parse the connection ID of short header packets.
+func NewAttacker(local string, connIDLen int) (*Attacker, error) {
+	laddr, err := net.ResolveUDPAddr("udp", local)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	a := &Attacker{
+		conn:      conn,
+		connIDLen: connIDLen,
+		runDone:   make(chan struct{}),
+		logger:    utils.DefaultLogger.WithPrefix("attacker"),
+	}
+	go a.run()
+	return a, nil
+}
+
+// run reads (and discards) all packets that are sent to the attacker's address,
+// for example PATH_CHALLENGEs sent in response to a forged migration.
+func (a *Attacker) run() {
+	defer close(a.runDone)
+	buf := make([]byte, protocol.MaxPacketBufferSize)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		atomic.AddUint32(&a.numReceived, 1)
+		if a.logger.Debug() {
+			a.logger.Debugf("received packet (%d bytes) from %s", n, addr)
+		}
+	}
+}
+
+// Close closes the attacker's UDP connection.
+func (a *Attacker) Close() error {
+	var err error
+	a.closeOnce.Do(func() {
+		err = a.conn.Close()
+		<-a.runDone
+	})
+	return err
+}
+
+// LocalAddr is the address the attacker sends packets from.
+func (a *Attacker) LocalAddr() net.Addr {
+	return a.conn.LocalAddr()
+}
+
+// PacketsSent is the number of packets injected by the attacker.
+func (a *Attacker) PacketsSent() int {
+	return int(atomic.LoadUint32(&a.numSent))
+}
+
+// PacketsReceived is the number of packets that were sent to the attacker's address.
+func (a *Attacker) PacketsReceived() int {
+	return int(atomic.LoadUint32(&a.numReceived))
+}
+
+// SpoofVersionNegotiation sends a Version Negotiation packet listing the versions to target.
+// observed is a packet that was sent to target. Its connection IDs are used for the Version Negotiation packet.
+func (a *Attacker) SpoofVersionNegotiation(target net.Addr, observed []byte, versions []protocol.VersionNumber) error {
+	hdr, err := a.parseHeader(observed)
+	if err != nil {
+		return err
+	}
+	srcConnID := hdr.SrcConnectionID
+	if !hdr.IsLongHeader {
+		if srcConnID, err = protocol.GenerateConnectionID(a.connIDLen); err != nil {
+			return err
+		}
+	}
+	data, err := wire.ComposeVersionNegotiation(hdr.DestConnectionID, srcConnID, versions)
+	if err != nil {
+		return err
+	}
+	return a.send(target, data, "Version Negotiation")
+}
+
+// SpoofRetry sends a Retry packet to target.
+// observed is a packet that was sent to target. Its connection IDs are used for the Retry packet.
+// Since the attacker doesn't know the original destination connection ID, the Retry integrity tag will be invalid,
+// unless the observed packet is the client's first Initial packet.
+func (a *Attacker) SpoofRetry(target net.Addr, observed []byte, version protocol.VersionNumber) error {
+	hdr, err := a.parseHeader(observed)
+	if err != nil {
+		return err
+	}
+	srcConnID, err := protocol.GenerateConnectionID(a.connIDLen)
+	if err != nil {
+		return err
+	}
+	return a.send(target, testutils.ComposeRetryPacket(srcConnID, hdr.DestConnectionID, hdr.DestConnectionID, []byte("token"), version), "Retry")
+}
+
+// SpoofStatelessReset sends a packet that looks like a stateless reset to target.
+// observed is a packet that was sent to target. The packet uses its destination connection ID,
+// but since the attacker doesn't know the stateless reset token, the token is random.
+func (a *Attacker) SpoofStatelessReset(target net.Addr, observed []byte) error {
+	hdr, err := a.parseHeader(observed)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, protocol.MinStatelessResetSize)
+	if _, err := rand.Read(data); err != nil {
+		return err
+	}
+	data[0] = (data[0] & 0x3f) | 0x40 // short header packet
+	copy(data[1:], hdr.DestConnectionID)
+	return a.send(target, data, "stateless reset")
+}
+
+// ForgeMigration replays the observed packet to target, sending it from the attacker's address.
+// If the copy arrives before the original packet, it looks like the peer's address changed.
+func (a *Attacker) ForgeMigration(target net.Addr, observed []byte) error {
+	if _, err := a.parseHeader(observed); err != nil {
+		return err
+	}
+	data := make([]byte, len(observed))
+	copy(data, observed)
+	return a.send(target, data, "replayed")
+}
+
+func (a *Attacker) parseHeader(observed []byte) (*wire.Header, error) {
+	if wire.IsVersionNegotiationPacket(observed) {
+		return nil, errors.New("cannot use a Version Negotiation packet")
+	}
+	hdr, _, _, err := wire.ParsePacket(observed, a.connIDLen)
+	return hdr, err
+}
+
+func (a *Attacker) send(target net.Addr, data []byte, desc string) error {
+	if a.logger.Debug() {
+		a.logger.Debugf("injecting %s packet (%d bytes) to %s", desc, len(data), target)
+	}
+	if _, err := a.conn.WriteTo(data, target); err != nil {
+		return err
+	}
+	atomic.AddUint32(&a.numSent, 1)
+	return nil
+}
@@ -0,0 +1,147 @@
+package offpath
+
+import (
+	"bytes"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Off-path attacker", func() {
+	const connIDLen = 6
+
+	var (
+		attacker *Attacker
+		target   *net.UDPConn
+	)
+
+	destConnID := protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0x13, 0x37}
+	srcConnID := protocol.ConnectionID{0xca, 0xfe, 0xba, 0xbe, 0x42, 0x42}
+
+	makeLongHeaderPacket := func() []byte {
+		b := &bytes.Buffer{}
+		hdr := wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeHandshake,
+				Version:          protocol.VersionTLS,
+				Length:           4 + 10,
+				DestConnectionID: destConnID,
+				SrcConnectionID:  srcConnID,
+			},
+			PacketNumber:    42,
+			PacketNumberLen: protocol.PacketNumberLen4,
+		}
+		Expect(hdr.Write(b, protocol.VersionTLS)).To(Succeed())
+		return append(b.Bytes(), make([]byte, 10)...)
+	}
+
+	makeShortHeaderPacket := func() []byte {
+		b := &bytes.Buffer{}
+		hdr := wire.ExtendedHeader{
+			Header:          wire.Header{DestConnectionID: destConnID},
+			PacketNumber:    42,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}
+		Expect(hdr.Write(b, protocol.VersionTLS)).To(Succeed())
+		return append(b.Bytes(), make([]byte, 20)...)
+	}
+
+	receive := func() ([]byte, net.Addr) {
+		b := make([]byte, protocol.MaxPacketBufferSize)
+		Expect(target.SetReadDeadline(time.Now().Add(time.Second))).To(Succeed())
+		n, addr, err := target.ReadFrom(b)
+		ExpectWithOffset(1, err).ToNot(HaveOccurred())
+		return b[:n], addr
+	}
+
+	BeforeEach(func() {
+		var err error
+		attacker, err = NewAttacker("localhost:0", connIDLen)
+		Expect(err).ToNot(HaveOccurred())
+		addr, err := net.ResolveUDPAddr("udp", "localhost:0")
+		Expect(err).ToNot(HaveOccurred())
+		target, err = net.ListenUDP("udp", addr)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(attacker.Close()).To(Succeed())
+		Expect(target.Close()).To(Succeed())
+	})
+
+	It("counts the packets sent to the attacker", func() {
+		Expect(attacker.PacketsReceived()).To(BeZero())
+		_, err := target.WriteTo([]byte("foobar"), attacker.LocalAddr())
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(attacker.PacketsReceived).Should(Equal(1))
+	})
+
+	It("spoofs Version Negotiation packets", func() {
+		versions := []protocol.VersionNumber{0x1234, 0x4321}
+		Expect(attacker.SpoofVersionNegotiation(target.LocalAddr(), makeLongHeaderPacket(), versions)).To(Succeed())
+		data, addr := receive()
+		Expect(addr.String()).To(Equal(attacker.LocalAddr().String()))
+		Expect(wire.IsVersionNegotiationPacket(data)).To(BeTrue())
+		hdr, vs, err := wire.ParseVersionNegotiationPacket(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID).To(Equal(srcConnID))
+		Expect(vs).To(ContainElements(protocol.VersionNumber(0x1234), protocol.VersionNumber(0x4321)))
+		Expect(attacker.PacketsSent()).To(Equal(1))
+	})
+
+	It("spoofs Version Negotiation packets for short header packets", func() {
+		Expect(attacker.SpoofVersionNegotiation(target.LocalAddr(), makeShortHeaderPacket(), []protocol.VersionNumber{0x1234})).To(Succeed())
+		data, _ := receive()
+		hdr, _, err := wire.ParseVersionNegotiationPacket(bytes.NewReader(data))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.SrcConnectionID.Len()).To(Equal(connIDLen))
+	})
+
+	It("spoofs Retry packets", func() {
+		Expect(attacker.SpoofRetry(target.LocalAddr(), makeLongHeaderPacket(), protocol.VersionTLS)).To(Succeed())
+		data, _ := receive()
+		hdr, _, _, err := wire.ParsePacket(data, connIDLen)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.Type).To(Equal(protocol.PacketTypeRetry))
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+		Expect(hdr.Token).To(Equal([]byte("token")))
+	})
+
+	It("spoofs stateless resets", func() {
+		Expect(attacker.SpoofStatelessReset(target.LocalAddr(), makeShortHeaderPacket())).To(Succeed())
+		data, _ := receive()
+		Expect(data).To(HaveLen(protocol.MinStatelessResetSize))
+		hdr, _, _, err := wire.ParsePacket(data, connIDLen)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(hdr.IsLongHeader).To(BeFalse())
+		Expect(hdr.DestConnectionID).To(Equal(destConnID))
+	})
+
+	It("forges migrations by replaying packets", func() {
+		packet := makeShortHeaderPacket()
+		Expect(attacker.ForgeMigration(target.LocalAddr(), packet)).To(Succeed())
+		data, addr := receive()
+		Expect(data).To(Equal(packet))
+		Expect(addr.String()).To(Equal(attacker.LocalAddr().String()))
+	})
+
+	It("refuses to use Version Negotiation packets", func() {
+		vn, err := wire.ComposeVersionNegotiation(destConnID, srcConnID, []protocol.VersionNumber{0x1234})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(attacker.ForgeMigration(target.LocalAddr(), vn)).To(MatchError("cannot use a Version Negotiation packet"))
+		Expect(attacker.PacketsSent()).To(BeZero())
+	})
+
+	It("returns an error when the packet can't be parsed", func() {
+		Expect(attacker.SpoofStatelessReset(target.LocalAddr(), []byte{0x40, 0x1})).ToNot(Succeed())
+		Expect(attacker.PacketsSent()).To(BeZero())
+	})
+})
@@ -0,0 +1,13 @@
+package offpath
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOffPath(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Off-Path Attacker")
+}
@@ -18,6 +18,19 @@ type StreamID = protocol.StreamID
 // A VersionNumber is a QUIC version number.
 type VersionNumber = protocol.VersionNumber
 
+// An AckRange is a range of packet numbers, see Config.AckRangeFilter.
+type AckRange = logging.AckRange
+
+// The EncryptionLevel is the encryption level of a QUIC packet.
+type EncryptionLevel = protocol.EncryptionLevel
+
+const (
+	// EncryptionHandshake is the encryption level of Handshake packets.
+	EncryptionHandshake = protocol.EncryptionHandshake
+	// Encryption1RTT is the encryption level of 1-RTT packets.
+	Encryption1RTT = protocol.Encryption1RTT
+)
+
 const (
 	// VersionDraft29 is IETF QUIC draft-29
 	VersionDraft29 = protocol.VersionDraft29
@@ -59,6 +72,10 @@ type TokenStore interface {
 // when the server rejects a 0-RTT connection attempt.
 var Err0RTTRejected = errors.New("0-RTT rejected")
 
+// ErrWouldBlock is returned by ReceiveStream.TryRead and SendStream.TryWrite,
+// if the operation can't make any progress without blocking.
+var ErrWouldBlock = errors.New("operation would block")
+
 // SessionTracingKey can be used to associate a ConnectionTracer with a Session.
 // It is set on the Session.Context() context,
 // as well as on the context passed to logging.Tracer.NewConnectionTracer.
@@ -75,10 +92,13 @@ type Stream interface {
 	// SetDeadline sets the read and write deadlines associated
 	// with the connection. It is equivalent to calling both
 	// SetReadDeadline and SetWriteDeadline.
+	// As for a net.Conn, the error returned when a deadline is exceeded wraps os.ErrDeadlineExceeded.
 	SetDeadline(t time.Time) error
 }
 
 // A ReceiveStream is a unidirectional Receive Stream.
+// The streams returned by a Session also implement io.WriterTo,
+// so that io.Copy writes the data directly from the frames received, without an intermediate buffer.
 type ReceiveStream interface {
 	// StreamID returns the stream ID.
 	StreamID() StreamID
@@ -87,22 +107,98 @@ type ReceiveStream interface {
 	// after a fixed time limit; see SetDeadline and SetReadDeadline.
 	// If the stream was canceled by the peer, the error implements the StreamError
 	// interface, and Canceled() == true.
+	// If the peer canceled the stream using a RESET_STREAM_AT frame (see Config.EnableResetStreamAt),
+	// the data up to the reliable size is delivered before the error is returned.
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Reader
+	// ReadContext is like Read, but it unblocks when the context is canceled.
+	// In that case, it returns the context's error.
+	// The context only applies to this call. It doesn't affect the read deadline.
+	ReadContext(ctx context.Context, p []byte) (int, error)
 	// CancelRead aborts receiving on this stream.
 	// It will ask the peer to stop transmitting stream data.
 	// Read will unblock immediately, and future Read calls will fail.
 	// When called multiple times or after reading the io.EOF it is a no-op.
 	CancelRead(StreamErrorCode)
+	// CancelReadAfterBuffered aborts receiving on this stream, like CancelRead,
+	// but the data that was already received in order is still returned by Read, before Read fails.
+	// Data received after the call is discarded, even if the peer resets the stream in the meantime.
+	// This allows proxies to forward the data they already received, instead of dropping it.
+	// Calling CancelRead afterwards discards the data that wasn't read yet.
+	// When called after reading the io.EOF it is a no-op.
+	CancelReadAfterBuffered(StreamErrorCode)
+	// CancelReadAfter aborts receiving on this stream once all data up to offset was read.
+	// Read returns the data up to offset, and fails afterwards.
+	// The peer is asked to stop transmitting once all data up to offset has been received.
+	// If the peer resets the stream before that, Read fails with the StreamError once it reaches
+	// the reliable size of the reset (or the offset, whichever is smaller).
+	// If the stream ends before offset, it is a no-op.
+	// If offset was already read, it behaves like CancelRead.
+	// When called after CancelReadAfterBuffered, or after reading the io.EOF, it is a no-op.
+	CancelReadAfter(offset uint64, errorCode StreamErrorCode)
+	// PeerClosed returns a channel that is closed as soon as the peer has finished sending on this stream.
+	// This happens when all data up to the FIN has been received, or when the peer resets the stream.
+	// Data might still be buffered: Read returns it, followed by io.EOF (or the StreamError).
+	// This allows applications to start processing a request while they're still writing on the stream.
+	// The channel is not closed when the session is closed.
+	PeerClosed() <-chan struct{}
+	// ReadBuffers returns the data received on the stream, without copying it out of the received frames.
+	// It blocks until data is available, and then returns all data that can be read without blocking.
+	// The buffers are only valid until release is called, which must be done exactly once.
+	// At the end of the stream, it returns io.EOF, possibly together with the last buffers.
+	ReadBuffers() (bufs [][]byte, release func(), err error)
+	// Peek returns the next n bytes without consuming them, so that they are returned by the next Read.
+	// It blocks until n bytes are available, the peer closes the stream, or an error occurs.
+	// If fewer than n bytes are returned, the error explains why, e.g. io.EOF at the end of the stream.
+	// Peeked data is not counted as read for flow control purposes, so the peer can't send more data than
+	// the stream's receive window allows. If n bytes can't be buffered without reading, because n is larger
+	// than the receive window, or the peer used up its flow control credit, Peek returns bufio.ErrBufferFull.
+	Peek(n int) ([]byte, error)
+	// Discard skips the next n bytes without copying them, analogous to bufio.Reader.Discard.
+	// The data is released from the receive buffer, and counted as read for flow control purposes.
+	// If fewer than n bytes were discarded, the error explains why, e.g. io.EOF at the end of the stream.
+	Discard(n int64) (int64, error)
+	// FlowControlState returns a snapshot of the stream's flow control state.
+	FlowControlState() FlowControlState
+	// ReadableBytes returns the number of bytes that can be read without blocking,
+	// i.e. the data that was received contiguously, but wasn't read yet.
+	// It returns 0 once reading from the stream returns an error, e.g. after the stream was reset.
+	ReadableBytes() uint64
+	// TryRead reads data like Read, but it never blocks.
+	// If no data is available, it returns ErrWouldBlock. The read deadline doesn't apply to TryRead.
+	// Together with OnReadable, it allows serving a large number of streams without parking a goroutine in Read for every stream.
+	// It must not be called concurrently with Read.
+	TryRead(p []byte) (int, error)
+	// OnReadable sets a callback that is called when the stream might have become readable:
+	// when new data can be read, when the peer closed the stream, and when reading fails (e.g. because the stream was reset).
+	// If the stream is readable when the callback is set, it is called right away.
+	// It is usually called from the session's run loop, so it must not block, but it may call TryRead.
+	// Passing nil removes the callback.
+	OnReadable(func())
+	// SetReceiveWindow sets the maximum receive window for this stream, overriding Config.MaxStreamReceiveWindow.
+	// The window is increased up to this size by auto-tuning, but is still limited by the connection-level window.
+	// If the current window is larger, it is reduced. Flow control credit that was already granted to the peer is not revoked,
+	// so the stream should be configured right after it was opened or accepted.
+	// A size of 0 is ignored.
+	SetReceiveWindow(size uint64)
+	// GrantReceiveCredit allows the peer to send n more bytes on this stream.
+	// The first call switches the stream to pull-based flow control (see Config.ManualStreamReceiveCredit):
+	// From then on, reading data doesn't extend the receive window any more, only GrantReceiveCredit does.
+	// This allows proxies to apply precise backpressure when piping stream data into a constrained downstream.
+	// A credit of 0 only switches the mode. The window is still limited by the connection-level window.
+	// The window can't grow beyond 2^62-1 bytes, the largest value that can be sent in a MAX_STREAM_DATA frame.
+	GrantReceiveCredit(n uint64)
 	// SetReadDeadline sets the deadline for future Read calls and
 	// any currently-blocked Read call.
 	// A zero value for t means Read will not time out.
-
+	// If the deadline is exceeded, Read returns an error that wraps os.ErrDeadlineExceeded.
 	SetReadDeadline(t time.Time) error
 }
 
 // A SendStream is a unidirectional Send Stream.
+// The streams returned by a Session also implement io.ReaderFrom,
+// so that io.Copy reads the data directly into the frames sent, without an intermediate buffer.
 type SendStream interface {
 	// StreamID returns the stream ID.
 	StreamID() StreamID
@@ -111,9 +207,28 @@ type SendStream interface {
 	// after a fixed time limit; see SetDeadline and SetWriteDeadline.
 	// If the stream was canceled by the peer, the error implements the StreamError
 	// interface, and Canceled() == true.
+	// If the peer sent a STOP_SENDING frame, the error is a *StopSendingError, which also
+	// reports up to which offset data was delivered to the peer.
 	// If the session was closed due to a timeout, the error satisfies
 	// the net.Error interface, and Timeout() will be true.
 	io.Writer
+	// WriteContext is like Write, but it unblocks when the context is canceled.
+	// In that case, it returns the context's error.
+	// As with a write deadline, it may return n > 0, indicating that
+	// some of the data was successfully written.
+	// The context only applies to this call. It doesn't affect the write deadline.
+	WriteContext(ctx context.Context, p []byte) (int, error)
+	// WriteBuffers writes the buffers to the stream, as if they were concatenated.
+	// The buffers are copied directly into the STREAM frames, so that a header and a body
+	// kept in separate buffers (e.g. a net.Buffers) don't need to be joined first.
+	// It returns the number of bytes written, and the same errors as Write.
+	WriteBuffers(bufs [][]byte) (int64, error)
+	// WriteMessage writes a message to the stream, and returns the same values as Write.
+	// The message boundaries are used as a hint when packing STREAM frames:
+	// If a small message doesn't fit into the space left in a packet, it is sent in the next packet,
+	// instead of splitting it across two packets. Messages larger than 1000 bytes are split as usual.
+	// It must not be called concurrently with Write.
+	WriteMessage(p []byte) (int, error)
 	// Close closes the write-direction of the stream.
 	// Future calls to Write are not permitted after calling Close.
 	// It must not be called concurrently with Write.
@@ -124,17 +239,86 @@ type SendStream interface {
 	// Write will unblock immediately, and future calls to Write will fail.
 	// When called multiple times or after closing the stream it is a no-op.
 	CancelWrite(StreamErrorCode)
+	// CancelWriteAt aborts sending on this stream, like CancelWrite,
+	// but guarantees that the first reliableSize bytes are delivered to the peer.
+	// It uses a RESET_STREAM_AT frame, see draft-ietf-quic-reliable-stream-reset.
+	// The reliableSize must not be larger than the number of bytes written.
+	// It returns an error if the extension was not enabled by both peers, see Config.EnableResetStreamAt.
+	// When called after the write-side of the stream was canceled, it is a no-op.
+	CancelWriteAt(errorCode StreamErrorCode, reliableSize uint64) error
+	// BufferedAmount returns the number of bytes written to the stream that haven't been sent yet,
+	// including data that was declared lost and is waiting to be retransmitted.
+	// It can be used to apply backpressure, without relying on Write blocking.
+	// It returns 0 once the write-side of the stream was canceled, unless data up to a reliable size is still sent.
+	BufferedAmount() uint64
+	// TryWrite writes data like Write, but it never blocks.
+	// It only accepts as much data as fits into the stream's send buffer, which holds one packet's worth of data,
+	// and returns the number of bytes accepted. If the buffer is full, it returns ErrWouldBlock.
+	// The write deadline doesn't apply to TryWrite.
+	// Together with OnWritable, it allows serving a large number of streams without parking a goroutine in Write for every stream.
+	// It must not be called concurrently with Write.
+	TryWrite(p []byte) (int, error)
+	// OnWritable sets a callback that is called when the stream becomes writable:
+	// when the send buffer was emptied by sending a packet, and when writing fails (e.g. because the peer sent a STOP_SENDING frame).
+	// If the stream is writable when the callback is set, it is called right away.
+	// It is usually called from the session's run loop, so it must not block, but it may call TryWrite.
+	// Passing nil removes the callback.
+	OnWritable(func())
+	// DeliveredOffset returns the offset up to which all data written to the stream was acknowledged by the peer.
+	DeliveredOffset() uint64
+	// Delivered returns a channel that is closed once all data up to offset was acknowledged by the peer.
+	// Passing the total number of bytes written allows waiting until all data written so far was received by the peer.
+	// The channel is also closed when the data can't be delivered any more, i.e. when the write-side of the stream
+	// is canceled or the session is closed. DeliveredOffset then tells how much of the data was delivered.
+	Delivered(offset uint64) <-chan struct{}
 	// The context is canceled as soon as the write-side of the stream is closed.
 	// This happens when Close() or CancelWrite() is called, or when the peer
 	// cancels the read-side of their stream.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
+	// Done returns a channel that is closed once the fate of the data written to the stream is known:
+	// when all data, including the FIN, was acknowledged by the peer, when the write-side of the stream is canceled,
+	// either by calling CancelWrite or by the peer sending a STOP_SENDING frame, or when the session is closed.
+	Done() <-chan struct{}
+	// Err returns the reason why the Done channel was closed.
+	// It returns nil if all data was acknowledged by the peer, as well as before the Done channel is closed.
+	// Otherwise, it returns the error that a call to Write would return, e.g. a *StopSendingError if the peer sent a STOP_SENDING frame.
+	Err() error
+	// FlowControlState returns a snapshot of the stream's flow control state.
+	// Note that sending might also be blocked by connection-level flow control.
+	FlowControlState() FlowControlState
+	// SetPriority sets the priority of the stream.
+	// It determines the order in which the data of different streams is sent, see Config.StreamScheduler.
+	SetPriority(StreamPriority)
+	// Priority returns the priority of the stream.
+	Priority() StreamPriority
+	// SetTrafficClass sets the traffic class of the stream, see Config.TrafficClasses.
+	// If a priority is configured for the class, the priority of the stream is set to it,
+	// and can be changed afterwards using SetPriority.
+	SetTrafficClass(TrafficClass)
+	// TrafficClass returns the traffic class of the stream.
+	TrafficClass() TrafficClass
 	// SetWriteDeadline sets the deadline for future Write calls
 	// and any currently-blocked Write call.
 	// Even if write times out, it may return n > 0, indicating that
 	// some of the data was successfully written.
 	// A zero value for t means Write will not time out.
+	// If the deadline is exceeded, Write returns an error that wraps os.ErrDeadlineExceeded.
 	SetWriteDeadline(t time.Time) error
+	// SetDiscardOnWriteTimeout changes what happens to the data of a Write that times out.
+	// By default, Write returns as soon as the remaining data fits into a single STREAM frame,
+	// and this data is sent even if the write deadline (or the context passed to WriteContext) expires later.
+	// If discard is true and a deadline or a context is used, Write only returns when all data was packed into packets.
+	// If it times out, it returns the number of bytes that were actually sent, and the rest of the data is never sent.
+	// This is useful for real-time applications that use deadlines to control the freshness of the data.
+	SetDiscardOnWriteTimeout(discard bool)
+	// Flush makes sure that the data written to the stream is sent out immediately.
+	// quic-go doesn't delay small writes in order to coalesce them, but packets are paced.
+	// After calling Flush, the next packet is sent right away, even if the pacer would delay it,
+	// or if it would be delayed to coalesce it with more data, see Config.CoalescingDelay.
+	// It is still subject to congestion control.
+	// It returns an error if the stream was canceled or the session was closed.
+	Flush() error
 }
 
 // A Session is a QUIC connection between two peers.
@@ -176,20 +360,45 @@ type Session interface {
 	// If the error is non-nil, it satisfies the net.Error interface.
 	// If the session was closed due to a timeout, Timeout() will be true.
 	OpenUniStreamSync(context.Context) (SendStream, error)
+	// OpenStreamSyncWithOptions is like OpenStreamSync, but applies the StreamOptions
+	// before the stream is returned, and therefore before any data is written to it.
+	OpenStreamSyncWithOptions(context.Context, StreamOptions) (Stream, error)
+	// OpenUniStreamSyncWithOptions is like OpenUniStreamSync, but applies the StreamOptions
+	// before the stream is returned, and therefore before any data is written to it.
+	OpenUniStreamSyncWithOptions(context.Context, StreamOptions) (SendStream, error)
 	// LocalAddr returns the local address.
 	LocalAddr() net.Addr
 	// RemoteAddr returns the address of the peer.
 	RemoteAddr() net.Addr
 	// CloseWithError closes the connection with an error.
 	// The error string will be sent to the peer.
+	// It is truncated if it is longer than 512 bytes.
 	CloseWithError(ApplicationErrorCode, string) error
+	// CloseWithPayload is like CloseWithError, but additionally sends a structured payload to the peer,
+	// which is available as the Payload of the peer's ApplicationError.
+	// The payload can be at most 256 bytes, its format is defined by the application.
+	CloseWithPayload(code ApplicationErrorCode, desc string, payload []byte) error
 	// The context is cancelled when the session is closed.
+	// It is only cancelled after all streams have been closed.
+	// SessionCloseCause can be used to find out why the session was closed.
 	// Warning: This API should not be considered stable and might change soon.
 	Context() context.Context
 	// ConnectionState returns basic details about the QUIC connection.
 	// It blocks until the handshake completes.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// ConnectionStats returns statistics about the QUIC connection.
+	// In contrast to ConnectionState, it doesn't block.
+	ConnectionStats() ConnectionStats
+	// FlowControlState returns a snapshot of the connection-level flow control state.
+	FlowControlState() FlowControlState
+	// PacingState returns a snapshot of the state of the pacer and the send queue.
+	PacingState() PacingState
+	// InFlightPackets returns a snapshot of the packets that were sent, but neither acknowledged nor declared lost yet.
+	// It is intended for debugging stuck connections, and requires Config.EnablePacketHistorySnapshots.
+	// The snapshot is taken by the session's run loop, so InFlightPackets blocks until the run loop gets to it,
+	// or until ctx is canceled.
+	InFlightPackets(ctx context.Context) ([]InFlightPacket, error)
 
 	// SendMessage sends a message as a datagram.
 	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
@@ -197,6 +406,24 @@ type Session interface {
 	// ReceiveMessage gets a message received in a datagram.
 	// See https://datatracker.ietf.org/doc/draft-pauly-quic-datagram/.
 	ReceiveMessage() ([]byte, error)
+	// SendBDPFrame sends a BDP_FRAME, see Config.EnableBDPFrames.
+	// It returns an error if the extension wasn't enabled by both peers.
+	// The EndpointToken can be at most 255 bytes long.
+	SendBDPFrame(BDPInfo) error
+	// SetMaxIncomingStreams changes the maximum number of concurrent bidirectional streams that the peer is allowed to open,
+	// which was initially set by Config.MaxIncomingStreams. Values above 2^60 are invalid.
+	// When the limit is raised, a MAX_STREAMS frame is sent right away.
+	// Stream credit that was already granted can't be revoked, so when the limit is lowered,
+	// the peer won't be allowed to open new streams until enough of its streams were closed.
+	SetMaxIncomingStreams(uint64) error
+	// SetMaxIncomingUniStreams is like SetMaxIncomingStreams, but for unidirectional streams.
+	SetMaxIncomingUniStreams(uint64) error
+	// NewStreamGroup creates a new StreamGroup, which allows canceling, prioritizing and flow-controlling
+	// a set of streams of this session as a unit.
+	NewStreamGroup() *StreamGroup
+	// StreamErrors returns the registry used to translate stream error codes into application-defined errors, and vice versa.
+	// Errors should be registered before any streams are canceled.
+	StreamErrors() *StreamErrorRegistry
 }
 
 // An EarlySession is a session that is handshaking.
@@ -214,6 +441,17 @@ type EarlySession interface {
 	NextSession() Session
 }
 
+// A ConnectionIDGenerator generates the connection IDs that the peer uses to address an endpoint.
+// It can be used to encode routing information into the connection IDs,
+// such that a load balancer can route packets to the right node (see the cidaffinity package).
+type ConnectionIDGenerator interface {
+	// GenerateConnectionID generates a new connection ID.
+	// The connection ID must be ConnectionIDLen bytes long.
+	GenerateConnectionID() ([]byte, error)
+	// ConnectionIDLen is the length of the connection IDs generated.
+	ConnectionIDLen() int
+}
+
 // Config contains all configuration data needed for a QUIC server or client.
 type Config struct {
 	// The QUIC versions that can be negotiated.
@@ -227,6 +465,16 @@ type Config struct {
 	// If used for a server, or dialing on a packet conn, a 4 byte connection ID will be used.
 	// When dialing on a packet conn, the ConnectionIDLength value must be the same for every Dial call.
 	ConnectionIDLength int
+	// The ConnectionIDGenerator is used to generate connection IDs.
+	// If set, ConnectionIDLength must either be unset or equal to the length of the generated connection IDs,
+	// which must be between 4 and 20 bytes.
+	// If not set, random connection IDs are used.
+	ConnectionIDGenerator ConnectionIDGenerator
+	// ConnectionIDRotation determines when the connection ID used to address the peer is changed.
+	// Changing it regularly makes it harder for on-path observers to link the packets of a connection.
+	// If nil, the connection ID is changed after a randomized number of packets (10000 on average),
+	// as long as the peer provided enough connection IDs.
+	ConnectionIDRotation *ConnectionIDRotationPolicy
 	// HandshakeIdleTimeout is the idle timeout before completion of the handshake.
 	// Specifically, if we don't receive any packet from the peer within this time, the connection attempt is aborted.
 	// If this value is zero, the timeout is set to 5 seconds.
@@ -240,11 +488,21 @@ type Config struct {
 	// AcceptToken determines if a Token is accepted.
 	// It is called with token = nil if the client didn't send a token.
 	// If not set, a default verification function is used:
-	// * it verifies that the address matches, and
+	// * it verifies that the address matches (or, for tokens bound to a network prefix, that it lies within the prefix), and
 	//   * if the token is a retry token, that it was issued within the last 5 seconds
 	//   * else, that it was issued within the last 24 hours.
 	// This option is only valid for the server.
 	AcceptToken func(clientAddr net.Addr, token *Token) bool
+	// TokenIPv4PrefixLength and TokenIPv6PrefixLength bind the tokens sent in NEW_TOKEN frames to a network prefix
+	// of the client's address (e.g. a /24 for IPv4, or a /56 for IPv6) instead of the exact address.
+	// This allows clients whose address changed within that network, e.g. mobile clients, to skip address validation.
+	// The RemoteAddr of such a Token is the prefix in CIDR notation, and the time the token was issued
+	// is authenticated the same way as the address is.
+	// Retry tokens are always bound to the exact address.
+	// If zero, tokens are bound to the exact address.
+	// This option is only valid for the server.
+	TokenIPv4PrefixLength int
+	TokenIPv6PrefixLength int
 	// The TokenStore stores tokens received from the server.
 	// Tokens are used to skip address validation on future connection attempts.
 	// The key used to store tokens is the ServerName from the tls.Config, if set
@@ -276,6 +534,59 @@ type Config struct {
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any unidirectional streams.
 	MaxIncomingUniStreams int64
+	// MaxInitialCryptoData is the maximum number of bytes of CRYPTO data that can be received at the Initial encryption level.
+	// This limits the size of the ClientHello (for the server) and of the ServerHello (for the client).
+	// If this value is zero, it will default to 16 KB.
+	// If it is exceeded, the session is closed with a CryptoBufferExceededError.
+	MaxInitialCryptoData uint64
+	// MaxHandshakeCryptoData is the maximum number of bytes of CRYPTO data that can be received at the Handshake encryption level.
+	// This limits the size of the certificate chain that can be received.
+	// If this value is zero, it will default to 16 KB.
+	// If it is exceeded, the session is closed with a CryptoBufferExceededError.
+	MaxHandshakeCryptoData uint64
+	// MaxCertificateChainSize is the maximum size of the certificate chain accepted from the peer,
+	// i.e. the size of the TLS Certificate message.
+	// The handshake is aborted as soon as the header of a larger message is received, without buffering the chain,
+	// and the session is closed with a CertificateChainTooLargeError. The reason is sent to the peer.
+	// If this value is zero, the certificate chain is only limited by MaxHandshakeCryptoData.
+	MaxCertificateChainSize uint64
+	// MaxOneRTTCryptoData is the maximum number of bytes of CRYPTO data that can be received after completion of the handshake,
+	// e.g. for session tickets.
+	// If this value is zero, it will default to 16 KB.
+	MaxOneRTTCryptoData uint64
+	// MaxCryptoFrameFragments is the maximum number of CRYPTO frames that are buffered out of order at each encryption level.
+	// This protects against peers that split their handshake messages into many tiny out-of-order fragments.
+	// Negative values and values above 998 are invalid.
+	// If this value is zero, it will default to 256.
+	MaxCryptoFrameFragments int
+	// Certificates is a set of certificate chains that the server selects from for every handshake,
+	// based on the server name and the signature schemes offered by the client, see CertificateSet.
+	// If set, it replaces the Certificates and GetCertificate fields of the tls.Config passed to Listen,
+	// and of the tls.Config returned by its GetConfigForClient callback.
+	// Certificates can be added to and removed from the set while the server is running.
+	// It has no effect for a client.
+	Certificates *CertificateSet
+	// NumSessionTickets is the number of session tickets (TLS NewSessionTicket messages) that the server
+	// sends after completion of the handshake.
+	// Sending multiple tickets allows a client to resume multiple connections without reusing a ticket.
+	// If zero, a single ticket is sent. It must not be larger than 16.
+	// Negative values disable sending of session tickets for this connection, as does tls.Config.SessionTicketsDisabled.
+	// It has no effect for a client.
+	NumSessionTickets int
+	// DiscardSessionTickets makes the client drop all session tickets it receives,
+	// without passing them to TLS. They are then not stored in the tls.Config.ClientSessionCache.
+	// The number of session tickets received is still reported in the ConnectionStats.
+	// It has no effect for a server.
+	DiscardSessionTickets bool
+	// SessionTicketStored is called on the client when a session ticket received from the server
+	// is stored in the tls.Config.ClientSessionCache.
+	// It is called from the session's run loop, so it must not block.
+	// It has no effect for a server.
+	SessionTicketStored func(sess Session)
+	// SessionResumed is called on the server when the handshake of a session that resumed a TLS session completes.
+	// It is called from the session's run loop, so it must not block.
+	// It has no effect for a client.
+	SessionResumed func(sess Session, info ResumptionInfo)
 	// The StatelessResetKey is used to generate stateless reset tokens.
 	// If no key is configured, sending of stateless resets is disabled.
 	StatelessResetKey []byte
@@ -288,16 +599,583 @@ type Config struct {
 	// This can be useful if version information is exchanged out-of-band.
 	// It has no effect for a client.
 	DisableVersionNegotiationPackets bool
+	// MaxUnprocessedPackets is the maximum number of packets that the server queues
+	// for connections that it doesn't know yet (mostly Initial packets of new connections),
+	// while they are waiting to be processed.
+	// Packets that don't fit into the queue are dropped (and reported to the Tracer).
+	// If zero, 1024 packets are queued.
+	// It has no effect for a client.
+	MaxUnprocessedPackets int
+	// UnprocessedPacketsEvictionPolicy determines which packet is dropped when the queue of unprocessed packets is full.
+	// If zero, EvictNewest is used.
+	// It has no effect for a client.
+	UnprocessedPacketsEvictionPolicy EvictionPolicy
+	// MaxUndecryptablePackets is the maximum number of packets that a session queues during the handshake,
+	// because the keys needed to decrypt them are not available yet (e.g. when a Handshake packet arrives before the Initial).
+	// Packets that don't fit into the queue are dropped (and reported to the Tracer), and need to be retransmitted by the peer,
+	// which slows down the handshake. The current size of the queue is reported in the ConnectionStats.
+	// If zero, 32 packets are queued.
+	MaxUndecryptablePackets int
+	// MaxUndecryptableBytes is the maximum total size of the packets queued during the handshake, see MaxUndecryptablePackets.
+	// If zero, 46464 bytes (32 full-sized packets) are queued.
+	MaxUndecryptableBytes uint64
+	// ProbePolicy determines the content of the probe packets sent when the Probe Timeout (PTO) expires.
+	// If zero, ProbeRetransmitOldest is used.
+	ProbePolicy ProbePolicy
+	// StreamScheduler determines in which order the data of different streams is sent,
+	// taking into account the priorities set by SendStream.SetPriority.
+	// If zero, StreamSchedulerStrict is used.
+	StreamScheduler StreamScheduler
+	// TrafficClasses configures the traffic classes that streams can be assigned to, see SendStream.SetTrafficClass.
+	// This allows carrying traffic with different requirements (e.g. voice and bulk data) on a single connection.
+	// If nil, all streams are treated the same.
+	TrafficClasses map[TrafficClass]TrafficClassConfig
+	// PersistentCongestionThreshold is the multiplier applied to the Probe Timeout (PTO) to obtain the persistent congestion duration.
+	// If all packets sent over a period longer than this duration are lost, the congestion window is reset to its minimum.
+	// If zero, the default value of 3 is used (see section 7.6.1 of RFC 9002).
+	PersistentCongestionThreshold int
+	// AdaptiveReorderingThreshold enables adapting the reordering threshold used for loss detection.
+	// When a packet that was declared lost (because 3 or more later packets were acknowledged) is acknowledged,
+	// the threshold is increased, up to a maximum of 10 packets.
+	// This avoids spurious retransmissions and congestion window reductions on paths that reorder packets.
+	AdaptiveReorderingThreshold bool
+	// WindowUpdateStrategy decides when flow control window updates (MAX_DATA and MAX_STREAM_DATA frames) are sent.
+	// If nil, a window update is sent once more than 25% of the window was consumed.
+	WindowUpdateStrategy WindowUpdateStrategy
+	// ManualStreamReceiveCredit enables pull-based flow control for all streams:
+	// The stream-level receive window is only extended when the application calls ReceiveStream.GrantReceiveCredit,
+	// not when data is read. The initial window (InitialStreamReceiveWindow) is granted when the stream is opened.
+	// Connection-level flow control is not affected.
+	ManualStreamReceiveCredit bool
+	// AllowIncomingStream is called when the peer opens a new stream, before the stream is returned by AcceptStream or AcceptUniStream.
+	// If it returns false, the stream is rejected: it is canceled with the returned error code
+	// (using STOP_SENDING, and RESET_STREAM for bidirectional streams), and never returned by AcceptStream or AcceptUniStream.
+	// It is called from the session's run loop, so it must not block. If nil, all streams are allowed.
+	AllowIncomingStream func(sess Session, id StreamID) (bool, StreamErrorCode)
+	// MaxStreamAcceptBacklog is the maximum number of bidirectional streams opened by the peer that are waiting to be accepted using AcceptStream.
+	// Further streams are rejected with the RejectedStreamErrorCode, as if they were rejected by AllowIncomingStream.
+	// In contrast to MaxIncomingStreams, this doesn't limit the number of streams that were accepted by the application.
+	// If zero, the backlog is only limited by MaxIncomingStreams.
+	MaxStreamAcceptBacklog int
+	// MaxUniStreamAcceptBacklog is the maximum number of unidirectional streams opened by the peer that are waiting to be accepted using AcceptUniStream.
+	// If zero, the backlog is only limited by MaxIncomingUniStreams.
+	MaxUniStreamAcceptBacklog int
+	// RejectedStreamErrorCode is the error code used to cancel streams that exceed the MaxStreamAcceptBacklog or MaxUniStreamAcceptBacklog.
+	RejectedStreamErrorCode StreamErrorCode
+	// StreamIdleTimeout is the time after which a stream is canceled if no STREAM frames were sent or received on it.
+	// Idle streams are canceled in both directions (using STOP_SENDING and RESET_STREAM frames)
+	// with the StreamIdleTimeoutErrorCode, independent of the connection's idle timeout.
+	// This prevents abandoned streams from holding on to flow control credit and memory.
+	// If zero, streams don't time out.
+	StreamIdleTimeout time.Duration
+	// StreamIdleTimeoutErrorCode is the error code used to cancel streams that timed out, see StreamIdleTimeout.
+	StreamIdleTimeoutErrorCode StreamErrorCode
+	// MaxStreamReassemblyMemory is the maximum memory that the data buffered for a single receive stream may hold.
+	// The memory is accounted by the size of the packet buffers that the received STREAM frames are kept in,
+	// so a peer sending many small out-of-order frames uses up the limit well before it exceeds the flow control window.
+	// When the limit is exceeded, the buffered data is dropped and reading is canceled with the ReassemblyMemoryErrorCode.
+	// Read then returns a StreamReassemblyMemoryError.
+	// If zero, the memory is only limited by the flow control window.
+	MaxStreamReassemblyMemory uint64
+	// MaxConnectionReassemblyMemory is the maximum memory that the data buffered for all receive streams may hold together,
+	// accounted in the same way as for MaxStreamReassemblyMemory.
+	// When the limit is exceeded, the session is closed with a ConnectionReassemblyMemoryError.
+	// If zero, the memory is only limited by the flow control windows.
+	MaxConnectionReassemblyMemory uint64
+	// ReassemblyMemoryErrorCode is the error code used to cancel reading from streams that exceeded the MaxStreamReassemblyMemory.
+	ReassemblyMemoryErrorCode StreamErrorCode
+	// CoalescingDelay enables a Nagle-like algorithm, trading a bounded latency for fewer packets.
+	// If only a small amount of stream data is waiting to be sent, sending is delayed by up to this duration,
+	// allowing more data to be written and sent in the same packet, see CoalescingMinFill.
+	// Control frames, ACKs, retransmissions and the end of a stream are never delayed, and neither is data that was flushed
+	// using SendStream.Flush. If zero, packets are sent as soon as possible.
+	CoalescingDelay time.Duration
+	// CoalescingMinFill is the fraction (between 0 and 1) of the maximum packet size that the pending stream data
+	// needs to fill for a packet to be sent before the CoalescingDelay expires.
+	// It is only used if CoalescingDelay is set. If zero, a value of 0.5 is used.
+	CoalescingMinFill float64
 	// See https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/.
 	// Datagrams will only be available when both peers enable datagram support.
 	EnableDatagrams bool
-	Tracer          logging.Tracer
+	// EnableBDPFrames enables the experimental BDP_FRAME extension, see draft-kuhn-quic-bdpframe-extension.
+	// Support is advertised in the transport parameters, and BDP_FRAMEs are only sent if the peer advertised support as well.
+	// The extension is not standardized, and it is only intended for interop testing.
+	EnableBDPFrames bool
+	// EnableResetStreamAt enables the reliable stream reset extension, see draft-ietf-quic-reliable-stream-reset.
+	// It allows canceling a stream while guaranteeing the delivery of a prefix of the data, see SendStream.CancelWriteAt.
+	EnableResetStreamAt bool
+	// EnablePacketHistorySnapshots enables Session.InFlightPackets, which is intended for debugging.
+	EnablePacketHistorySnapshots bool
+	Tracer                       logging.Tracer
+	// MemoryBudget limits the memory used for buffering received data.
+	// The same MemoryBudget can be shared between many sessions, see MemoryBudget for details.
+	// If nil, the memory usage is only limited by the flow control windows.
+	MemoryBudget *MemoryBudget
+	// PanicHandler is called when a panic occurs in one of the goroutines run by a session.
+	// It is called with the recovered value and the stack trace of the panicking goroutine.
+	// The panic is then contained to this session: it is closed with an INTERNAL_ERROR.
+	// If nil, panics are not recovered and crash the process.
+	PanicHandler func(sess Session, recovered interface{}, stack []byte)
+	// DatagramPayloadSizeChanged is called when the maximum size of a message that can be sent using SendMessage changes,
+	// see ConnectionState.MaxDatagramPayloadSize.
+	// It is called when the peer's transport parameters are received, and every time Path MTU Discovery finds a larger MTU.
+	// It is called from the session's run loop, so it must not block.
+	DatagramPayloadSizeChanged func(sess Session, size int)
+	// StreamObserver is notified when streams are opened, either by us or by the peer.
+	// If nil, no notifications are sent.
+	StreamObserver StreamObserver
+	// InspectLongHeaderPacket is called for every long header packet received,
+	// i.e. for Initial, 0-RTT, Handshake, Retry and Version Negotiation packets.
+	// It is intended for measurement tools, and only exposes the packet header.
+	// Packets handled by a session are reported once they were decrypted, and include the packet number.
+	// Packets that are dropped before they can be decrypted, or that are not encrypted, are reported without it.
+	// It is called synchronously when the packet is processed, so it must not block.
+	InspectLongHeaderPacket func(info *LongHeaderPacketInfo)
+	// BDPFrameReceived is called when a BDP_FRAME is received, see EnableBDPFrames.
+	// It is called from the session's run loop, so it must not block.
+	BDPFrameReceived func(sess Session, info BDPInfo)
+	// PeerAddressChanged is called when the peer's address changes, e.g. due to a NAT rebinding.
+	// The server switches to the new address when it receives a packet from it, and validates the address
+	// by sending a PATH_CHALLENGE. PeerAddressChanged is called once when the change is detected,
+	// and again when the validation succeeds or fails. If the validation fails, the server reverts to the old address.
+	// Until the validation succeeds, the server sends at most three times the amount of data it received from the new address.
+	// Address changes are only handled by the server, after the handshake was confirmed.
+	// It is called from the session's run loop, so it must not block.
+	PeerAddressChanged func(sess Session, change AddressChange)
+	// CheckPeerAddressChange is called when a change of the peer's address is detected, before the server switches to the new address.
+	// It allows the application to refuse the change, e.g. if sessions are required to stay on the same IP address.
+	// Accepted changes are always validated, and the amount of data sent to the new address is limited until then.
+	// The State of the AddressChange is always AddressValidationPending.
+	// If nil, all address changes are accepted.
+	// It is called from the session's run loop, so it must not block.
+	CheckPeerAddressChange func(sess Session, change AddressChange) PeerAddressChangeDecision
+	// KeysAvailable is called when the keys for the Handshake or the 1-RTT encryption level were installed.
+	// The keys for sending and for receiving packets become available at different points in the handshake,
+	// and are reported separately. For example, the server can send 1-RTT packets (0.5-RTT data)
+	// as soon as it has the 1-RTT keys for sending, which happens before it can decrypt 1-RTT packets sent by the client.
+	// It is called from the goroutine running the TLS handshake, so it must not block.
+	KeysAvailable func(sess Session, encLevel EncryptionLevel, forSending bool)
+	// AckRangeFilter is an experimental hook into the generation of ACK frames, intended for research on
+	// proxy-assisted retransmission (e.g. performance enhancing proxies on satellite links).
+	// It is called with the ranges of received 1-RTT packets (in descending order) every time an ACK frame is sent,
+	// and returns the ranges that are acknowledged. Packets that are not acknowledged will eventually be declared lost by the peer.
+	// The returned ranges are restricted to the packets that were actually received:
+	// the filter can withhold acknowledgments, but it can't acknowledge packets that were not received.
+	// If no ranges are returned, no ACK frame is sent.
+	// Initial and Handshake packets are always acknowledged.
+	// It is called from the session's run loop, so it must not block.
+	AckRangeFilter func(sess Session, received []AckRange) []AckRange
+	// StreamTap receives the plaintext stream data, for debugging and audit tooling.
+	// It is called for every STREAM frame that is delivered to a stream, and for every STREAM frame that is sent.
+	// Data may be reported more than once, e.g. when the peer or we retransmit it. The offset can be used to deduplicate it.
+	// To avoid copying, data is borrowed from the frame: it is only valid until StreamTap returns, and must not be modified.
+	// If nil, no data is reported, and no additional cost is incurred.
+	// It is called from the session's run loop, so it must not block.
+	StreamTap func(sess Session, dir StreamDataDirection, id StreamID, offset uint64, data []byte, fin bool)
+
+	// faultInjector injects faults (drops, delays, duplicates and corruption) into the packets sent and received.
+	faultInjector faultInjector // only set for testing
+}
+
+// StreamDataDirection is the direction of the stream data reported to Config.StreamTap.
+type StreamDataDirection uint8
+
+const (
+	// StreamDataReceived is stream data received from the peer, when it is delivered to the stream.
+	StreamDataReceived StreamDataDirection = iota
+	// StreamDataSent is stream data taken from a stream, when it is packed into a packet.
+	StreamDataSent
+)
+
+// BDPInfo contains the path characteristics carried in a BDP_FRAME.
+type BDPInfo struct {
+	// Lifetime is the time for which the values are valid. It is transmitted with a granularity of seconds.
+	Lifetime time.Duration
+	// SavedCapacity is the bandwidth-delay product of the path, in bytes.
+	SavedCapacity uint64
+	// SavedRTT is the minimum RTT of the path. It is transmitted with a granularity of microseconds.
+	SavedRTT time.Duration
+	// EndpointToken identifies the endpoint that the values were observed for.
+	EndpointToken []byte
+}
+
+// AddressValidationState is the state of the validation of a new peer address.
+type AddressValidationState uint8
+
+const (
+	// AddressValidationPending means that the new address is being validated.
+	AddressValidationPending AddressValidationState = iota
+	// AddressValidationSucceeded means that the peer proved that it can receive packets at the new address.
+	AddressValidationSucceeded
+	// AddressValidationFailed means that the validation timed out. The server reverted to the old address.
+	AddressValidationFailed
+)
+
+// A PeerAddressChangeDecision is the decision of the application about a change of the peer's address,
+// see Config.CheckPeerAddressChange.
+type PeerAddressChangeDecision uint8
+
+const (
+	// PeerAddressChangeAccept accepts the change. The server switches to the new address, and validates it.
+	// Until the new address is validated, the server sends at most three times the amount of data it received from that address.
+	PeerAddressChangeAccept PeerAddressChangeDecision = iota
+	// PeerAddressChangeRequireValidation is the same as PeerAddressChangeAccept.
+	// The amount of data sent to a new address is always limited until the address is validated, as required by RFC 9000.
+	PeerAddressChangeRequireValidation
+	// PeerAddressChangeRefuse refuses the change. The server closes the connection with a NO_VIABLE_PATH error.
+	// The CONNECTION_CLOSE is sent to the old address.
+	PeerAddressChangeRefuse
+)
+
+// An AddressChange describes a change of the peer's address.
+type AddressChange struct {
+	// OldAddr is the last validated address of the peer.
+	OldAddr net.Addr
+	// NewAddr is the address that the peer's packets were received from.
+	NewAddr net.Addr
+	State   AddressValidationState
+}
+
+// ResumptionInfo contains information about a session that was resumed using a session ticket.
+type ResumptionInfo struct {
+	// TicketAge is the time that passed since the server issued the session ticket.
+	// The issue time is stored in the ticket, but it is only available if the client offered 0-RTT.
+	// Otherwise, TicketAge is 0.
+	TicketAge time.Duration
+	// Used0RTT says if 0-RTT was accepted.
+	Used0RTT bool
+}
+
+// LongHeaderPacketInfo describes a received long header packet.
+// All fields are copies, they may be retained after InspectLongHeaderPacket returns.
+type LongHeaderPacketInfo struct {
+	// RemoteAddr is the address the packet was received from.
+	RemoteAddr net.Addr
+	// Type is the packet type.
+	Type logging.PacketType
+	// Version is the QUIC version. It is 0 for Version Negotiation packets.
+	Version VersionNumber
+	// DestConnectionID and SrcConnectionID are the connection IDs.
+	DestConnectionID []byte
+	SrcConnectionID  []byte
+	// Token is the token sent in Initial and Retry packets.
+	Token []byte
+	// SupportedVersions is the list of versions offered in a Version Negotiation packet.
+	SupportedVersions []VersionNumber
+	// Size is the size of the packet, in bytes.
+	Size int
+	// Decrypted says if the packet was decrypted.
+	Decrypted bool
+	// PacketNumber is the packet number. It is only set if the packet was decrypted.
+	PacketNumber logging.PacketNumber
+}
+
+// An EvictionPolicy determines which packet is dropped when a packet queue is full.
+type EvictionPolicy uint8
+
+const (
+	// EvictNewest drops the packet that was just received.
+	EvictNewest EvictionPolicy = iota
+	// EvictOldest drops the packet that has been in the queue for the longest time,
+	// making room for the packet that was just received.
+	// This is useful if clients retransmit their Initial packets during bursts of new connections.
+	EvictOldest
+)
+
+// A ConnectionIDRotationPolicy determines when the connection ID used to address the peer is changed.
+// The connection ID is changed as soon as any of the limits is reached, using one of the connection IDs
+// provided by the peer in NEW_CONNECTION_ID frames. If the peer didn't provide any, it can't be changed.
+// A zero value for a limit means that it is not used.
+type ConnectionIDRotationPolicy struct {
+	// Packets is the number of packets sent using one connection ID.
+	Packets uint64
+	// Bytes is the number of bytes sent using one connection ID.
+	Bytes uint64
+	// Interval is the time a connection ID is used for, measured from the first packet sent using it.
+	Interval time.Duration
+}
+
+// A ProbePolicy determines the content of probe packets.
+type ProbePolicy uint8
+
+const (
+	// ProbeRetransmitOldest retransmits the data of the oldest outstanding packet.
+	ProbeRetransmitOldest ProbePolicy = iota
+	// ProbeNewData sends new data, if available.
+	// If there's no new data to send, the data of the oldest outstanding packet is retransmitted.
+	// This reduces latency if the peer didn't receive the packets because of a tail loss,
+	// but the new data is also lost.
+	ProbeNewData
+	// ProbePing sends a PING frame.
+	// This elicits an ACK, without retransmitting any data.
+	// This is useful if the application sends small objects that fit into a single packet,
+	// and retransmissions should only happen when a loss was actually detected.
+	// During the handshake, Initial and Handshake probe packets always retransmit data.
+	ProbePing
+)
+
+// A StreamScheduler determines in which order the data of different streams is sent.
+// It only matters if the amount of data that can be sent is limited, for example by the congestion window.
+type StreamScheduler uint8
+
+const (
+	// StreamSchedulerStrict sends the data of more urgent streams first.
+	// Streams with the same urgency are served round-robin.
+	// As long as all streams use the default priority, all streams are served round-robin.
+	StreamSchedulerStrict StreamScheduler = iota
+	// StreamSchedulerWeighted shares the available bandwidth between all streams,
+	// proportionally to their weight. The urgency is ignored.
+	StreamSchedulerWeighted
+	// StreamSchedulerIncremental follows the scheduling of HTTP/3 extensible priorities (RFC 9218):
+	// The data of more urgent streams is sent first. Of the streams with the same urgency,
+	// non-incremental streams are served one after the other, in the order of their stream IDs,
+	// before incremental streams, which are served round-robin.
+	StreamSchedulerIncremental
+)
+
+// A StreamPriority is the priority of a stream.
+// New streams have an urgency of 3, are not incremental, and have a weight of 16.
+type StreamPriority struct {
+	// Urgency is the urgency of the stream. Lower values are more urgent.
+	// HTTP/3 extensible priorities use the range from 0 to 7.
+	Urgency uint8
+	// Incremental says if the data of the stream can be interleaved with the data of other streams
+	// that have the same urgency. It is only used by the StreamSchedulerIncremental.
+	Incremental bool
+	// Weight is the share of the bandwidth that the stream receives, relative to the other streams.
+	// It is only used by the StreamSchedulerWeighted. A weight of 0 is treated as 1.
+	Weight uint8
+}
+
+// A TrafficClass is an application-defined class of service of a stream, see Config.TrafficClasses.
+type TrafficClass uint8
+
+// TrafficClassDefault is the traffic class of new streams.
+// It can't be configured: streams of this class use the default priority, and packets are not marked.
+const TrafficClassDefault TrafficClass = 0
+
+// A TrafficClassConfig configures how the streams of a traffic class are sent.
+type TrafficClassConfig struct {
+	// Priority is the priority that a stream is set to when it is assigned to the class.
+	// If nil, the priority of the stream is not changed.
+	Priority *StreamPriority
+	// DSCP is the Differentiated Services Code Point (RFC 2474) that packets are marked with
+	// if all the STREAM frames they contain belong to streams of this class.
+	// Packets that contain other frames, except for ACK frames, are not marked.
+	// It must be smaller than 64. If zero, packets are not marked.
+	// Marking packets is only supported on Linux, when using a *net.UDPConn.
+	DSCP uint8
+}
+
+// StreamOptions are the options used when opening a stream,
+// see Session.OpenStreamSyncWithOptions.
+type StreamOptions struct {
+	// Priority is the initial priority of the stream, see SendStream.SetPriority.
+	// If nil, the default priority is used.
+	Priority *StreamPriority
+	// Label is an application-defined label for the stream, e.g. the type of request sent on it.
+	// It is not sent to the peer, but recorded by the ConnectionTracer (and in qlog).
+	Label string
+	// TrafficClass is the traffic class of the stream, see SendStream.SetTrafficClass.
+	// It is applied before the Priority. If zero, the stream is in the TrafficClassDefault.
+	TrafficClass TrafficClass
 }
 
 // ConnectionState records basic details about a QUIC connection
 type ConnectionState struct {
 	TLS               handshake.ConnectionState
 	SupportsDatagrams bool
+	// MaxDatagramPayloadSize is the maximum size of a message that can be sent using SendMessage.
+	// It depends on the current packet size, and increases when Path MTU Discovery finds a larger MTU.
+	// It is 0 if the peer's transport parameters have not been received yet, or if the peer doesn't support datagrams.
+	MaxDatagramPayloadSize int
+	// UsedRetry says if the server performed a Retry to validate the client's address.
+	UsedRetry bool
+	// Used0RTT says if 0-RTT data was accepted by the server. It is the same as TLS.Used0RTT.
+	Used0RTT bool
+	// DidResume says if the session resumed a previous TLS session. It is the same as TLS.DidResume.
+	DidResume bool
+	// HandshakeDuration is the time from the creation of the session until completion of the handshake.
+	// It is 0 until the handshake completes.
+	HandshakeDuration time.Duration
+	// HandshakeRTTs is the number of round trips it took before the client could send application data.
+	// It is 0 if 0-RTT was accepted, and 1 for a regular handshake.
+	// A Retry and (on the client side) Version Negotiation each add one round trip.
+	// Round trips caused by a TLS HelloRetryRequest or by packet loss are not included.
+	// It is 0 until the handshake completes.
+	HandshakeRTTs int
+	// IdleTimeout is the effective idle timeout: the minimum of Config.MaxIdleTimeout and the peer's max_idle_timeout.
+	// It might be a lot shorter than the configured value, and applications that rely on idle connections staying open
+	// should take it into account, e.g. by enabling KeepAlive.
+	// It is 0 if the peer's transport parameters have not been received yet.
+	IdleTimeout time.Duration
+}
+
+// ConnectionStats are statistics about a QUIC connection.
+type ConnectionStats struct {
+	// PacketsSent is the number of QUIC packets sent.
+	// Coalesced packets are counted individually.
+	PacketsSent uint64
+	// AckOnlyPacketsSent is the number of packets sent that didn't contain any ack-eliciting frames,
+	// i.e. packets that only contained an ACK frame (and padding).
+	AckOnlyPacketsSent uint64
+	// ProbePacketsSent is the number of probe packets sent when the Probe Timeout (PTO) expired.
+	ProbePacketsSent uint64
+	// PingProbePacketsSent is the number of probe packets that a PING frame was added to,
+	// either because the ProbePolicy is ProbePing, or because there was no data to send.
+	PingProbePacketsSent uint64
+	// SpuriousLosses is the number of packets that were declared lost, but were acknowledged later.
+	// A high number of spurious losses indicates that packets are reordered on the path.
+	SpuriousLosses uint64
+	// SuspectedStatelessResets is the number of times that a series of 1-RTT packets couldn't be decrypted,
+	// which might be caused by a stateless reset with a token that is not known.
+	// Every time, a PING is sent to check if the peer is still alive.
+	SuspectedStatelessResets uint64
+	// PathChallengesSent is the number of PATH_CHALLENGE frames sent, one for every new peer address that was validated.
+	PathChallengesSent uint64
+	// PathChallengesReceived is the number of PATH_CHALLENGE frames received.
+	PathChallengesReceived uint64
+	// PathValidationsSucceeded is the number of new peer addresses that were validated successfully.
+	PathValidationsSucceeded uint64
+	// PathValidationsFailed is the number of new peer addresses that couldn't be validated in time.
+	// Validations that are superseded by another change of the peer address are counted neither as succeeded nor as failed.
+	PathValidationsFailed uint64
+	// LastPathValidationTime is the time it took to validate the most recently validated peer address,
+	// i.e. the time between sending the PATH_CHALLENGE and receiving the matching PATH_RESPONSE frame.
+	LastPathValidationTime time.Duration
+	// SessionTicketsSent is the number of session tickets sent by the server.
+	SessionTicketsSent uint64
+	// SessionTicketsReceived is the number of session tickets received by the client,
+	// including tickets that were discarded (see Config.DiscardSessionTickets).
+	SessionTicketsReceived uint64
+
+	// BytesSent is the number of bytes sent in QUIC packets, including the packet headers.
+	// Retransmissions are counted every time they are sent.
+	BytesSent uint64
+	// PayloadBytesSent is the number of bytes of application payload sent,
+	// i.e. the data sent in STREAM and DATAGRAM frames, excluding the frame headers.
+	PayloadBytesSent uint64
+	// AckFrameBytesSent is the number of bytes spent on ACK frames.
+	AckFrameBytesSent uint64
+	// FlowControlFrameBytesSent is the number of bytes spent on flow control frames,
+	// i.e. on MAX_DATA, MAX_STREAM_DATA, MAX_STREAMS, DATA_BLOCKED, STREAM_DATA_BLOCKED and STREAMS_BLOCKED frames.
+	FlowControlFrameBytesSent uint64
+	// PingFrameBytesSent is the number of bytes spent on PING frames.
+	PingFrameBytesSent uint64
+	// PathValidationFrameBytesSent is the number of bytes spent on PATH_CHALLENGE and PATH_RESPONSE frames.
+	PathValidationFrameBytesSent uint64
+	// OtherControlFrameBytesSent is the number of bytes spent on all other frames,
+	// e.g. on CRYPTO, RESET_STREAM and NEW_CONNECTION_ID frames.
+	OtherControlFrameBytesSent uint64
+
+	// DatagramsReceived is the number of UDP datagrams received,
+	// including datagrams that were dropped because too many datagrams were waiting to be processed.
+	DatagramsReceived uint64
+	// BytesReceived is the number of bytes received in these datagrams.
+	BytesReceived uint64
+	// ReceiveProcessingTime is the time spent processing received datagrams,
+	// i.e. parsing and decrypting the packets, and handling the frames.
+	// Like the SendProcessingTime, it is measured on the session's run loop. It approximates the CPU time spent,
+	// but also includes the time the run loop wasn't scheduled.
+	// The session waits for TLS to process the handshake messages, so the cost of the handshake is included.
+	ReceiveProcessingTime time.Duration
+	// SendProcessingTime is the time spent packing, encrypting and sending packets.
+	// For the client, this includes the time spent creating the ClientHello.
+	SendProcessingTime time.Duration
+
+	// UndecryptablePacketsQueued is the number of packets that are currently queued during the handshake,
+	// because the keys needed to decrypt them are not available yet.
+	UndecryptablePacketsQueued uint64
+	// UndecryptableBytesQueued is the total size of these packets.
+	UndecryptableBytesQueued uint64
+	// UndecryptablePacketsDropped is the number of packets that were dropped because the queue was full,
+	// see Config.MaxUndecryptablePackets and Config.MaxUndecryptableBytes.
+	UndecryptablePacketsDropped uint64
+
+	// SmoothedRTT is the smoothed RTT estimate, see Section 5.3 of RFC 9002.
+	SmoothedRTT time.Duration
+	// RTTVariance is the mean deviation of the RTT samples (rttvar in RFC 9002).
+	RTTVariance time.Duration
+	// MinRTT is the minimum RTT observed.
+	MinRTT time.Duration
+	// LatestRTT is the most recent RTT sample.
+	LatestRTT time.Duration
+	// CongestionWindow is the congestion window, in bytes.
+	CongestionWindow uint64
+	// BytesInFlight is the number of bytes in ack-eliciting packets that were sent,
+	// but neither acknowledged nor declared lost yet.
+	BytesInFlight uint64
+	// PacketsAcked is the number of packets acknowledged by the peer, in all packet number spaces.
+	PacketsAcked uint64
+	// PacketsLost is the number of packets declared lost, in all packet number spaces.
+	// This includes spurious losses, see SpuriousLosses.
+	PacketsLost uint64
+	// Initial, Handshake and ApplicationData are the statistics of the three packet number spaces.
+	// 0-RTT and 1-RTT packets are both counted in the ApplicationData packet number space.
+	Initial         PacketNumberSpaceStats
+	Handshake       PacketNumberSpaceStats
+	ApplicationData PacketNumberSpaceStats
+}
+
+// PacketNumberSpaceStats are the statistics of one packet number space.
+type PacketNumberSpaceStats struct {
+	// PacketsSent is the number of packets sent.
+	PacketsSent uint64
+	// PacketsAcked is the number of packets acknowledged by the peer.
+	PacketsAcked uint64
+	// PacketsLost is the number of packets declared lost.
+	PacketsLost uint64
+	// BytesSent is the number of bytes sent in these packets.
+	BytesSent uint64
+	// PacketsReceived is the number of packets received and successfully decrypted.
+	PacketsReceived uint64
+	// BytesReceived is the number of bytes received in these packets.
+	BytesReceived uint64
+}
+
+// packetNumberSpace returns the statistics of the packet number space of an encryption level.
+func (s *ConnectionStats) packetNumberSpace(encLevel protocol.EncryptionLevel) *PacketNumberSpaceStats {
+	//nolint:exhaustive // Packets are only sent and received at these encryption levels.
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		return &s.Initial
+	case protocol.EncryptionHandshake:
+		return &s.Handshake
+	default:
+		return &s.ApplicationData
+	}
+}
+
+// AckOnlyPacketRatio is the fraction of packets that were ACK-only packets.
+func (s ConnectionStats) AckOnlyPacketRatio() float64 {
+	if s.PacketsSent == 0 {
+		return 0
+	}
+	return float64(s.AckOnlyPacketsSent) / float64(s.PacketsSent)
+}
+
+// ProcessingTime is the total time spent processing received datagrams and sending packets.
+func (s ConnectionStats) ProcessingTime() time.Duration {
+	return s.ReceiveProcessingTime + s.SendProcessingTime
+}
+
+// ProcessingTimePerDatagram is the average processing time per received datagram.
+// Peers that make the connection spend a lot of time per datagram, or that send a lot of small datagrams,
+// can be detected using this value and DatagramsReceived.
+func (s ConnectionStats) ProcessingTimePerDatagram() time.Duration {
+	if s.DatagramsReceived == 0 {
+		return 0
+	}
+	return s.ProcessingTime() / time.Duration(s.DatagramsReceived)
+}
+
+// GoodputEfficiency is the fraction of the bytes sent that were application payload.
+// The remaining bytes were spent on packet headers, frame headers, control frames, padding and the AEAD overhead.
+func (s ConnectionStats) GoodputEfficiency() float64 {
+	if s.BytesSent == 0 {
+		return 0
+	}
+	return float64(s.PayloadBytesSent) / float64(s.BytesSent)
 }
 
 // A Listener for incoming QUIC connections
@@ -12,10 +12,13 @@ func NewAckHandler(
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	persistentCongestionThreshold int,
+	adaptivePacketThreshold bool,
+	ackRangeFilter AckRangeFilter,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) (SentPacketHandler, ReceivedPacketHandler) {
-	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, pers, tracer, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger, version)
+	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, pers, persistentCongestionThreshold, adaptivePacketThreshold, tracer, logger)
+	return sph, newReceivedPacketHandler(sph, rttStats, ackRangeFilter, logger, version)
 }
@@ -3,6 +3,7 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/congestion"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -20,9 +21,35 @@ type Packet struct {
 
 	includedInBytesInFlight bool
 	declaredLost            bool
+	lostByLossDetection     bool // declaredLost is also set when the packet's frames are retransmitted in a PTO probe packet
+	lostByPacketThreshold   bool
 	skippedPacket           bool
 }
 
+// PacketNumberSpaceStats are the statistics of the packets sent in one packet number space.
+type PacketNumberSpaceStats struct {
+	PacketsSent  uint64
+	PacketsAcked uint64
+	PacketsLost  uint64
+	BytesSent    uint64
+}
+
+// Stats is a snapshot of the RTT estimates, the congestion controller and the packets sent.
+type Stats struct {
+	SmoothedRTT      time.Duration
+	RTTVariance      time.Duration
+	MinRTT           time.Duration
+	LatestRTT        time.Duration
+	CongestionWindow protocol.ByteCount
+	BytesInFlight    protocol.ByteCount
+
+	Initial, Handshake, AppData PacketNumberSpaceStats
+}
+
+// An AckRangeFilter is passed the ranges of received 1-RTT packets before an ACK frame is sent,
+// and returns the ranges that are acknowledged.
+type AckRangeFilter func([]wire.AckRange) []wire.AckRange
+
 // SentPacketHandler handles ACKs received for outgoing packets
 type SentPacketHandler interface {
 	// SentPacket may modify the packet
@@ -32,6 +59,9 @@ type SentPacketHandler interface {
 	DropPackets(protocol.EncryptionLevel)
 	ResetForRetry() error
 	SetHandshakeConfirmed()
+	// MigratedPath resets the congestion controller and the RTT estimator,
+	// after the peer's new address was validated (see section 9.4 of RFC 9000).
+	MigratedPath()
 
 	// The SendMode determines if and what kind of packets can be sent.
 	SendMode() SendMode
@@ -50,6 +80,19 @@ type SentPacketHandler interface {
 
 	GetLossDetectionTimeout() time.Time
 	OnLossDetectionTimeout() error
+
+	// SpuriousLosses is the number of packets that were declared lost, but acknowledged later.
+	// It is safe to call it concurrently with the other methods.
+	SpuriousLosses() uint64
+	// PacerState returns a snapshot of the state of the pacer.
+	// It is safe to call it concurrently with the other methods.
+	PacerState() congestion.PacerState
+	// Stats returns a snapshot of the RTT estimates, the congestion controller and the packets sent.
+	// It is safe to call it concurrently with the other methods.
+	Stats() Stats
+	// InFlightPackets returns copies of the packets that were sent,
+	// and that were neither acknowledged nor declared lost yet, ordered by encryption level and packet number.
+	InFlightPackets() []Packet
 }
 
 type sentPacketTracker interface {
@@ -0,0 +1,78 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A lostPeriod tracks the period during which all sent packets were declared lost,
+// which is used to detect persistent congestion (see Section 7.6.2 of RFC 9002).
+// Lost packets are removed from the sent packet history after a while,
+// so the period needs to be tracked across calls to detectLostPackets.
+type lostPeriod struct {
+	// All packets up to this packet number were examined.
+	largestExamined protocol.PacketNumber
+	// The first and the last ack-eliciting packet of the period.
+	// firstSendTime is zero if there's no period.
+	firstPN, lastPN             protocol.PacketNumber
+	firstSendTime, lastSendTime time.Time
+}
+
+func newLostPeriod() lostPeriod {
+	return lostPeriod{largestExamined: protocol.InvalidPacketNumber}
+}
+
+// Add examines the next packet in the history.
+// Packets need to be passed in the order of their packet numbers, and lost is set if the packet was declared lost.
+// Packets that are not considered for persistent congestion (Path MTU probe packets and packets sent before the first RTT sample)
+// don't start or end a period, but they don't interrupt it either.
+// It returns false if the packet is still outstanding. The following packets can't be examined until it is lost or acknowledged.
+func (l *lostPeriod) Add(p *Packet, lost, considered bool) bool {
+	if p.PacketNumber <= l.largestExamined {
+		return true
+	}
+	// If there's a gap, the packets in between were acknowledged (or they weren't ack-eliciting).
+	if l.largestExamined != protocol.InvalidPacketNumber && p.PacketNumber != l.largestExamined+1 {
+		l.Reset()
+		l.largestExamined = p.PacketNumber - 1
+	}
+	if !lost && !p.skippedPacket {
+		return false
+	}
+	l.largestExamined = p.PacketNumber
+	if p.skippedPacket || !considered {
+		return true
+	}
+	if l.firstSendTime.IsZero() {
+		l.firstPN = p.PacketNumber
+		l.firstSendTime = p.SendTime
+	}
+	l.lastPN = p.PacketNumber
+	l.lastSendTime = p.SendTime
+	return true
+}
+
+// Duration is the time between sending the first and the last packet of the period.
+func (l *lostPeriod) Duration() time.Duration {
+	if l.firstSendTime.IsZero() {
+		return 0
+	}
+	return l.lastSendTime.Sub(l.firstSendTime)
+}
+
+// Acked is called when a packet is acknowledged.
+// This ends the period, if the packet was sent during the period.
+func (l *lostPeriod) Acked(pn protocol.PacketNumber) {
+	if !l.firstSendTime.IsZero() && pn >= l.firstPN && pn <= l.largestExamined {
+		l.Reset()
+	}
+}
+
+// Reset ends the period. The packets examined so far are not examined again.
+func (l *lostPeriod) Reset() {
+	l.firstPN = 0
+	l.lastPN = 0
+	l.firstSendTime = time.Time{}
+	l.lastSendTime = time.Time{}
+}
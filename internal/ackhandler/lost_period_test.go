@@ -0,0 +1,86 @@
+package ackhandler
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lost Period", func() {
+	var (
+		period lostPeriod
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		period = newLostPeriod()
+		now = time.Now()
+	})
+
+	packet := func(pn protocol.PacketNumber) *Packet {
+		return &Packet{PacketNumber: pn, SendTime: now.Add(time.Duration(pn) * time.Second)}
+	}
+
+	It("tracks lost packets", func() {
+		Expect(period.Duration()).To(BeZero())
+		Expect(period.Add(packet(1), true, true)).To(BeTrue())
+		Expect(period.Duration()).To(BeZero())
+		Expect(period.Add(packet(2), true, true)).To(BeTrue())
+		Expect(period.Add(packet(3), true, true)).To(BeTrue())
+		Expect(period.Duration()).To(Equal(2 * time.Second))
+		Expect(period.firstPN).To(Equal(protocol.PacketNumber(1)))
+		Expect(period.lastPN).To(Equal(protocol.PacketNumber(3)))
+	})
+
+	It("ignores packets that were already examined", func() {
+		Expect(period.Add(packet(1), true, true)).To(BeTrue())
+		Expect(period.Add(packet(2), true, true)).To(BeTrue())
+		Expect(period.Add(packet(1), false, true)).To(BeTrue())
+		Expect(period.Duration()).To(Equal(time.Second))
+	})
+
+	It("stops at outstanding packets", func() {
+		Expect(period.Add(packet(1), true, true)).To(BeTrue())
+		Expect(period.Add(packet(2), false, true)).To(BeFalse())
+		Expect(period.largestExamined).To(Equal(protocol.PacketNumber(1)))
+		// the packet is declared lost later
+		Expect(period.Add(packet(2), true, true)).To(BeTrue())
+		Expect(period.Duration()).To(Equal(time.Second))
+	})
+
+	It("doesn't start or end a period with packets that are not considered", func() {
+		Expect(period.Add(packet(1), true, false)).To(BeTrue())
+		Expect(period.Add(packet(2), true, true)).To(BeTrue())
+		Expect(period.Add(packet(3), true, false)).To(BeTrue())
+		Expect(period.Add(&Packet{PacketNumber: 4, skippedPacket: true}, false, true)).To(BeTrue())
+		Expect(period.Add(packet(5), true, true)).To(BeTrue())
+		Expect(period.firstPN).To(Equal(protocol.PacketNumber(2)))
+		Expect(period.lastPN).To(Equal(protocol.PacketNumber(5)))
+		Expect(period.Duration()).To(Equal(3 * time.Second))
+	})
+
+	It("starts a new period after a gap in the packet numbers", func() {
+		Expect(period.Add(packet(1), true, true)).To(BeTrue())
+		Expect(period.Add(packet(2), true, true)).To(BeTrue())
+		Expect(period.Add(packet(4), true, true)).To(BeTrue())
+		Expect(period.Duration()).To(BeZero())
+		Expect(period.Add(packet(5), true, true)).To(BeTrue())
+		Expect(period.firstPN).To(Equal(protocol.PacketNumber(4)))
+		Expect(period.Duration()).To(Equal(time.Second))
+	})
+
+	It("ends the period when a packet sent during the period is acknowledged", func() {
+		Expect(period.Add(packet(1), true, true)).To(BeTrue())
+		Expect(period.Add(packet(2), true, true)).To(BeTrue())
+		period.Acked(3)
+		Expect(period.Duration()).To(Equal(time.Second))
+		period.Acked(2)
+		Expect(period.Duration()).To(BeZero())
+		Expect(period.Add(packet(3), true, true)).To(BeTrue())
+		Expect(period.Add(packet(4), true, true)).To(BeTrue())
+		Expect(period.firstPN).To(Equal(protocol.PacketNumber(3)))
+	})
+})
@@ -24,14 +24,15 @@ var _ ReceivedPacketHandler = &receivedPacketHandler{}
 func newReceivedPacketHandler(
 	sentPackets sentPacketTracker,
 	rttStats *utils.RTTStats,
+	ackRangeFilter AckRangeFilter,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
 		sentPackets:      sentPackets,
-		initialPackets:   newReceivedPacketTracker(rttStats, logger, version),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger, version),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger, version),
+		initialPackets:   newReceivedPacketTracker(rttStats, nil, logger, version),
+		handshakePackets: newReceivedPacketTracker(rttStats, nil, logger, version),
+		appDataPackets:   newReceivedPacketTracker(rttStats, ackRangeFilter, logger, version),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
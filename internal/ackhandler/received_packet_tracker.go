@@ -1,6 +1,7 @@
 package ackhandler
 
 import (
+	"sort"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -19,8 +20,9 @@ type receivedPacketTracker struct {
 
 	packetHistory *receivedPacketHistory
 
-	maxAckDelay time.Duration
-	rttStats    *utils.RTTStats
+	maxAckDelay    time.Duration
+	rttStats       *utils.RTTStats
+	ackRangeFilter AckRangeFilter
 
 	hasNewAck bool // true as soon as we received an ack-eliciting new packet
 	ackQueued bool // true once we received more than 2 (or later in the connection 10) ack-eliciting packets
@@ -36,15 +38,17 @@ type receivedPacketTracker struct {
 
 func newReceivedPacketTracker(
 	rttStats *utils.RTTStats,
+	ackRangeFilter AckRangeFilter,
 	logger utils.Logger,
 	version protocol.VersionNumber,
 ) *receivedPacketTracker {
 	return &receivedPacketTracker{
-		packetHistory: newReceivedPacketHistory(),
-		maxAckDelay:   protocol.MaxAckDelay,
-		rttStats:      rttStats,
-		logger:        logger,
-		version:       version,
+		packetHistory:  newReceivedPacketHistory(),
+		maxAckDelay:    protocol.MaxAckDelay,
+		rttStats:       rttStats,
+		ackRangeFilter: ackRangeFilter,
+		logger:         logger,
+		version:        version,
 	}
 }
 
@@ -171,8 +175,20 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 		}
 	}
 
+	ackRanges := h.packetHistory.GetAckRanges()
+	if h.ackRangeFilter != nil {
+		ackRanges = filterAckRanges(ackRanges, h.ackRangeFilter)
+		if len(ackRanges) == 0 {
+			// All packets are withheld. Don't send an ACK, but also don't keep the ACK alarm armed.
+			h.ackAlarm = time.Time{}
+			h.ackQueued = false
+			h.hasNewAck = false
+			h.ackElicitingPacketsReceivedSinceLastAck = 0
+			return nil
+		}
+	}
 	ack := &wire.AckFrame{
-		AckRanges: h.packetHistory.GetAckRanges(),
+		AckRanges: ackRanges,
 		// Make sure that the DelayTime is always positive.
 		// This is not guaranteed on systems that don't have a monotonic clock.
 		DelayTime: utils.MaxDuration(0, now.Sub(h.largestObservedReceivedTime)),
@@ -189,6 +205,42 @@ func (h *receivedPacketTracker) GetAckFrame(onlyIfQueued bool) *wire.AckFrame {
 	return ack
 }
 
+// filterAckRanges passes a copy of the received ranges to the filter.
+// The ranges returned by the filter are restricted to packets that were actually received,
+// such that the filter can only withhold acknowledgments, but never acknowledge a packet that wasn't received.
+func filterAckRanges(received []wire.AckRange, filter AckRangeFilter) []wire.AckRange {
+	ranges := make([]wire.AckRange, len(received))
+	copy(ranges, received)
+	var acked []wire.AckRange
+	for _, a := range filter(ranges) {
+		if a.Smallest > a.Largest {
+			continue
+		}
+		for _, r := range received {
+			smallest := utils.MaxPacketNumber(r.Smallest, a.Smallest)
+			largest := utils.MinPacketNumber(r.Largest, a.Largest)
+			if smallest <= largest {
+				acked = append(acked, wire.AckRange{Smallest: smallest, Largest: largest})
+			}
+		}
+	}
+	if len(acked) == 0 {
+		return nil
+	}
+	// ACK ranges are sorted in descending order, and need to be separated by at least one packet
+	sort.Slice(acked, func(i, j int) bool { return acked[i].Largest > acked[j].Largest })
+	merged := acked[:1]
+	for _, r := range acked[1:] {
+		last := &merged[len(merged)-1]
+		if r.Largest+1 >= last.Smallest {
+			last.Smallest = utils.MinPacketNumber(last.Smallest, r.Smallest)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
 func (h *receivedPacketTracker) GetAlarmTimeout() time.Time { return h.ackAlarm }
 
 func (h *receivedPacketTracker) IsPotentiallyDuplicate(pn protocol.PacketNumber) bool {
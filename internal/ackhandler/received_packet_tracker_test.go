@@ -19,7 +19,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger, protocol.VersionWhatever)
+		tracker = newReceivedPacketTracker(rttStats, nil, utils.DefaultLogger, protocol.VersionWhatever)
 	})
 
 	Context("accepting packets", func() {
@@ -344,5 +344,77 @@ var _ = Describe("Received Packet Tracker", func() {
 				})
 			})
 		})
+
+		Context("filtering ACK ranges", func() {
+			receive := func(pns ...protocol.PacketNumber) {
+				for _, pn := range pns {
+					tracker.ReceivedPacket(pn, protocol.ECNNon, time.Now(), true)
+				}
+			}
+
+			It("passes the received ranges to the filter", func() {
+				var received []wire.AckRange
+				tracker.ackRangeFilter = func(r []wire.AckRange) []wire.AckRange {
+					received = r
+					return r
+				}
+				receive(1, 2, 3, 5, 6)
+				ack := tracker.GetAckFrame(false)
+				Expect(ack).ToNot(BeNil())
+				Expect(received).To(Equal([]wire.AckRange{{Smallest: 5, Largest: 6}, {Smallest: 1, Largest: 3}}))
+				Expect(ack.AckRanges).To(Equal(received))
+				// modifying the slice doesn't modify the history
+				received[0].Largest = 100
+				receive(8)
+				ack = tracker.GetAckFrame(false)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.AckRanges[0]).To(Equal(wire.AckRange{Smallest: 8, Largest: 8}))
+			})
+
+			It("withholds acknowledgments", func() {
+				tracker.ackRangeFilter = func([]wire.AckRange) []wire.AckRange {
+					return []wire.AckRange{{Smallest: 6, Largest: 6}, {Smallest: 1, Largest: 2}}
+				}
+				receive(1, 2, 3, 4, 5, 6)
+				ack := tracker.GetAckFrame(false)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.AckRanges).To(Equal([]wire.AckRange{{Smallest: 6, Largest: 6}, {Smallest: 1, Largest: 2}}))
+				Expect(ack.AcksPacket(4)).To(BeFalse())
+			})
+
+			It("doesn't acknowledge packets that were not received", func() {
+				tracker.ackRangeFilter = func([]wire.AckRange) []wire.AckRange {
+					return []wire.AckRange{{Smallest: 1, Largest: 100}}
+				}
+				receive(3, 4, 7, 8)
+				ack := tracker.GetAckFrame(false)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.AckRanges).To(Equal([]wire.AckRange{{Smallest: 7, Largest: 8}, {Smallest: 3, Largest: 4}}))
+			})
+
+			It("sorts and merges the ranges returned by the filter", func() {
+				tracker.ackRangeFilter = func([]wire.AckRange) []wire.AckRange {
+					return []wire.AckRange{
+						{Smallest: 1, Largest: 2},
+						{Smallest: 5, Largest: 4}, // invalid
+						{Smallest: 6, Largest: 9},
+						{Smallest: 3, Largest: 3},
+						{Smallest: 8, Largest: 10},
+					}
+				}
+				receive(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+				ack := tracker.GetAckFrame(false)
+				Expect(ack).ToNot(BeNil())
+				Expect(ack.AckRanges).To(Equal([]wire.AckRange{{Smallest: 6, Largest: 10}, {Smallest: 1, Largest: 3}}))
+			})
+
+			It("doesn't send an ACK if all packets are withheld", func() {
+				tracker.ackRangeFilter = func([]wire.AckRange) []wire.AckRange { return nil }
+				receive(1, 2, 3)
+				Expect(tracker.GetAckFrame(true)).To(BeNil())
+				Expect(tracker.GetAlarmTimeout()).To(BeZero())
+				Expect(tracker.GetAckFrame(false)).To(BeNil())
+			})
+		})
 	})
 })
@@ -3,6 +3,8 @@ package ackhandler
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/congestion"
@@ -19,6 +21,8 @@ const (
 	timeThreshold = 9.0 / 8
 	// Maximum reordering in packets before packet threshold loss detection considers a packet lost.
 	packetThreshold = 3
+	// When the packet threshold is adapted after detecting spurious losses, it is never increased beyond this value.
+	maxPacketThreshold = 10
 	// Before validating the client's address, the server won't send more than 3x bytes than it received.
 	amplificationFactor = 3
 	// We use Retry packets to derive an RTT estimate. Make sure we don't set the RTT to a super low value yet.
@@ -34,6 +38,8 @@ type packetNumberSpace struct {
 
 	largestAcked protocol.PacketNumber
 	largestSent  protocol.PacketNumber
+
+	lostPeriod lostPeriod
 }
 
 func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool, rttStats *utils.RTTStats) *packetNumberSpace {
@@ -48,6 +54,7 @@ func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool, rttStat
 		pns:          pns,
 		largestSent:  protocol.InvalidPacketNumber,
 		largestAcked: protocol.InvalidPacketNumber,
+		lostPeriod:   newLostPeriod(),
 	}
 }
 
@@ -79,6 +86,19 @@ type sentPacketHandler struct {
 
 	congestion congestion.SendAlgorithmWithDebugInfos
 	rttStats   *utils.RTTStats
+	// The time when the first RTT sample was obtained.
+	// Only packets sent after this time are considered for persistent congestion detection.
+	firstRTTSampleTime time.Time
+	// The multiplier applied to the PTO (including max_ack_delay) to obtain the persistent congestion duration.
+	persistentCongestionThreshold int
+
+	// The number of packets that were declared lost, but acknowledged later.
+	// Accessed atomically, since it is read by SpuriousLosses.
+	spuriousLosses uint64
+	// The reordering threshold used for packet threshold loss detection.
+	// If adaptivePacketThreshold is set, it is increased when a spurious loss is detected.
+	packetThreshold         protocol.PacketNumber
+	adaptivePacketThreshold bool
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -90,6 +110,10 @@ type sentPacketHandler struct {
 	// The alarm timeout
 	alarm time.Time
 
+	// stats is updated on the run loop, and read by Stats
+	statsMutex sync.Mutex
+	stats      Stats
+
 	perspective protocol.Perspective
 
 	tracer logging.ConnectionTracer
@@ -106,6 +130,8 @@ func newSentPacketHandler(
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
 	pers protocol.Perspective,
+	persistentCongestionThreshold int,
+	adaptivePacketThreshold bool,
 	tracer logging.ConnectionTracer,
 	logger utils.Logger,
 ) *sentPacketHandler {
@@ -125,6 +151,9 @@ func newSentPacketHandler(
 		appDataPackets:                 newPacketNumberSpace(0, true, rttStats),
 		rttStats:                       rttStats,
 		congestion:                     congestion,
+		persistentCongestionThreshold:  persistentCongestionThreshold,
+		packetThreshold:                packetThreshold,
+		adaptivePacketThreshold:        adaptivePacketThreshold,
 		perspective:                    pers,
 		tracer:                         tracer,
 		logger:                         logger,
@@ -194,6 +223,7 @@ func (h *sentPacketHandler) dropPackets(encLevel protocol.EncryptionLevel) {
 	h.ptoCount = 0
 	h.numProbesToSend = 0
 	h.ptoMode = SendNone
+	h.updateStats()
 	h.setLossDetectionTimer()
 }
 
@@ -231,6 +261,10 @@ func (h *sentPacketHandler) SentPacket(packet *Packet) {
 	}
 	isAckEliciting := h.sentPacketImpl(packet)
 	h.getPacketNumberSpace(packet.EncryptionLevel).history.SentPacket(packet, isAckEliciting)
+	h.countPackets(packet.EncryptionLevel, func(s *PacketNumberSpaceStats) {
+		s.PacketsSent++
+		s.BytesSent += uint64(packet.Length)
+	})
 	if h.tracer != nil && isAckEliciting {
 		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
 	}
@@ -282,6 +316,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 
 	largestAcked := ack.LargestAcked()
 	if largestAcked > pnSpace.largestSent {
+		if h.tracer != nil {
+			h.tracer.DetectedPeerAnomaly(logging.PeerAnomalyAckForUnsentPacket, fmt.Sprintf("largest acked: %d, largest sent: %d (%s)", largestAcked, pnSpace.largestSent, encLevel))
+		}
 		return false, &qerr.TransportError{
 			ErrorCode:    qerr.ProtocolViolation,
 			ErrorMessage: "received ACK for an unsent packet",
@@ -313,6 +350,9 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 				ackDelay = utils.MinDuration(ack.DelayTime, h.rttStats.MaxAckDelay())
 			}
 			h.rttStats.UpdateRTT(rcvTime.Sub(p.SendTime), ackDelay, rcvTime)
+			if h.firstRTTSampleTime.IsZero() {
+				h.firstRTTSampleTime = rcvTime
+			}
 			if h.logger.Debug() {
 				h.logger.Debugf("\tupdated RTT: %s (σ: %s)", h.rttStats.SmoothedRTT(), h.rttStats.MeanDeviation())
 			}
@@ -322,6 +362,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	if err := h.detectLostPackets(rcvTime, encLevel); err != nil {
 		return false, err
 	}
+	h.countPackets(encLevel, func(s *PacketNumberSpaceStats) { s.PacketsAcked += uint64(len(ackedPackets)) })
 	var acked1RTTPacket bool
 	for _, p := range ackedPackets {
 		if p.includedInBytesInFlight && !p.declaredLost {
@@ -345,6 +386,7 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	if h.tracer != nil {
 		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
 	}
+	h.updateStats()
 
 	pnSpace.history.DeleteOldPackets(rcvTime)
 	h.setLossDetectionTimer()
@@ -388,14 +430,21 @@ func (h *sentPacketHandler) detectAndRemoveAckedPackets(ack *wire.AckFrame, encL
 			}
 		}
 		if p.skippedPacket {
+			if h.tracer != nil {
+				h.tracer.DetectedPeerAnomaly(logging.PeerAnomalyAckForUnsentPacket, fmt.Sprintf("skipped packet number: %d (%s)", p.PacketNumber, encLevel))
+			}
 			return false, &qerr.TransportError{
 				ErrorCode:    qerr.ProtocolViolation,
 				ErrorMessage: fmt.Sprintf("received an ACK for skipped packet number: %d (%s)", p.PacketNumber, encLevel),
 			}
 		}
+		pnSpace.lostPeriod.Acked(p.PacketNumber)
 		h.ackedPackets = append(h.ackedPackets, p)
 		return true, nil
 	})
+	if err == nil {
+		h.detectSpuriousLosses(encLevel)
+	}
 	if h.logger.Debug() && len(h.ackedPackets) > 0 {
 		pns := make([]protocol.PacketNumber, len(h.ackedPackets))
 		for i, p := range h.ackedPackets {
@@ -425,6 +474,101 @@ func (h *sentPacketHandler) detectAndRemoveAckedPackets(ack *wire.AckFrame, encL
 	return h.ackedPackets, err
 }
 
+// detectSpuriousLosses counts the newly acknowledged packets that were previously declared lost.
+// If the adaptive packet threshold is enabled, and one of these packets was declared lost because
+// of the packet threshold, the packet threshold is increased (by at most one per ACK frame).
+func (h *sentPacketHandler) detectSpuriousLosses(encLevel protocol.EncryptionLevel) {
+	var increaseThreshold bool
+	for _, p := range h.ackedPackets {
+		// Packets retransmitted in PTO probe packets were never declared lost by loss detection.
+		if !p.lostByLossDetection || p.IsPathMTUProbePacket {
+			continue
+		}
+		atomic.AddUint64(&h.spuriousLosses, 1)
+		if h.logger.Debug() {
+			h.logger.Debugf("	spurious loss of packet %d (%s)", p.PacketNumber, encLevel)
+		}
+		if p.lostByPacketThreshold && h.adaptivePacketThreshold {
+			increaseThreshold = true
+		}
+	}
+	if increaseThreshold && h.packetThreshold < maxPacketThreshold {
+		h.packetThreshold++
+		if h.logger.Debug() {
+			h.logger.Debugf("	increasing packet threshold to %d", h.packetThreshold)
+		}
+	}
+}
+
+func (h *sentPacketHandler) PacerState() congestion.PacerState {
+	return h.congestion.PacerState()
+}
+
+func (h *sentPacketHandler) Stats() Stats {
+	h.statsMutex.Lock()
+	defer h.statsMutex.Unlock()
+	return h.stats
+}
+
+// countPackets applies update to the statistics of the packet number space of the encryption level,
+// and updates the snapshot returned by Stats.
+func (h *sentPacketHandler) countPackets(encLevel protocol.EncryptionLevel, update func(*PacketNumberSpaceStats)) {
+	h.statsMutex.Lock()
+	defer h.statsMutex.Unlock()
+
+	//nolint:exhaustive // Only packets of these encryption levels are sent.
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		update(&h.stats.Initial)
+	case protocol.EncryptionHandshake:
+		update(&h.stats.Handshake)
+	case protocol.Encryption0RTT, protocol.Encryption1RTT:
+		update(&h.stats.AppData)
+	}
+	h.snapshotStats()
+}
+
+// updateStats updates the snapshot of the RTT estimates and the congestion controller returned by Stats.
+func (h *sentPacketHandler) updateStats() {
+	h.statsMutex.Lock()
+	h.snapshotStats()
+	h.statsMutex.Unlock()
+}
+
+// snapshotStats must be called with the statsMutex held.
+func (h *sentPacketHandler) snapshotStats() {
+	h.stats.SmoothedRTT = h.rttStats.SmoothedRTT()
+	h.stats.RTTVariance = h.rttStats.MeanDeviation()
+	h.stats.MinRTT = h.rttStats.MinRTT()
+	h.stats.LatestRTT = h.rttStats.LatestRTT()
+	h.stats.CongestionWindow = h.congestion.GetCongestionWindow()
+	h.stats.BytesInFlight = h.bytesInFlight
+}
+
+func (h *sentPacketHandler) InFlightPackets() []Packet {
+	var packets []Packet
+	for _, pnSpace := range []*packetNumberSpace{h.initialPackets, h.handshakePackets, h.appDataPackets} {
+		if pnSpace == nil {
+			continue
+		}
+		pnSpace.history.Iterate(func(p *Packet) (bool, error) {
+			if p.declaredLost || p.skippedPacket {
+				return true, nil
+			}
+			packet := *p
+			packet.Frames = make([]Frame, len(p.Frames))
+			copy(packet.Frames, p.Frames)
+			packets = append(packets, packet)
+			return true, nil
+		})
+	}
+	return packets
+}
+
+func (h *sentPacketHandler) SpuriousLosses() uint64 {
+	return atomic.LoadUint64(&h.spuriousLosses)
+}
+
 func (h *sentPacketHandler) getLossTimeAndSpace() (time.Time, protocol.EncryptionLevel) {
 	var encLevel protocol.EncryptionLevel
 	var lossTime time.Time
@@ -563,12 +707,27 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 	// Packets sent before this time are deemed lost.
 	lostSendTime := now.Add(-lossDelay)
 
+	// Persistent congestion is detected if two ack-eliciting packets are declared lost,
+	// the time between sending them is longer than the persistent congestion duration,
+	// and all packets sent in between were lost as well.
+	// The packets might have been declared lost in different calls, so the lost period is kept across calls.
+	var persistentCongestion *logging.PersistentCongestion
+	var pcDuration time.Duration
+	if h.persistentCongestionThreshold > 0 && !h.firstRTTSampleTime.IsZero() {
+		pcDuration = h.rttStats.PTO(encLevel == protocol.Encryption1RTT) * time.Duration(h.persistentCongestionThreshold)
+	}
+	// set when a packet is still outstanding, and following packets can't be added to the lost period
+	lostPeriodBlocked := pcDuration == 0
+
 	priorInFlight := h.bytesInFlight
-	return pnSpace.history.Iterate(func(p *Packet) (bool, error) {
+	if err := pnSpace.history.Iterate(func(p *Packet) (bool, error) {
 		if p.PacketNumber > pnSpace.largestAcked {
 			return false, nil
 		}
 		if p.declaredLost || p.skippedPacket {
+			if !lostPeriodBlocked {
+				pnSpace.lostPeriod.Add(p, p.declaredLost, h.consideredForPersistentCongestion(p))
+			}
 			return true, nil
 		}
 
@@ -581,8 +740,9 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			if h.tracer != nil {
 				h.tracer.LostPacket(p.EncryptionLevel, p.PacketNumber, logging.PacketLossTimeThreshold)
 			}
-		} else if pnSpace.largestAcked >= p.PacketNumber+packetThreshold {
+		} else if pnSpace.largestAcked >= p.PacketNumber+h.packetThreshold {
 			packetLost = true
+			p.lostByPacketThreshold = true
 			if h.logger.Debug() {
 				h.logger.Debugf("\tlost packet %d (reordering threshold)", p.PacketNumber)
 			}
@@ -598,7 +758,9 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 			pnSpace.lossTime = lossTime
 		}
 		if packetLost {
+			h.countPackets(encLevel, func(s *PacketNumberSpaceStats) { s.PacketsLost++ })
 			p.declaredLost = true
+			p.lostByLossDetection = true
 			// the bytes in flight need to be reduced no matter if the frames in this packet will be retransmitted
 			h.removeFromBytesInFlight(p)
 			h.queueFramesForRetransmission(p)
@@ -606,8 +768,40 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 				h.congestion.OnPacketLost(p.PacketNumber, p.Length, priorInFlight)
 			}
 		}
+		if !lostPeriodBlocked {
+			lostPeriod := &pnSpace.lostPeriod
+			lostPeriodBlocked = !lostPeriod.Add(p, packetLost, h.consideredForPersistentCongestion(p))
+			if d := lostPeriod.Duration(); packetLost && d > pcDuration {
+				persistentCongestion = &logging.PersistentCongestion{
+					Duration:        d,
+					Threshold:       pcDuration,
+					FirstLostPacket: lostPeriod.firstPN,
+					LastLostPacket:  lostPeriod.lastPN,
+				}
+			}
+		}
 		return true, nil
-	})
+	}); err != nil {
+		return err
+	}
+	if persistentCongestion != nil {
+		// Start a new period, so that persistent congestion is not declared again for the same packets.
+		pnSpace.lostPeriod.Reset()
+		if h.logger.Debug() {
+			h.logger.Debugf("	persistent congestion: lost packets %d to %d, sent over %s", persistentCongestion.FirstLostPacket, persistentCongestion.LastLostPacket, persistentCongestion.Duration)
+		}
+		h.congestion.OnRetransmissionTimeout(true)
+		if h.tracer != nil {
+			h.tracer.DetectedPersistentCongestion(encLevel, *persistentCongestion)
+		}
+	}
+	return nil
+}
+
+// consideredForPersistentCongestion says if a packet can start or end a lost period.
+// Path MTU probe packets and packets sent before the first RTT sample are not considered.
+func (h *sentPacketHandler) consideredForPersistentCongestion(p *Packet) bool {
+	return !p.IsPathMTUProbePacket && !p.SendTime.Before(h.firstRTTSampleTime)
 }
 
 func (h *sentPacketHandler) OnLossDetectionTimeout() error {
@@ -830,6 +1024,14 @@ func (h *sentPacketHandler) ResetForRetry() error {
 	return nil
 }
 
+func (h *sentPacketHandler) MigratedPath() {
+	h.rttStats.OnConnectionMigration()
+	h.congestion.OnConnectionMigration()
+	// Only packets sent after the first RTT sample on the new path are considered for persistent congestion.
+	h.firstRTTSampleTime = time.Time{}
+	h.updateStats()
+}
+
 func (h *sentPacketHandler) SetHandshakeConfirmed() {
 	h.handshakeConfirmed = true
 	// We don't send PTOs for application data packets before the handshake completes.
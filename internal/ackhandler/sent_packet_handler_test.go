@@ -7,10 +7,12 @@ import (
 	"github.com/golang/mock/gomock"
 
 	"github.com/lucas-clemente/quic-go/internal/mocks"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -29,7 +31,7 @@ var _ = Describe("SentPacketHandler", func() {
 	JustBeforeEach(func() {
 		lostPackets = nil
 		rttStats := utils.NewRTTStats()
-		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, perspective, nil, utils.DefaultLogger)
+		handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, rttStats, perspective, protocol.DefaultPersistentCongestionThreshold, false, nil, utils.DefaultLogger)
 		streamFrame = wire.StreamFrame{
 			StreamID: 5,
 			Data:     []byte{0x13, 0x37},
@@ -143,6 +145,36 @@ var _ = Describe("SentPacketHandler", func() {
 			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: sendTime.Add(time.Hour), EncryptionLevel: protocol.Encryption1RTT}))
 			Expect(handler.initialPackets.lastAckElicitingPacketTime).To(Equal(sendTime))
 		})
+
+		It("returns the packets in flight", func() {
+			handler.SentPacket(initialPacket(&Packet{PacketNumber: 1}))
+			handler.SentPacket(handshakePacket(&Packet{PacketNumber: 2, Length: 42}))
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			// acknowledge packet 5, which declares packets 1 and 2 lost
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			packets := handler.InFlightPackets()
+			Expect(packets).To(HaveLen(5))
+			Expect(packets[0].EncryptionLevel).To(Equal(protocol.EncryptionInitial))
+			Expect(packets[0].PacketNumber).To(Equal(protocol.PacketNumber(1)))
+			Expect(packets[1].EncryptionLevel).To(Equal(protocol.EncryptionHandshake))
+			Expect(packets[1].PacketNumber).To(Equal(protocol.PacketNumber(2)))
+			Expect(packets[1].Length).To(Equal(protocol.ByteCount(42)))
+			Expect(packets[2].PacketNumber).To(Equal(protocol.PacketNumber(3)))
+			Expect(packets[3].PacketNumber).To(Equal(protocol.PacketNumber(4)))
+			Expect(packets[3].Frames).To(HaveLen(1))
+			Expect(packets[3].Frames[0].Frame).To(Equal(&wire.PingFrame{}))
+			Expect(packets[4].PacketNumber).To(Equal(protocol.PacketNumber(6)))
+			// the snapshot is not modified when the packets are acknowledged
+			packets[3].Frames[0].Frame = nil
+			Expect(getPacket(4, protocol.Encryption1RTT).Frames[0].Frame).ToNot(BeNil())
+			Expect(handler.InFlightPackets()).To(HaveLen(5))
+			handler.DropPackets(protocol.EncryptionInitial)
+			Expect(handler.InFlightPackets()).To(HaveLen(4))
+		})
 	})
 
 	Context("ACK processing", func() {
@@ -217,6 +249,15 @@ var _ = Describe("SentPacketHandler", func() {
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(10)))
 			})
 
+			It("reports ACKs for unsent packets to the tracer", func() {
+				tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+				handler.tracer = tracer
+				tracer.EXPECT().DetectedPeerAnomaly(logging.PeerAnomalyAckForUnsentPacket, "largest acked: 9999, largest sent: 9 (1-RTT)")
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 9999}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+				Expect(err).To(HaveOccurred())
+			})
+
 			It("ignores repeated ACKs", func() {
 				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 3}}}
 				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
@@ -487,6 +528,8 @@ var _ = Describe("SentPacketHandler", func() {
 
 		JustBeforeEach(func() {
 			cong = mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			// the congestion window is read for the stats whenever a packet is sent or acknowledged
+			cong.EXPECT().GetCongestionWindow().AnyTimes()
 			handler.congestion = cong
 		})
 
@@ -506,6 +549,16 @@ var _ = Describe("SentPacketHandler", func() {
 			})
 		})
 
+		It("resets the congestion controller and the RTT estimator when migrating to a new path", func() {
+			handler.rttStats.UpdateRTT(time.Second, 0, time.Now())
+			handler.firstRTTSampleTime = time.Now()
+			cong.EXPECT().OnConnectionMigration()
+			handler.MigratedPath()
+			Expect(handler.rttStats.SmoothedRTT()).To(BeZero())
+			Expect(handler.firstRTTSampleTime).To(BeZero())
+			Expect(handler.Stats().SmoothedRTT).To(BeZero())
+		})
+
 		It("should call MaybeExitSlowStart and OnPacketAcked", func() {
 			rcvTime := time.Now().Add(-5 * time.Second)
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
@@ -589,6 +642,130 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		Context("persistent congestion", func() {
+			It("detects persistent congestion", func() {
+				tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+				tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().AcknowledgedPacket(gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().LostPacket(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().LossTimerCanceled().AnyTimes()
+				handler.tracer = tracer
+				now := time.Now()
+				handler.firstRTTSampleTime = now.Add(-time.Hour)
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-10 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now.Add(-9 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, SendTime: now.Add(-8 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4, SendTime: now}))
+				cong.EXPECT().MaybeExitSlowStart()
+				cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(4), gomock.Any(), gomock.Any(), gomock.Any())
+				cong.EXPECT().OnRetransmissionTimeout(true)
+				tracer.EXPECT().DetectedPersistentCongestion(protocol.Encryption1RTT, gomock.Any()).Do(func(_ protocol.EncryptionLevel, pc logging.PersistentCongestion) {
+					Expect(pc.Duration).To(Equal(2 * time.Second))
+					Expect(pc.Threshold).To(Equal(handler.rttStats.PTO(true) * protocol.DefaultPersistentCongestionThreshold))
+					Expect(pc.FirstLostPacket).To(Equal(protocol.PacketNumber(1)))
+					Expect(pc.LastLostPacket).To(Equal(protocol.PacketNumber(3)))
+				})
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Millisecond))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("detects persistent congestion if the packets are declared lost in different calls", func() {
+				tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+				tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().AcknowledgedPacket(gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().LostPacket(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				tracer.EXPECT().LossTimerCanceled().AnyTimes()
+				handler.tracer = tracer
+				now := time.Now()
+				handler.firstRTTSampleTime = now.Add(-time.Hour)
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(6)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-2 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now.Add(-1950 * time.Millisecond)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, SendTime: now.Add(-105 * time.Millisecond)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4, SendTime: now.Add(-104 * time.Millisecond)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 5, SendTime: now.Add(-100 * time.Millisecond)}))
+				// packets 1 and 2 are declared lost, packets 3 and 4 are still outstanding
+				cong.EXPECT().MaybeExitSlowStart()
+				cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(5), gomock.Any(), gomock.Any(), gomock.Any())
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 5}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+				Expect(err).ToNot(HaveOccurred())
+				// packets 1 and 2 were removed from the history
+				Expect(getPacket(1, protocol.Encryption1RTT)).To(BeNil())
+				Expect(getPacket(2, protocol.Encryption1RTT)).To(BeNil())
+				// packets 3 and 4 are declared lost
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 6, SendTime: now.Add(900 * time.Millisecond)}))
+				cong.EXPECT().MaybeExitSlowStart()
+				cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(6), gomock.Any(), gomock.Any(), gomock.Any())
+				cong.EXPECT().OnRetransmissionTimeout(true)
+				tracer.EXPECT().DetectedPersistentCongestion(protocol.Encryption1RTT, gomock.Any()).Do(func(_ protocol.EncryptionLevel, pc logging.PersistentCongestion) {
+					Expect(pc.Duration).To(Equal(1896 * time.Millisecond))
+					Expect(pc.FirstLostPacket).To(Equal(protocol.PacketNumber(1)))
+					Expect(pc.LastLostPacket).To(Equal(protocol.PacketNumber(4)))
+				})
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Second))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("doesn't detect persistent congestion if a packet sent in between was acknowledged", func() {
+				now := time.Now()
+				handler.firstRTTSampleTime = now.Add(-time.Hour)
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(4)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-10 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now.Add(-9 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, SendTime: now.Add(-8 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 4, SendTime: now}))
+				cong.EXPECT().MaybeExitSlowStart()
+				cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				cong.EXPECT().OnPacketAcked(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				// don't EXPECT any calls to OnRetransmissionTimeout
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 4, Largest: 4}, {Smallest: 2, Largest: 2}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Millisecond))
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("doesn't detect persistent congestion for packets sent before the first RTT sample", func() {
+				now := time.Now()
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-10 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now.Add(-9 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, SendTime: now}))
+				cong.EXPECT().MaybeExitSlowStart()
+				cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(3), gomock.Any(), gomock.Any(), gomock.Any())
+				// don't EXPECT any calls to OnRetransmissionTimeout
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 3}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Millisecond))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.firstRTTSampleTime).ToNot(BeZero())
+			})
+
+			It("uses the configured persistent congestion threshold", func() {
+				handler.persistentCongestionThreshold = 1000
+				now := time.Now()
+				handler.firstRTTSampleTime = now.Add(-time.Hour)
+				cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 1, SendTime: now.Add(-10 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 2, SendTime: now.Add(-9 * time.Second)}))
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 3, SendTime: now}))
+				cong.EXPECT().MaybeExitSlowStart()
+				cong.EXPECT().OnPacketLost(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+				cong.EXPECT().OnPacketAcked(protocol.PacketNumber(3), gomock.Any(), gomock.Any(), gomock.Any())
+				// 1s is less than 1000 PTOs, so don't EXPECT any calls to OnRetransmissionTimeout
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 3, Largest: 3}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Millisecond))
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+
 		It("passes the bytes in flight to the congestion controller", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(42), gomock.Any(), protocol.ByteCount(42), true)
@@ -669,6 +846,17 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{10}))
 		})
 
+		It("doesn't count probed packets that are acknowledged as spurious losses", func() {
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 10}))
+			handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: 11}))
+			Expect(handler.QueueProbePacket(protocol.Encryption1RTT)).To(BeTrue())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{10}))
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10, Largest: 11}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.SpuriousLosses()).To(BeZero())
+		})
+
 		It("says when it can't queue a probe packet", func() {
 			queued := handler.QueueProbePacket(protocol.Encryption1RTT)
 			Expect(queued).To(BeFalse())
@@ -1057,6 +1245,99 @@ var _ = Describe("SentPacketHandler", func() {
 			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
 		})
+
+		It("counts spurious losses", func() {
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			Expect(handler.SpuriousLosses()).To(BeZero())
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 6}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.SpuriousLosses()).To(BeEquivalentTo(2))
+			// the packet threshold is not adapted by default
+			Expect(handler.packetThreshold).To(BeEquivalentTo(packetThreshold))
+		})
+
+		It("reports the packets sent, acknowledged and lost, and the RTT", func() {
+			now := time.Now()
+			handler.SentPacket(initialPacket(&Packet{PacketNumber: 1, Length: 1200}))
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i, Length: 100, SendTime: now.Add(-100 * time.Millisecond)}))
+			}
+			stats := handler.Stats()
+			Expect(stats.Initial).To(Equal(PacketNumberSpaceStats{PacketsSent: 1, BytesSent: 1200}))
+			Expect(stats.AppData).To(Equal(PacketNumberSpaceStats{PacketsSent: 6, BytesSent: 600}))
+			Expect(stats.BytesInFlight).To(BeEquivalentTo(1800))
+			Expect(stats.CongestionWindow).ToNot(BeZero())
+			Expect(stats.SmoothedRTT).To(BeZero())
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 6}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			stats = handler.Stats()
+			Expect(stats.AppData).To(Equal(PacketNumberSpaceStats{PacketsSent: 6, PacketsAcked: 2, PacketsLost: 3, BytesSent: 600}))
+			Expect(stats.Initial.PacketsAcked).To(BeZero())
+			Expect(stats.BytesInFlight).To(BeEquivalentTo(1300))
+			Expect(stats.SmoothedRTT).To(Equal(100 * time.Millisecond))
+			Expect(stats.LatestRTT).To(Equal(100 * time.Millisecond))
+			Expect(stats.MinRTT).To(Equal(100 * time.Millisecond))
+			Expect(stats.RTTVariance).To(Equal(50 * time.Millisecond))
+		})
+
+		It("increases the packet threshold when a spurious loss is detected, if enabled", func() {
+			handler.adaptivePacketThreshold = true
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			// acknowledging multiple spurious losses in a single ACK frame only increases the threshold once
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 6}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.SpuriousLosses()).To(BeEquivalentTo(3))
+			Expect(handler.packetThreshold).To(BeEquivalentTo(packetThreshold + 1))
+			// packet 7 is not declared lost when packet 10 is acknowledged
+			lostPackets = nil
+			for i := protocol.PacketNumber(7); i <= 11; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10, Largest: 10}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(BeEmpty())
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 10, Largest: 11}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{7}))
+		})
+
+		It("doesn't increase the packet threshold beyond the maximum", func() {
+			handler.adaptivePacketThreshold = true
+			handler.packetThreshold = maxPacketThreshold
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 11; i++ {
+				handler.SentPacket(ackElicitingPacket(&Packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 11, Largest: 11}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1}))
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 11, Largest: 11}, {Smallest: 1, Largest: 1}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.SpuriousLosses()).To(BeEquivalentTo(1))
+			Expect(handler.packetThreshold).To(BeEquivalentTo(maxPacketThreshold))
+		})
 	})
 
 	Context("Delay-based loss detection", func() {
@@ -151,6 +151,10 @@ func (c *cubicSender) CanSend(bytesInFlight protocol.ByteCount) bool {
 	return bytesInFlight < c.GetCongestionWindow()
 }
 
+func (c *cubicSender) PacerState() PacerState {
+	return c.pacer.State(c.clock.Now())
+}
+
 func (c *cubicSender) InRecovery() bool {
 	return c.largestAckedPacketNumber != protocol.InvalidPacketNumber && c.largestAckedPacketNumber <= c.largestSentAtLastCutback
 }
@@ -282,7 +286,7 @@ func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	c.congestionWindow = c.minCongestionWindow()
 }
 
-// OnConnectionMigration is called when the connection is migrated (?)
+// OnConnectionMigration is called when the connection is migrated to a new path.
 func (c *cubicSender) OnConnectionMigration() {
 	c.hybridSlowStart.Restart()
 	c.largestSentPacketNumber = protocol.InvalidPacketNumber
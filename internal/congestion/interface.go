@@ -16,6 +16,8 @@ type SendAlgorithm interface {
 	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time)
 	OnPacketLost(number protocol.PacketNumber, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount)
 	OnRetransmissionTimeout(packetsRetransmitted bool)
+	// OnConnectionMigration resets the congestion controller to its initial state.
+	OnConnectionMigration()
 	SetMaxDatagramSize(protocol.ByteCount)
 }
 
@@ -25,4 +27,7 @@ type SendAlgorithmWithDebugInfos interface {
 	InSlowStart() bool
 	InRecovery() bool
 	GetCongestionWindow() protocol.ByteCount
+	// PacerState returns a snapshot of the state of the pacer.
+	// It is safe to call it concurrently with the other methods.
+	PacerState() PacerState
 }
@@ -2,6 +2,7 @@ package congestion
 
 import (
 	"math"
+	"sync"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -16,6 +17,28 @@ type pacer struct {
 	maxDatagramSize      protocol.ByteCount
 	lastSentTime         time.Time
 	getAdjustedBandwidth func() uint64 // in bytes/s
+
+	// State is called concurrently with the other methods.
+	// It uses a copy of the values that were current when the last packet was sent.
+	stateMutex sync.Mutex
+	lastState  pacerValues
+}
+
+// PacerState is a snapshot of the state of the pacer.
+type PacerState struct {
+	// Budget is the number of bytes that can be sent right away.
+	Budget protocol.ByteCount
+	// NextSendTime is the time when the next full-size packet can be sent.
+	// It is the zero value of time.Time if a packet can be sent immediately.
+	NextSendTime time.Time
+}
+
+type pacerValues struct {
+	budgetAtLastSent protocol.ByteCount
+	lastSentTime     time.Time
+	maxDatagramSize  protocol.ByteCount
+	maxBurstSize     protocol.ByteCount
+	bandwidth        uint64 // in bytes/s
 }
 
 func newPacer(getBandwidth func() Bandwidth) *pacer {
@@ -32,6 +55,7 @@ func newPacer(getBandwidth func() Bandwidth) *pacer {
 		},
 	}
 	p.budgetAtLastSent = p.maxBurstSize()
+	p.lastState = p.values()
 	return p
 }
 
@@ -43,20 +67,28 @@ func (p *pacer) SentPacket(sendTime time.Time, size protocol.ByteCount) {
 		p.budgetAtLastSent = budget - size
 	}
 	p.lastSentTime = sendTime
+
+	values := p.values()
+	p.stateMutex.Lock()
+	p.lastState = values
+	p.stateMutex.Unlock()
 }
 
 func (p *pacer) Budget(now time.Time) protocol.ByteCount {
 	if p.lastSentTime.IsZero() {
 		return p.maxBurstSize()
 	}
-	budget := p.budgetAtLastSent + (protocol.ByteCount(p.getAdjustedBandwidth())*protocol.ByteCount(now.Sub(p.lastSentTime).Nanoseconds()))/1e9
-	return utils.MinByteCount(p.maxBurstSize(), budget)
+	return p.values().budget(now)
 }
 
 func (p *pacer) maxBurstSize() protocol.ByteCount {
+	return maxBurstSize(p.getAdjustedBandwidth(), p.maxDatagramSize)
+}
+
+func maxBurstSize(bandwidth uint64, maxDatagramSize protocol.ByteCount) protocol.ByteCount {
 	return utils.MaxByteCount(
-		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*p.getAdjustedBandwidth())/1e9,
-		maxBurstSizePackets*p.maxDatagramSize,
+		protocol.ByteCount(uint64((protocol.MinPacingDelay+protocol.TimerGranularity).Nanoseconds())*bandwidth)/1e9,
+		maxBurstSizePackets*maxDatagramSize,
 	)
 }
 
@@ -66,9 +98,48 @@ func (p *pacer) TimeUntilSend() time.Time {
 	if p.budgetAtLastSent >= p.maxDatagramSize {
 		return time.Time{}
 	}
-	return p.lastSentTime.Add(utils.MaxDuration(
+	return p.values().timeUntilSend()
+}
+
+// State returns a snapshot of the state of the pacer.
+// The bandwidth estimate used is the one that was current when the last packet was sent.
+// It is safe to call it concurrently with the other methods.
+func (p *pacer) State(now time.Time) PacerState {
+	p.stateMutex.Lock()
+	values := p.lastState
+	p.stateMutex.Unlock()
+	if values.lastSentTime.IsZero() {
+		return PacerState{Budget: values.maxBurstSize}
+	}
+	s := PacerState{Budget: values.budget(now)}
+	if values.budgetAtLastSent < values.maxDatagramSize {
+		if t := values.timeUntilSend(); t.After(now) {
+			s.NextSendTime = t
+		}
+	}
+	return s
+}
+
+func (p *pacer) values() pacerValues {
+	bw := p.getAdjustedBandwidth()
+	return pacerValues{
+		budgetAtLastSent: p.budgetAtLastSent,
+		lastSentTime:     p.lastSentTime,
+		maxDatagramSize:  p.maxDatagramSize,
+		maxBurstSize:     maxBurstSize(bw, p.maxDatagramSize),
+		bandwidth:        bw,
+	}
+}
+
+func (v pacerValues) budget(now time.Time) protocol.ByteCount {
+	budget := v.budgetAtLastSent + (protocol.ByteCount(v.bandwidth)*protocol.ByteCount(now.Sub(v.lastSentTime).Nanoseconds()))/1e9
+	return utils.MinByteCount(v.maxBurstSize, budget)
+}
+
+func (v pacerValues) timeUntilSend() time.Time {
+	return v.lastSentTime.Add(utils.MaxDuration(
 		protocol.MinPacingDelay,
-		time.Duration(math.Ceil(float64(p.maxDatagramSize-p.budgetAtLastSent)*1e9/float64(p.getAdjustedBandwidth())))*time.Nanosecond,
+		time.Duration(math.Ceil(float64(v.maxDatagramSize-v.budgetAtLastSent)*1e9/float64(v.bandwidth)))*time.Nanosecond,
 	))
 }
 
@@ -67,6 +67,35 @@ var _ = Describe("Pacer", func() {
 		}
 	})
 
+	Context("state", func() {
+		It("reports the burst size at the beginning", func() {
+			Expect(p.State(time.Now())).To(Equal(PacerState{Budget: maxBurstSizePackets * initialMaxDatagramSize}))
+		})
+
+		It("reports the next send time after a burst", func() {
+			t := time.Now()
+			sendBurst(t)
+			state := p.State(t)
+			Expect(state.Budget).To(BeZero())
+			Expect(state.NextSendTime).To(Equal(p.TimeUntilSend()))
+			// half of the time until the next packet can be sent has passed
+			state = p.State(t.Add(time.Second / packetsPerSecond / 2))
+			Expect(state.Budget).To(BeEquivalentTo(initialMaxDatagramSize / 2))
+			Expect(state.NextSendTime).To(Equal(p.TimeUntilSend()))
+			// the next send time has passed
+			state = p.State(t.Add(time.Second / packetsPerSecond))
+			Expect(state.Budget).To(BeEquivalentTo(initialMaxDatagramSize))
+			Expect(state.NextSendTime).To(BeZero())
+		})
+
+		It("uses the bandwidth that was current when the last packet was sent", func() {
+			t := time.Now()
+			sendBurst(t)
+			bandwidth *= 2
+			Expect(p.State(t.Add(time.Second / packetsPerSecond / 2)).Budget).To(BeEquivalentTo(initialMaxDatagramSize / 2))
+		})
+	})
+
 	It("accounts for non-full-size packets", func() {
 		t := time.Now()
 		sendBurst(t)
@@ -10,9 +10,11 @@ import (
 
 type baseFlowController struct {
 	// for sending data
+	// bytesSent and sendWindow are only modified while holding the mutex, such that State can read them.
 	bytesSent     protocol.ByteCount
 	sendWindow    protocol.ByteCount
 	lastBlockedAt protocol.ByteCount
+	blockedCount  uint64
 
 	// for receiving data
 	//nolint:structcheck // The mutex is used both by the stream and the connection flow controller
@@ -22,6 +24,8 @@ type baseFlowController struct {
 	receiveWindow        protocol.ByteCount
 	receiveWindowSize    protocol.ByteCount
 	maxReceiveWindowSize protocol.ByteCount
+	shouldUpdateWindow   WindowUpdateFunc // nil if the default threshold is used
+	lastWindowUpdate     time.Time
 
 	epochStartTime   time.Time
 	epochStartOffset protocol.ByteCount
@@ -38,18 +42,40 @@ func (c *baseFlowController) IsNewlyBlocked() (bool, protocol.ByteCount) {
 		return false, 0
 	}
 	c.lastBlockedAt = c.sendWindow
+	c.mutex.Lock()
+	c.blockedCount++
+	c.mutex.Unlock()
 	return true, c.sendWindow
 }
 
 func (c *baseFlowController) AddBytesSent(n protocol.ByteCount) {
+	c.mutex.Lock()
 	c.bytesSent += n
+	c.mutex.Unlock()
 }
 
 // UpdateSendWindow is be called after receiving a MAX_{STREAM_}DATA frame.
 func (c *baseFlowController) UpdateSendWindow(offset protocol.ByteCount) {
+	c.mutex.Lock()
 	if offset > c.sendWindow {
 		c.sendWindow = offset
 	}
+	c.mutex.Unlock()
+}
+
+func (c *baseFlowController) State() WindowState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return WindowState{
+		BytesSent:            c.bytesSent,
+		SendWindow:           c.sendWindow,
+		BlockedCount:         c.blockedCount,
+		BytesRead:            c.bytesRead,
+		HighestReceived:      c.highestReceived,
+		ReceiveWindow:        c.receiveWindow,
+		MaxReceiveWindowSize: c.maxReceiveWindowSize,
+	}
 }
 
 func (c *baseFlowController) sendWindowSize() protocol.ByteCount {
@@ -65,17 +91,44 @@ func (c *baseFlowController) addBytesRead(n protocol.ByteCount) {
 	// pretend we sent a WindowUpdate when reading the first byte
 	// this way auto-tuning of the window size already works for the first WindowUpdate
 	if c.bytesRead == 0 {
-		c.startNewAutoTuningEpoch(time.Now())
+		now := time.Now()
+		c.startNewAutoTuningEpoch(now)
+		c.lastWindowUpdate = now
 	}
 	c.bytesRead += n
 }
 
+// hasWindowUpdate says if a window update might be necessary.
+// It is called when the application reads data, so it must be cheap:
+// If a WindowUpdateFunc is set, it is true as soon as data was read since the last window update,
+// and the WindowUpdateFunc is called on the run loop, see windowUpdateFuncState.
+// needs to be called with locked mutex
 func (c *baseFlowController) hasWindowUpdate() bool {
 	bytesRemaining := c.receiveWindow - c.bytesRead
+	if c.shouldUpdateWindow != nil {
+		return bytesRemaining < c.receiveWindowSize
+	}
 	// update the window when more than the threshold was consumed
 	return bytesRemaining <= protocol.ByteCount(float64(c.receiveWindowSize)*(1-protocol.WindowUpdateThreshold))
 }
 
+// windowUpdateFuncState returns the state passed to the WindowUpdateFunc,
+// and false if no window update is necessary anyway.
+// The WindowUpdateFunc is user code, so the caller must not hold the mutex when calling it.
+// needs to be called with locked mutex
+func (c *baseFlowController) windowUpdateFuncState() (ReceiveWindow, bool) {
+	if !c.hasWindowUpdate() {
+		return ReceiveWindow{}, false
+	}
+	return ReceiveWindow{
+		BytesRead:       c.bytesRead,
+		Offset:          c.receiveWindow,
+		Size:            c.receiveWindowSize,
+		SmoothedRTT:     c.rttStats.SmoothedRTT(),
+		SinceLastUpdate: time.Since(c.lastWindowUpdate),
+	}, true
+}
+
 // getWindowUpdate updates the receive window, if necessary
 // it returns the new offset
 func (c *baseFlowController) getWindowUpdate() protocol.ByteCount {
@@ -85,6 +138,7 @@ func (c *baseFlowController) getWindowUpdate() protocol.ByteCount {
 
 	c.maybeAdjustWindowSize()
 	c.receiveWindow = c.bytesRead + c.receiveWindowSize
+	c.lastWindowUpdate = time.Now()
 	return c.receiveWindow
 }
 
@@ -86,6 +86,18 @@ var _ = Describe("Base Flow controller", func() {
 			newlyBlocked, _ = controller.IsNewlyBlocked()
 			Expect(newlyBlocked).To(BeTrue())
 		})
+
+		It("counts how often it was blocked", func() {
+			controller.UpdateSendWindow(100)
+			controller.AddBytesSent(100)
+			controller.IsNewlyBlocked()
+			controller.IsNewlyBlocked()
+			Expect(controller.State().BlockedCount).To(BeEquivalentTo(1))
+			controller.UpdateSendWindow(150)
+			controller.AddBytesSent(50)
+			controller.IsNewlyBlocked()
+			Expect(controller.State().BlockedCount).To(BeEquivalentTo(2))
+		})
 	})
 
 	Context("receive flow control", func() {
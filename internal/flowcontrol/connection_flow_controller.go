@@ -24,6 +24,7 @@ func NewConnectionFlowController(
 	receiveWindow protocol.ByteCount,
 	maxReceiveWindow protocol.ByteCount,
 	queueWindowUpdate func(),
+	shouldUpdateWindow WindowUpdateFunc,
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 ) ConnectionFlowController {
@@ -33,6 +34,7 @@ func NewConnectionFlowController(
 			receiveWindow:        receiveWindow,
 			receiveWindowSize:    receiveWindow,
 			maxReceiveWindowSize: maxReceiveWindow,
+			shouldUpdateWindow:   shouldUpdateWindow,
 			logger:               logger,
 		},
 		queueWindowUpdate: queueWindowUpdate,
@@ -68,8 +70,25 @@ func (c *connectionFlowController) AddBytesRead(n protocol.ByteCount) {
 	}
 }
 
+// BufferedBytes returns the number of bytes that were received on all streams,
+// but haven't been read by the application yet.
+func (c *connectionFlowController) BufferedBytes() protocol.ByteCount {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.highestReceived - c.bytesRead
+}
+
 func (c *connectionFlowController) GetWindowUpdate() protocol.ByteCount {
 	c.mutex.Lock()
+	if c.shouldUpdateWindow != nil {
+		w, ok := c.windowUpdateFuncState()
+		c.mutex.Unlock()
+		if !ok || !c.shouldUpdateWindow(w) {
+			return 0
+		}
+		c.mutex.Lock()
+	}
 	oldWindowSize := c.receiveWindowSize
 	offset := c.baseFlowController.getWindowUpdate()
 	if oldWindowSize < c.receiveWindowSize {
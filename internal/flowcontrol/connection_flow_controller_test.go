@@ -36,12 +36,29 @@ var _ = Describe("Connection Flow controller", func() {
 			receiveWindow := protocol.ByteCount(2000)
 			maxReceiveWindow := protocol.ByteCount(3000)
 
-			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, nil, rttStats, utils.DefaultLogger).(*connectionFlowController)
+			fc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, nil, nil, rttStats, utils.DefaultLogger).(*connectionFlowController)
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
 		})
 	})
 
+	It("returns a snapshot of its state", func() {
+		controller.UpdateSendWindow(1000)
+		controller.AddBytesSent(400)
+		controller.receiveWindow = 500
+		controller.maxReceiveWindowSize = 3000
+		Expect(controller.IncrementHighestReceived(200)).To(Succeed())
+		controller.AddBytesRead(50)
+		Expect(controller.State()).To(Equal(WindowState{
+			BytesSent:            400,
+			SendWindow:           1000,
+			BytesRead:            50,
+			HighestReceived:      200,
+			ReceiveWindow:        500,
+			MaxReceiveWindowSize: 3000,
+		}))
+	})
+
 	Context("receive flow control", func() {
 		It("increases the highestReceived by a given window size", func() {
 			controller.highestReceived = 1337
@@ -49,6 +66,14 @@ var _ = Describe("Connection Flow controller", func() {
 			Expect(controller.highestReceived).To(Equal(protocol.ByteCount(1337 + 123)))
 		})
 
+		It("says how many bytes are buffered", func() {
+			controller.receiveWindow = 1000
+			Expect(controller.IncrementHighestReceived(100)).To(Succeed())
+			Expect(controller.BufferedBytes()).To(Equal(protocol.ByteCount(100)))
+			controller.AddBytesRead(30)
+			Expect(controller.BufferedBytes()).To(Equal(protocol.ByteCount(70)))
+		})
+
 		Context("getting window updates", func() {
 			BeforeEach(func() {
 				controller.receiveWindow = 100
@@ -68,6 +93,54 @@ var _ = Describe("Connection Flow controller", func() {
 				Expect(queuedWindowUpdate).To(BeFalse())
 			})
 
+			It("uses the WindowUpdateFunc to decide when to send window updates", func() {
+				type window struct{ bytesRead, receiveWindow, windowSize protocol.ByteCount }
+				var windows []window
+				controller.shouldUpdateWindow = func(w ReceiveWindow) bool {
+					windows = append(windows, window{w.BytesRead, w.Offset, w.Size})
+					return w.BytesRead >= 45
+				}
+				// The WindowUpdateFunc isn't called when reading, the window update is queued as soon as data was read.
+				controller.AddBytesRead(1)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(windows).To(BeEmpty())
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				controller.AddBytesRead(4)
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(45 + 60)))
+				Expect(windows).To(Equal([]window{{41, 100, 60}, {45, 100, 60}}))
+				// no data was read since the last window update
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				Expect(windows).To(HaveLen(2))
+			})
+
+			It("doesn't hold the mutex when calling the WindowUpdateFunc", func() {
+				controller.shouldUpdateWindow = func(ReceiveWindow) bool {
+					// this would deadlock if the mutex was held
+					controller.AddBytesRead(1)
+					return true
+				}
+				controller.AddBytesRead(1)
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(42 + 60)))
+			})
+
+			It("passes the RTT and the time since the last window update to the WindowUpdateFunc", func() {
+				setRtt(scaleDuration(20 * time.Millisecond))
+				var windows []ReceiveWindow
+				controller.shouldUpdateWindow = func(w ReceiveWindow) bool {
+					windows = append(windows, w)
+					return true
+				}
+				controller.lastWindowUpdate = time.Now().Add(-time.Hour)
+				controller.AddBytesRead(1)
+				Expect(controller.GetWindowUpdate()).ToNot(BeZero())
+				Expect(windows).To(HaveLen(1))
+				Expect(windows[0].SmoothedRTT).To(Equal(scaleDuration(20 * time.Millisecond)))
+				Expect(windows[0].SinceLastUpdate).To(BeNumerically("~", time.Hour, time.Second))
+				controller.AddBytesRead(1)
+				Expect(controller.GetWindowUpdate()).ToNot(BeZero())
+				Expect(windows[len(windows)-1].SinceLastUpdate).To(BeNumerically("<", time.Second))
+			})
+
 			It("gets a window update", func() {
 				windowSize := controller.receiveWindowSize
 				oldOffset := controller.bytesRead
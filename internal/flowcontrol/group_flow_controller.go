@@ -0,0 +1,110 @@
+package flowcontrol
+
+import (
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A GroupFlowController limits the receive window of a group of streams.
+// QUIC doesn't have a frame to limit the data sent on a group of streams.
+// Instead, the flow controllers of the streams in the group only extend their receive windows
+// as long as the credit outstanding on all streams (the data the peer is allowed to send, but which wasn't read yet)
+// doesn't exceed the window of the group.
+// Credit that was granted before a stream joined the group is not revoked.
+type GroupFlowController struct {
+	mutex sync.Mutex
+
+	window      protocol.ByteCount
+	outstanding protocol.ByteCount
+	// streams that couldn't extend their receive window because the group's window was exhausted
+	blocked map[*streamFlowController]struct{}
+}
+
+// NewGroupFlowController creates a new GroupFlowController.
+func NewGroupFlowController(window protocol.ByteCount) *GroupFlowController {
+	return &GroupFlowController{
+		window:  window,
+		blocked: make(map[*streamFlowController]struct{}),
+	}
+}
+
+// SetWindow sets the window of the group.
+func (g *GroupFlowController) SetWindow(window protocol.ByteCount) {
+	g.mutex.Lock()
+	g.window = window
+	blocked := g.unblock()
+	g.mutex.Unlock()
+
+	for _, c := range blocked {
+		c.queueWindowUpdate()
+	}
+}
+
+// Outstanding returns the credit outstanding on all streams in the group.
+func (g *GroupFlowController) Outstanding() protocol.ByteCount {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.outstanding
+}
+
+// add adds credit that was granted without asking the group,
+// i.e. when a stream joins the group.
+func (g *GroupFlowController) add(n protocol.ByteCount) {
+	g.mutex.Lock()
+	g.outstanding += n
+	g.mutex.Unlock()
+}
+
+// reserve is called when a stream wants to extend its receive window by n bytes.
+// It returns the number of bytes the stream is allowed to extend its window by.
+// If that's less than n, the stream is notified when credit becomes available.
+func (g *GroupFlowController) reserve(c *streamFlowController, n protocol.ByteCount) protocol.ByteCount {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var available protocol.ByteCount
+	if g.window > g.outstanding {
+		available = g.window - g.outstanding
+	}
+	if n > available {
+		n = available
+		g.blocked[c] = struct{}{}
+	}
+	g.outstanding += n
+	return n
+}
+
+// release is called when the peer can't use n bytes of credit any more,
+// either because the data was read, or because the stream ended.
+func (g *GroupFlowController) release(n protocol.ByteCount) {
+	g.mutex.Lock()
+	g.outstanding -= n
+	blocked := g.unblock()
+	g.mutex.Unlock()
+
+	for _, c := range blocked {
+		c.queueWindowUpdate()
+	}
+}
+
+// remove removes a stream that won't extend its receive window any more.
+func (g *GroupFlowController) remove(c *streamFlowController) {
+	g.mutex.Lock()
+	delete(g.blocked, c)
+	g.mutex.Unlock()
+}
+
+// unblock returns the blocked streams, if credit is available.
+// It must be called with the mutex held.
+func (g *GroupFlowController) unblock() []*streamFlowController {
+	if len(g.blocked) == 0 || g.outstanding >= g.window {
+		return nil
+	}
+	blocked := make([]*streamFlowController, 0, len(g.blocked))
+	for c := range g.blocked {
+		blocked = append(blocked, c)
+		delete(g.blocked, c)
+	}
+	return blocked
+}
@@ -0,0 +1,102 @@
+package flowcontrol
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Group Flow controller", func() {
+	var (
+		group  *GroupFlowController
+		conn   ConnectionFlowController
+		queued []protocol.StreamID
+	)
+
+	BeforeEach(func() {
+		queued = nil
+		group = NewGroupFlowController(150)
+		conn = NewConnectionFlowController(1000, 1000, func() {}, nil, &utils.RTTStats{}, utils.DefaultLogger)
+	})
+
+	newStream := func(id protocol.StreamID) StreamFlowController {
+		queueWindowUpdate := func(id protocol.StreamID) { queued = append(queued, id) }
+		fc := NewStreamFlowController(id, conn, 100, 100, 0, queueWindowUpdate, nil, &utils.RTTStats{}, utils.DefaultLogger)
+		fc.SetGroup(group)
+		return fc
+	}
+
+	It("limits the credit granted on all streams", func() {
+		str1 := newStream(1)
+		str2 := newStream(5)
+		// the initial credit is not revoked
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(200)))
+		Expect(str1.UpdateHighestReceived(100, false)).To(Succeed())
+		str1.AddBytesRead(100)
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(100)))
+		Expect(queued).To(Equal([]protocol.StreamID{1}))
+		// the window is only extended up to the group's window
+		Expect(str1.GetWindowUpdate()).To(Equal(protocol.ByteCount(150)))
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(150)))
+		// reading data on the other stream frees credit
+		queued = nil
+		Expect(str2.UpdateHighestReceived(50, false)).To(Succeed())
+		str2.AddBytesRead(50)
+		Expect(queued).To(ConsistOf(protocol.StreamID(1), protocol.StreamID(5)))
+		Expect(str1.GetWindowUpdate()).To(Equal(protocol.ByteCount(200)))
+		Expect(str2.GetWindowUpdate()).To(BeZero())
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(150)))
+		// the credit beyond the final offset is released
+		queued = nil
+		Expect(str1.UpdateHighestReceived(120, true)).To(Succeed())
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(70)))
+		Expect(queued).To(Equal([]protocol.StreamID{5}))
+		Expect(str2.GetWindowUpdate()).To(Equal(protocol.ByteCount(150)))
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(120)))
+		// the credit of an abandoned stream is released
+		str2.Abandon()
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(20)))
+		str1.AddBytesRead(20)
+		Expect(group.Outstanding()).To(BeZero())
+	})
+
+	It("notifies blocked streams when the window is increased", func() {
+		str := newStream(1)
+		Expect(str.UpdateHighestReceived(100, false)).To(Succeed())
+		str.AddBytesRead(100)
+		group.SetWindow(100)
+		Expect(str.GetWindowUpdate()).To(Equal(protocol.ByteCount(200)))
+		Expect(str.UpdateHighestReceived(200, false)).To(Succeed())
+		str.AddBytesRead(100)
+		group.SetWindow(50)
+		queued = nil
+		Expect(str.GetWindowUpdate()).To(Equal(protocol.ByteCount(250)))
+		Expect(str.GetWindowUpdate()).To(BeZero())
+		group.SetWindow(200)
+		Expect(queued).To(Equal([]protocol.StreamID{1}))
+		Expect(str.GetWindowUpdate()).To(Equal(protocol.ByteCount(300)))
+	})
+
+	It("limits credit granted in manual mode", func() {
+		str := newStream(1)
+		str.GrantCredit(0)
+		Expect(str.UpdateHighestReceived(100, false)).To(Succeed())
+		str.AddBytesRead(100)
+		group.SetWindow(30)
+		str.GrantCredit(50)
+		Expect(str.GetWindowUpdate()).To(Equal(protocol.ByteCount(130)))
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(30)))
+	})
+
+	It("releases the credit when a stream leaves the group", func() {
+		str := newStream(1)
+		Expect(group.Outstanding()).To(Equal(protocol.ByteCount(100)))
+		str.SetGroup(nil)
+		Expect(group.Outstanding()).To(BeZero())
+		other := NewGroupFlowController(10)
+		str.SetGroup(other)
+		Expect(other.Outstanding()).To(Equal(protocol.ByteCount(100)))
+	})
+})
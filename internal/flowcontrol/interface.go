@@ -1,6 +1,43 @@
 package flowcontrol
 
-import "github.com/lucas-clemente/quic-go/internal/protocol"
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A ReceiveWindow is the state of a receive window, as passed to a WindowUpdateFunc.
+type ReceiveWindow struct {
+	BytesRead protocol.ByteCount
+	// Offset is the offset that was last advertised to the peer.
+	Offset protocol.ByteCount
+	// Size is the current size of the receive window (which is increased by auto-tuning).
+	Size protocol.ByteCount
+	// SmoothedRTT is the smoothed RTT of the connection, 0 if no RTT sample was taken yet.
+	SmoothedRTT time.Duration
+	// SinceLastUpdate is the time since the last window update (or since the first byte was read).
+	SinceLastUpdate time.Duration
+}
+
+// A WindowUpdateFunc decides if a window update should be sent, after the application read data.
+// It is called by GetWindowUpdate, without holding the flow controller's mutex.
+// If no WindowUpdateFunc is set, a window update is sent once more than 25% of the window was consumed.
+type WindowUpdateFunc func(ReceiveWindow) bool
+
+// A WindowState is a snapshot of the state of a flow controller.
+type WindowState struct {
+	// for sending
+	BytesSent  protocol.ByteCount
+	SendWindow protocol.ByteCount
+	// BlockedCount is the number of times that IsNewlyBlocked returned true
+	BlockedCount uint64
+	// for receiving
+	BytesRead       protocol.ByteCount
+	HighestReceived protocol.ByteCount
+	ReceiveWindow   protocol.ByteCount
+	// MaxReceiveWindowSize is the size up to which auto-tuning can increase the receive window
+	MaxReceiveWindowSize protocol.ByteCount
+}
 
 type flowController interface {
 	// for sending
@@ -11,6 +48,9 @@ type flowController interface {
 	AddBytesRead(protocol.ByteCount)
 	GetWindowUpdate() protocol.ByteCount // returns 0 if no update is necessary
 	IsNewlyBlocked() (bool, protocol.ByteCount)
+	// State returns a snapshot of the flow controller.
+	// It is safe to call it concurrently with the other methods.
+	State() WindowState
 }
 
 // A StreamFlowController is a flow controller for a QUIC stream.
@@ -21,6 +61,17 @@ type StreamFlowController interface {
 	// final has to be to true if this is the final offset of the stream,
 	// as contained in a STREAM frame with FIN bit, and the RESET_STREAM frame
 	UpdateHighestReceived(offset protocol.ByteCount, final bool) error
+	// SetMaxReceiveWindow sets the maximum size of the receive window, overriding the configured value.
+	// If the current window is larger, it is reduced. Flow control credit that was already granted is not revoked.
+	SetMaxReceiveWindow(protocol.ByteCount)
+	// GrantCredit extends the receive window by n bytes.
+	// The first call switches the flow controller to manual mode:
+	// From then on, reading data doesn't extend the receive window any more, only GrantCredit does.
+	// A credit of 0 only switches the mode.
+	GrantCredit(n protocol.ByteCount)
+	// SetGroup makes the stream a member of a group of streams.
+	// The receive window is then also limited by the window of the group.
+	SetGroup(*GroupFlowController)
 	// Abandon should be called when reading from the stream is aborted early,
 	// and there won't be any further calls to AddBytesRead.
 	Abandon()
@@ -29,6 +80,8 @@ type StreamFlowController interface {
 // The ConnectionFlowController is the flow controller for the connection.
 type ConnectionFlowController interface {
 	flowController
+	// BufferedBytes returns the number of bytes received, but not yet read
+	BufferedBytes() protocol.ByteCount
 	Reset() error
 }
 
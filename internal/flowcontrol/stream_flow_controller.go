@@ -18,6 +18,15 @@ type streamFlowController struct {
 	connection connectionFlowControllerI
 
 	receivedFinalOffset bool
+
+	// In manual mode, the receive window is only extended by GrantCredit, up to grantedWindow.
+	manualCredit  bool
+	grantedWindow protocol.ByteCount
+
+	// group is nil if the stream is not a member of a group.
+	// groupCredit is the credit this stream accounts for in the group.
+	group       *GroupFlowController
+	groupCredit protocol.ByteCount
 }
 
 var _ StreamFlowController = &streamFlowController{}
@@ -30,6 +39,7 @@ func NewStreamFlowController(
 	maxReceiveWindow protocol.ByteCount,
 	initialSendWindow protocol.ByteCount,
 	queueWindowUpdate func(protocol.StreamID),
+	shouldUpdateWindow WindowUpdateFunc,
 	rttStats *utils.RTTStats,
 	logger utils.Logger,
 ) StreamFlowController {
@@ -43,6 +53,7 @@ func NewStreamFlowController(
 			receiveWindowSize:    receiveWindow,
 			maxReceiveWindowSize: maxReceiveWindow,
 			sendWindow:           initialSendWindow,
+			shouldUpdateWindow:   shouldUpdateWindow,
 			logger:               logger,
 		},
 	}
@@ -68,8 +79,17 @@ func (c *streamFlowController) UpdateHighestReceived(offset protocol.ByteCount,
 		}
 	}
 
-	if final {
+	if final && !c.receivedFinalOffset {
 		c.receivedFinalOffset = true
+		c.mutex.Lock()
+		if c.group != nil {
+			// The peer can't use the credit beyond the final offset.
+			if c.receiveWindow > offset {
+				c.releaseGroupCredit(c.receiveWindow - offset)
+			}
+			c.group.remove(c)
+		}
+		c.mutex.Unlock()
 	}
 	if offset == c.highestReceived {
 		return nil
@@ -97,8 +117,94 @@ func (c *streamFlowController) UpdateHighestReceived(offset protocol.ByteCount,
 	return c.connection.IncrementHighestReceived(increment)
 }
 
+func (c *streamFlowController) SetMaxReceiveWindow(size protocol.ByteCount) {
+	c.mutex.Lock()
+	c.maxReceiveWindowSize = size
+	if c.receiveWindowSize > size {
+		c.receiveWindowSize = size
+	}
+	c.mutex.Unlock()
+}
+
+func (c *streamFlowController) GrantCredit(n protocol.ByteCount) {
+	c.mutex.Lock()
+	if !c.manualCredit {
+		c.manualCredit = true
+		c.grantedWindow = c.receiveWindow
+	}
+	// The window can't be larger than the maximum value of a variable-length integer.
+	if n > protocol.MaxByteCount-c.grantedWindow {
+		c.grantedWindow = protocol.MaxByteCount
+	} else {
+		c.grantedWindow += n
+	}
+	shouldQueueWindowUpdate := !c.receivedFinalOffset && c.grantedWindow > c.receiveWindow
+	c.mutex.Unlock()
+	if shouldQueueWindowUpdate {
+		c.queueWindowUpdate()
+	}
+}
+
+// SetGroup makes the stream a member of a group, replacing the group it was a member of before.
+func (c *streamFlowController) SetGroup(g *GroupFlowController) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.group == g {
+		return
+	}
+	if c.group != nil {
+		c.releaseGroupCredit(c.groupCredit)
+		c.group.remove(c)
+	}
+	c.group = g
+	if g == nil {
+		return
+	}
+	end := c.receiveWindow
+	if c.receivedFinalOffset {
+		end = c.highestReceived
+	}
+	c.groupCredit = 0
+	if end > c.bytesRead {
+		c.groupCredit = end - c.bytesRead
+	}
+	g.add(c.groupCredit)
+}
+
+// releaseGroupCredit releases up to n bytes of the credit accounted for in the group.
+// It must be called with the mutex held.
+func (c *streamFlowController) releaseGroupCredit(n protocol.ByteCount) {
+	if c.group == nil {
+		return
+	}
+	n = utils.MinByteCount(n, c.groupCredit)
+	if n == 0 {
+		return
+	}
+	c.groupCredit -= n
+	c.group.release(n)
+}
+
+// extendReceiveWindow extends the receive window to offset, as far as the group allows.
+// It returns the new offset, or 0 if the window wasn't extended.
+// It must be called with the mutex held.
+func (c *streamFlowController) extendReceiveWindow(offset protocol.ByteCount) protocol.ByteCount {
+	if c.group != nil {
+		granted := c.group.reserve(c, offset-c.receiveWindow)
+		c.groupCredit += granted
+		offset = c.receiveWindow + granted
+	}
+	if offset <= c.receiveWindow {
+		return 0
+	}
+	c.receiveWindow = offset
+	return offset
+}
+
 func (c *streamFlowController) AddBytesRead(n protocol.ByteCount) {
 	c.mutex.Lock()
+	c.releaseGroupCredit(n)
 	c.baseFlowController.addBytesRead(n)
 	shouldQueueWindowUpdate := c.shouldQueueWindowUpdate()
 	c.mutex.Unlock()
@@ -111,6 +217,10 @@ func (c *streamFlowController) AddBytesRead(n protocol.ByteCount) {
 func (c *streamFlowController) Abandon() {
 	c.mutex.Lock()
 	unread := c.highestReceived - c.bytesRead
+	if c.group != nil {
+		c.releaseGroupCredit(c.groupCredit)
+		c.group.remove(c)
+	}
 	c.mutex.Unlock()
 	if unread > 0 {
 		c.connection.AddBytesRead(unread)
@@ -127,7 +237,7 @@ func (c *streamFlowController) SendWindowSize() protocol.ByteCount {
 }
 
 func (c *streamFlowController) shouldQueueWindowUpdate() bool {
-	return !c.receivedFinalOffset && c.hasWindowUpdate()
+	return !c.receivedFinalOffset && !c.manualCredit && c.hasWindowUpdate()
 }
 
 func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
@@ -138,8 +248,29 @@ func (c *streamFlowController) GetWindowUpdate() protocol.ByteCount {
 
 	// Don't use defer for unlocking the mutex here, GetWindowUpdate() is called frequently and defer shows up in the profiler
 	c.mutex.Lock()
+	if c.manualCredit {
+		var offset protocol.ByteCount
+		if c.grantedWindow > c.receiveWindow {
+			offset = c.extendReceiveWindow(c.grantedWindow)
+		}
+		c.mutex.Unlock()
+		return offset
+	}
+	if c.shouldUpdateWindow != nil {
+		w, ok := c.windowUpdateFuncState()
+		c.mutex.Unlock()
+		if !ok || !c.shouldUpdateWindow(w) {
+			return 0
+		}
+		c.mutex.Lock()
+	}
 	oldWindowSize := c.receiveWindowSize
+	oldWindow := c.receiveWindow
 	offset := c.baseFlowController.getWindowUpdate()
+	if offset > 0 && c.group != nil {
+		c.receiveWindow = oldWindow
+		offset = c.extendReceiveWindow(offset)
+	}
 	if c.receiveWindowSize > oldWindowSize { // auto-tuning enlarged the window size
 		c.logger.Debugf("Increasing receive flow control window for stream %d to %d kB", c.streamID, c.receiveWindowSize/(1<<10))
 		c.connection.EnsureMinimumWindowSize(protocol.ByteCount(float64(c.receiveWindowSize) * protocol.ConnectionFlowControlMultiplier))
@@ -22,7 +22,7 @@ var _ = Describe("Stream Flow controller", func() {
 		rttStats := &utils.RTTStats{}
 		controller = &streamFlowController{
 			streamID:   10,
-			connection: NewConnectionFlowController(1000, 1000, func() {}, rttStats, utils.DefaultLogger).(*connectionFlowController),
+			connection: NewConnectionFlowController(1000, 1000, func() {}, nil, rttStats, utils.DefaultLogger).(*connectionFlowController),
 		}
 		controller.maxReceiveWindowSize = 10000
 		controller.rttStats = rttStats
@@ -37,8 +37,8 @@ var _ = Describe("Stream Flow controller", func() {
 		const sendWindow protocol.ByteCount = 4000
 
 		It("sets the send and receive windows", func() {
-			cc := NewConnectionFlowController(0, 0, nil, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(0, 0, nil, nil, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, nil, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
 			Expect(fc.streamID).To(Equal(protocol.StreamID(5)))
 			Expect(fc.receiveWindow).To(Equal(receiveWindow))
 			Expect(fc.maxReceiveWindowSize).To(Equal(maxReceiveWindow))
@@ -52,8 +52,8 @@ var _ = Describe("Stream Flow controller", func() {
 				queued = true
 			}
 
-			cc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, func() {}, nil, utils.DefaultLogger)
-			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, rttStats, utils.DefaultLogger).(*streamFlowController)
+			cc := NewConnectionFlowController(receiveWindow, maxReceiveWindow, func() {}, nil, nil, utils.DefaultLogger)
+			fc := NewStreamFlowController(5, cc, receiveWindow, maxReceiveWindow, sendWindow, queueWindowUpdate, nil, rttStats, utils.DefaultLogger).(*streamFlowController)
 			fc.AddBytesRead(receiveWindow)
 			Expect(queued).To(BeTrue())
 		})
@@ -189,6 +189,24 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(queuedWindowUpdate).To(BeFalse())
 			})
 
+			It("calls the WindowUpdateFunc when getting the window update", func() {
+				var windows []ReceiveWindow
+				sendUpdate := false
+				controller.shouldUpdateWindow = func(w ReceiveWindow) bool {
+					windows = append(windows, w)
+					return sendUpdate
+				}
+				controller.AddBytesRead(1)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(windows).To(BeEmpty())
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				Expect(windows).To(HaveLen(1))
+				Expect(windows[0].BytesRead).To(Equal(protocol.ByteCount(41)))
+				sendUpdate = true
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(41 + 60)))
+				Expect(windows).To(HaveLen(2))
+			})
+
 			It("tells the connection flow controller when the window was autotuned", func() {
 				oldOffset := controller.bytesRead
 				setRtt(scaleDuration(20 * time.Millisecond))
@@ -201,6 +219,28 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(controller.connection.(*connectionFlowController).receiveWindowSize).To(Equal(protocol.ByteCount(float64(controller.receiveWindowSize) * protocol.ConnectionFlowControlMultiplier)))
 			})
 
+			It("autotunes the window up to a maximum window that was set for the stream", func() {
+				controller.SetMaxReceiveWindow(100)
+				setRtt(scaleDuration(20 * time.Millisecond))
+				controller.epochStartOffset = controller.bytesRead
+				controller.epochStartTime = time.Now().Add(-time.Millisecond)
+				controller.AddBytesRead(55)
+				Expect(controller.GetWindowUpdate()).ToNot(BeZero())
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(100)))
+			})
+
+			It("reduces the window size when a smaller maximum window is set", func() {
+				controller.SetMaxReceiveWindow(40)
+				Expect(controller.maxReceiveWindowSize).To(Equal(protocol.ByteCount(40)))
+				Expect(controller.receiveWindowSize).To(Equal(protocol.ByteCount(40)))
+				// the window that was already advertised is not revoked
+				Expect(controller.UpdateHighestReceived(100, false)).To(Succeed())
+				controller.AddBytesRead(20)
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				controller.AddBytesRead(20)
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(80 + 40)))
+			})
+
 			It("sends a connection-level window update when a large stream is abandoned", func() {
 				Expect(controller.UpdateHighestReceived(90, true)).To(Succeed())
 				Expect(controller.connection.GetWindowUpdate()).To(BeZero())
@@ -208,6 +248,39 @@ var _ = Describe("Stream Flow controller", func() {
 				Expect(controller.connection.GetWindowUpdate()).ToNot(BeZero())
 			})
 
+			It("only increases the window when credit is granted, in manual mode", func() {
+				controller.connection.(*connectionFlowController).receiveWindow = 1000
+				controller.GrantCredit(0)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				controller.AddBytesRead(60)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				controller.GrantCredit(25)
+				controller.GrantCredit(5)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.ByteCount(130)))
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+				Expect(controller.UpdateHighestReceived(130, false)).To(Succeed())
+				Expect(controller.UpdateHighestReceived(131, false)).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.FlowControlError,
+					ErrorMessage: "received 131 bytes on stream 10, allowed 130 bytes",
+				}))
+			})
+
+			It("doesn't grant more credit than can be encoded in a MAX_STREAM_DATA frame", func() {
+				controller.GrantCredit(protocol.MaxByteCount - 10)
+				controller.GrantCredit(100)
+				Expect(queuedWindowUpdate).To(BeTrue())
+				Expect(controller.GetWindowUpdate()).To(Equal(protocol.MaxByteCount))
+			})
+
+			It("doesn't queue a window update when credit is granted after the final offset was received", func() {
+				Expect(controller.UpdateHighestReceived(90, true)).To(Succeed())
+				controller.GrantCredit(100)
+				Expect(queuedWindowUpdate).To(BeFalse())
+				Expect(controller.GetWindowUpdate()).To(BeZero())
+			})
+
 			It("doesn't increase the window after a final offset was already received", func() {
 				Expect(controller.UpdateHighestReceived(90, true)).To(Succeed())
 				controller.AddBytesRead(30)
@@ -112,6 +112,7 @@ type cryptoSetup struct {
 	closeChan chan struct{}
 
 	zeroRTTParameters      *wire.TransportParameters
+	ticketAge              time.Duration // only set for the server
 	clientHelloWritten     bool
 	clientHelloWrittenChan chan *wire.TransportParameters
 
@@ -343,7 +344,7 @@ func (h *cryptoSetup) Close() error {
 func (h *cryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLevel) bool /* stream finished */ {
 	msgType := messageType(data[0])
 	h.logger.Debugf("Received %s message (%d bytes, encryption level: %s)", msgType, len(data), encLevel)
-	if err := h.checkEncryptionLevel(msgType, encLevel); err != nil {
+	if err := checkEncryptionLevel(msgType, encLevel); err != nil {
 		h.onError(alertUnexpectedMessage, err.Error())
 		return false
 	}
@@ -377,7 +378,7 @@ readLoop:
 		msgType == typeFinished
 }
 
-func (h *cryptoSetup) checkEncryptionLevel(msgType messageType, encLevel protocol.EncryptionLevel) error {
+func checkEncryptionLevel(msgType messageType, encLevel protocol.EncryptionLevel) error {
 	var expected protocol.EncryptionLevel
 	switch msgType {
 	case typeClientHello,
@@ -413,7 +414,9 @@ func (h *cryptoSetup) handleTransportParameters(data []byte) {
 }
 
 // must be called after receiving the transport parameters
+// It is called for the client when a session ticket is stored in the ClientSessionCache.
 func (h *cryptoSetup) marshalDataForSessionState() []byte {
+	h.runner.OnSessionTicketStored()
 	buf := &bytes.Buffer{}
 	quicvarint.Write(buf, clientSessionStateRevision)
 	quicvarint.Write(buf, uint64(h.rttStats.SmoothedRTT().Microseconds()))
@@ -459,6 +462,7 @@ func (h *cryptoSetup) GetSessionTicket() ([]byte, error) {
 		appData = (&sessionTicket{
 			Parameters: h.ourParams,
 			RTT:        h.rttStats.SmoothedRTT(),
+			IssuedAt:   time.Now(),
 		}).Marshal()
 	}
 	return h.conn.GetSessionTicket(appData)
@@ -472,14 +476,19 @@ func (h *cryptoSetup) accept0RTT(sessionTicketData []byte) bool {
 		h.logger.Debugf("Unmarshalling transport parameters from session ticket failed: %s", err.Error())
 		return false
 	}
-	valid := h.ourParams.ValidFor0RTT(t.Parameters)
-	if valid {
-		h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
-		h.rttStats.SetInitialRTT(t.RTT)
-	} else {
-		h.logger.Debugf("Transport parameters changed. Rejecting 0-RTT.")
+	if !t.IssuedAt.IsZero() {
+		h.ticketAge = time.Since(t.IssuedAt)
 	}
-	return valid
+	if param := h.ourParams.Mismatched0RTTParameter(t.Parameters); param != "" {
+		h.logger.Debugf("Transport parameter %s changed. Rejecting 0-RTT.", param)
+		if h.tracer != nil {
+			h.tracer.Rejected0RTT(param)
+		}
+		return false
+	}
+	h.logger.Debugf("Accepting 0-RTT. Restoring RTT from session ticket: %s", t.RTT)
+	h.rttStats.SetInitialRTT(t.RTT)
+	return true
 }
 
 // rejected0RTT is called for the client when the server rejects 0-RTT.
@@ -580,10 +589,12 @@ func (h *cryptoSetup) SetReadKey(encLevel qtls.EncryptionLevel, suite *qtls.Ciph
 	default:
 		panic("unexpected read encryption level")
 	}
+	installedEncLevel := h.readEncLevel
 	h.mutex.Unlock()
 	if h.tracer != nil {
-		h.tracer.UpdatedKeyFromTLS(h.readEncLevel, h.perspective.Opposite())
+		h.tracer.UpdatedKeyFromTLS(installedEncLevel, h.perspective.Opposite())
 	}
+	h.runner.OnKeysAvailable(installedEncLevel, false)
 }
 
 func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.CipherSuiteTLS13, trafficSecret []byte) {
@@ -627,10 +638,12 @@ func (h *cryptoSetup) SetWriteKey(encLevel qtls.EncryptionLevel, suite *qtls.Cip
 	default:
 		panic("unexpected write encryption level")
 	}
+	installedEncLevel := h.writeEncLevel
 	h.mutex.Unlock()
 	if h.tracer != nil {
-		h.tracer.UpdatedKeyFromTLS(h.writeEncLevel, h.perspective)
+		h.tracer.UpdatedKeyFromTLS(installedEncLevel, h.perspective)
 	}
+	h.runner.OnKeysAvailable(installedEncLevel, true)
 }
 
 // WriteRecord is called when TLS writes data
@@ -795,6 +808,13 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 	return h.aead, nil
 }
 
+// TicketAge returns the time that passed since the session ticket used to resume the session was issued.
+// It is only known if the client offered 0-RTT, otherwise it is 0.
+// Only valid for the server, after completion of the handshake.
+func (h *cryptoSetup) TicketAge() time.Duration {
+	return h.ticketAge
+}
+
 func (h *cryptoSetup) ConnectionState() ConnectionState {
 	return qtls.GetConnectionState(h.conn)
 }
@@ -10,6 +10,7 @@ import (
 	"math/big"
 	"time"
 
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
 	mocktls "github.com/lucas-clemente/quic-go/internal/mocks/tls"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/qerr"
@@ -83,6 +84,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 	It("returns Handshake() when an error occurs in qtls", func() {
 		sErrChan := make(chan error, 1)
 		runner := NewMockHandshakeRunner(mockCtrl)
+		runner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 		runner.EXPECT().OnError(gomock.Any()).Do(func(e error) { sErrChan <- e })
 		_, sInitialStream, sHandshakeStream := initStreams()
 		var token protocol.StatelessResetToken
@@ -128,6 +130,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 		sErrChan := make(chan error, 1)
 		_, sInitialStream, sHandshakeStream := initStreams()
 		runner := NewMockHandshakeRunner(mockCtrl)
+		runner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 		runner.EXPECT().OnError(gomock.Any()).Do(func(e error) { sErrChan <- e })
 		var token protocol.StatelessResetToken
 		server := NewCryptoSetupServer(
@@ -169,6 +172,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 		sErrChan := make(chan error, 1)
 		_, sInitialStream, sHandshakeStream := initStreams()
 		runner := NewMockHandshakeRunner(mockCtrl)
+		runner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 		runner.EXPECT().OnError(gomock.Any()).Do(func(e error) { sErrChan <- e })
 		var token protocol.StatelessResetToken
 		server := NewCryptoSetupServer(
@@ -319,10 +323,12 @@ var _ = Describe("Crypto Setup TLS", func() {
 			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
 			cErrChan := make(chan error, 1)
 			cRunner := NewMockHandshakeRunner(mockCtrl)
+			cRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 			cRunner.EXPECT().OnReceivedParams(gomock.Any())
 			cRunner.EXPECT().OnError(gomock.Any()).Do(func(e error) { cErrChan <- e }).MaxTimes(1)
 			cRunner.EXPECT().OnHandshakeComplete().Do(func() { cHandshakeComplete = true }).MaxTimes(1)
 			cRunner.EXPECT().DropKeys(gomock.Any()).MaxTimes(1)
+			cRunner.EXPECT().OnSessionTicketStored().AnyTimes()
 			client, clientHelloWrittenChan := NewCryptoSetupClient(
 				cInitialStream,
 				cHandshakeStream,
@@ -343,6 +349,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 			sErrChan := make(chan error, 1)
 			sRunner := NewMockHandshakeRunner(mockCtrl)
+			sRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 			sRunner.EXPECT().OnReceivedParams(gomock.Any())
 			sRunner.EXPECT().OnError(gomock.Any()).Do(func(e error) { sErrChan <- e }).MaxTimes(1)
 			sRunner.EXPECT().OnHandshakeComplete().Do(func() { sHandshakeComplete = true }).MaxTimes(1)
@@ -419,6 +426,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 		It("signals when it has written the ClientHello", func() {
 			runner := NewMockHandshakeRunner(mockCtrl)
+			runner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
 			client, chChan := NewCryptoSetupClient(
 				cInitialStream,
@@ -456,11 +464,85 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("reports when keys become available", func() {
+			type keysEvent struct {
+				encLevel   protocol.EncryptionLevel
+				forSending bool
+			}
+			var cEvents, sEvents []keysEvent
+			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
+			cRunner := NewMockHandshakeRunner(mockCtrl)
+			cRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).Do(func(encLevel protocol.EncryptionLevel, forSending bool) {
+				cEvents = append(cEvents, keysEvent{encLevel: encLevel, forSending: forSending})
+			}).AnyTimes()
+			cRunner.EXPECT().OnReceivedParams(gomock.Any())
+			cRunner.EXPECT().OnHandshakeComplete()
+			client, _ := NewCryptoSetupClient(
+				cInitialStream,
+				cHandshakeStream,
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				&wire.TransportParameters{},
+				cRunner,
+				clientConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("client"),
+				protocol.VersionTLS,
+			)
+
+			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
+			var token protocol.StatelessResetToken
+			sRunner := NewMockHandshakeRunner(mockCtrl)
+			sRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).Do(func(encLevel protocol.EncryptionLevel, forSending bool) {
+				sEvents = append(sEvents, keysEvent{encLevel: encLevel, forSending: forSending})
+			}).AnyTimes()
+			sRunner.EXPECT().OnReceivedParams(gomock.Any())
+			sRunner.EXPECT().OnHandshakeComplete()
+			server := NewCryptoSetupServer(
+				sInitialStream,
+				sHandshakeStream,
+				protocol.ConnectionID{},
+				nil,
+				nil,
+				&wire.TransportParameters{StatelessResetToken: &token},
+				sRunner,
+				serverConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("server"),
+				protocol.VersionTLS,
+			)
+
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				handshake(client, cChunkChan, server, sChunkChan)
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+			for _, events := range [][]keysEvent{cEvents, sEvents} {
+				Expect(events).To(ConsistOf(
+					keysEvent{encLevel: protocol.EncryptionHandshake, forSending: true},
+					keysEvent{encLevel: protocol.EncryptionHandshake, forSending: false},
+					keysEvent{encLevel: protocol.Encryption1RTT, forSending: true},
+					keysEvent{encLevel: protocol.Encryption1RTT, forSending: false},
+				))
+			}
+			// the server can send 1-RTT packets before it can decrypt the client's 1-RTT packets
+			Expect(sEvents[2]).To(Equal(keysEvent{encLevel: protocol.Encryption1RTT, forSending: true}))
+			Expect(sEvents[3]).To(Equal(keysEvent{encLevel: protocol.Encryption1RTT, forSending: false}))
+		})
+
 		It("receives transport parameters", func() {
 			var cTransportParametersRcvd, sTransportParametersRcvd *wire.TransportParameters
 			cChunkChan, cInitialStream, cHandshakeStream := initStreams()
 			cTransportParameters := &wire.TransportParameters{MaxIdleTimeout: 0x42 * time.Second}
 			cRunner := NewMockHandshakeRunner(mockCtrl)
+			cRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 			cRunner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(tp *wire.TransportParameters) { sTransportParametersRcvd = tp })
 			cRunner.EXPECT().OnHandshakeComplete()
 			client, _ := NewCryptoSetupClient(
@@ -482,6 +564,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 			var token protocol.StatelessResetToken
 			sRunner := NewMockHandshakeRunner(mockCtrl)
+			sRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 			sRunner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(tp *wire.TransportParameters) { cTransportParametersRcvd = tp })
 			sRunner.EXPECT().OnHandshakeComplete()
 			sTransportParameters := &wire.TransportParameters{
@@ -520,6 +603,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			It("errors when the NewSessionTicket is sent at the wrong encryption level", func() {
 				cChunkChan, cInitialStream, cHandshakeStream := initStreams()
 				cRunner := NewMockHandshakeRunner(mockCtrl)
+				cRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 				cRunner.EXPECT().OnReceivedParams(gomock.Any())
 				cRunner.EXPECT().OnHandshakeComplete()
 				client, _ := NewCryptoSetupClient(
@@ -540,6 +624,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 				sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 				sRunner := NewMockHandshakeRunner(mockCtrl)
+				sRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 				sRunner.EXPECT().OnReceivedParams(gomock.Any())
 				sRunner.EXPECT().OnHandshakeComplete()
 				var token protocol.StatelessResetToken
@@ -579,6 +664,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 			It("errors when handling the NewSessionTicket fails", func() {
 				cChunkChan, cInitialStream, cHandshakeStream := initStreams()
 				cRunner := NewMockHandshakeRunner(mockCtrl)
+				cRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 				cRunner.EXPECT().OnReceivedParams(gomock.Any())
 				cRunner.EXPECT().OnHandshakeComplete()
 				client, _ := NewCryptoSetupClient(
@@ -599,6 +685,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 
 				sChunkChan, sInitialStream, sHandshakeStream := initStreams()
 				sRunner := NewMockHandshakeRunner(mockCtrl)
+				sRunner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
 				sRunner.EXPECT().OnReceivedParams(gomock.Any())
 				sRunner.EXPECT().OnHandshakeComplete()
 				var token protocol.StatelessResetToken
@@ -676,6 +763,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(client.ConnectionState().DidResume).To(BeTrue())
 				Expect(clientRTTStats.SmoothedRTT()).To(Equal(clientRTT))
 				Expect(clientHelloWrittenChan).To(Receive(BeNil()))
+				// the ticket age is only known if the client offers 0-RTT
+				Expect(server.TicketAge()).To(BeZero())
 			})
 
 			It("doesn't use session resumption if the server disabled it", func() {
@@ -768,6 +857,7 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(client.ConnectionState().DidResume).To(BeTrue())
 				Expect(server.ConnectionState().Used0RTT).To(BeTrue())
 				Expect(client.ConnectionState().Used0RTT).To(BeTrue())
+				Expect(server.TicketAge()).To(And(BeNumerically(">", 0), BeNumerically("<", time.Minute)))
 			})
 
 			It("rejects 0-RTT, when the transport parameters changed", func() {
@@ -820,6 +910,31 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Expect(server.ConnectionState().Used0RTT).To(BeFalse())
 				Expect(client.ConnectionState().Used0RTT).To(BeFalse())
 			})
+
+			It("traces which transport parameter caused 0-RTT to be rejected", func() {
+				tracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+				tracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
+				server, _ := newCryptoSetup(
+					&bytes.Buffer{},
+					&bytes.Buffer{},
+					protocol.ConnectionID{},
+					&wire.TransportParameters{InitialMaxData: 1000, MaxBidiStreamNum: 10},
+					NewMockHandshakeRunner(mockCtrl),
+					serverConf,
+					true,
+					&utils.RTTStats{},
+					tracer,
+					utils.DefaultLogger,
+					protocol.PerspectiveServer,
+					protocol.VersionTLS,
+				)
+				ticket := (&sessionTicket{
+					Parameters: &wire.TransportParameters{InitialMaxData: 1000, MaxBidiStreamNum: 11},
+					RTT:        10 * time.Millisecond,
+				}).Marshal()
+				tracer.EXPECT().Rejected0RTT("initial_max_streams_bidi")
+				Expect(server.accept0RTT(ticket)).To(BeFalse())
+			})
 		})
 	})
 })
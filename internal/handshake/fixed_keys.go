@@ -0,0 +1,473 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/qtls"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// FixedKeys are the TLS secrets of a connection, as written to the tls.Config.KeyLogWriter.
+// Secrets that are nil are not available, and packets using the respective keys can't be decrypted.
+type FixedKeys struct {
+	// CipherSuite is the TLS 1.3 cipher suite.
+	// If zero, the client uses the cipher suite from the ServerHello,
+	// and the server derives it from the length of the secrets, assuming AES-GCM.
+	CipherSuite uint16
+
+	ClientEarlyTrafficSecret     []byte
+	ClientHandshakeTrafficSecret []byte
+	ServerHandshakeTrafficSecret []byte
+	ClientTrafficSecret          []byte
+	ServerTrafficSecret          []byte
+}
+
+func (k *FixedKeys) readWriteSecrets(pers protocol.Perspective, encLevel protocol.EncryptionLevel) (read, write []byte) {
+	var client, server []byte
+	switch encLevel {
+	case protocol.EncryptionHandshake:
+		client, server = k.ClientHandshakeTrafficSecret, k.ServerHandshakeTrafficSecret
+	case protocol.Encryption1RTT:
+		client, server = k.ClientTrafficSecret, k.ServerTrafficSecret
+	}
+	if pers == protocol.PerspectiveClient {
+		return server, client
+	}
+	return client, server
+}
+
+// fixedKeysCryptoSetup is a CryptoSetup that doesn't run a TLS handshake.
+// Instead, it installs fixed keys when processing the handshake messages of the peer,
+// at the same point in the handshake where the TLS stack would have derived them.
+// It is used to replay packet traces.
+type fixedKeysCryptoSetup struct {
+	keys   FixedKeys
+	suite  *qtls.CipherSuiteTLS13
+	runner handshakeRunner
+
+	receivedParams    bool
+	handshakeComplete bool
+
+	initialOpener   LongHeaderOpener
+	initialSealer   LongHeaderSealer
+	zeroRTTOpener   LongHeaderOpener
+	handshakeOpener LongHeaderOpener
+	handshakeSealer LongHeaderSealer
+	aead            *updatableAEAD
+	has1RTTOpener   bool
+	has1RTTSealer   bool
+
+	tracer      logging.ConnectionTracer
+	logger      utils.Logger
+	perspective protocol.Perspective
+	version     protocol.VersionNumber
+}
+
+var _ CryptoSetup = &fixedKeysCryptoSetup{}
+
+// NewFixedKeysCryptoSetup creates a CryptoSetup that uses fixed keys instead of running a TLS handshake.
+// The Initial keys are derived from connID.
+// CRYPTO frames are not passed to TLS, but the CryptoSetup extracts the peer's transport parameters,
+// and it completes the handshake when it receives the peer's Finished message.
+// The CryptoSetup can't be used to establish a connection: it only processes received packets.
+func NewFixedKeysCryptoSetup(
+	connID protocol.ConnectionID,
+	keys *FixedKeys,
+	runner handshakeRunner,
+	rttStats *utils.RTTStats,
+	tracer logging.ConnectionTracer,
+	logger utils.Logger,
+	perspective protocol.Perspective,
+	version protocol.VersionNumber,
+) (CryptoSetup, error) {
+	h := &fixedKeysCryptoSetup{
+		keys:        *keys,
+		runner:      runner,
+		aead:        newUpdatableAEAD(rttStats, tracer, logger),
+		tracer:      tracer,
+		logger:      logger,
+		perspective: perspective,
+		version:     version,
+	}
+	if keys.CipherSuite != 0 {
+		if err := h.setCipherSuite(keys.CipherSuite); err != nil {
+			return nil, err
+		}
+	} else if perspective == protocol.PerspectiveServer {
+		if err := h.setCipherSuite(cipherSuiteFromSecrets(keys)); err != nil {
+			return nil, err
+		}
+	}
+	h.initialSealer, h.initialOpener = NewInitialAEAD(connID, perspective, version)
+	return h, nil
+}
+
+func cipherSuiteFromSecrets(keys *FixedKeys) uint16 {
+	for _, secret := range [][]byte{
+		keys.ClientHandshakeTrafficSecret,
+		keys.ServerHandshakeTrafficSecret,
+		keys.ClientTrafficSecret,
+		keys.ServerTrafficSecret,
+		keys.ClientEarlyTrafficSecret,
+	} {
+		switch len(secret) {
+		case 32:
+			return tls.TLS_AES_128_GCM_SHA256
+		case 48:
+			return tls.TLS_AES_256_GCM_SHA384
+		}
+	}
+	return tls.TLS_AES_128_GCM_SHA256
+}
+
+func (h *fixedKeysCryptoSetup) setCipherSuite(id uint16) error {
+	switch id {
+	case tls.TLS_AES_128_GCM_SHA256, tls.TLS_AES_256_GCM_SHA384, tls.TLS_CHACHA20_POLY1305_SHA256:
+	default:
+		return fmt.Errorf("unsupported cipher suite: %#x", id)
+	}
+	h.suite = qtls.CipherSuiteTLS13ByID(id)
+	return nil
+}
+
+func (h *fixedKeysCryptoSetup) RunHandshake() {}
+
+func (h *fixedKeysCryptoSetup) Close() error { return nil }
+
+func (h *fixedKeysCryptoSetup) ChangeConnectionID(id protocol.ConnectionID) {
+	h.initialSealer, h.initialOpener = NewInitialAEAD(id, h.perspective, h.version)
+}
+
+func (h *fixedKeysCryptoSetup) GetSessionTicket() ([]byte, error) { return nil, nil }
+
+func (h *fixedKeysCryptoSetup) HandleMessage(data []byte, encLevel protocol.EncryptionLevel) bool {
+	msgType := messageType(data[0])
+	h.logger.Debugf("Received %s message (%d bytes, encryption level: %s)", msgType, len(data), encLevel)
+	if err := checkEncryptionLevel(msgType, encLevel); err != nil {
+		h.runner.OnError(qerr.NewCryptoError(alertUnexpectedMessage, err.Error()))
+		return false
+	}
+	switch msgType {
+	case typeClientHello:
+		if h.perspective == protocol.PerspectiveClient {
+			break
+		}
+		if !h.handleTransportParameters(data) {
+			return false
+		}
+		h.installKeys(protocol.Encryption0RTT)
+		h.installKeys(protocol.EncryptionHandshake)
+		// The server derives the 1-RTT write keys when sending its Finished message.
+		h.installWriteKeys(protocol.Encryption1RTT)
+		return h.handshakeOpener != nil
+	case typeServerHello:
+		if h.perspective == protocol.PerspectiveServer {
+			break
+		}
+		if h.suite == nil {
+			id, err := cipherSuiteFromServerHello(data)
+			if err == nil {
+				err = h.setCipherSuite(id)
+			}
+			if err != nil {
+				h.runner.OnError(qerr.NewCryptoError(alertUnexpectedMessage, err.Error()))
+				return false
+			}
+		}
+		h.installKeys(protocol.EncryptionHandshake)
+		return h.handshakeOpener != nil
+	case typeEncryptedExtensions:
+		if h.perspective == protocol.PerspectiveClient {
+			h.handleTransportParameters(data)
+		}
+	case typeFinished:
+		h.handleFinished()
+		return true
+	}
+	return false
+}
+
+func (h *fixedKeysCryptoSetup) handleTransportParameters(msg []byte) bool {
+	extType := uint16(quicTLSExtensionType)
+	if h.version != protocol.Version1 {
+		extType = quicTLSExtensionTypeOldDrafts
+	}
+	data, err := findExtension(msg, extType)
+	if err != nil {
+		h.runner.OnError(qerr.NewCryptoError(alertUnexpectedMessage, err.Error()))
+		return false
+	}
+	if data == nil {
+		h.runner.OnError(qerr.NewCryptoError(0x6d, "missing quic_transport_parameters extension"))
+		return false
+	}
+	var tp wire.TransportParameters
+	if err := tp.Unmarshal(data, h.perspective.Opposite()); err != nil {
+		h.runner.OnError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: err.Error(),
+		})
+		return false
+	}
+	h.receivedParams = true
+	h.runner.OnReceivedParams(&tp)
+	return true
+}
+
+func (h *fixedKeysCryptoSetup) handleFinished() {
+	if h.handshakeComplete {
+		return
+	}
+	if !h.receivedParams {
+		h.runner.OnError(qerr.NewCryptoError(0x6d, "missing quic_transport_parameters extension"))
+		return
+	}
+	h.handshakeComplete = true
+	h.installKeys(protocol.Encryption1RTT)
+	if h.perspective == protocol.PerspectiveClient {
+		// The client drops the Initial keys when sending its Finished message.
+		h.dropInitialKeys()
+	}
+	h.runner.OnHandshakeComplete()
+}
+
+func (h *fixedKeysCryptoSetup) installKeys(encLevel protocol.EncryptionLevel) {
+	h.installReadKeys(encLevel)
+	h.installWriteKeys(encLevel)
+}
+
+func (h *fixedKeysCryptoSetup) installReadKeys(encLevel protocol.EncryptionLevel) {
+	if h.suite == nil {
+		return
+	}
+	var secret []byte
+	if encLevel == protocol.Encryption0RTT {
+		if h.perspective == protocol.PerspectiveClient {
+			return
+		}
+		secret = h.keys.ClientEarlyTrafficSecret
+	} else {
+		secret, _ = h.keys.readWriteSecrets(h.perspective, encLevel)
+	}
+	if secret == nil {
+		return
+	}
+	switch encLevel {
+	case protocol.Encryption0RTT:
+		h.zeroRTTOpener = newLongHeaderOpener(createAEAD(h.suite, secret), newHeaderProtector(h.suite, secret, true))
+	case protocol.EncryptionHandshake:
+		h.handshakeOpener = newHandshakeOpener(
+			createAEAD(h.suite, secret),
+			newHeaderProtector(h.suite, secret, true),
+			h.dropInitialKeys,
+			h.perspective,
+		)
+	case protocol.Encryption1RTT:
+		h.aead.SetReadKey(h.suite, secret)
+		h.has1RTTOpener = true
+	}
+	h.logger.Debugf("Installed %s Read keys (using %s)", encLevel, tls.CipherSuiteName(h.suite.ID))
+	if h.tracer != nil {
+		h.tracer.UpdatedKeyFromTLS(encLevel, h.perspective.Opposite())
+	}
+	if encLevel != protocol.Encryption0RTT {
+		h.runner.OnKeysAvailable(encLevel, false)
+	}
+}
+
+func (h *fixedKeysCryptoSetup) installWriteKeys(encLevel protocol.EncryptionLevel) {
+	if h.suite == nil || encLevel == protocol.Encryption0RTT {
+		return
+	}
+	_, secret := h.keys.readWriteSecrets(h.perspective, encLevel)
+	if secret == nil || (encLevel == protocol.Encryption1RTT && h.has1RTTSealer) {
+		return
+	}
+	switch encLevel {
+	case protocol.EncryptionHandshake:
+		h.handshakeSealer = newLongHeaderSealer(createAEAD(h.suite, secret), newHeaderProtector(h.suite, secret, true))
+	case protocol.Encryption1RTT:
+		h.aead.SetWriteKey(h.suite, secret)
+		h.has1RTTSealer = true
+	}
+	h.logger.Debugf("Installed %s Write keys (using %s)", encLevel, tls.CipherSuiteName(h.suite.ID))
+	if h.tracer != nil {
+		h.tracer.UpdatedKeyFromTLS(encLevel, h.perspective)
+	}
+	h.runner.OnKeysAvailable(encLevel, true)
+}
+
+func (h *fixedKeysCryptoSetup) dropInitialKeys() {
+	if h.initialOpener == nil {
+		return
+	}
+	h.initialOpener = nil
+	h.initialSealer = nil
+	h.runner.DropKeys(protocol.EncryptionInitial)
+	h.logger.Debugf("Dropping Initial keys.")
+}
+
+func (h *fixedKeysCryptoSetup) SetLargest1RTTAcked(pn protocol.PacketNumber) error {
+	return h.aead.SetLargestAcked(pn)
+}
+
+func (h *fixedKeysCryptoSetup) SetHandshakeConfirmed() {
+	h.aead.SetHandshakeConfirmed()
+	if h.handshakeOpener != nil {
+		h.handshakeOpener = nil
+		h.handshakeSealer = nil
+		h.runner.DropKeys(protocol.EncryptionHandshake)
+		h.logger.Debugf("Dropping Handshake keys.")
+	}
+}
+
+func (h *fixedKeysCryptoSetup) ConnectionState() ConnectionState { return ConnectionState{} }
+func (h *fixedKeysCryptoSetup) TicketAge() time.Duration         { return 0 }
+
+func (h *fixedKeysCryptoSetup) GetInitialOpener() (LongHeaderOpener, error) {
+	if h.initialOpener == nil {
+		return nil, ErrKeysDropped
+	}
+	return h.initialOpener, nil
+}
+
+func (h *fixedKeysCryptoSetup) GetHandshakeOpener() (LongHeaderOpener, error) {
+	if h.handshakeOpener == nil {
+		if h.initialOpener != nil {
+			return nil, ErrKeysNotYetAvailable
+		}
+		return nil, ErrKeysDropped
+	}
+	return h.handshakeOpener, nil
+}
+
+func (h *fixedKeysCryptoSetup) Get0RTTOpener() (LongHeaderOpener, error) {
+	if h.zeroRTTOpener == nil {
+		if h.initialOpener != nil {
+			return nil, ErrKeysNotYetAvailable
+		}
+		return nil, ErrKeysDropped
+	}
+	return h.zeroRTTOpener, nil
+}
+
+func (h *fixedKeysCryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
+	if !h.has1RTTOpener {
+		return nil, ErrKeysNotYetAvailable
+	}
+	return h.aead, nil
+}
+
+func (h *fixedKeysCryptoSetup) GetInitialSealer() (LongHeaderSealer, error) {
+	if h.initialSealer == nil {
+		return nil, ErrKeysDropped
+	}
+	return h.initialSealer, nil
+}
+
+func (h *fixedKeysCryptoSetup) GetHandshakeSealer() (LongHeaderSealer, error) {
+	if h.handshakeSealer == nil {
+		if h.initialSealer == nil {
+			return nil, ErrKeysDropped
+		}
+		return nil, ErrKeysNotYetAvailable
+	}
+	return h.handshakeSealer, nil
+}
+
+// The fixed keys are only used to process received packets, so 0-RTT packets are never sent.
+func (h *fixedKeysCryptoSetup) Get0RTTSealer() (LongHeaderSealer, error) {
+	return nil, ErrKeysDropped
+}
+
+func (h *fixedKeysCryptoSetup) Get1RTTSealer() (ShortHeaderSealer, error) {
+	if !h.has1RTTSealer {
+		return nil, ErrKeysNotYetAvailable
+	}
+	return h.aead, nil
+}
+
+var errInvalidHandshakeMessage = errors.New("invalid handshake message")
+
+// cipherSuiteFromServerHello extracts the cipher suite from a ServerHello message.
+func cipherSuiteFromServerHello(msg []byte) (uint16, error) {
+	// message type (1), length (3), legacy_version (2), random (32)
+	b := msg
+	if len(b) < 4+2+32+1 {
+		return 0, errInvalidHandshakeMessage
+	}
+	b = b[4+2+32:]
+	sessionIDLen := int(b[0])
+	if len(b) < 1+sessionIDLen+2 {
+		return 0, errInvalidHandshakeMessage
+	}
+	return binary.BigEndian.Uint16(b[1+sessionIDLen:]), nil
+}
+
+// findExtension returns the data of a TLS extension contained in a ClientHello or an EncryptedExtensions message.
+// It returns nil if the message doesn't contain the extension.
+func findExtension(msg []byte, extType uint16) ([]byte, error) {
+	if len(msg) < 4 {
+		return nil, errInvalidHandshakeMessage
+	}
+	b := msg[4:]
+	if messageType(msg[0]) == typeClientHello {
+		// legacy_version (2), random (32)
+		if len(b) < 2+32 {
+			return nil, errInvalidHandshakeMessage
+		}
+		b = b[2+32:]
+		var ok bool
+		// legacy_session_id, cipher_suites, legacy_compression_methods
+		for _, lenLen := range []int{1, 2, 1} {
+			if _, b, ok = readVector(b, lenLen); !ok {
+				return nil, errInvalidHandshakeMessage
+			}
+		}
+	}
+	exts, _, ok := readVector(b, 2)
+	if !ok {
+		return nil, errInvalidHandshakeMessage
+	}
+	for len(exts) > 0 {
+		if len(exts) < 2 {
+			return nil, errInvalidHandshakeMessage
+		}
+		typ := binary.BigEndian.Uint16(exts)
+		var data []byte
+		if data, exts, ok = readVector(exts[2:], 2); !ok {
+			return nil, errInvalidHandshakeMessage
+		}
+		if typ == extType {
+			if data == nil {
+				data = []byte{}
+			}
+			return data, nil
+		}
+	}
+	return nil, nil
+}
+
+// readVector reads a TLS vector with a length prefix of lenLen bytes.
+func readVector(b []byte, lenLen int) (vec, rest []byte, ok bool) {
+	if len(b) < lenLen {
+		return nil, nil, false
+	}
+	var l int
+	for _, x := range b[:lenLen] {
+		l = l<<8 | int(x)
+	}
+	b = b[lenLen:]
+	if len(b) < l {
+		return nil, nil, false
+	}
+	return b[:l], b[l:], true
+}
@@ -0,0 +1,214 @@
+package handshake
+
+import (
+	"bytes"
+	"crypto/tls"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fixed Keys Crypto Setup", func() {
+	tlsMessage := func(typ messageType, body []byte) []byte {
+		l := len(body)
+		return append([]byte{byte(typ), byte(l >> 16), byte(l >> 8), byte(l)}, body...)
+	}
+
+	extensions := func(extType uint16, data []byte) []byte {
+		ext := []byte{byte(extType >> 8), byte(extType), byte(len(data) >> 8), byte(len(data))}
+		ext = append(ext, data...)
+		return append([]byte{byte(len(ext) >> 8), byte(len(ext))}, ext...)
+	}
+
+	clientHello := func(exts []byte) []byte {
+		body := []byte{0x3, 0x3}
+		body = append(body, make([]byte, 32)...) // random
+		body = append(body, 0)                   // legacy_session_id
+		body = append(body, 0, 2, 0x13, 0x1)     // cipher_suites
+		body = append(body, 1, 0)                // legacy_compression_methods
+		return tlsMessage(typeClientHello, append(body, exts...))
+	}
+
+	serverHello := func(suite uint16) []byte {
+		body := []byte{0x3, 0x3}
+		body = append(body, make([]byte, 32)...) // random
+		body = append(body, 0)                   // legacy_session_id
+		body = append(body, byte(suite>>8), byte(suite), 0, 0, 0)
+		return tlsMessage(typeServerHello, body)
+	}
+
+	var (
+		keys   *FixedKeys
+		params []byte
+	)
+
+	BeforeEach(func() {
+		keys = &FixedKeys{
+			ClientHandshakeTrafficSecret: bytes.Repeat([]byte{1}, 32),
+			ServerHandshakeTrafficSecret: bytes.Repeat([]byte{2}, 32),
+			ClientTrafficSecret:          bytes.Repeat([]byte{3}, 32),
+			ServerTrafficSecret:          bytes.Repeat([]byte{4}, 32),
+		}
+		params = (&wire.TransportParameters{
+			InitialSourceConnectionID: protocol.ConnectionID{1, 2, 3, 4},
+			MaxUniStreamNum:           42,
+			ActiveConnectionIDLimit:   2,
+		}).Marshal(protocol.PerspectiveClient)
+	})
+
+	newCryptoSetup := func(runner *MockHandshakeRunner, pers protocol.Perspective) CryptoSetup {
+		runner.EXPECT().OnKeysAvailable(gomock.Any(), gomock.Any()).AnyTimes()
+		cs, err := NewFixedKeysCryptoSetup(
+			protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef},
+			keys,
+			runner,
+			&utils.RTTStats{},
+			nil,
+			utils.DefaultLogger,
+			pers,
+			protocol.Version1,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		return cs
+	}
+
+	It("rejects unsupported cipher suites", func() {
+		keys.CipherSuite = tls.TLS_RSA_WITH_AES_128_GCM_SHA256
+		_, err := NewFixedKeysCryptoSetup(nil, keys, nil, &utils.RTTStats{}, nil, utils.DefaultLogger, protocol.PerspectiveServer, protocol.Version1)
+		Expect(err).To(MatchError("unsupported cipher suite: 0x9c"))
+	})
+
+	It("derives the Initial keys from the connection ID", func() {
+		cs := newCryptoSetup(NewMockHandshakeRunner(mockCtrl), protocol.PerspectiveServer)
+		clientSealer, _ := NewInitialAEAD(protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}, protocol.PerspectiveClient, protocol.Version1)
+		opener, err := cs.GetInitialOpener()
+		Expect(err).ToNot(HaveOccurred())
+		sealed := clientSealer.Seal(nil, []byte("foobar"), 10, []byte("ad"))
+		opened, err := opener.Open(nil, sealed, 10, []byte("ad"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opened).To(Equal([]byte("foobar")))
+	})
+
+	It("installs the Handshake keys when the server receives the ClientHello", func() {
+		runner := NewMockHandshakeRunner(mockCtrl)
+		server := newCryptoSetup(runner, protocol.PerspectiveServer)
+		_, err := server.GetHandshakeOpener()
+		Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+		var tp *wire.TransportParameters
+		runner.EXPECT().OnReceivedParams(gomock.Any()).Do(func(p *wire.TransportParameters) { tp = p })
+		Expect(server.HandleMessage(clientHello(extensions(quicTLSExtensionType, params)), protocol.EncryptionInitial)).To(BeTrue())
+		Expect(tp.MaxUniStreamNum).To(BeEquivalentTo(42))
+		opener, err := server.GetHandshakeOpener()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = server.Get1RTTSealer()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = server.Get1RTTOpener()
+		Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+
+		// The client uses the cipher suite from the ServerHello.
+		client := newCryptoSetup(NewMockHandshakeRunner(mockCtrl), protocol.PerspectiveClient)
+		Expect(client.HandleMessage(serverHello(tls.TLS_AES_128_GCM_SHA256), protocol.EncryptionInitial)).To(BeTrue())
+		sealer, err := client.GetHandshakeSealer()
+		Expect(err).ToNot(HaveOccurred())
+		sealed := sealer.Seal(nil, []byte("foobar"), 10, []byte("ad"))
+		runner.EXPECT().DropKeys(protocol.EncryptionInitial)
+		opened, err := opener.Open(nil, sealed, 10, []byte("ad"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opened).To(Equal([]byte("foobar")))
+		_, err = server.GetInitialOpener()
+		Expect(err).To(MatchError(ErrKeysDropped))
+	})
+
+	It("errors when the ClientHello doesn't contain the transport parameters", func() {
+		runner := NewMockHandshakeRunner(mockCtrl)
+		server := newCryptoSetup(runner, protocol.PerspectiveServer)
+		runner.EXPECT().OnError(gomock.Any()).Do(func(err error) {
+			Expect(err).To(MatchError(qerr.NewCryptoError(0x6d, "missing quic_transport_parameters extension")))
+		})
+		Expect(server.HandleMessage(clientHello(extensions(0x1234, []byte("foo"))), protocol.EncryptionInitial)).To(BeFalse())
+		_, err := server.GetHandshakeOpener()
+		Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+	})
+
+	It("errors on malformed ClientHellos", func() {
+		runner := NewMockHandshakeRunner(mockCtrl)
+		server := newCryptoSetup(runner, protocol.PerspectiveServer)
+		runner.EXPECT().OnError(gomock.Any()).Do(func(err error) {
+			Expect(err).To(MatchError(qerr.NewCryptoError(alertUnexpectedMessage, "invalid handshake message")))
+		})
+		Expect(server.HandleMessage(tlsMessage(typeClientHello, []byte{0x3, 0x3}), protocol.EncryptionInitial)).To(BeFalse())
+	})
+
+	It("completes the handshake when the client receives the server's Finished", func() {
+		runner := NewMockHandshakeRunner(mockCtrl)
+		client := newCryptoSetup(runner, protocol.PerspectiveClient)
+		Expect(client.HandleMessage(serverHello(tls.TLS_AES_128_GCM_SHA256), protocol.EncryptionInitial)).To(BeTrue())
+		serverParams := (&wire.TransportParameters{
+			InitialSourceConnectionID: protocol.ConnectionID{1, 2, 3, 4},
+			StatelessResetToken:       &protocol.StatelessResetToken{},
+			ActiveConnectionIDLimit:   2,
+		}).Marshal(protocol.PerspectiveServer)
+		runner.EXPECT().OnReceivedParams(gomock.Any())
+		Expect(client.HandleMessage(tlsMessage(typeEncryptedExtensions, extensions(quicTLSExtensionType, serverParams)), protocol.EncryptionHandshake)).To(BeFalse())
+		_, err := client.Get1RTTOpener()
+		Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+		gomock.InOrder(
+			runner.EXPECT().DropKeys(protocol.EncryptionInitial),
+			runner.EXPECT().OnHandshakeComplete(),
+		)
+		Expect(client.HandleMessage(tlsMessage(typeFinished, make([]byte, 32)), protocol.EncryptionHandshake)).To(BeTrue())
+		_, err = client.Get1RTTOpener()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.Get1RTTSealer()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.GetInitialOpener()
+		Expect(err).To(MatchError(ErrKeysDropped))
+	})
+
+	It("doesn't complete the handshake without the transport parameters", func() {
+		runner := NewMockHandshakeRunner(mockCtrl)
+		client := newCryptoSetup(runner, protocol.PerspectiveClient)
+		Expect(client.HandleMessage(serverHello(tls.TLS_AES_128_GCM_SHA256), protocol.EncryptionInitial)).To(BeTrue())
+		runner.EXPECT().OnError(gomock.Any())
+		client.HandleMessage(tlsMessage(typeFinished, make([]byte, 32)), protocol.EncryptionHandshake)
+		_, err := client.Get1RTTOpener()
+		Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+	})
+
+	It("rejects messages sent at the wrong encryption level", func() {
+		runner := NewMockHandshakeRunner(mockCtrl)
+		server := newCryptoSetup(runner, protocol.PerspectiveServer)
+		runner.EXPECT().OnError(gomock.Any())
+		Expect(server.HandleMessage(clientHello(extensions(quicTLSExtensionType, params)), protocol.EncryptionHandshake)).To(BeFalse())
+	})
+
+	It("doesn't install keys for missing secrets", func() {
+		keys.ClientTrafficSecret = nil
+		runner := NewMockHandshakeRunner(mockCtrl)
+		server := newCryptoSetup(runner, protocol.PerspectiveServer)
+		runner.EXPECT().OnReceivedParams(gomock.Any())
+		server.HandleMessage(clientHello(extensions(quicTLSExtensionType, params)), protocol.EncryptionInitial)
+		runner.EXPECT().OnHandshakeComplete()
+		Expect(server.HandleMessage(tlsMessage(typeFinished, make([]byte, 32)), protocol.EncryptionHandshake)).To(BeTrue())
+		_, err := server.Get1RTTOpener()
+		Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+	})
+
+	It("drops the Handshake keys when the handshake is confirmed", func() {
+		runner := NewMockHandshakeRunner(mockCtrl)
+		server := newCryptoSetup(runner, protocol.PerspectiveServer)
+		runner.EXPECT().OnReceivedParams(gomock.Any())
+		server.HandleMessage(clientHello(extensions(quicTLSExtensionType, params)), protocol.EncryptionInitial)
+		runner.EXPECT().DropKeys(protocol.EncryptionHandshake)
+		server.SetHandshakeConfirmed()
+		_, err := server.GetHandshakeOpener()
+		Expect(err).To(MatchError(ErrKeysNotYetAvailable))
+	})
+})
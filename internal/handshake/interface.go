@@ -67,7 +67,10 @@ type tlsExtensionHandler interface {
 type handshakeRunner interface {
 	OnReceivedParams(*wire.TransportParameters)
 	OnHandshakeComplete()
+	OnSessionTicketStored()
 	OnError(error)
+	// OnKeysAvailable is called when the Handshake or 1-RTT keys for sending or for receiving packets were installed.
+	OnKeysAvailable(encLevel protocol.EncryptionLevel, forSending bool)
 	DropKeys(protocol.EncryptionLevel)
 }
 
@@ -82,6 +85,7 @@ type CryptoSetup interface {
 	SetLargest1RTTAcked(protocol.PacketNumber) error
 	SetHandshakeConfirmed()
 	ConnectionState() ConnectionState
+	TicketAge() time.Duration
 
 	GetInitialOpener() (LongHeaderOpener, error)
 	GetHandshakeOpener() (LongHeaderOpener, error)
@@ -71,6 +71,18 @@ func (mr *MockHandshakeRunnerMockRecorder) OnHandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnHandshakeComplete", reflect.TypeOf((*MockHandshakeRunner)(nil).OnHandshakeComplete))
 }
 
+// OnKeysAvailable mocks base method.
+func (m *MockHandshakeRunner) OnKeysAvailable(encLevel protocol.EncryptionLevel, forSending bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnKeysAvailable", encLevel, forSending)
+}
+
+// OnKeysAvailable indicates an expected call of OnKeysAvailable.
+func (mr *MockHandshakeRunnerMockRecorder) OnKeysAvailable(encLevel, forSending interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnKeysAvailable", reflect.TypeOf((*MockHandshakeRunner)(nil).OnKeysAvailable), encLevel, forSending)
+}
+
 // OnReceivedParams mocks base method.
 func (m *MockHandshakeRunner) OnReceivedParams(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
@@ -82,3 +94,15 @@ func (mr *MockHandshakeRunnerMockRecorder) OnReceivedParams(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnReceivedParams", reflect.TypeOf((*MockHandshakeRunner)(nil).OnReceivedParams), arg0)
 }
+
+// OnSessionTicketStored mocks base method.
+func (m *MockHandshakeRunner) OnSessionTicketStored() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnSessionTicketStored")
+}
+
+// OnSessionTicketStored indicates an expected call of OnSessionTicketStored.
+func (mr *MockHandshakeRunnerMockRecorder) OnSessionTicketStored() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnSessionTicketStored", reflect.TypeOf((*MockHandshakeRunner)(nil).OnSessionTicketStored))
+}
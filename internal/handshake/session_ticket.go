@@ -10,17 +10,23 @@ import (
 	"github.com/lucas-clemente/quic-go/quicvarint"
 )
 
-const sessionTicketRevision = 2
+const sessionTicketRevision = 3
 
 type sessionTicket struct {
 	Parameters *wire.TransportParameters
 	RTT        time.Duration // to be encoded in mus
+	IssuedAt   time.Time     // to be encoded in mus since the Unix epoch
 }
 
 func (t *sessionTicket) Marshal() []byte {
 	b := &bytes.Buffer{}
 	quicvarint.Write(b, sessionTicketRevision)
 	quicvarint.Write(b, uint64(t.RTT.Microseconds()))
+	var issuedAt uint64
+	if !t.IssuedAt.IsZero() {
+		issuedAt = uint64(t.IssuedAt.UnixNano() / 1000)
+	}
+	quicvarint.Write(b, issuedAt)
 	t.Parameters.MarshalForSessionTicket(b)
 	return b.Bytes()
 }
@@ -38,11 +44,18 @@ func (t *sessionTicket) Unmarshal(b []byte) error {
 	if err != nil {
 		return errors.New("failed to read RTT")
 	}
+	issuedAt, err := quicvarint.Read(r)
+	if err != nil {
+		return errors.New("failed to read issue time")
+	}
 	var tp wire.TransportParameters
 	if err := tp.UnmarshalFromSessionTicket(r); err != nil {
 		return fmt.Errorf("unmarshaling transport parameters from session ticket failed: %s", err.Error())
 	}
 	t.Parameters = &tp
 	t.RTT = time.Duration(rtt) * time.Microsecond
+	if issuedAt > 0 {
+		t.IssuedAt = time.Unix(0, int64(issuedAt)*1000)
+	}
 	return nil
 }
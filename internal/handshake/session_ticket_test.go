@@ -18,13 +18,22 @@ var _ = Describe("Session Ticket", func() {
 				InitialMaxStreamDataBidiLocal:  1,
 				InitialMaxStreamDataBidiRemote: 2,
 			},
-			RTT: 1337 * time.Microsecond,
+			RTT:      1337 * time.Microsecond,
+			IssuedAt: time.Unix(1600000000, 123456000),
 		}
 		var t sessionTicket
 		Expect(t.Unmarshal(ticket.Marshal())).To(Succeed())
 		Expect(t.Parameters.InitialMaxStreamDataBidiLocal).To(BeEquivalentTo(1))
 		Expect(t.Parameters.InitialMaxStreamDataBidiRemote).To(BeEquivalentTo(2))
 		Expect(t.RTT).To(Equal(1337 * time.Microsecond))
+		Expect(t.IssuedAt).To(Equal(time.Unix(1600000000, 123456000)))
+	})
+
+	It("marshals and unmarshals a session ticket without an issue time", func() {
+		ticket := &sessionTicket{Parameters: &wire.TransportParameters{}}
+		var t sessionTicket
+		Expect(t.Unmarshal(ticket.Marshal())).To(Succeed())
+		Expect(t.IssuedAt.IsZero()).To(BeTrue())
 	})
 
 	It("refuses to unmarshal if the ticket is too short for the revision", func() {
@@ -43,6 +52,13 @@ var _ = Describe("Session Ticket", func() {
 		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read RTT"))
 	})
 
+	It("refuses to unmarshal if the issue time cannot be read", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, sessionTicketRevision)
+		quicvarint.Write(b, 1337)
+		Expect((&sessionTicket{}).Unmarshal(b.Bytes())).To(MatchError("failed to read issue time"))
+	})
+
 	It("refuses to unmarshal if unmarshaling the transport parameters fails", func() {
 		b := &bytes.Buffer{}
 		quicvarint.Write(b, sessionTicketRevision)
@@ -13,6 +13,7 @@ import (
 const (
 	tokenPrefixIP byte = iota
 	tokenPrefixString
+	tokenPrefixIPNet
 )
 
 // A Token is derived from the client address and can be used to verify the ownership of this address.
@@ -81,6 +82,21 @@ func (g *TokenGenerator) NewToken(raddr net.Addr) ([]byte, error) {
 	return g.tokenProtector.NewToken(data)
 }
 
+// NewPrefixToken generates a new token to be sent in a NEW_TOKEN frame.
+// Instead of the exact IP address, the token is bound to the network prefix of the address,
+// such that clients whose address changed within that network can still use the token.
+// A prefix length of 0 (or a prefix length that covers the whole address) binds the token to the exact address.
+func (g *TokenGenerator) NewPrefixToken(raddr net.Addr, ipv4PrefixLen, ipv6PrefixLen int) ([]byte, error) {
+	data, err := asn1.Marshal(token{
+		RemoteAddr: encodeRemoteAddrPrefix(raddr, ipv4PrefixLen, ipv6PrefixLen),
+		Timestamp:  time.Now().UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g.tokenProtector.NewToken(data)
+}
+
 // DecodeToken decodes a token
 func (g *TokenGenerator) DecodeToken(encrypted []byte) (*Token, error) {
 	// if the client didn't send any token, DecodeToken will be called with a nil-slice
@@ -120,15 +136,42 @@ func encodeRemoteAddr(remoteAddr net.Addr) []byte {
 	return append([]byte{tokenPrefixString}, []byte(remoteAddr.String())...)
 }
 
-// decodeRemoteAddr decodes the remote address saved in the token
+// encodeRemoteAddrPrefix encodes the network prefix of a remote address such that it can be saved in the token
+func encodeRemoteAddrPrefix(remoteAddr net.Addr, ipv4PrefixLen, ipv6PrefixLen int) []byte {
+	udpAddr, ok := remoteAddr.(*net.UDPAddr)
+	if !ok {
+		return encodeRemoteAddr(remoteAddr)
+	}
+	ip := udpAddr.IP.To4()
+	prefixLen := ipv4PrefixLen
+	if ip == nil {
+		ip = udpAddr.IP.To16()
+		prefixLen = ipv6PrefixLen
+	}
+	if ip == nil || prefixLen <= 0 || prefixLen >= 8*len(ip) {
+		return encodeRemoteAddr(remoteAddr)
+	}
+	mask := net.CIDRMask(prefixLen, 8*len(ip))
+	return append([]byte{tokenPrefixIPNet, byte(prefixLen)}, ip.Mask(mask)...)
+}
+
+// decodeRemoteAddr decodes the remote address saved in the token.
+// For tokens bound to a network prefix, the prefix is returned in CIDR notation.
 func decodeRemoteAddr(data []byte) string {
 	// data will never be empty for a token that we generated.
 	// Check it to be on the safe side
 	if len(data) == 0 {
 		return ""
 	}
-	if data[0] == tokenPrefixIP {
+	switch data[0] {
+	case tokenPrefixIP:
 		return net.IP(data[1:]).String()
+	case tokenPrefixIPNet:
+		if len(data) < 2 {
+			return ""
+		}
+		ip := net.IP(data[2:])
+		return (&net.IPNet{IP: ip, Mask: net.CIDRMask(int(data[1]), 8*len(ip))}).String()
 	}
 	return string(data[1:])
 }
@@ -124,4 +124,37 @@ var _ = Describe("Token Generator", func() {
 		Expect(token.RemoteAddr).To(Equal("192.168.13.37:1337"))
 		Expect(token.SentTime).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
 	})
+
+	Context("tokens bound to a network prefix", func() {
+		decode := func(raddr net.Addr, ipv4PrefixLen, ipv6PrefixLen int) *Token {
+			tokenEnc, err := tokenGen.NewPrefixToken(raddr, ipv4PrefixLen, ipv6PrefixLen)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			token, err := tokenGen.DecodeToken(tokenEnc)
+			ExpectWithOffset(1, err).ToNot(HaveOccurred())
+			ExpectWithOffset(1, token.IsRetryToken).To(BeFalse())
+			ExpectWithOffset(1, token.SentTime).To(BeTemporally("~", time.Now(), 100*time.Millisecond))
+			return token
+		}
+
+		It("binds IPv4 addresses to a prefix", func() {
+			token := decode(&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}, 24, 56)
+			Expect(token.RemoteAddr).To(Equal("192.168.13.0/24"))
+		})
+
+		It("binds IPv6 addresses to a prefix", func() {
+			token := decode(&net.UDPAddr{IP: net.ParseIP("2001:db8:1234:5678::68"), Port: 1337}, 24, 56)
+			Expect(token.RemoteAddr).To(Equal("2001:db8:1234:5600::/56"))
+		})
+
+		It("uses the exact address if the prefix length is 0, or covers the whole address", func() {
+			Expect(decode(&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37)}, 0, 56).RemoteAddr).To(Equal("192.168.13.37"))
+			Expect(decode(&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37)}, 32, 56).RemoteAddr).To(Equal("192.168.13.37"))
+			Expect(decode(&net.UDPAddr{IP: net.ParseIP("2001:db8::68")}, 24, 0).RemoteAddr).To(Equal("2001:db8::68"))
+		})
+
+		It("uses the string representation an address that is not a UDP address", func() {
+			token := decode(&net.TCPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 1337}, 24, 56)
+			Expect(token.RemoteAddr).To(Equal("192.168.13.37:1337"))
+		})
+	})
 })
@@ -10,6 +10,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
+	congestion "github.com/lucas-clemente/quic-go/internal/congestion"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -77,6 +78,32 @@ func (mr *MockSentPacketHandlerMockRecorder) HasPacingBudget() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasPacingBudget", reflect.TypeOf((*MockSentPacketHandler)(nil).HasPacingBudget))
 }
 
+// InFlightPackets mocks base method.
+func (m *MockSentPacketHandler) InFlightPackets() []ackhandler.Packet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InFlightPackets")
+	ret0, _ := ret[0].([]ackhandler.Packet)
+	return ret0
+}
+
+// InFlightPackets indicates an expected call of InFlightPackets.
+func (mr *MockSentPacketHandlerMockRecorder) InFlightPackets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InFlightPackets", reflect.TypeOf((*MockSentPacketHandler)(nil).InFlightPackets))
+}
+
+// MigratedPath mocks base method.
+func (m *MockSentPacketHandler) MigratedPath() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MigratedPath")
+}
+
+// MigratedPath indicates an expected call of MigratedPath.
+func (mr *MockSentPacketHandlerMockRecorder) MigratedPath() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigratedPath", reflect.TypeOf((*MockSentPacketHandler)(nil).MigratedPath))
+}
+
 // OnLossDetectionTimeout mocks base method.
 func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
 	m.ctrl.T.Helper()
@@ -91,6 +118,20 @@ func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
 }
 
+// PacerState mocks base method.
+func (m *MockSentPacketHandler) PacerState() congestion.PacerState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacerState")
+	ret0, _ := ret[0].(congestion.PacerState)
+	return ret0
+}
+
+// PacerState indicates an expected call of PacerState.
+func (mr *MockSentPacketHandlerMockRecorder) PacerState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacerState", reflect.TypeOf((*MockSentPacketHandler)(nil).PacerState))
+}
+
 // PeekPacketNumber mocks base method.
 func (m *MockSentPacketHandler) PeekPacketNumber(arg0 protocol.EncryptionLevel) (protocol.PacketNumber, protocol.PacketNumberLen) {
 	m.ctrl.T.Helper()
@@ -225,6 +266,34 @@ func (mr *MockSentPacketHandlerMockRecorder) SetMaxDatagramSize(arg0 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxDatagramSize", reflect.TypeOf((*MockSentPacketHandler)(nil).SetMaxDatagramSize), arg0)
 }
 
+// SpuriousLosses mocks base method.
+func (m *MockSentPacketHandler) SpuriousLosses() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SpuriousLosses")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// SpuriousLosses indicates an expected call of SpuriousLosses.
+func (mr *MockSentPacketHandlerMockRecorder) SpuriousLosses() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SpuriousLosses", reflect.TypeOf((*MockSentPacketHandler)(nil).SpuriousLosses))
+}
+
+// Stats mocks base method.
+func (m *MockSentPacketHandler) Stats() ackhandler.Stats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(ackhandler.Stats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockSentPacketHandlerMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockSentPacketHandler)(nil).Stats))
+}
+
 // TimeUntilSend mocks base method.
 func (m *MockSentPacketHandler) TimeUntilSend() time.Time {
 	m.ctrl.T.Helper()
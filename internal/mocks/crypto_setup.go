@@ -6,6 +6,7 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	handshake "github.com/lucas-clemente/quic-go/internal/handshake"
@@ -262,3 +263,17 @@ func (mr *MockCryptoSetupMockRecorder) SetLargest1RTTAcked(arg0 interface{}) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLargest1RTTAcked", reflect.TypeOf((*MockCryptoSetup)(nil).SetLargest1RTTAcked), arg0)
 }
+
+// TicketAge mocks base method.
+func (m *MockCryptoSetup) TicketAge() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TicketAge")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// TicketAge indicates an expected call of TicketAge.
+func (mr *MockCryptoSetupMockRecorder) TicketAge() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TicketAge", reflect.TypeOf((*MockCryptoSetup)(nil).TicketAge))
+}
@@ -51,6 +51,18 @@ func (mr *MockConnectionTracerMockRecorder) AcknowledgedPacket(arg0, arg1 interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcknowledgedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).AcknowledgedPacket), arg0, arg1)
 }
 
+// BlockedByFlowControl mocks base method.
+func (m *MockConnectionTracer) BlockedByFlowControl(arg0 protocol.StreamID, arg1 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BlockedByFlowControl", arg0, arg1)
+}
+
+// BlockedByFlowControl indicates an expected call of BlockedByFlowControl.
+func (mr *MockConnectionTracerMockRecorder) BlockedByFlowControl(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockedByFlowControl", reflect.TypeOf((*MockConnectionTracer)(nil).BlockedByFlowControl), arg0, arg1)
+}
+
 // BufferedPacket mocks base method.
 func (m *MockConnectionTracer) BufferedPacket(arg0 logging.PacketType) {
 	m.ctrl.T.Helper()
@@ -99,6 +111,30 @@ func (mr *MockConnectionTracerMockRecorder) Debug(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Debug", reflect.TypeOf((*MockConnectionTracer)(nil).Debug), arg0, arg1)
 }
 
+// DetectedPeerAnomaly mocks base method.
+func (m *MockConnectionTracer) DetectedPeerAnomaly(arg0 logging.PeerAnomaly, arg1 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DetectedPeerAnomaly", arg0, arg1)
+}
+
+// DetectedPeerAnomaly indicates an expected call of DetectedPeerAnomaly.
+func (mr *MockConnectionTracerMockRecorder) DetectedPeerAnomaly(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectedPeerAnomaly", reflect.TypeOf((*MockConnectionTracer)(nil).DetectedPeerAnomaly), arg0, arg1)
+}
+
+// DetectedPersistentCongestion mocks base method.
+func (m *MockConnectionTracer) DetectedPersistentCongestion(arg0 protocol.EncryptionLevel, arg1 logging.PersistentCongestion) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DetectedPersistentCongestion", arg0, arg1)
+}
+
+// DetectedPersistentCongestion indicates an expected call of DetectedPersistentCongestion.
+func (mr *MockConnectionTracerMockRecorder) DetectedPersistentCongestion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetectedPersistentCongestion", reflect.TypeOf((*MockConnectionTracer)(nil).DetectedPersistentCongestion), arg0, arg1)
+}
+
 // DroppedEncryptionLevel mocks base method.
 func (m *MockConnectionTracer) DroppedEncryptionLevel(arg0 protocol.EncryptionLevel) {
 	m.ctrl.T.Helper()
@@ -135,6 +171,30 @@ func (mr *MockConnectionTracerMockRecorder) DroppedPacket(arg0, arg1, arg2 inter
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DroppedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).DroppedPacket), arg0, arg1, arg2)
 }
 
+// FinishedPathValidation mocks base method.
+func (m *MockConnectionTracer) FinishedPathValidation(arg0 net.Addr, arg1 bool, arg2 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "FinishedPathValidation", arg0, arg1, arg2)
+}
+
+// FinishedPathValidation indicates an expected call of FinishedPathValidation.
+func (mr *MockConnectionTracerMockRecorder) FinishedPathValidation(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FinishedPathValidation", reflect.TypeOf((*MockConnectionTracer)(nil).FinishedPathValidation), arg0, arg1, arg2)
+}
+
+// LabeledStream mocks base method.
+func (m *MockConnectionTracer) LabeledStream(arg0 protocol.StreamID, arg1 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "LabeledStream", arg0, arg1)
+}
+
+// LabeledStream indicates an expected call of LabeledStream.
+func (mr *MockConnectionTracerMockRecorder) LabeledStream(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LabeledStream", reflect.TypeOf((*MockConnectionTracer)(nil).LabeledStream), arg0, arg1)
+}
+
 // LossTimerCanceled mocks base method.
 func (m *MockConnectionTracer) LossTimerCanceled() {
 	m.ctrl.T.Helper()
@@ -171,6 +231,18 @@ func (mr *MockConnectionTracerMockRecorder) LostPacket(arg0, arg1, arg2 interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LostPacket", reflect.TypeOf((*MockConnectionTracer)(nil).LostPacket), arg0, arg1, arg2)
 }
 
+// NegotiatedIdleTimeout mocks base method.
+func (m *MockConnectionTracer) NegotiatedIdleTimeout(arg0, arg1, arg2 time.Duration) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "NegotiatedIdleTimeout", arg0, arg1, arg2)
+}
+
+// NegotiatedIdleTimeout indicates an expected call of NegotiatedIdleTimeout.
+func (mr *MockConnectionTracerMockRecorder) NegotiatedIdleTimeout(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedIdleTimeout", reflect.TypeOf((*MockConnectionTracer)(nil).NegotiatedIdleTimeout), arg0, arg1, arg2)
+}
+
 // NegotiatedVersion mocks base method.
 func (m *MockConnectionTracer) NegotiatedVersion(arg0 protocol.VersionNumber, arg1, arg2 []protocol.VersionNumber) {
 	m.ctrl.T.Helper()
@@ -183,6 +255,18 @@ func (mr *MockConnectionTracerMockRecorder) NegotiatedVersion(arg0, arg1, arg2 i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NegotiatedVersion", reflect.TypeOf((*MockConnectionTracer)(nil).NegotiatedVersion), arg0, arg1, arg2)
 }
 
+// ReassembledCryptoData mocks base method.
+func (m *MockConnectionTracer) ReassembledCryptoData(arg0 protocol.EncryptionLevel, arg1 logging.CryptoStreamStats) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReassembledCryptoData", arg0, arg1)
+}
+
+// ReassembledCryptoData indicates an expected call of ReassembledCryptoData.
+func (mr *MockConnectionTracerMockRecorder) ReassembledCryptoData(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReassembledCryptoData", reflect.TypeOf((*MockConnectionTracer)(nil).ReassembledCryptoData), arg0, arg1)
+}
+
 // ReceivedPacket mocks base method.
 func (m *MockConnectionTracer) ReceivedPacket(arg0 *wire.ExtendedHeader, arg1 protocol.ByteCount, arg2 []logging.Frame) {
 	m.ctrl.T.Helper()
@@ -195,6 +279,18 @@ func (mr *MockConnectionTracerMockRecorder) ReceivedPacket(arg0, arg1, arg2 inte
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedPacket", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedPacket), arg0, arg1, arg2)
 }
 
+// ReceivedPathChallenge mocks base method.
+func (m *MockConnectionTracer) ReceivedPathChallenge(arg0 [8]byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ReceivedPathChallenge", arg0)
+}
+
+// ReceivedPathChallenge indicates an expected call of ReceivedPathChallenge.
+func (mr *MockConnectionTracerMockRecorder) ReceivedPathChallenge(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedPathChallenge", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedPathChallenge), arg0)
+}
+
 // ReceivedRetry mocks base method.
 func (m *MockConnectionTracer) ReceivedRetry(arg0 *wire.Header) {
 	m.ctrl.T.Helper()
@@ -231,6 +327,18 @@ func (mr *MockConnectionTracerMockRecorder) ReceivedVersionNegotiationPacket(arg
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReceivedVersionNegotiationPacket", reflect.TypeOf((*MockConnectionTracer)(nil).ReceivedVersionNegotiationPacket), arg0, arg1)
 }
 
+// Rejected0RTT mocks base method.
+func (m *MockConnectionTracer) Rejected0RTT(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Rejected0RTT", arg0)
+}
+
+// Rejected0RTT indicates an expected call of Rejected0RTT.
+func (mr *MockConnectionTracerMockRecorder) Rejected0RTT(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rejected0RTT", reflect.TypeOf((*MockConnectionTracer)(nil).Rejected0RTT), arg0)
+}
+
 // RestoredTransportParameters mocks base method.
 func (m *MockConnectionTracer) RestoredTransportParameters(arg0 *wire.TransportParameters) {
 	m.ctrl.T.Helper()
@@ -291,6 +399,42 @@ func (mr *MockConnectionTracerMockRecorder) StartedConnection(arg0, arg1, arg2,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartedConnection", reflect.TypeOf((*MockConnectionTracer)(nil).StartedConnection), arg0, arg1, arg2, arg3)
 }
 
+// StartedPathValidation mocks base method.
+func (m *MockConnectionTracer) StartedPathValidation(arg0 net.Addr, arg1 [8]byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "StartedPathValidation", arg0, arg1)
+}
+
+// StartedPathValidation indicates an expected call of StartedPathValidation.
+func (mr *MockConnectionTracerMockRecorder) StartedPathValidation(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartedPathValidation", reflect.TypeOf((*MockConnectionTracer)(nil).StartedPathValidation), arg0, arg1)
+}
+
+// SuspectedStatelessReset mocks base method.
+func (m *MockConnectionTracer) SuspectedStatelessReset(arg0 protocol.StatelessResetToken, arg1 int) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SuspectedStatelessReset", arg0, arg1)
+}
+
+// SuspectedStatelessReset indicates an expected call of SuspectedStatelessReset.
+func (mr *MockConnectionTracerMockRecorder) SuspectedStatelessReset(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuspectedStatelessReset", reflect.TypeOf((*MockConnectionTracer)(nil).SuspectedStatelessReset), arg0, arg1)
+}
+
+// UndecryptablePacketQueueFull mocks base method.
+func (m *MockConnectionTracer) UndecryptablePacketQueueFull(arg0 logging.PacketType, arg1 protocol.ByteCount, arg2 int, arg3 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UndecryptablePacketQueueFull", arg0, arg1, arg2, arg3)
+}
+
+// UndecryptablePacketQueueFull indicates an expected call of UndecryptablePacketQueueFull.
+func (mr *MockConnectionTracerMockRecorder) UndecryptablePacketQueueFull(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UndecryptablePacketQueueFull", reflect.TypeOf((*MockConnectionTracer)(nil).UndecryptablePacketQueueFull), arg0, arg1, arg2, arg3)
+}
+
 // UpdatedCongestionState mocks base method.
 func (m *MockConnectionTracer) UpdatedCongestionState(arg0 logging.CongestionState) {
 	m.ctrl.T.Helper()
@@ -81,6 +81,20 @@ func (mr *MockEarlySessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockEarlySession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CloseWithPayload mocks base method.
+func (m *MockEarlySession) CloseWithPayload(arg0 qerr.ApplicationErrorCode, arg1 string, arg2 []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithPayload", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithPayload indicates an expected call of CloseWithPayload.
+func (mr *MockEarlySessionMockRecorder) CloseWithPayload(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithPayload", reflect.TypeOf((*MockEarlySession)(nil).CloseWithPayload), arg0, arg1, arg2)
+}
+
 // ConnectionState mocks base method.
 func (m *MockEarlySession) ConnectionState() quic.ConnectionState {
 	m.ctrl.T.Helper()
@@ -95,6 +109,20 @@ func (mr *MockEarlySessionMockRecorder) ConnectionState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionState", reflect.TypeOf((*MockEarlySession)(nil).ConnectionState))
 }
 
+// ConnectionStats mocks base method.
+func (m *MockEarlySession) ConnectionStats() quic.ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionStats")
+	ret0, _ := ret[0].(quic.ConnectionStats)
+	return ret0
+}
+
+// ConnectionStats indicates an expected call of ConnectionStats.
+func (mr *MockEarlySessionMockRecorder) ConnectionStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionStats", reflect.TypeOf((*MockEarlySession)(nil).ConnectionStats))
+}
+
 // Context mocks base method.
 func (m *MockEarlySession) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -109,6 +137,20 @@ func (mr *MockEarlySessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockEarlySession)(nil).Context))
 }
 
+// FlowControlState mocks base method.
+func (m *MockEarlySession) FlowControlState() quic.FlowControlState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlState")
+	ret0, _ := ret[0].(quic.FlowControlState)
+	return ret0
+}
+
+// FlowControlState indicates an expected call of FlowControlState.
+func (mr *MockEarlySessionMockRecorder) FlowControlState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlState", reflect.TypeOf((*MockEarlySession)(nil).FlowControlState))
+}
+
 // HandshakeComplete mocks base method.
 func (m *MockEarlySession) HandshakeComplete() context.Context {
 	m.ctrl.T.Helper()
@@ -123,6 +165,21 @@ func (mr *MockEarlySessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockEarlySession)(nil).HandshakeComplete))
 }
 
+// InFlightPackets mocks base method.
+func (m *MockEarlySession) InFlightPackets(arg0 context.Context) ([]quic.InFlightPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InFlightPackets", arg0)
+	ret0, _ := ret[0].([]quic.InFlightPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InFlightPackets indicates an expected call of InFlightPackets.
+func (mr *MockEarlySessionMockRecorder) InFlightPackets(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InFlightPackets", reflect.TypeOf((*MockEarlySession)(nil).InFlightPackets), arg0)
+}
+
 // LocalAddr mocks base method.
 func (m *MockEarlySession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -137,6 +194,20 @@ func (mr *MockEarlySessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockEarlySession)(nil).LocalAddr))
 }
 
+// NewStreamGroup mocks base method.
+func (m *MockEarlySession) NewStreamGroup() *quic.StreamGroup {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewStreamGroup")
+	ret0, _ := ret[0].(*quic.StreamGroup)
+	return ret0
+}
+
+// NewStreamGroup indicates an expected call of NewStreamGroup.
+func (mr *MockEarlySessionMockRecorder) NewStreamGroup() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewStreamGroup", reflect.TypeOf((*MockEarlySession)(nil).NewStreamGroup))
+}
+
 // NextSession mocks base method.
 func (m *MockEarlySession) NextSession() quic.Session {
 	m.ctrl.T.Helper()
@@ -181,6 +252,21 @@ func (mr *MockEarlySessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamSyncWithOptions mocks base method.
+func (m *MockEarlySession) OpenStreamSyncWithOptions(arg0 context.Context, arg1 quic.StreamOptions) (quic.Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamSyncWithOptions", arg0, arg1)
+	ret0, _ := ret[0].(quic.Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamSyncWithOptions indicates an expected call of OpenStreamSyncWithOptions.
+func (mr *MockEarlySessionMockRecorder) OpenStreamSyncWithOptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSyncWithOptions", reflect.TypeOf((*MockEarlySession)(nil).OpenStreamSyncWithOptions), arg0, arg1)
+}
+
 // OpenUniStream mocks base method.
 func (m *MockEarlySession) OpenUniStream() (quic.SendStream, error) {
 	m.ctrl.T.Helper()
@@ -211,6 +297,35 @@ func (mr *MockEarlySessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockEarlySession)(nil).OpenUniStreamSync), arg0)
 }
 
+// OpenUniStreamSyncWithOptions mocks base method.
+func (m *MockEarlySession) OpenUniStreamSyncWithOptions(arg0 context.Context, arg1 quic.StreamOptions) (quic.SendStream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenUniStreamSyncWithOptions", arg0, arg1)
+	ret0, _ := ret[0].(quic.SendStream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenUniStreamSyncWithOptions indicates an expected call of OpenUniStreamSyncWithOptions.
+func (mr *MockEarlySessionMockRecorder) OpenUniStreamSyncWithOptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSyncWithOptions", reflect.TypeOf((*MockEarlySession)(nil).OpenUniStreamSyncWithOptions), arg0, arg1)
+}
+
+// PacingState mocks base method.
+func (m *MockEarlySession) PacingState() quic.PacingState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacingState")
+	ret0, _ := ret[0].(quic.PacingState)
+	return ret0
+}
+
+// PacingState indicates an expected call of PacingState.
+func (mr *MockEarlySessionMockRecorder) PacingState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacingState", reflect.TypeOf((*MockEarlySession)(nil).PacingState))
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockEarlySession) ReceiveMessage() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -240,6 +355,20 @@ func (mr *MockEarlySessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockEarlySession)(nil).RemoteAddr))
 }
 
+// SendBDPFrame mocks base method.
+func (m *MockEarlySession) SendBDPFrame(arg0 quic.BDPInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendBDPFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendBDPFrame indicates an expected call of SendBDPFrame.
+func (mr *MockEarlySessionMockRecorder) SendBDPFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendBDPFrame", reflect.TypeOf((*MockEarlySession)(nil).SendBDPFrame), arg0)
+}
+
 // SendMessage mocks base method.
 func (m *MockEarlySession) SendMessage(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -253,3 +382,45 @@ func (mr *MockEarlySessionMockRecorder) SendMessage(arg0 interface{}) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockEarlySession)(nil).SendMessage), arg0)
 }
+
+// SetMaxIncomingStreams mocks base method.
+func (m *MockEarlySession) SetMaxIncomingStreams(arg0 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMaxIncomingStreams", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMaxIncomingStreams indicates an expected call of SetMaxIncomingStreams.
+func (mr *MockEarlySessionMockRecorder) SetMaxIncomingStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingStreams", reflect.TypeOf((*MockEarlySession)(nil).SetMaxIncomingStreams), arg0)
+}
+
+// SetMaxIncomingUniStreams mocks base method.
+func (m *MockEarlySession) SetMaxIncomingUniStreams(arg0 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMaxIncomingUniStreams", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMaxIncomingUniStreams indicates an expected call of SetMaxIncomingUniStreams.
+func (mr *MockEarlySessionMockRecorder) SetMaxIncomingUniStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingUniStreams", reflect.TypeOf((*MockEarlySession)(nil).SetMaxIncomingUniStreams), arg0)
+}
+
+// StreamErrors mocks base method.
+func (m *MockEarlySession) StreamErrors() *quic.StreamErrorRegistry {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamErrors")
+	ret0, _ := ret[0].(*quic.StreamErrorRegistry)
+	return ret0
+}
+
+// StreamErrors indicates an expected call of StreamErrors.
+func (mr *MockEarlySessionMockRecorder) StreamErrors() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamErrors", reflect.TypeOf((*MockEarlySession)(nil).StreamErrors))
+}
@@ -10,6 +10,7 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	quic "github.com/lucas-clemente/quic-go"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	qerr "github.com/lucas-clemente/quic-go/internal/qerr"
 )
@@ -37,6 +38,20 @@ func (m *MockStream) EXPECT() *MockStreamMockRecorder {
 	return m.recorder
 }
 
+// BufferedAmount mocks base method.
+func (m *MockStream) BufferedAmount() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedAmount")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedAmount indicates an expected call of BufferedAmount.
+func (mr *MockStreamMockRecorder) BufferedAmount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedAmount", reflect.TypeOf((*MockStream)(nil).BufferedAmount))
+}
+
 // CancelRead mocks base method.
 func (m *MockStream) CancelRead(arg0 qerr.StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -49,6 +64,30 @@ func (mr *MockStreamMockRecorder) CancelRead(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRead", reflect.TypeOf((*MockStream)(nil).CancelRead), arg0)
 }
 
+// CancelReadAfter mocks base method.
+func (m *MockStream) CancelReadAfter(arg0 uint64, arg1 qerr.StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelReadAfter", arg0, arg1)
+}
+
+// CancelReadAfter indicates an expected call of CancelReadAfter.
+func (mr *MockStreamMockRecorder) CancelReadAfter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReadAfter", reflect.TypeOf((*MockStream)(nil).CancelReadAfter), arg0, arg1)
+}
+
+// CancelReadAfterBuffered mocks base method.
+func (m *MockStream) CancelReadAfterBuffered(arg0 qerr.StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelReadAfterBuffered", arg0)
+}
+
+// CancelReadAfterBuffered indicates an expected call of CancelReadAfterBuffered.
+func (mr *MockStreamMockRecorder) CancelReadAfterBuffered(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReadAfterBuffered", reflect.TypeOf((*MockStream)(nil).CancelReadAfterBuffered), arg0)
+}
+
 // CancelWrite mocks base method.
 func (m *MockStream) CancelWrite(arg0 qerr.StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -61,6 +100,20 @@ func (mr *MockStreamMockRecorder) CancelWrite(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockStream)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method.
+func (m *MockStream) CancelWriteAt(arg0 qerr.StreamErrorCode, arg1 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelWriteAt", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt.
+func (mr *MockStreamMockRecorder) CancelWriteAt(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockStream)(nil).CancelWriteAt), arg0, arg1)
+}
+
 // Close mocks base method.
 func (m *MockStream) Close() error {
 	m.ctrl.T.Helper()
@@ -89,6 +142,184 @@ func (mr *MockStreamMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStream)(nil).Context))
 }
 
+// Delivered mocks base method.
+func (m *MockStream) Delivered(arg0 uint64) <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delivered", arg0)
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Delivered indicates an expected call of Delivered.
+func (mr *MockStreamMockRecorder) Delivered(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delivered", reflect.TypeOf((*MockStream)(nil).Delivered), arg0)
+}
+
+// DeliveredOffset mocks base method.
+func (m *MockStream) DeliveredOffset() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliveredOffset")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// DeliveredOffset indicates an expected call of DeliveredOffset.
+func (mr *MockStreamMockRecorder) DeliveredOffset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliveredOffset", reflect.TypeOf((*MockStream)(nil).DeliveredOffset))
+}
+
+// Discard mocks base method.
+func (m *MockStream) Discard(arg0 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discard", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Discard indicates an expected call of Discard.
+func (mr *MockStreamMockRecorder) Discard(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discard", reflect.TypeOf((*MockStream)(nil).Discard), arg0)
+}
+
+// Done mocks base method.
+func (m *MockStream) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done.
+func (mr *MockStreamMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockStream)(nil).Done))
+}
+
+// Err mocks base method.
+func (m *MockStream) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockStreamMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockStream)(nil).Err))
+}
+
+// FlowControlState mocks base method.
+func (m *MockStream) FlowControlState() quic.FlowControlState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlState")
+	ret0, _ := ret[0].(quic.FlowControlState)
+	return ret0
+}
+
+// FlowControlState indicates an expected call of FlowControlState.
+func (mr *MockStreamMockRecorder) FlowControlState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlState", reflect.TypeOf((*MockStream)(nil).FlowControlState))
+}
+
+// Flush mocks base method.
+func (m *MockStream) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockStreamMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockStream)(nil).Flush))
+}
+
+// GrantReceiveCredit mocks base method.
+func (m *MockStream) GrantReceiveCredit(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GrantReceiveCredit", arg0)
+}
+
+// GrantReceiveCredit indicates an expected call of GrantReceiveCredit.
+func (mr *MockStreamMockRecorder) GrantReceiveCredit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantReceiveCredit", reflect.TypeOf((*MockStream)(nil).GrantReceiveCredit), arg0)
+}
+
+// OnReadable mocks base method.
+func (m *MockStream) OnReadable(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnReadable", arg0)
+}
+
+// OnReadable indicates an expected call of OnReadable.
+func (mr *MockStreamMockRecorder) OnReadable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnReadable", reflect.TypeOf((*MockStream)(nil).OnReadable), arg0)
+}
+
+// OnWritable mocks base method.
+func (m *MockStream) OnWritable(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnWritable", arg0)
+}
+
+// OnWritable indicates an expected call of OnWritable.
+func (mr *MockStreamMockRecorder) OnWritable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnWritable", reflect.TypeOf((*MockStream)(nil).OnWritable), arg0)
+}
+
+// Peek mocks base method.
+func (m *MockStream) Peek(arg0 int) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Peek", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Peek indicates an expected call of Peek.
+func (mr *MockStreamMockRecorder) Peek(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Peek", reflect.TypeOf((*MockStream)(nil).Peek), arg0)
+}
+
+// PeerClosed mocks base method.
+func (m *MockStream) PeerClosed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PeerClosed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// PeerClosed indicates an expected call of PeerClosed.
+func (mr *MockStreamMockRecorder) PeerClosed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeerClosed", reflect.TypeOf((*MockStream)(nil).PeerClosed))
+}
+
+// Priority mocks base method.
+func (m *MockStream) Priority() quic.StreamPriority {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Priority")
+	ret0, _ := ret[0].(quic.StreamPriority)
+	return ret0
+}
+
+// Priority indicates an expected call of Priority.
+func (mr *MockStreamMockRecorder) Priority() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Priority", reflect.TypeOf((*MockStream)(nil).Priority))
+}
+
 // Read mocks base method.
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -104,6 +335,51 @@ func (mr *MockStreamMockRecorder) Read(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStream)(nil).Read), arg0)
 }
 
+// ReadBuffers mocks base method.
+func (m *MockStream) ReadBuffers() ([][]byte, func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffers")
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(func())
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadBuffers indicates an expected call of ReadBuffers.
+func (mr *MockStreamMockRecorder) ReadBuffers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffers", reflect.TypeOf((*MockStream)(nil).ReadBuffers))
+}
+
+// ReadContext mocks base method.
+func (m *MockStream) ReadContext(arg0 context.Context, arg1 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadContext", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadContext indicates an expected call of ReadContext.
+func (mr *MockStreamMockRecorder) ReadContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadContext", reflect.TypeOf((*MockStream)(nil).ReadContext), arg0, arg1)
+}
+
+// ReadableBytes mocks base method.
+func (m *MockStream) ReadableBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadableBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ReadableBytes indicates an expected call of ReadableBytes.
+func (mr *MockStreamMockRecorder) ReadableBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadableBytes", reflect.TypeOf((*MockStream)(nil).ReadableBytes))
+}
+
 // SetDeadline mocks base method.
 func (m *MockStream) SetDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -118,6 +394,30 @@ func (mr *MockStreamMockRecorder) SetDeadline(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStream)(nil).SetDeadline), arg0)
 }
 
+// SetDiscardOnWriteTimeout mocks base method.
+func (m *MockStream) SetDiscardOnWriteTimeout(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDiscardOnWriteTimeout", arg0)
+}
+
+// SetDiscardOnWriteTimeout indicates an expected call of SetDiscardOnWriteTimeout.
+func (mr *MockStreamMockRecorder) SetDiscardOnWriteTimeout(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDiscardOnWriteTimeout", reflect.TypeOf((*MockStream)(nil).SetDiscardOnWriteTimeout), arg0)
+}
+
+// SetPriority mocks base method.
+func (m *MockStream) SetPriority(arg0 quic.StreamPriority) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority.
+func (mr *MockStreamMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockStream)(nil).SetPriority), arg0)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStream) SetReadDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -132,6 +432,30 @@ func (mr *MockStreamMockRecorder) SetReadDeadline(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStream)(nil).SetReadDeadline), arg0)
 }
 
+// SetReceiveWindow mocks base method.
+func (m *MockStream) SetReceiveWindow(arg0 uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReceiveWindow", arg0)
+}
+
+// SetReceiveWindow indicates an expected call of SetReceiveWindow.
+func (mr *MockStreamMockRecorder) SetReceiveWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindow", reflect.TypeOf((*MockStream)(nil).SetReceiveWindow), arg0)
+}
+
+// SetTrafficClass mocks base method.
+func (m *MockStream) SetTrafficClass(arg0 quic.TrafficClass) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrafficClass", arg0)
+}
+
+// SetTrafficClass indicates an expected call of SetTrafficClass.
+func (mr *MockStreamMockRecorder) SetTrafficClass(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrafficClass", reflect.TypeOf((*MockStream)(nil).SetTrafficClass), arg0)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStream) SetWriteDeadline(arg0 time.Time) error {
 	m.ctrl.T.Helper()
@@ -160,6 +484,50 @@ func (mr *MockStreamMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStream)(nil).StreamID))
 }
 
+// TrafficClass mocks base method.
+func (m *MockStream) TrafficClass() quic.TrafficClass {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrafficClass")
+	ret0, _ := ret[0].(quic.TrafficClass)
+	return ret0
+}
+
+// TrafficClass indicates an expected call of TrafficClass.
+func (mr *MockStreamMockRecorder) TrafficClass() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrafficClass", reflect.TypeOf((*MockStream)(nil).TrafficClass))
+}
+
+// TryRead mocks base method.
+func (m *MockStream) TryRead(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockStreamMockRecorder) TryRead(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockStream)(nil).TryRead), arg0)
+}
+
+// TryWrite mocks base method.
+func (m *MockStream) TryWrite(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockStreamMockRecorder) TryWrite(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockStream)(nil).TryWrite), arg0)
+}
+
 // Write mocks base method.
 func (m *MockStream) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -174,3 +542,48 @@ func (mr *MockStreamMockRecorder) Write(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStream)(nil).Write), arg0)
 }
+
+// WriteBuffers mocks base method.
+func (m *MockStream) WriteBuffers(arg0 [][]byte) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockStreamMockRecorder) WriteBuffers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockStream)(nil).WriteBuffers), arg0)
+}
+
+// WriteContext mocks base method.
+func (m *MockStream) WriteContext(arg0 context.Context, arg1 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteContext", arg0, arg1)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteContext indicates an expected call of WriteContext.
+func (mr *MockStreamMockRecorder) WriteContext(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteContext", reflect.TypeOf((*MockStream)(nil).WriteContext), arg0, arg1)
+}
+
+// WriteMessage mocks base method.
+func (m *MockStream) WriteMessage(arg0 []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteMessage", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteMessage indicates an expected call of WriteMessage.
+func (mr *MockStreamMockRecorder) WriteMessage(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteMessage", reflect.TypeOf((*MockStream)(nil).WriteMessage), arg0)
+}
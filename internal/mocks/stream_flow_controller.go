@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
@@ -84,6 +85,18 @@ func (mr *MockStreamFlowControllerMockRecorder) GetWindowUpdate() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWindowUpdate", reflect.TypeOf((*MockStreamFlowController)(nil).GetWindowUpdate))
 }
 
+// GrantCredit mocks base method.
+func (m *MockStreamFlowController) GrantCredit(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GrantCredit", arg0)
+}
+
+// GrantCredit indicates an expected call of GrantCredit.
+func (mr *MockStreamFlowControllerMockRecorder) GrantCredit(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantCredit", reflect.TypeOf((*MockStreamFlowController)(nil).GrantCredit), arg0)
+}
+
 // IsNewlyBlocked mocks base method.
 func (m *MockStreamFlowController) IsNewlyBlocked() (bool, protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -113,6 +126,44 @@ func (mr *MockStreamFlowControllerMockRecorder) SendWindowSize() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWindowSize", reflect.TypeOf((*MockStreamFlowController)(nil).SendWindowSize))
 }
 
+// SetGroup mocks base method.
+func (m *MockStreamFlowController) SetGroup(arg0 *flowcontrol.GroupFlowController) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetGroup", arg0)
+}
+
+// SetGroup indicates an expected call of SetGroup.
+func (mr *MockStreamFlowControllerMockRecorder) SetGroup(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGroup", reflect.TypeOf((*MockStreamFlowController)(nil).SetGroup), arg0)
+}
+
+// SetMaxReceiveWindow mocks base method.
+func (m *MockStreamFlowController) SetMaxReceiveWindow(arg0 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetMaxReceiveWindow", arg0)
+}
+
+// SetMaxReceiveWindow indicates an expected call of SetMaxReceiveWindow.
+func (mr *MockStreamFlowControllerMockRecorder) SetMaxReceiveWindow(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxReceiveWindow", reflect.TypeOf((*MockStreamFlowController)(nil).SetMaxReceiveWindow), arg0)
+}
+
+// State mocks base method.
+func (m *MockStreamFlowController) State() flowcontrol.WindowState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State")
+	ret0, _ := ret[0].(flowcontrol.WindowState)
+	return ret0
+}
+
+// State indicates an expected call of State.
+func (mr *MockStreamFlowControllerMockRecorder) State() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockStreamFlowController)(nil).State))
+}
+
 // UpdateHighestReceived mocks base method.
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
@@ -17,6 +17,9 @@ const MaxCongestionWindowPackets = 10000
 // MaxUndecryptablePackets limits the number of undecryptable packets that are queued in the session.
 const MaxUndecryptablePackets = 32
 
+// MaxUndecryptableBytes limits the total size of the undecryptable packets that are queued in the session.
+const MaxUndecryptableBytes = MaxUndecryptablePackets * MaxPacketBufferSize
+
 // ConnectionFlowControlMultiplier determines how much larger the connection flow control windows needs to be relative to any stream's flow control window
 // This is the value that Chromium is using
 const ConnectionFlowControlMultiplier = 1.5
@@ -92,9 +95,26 @@ const MinStreamFrameBufferSize = 128
 // If a packet has less than this number of bytes, we won't coalesce any more packets onto it.
 const MinCoalescedPacketSize = 128
 
-// MaxCryptoStreamOffset is the maximum offset allowed on any of the crypto streams.
+// DefaultMaxCryptoStreamOffset is the default maximum offset allowed on any of the crypto streams.
 // This limits the size of the ClientHello and Certificates that can be received.
-const MaxCryptoStreamOffset = 16 * (1 << 10)
+const DefaultMaxCryptoStreamOffset = 16 * (1 << 10)
+
+// DefaultMaxCryptoFrameFragments is the default maximum number of CRYPTO frames that are buffered out of order on a crypto stream.
+const DefaultMaxCryptoFrameFragments = 256
+
+// MaxCryptoFrameFragments is the maximum value that can be configured for the number of out-of-order CRYPTO frames.
+// It is chosen such that the frame sorter never runs out of gaps before this limit is hit.
+const MaxCryptoFrameFragments = MaxStreamFrameSorterGaps - 2
+
+// MaxSessionTickets is the maximum number of session tickets that a server can be configured to send on a connection.
+const MaxSessionTickets = 16
+
+// MaxReasonPhraseLength is the maximum length of the reason phrase sent in a CONNECTION_CLOSE frame.
+// Longer error messages are truncated.
+const MaxReasonPhraseLength = 512
+
+// MaxClosePayloadSize is the maximum size of the structured payload sent in an application CONNECTION_CLOSE frame.
+const MaxClosePayloadSize = 256
 
 // MinRemoteIdleTimeout is the minimum value that we accept for the remote idle timeout
 const MinRemoteIdleTimeout = 5 * time.Second
@@ -182,6 +202,18 @@ const MaxAckDelayInclGranularity = MaxAckDelay + TimerGranularity
 // KeyUpdateInterval is the maximum number of packets we send or receive before initiating a key update.
 const KeyUpdateInterval = 100 * 1000
 
+// DefaultCoalescingMinFill is the default fraction of the maximum packet size
+// that pending stream data needs to fill for a packet to be sent without waiting for the coalescing delay.
+const DefaultCoalescingMinFill = 0.5
+
+// DefaultPersistentCongestionThreshold is the default multiplier applied to the PTO to obtain the persistent congestion duration.
+// See section 7.6.1 of RFC 9002.
+const DefaultPersistentCongestionThreshold = 3
+
+// AckPiggybackWindow is the maximum time that we delay an ACK-only packet when we're pacing limited.
+// If the pacer allows sending a packet containing data within this time, the ACK is sent in that packet.
+const AckPiggybackWindow = time.Millisecond
+
 // Max0RTTQueueingDuration is the maximum time that we store 0-RTT packets in order to wait for the corresponding Initial to be received.
 const Max0RTTQueueingDuration = 100 * time.Millisecond
 
@@ -193,3 +225,11 @@ const Max0RTTQueues = 32
 // To avoid blocking, this value has to be smaller than MaxSessionUnprocessedPackets.
 // To avoid packets being dropped as undecryptable by the session, this value has to be smaller than MaxUndecryptablePackets.
 const Max0RTTQueueLen = 31
+
+// MinPathValidationPTO is the minimum PTO used to derive the timeout for validating a new peer address.
+// It corresponds to the PTO of a new path, for which no RTT sample is available yet (see section 8.2.4 of RFC 9000).
+const MinPathValidationPTO = time.Second
+
+// MaxBDPEndpointTokenLength is the maximum length of the endpoint token sent in a BDP_FRAME.
+// It ensures that the frame always fits into a single packet.
+const MaxBDPEndpointTokenLength = 255
@@ -71,6 +71,14 @@ const MinUnknownVersionPacketSize = MinInitialPacketSize
 // MinStatelessResetSize is the minimum size of a stateless reset packet that we send
 const MinStatelessResetSize = 1 /* first byte */ + 20 /* max. conn ID length */ + 4 /* max. packet number length */ + 1 /* min. payload length */ + 16 /* token */
 
+// StatelessResetSuspicionThreshold is the number of consecutive 1-RTT packets that fail to decrypt,
+// after which we suspect that the peer sent a stateless reset that we didn't recognize.
+const StatelessResetSuspicionThreshold = 3
+
+// DuplicatePacketAnomalyThreshold is the number of duplicate packets after which the peer is reported to the tracer.
+// The peer is reported again every time this number of duplicate packets was received.
+const DuplicatePacketAnomalyThreshold = 10
+
 // MinConnectionIDLenInitial is the minimum length of the destination connection ID on an Initial packet.
 const MinConnectionIDLenInitial = 8
 
@@ -1,6 +1,7 @@
 package qerr
 
 import (
+	"errors"
 	"fmt"
 	"net"
 
@@ -10,6 +11,12 @@ import (
 var (
 	ErrHandshakeTimeout = &HandshakeTimeoutError{}
 	ErrIdleTimeout      = &IdleTimeoutError{}
+	// ErrStatelessReset is matched by every StatelessResetError.
+	ErrStatelessReset = errors.New("stateless reset")
+	// ErrVersionNegotiation is matched by every VersionNegotiationError.
+	ErrVersionNegotiation = errors.New("version negotiation failed")
+	// ErrApplicationClose is matched by every ApplicationError.
+	ErrApplicationClose = errors.New("application close")
 )
 
 type TransportError struct {
@@ -52,7 +59,7 @@ func (e *TransportError) Is(target error) bool {
 type ApplicationErrorCode uint64
 
 func (e *ApplicationError) Is(target error) bool {
-	return target == net.ErrClosed
+	return target == net.ErrClosed || target == ErrApplicationClose
 }
 
 // A StreamErrorCode is an error code used to cancel streams.
@@ -62,6 +69,9 @@ type ApplicationError struct {
 	Remote       bool
 	ErrorCode    ApplicationErrorCode
 	ErrorMessage string
+	// Payload is a structured payload sent along with the error message.
+	// Its format is defined by the application.
+	Payload []byte
 }
 
 var _ error = &ApplicationError{}
@@ -77,19 +87,25 @@ type IdleTimeoutError struct{}
 
 var _ error = &IdleTimeoutError{}
 
-func (e *IdleTimeoutError) Timeout() bool        { return true }
-func (e *IdleTimeoutError) Temporary() bool      { return false }
-func (e *IdleTimeoutError) Error() string        { return "timeout: no recent network activity" }
-func (e *IdleTimeoutError) Is(target error) bool { return target == net.ErrClosed }
+func (e *IdleTimeoutError) Timeout() bool   { return true }
+func (e *IdleTimeoutError) Temporary() bool { return false }
+func (e *IdleTimeoutError) Error() string   { return "timeout: no recent network activity" }
+func (e *IdleTimeoutError) Is(target error) bool {
+	_, ok := target.(*IdleTimeoutError)
+	return ok || target == net.ErrClosed
+}
 
 type HandshakeTimeoutError struct{}
 
 var _ error = &HandshakeTimeoutError{}
 
-func (e *HandshakeTimeoutError) Timeout() bool        { return true }
-func (e *HandshakeTimeoutError) Temporary() bool      { return false }
-func (e *HandshakeTimeoutError) Error() string        { return "timeout: handshake did not complete in time" }
-func (e *HandshakeTimeoutError) Is(target error) bool { return target == net.ErrClosed }
+func (e *HandshakeTimeoutError) Timeout() bool   { return true }
+func (e *HandshakeTimeoutError) Temporary() bool { return false }
+func (e *HandshakeTimeoutError) Error() string   { return "timeout: handshake did not complete in time" }
+func (e *HandshakeTimeoutError) Is(target error) bool {
+	_, ok := target.(*HandshakeTimeoutError)
+	return ok || target == net.ErrClosed
+}
 
 // A VersionNegotiationError occurs when the client and the server can't agree on a QUIC version.
 type VersionNegotiationError struct {
@@ -102,7 +118,7 @@ func (e *VersionNegotiationError) Error() string {
 }
 
 func (e *VersionNegotiationError) Is(target error) bool {
-	return target == net.ErrClosed
+	return target == net.ErrClosed || target == ErrVersionNegotiation
 }
 
 // A StatelessResetError occurs when we receive a stateless reset.
@@ -117,7 +133,7 @@ func (e *StatelessResetError) Error() string {
 }
 
 func (e *StatelessResetError) Is(target error) bool {
-	return target == net.ErrClosed
+	return target == net.ErrClosed || target == ErrStatelessReset
 }
 
 func (e *StatelessResetError) Timeout() bool   { return false }
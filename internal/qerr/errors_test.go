@@ -2,6 +2,7 @@ package qerr
 
 import (
 	"errors"
+	"fmt"
 	"net"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -124,4 +125,22 @@ var _ = Describe("QUIC Errors", func() {
 		Expect(errors.Is(&StatelessResetError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&VersionNegotiationError{}, net.ErrClosed)).To(BeTrue())
 	})
+
+	It("matches the sentinel errors", func() {
+		Expect(errors.Is(&IdleTimeoutError{}, ErrIdleTimeout)).To(BeTrue())
+		Expect(errors.Is(&HandshakeTimeoutError{}, ErrHandshakeTimeout)).To(BeTrue())
+		Expect(errors.Is(&StatelessResetError{Token: protocol.StatelessResetToken{1, 2, 3}}, ErrStatelessReset)).To(BeTrue())
+		Expect(errors.Is(&VersionNegotiationError{Ours: []protocol.VersionNumber{1}}, ErrVersionNegotiation)).To(BeTrue())
+		Expect(errors.Is(&ApplicationError{ErrorCode: 0x42, Remote: true}, ErrApplicationClose)).To(BeTrue())
+		Expect(errors.Is(fmt.Errorf("wrapped: %w", &IdleTimeoutError{}), ErrIdleTimeout)).To(BeTrue())
+	})
+
+	It("doesn't match the sentinel errors of other errors", func() {
+		Expect(errors.Is(&IdleTimeoutError{}, ErrHandshakeTimeout)).To(BeFalse())
+		Expect(errors.Is(&HandshakeTimeoutError{}, ErrIdleTimeout)).To(BeFalse())
+		Expect(errors.Is(&StatelessResetError{}, ErrVersionNegotiation)).To(BeFalse())
+		Expect(errors.Is(&VersionNegotiationError{}, ErrStatelessReset)).To(BeFalse())
+		Expect(errors.Is(&TransportError{}, ErrApplicationClose)).To(BeFalse())
+		Expect(errors.Is(&ApplicationError{}, ErrIdleTimeout)).To(BeFalse())
+	})
 })
@@ -0,0 +1,47 @@
+package qerr
+
+import (
+	"encoding/binary"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// reasonPhrasePayloadPrefix starts a reason phrase that carries a structured payload.
+// The leading 0 byte makes it unlikely to be confused with a human-readable reason phrase.
+const reasonPhrasePayloadPrefix = "\x00qcp"
+
+// EncodeReasonPhrase encodes an error message and a payload into the reason phrase of a CONNECTION_CLOSE frame.
+// The error message is truncated, such that the reason phrase doesn't exceed protocol.MaxReasonPhraseLength.
+// The payload must not be larger than protocol.MaxClosePayloadSize.
+func EncodeReasonPhrase(msg string, payload []byte) string {
+	if len(payload) == 0 {
+		if len(msg) > protocol.MaxReasonPhraseLength {
+			msg = msg[:protocol.MaxReasonPhraseLength]
+		}
+		return msg
+	}
+	if maxLen := protocol.MaxReasonPhraseLength - len(reasonPhrasePayloadPrefix) - 2 - len(payload); len(msg) > maxLen {
+		msg = msg[:maxLen]
+	}
+	b := make([]byte, 0, len(reasonPhrasePayloadPrefix)+2+len(msg)+len(payload))
+	b = append(b, reasonPhrasePayloadPrefix...)
+	b = append(b, uint8(len(msg)>>8), uint8(len(msg)))
+	b = append(b, msg...)
+	b = append(b, payload...)
+	return string(b)
+}
+
+// DecodeReasonPhrase decodes a reason phrase encoded by EncodeReasonPhrase.
+// If the reason phrase doesn't carry a payload, it is returned as the error message.
+func DecodeReasonPhrase(reason string) (string, []byte) {
+	if len(reason) < len(reasonPhrasePayloadPrefix)+2 || reason[:len(reasonPhrasePayloadPrefix)] != reasonPhrasePayloadPrefix {
+		return reason, nil
+	}
+	b := reason[len(reasonPhrasePayloadPrefix):]
+	msgLen := int(binary.BigEndian.Uint16([]byte(b[:2])))
+	b = b[2:]
+	if msgLen > len(b) {
+		return reason, nil
+	}
+	return b[:msgLen], []byte(b[msgLen:])
+}
@@ -0,0 +1,54 @@
+package qerr
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reason Phrases", func() {
+	It("encodes error messages without a payload as is", func() {
+		reason := EncodeReasonPhrase("foobar", nil)
+		Expect(reason).To(Equal("foobar"))
+		msg, payload := DecodeReasonPhrase(reason)
+		Expect(msg).To(Equal("foobar"))
+		Expect(payload).To(BeNil())
+	})
+
+	It("truncates long error messages", func() {
+		reason := EncodeReasonPhrase(strings.Repeat("a", 1000), nil)
+		Expect(reason).To(HaveLen(protocol.MaxReasonPhraseLength))
+	})
+
+	It("encodes and decodes a payload", func() {
+		reason := EncodeReasonPhrase("foobar", []byte("key=value"))
+		msg, payload := DecodeReasonPhrase(reason)
+		Expect(msg).To(Equal("foobar"))
+		Expect(payload).To(Equal([]byte("key=value")))
+	})
+
+	It("encodes a payload with an empty error message", func() {
+		msg, payload := DecodeReasonPhrase(EncodeReasonPhrase("", []byte{0, 1, 2}))
+		Expect(msg).To(BeEmpty())
+		Expect(payload).To(Equal([]byte{0, 1, 2}))
+	})
+
+	It("truncates the error message to make room for the payload", func() {
+		p := bytes.Repeat([]byte{0x42}, protocol.MaxClosePayloadSize)
+		reason := EncodeReasonPhrase(strings.Repeat("a", 1000), p)
+		Expect(reason).To(HaveLen(protocol.MaxReasonPhraseLength))
+		msg, payload := DecodeReasonPhrase(reason)
+		Expect(msg).To(Equal(strings.Repeat("a", protocol.MaxReasonPhraseLength-len(reasonPhrasePayloadPrefix)-2-protocol.MaxClosePayloadSize)))
+		Expect(payload).To(Equal(p))
+	})
+
+	It("doesn't decode a reason phrase with an invalid length", func() {
+		reason := reasonPhrasePayloadPrefix + "\x00\x10foo"
+		msg, payload := DecodeReasonPhrase(reason)
+		Expect(msg).To(Equal(reason))
+		Expect(payload).To(BeNil())
+	})
+})
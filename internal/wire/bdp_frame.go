@@ -0,0 +1,85 @@
+package wire
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// bdpFrameType is the frame type of the BDP_FRAME.
+// The extension is experimental, and the frame type is not registered yet.
+const bdpFrameType = 0x1f
+
+// A BDPFrame is a BDP_FRAME, see draft-kuhn-quic-bdpframe-extension.
+// It carries the path characteristics observed by an endpoint,
+// such that a later connection can skip probing for the capacity of the path.
+type BDPFrame struct {
+	// Lifetime is the time for which the values are valid. It is encoded in seconds.
+	Lifetime time.Duration
+	// SavedCapacity is the bandwidth-delay product of the path, in bytes.
+	SavedCapacity uint64
+	// SavedRTT is the minimum RTT of the path. It is encoded in microseconds.
+	SavedRTT time.Duration
+	// EndpointToken identifies the endpoint that the values were observed for.
+	EndpointToken []byte
+}
+
+func parseBDPFrame(r *bytes.Reader, _ protocol.VersionNumber) (*BDPFrame, error) {
+	if _, err := r.ReadByte(); err != nil {
+		return nil, err
+	}
+	lifetime, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	rtt, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	tokenLen, err := quicvarint.Read(r)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(r.Len()) < tokenLen {
+		return nil, io.EOF
+	}
+	f := &BDPFrame{
+		Lifetime:      time.Duration(lifetime) * time.Second,
+		SavedCapacity: capacity,
+		SavedRTT:      time.Duration(rtt) * time.Microsecond,
+	}
+	if tokenLen > 0 {
+		f.EndpointToken = make([]byte, int(tokenLen))
+		if _, err := io.ReadFull(r, f.EndpointToken); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *BDPFrame) Write(b *bytes.Buffer, _ protocol.VersionNumber) error {
+	b.WriteByte(bdpFrameType)
+	quicvarint.Write(b, uint64(f.Lifetime/time.Second))
+	quicvarint.Write(b, f.SavedCapacity)
+	quicvarint.Write(b, uint64(f.SavedRTT/time.Microsecond))
+	quicvarint.Write(b, uint64(len(f.EndpointToken)))
+	b.Write(f.EndpointToken)
+	return nil
+}
+
+// Length of a written frame
+func (f *BDPFrame) Length(protocol.VersionNumber) protocol.ByteCount {
+	return 1 +
+		quicvarint.Len(uint64(f.Lifetime/time.Second)) +
+		quicvarint.Len(f.SavedCapacity) +
+		quicvarint.Len(uint64(f.SavedRTT/time.Microsecond)) +
+		quicvarint.Len(uint64(len(f.EndpointToken))) +
+		protocol.ByteCount(len(f.EndpointToken))
+}
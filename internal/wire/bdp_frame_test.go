@@ -0,0 +1,79 @@
+package wire
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BDP_FRAME", func() {
+	Context("parsing", func() {
+		It("accepts a sample frame", func() {
+			data := []byte{0x1f}
+			data = append(data, encodeVarInt(3600)...)       // lifetime
+			data = append(data, encodeVarInt(0xdeadbeef)...) // saved capacity
+			data = append(data, encodeVarInt(42000)...)      // saved RTT
+			data = append(data, encodeVarInt(6)...)          // token length
+			data = append(data, []byte("foobar")...)
+			b := bytes.NewReader(data)
+			f, err := parseBDPFrame(b, protocol.VersionWhatever)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.Lifetime).To(Equal(time.Hour))
+			Expect(f.SavedCapacity).To(Equal(uint64(0xdeadbeef)))
+			Expect(f.SavedRTT).To(Equal(42 * time.Millisecond))
+			Expect(f.EndpointToken).To(Equal([]byte("foobar")))
+			Expect(b.Len()).To(BeZero())
+		})
+
+		It("accepts a frame without an endpoint token", func() {
+			data := []byte{0x1f}
+			data = append(data, encodeVarInt(60)...)
+			data = append(data, encodeVarInt(1000)...)
+			data = append(data, encodeVarInt(1000)...)
+			data = append(data, encodeVarInt(0)...)
+			f, err := parseBDPFrame(bytes.NewReader(data), protocol.VersionWhatever)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(f.EndpointToken).To(BeNil())
+		})
+
+		It("errors on EOFs", func() {
+			data := []byte{0x1f}
+			data = append(data, encodeVarInt(3600)...)
+			data = append(data, encodeVarInt(0xdeadbeef)...)
+			data = append(data, encodeVarInt(42000)...)
+			data = append(data, encodeVarInt(6)...)
+			data = append(data, []byte("foobar")...)
+			_, err := parseBDPFrame(bytes.NewReader(data), protocol.VersionWhatever)
+			Expect(err).NotTo(HaveOccurred())
+			for i := range data {
+				_, err := parseBDPFrame(bytes.NewReader(data[0:i]), protocol.VersionWhatever)
+				Expect(err).To(HaveOccurred())
+			}
+		})
+	})
+
+	Context("writing", func() {
+		It("writes a sample frame", func() {
+			f := &BDPFrame{
+				Lifetime:      time.Hour,
+				SavedCapacity: 0xdeadbeef,
+				SavedRTT:      42 * time.Millisecond,
+				EndpointToken: []byte("foobar"),
+			}
+			b := &bytes.Buffer{}
+			Expect(f.Write(b, protocol.VersionWhatever)).To(Succeed())
+			expected := []byte{0x1f}
+			expected = append(expected, encodeVarInt(3600)...)
+			expected = append(expected, encodeVarInt(0xdeadbeef)...)
+			expected = append(expected, encodeVarInt(42000)...)
+			expected = append(expected, encodeVarInt(6)...)
+			expected = append(expected, []byte("foobar")...)
+			Expect(b.Bytes()).To(Equal(expected))
+			Expect(f.Length(protocol.VersionWhatever)).To(BeEquivalentTo(b.Len()))
+		})
+	})
+})
@@ -13,16 +13,20 @@ import (
 type frameParser struct {
 	ackDelayExponent uint8
 
-	supportsDatagrams bool
+	supportsDatagrams     bool
+	supportsBDPFrames     bool
+	supportsResetStreamAt bool
 
 	version protocol.VersionNumber
 }
 
 // NewFrameParser creates a new frame parser.
-func NewFrameParser(supportsDatagrams bool, v protocol.VersionNumber) FrameParser {
+func NewFrameParser(supportsDatagrams, supportsBDPFrames, supportsResetStreamAt bool, v protocol.VersionNumber) FrameParser {
 	return &frameParser{
-		supportsDatagrams: supportsDatagrams,
-		version:           v,
+		supportsDatagrams:     supportsDatagrams,
+		supportsBDPFrames:     supportsBDPFrames,
+		supportsResetStreamAt: supportsResetStreamAt,
+		version:               v,
 	}
 }
 
@@ -96,6 +100,18 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typeByte byte, encLevel protoc
 			frame, err = parseConnectionCloseFrame(r, p.version)
 		case 0x1e:
 			frame, err = parseHandshakeDoneFrame(r, p.version)
+		case bdpFrameType:
+			if p.supportsBDPFrames {
+				frame, err = parseBDPFrame(r, p.version)
+				break
+			}
+			err = errors.New("unknown frame type")
+		case resetStreamAtFrameType:
+			if p.supportsResetStreamAt {
+				frame, err = parseResetStreamFrame(r, p.version)
+				break
+			}
+			err = errors.New("unknown frame type")
 		case 0x30, 0x31:
 			if p.supportsDatagrams {
 				frame, err = parseDatagramFrame(r, p.version)
@@ -18,7 +18,7 @@ var _ = Describe("Frame parsing", func() {
 
 	BeforeEach(func() {
 		buf = &bytes.Buffer{}
-		parser = NewFrameParser(true, versionIETFFrames)
+		parser = NewFrameParser(true, true, true, versionIETFFrames)
 	})
 
 	It("returns nil if there's nothing more to read", func() {
@@ -290,7 +290,7 @@ var _ = Describe("Frame parsing", func() {
 	})
 
 	It("errors when DATAGRAM frames are not supported", func() {
-		parser = NewFrameParser(false, versionIETFFrames)
+		parser = NewFrameParser(false, false, false, versionIETFFrames)
 		f := &DatagramFrame{Data: []byte("foobar")}
 		buf := &bytes.Buffer{}
 		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
@@ -302,6 +302,60 @@ var _ = Describe("Frame parsing", func() {
 		}))
 	})
 
+	It("unpacks BDP_FRAMEs", func() {
+		f := &BDPFrame{
+			Lifetime:      time.Hour,
+			SavedCapacity: 1337,
+			SavedRTT:      42 * time.Millisecond,
+			EndpointToken: []byte("foobar"),
+		}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("errors when BDP_FRAMEs are not supported", func() {
+		parser = NewFrameParser(false, false, false, versionIETFFrames)
+		f := &BDPFrame{SavedCapacity: 1337}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		_, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0x1f,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
+	It("unpacks RESET_STREAM_AT frames", func() {
+		f := &ResetStreamFrame{
+			StreamID:     0xdeadbeef,
+			FinalSize:    0xdecafbad1234,
+			ErrorCode:    0x1337,
+			ReliableSize: 0x1234,
+		}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		frame, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(frame).To(Equal(f))
+	})
+
+	It("errors when RESET_STREAM_AT frames are not supported", func() {
+		parser = NewFrameParser(false, false, false, versionIETFFrames)
+		f := &ResetStreamFrame{StreamID: 4, FinalSize: 1000, ReliableSize: 100}
+		buf := &bytes.Buffer{}
+		Expect(f.Write(buf, versionIETFFrames)).To(Succeed())
+		_, err := parser.ParseNext(bytes.NewReader(buf.Bytes()), protocol.Encryption1RTT)
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.FrameEncodingError,
+			FrameType:    0x24,
+			ErrorMessage: "unknown frame type",
+		}))
+	})
+
 	It("errors on invalid type", func() {
 		_, err := parser.ParseNext(bytes.NewReader([]byte{0x42}), protocol.Encryption1RTT)
 		Expect(err).To(MatchError(&qerr.TransportError{
@@ -345,6 +399,7 @@ var _ = Describe("Frame parsing", func() {
 			&ConnectionCloseFrame{},
 			&HandshakeDoneFrame{},
 			&DatagramFrame{},
+			&BDPFrame{},
 		}
 
 		var framesSerialized [][]byte
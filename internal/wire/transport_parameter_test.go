@@ -57,8 +57,10 @@ var _ = Describe("Transport Parameters", func() {
 			StatelessResetToken:             &protocol.StatelessResetToken{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff, 0x00},
 			ActiveConnectionIDLimit:         123,
 			MaxDatagramFrameSize:            876,
+			SupportsBDPFrames:               true,
+			SupportsResetStreamAt:           true,
 		}
-		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876}"))
+		Expect(p.String()).To(Equal("&wire.TransportParameters{OriginalDestinationConnectionID: deadbeef, InitialSourceConnectionID: decafbad, RetrySourceConnectionID: deadc0de, InitialMaxStreamDataBidiLocal: 1234, InitialMaxStreamDataBidiRemote: 2345, InitialMaxStreamDataUni: 3456, InitialMaxData: 4567, MaxBidiStreamNum: 1337, MaxUniStreamNum: 7331, MaxIdleTimeout: 42s, AckDelayExponent: 14, MaxAckDelay: 37ms, ActiveConnectionIDLimit: 123, StatelessResetToken: 0x112233445566778899aabbccddeeff00, MaxDatagramFrameSize: 876, SupportsBDPFrames: true, SupportsResetStreamAt: true}"))
 	})
 
 	It("has a string representation, if there's no stateless reset token, no Retry source connection id and no datagram support", func() {
@@ -100,6 +102,8 @@ var _ = Describe("Transport Parameters", func() {
 			MaxAckDelay:                     42 * time.Millisecond,
 			ActiveConnectionIDLimit:         getRandomValue(),
 			MaxDatagramFrameSize:            protocol.ByteCount(getRandomValue()),
+			SupportsBDPFrames:               true,
+			SupportsResetStreamAt:           true,
 		}
 		data := params.Marshal(protocol.PerspectiveServer)
 
@@ -121,6 +125,8 @@ var _ = Describe("Transport Parameters", func() {
 		Expect(p.MaxAckDelay).To(Equal(42 * time.Millisecond))
 		Expect(p.ActiveConnectionIDLimit).To(Equal(params.ActiveConnectionIDLimit))
 		Expect(p.MaxDatagramFrameSize).To(Equal(params.MaxDatagramFrameSize))
+		Expect(p.SupportsBDPFrames).To(BeTrue())
+		Expect(p.SupportsResetStreamAt).To(BeTrue())
 	})
 
 	It("doesn't marshal a retry_source_connection_id, if no Retry was performed", func() {
@@ -176,6 +182,28 @@ var _ = Describe("Transport Parameters", func() {
 		}))
 	})
 
+	It("errors when enable_bdp_frames has content", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, uint64(enableBDPFramesParameterID))
+		quicvarint.Write(b, 6)
+		b.Write([]byte("foobar"))
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "wrong length for enable_bdp_frames: 6 (expected empty)",
+		}))
+	})
+
+	It("errors when reset_stream_at has content", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, uint64(resetStreamAtParameterID))
+		quicvarint.Write(b, 6)
+		b.Write([]byte("foobar"))
+		Expect((&TransportParameters{}).Unmarshal(b.Bytes(), protocol.PerspectiveServer)).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.TransportParameterError,
+			ErrorMessage: "wrong length for reset_stream_at: 6 (expected empty)",
+		}))
+	})
+
 	It("errors when the server doesn't set the original_destination_connection_id", func() {
 		b := &bytes.Buffer{}
 		quicvarint.Write(b, uint64(statelessResetTokenParameterID))
@@ -541,11 +569,13 @@ var _ = Describe("Transport Parameters", func() {
 			BeforeEach(func() {
 				p = *saved
 				Expect(p.ValidFor0RTT(saved)).To(BeTrue())
+				Expect(p.Mismatched0RTTParameter(saved)).To(BeEmpty())
 			})
 
 			It("rejects the parameters if the InitialMaxStreamDataBidiLocal was reduced", func() {
 				p.InitialMaxStreamDataBidiLocal = saved.InitialMaxStreamDataBidiLocal - 1
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+				Expect(p.Mismatched0RTTParameter(saved)).To(Equal("initial_max_stream_data_bidi_local"))
 			})
 
 			It("doesn't reject the parameters if the InitialMaxStreamDataBidiLocal was increased", func() {
@@ -556,6 +586,7 @@ var _ = Describe("Transport Parameters", func() {
 			It("rejects the parameters if the InitialMaxStreamDataBidiRemote was reduced", func() {
 				p.InitialMaxStreamDataBidiRemote = saved.InitialMaxStreamDataBidiRemote - 1
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+				Expect(p.Mismatched0RTTParameter(saved)).To(Equal("initial_max_stream_data_bidi_remote"))
 			})
 
 			It("doesn't reject the parameters if the InitialMaxStreamDataBidiRemote was increased", func() {
@@ -566,6 +597,7 @@ var _ = Describe("Transport Parameters", func() {
 			It("rejects the parameters if the InitialMaxStreamDataUni was reduced", func() {
 				p.InitialMaxStreamDataUni = saved.InitialMaxStreamDataUni - 1
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+				Expect(p.Mismatched0RTTParameter(saved)).To(Equal("initial_max_stream_data_uni"))
 			})
 
 			It("doesn't reject the parameters if the InitialMaxStreamDataUni was increased", func() {
@@ -576,6 +608,7 @@ var _ = Describe("Transport Parameters", func() {
 			It("rejects the parameters if the InitialMaxData was reduced", func() {
 				p.InitialMaxData = saved.InitialMaxData - 1
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+				Expect(p.Mismatched0RTTParameter(saved)).To(Equal("initial_max_data"))
 			})
 
 			It("doesn't reject the parameters if the InitialMaxData was increased", func() {
@@ -586,6 +619,7 @@ var _ = Describe("Transport Parameters", func() {
 			It("rejects the parameters if the MaxBidiStreamNum was reduced", func() {
 				p.MaxBidiStreamNum = saved.MaxBidiStreamNum - 1
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+				Expect(p.Mismatched0RTTParameter(saved)).To(Equal("initial_max_streams_bidi"))
 			})
 
 			It("accepts the parameters if the MaxBidiStreamNum was increased", func() {
@@ -596,6 +630,7 @@ var _ = Describe("Transport Parameters", func() {
 			It("rejects the parameters if the MaxUniStreamNum changed", func() {
 				p.MaxUniStreamNum = saved.MaxUniStreamNum - 1
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+				Expect(p.Mismatched0RTTParameter(saved)).To(Equal("initial_max_streams_uni"))
 			})
 
 			It("accepts the parameters if the MaxUniStreamNum was increased", func() {
@@ -606,6 +641,7 @@ var _ = Describe("Transport Parameters", func() {
 			It("rejects the parameters if the ActiveConnectionIDLimit changed", func() {
 				p.ActiveConnectionIDLimit = 0
 				Expect(p.ValidFor0RTT(saved)).To(BeFalse())
+				Expect(p.Mismatched0RTTParameter(saved)).To(Equal("active_connection_id_limit"))
 			})
 		})
 	})
@@ -44,6 +44,11 @@ const (
 	retrySourceConnectionIDParameterID         transportParameterID = 0x10
 	// https://datatracker.ietf.org/doc/draft-ietf-quic-datagram/
 	maxDatagramFrameSizeParameterID transportParameterID = 0x20
+	// https://datatracker.ietf.org/doc/draft-kuhn-quic-bdpframe-extension/
+	// This extension is experimental, and the parameter ID is not registered yet.
+	enableBDPFramesParameterID transportParameterID = 0xbdf
+	// https://datatracker.ietf.org/doc/draft-ietf-quic-reliable-stream-reset/
+	resetStreamAtParameterID transportParameterID = 0x17f7586d2cb571
 )
 
 // PreferredAddress is the value encoding in the preferred_address transport parameter
@@ -85,6 +90,11 @@ type TransportParameters struct {
 	ActiveConnectionIDLimit uint64
 
 	MaxDatagramFrameSize protocol.ByteCount
+
+	// SupportsBDPFrames says if the endpoint accepts BDP_FRAMEs.
+	SupportsBDPFrames bool
+	// SupportsResetStreamAt says if the endpoint accepts RESET_STREAM_AT frames.
+	SupportsResetStreamAt bool
 }
 
 // Unmarshal the transport parameters
@@ -153,6 +163,16 @@ func (p *TransportParameters) unmarshal(r *bytes.Reader, sentBy protocol.Perspec
 				return fmt.Errorf("wrong length for disable_active_migration: %d (expected empty)", paramLen)
 			}
 			p.DisableActiveMigration = true
+		case enableBDPFramesParameterID:
+			if paramLen != 0 {
+				return fmt.Errorf("wrong length for enable_bdp_frames: %d (expected empty)", paramLen)
+			}
+			p.SupportsBDPFrames = true
+		case resetStreamAtParameterID:
+			if paramLen != 0 {
+				return fmt.Errorf("wrong length for reset_stream_at: %d (expected empty)", paramLen)
+			}
+			p.SupportsResetStreamAt = true
 		case statelessResetTokenParameterID:
 			if sentBy == protocol.PerspectiveClient {
 				return errors.New("client sent a stateless_reset_token")
@@ -394,6 +414,16 @@ func (p *TransportParameters) Marshal(pers protocol.Perspective) []byte {
 	if p.MaxDatagramFrameSize != protocol.InvalidByteCount {
 		p.marshalVarintParam(b, maxDatagramFrameSizeParameterID, uint64(p.MaxDatagramFrameSize))
 	}
+	// enable_bdp_frames
+	if p.SupportsBDPFrames {
+		quicvarint.Write(b, uint64(enableBDPFramesParameterID))
+		quicvarint.Write(b, 0)
+	}
+	// reset_stream_at
+	if p.SupportsResetStreamAt {
+		quicvarint.Write(b, uint64(resetStreamAtParameterID))
+		quicvarint.Write(b, 0)
+	}
 	return b.Bytes()
 }
 
@@ -444,13 +474,32 @@ func (p *TransportParameters) UnmarshalFromSessionTicket(r *bytes.Reader) error
 
 // ValidFor0RTT checks if the transport parameters match those saved in the session ticket.
 func (p *TransportParameters) ValidFor0RTT(saved *TransportParameters) bool {
-	return p.InitialMaxStreamDataBidiLocal >= saved.InitialMaxStreamDataBidiLocal &&
-		p.InitialMaxStreamDataBidiRemote >= saved.InitialMaxStreamDataBidiRemote &&
-		p.InitialMaxStreamDataUni >= saved.InitialMaxStreamDataUni &&
-		p.InitialMaxData >= saved.InitialMaxData &&
-		p.MaxBidiStreamNum >= saved.MaxBidiStreamNum &&
-		p.MaxUniStreamNum >= saved.MaxUniStreamNum &&
-		p.ActiveConnectionIDLimit == saved.ActiveConnectionIDLimit
+	return p.Mismatched0RTTParameter(saved) == ""
+}
+
+// Mismatched0RTTParameter returns the name of the first transport parameter (as defined in RFC 9000)
+// that was reduced (or, for the active_connection_id_limit, changed) compared to the parameters saved in the session ticket,
+// such that data sent in 0-RTT might violate it, see Section 7.4.1 of RFC 9000.
+// It returns an empty string if the transport parameters are valid for 0-RTT.
+func (p *TransportParameters) Mismatched0RTTParameter(saved *TransportParameters) string {
+	switch {
+	case p.InitialMaxStreamDataBidiLocal < saved.InitialMaxStreamDataBidiLocal:
+		return "initial_max_stream_data_bidi_local"
+	case p.InitialMaxStreamDataBidiRemote < saved.InitialMaxStreamDataBidiRemote:
+		return "initial_max_stream_data_bidi_remote"
+	case p.InitialMaxStreamDataUni < saved.InitialMaxStreamDataUni:
+		return "initial_max_stream_data_uni"
+	case p.InitialMaxData < saved.InitialMaxData:
+		return "initial_max_data"
+	case p.MaxBidiStreamNum < saved.MaxBidiStreamNum:
+		return "initial_max_streams_bidi"
+	case p.MaxUniStreamNum < saved.MaxUniStreamNum:
+		return "initial_max_streams_uni"
+	case p.ActiveConnectionIDLimit != saved.ActiveConnectionIDLimit:
+		return "active_connection_id_limit"
+	default:
+		return ""
+	}
 }
 
 // String returns a string representation, intended for logging.
@@ -471,6 +520,12 @@ func (p *TransportParameters) String() string {
 		logString += ", MaxDatagramFrameSize: %d"
 		logParams = append(logParams, p.MaxDatagramFrameSize)
 	}
+	if p.SupportsBDPFrames {
+		logString += ", SupportsBDPFrames: true"
+	}
+	if p.SupportsResetStreamAt {
+		logString += ", SupportsResetStreamAt: true"
+	}
 	logString += "}"
 	return fmt.Sprintf(logString, logParams...)
 }
@@ -9,7 +9,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -115,7 +114,7 @@ func runVersionNegotiationTest(r *http09.RoundTripper, urls []string) error {
 	if err == nil {
 		return errors.New("expected version negotiation to fail")
 	}
-	if !strings.Contains(err.Error(), "No compatible QUIC version found") {
+	if !errors.Is(err, quic.ErrVersionNegotiation) {
 		return fmt.Errorf("expect version negotiation error, got: %s", err.Error())
 	}
 	return nil
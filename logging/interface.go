@@ -110,20 +110,58 @@ type ConnectionTracer interface {
 	SentTransportParameters(*TransportParameters)
 	ReceivedTransportParameters(*TransportParameters)
 	RestoredTransportParameters(parameters *TransportParameters) // for 0-RTT
+	// Rejected0RTT is called by the server when it rejects 0-RTT because its transport parameters
+	// don't allow the use of the parameters saved in the session ticket (see Section 7.4.1 of RFC 9000).
+	// parameter is the name of the first transport parameter that was reduced, e.g. "initial_max_data".
+	Rejected0RTT(parameter string)
+	// NegotiatedIdleTimeout is called when the peer's transport parameters are applied.
+	// The effective idle timeout is the minimum of the local and the peer's max_idle_timeout,
+	// where 0 means that the peer didn't set a limit.
+	NegotiatedIdleTimeout(local, remote, effective time.Duration)
 	SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame)
 	ReceivedVersionNegotiationPacket(*Header, []VersionNumber)
 	ReceivedRetry(*Header)
 	ReceivedPacket(hdr *ExtendedHeader, size ByteCount, frames []Frame)
 	BufferedPacket(PacketType)
 	DroppedPacket(PacketType, ByteCount, PacketDropReason)
+	// SuspectedStatelessReset is called when a number of consecutive 1-RTT packets couldn't be decrypted.
+	// This might be caused by a stateless reset sent by the peer using a token that we don't know,
+	// for example when the peer sits behind a load balancer that isn't configured with the same StatelessResetKey.
+	// The token contains the last 16 bytes of the last undecryptable packet.
+	SuspectedStatelessReset(token StatelessResetToken, undecryptablePackets int)
+	// UndecryptablePacketQueueFull is called when a packet that can't be decrypted yet is dropped,
+	// because the queue of packets waiting for their keys is full.
+	// queuedPackets and queuedBytes are the number and the total size of the packets in the queue.
+	// The packet is also reported as dropped using DroppedPacket.
+	UndecryptablePacketQueueFull(packetType PacketType, size ByteCount, queuedPackets int, queuedBytes ByteCount)
+	// DetectedPeerAnomaly is called when the peer behaves in a way that (usually) doesn't close the connection,
+	// but that indicates a buggy or malicious peer.
+	DetectedPeerAnomaly(anomaly PeerAnomaly, details string)
+	// StartedPathValidation is called when a PATH_CHALLENGE frame is sent to validate a new peer address.
+	StartedPathValidation(remote net.Addr, challenge [8]byte)
+	// ReceivedPathChallenge is called when a PATH_CHALLENGE frame is received. It is answered with a PATH_RESPONSE frame.
+	ReceivedPathChallenge(challenge [8]byte)
+	// FinishedPathValidation is called when the validation of a new peer address succeeded, i.e. when a matching
+	// PATH_RESPONSE frame was received, or when it failed because no PATH_RESPONSE frame was received in time.
+	// duration is the time since the PATH_CHALLENGE frame was sent.
+	FinishedPathValidation(remote net.Addr, succeeded bool, duration time.Duration)
 	UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFlight ByteCount, packetsInFlight int)
 	AcknowledgedPacket(EncryptionLevel, PacketNumber)
 	LostPacket(EncryptionLevel, PacketNumber, PacketLossReason)
 	UpdatedCongestionState(CongestionState)
 	UpdatedPTOCount(value uint32)
+	DetectedPersistentCongestion(EncryptionLevel, PersistentCongestion)
 	UpdatedKeyFromTLS(EncryptionLevel, Perspective)
 	UpdatedKey(generation KeyPhase, remote bool)
 	DroppedEncryptionLevel(EncryptionLevel)
+	// ReassembledCryptoData is called when all CRYPTO data of an encryption level was received.
+	// For 1-RTT, it is called when the connection is closed, if any CRYPTO frames were received after completion of the handshake.
+	ReassembledCryptoData(EncryptionLevel, CryptoStreamStats)
+	// LabeledStream is called when a stream is opened with an application-defined label.
+	LabeledStream(id StreamID, label string)
+	// BlockedByFlowControl is called when a stream that has data to send is blocked by flow control.
+	// If connectionLevel is true, it is blocked by connection-level flow control, and not by the stream's limit.
+	BlockedByFlowControl(id StreamID, connectionLevel bool)
 	DroppedKey(generation KeyPhase)
 	SetLossTimer(TimerType, EncryptionLevel, time.Time)
 	LossTimerExpired(TimerType, EncryptionLevel)
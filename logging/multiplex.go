@@ -98,6 +98,18 @@ func (m *connTracerMultiplexer) RestoredTransportParameters(tp *TransportParamet
 	}
 }
 
+func (m *connTracerMultiplexer) Rejected0RTT(parameter string) {
+	for _, t := range m.tracers {
+		t.Rejected0RTT(parameter)
+	}
+}
+
+func (m *connTracerMultiplexer) NegotiatedIdleTimeout(local, remote, effective time.Duration) {
+	for _, t := range m.tracers {
+		t.NegotiatedIdleTimeout(local, remote, effective)
+	}
+}
+
 func (m *connTracerMultiplexer) SentPacket(hdr *ExtendedHeader, size ByteCount, ack *AckFrame, frames []Frame) {
 	for _, t := range m.tracers {
 		t.SentPacket(hdr, size, ack, frames)
@@ -140,6 +152,42 @@ func (m *connTracerMultiplexer) UpdatedCongestionState(state CongestionState) {
 	}
 }
 
+func (m *connTracerMultiplexer) SuspectedStatelessReset(token StatelessResetToken, undecryptablePackets int) {
+	for _, t := range m.tracers {
+		t.SuspectedStatelessReset(token, undecryptablePackets)
+	}
+}
+
+func (m *connTracerMultiplexer) UndecryptablePacketQueueFull(packetType PacketType, size ByteCount, queuedPackets int, queuedBytes ByteCount) {
+	for _, t := range m.tracers {
+		t.UndecryptablePacketQueueFull(packetType, size, queuedPackets, queuedBytes)
+	}
+}
+
+func (m *connTracerMultiplexer) DetectedPeerAnomaly(anomaly PeerAnomaly, details string) {
+	for _, t := range m.tracers {
+		t.DetectedPeerAnomaly(anomaly, details)
+	}
+}
+
+func (m *connTracerMultiplexer) StartedPathValidation(remote net.Addr, challenge [8]byte) {
+	for _, t := range m.tracers {
+		t.StartedPathValidation(remote, challenge)
+	}
+}
+
+func (m *connTracerMultiplexer) ReceivedPathChallenge(challenge [8]byte) {
+	for _, t := range m.tracers {
+		t.ReceivedPathChallenge(challenge)
+	}
+}
+
+func (m *connTracerMultiplexer) FinishedPathValidation(remote net.Addr, succeeded bool, duration time.Duration) {
+	for _, t := range m.tracers {
+		t.FinishedPathValidation(remote, succeeded, duration)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedMetrics(rttStats *RTTStats, cwnd, bytesInFLight ByteCount, packetsInFlight int) {
 	for _, t := range m.tracers {
 		t.UpdatedMetrics(rttStats, cwnd, bytesInFLight, packetsInFlight)
@@ -164,6 +212,12 @@ func (m *connTracerMultiplexer) UpdatedPTOCount(value uint32) {
 	}
 }
 
+func (m *connTracerMultiplexer) DetectedPersistentCongestion(encLevel EncryptionLevel, pc PersistentCongestion) {
+	for _, t := range m.tracers {
+		t.DetectedPersistentCongestion(encLevel, pc)
+	}
+}
+
 func (m *connTracerMultiplexer) UpdatedKeyFromTLS(encLevel EncryptionLevel, perspective Perspective) {
 	for _, t := range m.tracers {
 		t.UpdatedKeyFromTLS(encLevel, perspective)
@@ -182,6 +236,24 @@ func (m *connTracerMultiplexer) DroppedEncryptionLevel(encLevel EncryptionLevel)
 	}
 }
 
+func (m *connTracerMultiplexer) ReassembledCryptoData(encLevel EncryptionLevel, stats CryptoStreamStats) {
+	for _, t := range m.tracers {
+		t.ReassembledCryptoData(encLevel, stats)
+	}
+}
+
+func (m *connTracerMultiplexer) LabeledStream(id StreamID, label string) {
+	for _, t := range m.tracers {
+		t.LabeledStream(id, label)
+	}
+}
+
+func (m *connTracerMultiplexer) BlockedByFlowControl(id StreamID, connectionLevel bool) {
+	for _, t := range m.tracers {
+		t.BlockedByFlowControl(id, connectionLevel)
+	}
+}
+
 func (m *connTracerMultiplexer) DroppedKey(generation KeyPhase) {
 	for _, t := range m.tracers {
 		t.DroppedKey(generation)
@@ -139,6 +139,18 @@ var _ = Describe("Tracing", func() {
 			tracer.RestoredTransportParameters(tp)
 		})
 
+		It("traces the Rejected0RTT event", func() {
+			tr1.EXPECT().Rejected0RTT("initial_max_data")
+			tr2.EXPECT().Rejected0RTT("initial_max_data")
+			tracer.Rejected0RTT("initial_max_data")
+		})
+
+		It("traces the NegotiatedIdleTimeout event", func() {
+			tr1.EXPECT().NegotiatedIdleTimeout(time.Minute, 10*time.Second, 10*time.Second)
+			tr2.EXPECT().NegotiatedIdleTimeout(time.Minute, 10*time.Second, 10*time.Second)
+			tracer.NegotiatedIdleTimeout(time.Minute, 10*time.Second, 10*time.Second)
+		})
+
 		It("traces the SentPacket event", func() {
 			hdr := &ExtendedHeader{Header: Header{DestConnectionID: ConnectionID{1, 2, 3}}}
 			ack := &AckFrame{AckRanges: []AckRange{{Smallest: 1, Largest: 10}}}
@@ -214,6 +226,26 @@ var _ = Describe("Tracing", func() {
 			tracer.UpdatedPTOCount(88)
 		})
 
+		It("traces the SuspectedStatelessReset event", func() {
+			token := StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+			tr1.EXPECT().SuspectedStatelessReset(token, 3)
+			tr2.EXPECT().SuspectedStatelessReset(token, 3)
+			tracer.SuspectedStatelessReset(token, 3)
+		})
+
+		It("traces the UndecryptablePacketQueueFull event", func() {
+			tr1.EXPECT().UndecryptablePacketQueueFull(PacketTypeHandshake, ByteCount(1234), 32, ByteCount(40000))
+			tr2.EXPECT().UndecryptablePacketQueueFull(PacketTypeHandshake, ByteCount(1234), 32, ByteCount(40000))
+			tracer.UndecryptablePacketQueueFull(PacketTypeHandshake, 1234, 32, 40000)
+		})
+
+		It("traces the DetectedPersistentCongestion event", func() {
+			pc := PersistentCongestion{Duration: time.Second, Threshold: 900 * time.Millisecond, FirstLostPacket: 10, LastLostPacket: 20}
+			tr1.EXPECT().DetectedPersistentCongestion(Encryption1RTT, pc)
+			tr2.EXPECT().DetectedPersistentCongestion(Encryption1RTT, pc)
+			tracer.DetectedPersistentCongestion(Encryption1RTT, pc)
+		})
+
 		It("traces the UpdatedKeyFromTLS event", func() {
 			tr1.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
 			tr2.EXPECT().UpdatedKeyFromTLS(EncryptionHandshake, PerspectiveClient)
@@ -232,6 +264,51 @@ var _ = Describe("Tracing", func() {
 			tracer.DroppedEncryptionLevel(EncryptionHandshake)
 		})
 
+		It("traces the ReassembledCryptoData event", func() {
+			stats := CryptoStreamStats{Frames: 10, OutOfOrderFrames: 3, MaxBufferedFragments: 2}
+			tr1.EXPECT().ReassembledCryptoData(EncryptionHandshake, stats)
+			tr2.EXPECT().ReassembledCryptoData(EncryptionHandshake, stats)
+			tracer.ReassembledCryptoData(EncryptionHandshake, stats)
+		})
+
+		It("traces the DetectedPeerAnomaly event", func() {
+			tr1.EXPECT().DetectedPeerAnomaly(PeerAnomalyDuplicatePackets, "foobar")
+			tr2.EXPECT().DetectedPeerAnomaly(PeerAnomalyDuplicatePackets, "foobar")
+			tracer.DetectedPeerAnomaly(PeerAnomalyDuplicatePackets, "foobar")
+		})
+
+		It("traces the StartedPathValidation event", func() {
+			addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+			tr1.EXPECT().StartedPathValidation(addr, [8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+			tr2.EXPECT().StartedPathValidation(addr, [8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+			tracer.StartedPathValidation(addr, [8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		})
+
+		It("traces the ReceivedPathChallenge event", func() {
+			tr1.EXPECT().ReceivedPathChallenge([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+			tr2.EXPECT().ReceivedPathChallenge([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+			tracer.ReceivedPathChallenge([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+		})
+
+		It("traces the FinishedPathValidation event", func() {
+			addr := &net.UDPAddr{IP: net.IPv4(1, 2, 3, 4), Port: 1234}
+			tr1.EXPECT().FinishedPathValidation(addr, true, 25*time.Millisecond)
+			tr2.EXPECT().FinishedPathValidation(addr, true, 25*time.Millisecond)
+			tracer.FinishedPathValidation(addr, true, 25*time.Millisecond)
+		})
+
+		It("traces the LabeledStream event", func() {
+			tr1.EXPECT().LabeledStream(StreamID(4), "request")
+			tr2.EXPECT().LabeledStream(StreamID(4), "request")
+			tracer.LabeledStream(4, "request")
+		})
+
+		It("traces the BlockedByFlowControl event", func() {
+			tr1.EXPECT().BlockedByFlowControl(StreamID(4), true)
+			tr2.EXPECT().BlockedByFlowControl(StreamID(4), true)
+			tracer.BlockedByFlowControl(4, true)
+		})
+
 		It("traces the DroppedKey event", func() {
 			tr1.EXPECT().DroppedKey(KeyPhase(123))
 			tr2.EXPECT().DroppedKey(KeyPhase(123))
@@ -1,5 +1,7 @@
 package logging
 
+import "time"
+
 // PacketType is the packet type of a QUIC packet
 type PacketType uint8
 
@@ -92,3 +94,39 @@ const (
 	// CongestionStateApplicationLimited means that the congestion controller is application limited
 	CongestionStateApplicationLimited
 )
+
+// CryptoStreamStats are statistics about the reassembly of the CRYPTO data received at one encryption level.
+type CryptoStreamStats struct {
+	// Frames is the number of CRYPTO frames received.
+	Frames int
+	// OutOfOrderFrames is the number of CRYPTO frames that were received with a gap in front of them.
+	OutOfOrderFrames int
+	// MaxBufferedFragments is the maximum number of CRYPTO frames that were buffered at the same time.
+	MaxBufferedFragments int
+}
+
+// PeerAnomaly is a behavior of the peer that indicates a buggy or malicious implementation.
+type PeerAnomaly uint8
+
+const (
+	// PeerAnomalyAckForUnsentPacket is used when the peer acknowledges a packet that was never sent,
+	// i.e. a packet number beyond the largest sent packet number, or a skipped packet number.
+	// This is a protocol violation, and the connection is closed right afterwards.
+	PeerAnomalyAckForUnsentPacket PeerAnomaly = iota
+	// PeerAnomalyDuplicatePackets is used when the peer frequently sends duplicate packets.
+	PeerAnomalyDuplicatePackets
+	// PeerAnomalyFlowControlLimitDecreased is used when the peer sends a MAX_DATA or MAX_STREAM_DATA frame
+	// that would decrease the flow control limit. The frame is ignored.
+	PeerAnomalyFlowControlLimitDecreased
+)
+
+// PersistentCongestion describes a period of persistent congestion.
+type PersistentCongestion struct {
+	// Duration is the time between sending the first and the last lost packet.
+	Duration time.Duration
+	// Threshold is the minimum duration that is considered persistent congestion.
+	Threshold time.Duration
+	// FirstLostPacket and LastLostPacket are the packet numbers of the first and the last lost packet.
+	FirstLostPacket PacketNumber
+	LastLostPacket  PacketNumber
+}
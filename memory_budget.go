@@ -0,0 +1,194 @@
+package quic
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A MemoryBudget limits the amount of memory that sessions use for buffering received data.
+// The same MemoryBudget can be used by many sessions (by setting it on the Config).
+// It accounts for the data that was received on streams, but not yet read by the application,
+// the CRYPTO data that is buffered until a TLS message is complete,
+// the packets that are queued because they can't be decrypted yet,
+// and the DATAGRAM frames that were not yet read by the application.
+//
+// When the memory usage exceeds 3/4 of the limit, the budget is under pressure:
+// sessions stop increasing their connection-level flow control window,
+// such that peers can't send more data until the application has read data.
+// When the memory usage exceeds the limit, the session using the most memory is closed.
+type MemoryBudget struct {
+	limit int64
+	used  int64 // accessed atomically, such that sessions don't contend for the mutex on every update
+
+	// The mutex is only acquired when the usage crosses the pressure threshold or exceeds the limit.
+	mutex    sync.Mutex
+	accounts map[*memoryAccount]struct{}
+
+	withheldWindowUpdates uint64
+	closedSessions        uint64
+}
+
+// MemoryBudgetStats are statistics about a MemoryBudget.
+type MemoryBudgetStats struct {
+	// Limit is the limit of the budget, in bytes.
+	Limit int64
+	// Used is the memory currently used by all sessions, in bytes.
+	Used int64
+	// Sessions is the number of sessions using this budget.
+	Sessions int
+	// UnderPressure says if the budget is currently under pressure.
+	UnderPressure bool
+	// WithheldWindowUpdates is the number of flow control window updates that were withheld due to memory pressure.
+	WithheldWindowUpdates uint64
+	// ClosedSessions is the number of sessions that were closed because the limit was exceeded.
+	ClosedSessions uint64
+}
+
+// NewMemoryBudget creates a new MemoryBudget.
+// The limit is the number of bytes that all sessions using the budget are allowed to use.
+func NewMemoryBudget(limit int64) *MemoryBudget {
+	return &MemoryBudget{
+		limit:    limit,
+		accounts: make(map[*memoryAccount]struct{}),
+	}
+}
+
+// Stats returns statistics about the memory budget.
+func (b *MemoryBudget) Stats() MemoryBudgetStats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	used := atomic.LoadInt64(&b.used)
+	return MemoryBudgetStats{
+		Limit:                 b.limit,
+		Used:                  used,
+		Sessions:              len(b.accounts),
+		UnderPressure:         b.isUnderPressure(used),
+		WithheldWindowUpdates: b.withheldWindowUpdates,
+		ClosedSessions:        b.closedSessions,
+	}
+}
+
+func (b *MemoryBudget) isUnderPressure(used int64) bool {
+	return used >= b.limit/4*3
+}
+
+func (b *MemoryBudget) newAccount(onPressureRelieved, shed func()) *memoryAccount {
+	a := &memoryAccount{
+		budget:             b,
+		onPressureRelieved: onPressureRelieved,
+		shed:               shed,
+	}
+	b.mutex.Lock()
+	b.accounts[a] = struct{}{}
+	b.mutex.Unlock()
+	return a
+}
+
+// update adds the change of the memory used by a session to the total.
+// Only if the total exceeds the limit, or if the pressure is relieved, the mutex is acquired.
+func (b *MemoryBudget) update(delta int64) {
+	if delta == 0 {
+		return
+	}
+	used := atomic.AddInt64(&b.used, delta)
+	if used > b.limit {
+		b.shedWorst()
+	}
+	// Only the update that moved the usage below the threshold notifies the accounts.
+	if b.isUnderPressure(used-delta) && !b.isUnderPressure(used) {
+		b.relievePressure()
+	}
+}
+
+// shedWorst closes the session using the most memory
+func (b *MemoryBudget) shedWorst() {
+	b.mutex.Lock()
+	// another session might have released memory in the meantime
+	if atomic.LoadInt64(&b.used) <= b.limit {
+		b.mutex.Unlock()
+		return
+	}
+	var worst *memoryAccount
+	for acc := range b.accounts {
+		if !acc.shedding && (worst == nil || atomic.LoadInt64(&acc.used) > atomic.LoadInt64(&worst.used)) {
+			worst = acc
+		}
+	}
+	if worst != nil {
+		worst.shedding = true
+		b.closedSessions++
+	}
+	b.mutex.Unlock()
+
+	if worst != nil {
+		worst.shed()
+	}
+}
+
+func (b *MemoryBudget) relievePressure() {
+	var relieved []*memoryAccount
+	b.mutex.Lock()
+	for acc := range b.accounts {
+		if acc.withheldWindowUpdate {
+			acc.withheldWindowUpdate = false
+			relieved = append(relieved, acc)
+		}
+	}
+	b.mutex.Unlock()
+
+	for _, acc := range relieved {
+		acc.onPressureRelieved()
+	}
+}
+
+// A memoryAccount tracks the memory used by a single session.
+type memoryAccount struct {
+	used int64 // accessed atomically, first field to ensure 64-bit alignment on 32-bit platforms
+
+	budget *MemoryBudget
+
+	// the following fields are protected by the budget's mutex
+	withheldWindowUpdate bool
+	shedding             bool
+
+	onPressureRelieved func()
+	shed               func()
+}
+
+// Update updates the memory used by the session.
+// It doesn't acquire the budget's mutex, unless the usage crosses the pressure threshold or exceeds the limit.
+func (a *memoryAccount) Update(used int64) {
+	a.budget.update(used - atomic.SwapInt64(&a.used, used))
+}
+
+// WithholdWindowUpdate says if a connection-level flow control window update should be withheld.
+// If it returns true, onPressureRelieved is called once the budget is not under pressure any more.
+func (a *memoryAccount) WithholdWindowUpdate() bool {
+	if !a.budget.isUnderPressure(atomic.LoadInt64(&a.budget.used)) {
+		return false
+	}
+	a.budget.mutex.Lock()
+	defer a.budget.mutex.Unlock()
+
+	// Check again while holding the mutex:
+	// If the pressure was relieved in the meantime, relievePressure might already have run.
+	if !a.budget.isUnderPressure(atomic.LoadInt64(&a.budget.used)) {
+		return false
+	}
+	if !a.withheldWindowUpdate {
+		a.withheldWindowUpdate = true
+		a.budget.withheldWindowUpdates++
+	}
+	return true
+}
+
+// Close releases the memory used by the session.
+func (a *memoryAccount) Close() {
+	a.budget.mutex.Lock()
+	a.withheldWindowUpdate = false
+	delete(a.budget.accounts, a)
+	a.budget.mutex.Unlock()
+
+	a.Update(0)
+}
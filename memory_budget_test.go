@@ -0,0 +1,104 @@
+package quic
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory Budget", func() {
+	var budget *MemoryBudget
+
+	BeforeEach(func() {
+		budget = NewMemoryBudget(1000)
+	})
+
+	It("accounts the memory used by all sessions", func() {
+		a1 := budget.newAccount(func() {}, func() {})
+		a2 := budget.newAccount(func() {}, func() {})
+		a1.Update(100)
+		a2.Update(200)
+		a1.Update(50)
+		stats := budget.Stats()
+		Expect(stats.Limit).To(BeEquivalentTo(1000))
+		Expect(stats.Used).To(BeEquivalentTo(250))
+		Expect(stats.Sessions).To(Equal(2))
+		Expect(stats.UnderPressure).To(BeFalse())
+	})
+
+	It("releases the memory when an account is closed", func() {
+		a1 := budget.newAccount(func() {}, func() {})
+		a2 := budget.newAccount(func() {}, func() {})
+		a1.Update(100)
+		a2.Update(200)
+		a1.Close()
+		Expect(budget.Stats().Used).To(BeEquivalentTo(200))
+		Expect(budget.Stats().Sessions).To(Equal(1))
+	})
+
+	It("withholds window updates under pressure", func() {
+		var relieved int
+		a1 := budget.newAccount(func() { relieved++ }, func() {})
+		a2 := budget.newAccount(func() {}, func() {})
+		a1.Update(100)
+		Expect(a1.WithholdWindowUpdate()).To(BeFalse())
+		a2.Update(700)
+		Expect(budget.Stats().UnderPressure).To(BeTrue())
+		Expect(a1.WithholdWindowUpdate()).To(BeTrue())
+		Expect(a1.WithholdWindowUpdate()).To(BeTrue())
+		Expect(budget.Stats().WithheldWindowUpdates).To(BeEquivalentTo(1))
+		Expect(relieved).To(BeZero())
+		a2.Update(600)
+		Expect(budget.Stats().UnderPressure).To(BeFalse())
+		Expect(relieved).To(Equal(1))
+		// only call the callback once
+		a2.Update(500)
+		Expect(relieved).To(Equal(1))
+	})
+
+	It("notifies accounts when the pressure is relieved by closing another account", func() {
+		var relieved bool
+		a1 := budget.newAccount(func() { relieved = true }, func() {})
+		a2 := budget.newAccount(func() {}, func() {})
+		a2.Update(800)
+		Expect(a1.WithholdWindowUpdate()).To(BeTrue())
+		a2.Close()
+		Expect(relieved).To(BeTrue())
+	})
+
+	It("closes the session using the most memory when the limit is exceeded", func() {
+		var shed1, shed2 int
+		a1 := budget.newAccount(func() {}, func() { shed1++ })
+		a2 := budget.newAccount(func() {}, func() { shed2++ })
+		a1.Update(600)
+		a2.Update(300)
+		Expect(shed1 + shed2).To(BeZero())
+		a2.Update(500)
+		Expect(shed1).To(Equal(1))
+		Expect(shed2).To(BeZero())
+		Expect(budget.Stats().ClosedSessions).To(BeEquivalentTo(1))
+		// don't close the same session again
+		a2.Update(600)
+		Expect(shed1).To(Equal(1))
+		Expect(shed2).To(Equal(1))
+		Expect(budget.Stats().ClosedSessions).To(BeEquivalentTo(2))
+	})
+
+	It("handles concurrent updates", func() {
+		budget = NewMemoryBudget(1 << 40)
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			a := budget.newAccount(func() {}, func() {})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := int64(1); j <= 1000; j++ {
+					a.Update(j)
+				}
+			}()
+		}
+		wg.Wait()
+		Expect(budget.Stats().Used).To(BeEquivalentTo(10 * 1000))
+	})
+})
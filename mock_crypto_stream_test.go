@@ -10,6 +10,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
+	logging "github.com/lucas-clemente/quic-go/logging"
 )
 
 // MockCryptoStream is a mock of CryptoStream interface.
@@ -35,6 +36,20 @@ func (m *MockCryptoStream) EXPECT() *MockCryptoStreamMockRecorder {
 	return m.recorder
 }
 
+// BufferedBytes mocks base method.
+func (m *MockCryptoStream) BufferedBytes() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedBytes")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// BufferedBytes indicates an expected call of BufferedBytes.
+func (mr *MockCryptoStreamMockRecorder) BufferedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedBytes", reflect.TypeOf((*MockCryptoStream)(nil).BufferedBytes))
+}
+
 // Finish mocks base method.
 func (m *MockCryptoStream) Finish() error {
 	m.ctrl.T.Helper()
@@ -105,6 +120,20 @@ func (mr *MockCryptoStreamMockRecorder) PopCryptoFrame(arg0 interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PopCryptoFrame", reflect.TypeOf((*MockCryptoStream)(nil).PopCryptoFrame), arg0)
 }
 
+// Stats mocks base method.
+func (m *MockCryptoStream) Stats() logging.CryptoStreamStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(logging.CryptoStreamStats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockCryptoStreamMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockCryptoStream)(nil).Stats))
+}
+
 // Write mocks base method.
 func (m *MockCryptoStream) Write(p []byte) (int, error) {
 	m.ctrl.T.Helper()
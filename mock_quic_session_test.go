@@ -80,6 +80,20 @@ func (mr *MockQuicSessionMockRecorder) CloseWithError(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithError", reflect.TypeOf((*MockQuicSession)(nil).CloseWithError), arg0, arg1)
 }
 
+// CloseWithPayload mocks base method.
+func (m *MockQuicSession) CloseWithPayload(code ApplicationErrorCode, desc string, payload []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseWithPayload", code, desc, payload)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CloseWithPayload indicates an expected call of CloseWithPayload.
+func (mr *MockQuicSessionMockRecorder) CloseWithPayload(code, desc, payload interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseWithPayload", reflect.TypeOf((*MockQuicSession)(nil).CloseWithPayload), code, desc, payload)
+}
+
 // ConnectionState mocks base method.
 func (m *MockQuicSession) ConnectionState() ConnectionState {
 	m.ctrl.T.Helper()
@@ -94,6 +108,20 @@ func (mr *MockQuicSessionMockRecorder) ConnectionState() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionState", reflect.TypeOf((*MockQuicSession)(nil).ConnectionState))
 }
 
+// ConnectionStats mocks base method.
+func (m *MockQuicSession) ConnectionStats() ConnectionStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectionStats")
+	ret0, _ := ret[0].(ConnectionStats)
+	return ret0
+}
+
+// ConnectionStats indicates an expected call of ConnectionStats.
+func (mr *MockQuicSessionMockRecorder) ConnectionStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectionStats", reflect.TypeOf((*MockQuicSession)(nil).ConnectionStats))
+}
+
 // Context mocks base method.
 func (m *MockQuicSession) Context() context.Context {
 	m.ctrl.T.Helper()
@@ -108,6 +136,20 @@ func (mr *MockQuicSessionMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockQuicSession)(nil).Context))
 }
 
+// FlowControlState mocks base method.
+func (m *MockQuicSession) FlowControlState() FlowControlState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlState")
+	ret0, _ := ret[0].(FlowControlState)
+	return ret0
+}
+
+// FlowControlState indicates an expected call of FlowControlState.
+func (mr *MockQuicSessionMockRecorder) FlowControlState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlState", reflect.TypeOf((*MockQuicSession)(nil).FlowControlState))
+}
+
 // GetVersion mocks base method.
 func (m *MockQuicSession) GetVersion() protocol.VersionNumber {
 	m.ctrl.T.Helper()
@@ -136,6 +178,21 @@ func (mr *MockQuicSessionMockRecorder) HandshakeComplete() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandshakeComplete", reflect.TypeOf((*MockQuicSession)(nil).HandshakeComplete))
 }
 
+// InFlightPackets mocks base method.
+func (m *MockQuicSession) InFlightPackets(ctx context.Context) ([]InFlightPacket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InFlightPackets", ctx)
+	ret0, _ := ret[0].([]InFlightPacket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InFlightPackets indicates an expected call of InFlightPackets.
+func (mr *MockQuicSessionMockRecorder) InFlightPackets(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InFlightPackets", reflect.TypeOf((*MockQuicSession)(nil).InFlightPackets), ctx)
+}
+
 // LocalAddr mocks base method.
 func (m *MockQuicSession) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -150,6 +207,20 @@ func (mr *MockQuicSessionMockRecorder) LocalAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LocalAddr", reflect.TypeOf((*MockQuicSession)(nil).LocalAddr))
 }
 
+// NewStreamGroup mocks base method.
+func (m *MockQuicSession) NewStreamGroup() *StreamGroup {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewStreamGroup")
+	ret0, _ := ret[0].(*StreamGroup)
+	return ret0
+}
+
+// NewStreamGroup indicates an expected call of NewStreamGroup.
+func (mr *MockQuicSessionMockRecorder) NewStreamGroup() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewStreamGroup", reflect.TypeOf((*MockQuicSession)(nil).NewStreamGroup))
+}
+
 // NextSession mocks base method.
 func (m *MockQuicSession) NextSession() Session {
 	m.ctrl.T.Helper()
@@ -194,6 +265,21 @@ func (mr *MockQuicSessionMockRecorder) OpenStreamSync(arg0 interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamSync), arg0)
 }
 
+// OpenStreamSyncWithOptions mocks base method.
+func (m *MockQuicSession) OpenStreamSyncWithOptions(arg0 context.Context, arg1 StreamOptions) (Stream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenStreamSyncWithOptions", arg0, arg1)
+	ret0, _ := ret[0].(Stream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenStreamSyncWithOptions indicates an expected call of OpenStreamSyncWithOptions.
+func (mr *MockQuicSessionMockRecorder) OpenStreamSyncWithOptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenStreamSyncWithOptions", reflect.TypeOf((*MockQuicSession)(nil).OpenStreamSyncWithOptions), arg0, arg1)
+}
+
 // OpenUniStream mocks base method.
 func (m *MockQuicSession) OpenUniStream() (SendStream, error) {
 	m.ctrl.T.Helper()
@@ -224,6 +310,35 @@ func (mr *MockQuicSessionMockRecorder) OpenUniStreamSync(arg0 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSync", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSync), arg0)
 }
 
+// OpenUniStreamSyncWithOptions mocks base method.
+func (m *MockQuicSession) OpenUniStreamSyncWithOptions(arg0 context.Context, arg1 StreamOptions) (SendStream, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenUniStreamSyncWithOptions", arg0, arg1)
+	ret0, _ := ret[0].(SendStream)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenUniStreamSyncWithOptions indicates an expected call of OpenUniStreamSyncWithOptions.
+func (mr *MockQuicSessionMockRecorder) OpenUniStreamSyncWithOptions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenUniStreamSyncWithOptions", reflect.TypeOf((*MockQuicSession)(nil).OpenUniStreamSyncWithOptions), arg0, arg1)
+}
+
+// PacingState mocks base method.
+func (m *MockQuicSession) PacingState() PacingState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PacingState")
+	ret0, _ := ret[0].(PacingState)
+	return ret0
+}
+
+// PacingState indicates an expected call of PacingState.
+func (mr *MockQuicSessionMockRecorder) PacingState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PacingState", reflect.TypeOf((*MockQuicSession)(nil).PacingState))
+}
+
 // ReceiveMessage mocks base method.
 func (m *MockQuicSession) ReceiveMessage() ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -253,6 +368,20 @@ func (mr *MockQuicSessionMockRecorder) RemoteAddr() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoteAddr", reflect.TypeOf((*MockQuicSession)(nil).RemoteAddr))
 }
 
+// SendBDPFrame mocks base method.
+func (m *MockQuicSession) SendBDPFrame(arg0 BDPInfo) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendBDPFrame", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendBDPFrame indicates an expected call of SendBDPFrame.
+func (mr *MockQuicSessionMockRecorder) SendBDPFrame(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendBDPFrame", reflect.TypeOf((*MockQuicSession)(nil).SendBDPFrame), arg0)
+}
+
 // SendMessage mocks base method.
 func (m *MockQuicSession) SendMessage(arg0 []byte) error {
 	m.ctrl.T.Helper()
@@ -267,6 +396,48 @@ func (mr *MockQuicSessionMockRecorder) SendMessage(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMessage", reflect.TypeOf((*MockQuicSession)(nil).SendMessage), arg0)
 }
 
+// SetMaxIncomingStreams mocks base method.
+func (m *MockQuicSession) SetMaxIncomingStreams(arg0 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMaxIncomingStreams", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMaxIncomingStreams indicates an expected call of SetMaxIncomingStreams.
+func (mr *MockQuicSessionMockRecorder) SetMaxIncomingStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingStreams", reflect.TypeOf((*MockQuicSession)(nil).SetMaxIncomingStreams), arg0)
+}
+
+// SetMaxIncomingUniStreams mocks base method.
+func (m *MockQuicSession) SetMaxIncomingUniStreams(arg0 uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMaxIncomingUniStreams", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMaxIncomingUniStreams indicates an expected call of SetMaxIncomingUniStreams.
+func (mr *MockQuicSessionMockRecorder) SetMaxIncomingUniStreams(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMaxIncomingUniStreams", reflect.TypeOf((*MockQuicSession)(nil).SetMaxIncomingUniStreams), arg0)
+}
+
+// StreamErrors mocks base method.
+func (m *MockQuicSession) StreamErrors() *StreamErrorRegistry {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamErrors")
+	ret0, _ := ret[0].(*StreamErrorRegistry)
+	return ret0
+}
+
+// StreamErrors indicates an expected call of StreamErrors.
+func (mr *MockQuicSessionMockRecorder) StreamErrors() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamErrors", reflect.TypeOf((*MockQuicSession)(nil).StreamErrors))
+}
+
 // destroy mocks base method.
 func (m *MockQuicSession) destroy(arg0 error) {
 	m.ctrl.T.Helper()
@@ -5,10 +5,12 @@
 package quic
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -48,6 +50,112 @@ func (mr *MockReceiveStreamIMockRecorder) CancelRead(arg0 interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRead", reflect.TypeOf((*MockReceiveStreamI)(nil).CancelRead), arg0)
 }
 
+// CancelReadAfter mocks base method.
+func (m *MockReceiveStreamI) CancelReadAfter(offset uint64, errorCode StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelReadAfter", offset, errorCode)
+}
+
+// CancelReadAfter indicates an expected call of CancelReadAfter.
+func (mr *MockReceiveStreamIMockRecorder) CancelReadAfter(offset, errorCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReadAfter", reflect.TypeOf((*MockReceiveStreamI)(nil).CancelReadAfter), offset, errorCode)
+}
+
+// CancelReadAfterBuffered mocks base method.
+func (m *MockReceiveStreamI) CancelReadAfterBuffered(arg0 StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelReadAfterBuffered", arg0)
+}
+
+// CancelReadAfterBuffered indicates an expected call of CancelReadAfterBuffered.
+func (mr *MockReceiveStreamIMockRecorder) CancelReadAfterBuffered(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReadAfterBuffered", reflect.TypeOf((*MockReceiveStreamI)(nil).CancelReadAfterBuffered), arg0)
+}
+
+// Discard mocks base method.
+func (m *MockReceiveStreamI) Discard(n int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discard", n)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Discard indicates an expected call of Discard.
+func (mr *MockReceiveStreamIMockRecorder) Discard(n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discard", reflect.TypeOf((*MockReceiveStreamI)(nil).Discard), n)
+}
+
+// FlowControlState mocks base method.
+func (m *MockReceiveStreamI) FlowControlState() FlowControlState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlState")
+	ret0, _ := ret[0].(FlowControlState)
+	return ret0
+}
+
+// FlowControlState indicates an expected call of FlowControlState.
+func (mr *MockReceiveStreamIMockRecorder) FlowControlState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlState", reflect.TypeOf((*MockReceiveStreamI)(nil).FlowControlState))
+}
+
+// GrantReceiveCredit mocks base method.
+func (m *MockReceiveStreamI) GrantReceiveCredit(n uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GrantReceiveCredit", n)
+}
+
+// GrantReceiveCredit indicates an expected call of GrantReceiveCredit.
+func (mr *MockReceiveStreamIMockRecorder) GrantReceiveCredit(n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantReceiveCredit", reflect.TypeOf((*MockReceiveStreamI)(nil).GrantReceiveCredit), n)
+}
+
+// OnReadable mocks base method.
+func (m *MockReceiveStreamI) OnReadable(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnReadable", arg0)
+}
+
+// OnReadable indicates an expected call of OnReadable.
+func (mr *MockReceiveStreamIMockRecorder) OnReadable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnReadable", reflect.TypeOf((*MockReceiveStreamI)(nil).OnReadable), arg0)
+}
+
+// Peek mocks base method.
+func (m *MockReceiveStreamI) Peek(n int) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Peek", n)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Peek indicates an expected call of Peek.
+func (mr *MockReceiveStreamIMockRecorder) Peek(n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Peek", reflect.TypeOf((*MockReceiveStreamI)(nil).Peek), n)
+}
+
+// PeerClosed mocks base method.
+func (m *MockReceiveStreamI) PeerClosed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PeerClosed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// PeerClosed indicates an expected call of PeerClosed.
+func (mr *MockReceiveStreamIMockRecorder) PeerClosed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeerClosed", reflect.TypeOf((*MockReceiveStreamI)(nil).PeerClosed))
+}
+
 // Read mocks base method.
 func (m *MockReceiveStreamI) Read(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -63,6 +171,51 @@ func (mr *MockReceiveStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockReceiveStreamI)(nil).Read), p)
 }
 
+// ReadBuffers mocks base method.
+func (m *MockReceiveStreamI) ReadBuffers() ([][]byte, func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffers")
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(func())
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadBuffers indicates an expected call of ReadBuffers.
+func (mr *MockReceiveStreamIMockRecorder) ReadBuffers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffers", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadBuffers))
+}
+
+// ReadContext mocks base method.
+func (m *MockReceiveStreamI) ReadContext(ctx context.Context, p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadContext", ctx, p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadContext indicates an expected call of ReadContext.
+func (mr *MockReceiveStreamIMockRecorder) ReadContext(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadContext", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadContext), ctx, p)
+}
+
+// ReadableBytes mocks base method.
+func (m *MockReceiveStreamI) ReadableBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadableBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ReadableBytes indicates an expected call of ReadableBytes.
+func (mr *MockReceiveStreamIMockRecorder) ReadableBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadableBytes", reflect.TypeOf((*MockReceiveStreamI)(nil).ReadableBytes))
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockReceiveStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -77,6 +230,18 @@ func (mr *MockReceiveStreamIMockRecorder) SetReadDeadline(t interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReadDeadline), t)
 }
 
+// SetReceiveWindow mocks base method.
+func (m *MockReceiveStreamI) SetReceiveWindow(size uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReceiveWindow", size)
+}
+
+// SetReceiveWindow indicates an expected call of SetReceiveWindow.
+func (mr *MockReceiveStreamIMockRecorder) SetReceiveWindow(size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindow", reflect.TypeOf((*MockReceiveStreamI)(nil).SetReceiveWindow), size)
+}
+
 // StreamID mocks base method.
 func (m *MockReceiveStreamI) StreamID() StreamID {
 	m.ctrl.T.Helper()
@@ -91,6 +256,21 @@ func (mr *MockReceiveStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockReceiveStreamI)(nil).StreamID))
 }
 
+// TryRead mocks base method.
+func (m *MockReceiveStreamI) TryRead(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockReceiveStreamIMockRecorder) TryRead(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockReceiveStreamI)(nil).TryRead), p)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockReceiveStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -144,3 +324,15 @@ func (mr *MockReceiveStreamIMockRecorder) handleStreamFrame(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "handleStreamFrame", reflect.TypeOf((*MockReceiveStreamI)(nil).handleStreamFrame), arg0)
 }
+
+// setFlowControlGroup mocks base method.
+func (m *MockReceiveStreamI) setFlowControlGroup(arg0 *flowcontrol.GroupFlowController) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setFlowControlGroup", arg0)
+}
+
+// setFlowControlGroup indicates an expected call of setFlowControlGroup.
+func (mr *MockReceiveStreamIMockRecorder) setFlowControlGroup(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setFlowControlGroup", reflect.TypeOf((*MockReceiveStreamI)(nil).setFlowControlGroup), arg0)
+}
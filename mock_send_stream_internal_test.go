@@ -38,6 +38,20 @@ func (m *MockSendStreamI) EXPECT() *MockSendStreamIMockRecorder {
 	return m.recorder
 }
 
+// BufferedAmount mocks base method.
+func (m *MockSendStreamI) BufferedAmount() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedAmount")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedAmount indicates an expected call of BufferedAmount.
+func (mr *MockSendStreamIMockRecorder) BufferedAmount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedAmount", reflect.TypeOf((*MockSendStreamI)(nil).BufferedAmount))
+}
+
 // CancelWrite mocks base method.
 func (m *MockSendStreamI) CancelWrite(arg0 StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -50,6 +64,20 @@ func (mr *MockSendStreamIMockRecorder) CancelWrite(arg0 interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockSendStreamI)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method.
+func (m *MockSendStreamI) CancelWriteAt(errorCode StreamErrorCode, reliableSize uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelWriteAt", errorCode, reliableSize)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt.
+func (mr *MockSendStreamIMockRecorder) CancelWriteAt(errorCode, reliableSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockSendStreamI)(nil).CancelWriteAt), errorCode, reliableSize)
+}
+
 // Close mocks base method.
 func (m *MockSendStreamI) Close() error {
 	m.ctrl.T.Helper()
@@ -78,6 +106,152 @@ func (mr *MockSendStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockSendStreamI)(nil).Context))
 }
 
+// Delivered mocks base method.
+func (m *MockSendStreamI) Delivered(offset uint64) <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delivered", offset)
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Delivered indicates an expected call of Delivered.
+func (mr *MockSendStreamIMockRecorder) Delivered(offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delivered", reflect.TypeOf((*MockSendStreamI)(nil).Delivered), offset)
+}
+
+// DeliveredOffset mocks base method.
+func (m *MockSendStreamI) DeliveredOffset() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliveredOffset")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// DeliveredOffset indicates an expected call of DeliveredOffset.
+func (mr *MockSendStreamIMockRecorder) DeliveredOffset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliveredOffset", reflect.TypeOf((*MockSendStreamI)(nil).DeliveredOffset))
+}
+
+// Done mocks base method.
+func (m *MockSendStreamI) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done.
+func (mr *MockSendStreamIMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockSendStreamI)(nil).Done))
+}
+
+// Err mocks base method.
+func (m *MockSendStreamI) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockSendStreamIMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockSendStreamI)(nil).Err))
+}
+
+// FlowControlState mocks base method.
+func (m *MockSendStreamI) FlowControlState() FlowControlState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlState")
+	ret0, _ := ret[0].(FlowControlState)
+	return ret0
+}
+
+// FlowControlState indicates an expected call of FlowControlState.
+func (mr *MockSendStreamIMockRecorder) FlowControlState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlState", reflect.TypeOf((*MockSendStreamI)(nil).FlowControlState))
+}
+
+// Flush mocks base method.
+func (m *MockSendStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockSendStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockSendStreamI)(nil).Flush))
+}
+
+// OnWritable mocks base method.
+func (m *MockSendStreamI) OnWritable(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnWritable", arg0)
+}
+
+// OnWritable indicates an expected call of OnWritable.
+func (mr *MockSendStreamIMockRecorder) OnWritable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnWritable", reflect.TypeOf((*MockSendStreamI)(nil).OnWritable), arg0)
+}
+
+// Priority mocks base method.
+func (m *MockSendStreamI) Priority() StreamPriority {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Priority")
+	ret0, _ := ret[0].(StreamPriority)
+	return ret0
+}
+
+// Priority indicates an expected call of Priority.
+func (mr *MockSendStreamIMockRecorder) Priority() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Priority", reflect.TypeOf((*MockSendStreamI)(nil).Priority))
+}
+
+// SetDiscardOnWriteTimeout mocks base method.
+func (m *MockSendStreamI) SetDiscardOnWriteTimeout(discard bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDiscardOnWriteTimeout", discard)
+}
+
+// SetDiscardOnWriteTimeout indicates an expected call of SetDiscardOnWriteTimeout.
+func (mr *MockSendStreamIMockRecorder) SetDiscardOnWriteTimeout(discard interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDiscardOnWriteTimeout", reflect.TypeOf((*MockSendStreamI)(nil).SetDiscardOnWriteTimeout), discard)
+}
+
+// SetPriority mocks base method.
+func (m *MockSendStreamI) SetPriority(arg0 StreamPriority) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority.
+func (mr *MockSendStreamIMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockSendStreamI)(nil).SetPriority), arg0)
+}
+
+// SetTrafficClass mocks base method.
+func (m *MockSendStreamI) SetTrafficClass(arg0 TrafficClass) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrafficClass", arg0)
+}
+
+// SetTrafficClass indicates an expected call of SetTrafficClass.
+func (mr *MockSendStreamIMockRecorder) SetTrafficClass(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrafficClass", reflect.TypeOf((*MockSendStreamI)(nil).SetTrafficClass), arg0)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockSendStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -106,6 +280,35 @@ func (mr *MockSendStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockSendStreamI)(nil).StreamID))
 }
 
+// TrafficClass mocks base method.
+func (m *MockSendStreamI) TrafficClass() TrafficClass {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrafficClass")
+	ret0, _ := ret[0].(TrafficClass)
+	return ret0
+}
+
+// TrafficClass indicates an expected call of TrafficClass.
+func (mr *MockSendStreamIMockRecorder) TrafficClass() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrafficClass", reflect.TypeOf((*MockSendStreamI)(nil).TrafficClass))
+}
+
+// TryWrite mocks base method.
+func (m *MockSendStreamI) TryWrite(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockSendStreamIMockRecorder) TryWrite(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockSendStreamI)(nil).TryWrite), p)
+}
+
 // Write mocks base method.
 func (m *MockSendStreamI) Write(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -121,6 +324,51 @@ func (mr *MockSendStreamIMockRecorder) Write(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockSendStreamI)(nil).Write), p)
 }
 
+// WriteBuffers mocks base method.
+func (m *MockSendStreamI) WriteBuffers(bufs [][]byte) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", bufs)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockSendStreamIMockRecorder) WriteBuffers(bufs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockSendStreamI)(nil).WriteBuffers), bufs)
+}
+
+// WriteContext mocks base method.
+func (m *MockSendStreamI) WriteContext(ctx context.Context, p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteContext", ctx, p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteContext indicates an expected call of WriteContext.
+func (mr *MockSendStreamIMockRecorder) WriteContext(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteContext", reflect.TypeOf((*MockSendStreamI)(nil).WriteContext), ctx, p)
+}
+
+// WriteMessage mocks base method.
+func (m *MockSendStreamI) WriteMessage(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteMessage", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteMessage indicates an expected call of WriteMessage.
+func (mr *MockSendStreamIMockRecorder) WriteMessage(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteMessage", reflect.TypeOf((*MockSendStreamI)(nil).WriteMessage), p)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockSendStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -159,6 +407,20 @@ func (mr *MockSendStreamIMockRecorder) hasData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasData", reflect.TypeOf((*MockSendStreamI)(nil).hasData))
 }
 
+// pendingData mocks base method.
+func (m *MockSendStreamI) pendingData() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "pendingData")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// pendingData indicates an expected call of pendingData.
+func (mr *MockSendStreamIMockRecorder) pendingData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pendingData", reflect.TypeOf((*MockSendStreamI)(nil).pendingData))
+}
+
 // popStreamFrame mocks base method.
 func (m *MockSendStreamI) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool) {
 	m.ctrl.T.Helper()
@@ -8,6 +8,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 )
 
 // MockSender is a mock of Sender interface.
@@ -59,6 +60,20 @@ func (mr *MockSenderMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockSender)(nil).Close))
 }
 
+// QueuedBytes mocks base method.
+func (m *MockSender) QueuedBytes() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QueuedBytes")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// QueuedBytes indicates an expected call of QueuedBytes.
+func (mr *MockSenderMockRecorder) QueuedBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueuedBytes", reflect.TypeOf((*MockSender)(nil).QueuedBytes))
+}
+
 // Run mocks base method.
 func (m *MockSender) Run() error {
 	m.ctrl.T.Helper()
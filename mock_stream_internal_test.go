@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	ackhandler "github.com/lucas-clemente/quic-go/internal/ackhandler"
+	flowcontrol "github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	protocol "github.com/lucas-clemente/quic-go/internal/protocol"
 	wire "github.com/lucas-clemente/quic-go/internal/wire"
 )
@@ -38,6 +39,20 @@ func (m *MockStreamI) EXPECT() *MockStreamIMockRecorder {
 	return m.recorder
 }
 
+// BufferedAmount mocks base method.
+func (m *MockStreamI) BufferedAmount() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BufferedAmount")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// BufferedAmount indicates an expected call of BufferedAmount.
+func (mr *MockStreamIMockRecorder) BufferedAmount() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BufferedAmount", reflect.TypeOf((*MockStreamI)(nil).BufferedAmount))
+}
+
 // CancelRead mocks base method.
 func (m *MockStreamI) CancelRead(arg0 StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -50,6 +65,30 @@ func (mr *MockStreamIMockRecorder) CancelRead(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelRead", reflect.TypeOf((*MockStreamI)(nil).CancelRead), arg0)
 }
 
+// CancelReadAfter mocks base method.
+func (m *MockStreamI) CancelReadAfter(offset uint64, errorCode StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelReadAfter", offset, errorCode)
+}
+
+// CancelReadAfter indicates an expected call of CancelReadAfter.
+func (mr *MockStreamIMockRecorder) CancelReadAfter(offset, errorCode interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReadAfter", reflect.TypeOf((*MockStreamI)(nil).CancelReadAfter), offset, errorCode)
+}
+
+// CancelReadAfterBuffered mocks base method.
+func (m *MockStreamI) CancelReadAfterBuffered(arg0 StreamErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CancelReadAfterBuffered", arg0)
+}
+
+// CancelReadAfterBuffered indicates an expected call of CancelReadAfterBuffered.
+func (mr *MockStreamIMockRecorder) CancelReadAfterBuffered(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelReadAfterBuffered", reflect.TypeOf((*MockStreamI)(nil).CancelReadAfterBuffered), arg0)
+}
+
 // CancelWrite mocks base method.
 func (m *MockStreamI) CancelWrite(arg0 StreamErrorCode) {
 	m.ctrl.T.Helper()
@@ -62,6 +101,20 @@ func (mr *MockStreamIMockRecorder) CancelWrite(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWrite", reflect.TypeOf((*MockStreamI)(nil).CancelWrite), arg0)
 }
 
+// CancelWriteAt mocks base method.
+func (m *MockStreamI) CancelWriteAt(errorCode StreamErrorCode, reliableSize uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelWriteAt", errorCode, reliableSize)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelWriteAt indicates an expected call of CancelWriteAt.
+func (mr *MockStreamIMockRecorder) CancelWriteAt(errorCode, reliableSize interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelWriteAt", reflect.TypeOf((*MockStreamI)(nil).CancelWriteAt), errorCode, reliableSize)
+}
+
 // Close mocks base method.
 func (m *MockStreamI) Close() error {
 	m.ctrl.T.Helper()
@@ -90,6 +143,184 @@ func (mr *MockStreamIMockRecorder) Context() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Context", reflect.TypeOf((*MockStreamI)(nil).Context))
 }
 
+// Delivered mocks base method.
+func (m *MockStreamI) Delivered(offset uint64) <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delivered", offset)
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Delivered indicates an expected call of Delivered.
+func (mr *MockStreamIMockRecorder) Delivered(offset interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delivered", reflect.TypeOf((*MockStreamI)(nil).Delivered), offset)
+}
+
+// DeliveredOffset mocks base method.
+func (m *MockStreamI) DeliveredOffset() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliveredOffset")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// DeliveredOffset indicates an expected call of DeliveredOffset.
+func (mr *MockStreamIMockRecorder) DeliveredOffset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliveredOffset", reflect.TypeOf((*MockStreamI)(nil).DeliveredOffset))
+}
+
+// Discard mocks base method.
+func (m *MockStreamI) Discard(n int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discard", n)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Discard indicates an expected call of Discard.
+func (mr *MockStreamIMockRecorder) Discard(n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discard", reflect.TypeOf((*MockStreamI)(nil).Discard), n)
+}
+
+// Done mocks base method.
+func (m *MockStreamI) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done.
+func (mr *MockStreamIMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockStreamI)(nil).Done))
+}
+
+// Err mocks base method.
+func (m *MockStreamI) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockStreamIMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockStreamI)(nil).Err))
+}
+
+// FlowControlState mocks base method.
+func (m *MockStreamI) FlowControlState() FlowControlState {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FlowControlState")
+	ret0, _ := ret[0].(FlowControlState)
+	return ret0
+}
+
+// FlowControlState indicates an expected call of FlowControlState.
+func (mr *MockStreamIMockRecorder) FlowControlState() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FlowControlState", reflect.TypeOf((*MockStreamI)(nil).FlowControlState))
+}
+
+// Flush mocks base method.
+func (m *MockStreamI) Flush() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Flush")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Flush indicates an expected call of Flush.
+func (mr *MockStreamIMockRecorder) Flush() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Flush", reflect.TypeOf((*MockStreamI)(nil).Flush))
+}
+
+// GrantReceiveCredit mocks base method.
+func (m *MockStreamI) GrantReceiveCredit(n uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GrantReceiveCredit", n)
+}
+
+// GrantReceiveCredit indicates an expected call of GrantReceiveCredit.
+func (mr *MockStreamIMockRecorder) GrantReceiveCredit(n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GrantReceiveCredit", reflect.TypeOf((*MockStreamI)(nil).GrantReceiveCredit), n)
+}
+
+// OnReadable mocks base method.
+func (m *MockStreamI) OnReadable(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnReadable", arg0)
+}
+
+// OnReadable indicates an expected call of OnReadable.
+func (mr *MockStreamIMockRecorder) OnReadable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnReadable", reflect.TypeOf((*MockStreamI)(nil).OnReadable), arg0)
+}
+
+// OnWritable mocks base method.
+func (m *MockStreamI) OnWritable(arg0 func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnWritable", arg0)
+}
+
+// OnWritable indicates an expected call of OnWritable.
+func (mr *MockStreamIMockRecorder) OnWritable(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnWritable", reflect.TypeOf((*MockStreamI)(nil).OnWritable), arg0)
+}
+
+// Peek mocks base method.
+func (m *MockStreamI) Peek(n int) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Peek", n)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Peek indicates an expected call of Peek.
+func (mr *MockStreamIMockRecorder) Peek(n interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Peek", reflect.TypeOf((*MockStreamI)(nil).Peek), n)
+}
+
+// PeerClosed mocks base method.
+func (m *MockStreamI) PeerClosed() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PeerClosed")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// PeerClosed indicates an expected call of PeerClosed.
+func (mr *MockStreamIMockRecorder) PeerClosed() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeerClosed", reflect.TypeOf((*MockStreamI)(nil).PeerClosed))
+}
+
+// Priority mocks base method.
+func (m *MockStreamI) Priority() StreamPriority {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Priority")
+	ret0, _ := ret[0].(StreamPriority)
+	return ret0
+}
+
+// Priority indicates an expected call of Priority.
+func (mr *MockStreamIMockRecorder) Priority() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Priority", reflect.TypeOf((*MockStreamI)(nil).Priority))
+}
+
 // Read mocks base method.
 func (m *MockStreamI) Read(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -105,6 +336,51 @@ func (mr *MockStreamIMockRecorder) Read(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockStreamI)(nil).Read), p)
 }
 
+// ReadBuffers mocks base method.
+func (m *MockStreamI) ReadBuffers() ([][]byte, func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadBuffers")
+	ret0, _ := ret[0].([][]byte)
+	ret1, _ := ret[1].(func())
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ReadBuffers indicates an expected call of ReadBuffers.
+func (mr *MockStreamIMockRecorder) ReadBuffers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadBuffers", reflect.TypeOf((*MockStreamI)(nil).ReadBuffers))
+}
+
+// ReadContext mocks base method.
+func (m *MockStreamI) ReadContext(ctx context.Context, p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadContext", ctx, p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReadContext indicates an expected call of ReadContext.
+func (mr *MockStreamIMockRecorder) ReadContext(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadContext", reflect.TypeOf((*MockStreamI)(nil).ReadContext), ctx, p)
+}
+
+// ReadableBytes mocks base method.
+func (m *MockStreamI) ReadableBytes() uint64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReadableBytes")
+	ret0, _ := ret[0].(uint64)
+	return ret0
+}
+
+// ReadableBytes indicates an expected call of ReadableBytes.
+func (mr *MockStreamIMockRecorder) ReadableBytes() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReadableBytes", reflect.TypeOf((*MockStreamI)(nil).ReadableBytes))
+}
+
 // SetDeadline mocks base method.
 func (m *MockStreamI) SetDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -119,6 +395,30 @@ func (mr *MockStreamIMockRecorder) SetDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDeadline", reflect.TypeOf((*MockStreamI)(nil).SetDeadline), t)
 }
 
+// SetDiscardOnWriteTimeout mocks base method.
+func (m *MockStreamI) SetDiscardOnWriteTimeout(discard bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetDiscardOnWriteTimeout", discard)
+}
+
+// SetDiscardOnWriteTimeout indicates an expected call of SetDiscardOnWriteTimeout.
+func (mr *MockStreamIMockRecorder) SetDiscardOnWriteTimeout(discard interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDiscardOnWriteTimeout", reflect.TypeOf((*MockStreamI)(nil).SetDiscardOnWriteTimeout), discard)
+}
+
+// SetPriority mocks base method.
+func (m *MockStreamI) SetPriority(arg0 StreamPriority) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetPriority", arg0)
+}
+
+// SetPriority indicates an expected call of SetPriority.
+func (mr *MockStreamIMockRecorder) SetPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPriority", reflect.TypeOf((*MockStreamI)(nil).SetPriority), arg0)
+}
+
 // SetReadDeadline mocks base method.
 func (m *MockStreamI) SetReadDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -133,6 +433,30 @@ func (mr *MockStreamIMockRecorder) SetReadDeadline(t interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReadDeadline", reflect.TypeOf((*MockStreamI)(nil).SetReadDeadline), t)
 }
 
+// SetReceiveWindow mocks base method.
+func (m *MockStreamI) SetReceiveWindow(size uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetReceiveWindow", size)
+}
+
+// SetReceiveWindow indicates an expected call of SetReceiveWindow.
+func (mr *MockStreamIMockRecorder) SetReceiveWindow(size interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetReceiveWindow", reflect.TypeOf((*MockStreamI)(nil).SetReceiveWindow), size)
+}
+
+// SetTrafficClass mocks base method.
+func (m *MockStreamI) SetTrafficClass(arg0 TrafficClass) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTrafficClass", arg0)
+}
+
+// SetTrafficClass indicates an expected call of SetTrafficClass.
+func (mr *MockStreamIMockRecorder) SetTrafficClass(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTrafficClass", reflect.TypeOf((*MockStreamI)(nil).SetTrafficClass), arg0)
+}
+
 // SetWriteDeadline mocks base method.
 func (m *MockStreamI) SetWriteDeadline(t time.Time) error {
 	m.ctrl.T.Helper()
@@ -161,6 +485,50 @@ func (mr *MockStreamIMockRecorder) StreamID() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamID", reflect.TypeOf((*MockStreamI)(nil).StreamID))
 }
 
+// TrafficClass mocks base method.
+func (m *MockStreamI) TrafficClass() TrafficClass {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrafficClass")
+	ret0, _ := ret[0].(TrafficClass)
+	return ret0
+}
+
+// TrafficClass indicates an expected call of TrafficClass.
+func (mr *MockStreamIMockRecorder) TrafficClass() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrafficClass", reflect.TypeOf((*MockStreamI)(nil).TrafficClass))
+}
+
+// TryRead mocks base method.
+func (m *MockStreamI) TryRead(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryRead", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryRead indicates an expected call of TryRead.
+func (mr *MockStreamIMockRecorder) TryRead(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryRead", reflect.TypeOf((*MockStreamI)(nil).TryRead), p)
+}
+
+// TryWrite mocks base method.
+func (m *MockStreamI) TryWrite(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TryWrite", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TryWrite indicates an expected call of TryWrite.
+func (mr *MockStreamIMockRecorder) TryWrite(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TryWrite", reflect.TypeOf((*MockStreamI)(nil).TryWrite), p)
+}
+
 // Write mocks base method.
 func (m *MockStreamI) Write(p []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -176,6 +544,51 @@ func (mr *MockStreamIMockRecorder) Write(p interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Write", reflect.TypeOf((*MockStreamI)(nil).Write), p)
 }
 
+// WriteBuffers mocks base method.
+func (m *MockStreamI) WriteBuffers(bufs [][]byte) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteBuffers", bufs)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteBuffers indicates an expected call of WriteBuffers.
+func (mr *MockStreamIMockRecorder) WriteBuffers(bufs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteBuffers", reflect.TypeOf((*MockStreamI)(nil).WriteBuffers), bufs)
+}
+
+// WriteContext mocks base method.
+func (m *MockStreamI) WriteContext(ctx context.Context, p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteContext", ctx, p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteContext indicates an expected call of WriteContext.
+func (mr *MockStreamIMockRecorder) WriteContext(ctx, p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteContext", reflect.TypeOf((*MockStreamI)(nil).WriteContext), ctx, p)
+}
+
+// WriteMessage mocks base method.
+func (m *MockStreamI) WriteMessage(p []byte) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteMessage", p)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WriteMessage indicates an expected call of WriteMessage.
+func (mr *MockStreamIMockRecorder) WriteMessage(p interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteMessage", reflect.TypeOf((*MockStreamI)(nil).WriteMessage), p)
+}
+
 // closeForShutdown mocks base method.
 func (m *MockStreamI) closeForShutdown(arg0 error) {
 	m.ctrl.T.Helper()
@@ -256,6 +669,20 @@ func (mr *MockStreamIMockRecorder) hasData() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "hasData", reflect.TypeOf((*MockStreamI)(nil).hasData))
 }
 
+// pendingData mocks base method.
+func (m *MockStreamI) pendingData() protocol.ByteCount {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "pendingData")
+	ret0, _ := ret[0].(protocol.ByteCount)
+	return ret0
+}
+
+// pendingData indicates an expected call of pendingData.
+func (mr *MockStreamIMockRecorder) pendingData() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "pendingData", reflect.TypeOf((*MockStreamI)(nil).pendingData))
+}
+
 // popStreamFrame mocks base method.
 func (m *MockStreamI) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool) {
 	m.ctrl.T.Helper()
@@ -271,6 +698,18 @@ func (mr *MockStreamIMockRecorder) popStreamFrame(maxBytes interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popStreamFrame", reflect.TypeOf((*MockStreamI)(nil).popStreamFrame), maxBytes)
 }
 
+// setFlowControlGroup mocks base method.
+func (m *MockStreamI) setFlowControlGroup(arg0 *flowcontrol.GroupFlowController) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "setFlowControlGroup", arg0)
+}
+
+// setFlowControlGroup indicates an expected call of setFlowControlGroup.
+func (mr *MockStreamIMockRecorder) setFlowControlGroup(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "setFlowControlGroup", reflect.TypeOf((*MockStreamI)(nil).setFlowControlGroup), arg0)
+}
+
 // updateSendWindow mocks base method.
 func (m *MockStreamI) updateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -47,6 +47,18 @@ func (mr *MockStreamSenderMockRecorder) onHasStreamData(arg0 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onHasStreamData", reflect.TypeOf((*MockStreamSender)(nil).onHasStreamData), arg0)
 }
 
+// onStreamBlocked mocks base method.
+func (m *MockStreamSender) onStreamBlocked(id protocol.StreamID, connectionLevel bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onStreamBlocked", id, connectionLevel)
+}
+
+// onStreamBlocked indicates an expected call of onStreamBlocked.
+func (mr *MockStreamSenderMockRecorder) onStreamBlocked(id, connectionLevel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamBlocked", reflect.TypeOf((*MockStreamSender)(nil).onStreamBlocked), id, connectionLevel)
+}
+
 // onStreamCompleted mocks base method.
 func (m *MockStreamSender) onStreamCompleted(arg0 protocol.StreamID) {
 	m.ctrl.T.Helper()
@@ -59,6 +71,42 @@ func (mr *MockStreamSenderMockRecorder) onStreamCompleted(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamCompleted", reflect.TypeOf((*MockStreamSender)(nil).onStreamCompleted), arg0)
 }
 
+// onStreamFlushed mocks base method.
+func (m *MockStreamSender) onStreamFlushed(arg0 protocol.StreamID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onStreamFlushed", arg0)
+}
+
+// onStreamFlushed indicates an expected call of onStreamFlushed.
+func (mr *MockStreamSenderMockRecorder) onStreamFlushed(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamFlushed", reflect.TypeOf((*MockStreamSender)(nil).onStreamFlushed), arg0)
+}
+
+// onStreamPriorityChanged mocks base method.
+func (m *MockStreamSender) onStreamPriorityChanged(arg0 protocol.StreamID, arg1 StreamPriority) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onStreamPriorityChanged", arg0, arg1)
+}
+
+// onStreamPriorityChanged indicates an expected call of onStreamPriorityChanged.
+func (mr *MockStreamSenderMockRecorder) onStreamPriorityChanged(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamPriorityChanged", reflect.TypeOf((*MockStreamSender)(nil).onStreamPriorityChanged), arg0, arg1)
+}
+
+// onStreamWritable mocks base method.
+func (m *MockStreamSender) onStreamWritable(notify func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onStreamWritable", notify)
+}
+
+// onStreamWritable indicates an expected call of onStreamWritable.
+func (mr *MockStreamSenderMockRecorder) onStreamWritable(notify interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onStreamWritable", reflect.TypeOf((*MockStreamSender)(nil).onStreamWritable), notify)
+}
+
 // queueControlFrame mocks base method.
 func (m *MockStreamSender) queueControlFrame(arg0 wire.Frame) {
 	m.ctrl.T.Helper()
@@ -70,3 +118,32 @@ func (mr *MockStreamSenderMockRecorder) queueControlFrame(arg0 interface{}) *gom
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "queueControlFrame", reflect.TypeOf((*MockStreamSender)(nil).queueControlFrame), arg0)
 }
+
+// supportsResetStreamAt mocks base method.
+func (m *MockStreamSender) supportsResetStreamAt() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "supportsResetStreamAt")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// supportsResetStreamAt indicates an expected call of supportsResetStreamAt.
+func (mr *MockStreamSenderMockRecorder) supportsResetStreamAt() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "supportsResetStreamAt", reflect.TypeOf((*MockStreamSender)(nil).supportsResetStreamAt))
+}
+
+// trafficClassPriority mocks base method.
+func (m *MockStreamSender) trafficClassPriority(arg0 TrafficClass) (StreamPriority, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "trafficClassPriority", arg0)
+	ret0, _ := ret[0].(StreamPriority)
+	ret1, _ := ret[1].(bool)
+	return ret0, ret1
+}
+
+// trafficClassPriority indicates an expected call of trafficClassPriority.
+func (mr *MockStreamSenderMockRecorder) trafficClassPriority(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "trafficClassPriority", reflect.TypeOf((*MockStreamSender)(nil).trafficClassPriority), arg0)
+}
@@ -0,0 +1,30 @@
+package quic
+
+import (
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+)
+
+// PacingState is a snapshot of the state of the send path.
+// Applications can use it to decide not to generate data that would only be queued,
+// e.g. to drop a video frame instead of sending it late.
+type PacingState struct {
+	// Budget is the number of bytes that the pacer allows to be sent right away.
+	// It is calculated using the bandwidth estimate at the time the last packet was sent.
+	Budget uint64
+	// NextSendTime is the time when the pacer allows the next full-size packet to be sent.
+	// It is the zero value of time.Time if a packet can be sent immediately.
+	NextSendTime time.Time
+	// QueuedBytes is the number of bytes in packets that were already packed,
+	// but not yet written to the underlying connection.
+	QueuedBytes uint64
+}
+
+func newPacingState(s congestion.PacerState, queued uint64) PacingState {
+	return PacingState{
+		Budget:       uint64(s.Budget),
+		NextSendTime: s.NextSendTime,
+		QueuedBytes:  queued,
+	}
+}
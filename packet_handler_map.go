@@ -26,9 +26,17 @@ type zeroRTTQueue struct {
 var _ packetHandler = &zeroRTTQueue{}
 
 func (h *zeroRTTQueue) handlePacket(p *receivedPacket) {
-	if len(h.queue) < protocol.Max0RTTQueueLen {
-		h.queue = append(h.queue, p)
+	h.enqueue(p)
+}
+
+// enqueue enqueues a packet, if the queue is not full yet.
+// It returns false if the packet was not enqueued.
+func (h *zeroRTTQueue) enqueue(p *receivedPacket) bool {
+	if len(h.queue) >= protocol.Max0RTTQueueLen {
+		return false
 	}
+	h.queue = append(h.queue, p)
+	return true
 }
 func (h *zeroRTTQueue) shutdown()                            {}
 func (h *zeroRTTQueue) destroy(error)                        {}
@@ -120,10 +128,12 @@ func newPacketHandlerMap(
 	tracer logging.Tracer,
 	logger utils.Logger,
 ) (packetHandlerManager, error) {
-	if err := setReceiveBuffer(c, logger); err != nil {
-		receiveBufferWarningOnce.Do(func() {
-			log.Printf("%s. See https://github.com/lucas-clemente/quic-go/wiki/UDP-Receive-Buffer-Size for details.", err)
-		})
+	if !isGenericPacketConn(c) {
+		if err := setReceiveBuffer(c, logger); err != nil {
+			receiveBufferWarningOnce.Do(func() {
+				log.Printf("%s. See https://github.com/lucas-clemente/quic-go/wiki/UDP-Receive-Buffer-Size for details.", err)
+			})
+		}
 	}
 	conn, err := wrapConn(c)
 	if err != nil {
@@ -360,7 +370,9 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 	if entry, ok := h.handlers[string(connID)]; ok {
 		if entry.is0RTTQueue { // only enqueue 0-RTT packets in the 0-RTT queue
 			if wire.Is0RTTPacket(p.data) {
-				entry.packetHandler.handlePacket(p)
+				if !entry.packetHandler.(*zeroRTTQueue).enqueue(p) {
+					h.drop0RTTPacket(p, connID)
+				}
 				return
 			}
 		} else { // existing session
@@ -378,6 +390,7 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 	}
 	if wire.Is0RTTPacket(p.data) {
 		if h.numZeroRTTEntries >= protocol.Max0RTTQueues {
+			h.drop0RTTPacket(p, connID)
 			return
 		}
 		h.numZeroRTTEntries++
@@ -409,6 +422,16 @@ func (h *packetHandlerMap) handlePacket(p *receivedPacket) {
 	h.server.handlePacket(p)
 }
 
+func (h *packetHandlerMap) drop0RTTPacket(p *receivedPacket, connID protocol.ConnectionID) {
+	if h.logger.Debug() {
+		h.logger.Debugf("Dropping 0-RTT packet for %s (%d bytes). 0-RTT queue full.", connID, p.Size())
+	}
+	if h.tracer != nil {
+		h.tracer.DroppedPacket(p.remoteAddr, logging.PacketType0RTT, p.Size(), logging.PacketDropDOSPrevention)
+	}
+	p.buffer.Release()
+}
+
 func (h *packetHandlerMap) maybeHandleStatelessReset(data []byte) bool {
 	// stateless resets are always short header packets
 	if data[0]&0x80 != 0 {
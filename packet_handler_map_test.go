@@ -322,13 +322,37 @@ var _ = Describe("Packet Handler Map", func() {
 				}
 				// We're already storing the maximum number of queues. This packet will be dropped.
 				connID := protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8, 9}
-				handler.handlePacket(&receivedPacket{data: getPacketWithPacketType(connID, protocol.PacketType0RTT, 1)})
+				p := &receivedPacket{
+					data:   getPacketWithPacketType(connID, protocol.PacketType0RTT, 1),
+					buffer: getPacketBuffer(),
+				}
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketType0RTT, p.Size(), logging.PacketDropDOSPrevention)
+				handler.handlePacket(p)
 				// Don't EXPECT any handlePacket() calls.
 				sess := NewMockPacketHandler(mockCtrl)
 				handler.AddWithConnID(connID, protocol.ConnectionID{1, 2, 3, 4}, func() packetHandler { return sess })
 				time.Sleep(20 * time.Millisecond)
 			})
 
+			It("limits the number of packets in a 0-RTT queue", func() {
+				connID := protocol.ConnectionID{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+				for i := 0; i < protocol.Max0RTTQueueLen; i++ {
+					handler.handlePacket(&receivedPacket{data: getPacketWithPacketType(connID, protocol.PacketType0RTT, 1)})
+				}
+				// The queue is already full. This packet will be dropped.
+				p := &receivedPacket{
+					data:   getPacketWithPacketType(connID, protocol.PacketType0RTT, 2),
+					buffer: getPacketBuffer(),
+				}
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketType0RTT, p.Size(), logging.PacketDropDOSPrevention)
+				handler.handlePacket(p)
+				sess := NewMockPacketHandler(mockCtrl)
+				sess.EXPECT().handlePacket(gomock.Any()).Do(func(packet *receivedPacket) {
+					Expect(packet).ToNot(BeIdenticalTo(p))
+				}).Times(protocol.Max0RTTQueueLen)
+				handler.AddWithConnID(connID, protocol.ConnectionID{1, 2, 3, 4}, func() packetHandler { return sess })
+			})
+
 			It("deletes queues if no session is created for this connection ID", func() {
 				queueDuration := scaleDuration(10 * time.Millisecond)
 				handler.zeroRTTQueueDuration = queueDuration
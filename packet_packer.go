@@ -24,7 +24,9 @@ type packer interface {
 	PackApplicationClose(*qerr.ApplicationError) (*coalescedPacket, error)
 
 	SetMaxPacketSize(protocol.ByteCount)
+	MaxPacketSize() protocol.ByteCount
 	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error)
+	PackPathProbePacket(challenge ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error)
 
 	HandleTransportParameters(*wire.TransportParameters)
 	SetToken([]byte)
@@ -111,8 +113,23 @@ func (p *packetContents) ToAckHandlerPacket(now time.Time, q *retransmissionQueu
 	}
 }
 
+// A datagramSizeHinter is a net.Addr of a transport that knows the maximum datagram size, see NewGenericPacketConn.
+type datagramSizeHinter interface {
+	MaxDatagramSize() int
+}
+
 func getMaxPacketSize(addr net.Addr) protocol.ByteCount {
 	maxSize := protocol.ByteCount(protocol.MinInitialPacketSize)
+	if a, ok := addr.(datagramSizeHinter); ok {
+		size := protocol.ByteCount(a.MaxDatagramSize())
+		if size > protocol.MaxPacketBufferSize {
+			size = protocol.MaxPacketBufferSize
+		}
+		if size > maxSize {
+			maxSize = size
+		}
+		return maxSize
+	}
 	// If this is not a UDP address, we don't know anything about the MTU.
 	// Use the minimum size of an Initial packet as the max packet size.
 	if udpAddr, ok := addr.(*net.UDPAddr); ok {
@@ -209,14 +226,14 @@ func (p *packetPacker) PackConnectionClose(e *qerr.TransportError) (*coalescedPa
 	var reason string
 	// don't send details of crypto errors
 	if !e.ErrorCode.IsCryptoError() {
-		reason = e.ErrorMessage
+		reason = qerr.EncodeReasonPhrase(e.ErrorMessage, nil)
 	}
 	return p.packConnectionClose(false, uint64(e.ErrorCode), e.FrameType, reason)
 }
 
 // PackApplicationClose packs a packet that closes the connection with an application error.
 func (p *packetPacker) PackApplicationClose(e *qerr.ApplicationError) (*coalescedPacket, error) {
-	return p.packConnectionClose(true, uint64(e.ErrorCode), 0, e.ErrorMessage)
+	return p.packConnectionClose(true, uint64(e.ErrorCode), 0, qerr.EncodeReasonPhrase(e.ErrorMessage, e.Payload))
 }
 
 func (p *packetPacker) packConnectionClose(
@@ -722,6 +739,33 @@ func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.B
 	}, nil
 }
 
+// PackPathProbePacket packs a packet containing a PATH_CHALLENGE frame.
+// The packet is padded to size, see section 8.2.1 of RFC 9000.
+func (p *packetPacker) PackPathProbePacket(challenge ackhandler.Frame, size protocol.ByteCount) (*packedPacket, error) {
+	payload := &payload{
+		frames: []ackhandler.Frame{challenge},
+		length: challenge.Length(p.version),
+	}
+	sealer, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return nil, err
+	}
+	hdr := p.getShortHeader(sealer.KeyPhase())
+	var padding protocol.ByteCount
+	if l := p.packetLength(hdr, payload) + protocol.ByteCount(sealer.Overhead()); l < size {
+		padding = size - l
+	}
+	buffer := getPacketBuffer()
+	contents, err := p.appendPacket(buffer, hdr, payload, padding, protocol.Encryption1RTT, sealer, false)
+	if err != nil {
+		return nil, err
+	}
+	return &packedPacket{
+		buffer:         buffer,
+		packetContents: contents,
+	}, nil
+}
+
 func (p *packetPacker) getSealerAndHeader(encLevel protocol.EncryptionLevel) (sealer, *wire.ExtendedHeader, error) {
 	switch encLevel {
 	case protocol.EncryptionInitial:
@@ -885,6 +929,11 @@ func (p *packetPacker) SetMaxPacketSize(s protocol.ByteCount) {
 	p.maxPacketSize = s
 }
 
+// MaxPacketSize returns the maximum size of the packets sent.
+func (p *packetPacker) MaxPacketSize() protocol.ByteCount {
+	return p.maxPacketSize
+}
+
 // If the peer sets a max_packet_size that's smaller than the size we're currently using,
 // we need to reduce the size of packets we send.
 func (p *packetPacker) HandleTransportParameters(params *wire.TransportParameters) {
@@ -23,6 +23,13 @@ import (
 	. "github.com/onsi/gomega"
 )
 
+type datagramSizeAddr struct {
+	net.UnixAddr
+	size int
+}
+
+func (a *datagramSizeAddr) MaxDatagramSize() int { return a.size }
+
 var _ = Describe("Packet packer", func() {
 	const maxPacketSize protocol.ByteCount = 1357
 	const version = protocol.VersionTLS
@@ -127,6 +134,12 @@ var _ = Describe("Packet packer", func() {
 			addr := &net.UDPAddr{IP: ip, Port: 1337}
 			Expect(getMaxPacketSize(addr)).To(BeEquivalentTo(protocol.InitialPacketSizeIPv6))
 		})
+
+		It("uses the maximum datagram size of the remote address", func() {
+			Expect(getMaxPacketSize(&datagramSizeAddr{size: 1300})).To(BeEquivalentTo(1300))
+			Expect(getMaxPacketSize(&datagramSizeAddr{size: 1 << 16})).To(Equal(protocol.MaxPacketBufferSize))
+			Expect(getMaxPacketSize(&datagramSizeAddr{size: 1000})).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+		})
 	})
 
 	Context("generating a packet header", func() {
@@ -631,7 +644,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, false, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -668,7 +681,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(firstPayloadByte).To(Equal(byte(0)))
 				// ... followed by the STREAM frame
-				frameParser := wire.NewFrameParser(true, packer.version)
+				frameParser := wire.NewFrameParser(true, false, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.StreamFrame{}))
@@ -1221,7 +1234,7 @@ var _ = Describe("Packet packer", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(secondPayloadByte).To(Equal(byte(0)))
 				// ... followed by the PING
-				frameParser := wire.NewFrameParser(false, packer.version)
+				frameParser := wire.NewFrameParser(false, false, false, packer.version)
 				frame, err := frameParser.ParseNext(r, protocol.Encryption1RTT)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
@@ -1494,6 +1507,31 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.buffer.Data).To(HaveLen(int(probePacketSize)))
 				Expect(p.packetContents.isMTUProbePacket).To(BeTrue())
 			})
+
+			It("packs a padded path probe packet", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				challenge := ackhandler.Frame{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}
+				p, err := packer.PackPathProbePacket(challenge, protocol.MinInitialPacketSize)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.length).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+				Expect(p.header.IsLongHeader).To(BeFalse())
+				Expect(p.frames).To(Equal([]ackhandler.Frame{challenge}))
+				Expect(p.buffer.Data).To(HaveLen(protocol.MinInitialPacketSize))
+				Expect(p.packetContents.isMTUProbePacket).To(BeFalse())
+			})
+
+			It("doesn't pad path probe packets beyond the size", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				challenge := ackhandler.Frame{Frame: &wire.PathChallengeFrame{}}
+				p, err := packer.PackPathProbePacket(challenge, 10)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.length).To(BeNumerically(">", 10))
+				Expect(p.frames).To(Equal([]ackhandler.Frame{challenge}))
+			})
 		})
 	})
 })
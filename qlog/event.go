@@ -255,6 +255,24 @@ func (e eventPacketDropped) MarshalJSONObject(enc *gojay.Encoder) {
 	enc.StringKey("trigger", e.Trigger.String())
 }
 
+type eventUndecryptablePacketQueueFull struct {
+	PacketType    logging.PacketType
+	PacketSize    protocol.ByteCount
+	QueuedPackets int
+	QueuedBytes   protocol.ByteCount
+}
+
+func (e eventUndecryptablePacketQueueFull) Category() category { return categoryTransport }
+func (e eventUndecryptablePacketQueueFull) Name() string       { return "undecryptable_packet_queue_full" }
+func (e eventUndecryptablePacketQueueFull) IsNil() bool        { return false }
+
+func (e eventUndecryptablePacketQueueFull) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.ObjectKey("header", packetHeaderWithType{PacketType: e.PacketType})
+	enc.ObjectKey("raw", rawInfo{Length: e.PacketSize})
+	enc.IntKey("queued_packets", e.QueuedPackets)
+	enc.Int64Key("queued_bytes", int64(e.QueuedBytes))
+}
+
 type metrics struct {
 	MinRTT      time.Duration
 	SmoothedRTT time.Duration
@@ -368,6 +386,172 @@ func (e eventKeyRetired) MarshalJSONObject(enc *gojay.Encoder) {
 	}
 }
 
+type eventCryptoDataReassembled struct {
+	EncryptionLevel protocol.EncryptionLevel
+	Stats           logging.CryptoStreamStats
+}
+
+func (e eventCryptoDataReassembled) Category() category { return categorySecurity }
+func (e eventCryptoDataReassembled) Name() string       { return "crypto_data_reassembled" }
+func (e eventCryptoDataReassembled) IsNil() bool        { return false }
+
+func (e eventCryptoDataReassembled) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("packet_number_space", encLevelToPacketNumberSpace(e.EncryptionLevel))
+	enc.IntKey("frames", e.Stats.Frames)
+	enc.IntKey("out_of_order_frames", e.Stats.OutOfOrderFrames)
+	enc.IntKey("max_buffered_fragments", e.Stats.MaxBufferedFragments)
+}
+
+type eventStatelessResetSuspected struct {
+	Token                protocol.StatelessResetToken
+	UndecryptablePackets int
+}
+
+func (e eventStatelessResetSuspected) Category() category { return categoryConnectivity }
+func (e eventStatelessResetSuspected) Name() string       { return "stateless_reset_suspected" }
+func (e eventStatelessResetSuspected) IsNil() bool        { return false }
+
+func (e eventStatelessResetSuspected) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("stateless_reset_token", fmt.Sprintf("%x", e.Token))
+	enc.IntKey("undecryptable_packets", e.UndecryptablePackets)
+}
+
+type eventPeerAnomaly struct {
+	Anomaly logging.PeerAnomaly
+	Details string
+}
+
+func (e eventPeerAnomaly) Category() category { return categoryTransport }
+func (e eventPeerAnomaly) Name() string       { return "peer_anomaly" }
+func (e eventPeerAnomaly) IsNil() bool        { return false }
+
+func (e eventPeerAnomaly) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("trigger", peerAnomaly(e.Anomaly).String())
+	enc.StringKey("details", e.Details)
+}
+
+type eventPathValidationStarted struct {
+	Remote    net.Addr
+	Challenge [8]byte
+}
+
+func (e eventPathValidationStarted) Category() category { return categoryConnectivity }
+func (e eventPathValidationStarted) Name() string       { return "path_validation_started" }
+func (e eventPathValidationStarted) IsNil() bool        { return false }
+
+func (e eventPathValidationStarted) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("remote", e.Remote.String())
+	enc.StringKey("challenge", fmt.Sprintf("%x", e.Challenge[:]))
+}
+
+type eventPathChallengeReceived struct {
+	Challenge [8]byte
+}
+
+func (e eventPathChallengeReceived) Category() category { return categoryConnectivity }
+func (e eventPathChallengeReceived) Name() string       { return "path_challenge_received" }
+func (e eventPathChallengeReceived) IsNil() bool        { return false }
+
+func (e eventPathChallengeReceived) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("challenge", fmt.Sprintf("%x", e.Challenge[:]))
+}
+
+type eventPathValidationFinished struct {
+	Remote    net.Addr
+	Succeeded bool
+	Duration  time.Duration
+}
+
+func (e eventPathValidationFinished) Category() category { return categoryConnectivity }
+func (e eventPathValidationFinished) Name() string       { return "path_validation_finished" }
+func (e eventPathValidationFinished) IsNil() bool        { return false }
+
+func (e eventPathValidationFinished) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("remote", e.Remote.String())
+	if e.Succeeded {
+		enc.StringKey("result", "succeeded")
+	} else {
+		enc.StringKey("result", "failed")
+	}
+	enc.FloatKey("duration", milliseconds(e.Duration))
+}
+
+type eventPersistentCongestion struct {
+	EncryptionLevel      protocol.EncryptionLevel
+	PersistentCongestion logging.PersistentCongestion
+}
+
+func (e eventPersistentCongestion) Category() category { return categoryRecovery }
+func (e eventPersistentCongestion) Name() string       { return "persistent_congestion" }
+func (e eventPersistentCongestion) IsNil() bool        { return false }
+
+func (e eventPersistentCongestion) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("packet_number_space", encLevelToPacketNumberSpace(e.EncryptionLevel))
+	enc.FloatKey("duration", milliseconds(e.PersistentCongestion.Duration))
+	enc.FloatKey("threshold", milliseconds(e.PersistentCongestion.Threshold))
+	enc.Int64Key("first_lost_packet_number", int64(e.PersistentCongestion.FirstLostPacket))
+	enc.Int64Key("last_lost_packet_number", int64(e.PersistentCongestion.LastLostPacket))
+}
+
+type eventStreamLabeled struct {
+	StreamID protocol.StreamID
+	Label    string
+}
+
+func (e eventStreamLabeled) Category() category { return categoryTransport }
+func (e eventStreamLabeled) Name() string       { return "stream_labeled" }
+func (e eventStreamLabeled) IsNil() bool        { return false }
+
+func (e eventStreamLabeled) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("stream_id", int64(e.StreamID))
+	enc.StringKey("label", e.Label)
+}
+
+type eventStreamBlocked struct {
+	StreamID        protocol.StreamID
+	ConnectionLevel bool
+}
+
+func (e eventStreamBlocked) Category() category { return categoryTransport }
+func (e eventStreamBlocked) Name() string       { return "stream_flow_control_blocked" }
+func (e eventStreamBlocked) IsNil() bool        { return false }
+
+func (e eventStreamBlocked) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.Int64Key("stream_id", int64(e.StreamID))
+	if e.ConnectionLevel {
+		enc.StringKey("limit", "connection")
+	} else {
+		enc.StringKey("limit", "stream")
+	}
+}
+
+type eventZeroRTTRejected struct {
+	Parameter string
+}
+
+func (e eventZeroRTTRejected) Category() category { return categoryTransport }
+func (e eventZeroRTTRejected) Name() string       { return "0rtt_rejected" }
+func (e eventZeroRTTRejected) IsNil() bool        { return false }
+
+func (e eventZeroRTTRejected) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("trigger", "transport_parameters_changed")
+	enc.StringKey("parameter", e.Parameter)
+}
+
+type eventIdleTimeoutNegotiated struct {
+	Local, Remote, Effective time.Duration
+}
+
+func (e eventIdleTimeoutNegotiated) Category() category { return categoryTransport }
+func (e eventIdleTimeoutNegotiated) Name() string       { return "idle_timeout_negotiated" }
+func (e eventIdleTimeoutNegotiated) IsNil() bool        { return false }
+
+func (e eventIdleTimeoutNegotiated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.FloatKey("local", milliseconds(e.Local))
+	enc.FloatKey("remote", milliseconds(e.Remote))
+	enc.FloatKey("effective", milliseconds(e.Effective))
+}
+
 type eventTransportParameters struct {
 	Restore bool
 	Owner   owner
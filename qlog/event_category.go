@@ -0,0 +1,40 @@
+package qlog
+
+// An EventCategory is a set of qlog events.
+// Categories can be combined using a bitwise OR.
+type EventCategory uint32
+
+const (
+	// EventCategoryConnectivity are connectivity events, e.g. a suspected stateless reset.
+	EventCategoryConnectivity EventCategory = 1 << iota
+	// EventCategoryTransport are transport events that are not related to a single packet,
+	// e.g. the start and the end of a connection, and the transport parameters.
+	EventCategoryTransport
+	// EventCategoryPackets are the packet-level events, i.e. packets that are sent, received, buffered and dropped.
+	// They make up the largest part of a qlog.
+	EventCategoryPackets
+	// EventCategorySecurity are security events, e.g. key updates.
+	EventCategorySecurity
+	// EventCategoryRecovery are loss recovery and congestion control events, e.g. metrics updates and lost packets.
+	EventCategoryRecovery
+
+	// EventCategoryAll contains all events.
+	EventCategoryAll = EventCategoryConnectivity | EventCategoryTransport | EventCategoryPackets | EventCategorySecurity | EventCategoryRecovery
+)
+
+func eventCategoryOf(details eventDetails) EventCategory {
+	switch details.(type) {
+	case *eventPacketSent, *eventPacketReceived, *eventRetryReceived, *eventVersionNegotiationReceived, *eventPacketBuffered, *eventPacketDropped:
+		return EventCategoryPackets
+	}
+	switch details.Category() {
+	case categoryConnectivity:
+		return EventCategoryConnectivity
+	case categorySecurity:
+		return EventCategorySecurity
+	case categoryRecovery:
+		return EventCategoryRecovery
+	default:
+		return EventCategoryTransport
+	}
+}
@@ -59,6 +59,8 @@ func (f frame) MarshalJSONObject(enc *gojay.Encoder) {
 		marshalHandshakeDoneFrame(enc, frame)
 	case *logging.DatagramFrame:
 		marshalDatagramFrame(enc, frame)
+	case *logging.BDPFrame:
+		marshalBDPFrame(enc, frame)
 	default:
 		panic("unknown frame type")
 	}
@@ -112,10 +114,17 @@ func marshalAckFrame(enc *gojay.Encoder, f *logging.AckFrame) {
 }
 
 func marshalResetStreamFrame(enc *gojay.Encoder, f *logging.ResetStreamFrame) {
-	enc.StringKey("frame_type", "reset_stream")
+	if f.ReliableSize > 0 {
+		enc.StringKey("frame_type", "reset_stream_at")
+	} else {
+		enc.StringKey("frame_type", "reset_stream")
+	}
 	enc.Int64Key("stream_id", int64(f.StreamID))
 	enc.Int64Key("error_code", int64(f.ErrorCode))
 	enc.Int64Key("final_size", int64(f.FinalSize))
+	if f.ReliableSize > 0 {
+		enc.Int64Key("reliable_size", int64(f.ReliableSize))
+	}
 }
 
 func marshalStopSendingFrame(enc *gojay.Encoder, f *logging.StopSendingFrame) {
@@ -225,3 +234,13 @@ func marshalDatagramFrame(enc *gojay.Encoder, f *logging.DatagramFrame) {
 	enc.StringKey("frame_type", "datagram")
 	enc.Int64Key("length", int64(f.Length))
 }
+
+func marshalBDPFrame(enc *gojay.Encoder, f *logging.BDPFrame) {
+	enc.StringKey("frame_type", "bdp")
+	enc.FloatKey("lifetime", milliseconds(f.Lifetime))
+	enc.Uint64Key("saved_capacity", f.SavedCapacity)
+	enc.FloatKey("saved_rtt", milliseconds(f.SavedRTT))
+	if len(f.EndpointToken) > 0 {
+		enc.StringKey("endpoint_token", fmt.Sprintf("%x", f.EndpointToken))
+	}
+}
@@ -114,6 +114,24 @@ var _ = Describe("Frames", func() {
 		)
 	})
 
+	It("marshals RESET_STREAM_AT frames", func() {
+		check(
+			&logging.ResetStreamFrame{
+				StreamID:     987,
+				FinalSize:    1234,
+				ErrorCode:    42,
+				ReliableSize: 100,
+			},
+			map[string]interface{}{
+				"frame_type":    "reset_stream_at",
+				"stream_id":     987,
+				"error_code":    42,
+				"final_size":    1234,
+				"reliable_size": 100,
+			},
+		)
+	})
+
 	It("marshals STOP_SENDING frames", func() {
 		check(
 			&logging.StopSendingFrame{
@@ -374,4 +392,22 @@ var _ = Describe("Frames", func() {
 			},
 		)
 	})
+
+	It("marshals BDP_FRAMEs", func() {
+		check(
+			&logging.BDPFrame{
+				Lifetime:      time.Hour,
+				SavedCapacity: 1337,
+				SavedRTT:      42 * time.Millisecond,
+				EndpointToken: []byte{0xde, 0xad, 0xbe, 0xef},
+			},
+			map[string]interface{}{
+				"frame_type":     "bdp",
+				"lifetime":       3600000,
+				"saved_capacity": 1337,
+				"saved_rtt":      42,
+				"endpoint_token": "deadbeef",
+			},
+		)
+	})
 })
@@ -9,6 +9,7 @@ import (
 	"net"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -71,9 +72,22 @@ func (t *tracer) SentPacket(net.Addr, *logging.Header, protocol.ByteCount, []log
 func (t *tracer) DroppedPacket(net.Addr, logging.PacketType, protocol.ByteCount, logging.PacketDropReason) {
 }
 
+// A ConnectionTracer records a qlog for a connection.
+type ConnectionTracer interface {
+	logging.ConnectionTracer
+	// SetEventCategories sets the categories of events that are recorded.
+	// It can be called while the connection is running, e.g. to only record packet-level events while investigating a problem.
+	// Events of other categories are discarded before they are encoded.
+	SetEventCategories(EventCategory)
+	// EventCategories returns the categories of events that are recorded.
+	EventCategories() EventCategory
+}
+
 type connectionTracer struct {
 	mutex sync.Mutex
 
+	categories uint32 // EventCategory, to be accessed atomically
+
 	w             io.WriteCloser
 	odcid         protocol.ConnectionID
 	perspective   protocol.Perspective
@@ -86,11 +100,13 @@ type connectionTracer struct {
 	lastMetrics *metrics
 }
 
-var _ logging.ConnectionTracer = &connectionTracer{}
+var _ ConnectionTracer = &connectionTracer{}
 
 // NewConnectionTracer creates a new tracer to record a qlog for a connection.
-func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) logging.ConnectionTracer {
+// It records events of all categories, see SetEventCategories.
+func NewConnectionTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) ConnectionTracer {
 	t := &connectionTracer{
+		categories:    uint32(EventCategoryAll),
 		w:             w,
 		perspective:   p,
 		odcid:         odcid,
@@ -156,7 +172,22 @@ func (t *connectionTracer) export() error {
 	return t.w.Close()
 }
 
+func (t *connectionTracer) SetEventCategories(c EventCategory) {
+	atomic.StoreUint32(&t.categories, uint32(c))
+}
+
+func (t *connectionTracer) EventCategories() EventCategory {
+	return EventCategory(atomic.LoadUint32(&t.categories))
+}
+
+func (t *connectionTracer) recordsEvents(c EventCategory) bool {
+	return t.EventCategories()&c != 0
+}
+
 func (t *connectionTracer) recordEvent(eventTime time.Time, details eventDetails) {
+	if !t.recordsEvents(eventCategoryOf(details)) {
+		return
+	}
 	t.events <- event{
 		RelativeTime: eventTime.Sub(t.referenceTime),
 		eventDetails: details,
@@ -220,6 +251,22 @@ func (t *connectionTracer) ReceivedTransportParameters(tp *wire.TransportParamet
 	t.recordTransportParameters(t.perspective.Opposite(), tp)
 }
 
+func (t *connectionTracer) Rejected0RTT(parameter string) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventZeroRTTRejected{Parameter: parameter})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) NegotiatedIdleTimeout(local, remote, effective time.Duration) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventIdleTimeoutNegotiated{
+		Local:     local,
+		Remote:    remote,
+		Effective: effective,
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) RestoredTransportParameters(tp *wire.TransportParameters) {
 	ev := t.toTransportParameters(tp)
 	ev.Restore = true
@@ -277,6 +324,9 @@ func (t *connectionTracer) toTransportParameters(tp *wire.TransportParameters) *
 }
 
 func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize logging.ByteCount, ack *logging.AckFrame, frames []logging.Frame) {
+	if !t.recordsEvents(EventCategoryPackets) {
+		return
+	}
 	numFrames := len(frames)
 	if ack != nil {
 		numFrames++
@@ -300,6 +350,9 @@ func (t *connectionTracer) SentPacket(hdr *wire.ExtendedHeader, packetSize loggi
 }
 
 func (t *connectionTracer) ReceivedPacket(hdr *wire.ExtendedHeader, packetSize logging.ByteCount, frames []logging.Frame) {
+	if !t.recordsEvents(EventCategoryPackets) {
+		return
+	}
 	fs := make([]frame, len(frames))
 	for i, f := range frames {
 		fs[i] = frame{Frame: f}
@@ -353,6 +406,14 @@ func (t *connectionTracer) DroppedPacket(pt logging.PacketType, size protocol.By
 }
 
 func (t *connectionTracer) UpdatedMetrics(rttStats *utils.RTTStats, cwnd, bytesInFlight protocol.ByteCount, packetsInFlight int) {
+	if !t.recordsEvents(EventCategoryRecovery) {
+		// Metrics updates only contain the values that changed.
+		// Make sure that the first update after recording is resumed contains all values.
+		t.mutex.Lock()
+		t.lastMetrics = nil
+		t.mutex.Unlock()
+		return
+	}
 	m := &metrics{
 		MinRTT:           rttStats.MinRTT(),
 		SmoothedRTT:      rttStats.SmoothedRTT(),
@@ -389,12 +450,75 @@ func (t *connectionTracer) UpdatedCongestionState(state logging.CongestionState)
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) UndecryptablePacketQueueFull(pt logging.PacketType, size protocol.ByteCount, queuedPackets int, queuedBytes protocol.ByteCount) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventUndecryptablePacketQueueFull{
+		PacketType:    pt,
+		PacketSize:    size,
+		QueuedPackets: queuedPackets,
+		QueuedBytes:   queuedBytes,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) DetectedPeerAnomaly(anomaly logging.PeerAnomaly, details string) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPeerAnomaly{
+		Anomaly: anomaly,
+		Details: details,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) StartedPathValidation(remote net.Addr, challenge [8]byte) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPathValidationStarted{
+		Remote:    remote,
+		Challenge: challenge,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) ReceivedPathChallenge(challenge [8]byte) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPathChallengeReceived{Challenge: challenge})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) FinishedPathValidation(remote net.Addr, succeeded bool, duration time.Duration) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPathValidationFinished{
+		Remote:    remote,
+		Succeeded: succeeded,
+		Duration:  duration,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) SuspectedStatelessReset(token protocol.StatelessResetToken, undecryptablePackets int) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventStatelessResetSuspected{
+		Token:                token,
+		UndecryptablePackets: undecryptablePackets,
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) UpdatedPTOCount(value uint32) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventUpdatedPTO{Value: value})
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) DetectedPersistentCongestion(encLevel protocol.EncryptionLevel, pc logging.PersistentCongestion) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventPersistentCongestion{
+		EncryptionLevel:      encLevel,
+		PersistentCongestion: pc,
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) UpdatedKeyFromTLS(encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
 	t.mutex.Lock()
 	t.recordEvent(time.Now(), &eventKeyUpdated{
@@ -436,6 +560,33 @@ func (t *connectionTracer) DroppedEncryptionLevel(encLevel protocol.EncryptionLe
 	t.mutex.Unlock()
 }
 
+func (t *connectionTracer) ReassembledCryptoData(encLevel protocol.EncryptionLevel, stats logging.CryptoStreamStats) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventCryptoDataReassembled{
+		EncryptionLevel: encLevel,
+		Stats:           stats,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) LabeledStream(id protocol.StreamID, label string) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventStreamLabeled{
+		StreamID: id,
+		Label:    label,
+	})
+	t.mutex.Unlock()
+}
+
+func (t *connectionTracer) BlockedByFlowControl(id protocol.StreamID, connectionLevel bool) {
+	t.mutex.Lock()
+	t.recordEvent(time.Now(), &eventStreamBlocked{
+		StreamID:        id,
+		ConnectionLevel: connectionLevel,
+	})
+	t.mutex.Unlock()
+}
+
 func (t *connectionTracer) DroppedKey(generation protocol.KeyPhase) {
 	t.mutex.Lock()
 	now := time.Now()
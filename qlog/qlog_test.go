@@ -600,6 +600,20 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("trigger", "payload_decrypt_error"))
 			})
 
+			It("records when the queue of undecryptable packets is full", func() {
+				tracer.UndecryptablePacketQueueFull(logging.PacketTypeHandshake, 1337, 32, 42000)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:undecryptable_packet_queue_full"))
+				ev := entry.Event
+				Expect(ev).To(HaveKey("raw"))
+				Expect(ev["raw"].(map[string]interface{})).To(HaveKeyWithValue("length", float64(1337)))
+				Expect(ev).To(HaveKey("header"))
+				Expect(ev["header"].(map[string]interface{})).To(HaveKeyWithValue("packet_type", "handshake"))
+				Expect(ev).To(HaveKeyWithValue("queued_packets", float64(32)))
+				Expect(ev).To(HaveKeyWithValue("queued_bytes", float64(42000)))
+			})
+
 			It("records metrics updates", func() {
 				now := time.Now()
 				rttStats := utils.NewRTTStats()
@@ -779,6 +793,130 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveKeyWithValue("key_type", "server_0rtt_secret"))
 			})
 
+			It("records reassembled crypto data", func() {
+				tracer.ReassembledCryptoData(protocol.EncryptionHandshake, logging.CryptoStreamStats{
+					Frames:               12,
+					OutOfOrderFrames:     5,
+					MaxBufferedFragments: 3,
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("security:crypto_data_reassembled"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("packet_number_space", "handshake"))
+				Expect(ev).To(HaveKeyWithValue("frames", float64(12)))
+				Expect(ev).To(HaveKeyWithValue("out_of_order_frames", float64(5)))
+				Expect(ev).To(HaveKeyWithValue("max_buffered_fragments", float64(3)))
+			})
+
+			It("records suspected stateless resets", func() {
+				tracer.SuspectedStatelessReset(protocol.StatelessResetToken{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}, 3)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("connectivity:stateless_reset_suspected"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("stateless_reset_token", "00112233445566778899aabbccddeeff"))
+				Expect(ev).To(HaveKeyWithValue("undecryptable_packets", float64(3)))
+			})
+
+			It("records peer anomalies", func() {
+				tracer.DetectedPeerAnomaly(logging.PeerAnomalyFlowControlLimitDecreased, "MAX_DATA: 1000 (current limit: 2000)")
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:peer_anomaly"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("trigger", "flow_control_limit_decreased"))
+				Expect(ev).To(HaveKeyWithValue("details", "MAX_DATA: 1000 (current limit: 2000)"))
+			})
+
+			It("records the start of a path validation", func() {
+				tracer.StartedPathValidation(&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 4242}, [8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("connectivity:path_validation_started"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("remote", "192.168.13.37:4242"))
+				Expect(ev).To(HaveKeyWithValue("challenge", "0102030405060708"))
+			})
+
+			It("records received PATH_CHALLENGE frames", func() {
+				tracer.ReceivedPathChallenge([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("connectivity:path_challenge_received"))
+				Expect(entry.Event).To(HaveKeyWithValue("challenge", "0102030405060708"))
+			})
+
+			It("records the outcome of a path validation", func() {
+				tracer.FinishedPathValidation(&net.UDPAddr{IP: net.IPv4(192, 168, 13, 37), Port: 4242}, false, 1500*time.Millisecond)
+				entry := exportAndParseSingle()
+				Expect(entry.Name).To(Equal("connectivity:path_validation_finished"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("remote", "192.168.13.37:4242"))
+				Expect(ev).To(HaveKeyWithValue("result", "failed"))
+				Expect(ev).To(HaveKeyWithValue("duration", 1500.))
+			})
+
+			It("records persistent congestion", func() {
+				tracer.DetectedPersistentCongestion(protocol.Encryption1RTT, logging.PersistentCongestion{
+					Duration:        1500 * time.Millisecond,
+					Threshold:       time.Second,
+					FirstLostPacket: 10,
+					LastLostPacket:  25,
+				})
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("recovery:persistent_congestion"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("packet_number_space", "application_data"))
+				Expect(ev).To(HaveKeyWithValue("duration", float64(1500)))
+				Expect(ev).To(HaveKeyWithValue("threshold", float64(1000)))
+				Expect(ev).To(HaveKeyWithValue("first_lost_packet_number", float64(10)))
+				Expect(ev).To(HaveKeyWithValue("last_lost_packet_number", float64(25)))
+			})
+
+			It("records rejected 0-RTT", func() {
+				tracer.Rejected0RTT("initial_max_data")
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:0rtt_rejected"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("trigger", "transport_parameters_changed"))
+				Expect(ev).To(HaveKeyWithValue("parameter", "initial_max_data"))
+			})
+
+			It("records the negotiated idle timeout", func() {
+				tracer.NegotiatedIdleTimeout(time.Minute, 10*time.Second, 10*time.Second)
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:idle_timeout_negotiated"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("local", float64(60000)))
+				Expect(ev).To(HaveKeyWithValue("remote", float64(10000)))
+				Expect(ev).To(HaveKeyWithValue("effective", float64(10000)))
+			})
+
+			It("records labeled streams", func() {
+				tracer.LabeledStream(4, "request")
+				entry := exportAndParseSingle()
+				Expect(entry.Time).To(BeTemporally("~", time.Now(), scaleDuration(10*time.Millisecond)))
+				Expect(entry.Name).To(Equal("transport:stream_labeled"))
+				ev := entry.Event
+				Expect(ev).To(HaveKeyWithValue("stream_id", float64(4)))
+				Expect(ev).To(HaveKeyWithValue("label", "request"))
+			})
+
+			It("records streams blocked by flow control", func() {
+				tracer.BlockedByFlowControl(4, false)
+				tracer.BlockedByFlowControl(8, true)
+				entries := exportAndParse()
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].Name).To(Equal("transport:stream_flow_control_blocked"))
+				Expect(entries[0].Event).To(HaveKeyWithValue("stream_id", float64(4)))
+				Expect(entries[0].Event).To(HaveKeyWithValue("limit", "stream"))
+				Expect(entries[1].Event).To(HaveKeyWithValue("stream_id", float64(8)))
+				Expect(entries[1].Event).To(HaveKeyWithValue("limit", "connection"))
+			})
+
 			It("records dropped keys", func() {
 				tracer.DroppedKey(42)
 				entries := exportAndParse()
@@ -844,6 +982,65 @@ var _ = Describe("Tracing", func() {
 				Expect(ev).To(HaveLen(1))
 				Expect(ev).To(HaveKeyWithValue("details", "bar"))
 			})
+
+			Context("event categories", func() {
+				sendPacket := func() {
+					tracer.SentPacket(
+						&logging.ExtendedHeader{
+							Header:       logging.Header{DestConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+							PacketNumber: 1337,
+						},
+						987,
+						nil,
+						[]logging.Frame{&logging.PingFrame{}},
+					)
+				}
+
+				It("records all events by default", func() {
+					Expect(tracer.(ConnectionTracer).EventCategories()).To(Equal(EventCategoryAll))
+				})
+
+				It("only records events of the enabled categories", func() {
+					tracer.(ConnectionTracer).SetEventCategories(EventCategoryTransport | EventCategoryRecovery)
+					sendPacket()
+					tracer.ReceivedPacket(&logging.ExtendedHeader{PacketNumber: 42}, 1234, nil)
+					tracer.DroppedPacket(logging.PacketTypeHandshake, 1337, logging.PacketDropPayloadDecryptError)
+					tracer.UpdatedKey(42, true)
+					tracer.SuspectedStatelessReset(protocol.StatelessResetToken{}, 3)
+					tracer.Debug("foo", "bar")
+					tracer.UpdatedPTOCount(3)
+					entries := exportAndParse()
+					Expect(entries).To(HaveLen(2))
+					Expect(entries[0].Name).To(Equal("transport:foo"))
+					Expect(entries[1].Name).To(Equal("recovery:metrics_updated"))
+				})
+
+				It("enables and disables packet-level events on a running connection", func() {
+					tracer.(ConnectionTracer).SetEventCategories(EventCategoryAll &^ EventCategoryPackets)
+					sendPacket()
+					tracer.(ConnectionTracer).SetEventCategories(EventCategoryAll)
+					sendPacket()
+					tracer.(ConnectionTracer).SetEventCategories(EventCategoryAll &^ EventCategoryPackets)
+					sendPacket()
+					entries := exportAndParse()
+					Expect(entries).To(HaveLen(1))
+					Expect(entries[0].Name).To(Equal("transport:packet_sent"))
+				})
+
+				It("logs all values of the first metrics update after recovery events are enabled again", func() {
+					rttStats := utils.NewRTTStats()
+					rttStats.UpdateRTT(15*time.Millisecond, 0, time.Now())
+					tracer.UpdatedMetrics(rttStats, 4321, 1234, 42)
+					tracer.(ConnectionTracer).SetEventCategories(EventCategoryAll &^ EventCategoryRecovery)
+					tracer.UpdatedMetrics(rttStats, 4321, 12345, 42)
+					tracer.(ConnectionTracer).SetEventCategories(EventCategoryAll)
+					tracer.UpdatedMetrics(rttStats, 4321, 1234, 42)
+					entries := exportAndParse()
+					Expect(entries).To(HaveLen(2))
+					Expect(entries[0].Event).To(HaveLen(7))
+					Expect(entries[1].Event).To(HaveLen(7))
+				})
+			})
 		})
 	})
 })
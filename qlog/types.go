@@ -302,6 +302,21 @@ func (t timerType) String() string {
 	}
 }
 
+type peerAnomaly logging.PeerAnomaly
+
+func (a peerAnomaly) String() string {
+	switch logging.PeerAnomaly(a) {
+	case logging.PeerAnomalyAckForUnsentPacket:
+		return "ack_for_unsent_packet"
+	case logging.PeerAnomalyDuplicatePackets:
+		return "duplicate_packets"
+	case logging.PeerAnomalyFlowControlLimitDecreased:
+		return "flow_control_limit_decreased"
+	default:
+		return "unknown peer anomaly"
+	}
+}
+
 type congestionState logging.CongestionState
 
 func (s congestionState) String() string {
@@ -127,4 +127,10 @@ var _ = Describe("Types", func() {
 		Expect(congestionState(logging.CongestionStateApplicationLimited).String()).To(Equal("application_limited"))
 		Expect(congestionState(logging.CongestionStateRecovery).String()).To(Equal("recovery"))
 	})
+
+	It("has a string representation for peer anomalies", func() {
+		Expect(peerAnomaly(logging.PeerAnomalyAckForUnsentPacket).String()).To(Equal("ack_for_unsent_packet"))
+		Expect(peerAnomaly(logging.PeerAnomalyDuplicatePackets).String()).To(Equal("duplicate_packets"))
+		Expect(peerAnomaly(logging.PeerAnomalyFlowControlLimitDecreased).String()).To(Equal("flow_control_limit_decreased"))
+	})
 })
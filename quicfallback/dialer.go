@@ -0,0 +1,151 @@
+// Package quicfallback implements a dialer that falls back to TCP when QUIC is not available.
+//
+// Some networks block or throttle UDP traffic, or only allow certain QUIC versions.
+// The Dialer first attempts to establish a QUIC connection, using every configured QUIC version in turn.
+// If all attempts time out, or UDP is blocked, it falls back to a TCP connection
+// dialed by the application (usually a TLS connection).
+package quicfallback
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// DefaultAttemptTimeout is the default timeout for a single QUIC connection attempt.
+const DefaultAttemptTimeout = 3 * time.Second
+
+// A Transport is the transport used for a connection.
+type Transport uint8
+
+const (
+	// TransportQUIC is a QUIC connection.
+	TransportQUIC Transport = 1 + iota
+	// TransportTCP is a TCP connection, established after QUIC failed.
+	TransportTCP
+)
+
+func (t Transport) String() string {
+	switch t {
+	case TransportQUIC:
+		return "QUIC"
+	case TransportTCP:
+		return "TCP"
+	default:
+		return fmt.Sprintf("unknown transport: %d", t)
+	}
+}
+
+// A Conn is a connection established by the Dialer.
+type Conn struct {
+	// Transport is the transport used.
+	Transport Transport
+	// Session is the QUIC session. It is only set if the Transport is TransportQUIC.
+	Session quic.Session
+	// Version is the QUIC version used for the Session.
+	// It is zero if no QUIC version was configured on the Dialer.
+	Version quic.VersionNumber
+	// TCPConn is the connection returned by the Dialer's DialTCP. It is only set if the Transport is TransportTCP.
+	TCPConn net.Conn
+	// QUICErrors are the errors of the failed QUIC connection attempts.
+	QUICErrors []error
+}
+
+// A Dialer dials QUIC connections, and falls back to TCP if QUIC is not available.
+type Dialer struct {
+	// TLSConfig is the TLS configuration used for the QUIC connection.
+	TLSConfig *tls.Config
+	// QuicConfig is the QUIC configuration used for the QUIC connection.
+	QuicConfig *quic.Config
+	// Versions are the QUIC versions that are attempted, in this order.
+	// Every version is attempted in a separate connection attempt.
+	// If empty, a single attempt is made, using the versions of the QuicConfig.
+	Versions []quic.VersionNumber
+	// AttemptTimeout is the timeout for a single QUIC connection attempt.
+	// If zero, DefaultAttemptTimeout is used.
+	AttemptTimeout time.Duration
+	// DialTCP dials the TCP connection used if all QUIC connection attempts failed.
+	// If nil, the Dialer doesn't fall back to TCP.
+	DialTCP func(ctx context.Context, addr string) (net.Conn, error)
+
+	dial func(ctx context.Context, addr string, tlsConf *tls.Config, conf *quic.Config) (quic.Session, error) // only set for testing
+}
+
+// Dial connects to the given address.
+//
+// The Dialer only falls back to the next QUIC version, and eventually to TCP,
+// if the QUIC connection attempt timed out, the UDP socket returned an error,
+// or the server doesn't support the QUIC version.
+// Any other error means that the server was reached using QUIC, and is returned without falling back,
+// e.g. when the server's certificate is invalid.
+func (d *Dialer) Dial(ctx context.Context, addr string) (*Conn, error) {
+	versions := d.Versions
+	if len(versions) == 0 {
+		versions = []quic.VersionNumber{0}
+	}
+	var quicErrors []error
+	for _, v := range versions {
+		sess, err := d.dialQUIC(ctx, addr, v)
+		if err == nil {
+			return &Conn{Transport: TransportQUIC, Session: sess, Version: v, QUICErrors: quicErrors}, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !shouldFallBack(err) {
+			return nil, err
+		}
+		quicErrors = append(quicErrors, err)
+	}
+	if d.DialTCP == nil {
+		return nil, quicErrors[len(quicErrors)-1]
+	}
+	conn, err := d.DialTCP(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Transport: TransportTCP, TCPConn: conn, QUICErrors: quicErrors}, nil
+}
+
+func (d *Dialer) dialQUIC(ctx context.Context, addr string, v quic.VersionNumber) (quic.Session, error) {
+	timeout := d.AttemptTimeout
+	if timeout == 0 {
+		timeout = DefaultAttemptTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conf := d.QuicConfig
+	if v != 0 {
+		if conf == nil {
+			conf = &quic.Config{}
+		} else {
+			conf = conf.Clone()
+		}
+		conf.Versions = []quic.VersionNumber{v}
+	}
+	dial := d.dial
+	if dial == nil {
+		dial = quic.DialAddrContext
+	}
+	return dial(ctx, addr, d.TLSConfig, conf)
+}
+
+// shouldFallBack says if a QUIC connection attempt failed because QUIC (or this QUIC version)
+// is not available on the path to the server.
+func shouldFallBack(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, quic.ErrVersionNegotiation) {
+		return true
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr)
+}
@@ -0,0 +1,164 @@
+package quicfallback
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dialer", func() {
+	type dialResult struct {
+		sess quic.Session
+		err  error
+	}
+
+	var (
+		versions chan []quic.VersionNumber
+		results  chan dialResult
+		tcpConn  net.Conn
+	)
+
+	BeforeEach(func() {
+		versions = make(chan []quic.VersionNumber, 10)
+		results = make(chan dialResult, 10)
+		tcpConn, _ = net.Pipe()
+	})
+
+	newDialer := func() *Dialer {
+		return &Dialer{
+			Versions: []quic.VersionNumber{protocol.Version1, protocol.VersionDraft29},
+			dial: func(_ context.Context, addr string, _ *tls.Config, conf *quic.Config) (quic.Session, error) {
+				Expect(addr).To(Equal("localhost:443"))
+				versions <- conf.Versions
+				r := <-results
+				return r.sess, r.err
+			},
+			DialTCP: func(_ context.Context, addr string) (net.Conn, error) {
+				Expect(addr).To(Equal("localhost:443"))
+				return tcpConn, nil
+			},
+		}
+	}
+
+	It("dials QUIC", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		results <- dialResult{sess: sess}
+		conn, err := newDialer().Dial(context.Background(), "localhost:443")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Transport).To(Equal(TransportQUIC))
+		Expect(conn.Session).To(Equal(sess))
+		Expect(conn.Version).To(Equal(protocol.Version1))
+		Expect(conn.QUICErrors).To(BeEmpty())
+		Expect(versions).To(Receive(Equal([]quic.VersionNumber{protocol.Version1})))
+	})
+
+	It("uses the versions of the QUIC config, if no versions are set", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		results <- dialResult{sess: sess}
+		d := newDialer()
+		d.Versions = nil
+		d.QuicConfig = &quic.Config{Versions: []quic.VersionNumber{protocol.VersionDraft29}}
+		conn, err := d.Dial(context.Background(), "localhost:443")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Version).To(BeZero())
+		Expect(versions).To(Receive(Equal([]quic.VersionNumber{protocol.VersionDraft29})))
+	})
+
+	It("tries the next version", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		results <- dialResult{err: &quic.VersionNegotiationError{}}
+		results <- dialResult{sess: sess}
+		conn, err := newDialer().Dial(context.Background(), "localhost:443")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Transport).To(Equal(TransportQUIC))
+		Expect(conn.Version).To(Equal(protocol.VersionDraft29))
+		Expect(conn.QUICErrors).To(HaveLen(1))
+		Expect(versions).To(Receive(Equal([]quic.VersionNumber{protocol.Version1})))
+		Expect(versions).To(Receive(Equal([]quic.VersionNumber{protocol.VersionDraft29})))
+	})
+
+	It("falls back to TCP", func() {
+		results <- dialResult{err: &quic.HandshakeTimeoutError{}}
+		results <- dialResult{err: &net.OpError{Op: "read", Err: errors.New("connection refused")}}
+		conn, err := newDialer().Dial(context.Background(), "localhost:443")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Transport).To(Equal(TransportTCP))
+		Expect(conn.TCPConn).To(Equal(tcpConn))
+		Expect(conn.QUICErrors).To(HaveLen(2))
+	})
+
+	It("returns the error of the last QUIC attempt, if there's no TCP fallback", func() {
+		results <- dialResult{err: &quic.HandshakeTimeoutError{}}
+		results <- dialResult{err: context.DeadlineExceeded}
+		d := newDialer()
+		d.DialTCP = nil
+		_, err := d.Dial(context.Background(), "localhost:443")
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("doesn't fall back when the server was reached", func() {
+		testErr := &quic.TransportError{ErrorCode: 0x12a, Remote: true}
+		results <- dialResult{err: testErr}
+		_, err := newDialer().Dial(context.Background(), "localhost:443")
+		Expect(err).To(MatchError(testErr))
+		Expect(versions).To(HaveLen(1))
+	})
+
+	It("returns errors from the TCP dial", func() {
+		results <- dialResult{err: &quic.HandshakeTimeoutError{}}
+		results <- dialResult{err: &quic.HandshakeTimeoutError{}}
+		testErr := errors.New("tcp error")
+		d := newDialer()
+		d.DialTCP = func(context.Context, string) (net.Conn, error) { return nil, testErr }
+		_, err := d.Dial(context.Background(), "localhost:443")
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("stops when the context is canceled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		d := newDialer()
+		d.dial = func(context.Context, string, *tls.Config, *quic.Config) (quic.Session, error) {
+			cancel()
+			return nil, context.Canceled
+		}
+		_, err := d.Dial(ctx, "localhost:443")
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("falls back to TCP if there's no QUIC server", func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		// use the TCP port for UDP, no QUIC server is listening there
+		addr := ln.Addr().String()
+		d := &Dialer{
+			TLSConfig:      &tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"fallback"}},
+			AttemptTimeout: 50 * time.Millisecond,
+			DialTCP: func(ctx context.Context, addr string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "tcp", addr)
+			},
+		}
+		conn, err := d.Dial(context.Background(), addr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(conn.Transport).To(Equal(TransportTCP))
+		Expect(conn.QUICErrors).To(HaveLen(1))
+		conn.TCPConn.Close()
+	})
+
+	It("has a string representation for transports", func() {
+		Expect(TransportQUIC.String()).To(Equal("QUIC"))
+		Expect(TransportTCP.String()).To(Equal("TCP"))
+		Expect(Transport(42).String()).To(Equal("unknown transport: 42"))
+	})
+})
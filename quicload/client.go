@@ -0,0 +1,118 @@
+package quicload
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+)
+
+// DialQUIC returns a DialFunc for QUIC servers.
+// Every request is sent on a new bidirectional stream: requestSize bytes are written,
+// the send direction of the stream is closed, and the response is read until the end of the stream.
+// The server is expected to close the stream after sending the response.
+func DialQUIC(addr string, tlsConf *tls.Config, quicConf *quic.Config, requestSize int) DialFunc {
+	payload := bytes.Repeat([]byte{'a'}, requestSize)
+	return func(ctx context.Context) (Client, error) {
+		sess, err := quic.DialAddrContext(ctx, addr, tlsConf, quicConf)
+		if err != nil {
+			return nil, err
+		}
+		return &quicClient{sess: sess, payload: payload}, nil
+	}
+}
+
+type quicClient struct {
+	sess    quic.Session
+	payload []byte
+}
+
+var _ Client = &quicClient{}
+
+func (c *quicClient) Request(ctx context.Context) error {
+	str, err := c.sess.OpenStreamSync(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := str.Write(c.payload); err != nil {
+		str.CancelRead(0)
+		return err
+	}
+	if err := str.Close(); err != nil {
+		str.CancelRead(0)
+		return err
+	}
+	if _, err := io.Copy(ioutil.Discard, str); err != nil {
+		str.CancelRead(0)
+		return err
+	}
+	return nil
+}
+
+func (c *quicClient) Close() error {
+	return c.sess.CloseWithError(0, "")
+}
+
+// DialHTTP3 returns a DialFunc for HTTP/3 servers.
+// Every connection uses its own http3.RoundTripper.
+// If requestSize is zero, GET requests are sent, otherwise POST requests with a body of requestSize bytes.
+// The response body is read completely. Responses with a status code other than 2xx count as errors.
+func DialHTTP3(url string, tlsConf *tls.Config, quicConf *quic.Config, requestSize int) DialFunc {
+	payload := bytes.Repeat([]byte{'a'}, requestSize)
+	return func(ctx context.Context) (Client, error) {
+		c := &http3Client{
+			rt:      &http3.RoundTripper{TLSClientConfig: tlsConf, QuicConfig: quicConf},
+			url:     url,
+			payload: payload,
+		}
+		// Send a first request to establish the connection.
+		if err := c.Request(ctx); err != nil {
+			c.Close()
+			return nil, err
+		}
+		return c, nil
+	}
+}
+
+type http3Client struct {
+	rt      *http3.RoundTripper
+	url     string
+	payload []byte
+}
+
+var _ Client = &http3Client{}
+
+func (c *http3Client) Request(ctx context.Context) error {
+	method := http.MethodGet
+	var body io.Reader
+	if len(c.payload) > 0 {
+		method = http.MethodPost
+		body = bytes.NewReader(c.payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url, body)
+	if err != nil {
+		return err
+	}
+	rsp, err := c.rt.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if _, err := io.Copy(ioutil.Discard, rsp.Body); err != nil {
+		return err
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("quicload: unexpected status code %d", rsp.StatusCode)
+	}
+	return nil
+}
+
+func (c *http3Client) Close() error {
+	return c.rt.Close()
+}
@@ -0,0 +1,110 @@
+package quicload
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/http3"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Clients", func() {
+	tlsClientConf := func(proto string) *tls.Config {
+		return &tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{proto}}
+	}
+
+	It("runs a load test against a QUIC server", func() {
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"quicload"}
+		ln, err := quic.ListenAddr("127.0.0.1:0", tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		received := make(chan int, 100)
+		go func() {
+			for {
+				sess, err := ln.Accept(context.Background())
+				if err != nil {
+					return
+				}
+				go func() {
+					for {
+						str, err := sess.AcceptStream(context.Background())
+						if err != nil {
+							return
+						}
+						go func() {
+							data, _ := ioutil.ReadAll(str)
+							received <- len(data)
+							str.Write([]byte("response"))
+							str.Close()
+						}()
+					}
+				}()
+			}
+		}()
+
+		res, err := Run(context.Background(), &Config{
+			Dial:                 DialQUIC(ln.Addr().String(), tlsClientConf("quicload"), nil, 1000),
+			Connections:          2,
+			StreamsPerConnection: 2,
+			MaxRequests:          10,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).To(BeEquivalentTo(10))
+		Expect(res.Errors).To(BeZero())
+		Expect(res.Percentile(50)).ToNot(BeZero())
+		for i := 0; i < 10; i++ {
+			Eventually(received).Should(Receive(Equal(1000)))
+		}
+	})
+
+	It("runs a load test against an HTTP/3 server", func() {
+		mux := http.NewServeMux()
+		received := make(chan string, 100)
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received <- fmt.Sprintf("%s %d", r.Method, len(body))
+			w.Write([]byte("response"))
+		})
+		// use a fixed QUIC version, such that client and server agree on the ALPN
+		quicConf := &quic.Config{Versions: []quic.VersionNumber{quic.VersionDraft29}}
+		server := &http3.Server{
+			Server:     &http.Server{Handler: mux, TLSConfig: testdata.GetTLSConfig()},
+			QuicConfig: quicConf.Clone(),
+		}
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			server.Serve(conn)
+		}()
+		defer func() {
+			server.Close()
+			Eventually(done).Should(BeClosed())
+		}()
+
+		url := fmt.Sprintf("https://127.0.0.1:%d/", conn.LocalAddr().(*net.UDPAddr).Port)
+		res, err := Run(context.Background(), &Config{
+			Dial:        DialHTTP3(url, &tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost"}, quicConf, 100),
+			Connections: 2,
+			MaxRequests: 6,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).To(BeEquivalentTo(6))
+		Expect(res.Errors).To(BeZero())
+		// one additional request per connection is sent when dialing
+		for i := 0; i < 8; i++ {
+			Eventually(received).Should(Receive(Equal("POST 100")))
+		}
+	})
+})
@@ -0,0 +1,249 @@
+// Package quicload generates load against QUIC and HTTP/3 servers.
+//
+// It opens a number of connections, and runs a number of concurrent request loops (workers)
+// on every connection. Every worker sends requests one after another,
+// optionally limited to a request rate and pausing for a think time after every request.
+// The latency of every request is recorded, and reported as percentiles.
+package quicload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Client sends requests on a single connection.
+type Client interface {
+	// Request sends a single request and waits for the response.
+	// It is called concurrently by all workers using this Client.
+	Request(ctx context.Context) error
+	// Close closes the connection.
+	Close() error
+}
+
+// A DialFunc establishes a new connection to the server under test.
+type DialFunc func(context.Context) (Client, error)
+
+// Config configures a load test.
+type Config struct {
+	// Dial establishes the connections.
+	// Use DialQUIC or DialHTTP3, or provide a custom function.
+	Dial DialFunc
+	// Connections is the number of connections that are established.
+	// If zero, a single connection is used.
+	Connections int
+	// StreamsPerConnection is the number of requests that are sent concurrently on every connection.
+	// If zero, a single stream is used.
+	StreamsPerConnection int
+	// RequestsPerSecond limits the total request rate, across all connections and streams.
+	// If zero, requests are sent as fast as possible.
+	RequestsPerSecond float64
+	// ThinkTime is the time that every worker waits after receiving a response, before sending the next request.
+	ThinkTime time.Duration
+	// Duration is the duration of the load test.
+	// If zero, the load test runs until MaxRequests were sent or the context is canceled.
+	Duration time.Duration
+	// MaxRequests is the total number of requests that are sent.
+	// If zero, the number of requests is not limited.
+	MaxRequests uint64
+}
+
+// Result is the result of a load test.
+type Result struct {
+	// Requests is the number of requests that completed successfully.
+	Requests uint64
+	// Errors is the number of requests that failed.
+	Errors uint64
+	// DialErrors is the number of connections that couldn't be established.
+	DialErrors int
+	// Duration is the duration of the load test.
+	Duration time.Duration
+
+	// latencies of all successful requests, sorted
+	latencies []time.Duration
+}
+
+// RequestsPerSecond is the rate of successful requests.
+func (r *Result) RequestsPerSecond() float64 {
+	if r.Duration == 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Duration.Seconds()
+}
+
+// Percentile returns the p-th percentile (0 <= p <= 100) of the latency of the successful requests.
+// It returns 0 if there were no successful requests.
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	if p <= 0 {
+		return r.latencies[0]
+	}
+	if p >= 100 {
+		return r.latencies[len(r.latencies)-1]
+	}
+	// nearest-rank method
+	rank := int(math.Ceil(p / 100 * float64(len(r.latencies))))
+	return r.latencies[rank-1]
+}
+
+// MeanLatency is the mean latency of the successful requests.
+func (r *Result) MeanLatency() time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, l := range r.latencies {
+		sum += l
+	}
+	return sum / time.Duration(len(r.latencies))
+}
+
+// Run runs a load test.
+// It returns when the load test is finished, or when the context is canceled.
+// An error is only returned if the configuration is invalid, or if no connection could be established.
+func Run(ctx context.Context, conf *Config) (*Result, error) {
+	if conf.Dial == nil {
+		return nil, errors.New("quicload: no dial function")
+	}
+	if conf.RequestsPerSecond < 0 {
+		return nil, errors.New("quicload: invalid request rate")
+	}
+	if conf.Duration == 0 && conf.MaxRequests == 0 {
+		if _, ok := ctx.Deadline(); !ok && ctx.Done() == nil {
+			return nil, errors.New("quicload: load test would run forever")
+		}
+	}
+	numConns := conf.Connections
+	if numConns == 0 {
+		numConns = 1
+	}
+	numStreams := conf.StreamsPerConnection
+	if numStreams == 0 {
+		numStreams = 1
+	}
+	if conf.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.Duration)
+		defer cancel()
+	}
+
+	r := &runner{
+		conf:     conf,
+		interval: requestInterval(conf.RequestsPerSecond, numConns*numStreams),
+	}
+	start := time.Now()
+	clients := make([]Client, 0, numConns)
+	var dialErrors int
+	var dialErr error
+	var clientsMutex sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < numConns; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := conf.Dial(ctx)
+			clientsMutex.Lock()
+			defer clientsMutex.Unlock()
+			if err != nil {
+				dialErrors++
+				dialErr = err
+				return
+			}
+			clients = append(clients, c)
+			for j := 0; j < numStreams; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					r.work(ctx, c)
+				}()
+			}
+		}()
+	}
+	wg.Wait()
+	duration := time.Since(start)
+	for _, c := range clients {
+		c.Close()
+	}
+	if len(clients) == 0 {
+		return nil, fmt.Errorf("quicload: failed to establish any connection: %w", dialErr)
+	}
+
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	return &Result{
+		Requests:   uint64(len(r.latencies)),
+		Errors:     r.errors,
+		DialErrors: dialErrors,
+		Duration:   duration,
+		latencies:  r.latencies,
+	}, nil
+}
+
+// requestInterval is the interval between two consecutive requests of every worker.
+func requestInterval(rate float64, workers int) time.Duration {
+	if rate == 0 {
+		return 0
+	}
+	return time.Duration(float64(workers) / rate * float64(time.Second))
+}
+
+type runner struct {
+	conf     *Config
+	interval time.Duration
+
+	mutex     sync.Mutex
+	started   uint64
+	latencies []time.Duration
+	errors    uint64
+}
+
+// startRequest reports if another request may be sent, taking into account MaxRequests.
+func (r *runner) startRequest() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.conf.MaxRequests > 0 && r.started >= r.conf.MaxRequests {
+		return false
+	}
+	r.started++
+	return true
+}
+
+func (r *runner) work(ctx context.Context, c Client) {
+	next := time.Now()
+	for ctx.Err() == nil && r.startRequest() {
+		start := time.Now()
+		err := c.Request(ctx)
+		latency := time.Since(start)
+		if ctx.Err() != nil {
+			// Requests that were interrupted by the end of the load test are neither counted as success nor as failure.
+			return
+		}
+		r.mutex.Lock()
+		if err != nil {
+			r.errors++
+		} else {
+			r.latencies = append(r.latencies, latency)
+		}
+		r.mutex.Unlock()
+
+		wait := r.conf.ThinkTime
+		if r.interval > 0 {
+			next = next.Add(r.interval)
+			if d := time.Until(next); d > wait {
+				wait = d
+			}
+		}
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package quicload
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQuicLoad(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "quicload Suite")
+}
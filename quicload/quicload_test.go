@@ -0,0 +1,184 @@
+package quicload
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type fakeClient struct {
+	request func(context.Context) error
+	closed  int32
+}
+
+func (c *fakeClient) Request(ctx context.Context) error { return c.request(ctx) }
+func (c *fakeClient) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+var _ = Describe("Load Test", func() {
+	It("rejects invalid configs", func() {
+		_, err := Run(context.Background(), &Config{MaxRequests: 1})
+		Expect(err).To(MatchError("quicload: no dial function"))
+		dial := func(context.Context) (Client, error) { return &fakeClient{}, nil }
+		_, err = Run(context.Background(), &Config{Dial: dial, MaxRequests: 1, RequestsPerSecond: -1})
+		Expect(err).To(MatchError("quicload: invalid request rate"))
+		_, err = Run(context.Background(), &Config{Dial: dial})
+		Expect(err).To(MatchError("quicload: load test would run forever"))
+	})
+
+	It("opens connections and runs concurrent requests on every connection", func() {
+		var mutex sync.Mutex
+		var clients []*fakeClient
+		var concurrent, maxConcurrent int32
+		dial := func(context.Context) (Client, error) {
+			c := &fakeClient{request: func(context.Context) error {
+				n := atomic.AddInt32(&concurrent, 1)
+				defer atomic.AddInt32(&concurrent, -1)
+				mutex.Lock()
+				if n > maxConcurrent {
+					maxConcurrent = n
+				}
+				mutex.Unlock()
+				time.Sleep(time.Millisecond)
+				return nil
+			}}
+			mutex.Lock()
+			clients = append(clients, c)
+			mutex.Unlock()
+			return c, nil
+		}
+		res, err := Run(context.Background(), &Config{
+			Dial:                 dial,
+			Connections:          3,
+			StreamsPerConnection: 4,
+			MaxRequests:          120,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).To(BeEquivalentTo(120))
+		Expect(res.Errors).To(BeZero())
+		Expect(clients).To(HaveLen(3))
+		for _, c := range clients {
+			Expect(c.closed).To(BeEquivalentTo(1))
+		}
+		Expect(maxConcurrent).To(BeNumerically(">", 1))
+		Expect(maxConcurrent).To(BeNumerically("<=", 12))
+		Expect(res.Percentile(50)).To(BeNumerically(">=", time.Millisecond))
+		Expect(res.MeanLatency()).To(BeNumerically(">=", time.Millisecond))
+		Expect(res.RequestsPerSecond()).To(BeNumerically(">", 0))
+	})
+
+	It("counts errors", func() {
+		var counter int32
+		dial := func(context.Context) (Client, error) {
+			return &fakeClient{request: func(context.Context) error {
+				if atomic.AddInt32(&counter, 1)%2 == 0 {
+					return errors.New("request failed")
+				}
+				return nil
+			}}, nil
+		}
+		res, err := Run(context.Background(), &Config{Dial: dial, MaxRequests: 10})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).To(BeEquivalentTo(5))
+		Expect(res.Errors).To(BeEquivalentTo(5))
+	})
+
+	It("counts dial errors", func() {
+		var counter int32
+		dial := func(context.Context) (Client, error) {
+			if atomic.AddInt32(&counter, 1) == 1 {
+				return nil, errors.New("dial failed")
+			}
+			return &fakeClient{request: func(context.Context) error { return nil }}, nil
+		}
+		res, err := Run(context.Background(), &Config{Dial: dial, Connections: 2, MaxRequests: 10})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.DialErrors).To(Equal(1))
+		Expect(res.Requests).To(BeEquivalentTo(10))
+	})
+
+	It("errors if no connection can be established", func() {
+		dial := func(context.Context) (Client, error) { return nil, errors.New("dial failed") }
+		_, err := Run(context.Background(), &Config{Dial: dial, Connections: 2, MaxRequests: 10})
+		Expect(err).To(MatchError("quicload: failed to establish any connection: dial failed"))
+	})
+
+	It("limits the request rate", func() {
+		dial := func(context.Context) (Client, error) {
+			return &fakeClient{request: func(context.Context) error { return nil }}, nil
+		}
+		start := time.Now()
+		res, err := Run(context.Background(), &Config{
+			Dial:                 dial,
+			Connections:          2,
+			StreamsPerConnection: 2,
+			RequestsPerSecond:    200,
+			MaxRequests:          20,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).To(BeEquivalentTo(20))
+		// 4 workers, each sending a request every 20ms
+		Expect(time.Since(start)).To(BeNumerically(">=", 80*time.Millisecond))
+	})
+
+	It("waits for the think time", func() {
+		var counter int32
+		dial := func(context.Context) (Client, error) {
+			return &fakeClient{request: func(context.Context) error {
+				atomic.AddInt32(&counter, 1)
+				return nil
+			}}, nil
+		}
+		res, err := Run(context.Background(), &Config{
+			Dial:      dial,
+			ThinkTime: 20 * time.Millisecond,
+			Duration:  70 * time.Millisecond,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).To(BeNumerically("<=", 4))
+		Expect(res.Duration).To(BeNumerically(">=", 70*time.Millisecond))
+	})
+
+	It("stops when the context is canceled", func() {
+		dial := func(context.Context) (Client, error) {
+			return &fakeClient{request: func(ctx context.Context) error {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Millisecond):
+					return nil
+				}
+			}}, nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+		defer cancel()
+		res, err := Run(ctx, &Config{Dial: dial, StreamsPerConnection: 2})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.Requests).ToNot(BeZero())
+		// the interrupted requests are not counted as errors
+		Expect(res.Errors).To(BeZero())
+	})
+
+	It("calculates percentiles", func() {
+		res := &Result{}
+		Expect(res.Percentile(50)).To(BeZero())
+		Expect(res.MeanLatency()).To(BeZero())
+		Expect(res.RequestsPerSecond()).To(BeZero())
+		for i := 1; i <= 100; i++ {
+			res.latencies = append(res.latencies, time.Duration(i)*time.Millisecond)
+		}
+		Expect(res.Percentile(0)).To(Equal(time.Millisecond))
+		Expect(res.Percentile(50)).To(Equal(50 * time.Millisecond))
+		Expect(res.Percentile(99)).To(Equal(99 * time.Millisecond))
+		Expect(res.Percentile(99.9)).To(Equal(100 * time.Millisecond))
+		Expect(res.Percentile(100)).To(Equal(100 * time.Millisecond))
+		Expect(res.MeanLatency()).To(Equal(50500 * time.Microsecond))
+	})
+})
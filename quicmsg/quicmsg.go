@@ -0,0 +1,105 @@
+// Package quicmsg implements length-prefixed messages on QUIC streams.
+//
+// Every message is prefixed with its length, encoded as a QUIC variable-length integer
+// (see section 16 of RFC 9000). This allows sending multiple messages on a single stream,
+// and the receiver never has to deal with partial reads.
+package quicmsg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// DefaultMaxMessageSize is the default maximum size of a message.
+const DefaultMaxMessageSize = 1 << 20
+
+// ErrMessageTooLarge is returned when a message exceeds the maximum size.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// WriteMessage writes msg to the stream, prefixed with its length.
+// The prefix and the message are passed to the stream together (see quic.SendStream.WriteBuffers),
+// without joining them in an intermediate buffer.
+func WriteMessage(str quic.SendStream, msg []byte) error {
+	var prefix [8]byte
+	b := bytes.NewBuffer(prefix[:0])
+	quicvarint.Write(b, uint64(len(msg)))
+	_, err := str.WriteBuffers([][]byte{b.Bytes(), msg})
+	return err
+}
+
+// ReadMessage reads a single message from the stream.
+// It returns the same errors as Reader.ReadMessage.
+// If maxMessageSize is 0, DefaultMaxMessageSize is used.
+func ReadMessage(str quic.ReceiveStream, maxMessageSize int) ([]byte, error) {
+	return NewReader(str, maxMessageSize).ReadMessage()
+}
+
+// A Reader reads length-prefixed messages from a stream.
+// It reuses its buffer for subsequent messages.
+type Reader struct {
+	str            quic.ReceiveStream
+	maxMessageSize int
+	buf            []byte
+}
+
+// NewReader creates a new Reader.
+// If maxMessageSize is 0, DefaultMaxMessageSize is used.
+func NewReader(str quic.ReceiveStream, maxMessageSize int) *Reader {
+	if maxMessageSize == 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+	return &Reader{str: str, maxMessageSize: maxMessageSize}
+}
+
+// ReadMessage reads the next message.
+// The returned slice is only valid until the next call to ReadMessage.
+// At the end of the stream, it returns io.EOF. If the stream ends in the middle of a message,
+// it returns io.ErrUnexpectedEOF.
+// If the message exceeds the maximum size, it returns ErrMessageTooLarge without consuming the message.
+// The stream should then be canceled.
+func (r *Reader) ReadMessage() ([]byte, error) {
+	length, prefixLen, err := r.peekLength()
+	if err != nil {
+		return nil, err
+	}
+	if length > uint64(r.maxMessageSize) {
+		return nil, ErrMessageTooLarge
+	}
+	if _, err := r.str.Discard(int64(prefixLen)); err != nil {
+		return nil, err
+	}
+	if uint64(cap(r.buf)) < length {
+		r.buf = make([]byte, length)
+	}
+	r.buf = r.buf[:length]
+	if _, err := io.ReadFull(r.str, r.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return r.buf, nil
+}
+
+// peekLength parses the length prefix, without consuming it.
+// This way, the prefix is never consumed partially, e.g. when the read deadline expires.
+func (r *Reader) peekLength() (length uint64, prefixLen int, _ error) {
+	b, err := r.str.Peek(1)
+	if len(b) == 0 {
+		return 0, 0, err
+	}
+	prefixLen = 1 << (b[0] >> 6)
+	b, err = r.str.Peek(prefixLen)
+	if len(b) < prefixLen {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	length, err = quicvarint.Read(bytes.NewReader(b))
+	return length, prefixLen, err
+}
@@ -0,0 +1,94 @@
+package quicmsg
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/golang/mock/gomock"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Messages", func() {
+	// newStream returns a stream that reads the given data
+	newStream := func(data []byte) *mockquic.MockStream {
+		r := bufio.NewReaderSize(bytes.NewReader(data), 1<<16)
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Peek(gomock.Any()).DoAndReturn(r.Peek).AnyTimes()
+		str.EXPECT().Discard(gomock.Any()).DoAndReturn(func(n int64) (int64, error) {
+			m, err := r.Discard(int(n))
+			return int64(m), err
+		}).AnyTimes()
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+		return str
+	}
+
+	encode := func(msgs ...[]byte) []byte {
+		b := &bytes.Buffer{}
+		for _, msg := range msgs {
+			quicvarint.Write(b, uint64(len(msg)))
+			b.Write(msg)
+		}
+		return b.Bytes()
+	}
+
+	It("writes the length prefix and the message in a single call", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		msg := bytes.Repeat([]byte("a"), 100)
+		str.EXPECT().WriteBuffers([][]byte{{0x40, 100}, msg}).Return(int64(102), nil)
+		Expect(WriteMessage(str, msg)).To(Succeed())
+	})
+
+	It("returns write errors", func() {
+		testErr := errors.New("test error")
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().WriteBuffers(gomock.Any()).Return(int64(0), testErr)
+		Expect(WriteMessage(str, []byte("foobar"))).To(MatchError(testErr))
+	})
+
+	It("reads messages", func() {
+		r := NewReader(newStream(encode([]byte("foo"), nil, bytes.Repeat([]byte("b"), 1000))), 0)
+		msg, err := r.ReadMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal([]byte("foo")))
+		msg, err = r.ReadMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(BeEmpty())
+		msg, err = r.ReadMessage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal(bytes.Repeat([]byte("b"), 1000)))
+		_, err = r.ReadMessage()
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("reads a single message", func() {
+		msg, err := ReadMessage(newStream(encode([]byte("foobar"))), 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal([]byte("foobar")))
+	})
+
+	It("errors when the stream ends in the middle of the length prefix", func() {
+		_, err := ReadMessage(newStream([]byte{0x80, 0x1}), 0)
+		Expect(err).To(Equal(io.ErrUnexpectedEOF))
+	})
+
+	It("errors when the stream ends in the middle of a message", func() {
+		data := encode([]byte("foobar"))
+		_, err := ReadMessage(newStream(data[:len(data)-1]), 0)
+		Expect(err).To(Equal(io.ErrUnexpectedEOF))
+	})
+
+	It("errors when a message is too large, without consuming it", func() {
+		str := newStream(encode([]byte("foobar")))
+		_, err := ReadMessage(str, 5)
+		Expect(err).To(MatchError(ErrMessageTooLarge))
+		msg, err := ReadMessage(str, 6)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(msg).To(Equal([]byte("foobar")))
+	})
+})
@@ -0,0 +1,368 @@
+// Package quicpool implements a pool of client sessions.
+//
+// Sessions are pooled by the remote address and the ALPN.
+// Applications open streams using the Pool, which dials a new session when no usable session is in the pool.
+// Concurrent calls for the same address and ALPN wait for the same dial,
+// instead of dialing one session each.
+//
+// Sessions are retired when they reach the maximum age or the maximum number of streams.
+// Retired sessions are not used for new streams.
+// They are not closed by the pool, since streams opened on them might still be in use,
+// and are closed when their idle timeout expires (or when the Pool is closed).
+// Sessions that fail a health check are not usable any more, and are closed immediately.
+//
+// Sessions are keyed by the address used for dialing, not by the address of the peer.
+// This way, a session is still found after the server changed its address.
+package quicpool
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// DefaultHealthCheckInterval is the default interval between two health checks of a session.
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// ErrPoolClosed is returned when using a Pool after it was closed.
+var ErrPoolClosed = errors.New("quicpool: pool closed")
+
+var errHandshakeFailed = errors.New("quicpool: handshake failed")
+
+type key struct {
+	addr, alpn string
+}
+
+type pooledSession struct {
+	key     key
+	sess    quic.EarlySession
+	created time.Time
+	streams int
+	retired bool
+}
+
+// dialCall is a dial in progress.
+type dialCall struct {
+	done chan struct{}
+}
+
+// A Pool is a pool of client sessions.
+// Its methods can be called concurrently.
+// The zero value is a Pool without any limits.
+type Pool struct {
+	// TLSConfig is the TLS configuration used for dialing.
+	// The NextProtos are set to the ALPN requested when opening a stream.
+	TLSConfig *tls.Config
+	// QuicConfig is the QUIC configuration used for dialing.
+	QuicConfig *quic.Config
+	// Allow0RTT enables 0-RTT.
+	// Streams can be opened before the handshake completes.
+	// If the server rejects 0-RTT, these streams are reset, and writing to them fails with quic.Err0RTTRejected.
+	// Streams are then opened after the handshake completed.
+	Allow0RTT bool
+
+	// MaxAge is the maximum age of a session.
+	// Older sessions are not used for new streams.
+	// If zero, the age of sessions is not limited.
+	MaxAge time.Duration
+	// MaxStreams is the maximum number of streams opened on a session.
+	// If zero, the number of streams is only limited by the peer's stream limit.
+	MaxStreams int
+
+	// HealthCheck checks if a session is still usable, e.g. by sending a request on a new stream.
+	// It is called periodically for every session that completed the handshake.
+	// Sessions failing the health check are closed.
+	// If nil, sessions are only removed from the pool when they are closed.
+	HealthCheck func(context.Context, quic.Session) error
+	// HealthCheckInterval is the interval between two health checks of a session.
+	// The context passed to the HealthCheck expires after this interval.
+	// If zero, DefaultHealthCheckInterval is used.
+	HealthCheckInterval time.Duration
+
+	dial func(ctx context.Context, addr string, tlsConf *tls.Config, conf *quic.Config) (quic.EarlySession, error) // only set for testing
+
+	mutex    sync.Mutex
+	sessions map[key][]*pooledSession
+	dialing  map[key]*dialCall
+	all      map[*pooledSession]struct{} // all sessions, including retired sessions
+	closed   bool
+
+	healthCheckOnce sync.Once
+	closeChan       chan struct{}
+}
+
+// OpenStreamSync opens a new bidirectional stream to the server at addr, using the given ALPN.
+// It reuses a session from the pool, or dials a new session if no usable session exists.
+func (p *Pool) OpenStreamSync(ctx context.Context, addr, alpn string) (quic.Stream, error) {
+	var str quic.Stream
+	err := p.openStream(ctx, key{addr: addr, alpn: alpn}, func(sess quic.Session) error {
+		var err error
+		str, err = sess.OpenStreamSync(ctx)
+		return err
+	})
+	return str, err
+}
+
+// OpenUniStreamSync opens a new unidirectional stream to the server at addr, using the given ALPN.
+// It reuses a session from the pool, or dials a new session if no usable session exists.
+func (p *Pool) OpenUniStreamSync(ctx context.Context, addr, alpn string) (quic.SendStream, error) {
+	var str quic.SendStream
+	err := p.openStream(ctx, key{addr: addr, alpn: alpn}, func(sess quic.Session) error {
+		var err error
+		str, err = sess.OpenUniStreamSync(ctx)
+		return err
+	})
+	return str, err
+}
+
+func (p *Pool) openStream(ctx context.Context, k key, open func(quic.Session) error) error {
+	// If the session was closed just before opening the stream, try again with a new session.
+	for i := 0; ; i++ {
+		ps, err := p.getSession(ctx, k)
+		if err != nil {
+			return err
+		}
+		err = open(ps.sess)
+		if errors.Is(err, quic.Err0RTTRejected) {
+			// Wait for the handshake to complete, and open the stream on the 1-RTT session.
+			if err = waitForHandshake(ctx, ps.sess); err == nil {
+				err = open(ps.sess.NextSession())
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		// The stream wasn't opened, so it doesn't count towards the stream limit of the session.
+		p.mutex.Lock()
+		ps.streams--
+		p.mutex.Unlock()
+		if i > 0 || ps.sess.Context().Err() == nil {
+			return err
+		}
+		p.remove(ps)
+	}
+}
+
+// getSession returns a usable session from the pool, or dials a new session.
+func (p *Pool) getSession(ctx context.Context, k key) (*pooledSession, error) {
+	for {
+		p.mutex.Lock()
+		if p.closed {
+			p.mutex.Unlock()
+			return nil, ErrPoolClosed
+		}
+		if ps := p.findSessionLocked(k); ps != nil {
+			ps.streams++
+			p.mutex.Unlock()
+			return ps, nil
+		}
+		if call, ok := p.dialing[k]; ok {
+			p.mutex.Unlock()
+			select {
+			case <-call.done:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		call := &dialCall{done: make(chan struct{})}
+		if p.dialing == nil {
+			p.dialing = make(map[key]*dialCall)
+		}
+		p.dialing[k] = call
+		p.mutex.Unlock()
+
+		sess, err := p.dialSession(ctx, k)
+
+		p.mutex.Lock()
+		delete(p.dialing, k)
+		close(call.done)
+		if err != nil {
+			p.mutex.Unlock()
+			return nil, err
+		}
+		if p.closed {
+			p.mutex.Unlock()
+			sess.CloseWithError(0, "")
+			return nil, ErrPoolClosed
+		}
+		ps := &pooledSession{key: k, sess: sess, created: time.Now(), streams: 1}
+		p.addLocked(ps)
+		p.mutex.Unlock()
+		return ps, nil
+	}
+}
+
+func (p *Pool) dialSession(ctx context.Context, k key) (quic.EarlySession, error) {
+	var tlsConf *tls.Config
+	if p.TLSConfig == nil {
+		tlsConf = &tls.Config{}
+	} else {
+		tlsConf = p.TLSConfig.Clone()
+	}
+	tlsConf.NextProtos = []string{k.alpn}
+	dial := p.dial
+	if dial == nil {
+		dial = quic.DialAddrEarlyContext
+	}
+	sess, err := dial(ctx, k.addr, tlsConf, p.QuicConfig)
+	if err != nil {
+		return nil, err
+	}
+	if p.Allow0RTT {
+		return sess, nil
+	}
+	if err := waitForHandshake(ctx, sess); err != nil {
+		sess.CloseWithError(0, "")
+		return nil, err
+	}
+	return sess, nil
+}
+
+// waitForHandshake waits for the handshake to complete.
+// The HandshakeComplete context is not canceled if the handshake fails,
+// so we also need to wait for the session to be closed.
+func waitForHandshake(ctx context.Context, sess quic.EarlySession) error {
+	select {
+	case <-sess.HandshakeComplete().Done():
+		return nil
+	case <-sess.Context().Done():
+		return errHandshakeFailed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// findSessionLocked returns a usable session.
+// Sessions that are not usable any more are retired.
+func (p *Pool) findSessionLocked(k key) *pooledSession {
+	now := time.Now()
+	sessions := p.sessions[k]
+	for _, ps := range sessions {
+		if ps.sess.Context().Err() != nil ||
+			(p.MaxAge > 0 && now.Sub(ps.created) >= p.MaxAge) ||
+			(p.MaxStreams > 0 && ps.streams >= p.MaxStreams) {
+			p.retireLocked(ps)
+			continue
+		}
+		return ps
+	}
+	return nil
+}
+
+func (p *Pool) addLocked(ps *pooledSession) {
+	if p.sessions == nil {
+		p.sessions = make(map[key][]*pooledSession)
+		p.all = make(map[*pooledSession]struct{})
+		p.closeChan = make(chan struct{})
+	}
+	p.sessions[ps.key] = append(p.sessions[ps.key], ps)
+	p.all[ps] = struct{}{}
+	go func() {
+		<-ps.sess.Context().Done()
+		p.remove(ps)
+	}()
+	if p.HealthCheck != nil {
+		p.healthCheckOnce.Do(func() { go p.runHealthChecks(p.closeChan) })
+	}
+}
+
+// retireLocked removes a session from the pool, such that it is not used for new streams.
+func (p *Pool) retireLocked(ps *pooledSession) {
+	if ps.retired {
+		return
+	}
+	ps.retired = true
+	sessions := p.sessions[ps.key]
+	for i, s := range sessions {
+		if s == ps {
+			sessions = append(sessions[:i], sessions[i+1:]...)
+			break
+		}
+	}
+	if len(sessions) == 0 {
+		delete(p.sessions, ps.key)
+	} else {
+		p.sessions[ps.key] = sessions
+	}
+}
+
+// remove removes a session that was closed.
+func (p *Pool) remove(ps *pooledSession) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.retireLocked(ps)
+	delete(p.all, ps)
+}
+
+func (p *Pool) runHealthChecks(closeChan <-chan struct{}) {
+	interval := p.HealthCheckInterval
+	if interval == 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-closeChan:
+			return
+		case <-ticker.C:
+		}
+		p.mutex.Lock()
+		var sessions []*pooledSession
+		for _, s := range p.sessions {
+			sessions = append(sessions, s...)
+		}
+		p.mutex.Unlock()
+
+		var wg sync.WaitGroup
+		for _, ps := range sessions {
+			select {
+			case <-ps.sess.HandshakeComplete().Done():
+			default:
+				continue
+			}
+			wg.Add(1)
+			go func(ps *pooledSession) {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(ps.sess.Context(), interval)
+				defer cancel()
+				if err := p.HealthCheck(ctx, ps.sess); err != nil {
+					p.mutex.Lock()
+					p.retireLocked(ps)
+					p.mutex.Unlock()
+					ps.sess.CloseWithError(0, "")
+				}
+			}(ps)
+		}
+		wg.Wait()
+	}
+}
+
+// Close closes all sessions, including the retired sessions.
+// Subsequent attempts to open streams fail with ErrPoolClosed.
+func (p *Pool) Close() error {
+	p.mutex.Lock()
+	if p.closed {
+		p.mutex.Unlock()
+		return nil
+	}
+	p.closed = true
+	if p.closeChan != nil {
+		close(p.closeChan)
+	}
+	all := p.all
+	p.all = nil
+	p.sessions = nil
+	p.mutex.Unlock()
+
+	for ps := range all {
+		ps.sess.CloseWithError(0, "")
+	}
+	return nil
+}
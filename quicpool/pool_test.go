@@ -0,0 +1,332 @@
+package quicpool
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type dialArgs struct {
+	addr    string
+	tlsConf *tls.Config
+}
+
+var _ = Describe("Pool", func() {
+	var (
+		pool     *Pool
+		dials    chan dialArgs
+		sessions chan quic.EarlySession
+	)
+
+	BeforeEach(func() {
+		dials = make(chan dialArgs, 10)
+		sessions = make(chan quic.EarlySession, 10)
+		pool = &Pool{}
+		pool.dial = func(ctx context.Context, addr string, tlsConf *tls.Config, _ *quic.Config) (quic.EarlySession, error) {
+			dials <- dialArgs{addr: addr, tlsConf: tlsConf}
+			select {
+			case sess := <-sessions:
+				return sess, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	})
+
+	completedHandshake := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+
+	// newSession creates a new session that has completed the handshake.
+	// The session is closed when the returned function is called.
+	newSession := func() (*mockquic.MockEarlySession, context.CancelFunc) {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		sess.EXPECT().Context().Return(ctx).AnyTimes()
+		sess.EXPECT().HandshakeComplete().Return(completedHandshake()).AnyTimes()
+		sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any()).Do(func(quic.ApplicationErrorCode, string) { cancel() }).AnyTimes()
+		return sess, cancel
+	}
+
+	expectOpenStream := func(sess *mockquic.MockEarlySession) *mockquic.MockStream {
+		str := mockquic.NewMockStream(mockCtrl)
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		return str
+	}
+
+	It("reuses sessions", func() {
+		sess, _ := newSession()
+		sessions <- sess
+		str1 := expectOpenStream(sess)
+		str2 := expectOpenStream(sess)
+		str, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str).To(Equal(str1))
+		str, err = pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str).To(Equal(str2))
+		Expect(dials).To(HaveLen(1))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("opens unidirectional streams", func() {
+		sess, _ := newSession()
+		sessions <- sess
+		str := mockquic.NewMockStream(mockCtrl)
+		sess.EXPECT().OpenUniStreamSync(gomock.Any()).Return(str, nil)
+		s, err := pool.OpenUniStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("uses different sessions for different addresses and ALPNs", func() {
+		pool.TLSConfig = &tls.Config{ServerName: "example.com", NextProtos: []string{"foobar"}}
+		for i := 0; i < 3; i++ {
+			sess, _ := newSession()
+			expectOpenStream(sess)
+			sessions <- sess
+		}
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto1")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = pool.OpenStreamSync(context.Background(), "localhost:443", "proto2")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = pool.OpenStreamSync(context.Background(), "localhost:1337", "proto1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dials).To(HaveLen(3))
+		var d dialArgs
+		Expect(dials).To(Receive(&d))
+		Expect(d.addr).To(Equal("localhost:443"))
+		Expect(d.tlsConf.ServerName).To(Equal("example.com"))
+		Expect(d.tlsConf.NextProtos).To(Equal([]string{"proto1"}))
+		Expect(dials).To(Receive(&d))
+		Expect(d.addr).To(Equal("localhost:443"))
+		Expect(d.tlsConf.NextProtos).To(Equal([]string{"proto2"}))
+		Expect(dials).To(Receive(&d))
+		Expect(d.addr).To(Equal("localhost:1337"))
+		Expect(d.tlsConf.NextProtos).To(Equal([]string{"proto1"}))
+		Expect(pool.TLSConfig.NextProtos).To(Equal([]string{"foobar"}))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("only dials a single session when opening streams concurrently", func() {
+		sess, _ := newSession()
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(mockquic.NewMockStream(mockCtrl), nil).Times(3)
+		var opened int32
+		for i := 0; i < 3; i++ {
+			go func() {
+				defer GinkgoRecover()
+				_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+				Expect(err).ToNot(HaveOccurred())
+				atomic.AddInt32(&opened, 1)
+			}()
+		}
+		Eventually(dials).Should(HaveLen(1))
+		Consistently(dials).Should(HaveLen(1))
+		sessions <- sess
+		Eventually(func() int32 { return atomic.LoadInt32(&opened) }).Should(BeEquivalentTo(3))
+		Expect(dials).To(HaveLen(1))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("returns dial errors", func() {
+		testErr := errors.New("test error")
+		pool.dial = func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlySession, error) {
+			return nil, testErr
+		}
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("waits for the handshake to complete", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		handshakeCtx, handshakeComplete := context.WithCancel(context.Background())
+		sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+		sess.EXPECT().HandshakeComplete().Return(handshakeCtx).AnyTimes()
+		sessions <- sess
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+			Expect(err).ToNot(HaveOccurred())
+		}()
+		Consistently(done).ShouldNot(BeClosed())
+		expectOpenStream(sess)
+		handshakeComplete()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("returns an error when the handshake fails", func() {
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		sess.EXPECT().Context().Return(ctx).AnyTimes()
+		sess.EXPECT().HandshakeComplete().Return(context.Background()).AnyTimes()
+		sess.EXPECT().CloseWithError(gomock.Any(), gomock.Any())
+		sessions <- sess
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).To(MatchError("quicpool: handshake failed"))
+	})
+
+	It("opens streams before the handshake completes, if 0-RTT is allowed", func() {
+		pool.Allow0RTT = true
+		sess := mockquic.NewMockEarlySession(mockCtrl)
+		sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+		sess.EXPECT().HandshakeComplete().Return(context.Background()).AnyTimes()
+		sessions <- sess
+		expectOpenStream(sess)
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("opens the stream again when 0-RTT is rejected", func() {
+		pool.Allow0RTT = true
+		sess, _ := newSession()
+		sessions <- sess
+		str := mockquic.NewMockStream(mockCtrl)
+		gomock.InOrder(
+			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, quic.Err0RTTRejected),
+			sess.EXPECT().NextSession().Return(sess),
+			sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil),
+		)
+		s, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("retires sessions that reached the maximum number of streams", func() {
+		pool.MaxStreams = 2
+		sess1, _ := newSession()
+		sess2, _ := newSession()
+		sessions <- sess1
+		sessions <- sess2
+		expectOpenStream(sess1)
+		expectOpenStream(sess1)
+		expectOpenStream(sess2)
+		for i := 0; i < 3; i++ {
+			_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(dials).To(HaveLen(2))
+		// retired sessions are closed when the pool is closed
+		Expect(pool.Close()).To(Succeed())
+		Eventually(sess1.Context().Done()).Should(BeClosed())
+		Eventually(sess2.Context().Done()).Should(BeClosed())
+	})
+
+	It("doesn't count streams that failed to open towards the maximum number of streams", func() {
+		pool.MaxStreams = 1
+		sess, _ := newSession()
+		sessions <- sess
+		testErr := errors.New("stream open error")
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+		str := expectOpenStream(sess)
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).To(MatchError(testErr))
+		s, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+		Expect(dials).To(HaveLen(1))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("retires sessions that reached the maximum age", func() {
+		pool.MaxAge = 50 * time.Millisecond
+		sess1, _ := newSession()
+		sess2, _ := newSession()
+		sessions <- sess1
+		sessions <- sess2
+		expectOpenStream(sess1)
+		expectOpenStream(sess1)
+		expectOpenStream(sess2)
+		for i := 0; i < 2; i++ {
+			_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(dials).To(HaveLen(1))
+		time.Sleep(pool.MaxAge)
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dials).To(HaveLen(2))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("dials a new session when a session is closed", func() {
+		sess1, closeSess1 := newSession()
+		sess2, _ := newSession()
+		sessions <- sess1
+		sessions <- sess2
+		expectOpenStream(sess1)
+		expectOpenStream(sess2)
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		closeSess1()
+		_, err = pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dials).To(HaveLen(2))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("retries with a new session when the session is closed while opening the stream", func() {
+		sess1, closeSess1 := newSession()
+		sess2, _ := newSession()
+		sessions <- sess1
+		sessions <- sess2
+		sess1.EXPECT().OpenStreamSync(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+			closeSess1()
+			return nil, errors.New("session closed")
+		})
+		str := expectOpenStream(sess2)
+		s, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("closes sessions that fail the health check", func() {
+		pool.HealthCheckInterval = 20 * time.Millisecond
+		sess, _ := newSession()
+		var healthy int32 = 1
+		pool.HealthCheck = func(ctx context.Context, s quic.Session) error {
+			defer GinkgoRecover()
+			Expect(s).To(Equal(sess))
+			if atomic.LoadInt32(&healthy) == 1 {
+				return nil
+			}
+			return errors.New("unhealthy")
+		}
+		sessions <- sess
+		expectOpenStream(sess)
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(sess.Context().Done()).ShouldNot(BeClosed())
+		atomic.StoreInt32(&healthy, 0)
+		Eventually(sess.Context().Done()).Should(BeClosed())
+		Expect(pool.Close()).To(Succeed())
+	})
+
+	It("errors after the pool was closed", func() {
+		sess, _ := newSession()
+		sessions <- sess
+		expectOpenStream(sess)
+		_, err := pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pool.Close()).To(Succeed())
+		Eventually(sess.Context().Done()).Should(BeClosed())
+		_, err = pool.OpenStreamSync(context.Background(), "localhost:443", "proto")
+		Expect(err).To(MatchError(ErrPoolClosed))
+	})
+})
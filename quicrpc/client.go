@@ -0,0 +1,69 @@
+package quicrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// A Client sends requests on a QUIC session.
+type Client struct {
+	// Session is the session that requests are sent on.
+	Session quic.Session
+
+	// Timeout is the maximum duration of a request, including reading the response.
+	// If zero, the request is only limited by the context passed to Call.
+	Timeout time.Duration
+
+	// MaxResponseSize is the maximum size of a response.
+	// If zero, DefaultMaxMessageSize is used.
+	MaxResponseSize int
+}
+
+// Call sends a request on a new stream and waits for the response.
+// When the context is canceled (or the Timeout expires), the stream is canceled
+// and the context's error is returned.
+func (c *Client) Call(ctx context.Context, req []byte) ([]byte, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	str, err := c.Session.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stop := cancelOnDone(ctx, str)
+	defer stop()
+
+	if _, err := str.Write(req); err != nil {
+		str.CancelWrite(ErrorCodeCanceled)
+		str.CancelRead(ErrorCodeCanceled)
+		return nil, c.wrapError(ctx, err)
+	}
+	if err := str.Close(); err != nil {
+		str.CancelRead(ErrorCodeCanceled)
+		return nil, c.wrapError(ctx, err)
+	}
+	resp, err := readMessage(str, getMaxMessageSize(c.MaxResponseSize))
+	if err != nil {
+		if errors.Is(err, ErrMessageTooLarge) {
+			str.CancelRead(ErrorCodeMessageTooLarge)
+		} else {
+			str.CancelRead(ErrorCodeCanceled)
+		}
+		return nil, c.wrapError(ctx, err)
+	}
+	return resp, nil
+}
+
+// wrapError returns the context's error if the context was canceled.
+// In that case, err is just the result of canceling the stream.
+func (c *Client) wrapError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
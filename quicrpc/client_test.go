@@ -0,0 +1,89 @@
+package quicrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		client *Client
+		sess   *mockquic.MockEarlySession
+		str    *mockquic.MockStream
+	)
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		str = mockquic.NewMockStream(mockCtrl)
+		client = &Client{Session: sess}
+	})
+
+	It("sends a request and reads the response", func() {
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		resp := bytes.NewReader([]byte("response"))
+		gomock.InOrder(
+			str.EXPECT().Write([]byte("request")).Return(7, nil),
+			str.EXPECT().Close(),
+			str.EXPECT().Read(gomock.Any()).DoAndReturn(resp.Read).AnyTimes(),
+		)
+		data, err := client.Call(context.Background(), []byte("request"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("response")))
+	})
+
+	It("returns errors when opening the stream fails", func() {
+		testErr := errors.New("test error")
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(nil, testErr)
+		_, err := client.Call(context.Background(), []byte("request"))
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("cancels the stream when writing fails", func() {
+		testErr := errors.New("test error")
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		str.EXPECT().Write(gomock.Any()).Return(0, testErr)
+		str.EXPECT().CancelWrite(ErrorCodeCanceled)
+		str.EXPECT().CancelRead(ErrorCodeCanceled)
+		_, err := client.Call(context.Background(), []byte("request"))
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("errors when the response is too large", func() {
+		client.MaxResponseSize = 5
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		resp := bytes.NewReader([]byte("response"))
+		str.EXPECT().Write(gomock.Any()).Return(7, nil)
+		str.EXPECT().Close()
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(resp.Read).AnyTimes()
+		str.EXPECT().CancelRead(ErrorCodeMessageTooLarge)
+		_, err := client.Call(context.Background(), []byte("request"))
+		Expect(err).To(MatchError(ErrMessageTooLarge))
+	})
+
+	It("cancels the stream when the timeout expires", func() {
+		client.Timeout = 50 * time.Millisecond
+		sess.EXPECT().OpenStreamSync(gomock.Any()).Return(str, nil)
+		canceled := make(chan struct{})
+		str.EXPECT().Write(gomock.Any()).Return(7, nil)
+		str.EXPECT().Close()
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+			<-canceled
+			return 0, errors.New("canceled")
+		})
+		str.EXPECT().CancelWrite(ErrorCodeCanceled)
+		str.EXPECT().CancelRead(ErrorCodeCanceled).Do(func(quic.StreamErrorCode) { close(canceled) })
+		// canceling the stream after the read error
+		str.EXPECT().CancelRead(ErrorCodeCanceled)
+		_, err := client.Call(context.Background(), []byte("request"))
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+})
@@ -0,0 +1,80 @@
+// Package quicrpc implements a simple request / response protocol on top of QUIC.
+//
+// Every request is sent on a new bidirectional stream. The client writes the request
+// and closes the send direction of the stream. The server reads the request until
+// the end of the stream, writes the response and closes its send direction.
+// On errors, both directions of the stream are canceled, such that the peer doesn't
+// wait for data that will never arrive.
+package quicrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// DefaultMaxMessageSize is the default maximum size of a request or a response.
+const DefaultMaxMessageSize = 1 << 20
+
+const (
+	// ErrorCodeCanceled is used to cancel a stream when a request is aborted,
+	// e.g. because the context was canceled.
+	ErrorCodeCanceled quic.StreamErrorCode = 0x1
+	// ErrorCodeMessageTooLarge is used to cancel a stream when a message exceeds the maximum size.
+	ErrorCodeMessageTooLarge quic.StreamErrorCode = 0x2
+	// ErrorCodeHandlerError is used by the server to cancel a stream when the handler returned an error.
+	ErrorCodeHandlerError quic.StreamErrorCode = 0x3
+)
+
+// ErrMessageTooLarge is returned when a request or a response exceeds the maximum size.
+var ErrMessageTooLarge = errors.New("message too large")
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return &bytes.Buffer{} },
+}
+
+// readMessage reads from r until io.EOF.
+func readMessage(r io.Reader, maxSize int) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		bufferPool.Put(buf)
+	}()
+
+	if _, err := buf.ReadFrom(io.LimitReader(r, int64(maxSize)+1)); err != nil {
+		return nil, err
+	}
+	if buf.Len() > maxSize {
+		return nil, ErrMessageTooLarge
+	}
+	msg := make([]byte, buf.Len())
+	copy(msg, buf.Bytes())
+	return msg, nil
+}
+
+// cancelOnDone cancels both directions of the stream when the context is canceled.
+// This unblocks pending Read and Write calls.
+// The returned function must be called when the stream is not used any more.
+func cancelOnDone(ctx context.Context, str quic.Stream) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			str.CancelWrite(ErrorCodeCanceled)
+			str.CancelRead(ErrorCodeCanceled)
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+func getMaxMessageSize(size int) int {
+	if size == 0 {
+		return DefaultMaxMessageSize
+	}
+	return size
+}
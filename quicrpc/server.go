@@ -0,0 +1,72 @@
+package quicrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// A Handler handles a request and returns the response.
+// If it returns an error, the stream is canceled with ErrorCodeHandlerError.
+type Handler func(ctx context.Context, req []byte) ([]byte, error)
+
+// A Server serves requests received on a QUIC session.
+type Server struct {
+	// Handler is called for every request.
+	// It is called concurrently for different requests.
+	Handler Handler
+
+	// Timeout is the maximum duration for reading a request, handling it and writing the response.
+	// If zero, the request is only limited by the lifetime of the session.
+	Timeout time.Duration
+
+	// MaxRequestSize is the maximum size of a request.
+	// If zero, DefaultMaxMessageSize is used.
+	MaxRequestSize int
+}
+
+// Serve accepts streams on the session and serves the requests sent on them.
+// It returns when the session is closed.
+func (s *Server) Serve(sess quic.Session) error {
+	ctx := sess.Context()
+	for {
+		str, err := sess.AcceptStream(ctx)
+		if err != nil {
+			return err
+		}
+		go s.handleStream(ctx, str)
+	}
+}
+
+func (s *Server) handleStream(ctx context.Context, str quic.Stream) {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	stop := cancelOnDone(ctx, str)
+	defer stop()
+
+	req, err := readMessage(str, getMaxMessageSize(s.MaxRequestSize))
+	if err != nil {
+		errorCode := ErrorCodeCanceled
+		if errors.Is(err, ErrMessageTooLarge) {
+			errorCode = ErrorCodeMessageTooLarge
+		}
+		str.CancelRead(errorCode)
+		str.CancelWrite(errorCode)
+		return
+	}
+	resp, err := s.Handler(ctx, req)
+	if err != nil {
+		str.CancelWrite(ErrorCodeHandlerError)
+		return
+	}
+	if _, err := str.Write(resp); err != nil {
+		str.CancelWrite(ErrorCodeCanceled)
+		return
+	}
+	str.Close()
+}
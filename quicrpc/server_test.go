@@ -0,0 +1,90 @@
+package quicrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Server", func() {
+	var (
+		sess   *mockquic.MockEarlySession
+		str    *mockquic.MockStream
+		closed chan struct{}
+		done   chan struct{}
+	)
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		str = mockquic.NewMockStream(mockCtrl)
+		closed = make(chan struct{})
+		done = make(chan struct{})
+		sess.EXPECT().Context().Return(context.Background())
+		gomock.InOrder(
+			sess.EXPECT().AcceptStream(gomock.Any()).Return(str, nil),
+			sess.EXPECT().AcceptStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.Stream, error) {
+				<-closed
+				return nil, errors.New("session closed")
+			}),
+		)
+	})
+
+	AfterEach(func() {
+		close(closed)
+		Eventually(done).Should(BeClosed())
+	})
+
+	serve := func(s *Server, req []byte) {
+		r := bytes.NewReader(req)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(s.Serve(sess)).To(MatchError("session closed"))
+		}()
+	}
+
+	It("handles requests", func() {
+		handled := make(chan struct{})
+		str.EXPECT().Write([]byte("response")).Return(8, nil)
+		str.EXPECT().Close().Do(func() { close(handled) })
+		s := &Server{Handler: func(_ context.Context, req []byte) ([]byte, error) {
+			Expect(req).To(Equal([]byte("request")))
+			return []byte("response"), nil
+		}}
+		serve(s, []byte("request"))
+		Eventually(handled).Should(BeClosed())
+	})
+
+	It("cancels the stream when the handler errors", func() {
+		handled := make(chan struct{})
+		str.EXPECT().CancelWrite(ErrorCodeHandlerError).Do(func(quic.StreamErrorCode) { close(handled) })
+		s := &Server{Handler: func(context.Context, []byte) ([]byte, error) {
+			return nil, errors.New("handler error")
+		}}
+		serve(s, []byte("request"))
+		Eventually(handled).Should(BeClosed())
+	})
+
+	It("rejects requests that are too large", func() {
+		handled := make(chan struct{})
+		str.EXPECT().CancelRead(ErrorCodeMessageTooLarge)
+		str.EXPECT().CancelWrite(ErrorCodeMessageTooLarge).Do(func(quic.StreamErrorCode) { close(handled) })
+		s := &Server{
+			MaxRequestSize: 5,
+			Handler: func(context.Context, []byte) ([]byte, error) {
+				Fail("handler should not be called")
+				return nil, nil
+			},
+		}
+		serve(s, []byte("request"))
+		Eventually(handled).Should(BeClosed())
+	})
+})
@@ -0,0 +1,165 @@
+// Package quicsplice copies data between a QUIC stream and a TCP connection,
+// as needed by gateways that translate between protocols running on QUIC and on TCP,
+// e.g. from HTTP/3 to HTTP/1.1.
+//
+// Both directions are copied concurrently, and the end of each direction is propagated:
+// The FIN of the QUIC stream is translated into a TCP half-close (using CloseWrite), and vice versa.
+// Cancellations are propagated as well, as far as TCP allows:
+//
+//   - If the peer stops the QUIC stream (STOP_SENDING), the read side of the TCP connection is closed.
+//   - Any other error in either direction (e.g. the peer resetting the QUIC stream, the session being closed,
+//     or reading from or writing to the TCP connection failing) aborts both directions:
+//     The QUIC stream is canceled (RESET_STREAM and STOP_SENDING), and the TCP connection is aborted
+//     (using a TCP RST, if possible).
+package quicsplice
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// Stats are the number of bytes copied in both directions.
+type Stats struct {
+	// StreamToConn is the number of bytes copied from the QUIC stream to the TCP connection.
+	StreamToConn int64
+	// ConnToStream is the number of bytes copied from the TCP connection to the QUIC stream.
+	ConnToStream int64
+}
+
+// Splice copies data between the stream and the connection in both directions,
+// until both directions were closed or an error occurred. The connection is closed when Splice returns.
+// errorCode is used to cancel the stream when an error occurs.
+//
+// Data received on the stream is written to the connection using vectored I/O (writev) where possible,
+// directly from the frames received, without copying it into an intermediate buffer first.
+// Data read from the connection is read directly into the STREAM frames sent on the stream.
+//
+// Splice returns the first error that occurred, or nil if both directions were closed successfully.
+func Splice(str quic.Stream, conn net.Conn, errorCode quic.StreamErrorCode) (Stats, error) {
+	s := &splicer{str: str, conn: conn, errorCode: errorCode}
+	var stats Stats
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stats.StreamToConn = s.copyStreamToConn()
+	}()
+	go func() {
+		defer wg.Done()
+		stats.ConnToStream = s.copyConnToStream()
+	}()
+	wg.Wait()
+	conn.Close()
+	return stats, s.err
+}
+
+type splicer struct {
+	str       quic.Stream
+	conn      net.Conn
+	errorCode quic.StreamErrorCode
+
+	abortOnce sync.Once
+
+	mutex sync.Mutex
+	err   error // the first error that occurred
+}
+
+func (s *splicer) setError(err error) {
+	s.mutex.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mutex.Unlock()
+}
+
+func (s *splicer) copyStreamToConn() int64 {
+	var written int64
+	for {
+		bufs, release, err := s.str.ReadBuffers()
+		if len(bufs) > 0 {
+			// net.Buffers uses writev for TCP connections.
+			b := net.Buffers(bufs)
+			n, werr := b.WriteTo(s.conn)
+			written += n
+			if werr != nil {
+				release()
+				s.abort(werr)
+				return written
+			}
+		}
+		release()
+		if err == io.EOF {
+			if c, ok := s.conn.(interface{ CloseWrite() error }); ok {
+				if err := c.CloseWrite(); err != nil {
+					s.setError(err)
+				}
+			}
+			return written
+		}
+		if err != nil {
+			s.abort(err)
+			return written
+		}
+	}
+}
+
+func (s *splicer) copyConnToStream() int64 {
+	r := &connReader{conn: s.conn}
+	var n int64
+	var err error
+	// The streams returned by a session read directly into the STREAM frames.
+	if rf, ok := s.str.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(struct{ io.Writer }{s.str}, r)
+	}
+	if err == nil {
+		if err := s.str.Close(); err != nil {
+			s.setError(err)
+		}
+		return n
+	}
+	var stopSendingErr *quic.StopSendingError
+	if r.err == nil && errors.As(err, &stopSendingErr) {
+		if c, ok := s.conn.(interface{ CloseRead() error }); ok {
+			s.setError(err)
+			c.CloseRead()
+			return n
+		}
+	}
+	s.abort(err)
+	return n
+}
+
+// abort cancels both directions of the stream, and closes the connection,
+// sending a TCP RST instead of a FIN, if possible.
+// This unblocks the goroutine copying the other direction.
+func (s *splicer) abort(err error) {
+	s.setError(err)
+	s.abortOnce.Do(func() {
+		s.str.CancelRead(s.errorCode)
+		s.str.CancelWrite(s.errorCode)
+		if c, ok := s.conn.(interface{ SetLinger(int) error }); ok {
+			c.SetLinger(0)
+		}
+		s.conn.Close()
+	})
+}
+
+// The connReader records if reading from the connection failed.
+type connReader struct {
+	conn net.Conn
+	err  error
+}
+
+func (r *connReader) Read(p []byte) (int, error) {
+	n, err := r.conn.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,149 @@
+package quicsplice
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Splice", func() {
+	var (
+		str        *mockquic.MockStream
+		conn, peer *net.TCPConn
+	)
+
+	BeforeEach(func() {
+		ln, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		accepted := make(chan *net.TCPConn, 1)
+		go func() {
+			defer GinkgoRecover()
+			c, err := ln.AcceptTCP()
+			Expect(err).ToNot(HaveOccurred())
+			accepted <- c
+		}()
+		conn, err = net.DialTCP("tcp", nil, ln.Addr().(*net.TCPAddr))
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(accepted).Should(Receive(&peer))
+		str = mockquic.NewMockStream(mockCtrl)
+	})
+
+	AfterEach(func() {
+		peer.Close()
+	})
+
+	// expectReadBuffers makes the stream return the given buffers, followed by err
+	expectReadBuffers := func(err error, bufs ...[]byte) {
+		gomock.InOrder(
+			str.EXPECT().ReadBuffers().Return(bufs, func() {}, nil),
+			str.EXPECT().ReadBuffers().Return(nil, func() {}, err),
+		)
+	}
+
+	It("copies data in both directions, and propagates the end of each direction", func() {
+		expectReadBuffers(io.EOF, []byte("foo"), []byte("bar"))
+		var written bytes.Buffer
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(written.Write).AnyTimes()
+		str.EXPECT().Close()
+
+		go func() {
+			defer GinkgoRecover()
+			_, err := peer.Write([]byte("request"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(peer.CloseWrite()).To(Succeed())
+		}()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			stats, err := Splice(str, conn, 42)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(Stats{StreamToConn: 6, ConnToStream: 7}))
+		}()
+		// the FIN of the stream is translated into a TCP half-close
+		data, err := io.ReadAll(peer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+		Eventually(done).Should(BeClosed())
+		Expect(written.String()).To(Equal("request"))
+	})
+
+	It("aborts the TCP connection when the stream is reset", func() {
+		streamErr := &quic.StreamError{StreamID: 4, ErrorCode: 1337}
+		expectReadBuffers(streamErr, []byte("foo"))
+		str.EXPECT().CancelRead(quic.StreamErrorCode(42))
+		str.EXPECT().CancelWrite(quic.StreamErrorCode(42))
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := Splice(str, conn, 42)
+			Expect(err).To(MatchError(streamErr))
+		}()
+		data := make([]byte, 3)
+		_, err := io.ReadFull(peer, data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foo")))
+		_, err = peer.Read([]byte{0})
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(Equal(io.EOF))
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("closes the read side of the TCP connection when the peer stops the stream", func() {
+		stopErr := &quic.StopSendingError{StreamError: quic.StreamError{StreamID: 4, ErrorCode: 1337}}
+		str.EXPECT().Write(gomock.Any()).Return(0, stopErr)
+		expectReadBuffers(io.EOF)
+
+		_, err := peer.Write([]byte("request"))
+		Expect(err).ToNot(HaveOccurred())
+		stats, err := Splice(str, conn, 42)
+		Expect(err).To(MatchError(stopErr))
+		Expect(stats.ConnToStream).To(BeZero())
+	})
+
+	It("aborts both directions when the session is closed", func() {
+		sessErr := errors.New("session closed")
+		str.EXPECT().Write(gomock.Any()).DoAndReturn(func([]byte) (int, error) {
+			return 0, sessErr
+		}).AnyTimes()
+		str.EXPECT().ReadBuffers().Return(nil, func() {}, sessErr)
+		str.EXPECT().CancelRead(quic.StreamErrorCode(42))
+		str.EXPECT().CancelWrite(quic.StreamErrorCode(42))
+
+		// Nothing is sent on the TCP connection, so the goroutine copying to the stream
+		// is blocked in Read, until the connection is closed.
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := Splice(str, conn, 42)
+			Expect(err).To(MatchError(sessErr))
+		}()
+		_, err := peer.Read([]byte{0})
+		Expect(err).To(HaveOccurred())
+		Expect(err).ToNot(Equal(io.EOF))
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("cancels the stream when writing to the TCP connection fails", func() {
+		str.EXPECT().ReadBuffers().DoAndReturn(func() ([][]byte, func(), error) {
+			conn.Close() // make writing fail
+			return [][]byte{[]byte("foo")}, func() {}, nil
+		})
+		str.EXPECT().CancelRead(quic.StreamErrorCode(42))
+		str.EXPECT().CancelWrite(quic.StreamErrorCode(42))
+		_, err := Splice(str, conn, 42)
+		Expect(err).To(MatchError(net.ErrClosed))
+	})
+})
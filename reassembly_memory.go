@@ -0,0 +1,77 @@
+package quic
+
+import (
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+)
+
+// frameSorterEntryOverhead is the memory accounted for every frame queued in a frame sorter,
+// in addition to the capacity of the buffer holding the frame's data.
+// It approximates the size of the map entry and the bookkeeping of the gap list.
+const frameSorterEntryOverhead protocol.ByteCount = 64
+
+// A reassemblyMemory limits the memory held by the frame sorters of the receive streams of a session,
+// see Config.MaxStreamReassemblyMemory and Config.MaxConnectionReassemblyMemory.
+// The memory is accounted by the capacity of the buffers that the frames are kept in,
+// such that a peer can't inflate the memory usage beyond the flow control window by sending many small frames.
+// All methods can be called on a nil reassemblyMemory, which is used when no limit is configured.
+type reassemblyMemory struct {
+	maxStream     protocol.ByteCount
+	maxConnection protocol.ByteCount
+	errorCode     StreamErrorCode
+
+	used int64 // memory held by all frame sorters of the session, to be accessed atomically
+}
+
+func newReassemblyMemory(maxStream, maxConnection uint64, errorCode StreamErrorCode) *reassemblyMemory {
+	if maxStream == 0 && maxConnection == 0 {
+		return nil
+	}
+	return &reassemblyMemory{
+		maxStream:     protocol.ByteCount(maxStream),
+		maxConnection: protocol.ByteCount(maxConnection),
+		errorCode:     errorCode,
+	}
+}
+
+// Add is called by the frame sorters when frames are queued (positive n) or dequeued (negative n).
+func (m *reassemblyMemory) Add(n protocol.ByteCount) {
+	if m == nil {
+		return
+	}
+	atomic.AddInt64(&m.used, int64(n))
+}
+
+// Used returns the memory held by all frame sorters of the session.
+func (m *reassemblyMemory) Used() protocol.ByteCount {
+	if m == nil {
+		return 0
+	}
+	return protocol.ByteCount(atomic.LoadInt64(&m.used))
+}
+
+// StreamLimitExceeded says if a stream's frame sorter holds more memory than allowed.
+func (m *reassemblyMemory) StreamLimitExceeded(memory protocol.ByteCount) bool {
+	return m != nil && m.maxStream > 0 && memory > m.maxStream
+}
+
+// CheckConnectionLimit returns an error if the frame sorters of all streams together hold more memory than allowed.
+func (m *reassemblyMemory) CheckConnectionLimit() error {
+	if m == nil || m.maxConnection == 0 {
+		return nil
+	}
+	used := m.Used()
+	if used <= m.maxConnection {
+		return nil
+	}
+	return &ConnectionReassemblyMemoryError{
+		TransportError: TransportError{
+			ErrorCode:    qerr.InternalError,
+			ErrorMessage: "reassembly memory limit exceeded",
+		},
+		Memory: uint64(used),
+		Limit:  uint64(m.maxConnection),
+	}
+}
@@ -1,6 +1,9 @@
 package quic
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -20,6 +23,7 @@ type receiveStreamI interface {
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	closeForShutdown(error)
 	getWindowUpdate() protocol.ByteCount
+	setFlowControlGroup(*flowcontrol.GroupFlowController)
 }
 
 type receiveStream struct {
@@ -29,14 +33,27 @@ type receiveStream struct {
 
 	sender streamSender
 
-	frameQueue  *frameSorter
+	frameQueue  *frameSorter // nil once the stream is completed
 	finalOffset protocol.ByteCount
 
 	currentFrame       []byte
+	currentFrameOffset protocol.ByteCount
 	currentFrameDone   func()
 	currentFrameIsLast bool // is the currentFrame the last frame on this stream
 	readPosInFrame     int
 
+	// When a RESET_STREAM_AT frame is received, the data up to the reliable size is still delivered to the application.
+	// The stream is reset once this data was read.
+	resetPending bool
+	reliableSize protocol.ByteCount
+	// When CancelReadAfterBuffered or CancelReadAfter is called, the data up to drainOffset is still delivered to the application.
+	// Reading is canceled once this data was read.
+	cancelPending bool
+	drainOffset   protocol.ByteCount
+	// The STOP_SENDING frame is only sent once all data up to the drainOffset was received.
+	stopSendingPending bool
+	cancelErrorCode    StreamErrorCode
+
 	closeForShutdownErr error
 	cancelReadErr       error
 	resetRemotelyErr    *StreamError
@@ -45,17 +62,28 @@ type receiveStream struct {
 	finRead           bool // set once we read a frame with a Fin
 	canceledRead      bool // set when CancelRead() is called
 	resetRemotely     bool // set when HandleResetStreamFrame() is called
+	peerClosed        bool // set when the peerClosedChan is closed
+	completed         bool // set when this stream has been reported to the streamSender as completed
 
-	readChan chan struct{}
-	deadline time.Time
+	readChan       chan struct{}
+	peerClosedChan chan struct{}
+	deadline       time.Time
+	onReadable     func() // set by OnReadable
 
 	flowController flowcontrol.StreamFlowController
-	version        protocol.VersionNumber
+	// idleTimer is nil if Config.StreamIdleTimeout is not set
+	idleTimer *streamIdleTimer
+	// reassemblyMemory is nil if neither Config.MaxStreamReassemblyMemory nor Config.MaxConnectionReassemblyMemory is set
+	reassemblyMemory *reassemblyMemory
+	// errorRegistry is nil for streams that don't belong to a session
+	errorRegistry *StreamErrorRegistry
+	version       protocol.VersionNumber
 }
 
 var (
 	_ ReceiveStream  = &receiveStream{}
 	_ receiveStreamI = &receiveStream{}
+	_ io.WriterTo    = &receiveStream{}
 )
 
 func newReceiveStream(
@@ -70,28 +98,128 @@ func newReceiveStream(
 		flowController: flowController,
 		frameQueue:     newFrameSorter(),
 		readChan:       make(chan struct{}, 1),
+		peerClosedChan: make(chan struct{}),
 		finalOffset:    protocol.MaxByteCount,
 		version:        version,
 	}
 }
 
+// setReassemblyMemory makes the frame sorter account the memory held by the queued frames.
+// It must be called before any frames are received.
+func (s *receiveStream) setReassemblyMemory(m *reassemblyMemory) {
+	s.reassemblyMemory = m
+	s.frameQueue.connMemory = m
+}
+
+func (s *receiveStream) FlowControlState() FlowControlState {
+	return newFlowControlState(s.flowController.State())
+}
+
+func (s *receiveStream) isCompleted() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.completed
+}
+
+func (s *receiveStream) ReadableBytes() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return uint64(s.readableBytes())
+}
+
+func (s *receiveStream) readableBytes() protocol.ByteCount {
+	if s.finRead || s.frameQueue == nil || s.readError() != nil {
+		return 0
+	}
+	available := utils.MinByteCount(s.frameQueue.ContiguousOffset(), s.endOffset())
+	if readOffset := s.readOffset(); available > readOffset {
+		return available - readOffset
+	}
+	return 0
+}
+
+// isReadable says if Read would return without blocking.
+func (s *receiveStream) isReadable() bool {
+	if s.finRead {
+		return false
+	}
+	if s.readError() != nil || s.readableBytes() > 0 {
+		return true
+	}
+	// all data up to the end of the stream was read
+	return s.frameQueue != nil && s.endOffset() != protocol.MaxByteCount && s.frameQueue.ContiguousOffset() >= s.endOffset()
+}
+
+// readableCallback returns the OnReadable callback, if one is set and the stream is readable.
+// It must be called with the mutex held, and the callback must be called after releasing the mutex.
+func (s *receiveStream) readableCallback() func() {
+	if s.onReadable == nil || !s.isReadable() {
+		return nil
+	}
+	return s.onReadable
+}
+
+func (s *receiveStream) OnReadable(cb func()) {
+	s.mutex.Lock()
+	s.onReadable = cb
+	onReadable := s.readableCallback()
+	s.mutex.Unlock()
+
+	if onReadable != nil {
+		onReadable()
+	}
+}
+
+// setFlowControlGroup limits the receive window by the window of a StreamGroup.
+func (s *receiveStream) setFlowControlGroup(g *flowcontrol.GroupFlowController) {
+	s.flowController.SetGroup(g)
+}
+
+func (s *receiveStream) SetReceiveWindow(size uint64) {
+	if size == 0 {
+		return
+	}
+	s.flowController.SetMaxReceiveWindow(protocol.ByteCount(size))
+}
+
+func (s *receiveStream) GrantReceiveCredit(n uint64) {
+	if n > uint64(protocol.MaxByteCount) {
+		n = uint64(protocol.MaxByteCount)
+	}
+	s.flowController.GrantCredit(protocol.ByteCount(n))
+}
+
 func (s *receiveStream) StreamID() protocol.StreamID {
 	return s.streamID
 }
 
 // Read implements io.Reader. It is not thread safe!
 func (s *receiveStream) Read(p []byte) (int, error) {
+	return s.ReadContext(context.Background(), p)
+}
+
+func (s *receiveStream) ReadContext(ctx context.Context, p []byte) (int, error) {
+	return s.read(ctx, p, true)
+}
+
+func (s *receiveStream) TryRead(p []byte) (int, error) {
+	return s.read(context.Background(), p, false)
+}
+
+func (s *receiveStream) read(ctx context.Context, p []byte, block bool) (int, error) {
 	s.mutex.Lock()
-	completed, n, err := s.readImpl(p)
+	completed, n, err := s.readImpl(ctx, p, block)
 	s.mutex.Unlock()
 
 	if completed {
+		s.releaseFrameQueue()
 		s.sender.onStreamCompleted(s.streamID)
 	}
 	return n, err
 }
 
-func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, error) {
+func (s *receiveStream) readImpl(ctx context.Context, p []byte, block bool) (bool /*stream completed */, int, error) {
 	if s.finRead {
 		return false, 0, io.EOF
 	}
@@ -107,6 +235,11 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 
 	bytesRead := 0
 	var deadlineTimer *utils.Timer
+	defer func() {
+		if deadlineTimer != nil {
+			deadlineTimer.Stop()
+		}
+	}()
 	for bytesRead < len(p) {
 		if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
 			s.dequeueNextFrame()
@@ -114,48 +247,17 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 		if s.currentFrame == nil && bytesRead > 0 {
 			return false, bytesRead, s.closeForShutdownErr
 		}
-
-		for {
-			// Stop waiting on errors
-			if s.closedForShutdown {
-				return false, bytesRead, s.closeForShutdownErr
-			}
-			if s.canceledRead {
-				return false, bytesRead, s.cancelReadErr
-			}
-			if s.resetRemotely {
-				return false, bytesRead, s.resetRemotelyErr
+		if block {
+			if err := s.waitForFrame(ctx, &deadlineTimer); err != nil {
+				return false, bytesRead, err
 			}
-
-			deadline := s.deadline
-			if !deadline.IsZero() {
-				if !time.Now().Before(deadline) {
-					return false, bytesRead, errDeadline
-				}
-				if deadlineTimer == nil {
-					deadlineTimer = utils.NewTimer()
-					defer deadlineTimer.Stop()
-				}
-				deadlineTimer.Reset(deadline)
-			}
-
-			if s.currentFrame != nil || s.currentFrameIsLast {
-				break
-			}
-
-			s.mutex.Unlock()
-			if deadline.IsZero() {
-				<-s.readChan
-			} else {
-				select {
-				case <-s.readChan:
-				case <-deadlineTimer.Chan():
-					deadlineTimer.SetRead()
-				}
+		} else {
+			// The deadline doesn't apply to non-blocking reads.
+			if err := s.readError(); err != nil {
+				return false, bytesRead, err
 			}
-			s.mutex.Lock()
-			if s.currentFrame == nil {
-				s.dequeueNextFrame()
+			if s.currentFrame == nil && !s.currentFrameIsLast {
+				return false, bytesRead, ErrWouldBlock
 			}
 		}
 
@@ -179,13 +281,439 @@ func (s *receiveStream) readImpl(p []byte) (bool /*stream completed */, int, err
 		}
 
 		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
-			s.finRead = true
-			return true, bytesRead, io.EOF
+			completed, err := s.reachedEnd()
+			return completed, bytesRead, err
 		}
 	}
 	return false, bytesRead, nil
 }
 
+// WriteTo writes the data received on the stream to w, until the peer closes the stream or an error occurs.
+// The data is written directly from the received frames, without copying it into an intermediate buffer,
+// so that io.Copy(dst, str) avoids double-buffering.
+// Reaching the end of the stream is not reported as an error, as required by io.WriterTo.
+// It must not be called concurrently with Read.
+func (s *receiveStream) WriteTo(w io.Writer) (int64, error) {
+	s.mutex.Lock()
+	completed, n, err := s.writeToImpl(w)
+	s.mutex.Unlock()
+
+	if completed {
+		s.releaseFrameQueue()
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return n, err
+}
+
+func (s *receiveStream) writeToImpl(w io.Writer) (bool /* stream completed */, int64, error) {
+	if s.finRead {
+		return false, 0, nil
+	}
+	if err := s.readError(); err != nil {
+		return false, 0, err
+	}
+
+	var written int64
+	var deadlineTimer *utils.Timer
+	defer func() {
+		if deadlineTimer != nil {
+			deadlineTimer.Stop()
+		}
+	}()
+	for {
+		if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+			s.dequeueNextFrame()
+		}
+		if err := s.waitForFrame(context.Background(), &deadlineTimer); err != nil {
+			return false, written, err
+		}
+
+		data := s.currentFrame[s.readPosInFrame:]
+		var m int
+		var err error
+		consumed := true
+		if len(data) > 0 {
+			// The mutex is released while writing, so the frame is pinned:
+			// A concurrent call must not release its buffer before w.Write returns.
+			offset, pos, done := s.currentFrameOffset, s.readPosInFrame, s.currentFrameDone
+			s.currentFrameDone = nil
+			s.mutex.Unlock()
+			m, err = w.Write(data)
+			s.mutex.Lock()
+			consumed = s.unpinCurrentFrame(offset, pos, done)
+			if err == nil && m < len(data) {
+				err = io.ErrShortWrite
+			}
+		}
+		written += int64(m)
+		// If the data was consumed by a concurrent call, it was already accounted for.
+		if consumed {
+			s.readPosInFrame += m
+			// when a RESET_STREAM was received, the flow controller was already informed about the final byteOffset for this stream
+			if !s.resetRemotely {
+				s.flowController.AddBytesRead(protocol.ByteCount(m))
+			}
+		}
+		if err != nil {
+			return false, written, err
+		}
+
+		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
+			completed, err := s.reachedEnd()
+			if err != io.EOF {
+				return completed, written, err
+			}
+			return completed, written, nil
+		}
+	}
+}
+
+// ReadBuffers returns the data received on the stream, without copying it out of the received frames.
+// It blocks until data is available, and then returns all data that can be read without blocking.
+// The data is counted as read for flow control purposes.
+// The buffers are only valid until release is called, which must be done exactly once.
+// At the end of the stream, it returns io.EOF, possibly together with the last buffers.
+// It must not be called concurrently with Read.
+func (s *receiveStream) ReadBuffers() ([][]byte, func(), error) {
+	s.mutex.Lock()
+	completed, bufs, release, err := s.readBuffersImpl()
+	s.mutex.Unlock()
+
+	if completed {
+		s.releaseFrameQueue()
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return bufs, release, err
+}
+
+func (s *receiveStream) readBuffersImpl() (bool /* stream completed */, [][]byte, func(), error) {
+	if s.finRead {
+		return false, nil, func() {}, io.EOF
+	}
+	if err := s.readError(); err != nil {
+		return false, nil, func() {}, err
+	}
+
+	var deadlineTimer *utils.Timer
+	defer func() {
+		if deadlineTimer != nil {
+			deadlineTimer.Stop()
+		}
+	}()
+	if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+		s.dequeueNextFrame()
+	}
+	if err := s.waitForFrame(context.Background(), &deadlineTimer); err != nil {
+		return false, nil, func() {}, err
+	}
+
+	var bufs [][]byte
+	var dones []func()
+	release := func() {
+		for _, done := range dones {
+			done()
+		}
+	}
+	for {
+		if data := s.currentFrame[s.readPosInFrame:]; len(data) > 0 {
+			bufs = append(bufs, data)
+			// when a RESET_STREAM was received, the flow controller was already informed about the final byteOffset for this stream
+			if !s.resetRemotely {
+				s.flowController.AddBytesRead(protocol.ByteCount(len(data)))
+			}
+		}
+		// The buffer of the frame is now owned by the application.
+		if s.currentFrameDone != nil {
+			dones = append(dones, s.currentFrameDone)
+		}
+		s.currentFrame = nil
+		s.currentFrameDone = nil
+		s.readPosInFrame = 0
+		if s.currentFrameIsLast {
+			completed, err := s.reachedEnd()
+			return completed, bufs, release, err
+		}
+		s.dequeueNextFrame()
+		if s.currentFrame == nil && !s.currentFrameIsLast {
+			return false, bufs, release, nil
+		}
+	}
+}
+
+// Discard skips the next n bytes, without copying them.
+// The data is released from the receive buffer and counted as read for flow control purposes.
+// It blocks until n bytes were discarded, the stream ends, or an error occurs.
+// If fewer than n bytes were discarded, the error explains why, e.g. io.EOF at the end of the stream.
+// It must not be called concurrently with Read.
+func (s *receiveStream) Discard(n int64) (int64, error) {
+	if n < 0 {
+		return 0, errors.New("negative count")
+	}
+
+	s.mutex.Lock()
+	completed, discarded, err := s.discardImpl(n)
+	s.mutex.Unlock()
+
+	if completed {
+		s.releaseFrameQueue()
+		s.sender.onStreamCompleted(s.streamID)
+	}
+	return discarded, err
+}
+
+func (s *receiveStream) discardImpl(n int64) (bool /* stream completed */, int64, error) {
+	if s.finRead {
+		return false, 0, io.EOF
+	}
+	if err := s.readError(); err != nil {
+		return false, 0, err
+	}
+
+	var discarded int64
+	var deadlineTimer *utils.Timer
+	defer func() {
+		if deadlineTimer != nil {
+			deadlineTimer.Stop()
+		}
+	}()
+	for discarded < n {
+		if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+			s.dequeueNextFrame()
+		}
+		if err := s.waitForFrame(context.Background(), &deadlineTimer); err != nil {
+			return false, discarded, err
+		}
+
+		m := int(utils.MinInt64(n-discarded, int64(len(s.currentFrame)-s.readPosInFrame)))
+		s.readPosInFrame += m
+		discarded += int64(m)
+		// when a RESET_STREAM was received, the flow controller was already informed about the final byteOffset for this stream
+		if !s.resetRemotely {
+			s.flowController.AddBytesRead(protocol.ByteCount(m))
+		}
+
+		if s.readPosInFrame >= len(s.currentFrame) && s.currentFrameIsLast {
+			completed, err := s.reachedEnd()
+			if discarded < n {
+				return completed, discarded, err
+			}
+			return completed, discarded, nil
+		}
+	}
+	return false, discarded, nil
+}
+
+// reachedEnd is called when all data up to the end of the stream was read.
+// If CancelReadAfterBuffered or CancelReadAfter was called, reading is canceled now, and the cancellation error is returned.
+// If a RESET_STREAM_AT frame was received, the stream is reset now, and the reset error is returned.
+// Otherwise, it returns io.EOF.
+func (s *receiveStream) reachedEnd() (bool /* stream completed */, error) {
+	if s.cancelPending {
+		s.cancelPending = false
+		s.resetPending = false
+		s.canceledRead = true
+		// We're done with this stream if the final offset was already received.
+		completed := s.finalOffset != protocol.MaxByteCount
+		if completed {
+			s.flowController.Abandon()
+		}
+		return completed, s.cancelReadErr
+	}
+	if s.resetPending {
+		s.resetPending = false
+		s.resetRemotely = true
+		s.flowController.Abandon()
+		return true, s.resetRemotelyErr
+	}
+	s.finRead = true
+	return true, io.EOF
+}
+
+// readOffset is the offset up to which data was read by the application.
+func (s *receiveStream) readOffset() protocol.ByteCount {
+	if s.frameQueue == nil {
+		// The frame queue is only released when the stream was read until the end.
+		return s.finalOffset
+	}
+	if s.currentFrame == nil {
+		return s.frameQueue.readPos
+	}
+	return s.currentFrameOffset + protocol.ByteCount(s.readPosInFrame)
+}
+
+// endOffset is the offset up to which data is delivered to the application.
+func (s *receiveStream) endOffset() protocol.ByteCount {
+	end := s.finalOffset
+	if s.resetPending {
+		end = s.reliableSize
+	}
+	if s.cancelPending {
+		end = utils.MinByteCount(end, s.drainOffset)
+	}
+	return end
+}
+
+// readError returns the error that ends reading from the stream, if any.
+// It doesn't check if the stream was read until the end.
+func (s *receiveStream) readError() error {
+	if s.canceledRead {
+		return s.cancelReadErr
+	}
+	if s.resetRemotely {
+		return s.resetRemotelyErr
+	}
+	if s.closedForShutdown {
+		return s.closeForShutdownErr
+	}
+	return nil
+}
+
+// Peek returns the next n bytes without consuming them.
+// It blocks until n bytes are available, the peer closes the stream, or an error occurs.
+// If fewer than n bytes are returned, the error explains why, e.g. io.EOF at the end of the stream.
+// The returned slice is a copy of the data, and the data is not counted as read for flow control purposes.
+// Since the peer can't send more data than the receive window allows, Peek returns bufio.ErrBufferFull
+// if n is larger than the receive window, or if the peer used up its flow control credit.
+// It must not be called concurrently with Read.
+func (s *receiveStream) Peek(n int) ([]byte, error) {
+	if n < 0 {
+		return nil, errors.New("negative count")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.finRead {
+		return nil, io.EOF
+	}
+	if err := s.readError(); err != nil {
+		return nil, err
+	}
+	// The peer might already have been granted more credit than the maximum window size, using GrantCredit.
+	fcState := s.flowController.State()
+	if limit := utils.MaxByteCount(fcState.MaxReceiveWindowSize, fcState.ReceiveWindow-s.readOffset()); protocol.ByteCount(n) > limit {
+		return nil, bufio.ErrBufferFull
+	}
+
+	b := make([]byte, 0, n)
+	var deadlineTimer *utils.Timer
+	defer func() {
+		if deadlineTimer != nil {
+			deadlineTimer.Stop()
+		}
+	}()
+	if err := s.waitUntil(context.Background(), &deadlineTimer, func() bool {
+		if s.currentFrame == nil || s.readPosInFrame >= len(s.currentFrame) {
+			s.dequeueNextFrame()
+		}
+		b = b[:0]
+		if s.currentFrame != nil {
+			b = append(b, s.currentFrame[s.readPosInFrame:utils.Min(len(s.currentFrame), s.readPosInFrame+n)]...)
+		}
+		b = s.frameQueue.Peek(b, n)
+		if max := s.endOffset() - s.readOffset(); protocol.ByteCount(len(b)) > max {
+			b = b[:max]
+		}
+		// All data up to the final offset (or the reliable size) has been received.
+		if len(b) >= n || s.frameQueue.ContiguousOffset() >= s.endOffset() {
+			return true
+		}
+		// The peer used up its flow control credit, and won't send more data until data is read.
+		return s.readOffset()+protocol.ByteCount(len(b)) >= s.flowController.State().ReceiveWindow
+	}); err != nil {
+		return nil, err
+	}
+	if len(b) < n {
+		if s.cancelPending {
+			return b, s.cancelReadErr
+		}
+		if s.resetPending {
+			return b, s.resetRemotelyErr
+		}
+		if s.frameQueue.ContiguousOffset() < s.endOffset() {
+			return b, bufio.ErrBufferFull
+		}
+		return b, io.EOF
+	}
+	return b, nil
+}
+
+// waitForFrame blocks until the current frame contains data, or the last frame was dequeued.
+// It must be called with the mutex held.
+// The deadline timer is created when needed, and must be stopped by the caller.
+func (s *receiveStream) waitForFrame(ctx context.Context, deadlineTimer **utils.Timer) error {
+	return s.waitUntil(ctx, deadlineTimer, func() bool {
+		if s.currentFrame == nil {
+			s.dequeueNextFrame()
+		}
+		return s.currentFrame != nil || s.currentFrameIsLast
+	})
+}
+
+// waitUntil blocks until cond returns true, an error occurs, or the deadline expires.
+// It must be called with the mutex held, and cond is evaluated with the mutex held.
+// The deadline timer is created when needed, and must be stopped by the caller.
+func (s *receiveStream) waitUntil(ctx context.Context, deadlineTimer **utils.Timer, cond func() bool) error {
+	for {
+		// Stop waiting on errors
+		if s.closedForShutdown {
+			return s.closeForShutdownErr
+		}
+		if s.canceledRead {
+			return s.cancelReadErr
+		}
+		if s.resetRemotely {
+			return s.resetRemotelyErr
+		}
+
+		deadline := s.deadline
+		if !deadline.IsZero() {
+			if !time.Now().Before(deadline) {
+				return errDeadline
+			}
+			if *deadlineTimer == nil {
+				*deadlineTimer = utils.NewTimer()
+			}
+			(*deadlineTimer).Reset(deadline)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if cond() {
+			return nil
+		}
+
+		s.mutex.Unlock()
+		var deadlineChan <-chan time.Time
+		if !deadline.IsZero() {
+			deadlineChan = (*deadlineTimer).Chan()
+		}
+		select {
+		case <-s.readChan:
+		case <-deadlineChan:
+			(*deadlineTimer).SetRead()
+		case <-ctx.Done():
+		}
+		s.mutex.Lock()
+	}
+}
+
+// unpinCurrentFrame is called after writing from a frame that was pinned by writeToImpl.
+// It returns false if the data was consumed by a concurrent call in the meantime.
+// If the stream moved on to another frame, the buffer of the pinned frame is released.
+// It must be called with the mutex held.
+func (s *receiveStream) unpinCurrentFrame(offset protocol.ByteCount, pos int, done func()) bool {
+	if s.currentFrame != nil && s.currentFrameOffset == offset && s.currentFrameDone == nil {
+		s.currentFrameDone = done
+		return s.readPosInFrame == pos
+	}
+	if done != nil {
+		done()
+	}
+	return false
+}
+
 func (s *receiveStream) dequeueNextFrame() {
 	var offset protocol.ByteCount
 	// We're done with the last frame. Release the buffer.
@@ -193,19 +721,134 @@ func (s *receiveStream) dequeueNextFrame() {
 		s.currentFrameDone()
 	}
 	offset, s.currentFrame, s.currentFrameDone = s.frameQueue.Pop()
-	s.currentFrameIsLast = offset+protocol.ByteCount(len(s.currentFrame)) >= s.finalOffset
+	s.currentFrameOffset = offset
 	s.readPosInFrame = 0
+	s.truncateCurrentFrame()
+}
+
+// truncateCurrentFrame cuts off the data beyond the end of the stream,
+// and determines if the current frame is the last frame.
+func (s *receiveStream) truncateCurrentFrame() {
+	end := s.endOffset()
+	if s.currentFrameOffset+protocol.ByteCount(len(s.currentFrame)) > end {
+		s.currentFrame = s.currentFrame[:end-s.currentFrameOffset]
+	}
+	s.currentFrameIsLast = s.currentFrameOffset+protocol.ByteCount(len(s.currentFrame)) >= end
 }
 
 func (s *receiveStream) CancelRead(errorCode StreamErrorCode) {
 	s.mutex.Lock()
 	completed := s.cancelReadImpl(errorCode)
+	onReadable := s.readableCallback()
 	s.mutex.Unlock()
 
 	if completed {
 		s.flowController.Abandon()
+		s.releaseFrameQueue()
 		s.sender.onStreamCompleted(s.streamID)
 	}
+	if onReadable != nil {
+		onReadable()
+	}
+}
+
+// CancelReadAfterBuffered aborts receiving on this stream, like CancelRead,
+// but the data that was already received in order is still delivered before reading returns the error.
+func (s *receiveStream) CancelReadAfterBuffered(errorCode StreamErrorCode) {
+	s.mutex.Lock()
+	if s.cancelPending {
+		s.mutex.Unlock()
+		return
+	}
+	if s.finRead || s.canceledRead || s.resetRemotely || s.closedForShutdown || s.frameQueue == nil {
+		s.mutex.Unlock()
+		s.CancelRead(errorCode)
+		return
+	}
+	drainOffset := utils.MinByteCount(s.frameQueue.ContiguousOffset(), s.endOffset())
+	if drainOffset <= s.readOffset() {
+		// no data to deliver
+		s.mutex.Unlock()
+		s.CancelRead(errorCode)
+		return
+	}
+	s.cancelReadAt(drainOffset, errorCode)
+	onReadable := s.readableCallback()
+	s.mutex.Unlock()
+
+	if onReadable != nil {
+		onReadable()
+	}
+}
+
+// CancelReadAfter aborts receiving on this stream once the data up to offset was read.
+// The STOP_SENDING frame is sent as soon as all data up to offset was received.
+func (s *receiveStream) CancelReadAfter(offset uint64, errorCode StreamErrorCode) {
+	s.mutex.Lock()
+	if s.cancelPending {
+		s.mutex.Unlock()
+		return
+	}
+	if s.finRead || s.canceledRead || s.resetRemotely || s.closedForShutdown || s.frameQueue == nil {
+		s.mutex.Unlock()
+		s.CancelRead(errorCode)
+		return
+	}
+	// The stream ends before the offset anyway.
+	if end := s.endOffset(); end != protocol.MaxByteCount && protocol.ByteCount(offset) >= end {
+		s.mutex.Unlock()
+		return
+	}
+	if protocol.ByteCount(offset) <= s.readOffset() {
+		s.mutex.Unlock()
+		s.CancelRead(errorCode)
+		return
+	}
+	s.cancelReadAt(protocol.ByteCount(offset), errorCode)
+	onReadable := s.readableCallback()
+	s.mutex.Unlock()
+
+	if onReadable != nil {
+		onReadable()
+	}
+}
+
+// cancelReadAt cancels reading once the data up to drainOffset was read.
+// It must be called with the mutex held.
+func (s *receiveStream) cancelReadAt(drainOffset protocol.ByteCount, errorCode StreamErrorCode) {
+	s.cancelPending = true
+	s.drainOffset = drainOffset
+	s.cancelErrorCode = errorCode
+	s.cancelReadErr = newCancelReadError(s.streamID, errorCode, s.errorRegistry.Error(errorCode))
+	if s.currentFrame != nil {
+		s.truncateCurrentFrame()
+	}
+	s.signalRead()
+	s.stopSendingPending = true
+	s.maybeQueueStopSending()
+}
+
+// maybeQueueStopSending queues the STOP_SENDING frame for a pending cancellation,
+// once all data up to the drainOffset was received.
+// It must be called with the mutex held.
+func (s *receiveStream) maybeQueueStopSending() {
+	if !s.stopSendingPending || s.frameQueue.ContiguousOffset() < s.drainOffset {
+		return
+	}
+	s.stopSendingPending = false
+	s.sender.queueControlFrame(&wire.StopSendingFrame{
+		StreamID:  s.streamID,
+		ErrorCode: s.cancelErrorCode,
+	})
+}
+
+// newCancelReadError creates the error returned from Read after the stream was canceled locally.
+// It wraps appErr, the error registered for the error code, if any.
+func newCancelReadError(id protocol.StreamID, errorCode StreamErrorCode, appErr error) error {
+	if appErr != nil {
+		return fmt.Errorf("Read on stream %d canceled with error code %d: %w", id, errorCode, appErr)
+	}
+	return fmt.Errorf("Read on stream %d canceled with error code %d", id, errorCode)
 }
 
 func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* completed */ {
@@ -213,25 +856,40 @@ func (s *receiveStream) cancelReadImpl(errorCode qerr.StreamErrorCode) bool /* c
 		return false
 	}
 	s.canceledRead = true
-	s.cancelReadErr = fmt.Errorf("Read on stream %d canceled with error code %d", s.streamID, errorCode)
+	s.resetPending = false
+	s.cancelReadErr = newCancelReadError(s.streamID, errorCode, s.errorRegistry.Error(errorCode))
 	s.signalRead()
-	s.sender.queueControlFrame(&wire.StopSendingFrame{
-		StreamID:  s.streamID,
-		ErrorCode: errorCode,
-	})
+	// A STOP_SENDING frame might already have been sent by CancelReadAfterBuffered or CancelReadAfter.
+	if !s.cancelPending || s.stopSendingPending {
+		s.sender.queueControlFrame(&wire.StopSendingFrame{
+			StreamID:  s.streamID,
+			ErrorCode: errorCode,
+		})
+	}
+	s.cancelPending = false
+	s.stopSendingPending = false
 	// We're done with this stream if the final offset was already received.
 	return s.finalOffset != protocol.MaxByteCount
 }
 
 func (s *receiveStream) handleStreamFrame(frame *wire.StreamFrame) error {
+	s.idleTimer.Activity()
 	s.mutex.Lock()
 	completed, err := s.handleStreamFrameImpl(frame)
+	var onReadable func()
+	if err == nil {
+		onReadable = s.readableCallback()
+	}
 	s.mutex.Unlock()
 
 	if completed {
 		s.flowController.Abandon()
+		s.releaseFrameQueue()
 		s.sender.onStreamCompleted(s.streamID)
 	}
+	if onReadable != nil {
+		onReadable()
+	}
 	return err
 }
 
@@ -246,24 +904,89 @@ func (s *receiveStream) handleStreamFrameImpl(frame *wire.StreamFrame) (bool /*
 		s.finalOffset = maxOffset
 	}
 	if s.canceledRead {
+		if newlyRcvdFinalOffset {
+			s.signalPeerClosed()
+		}
 		return newlyRcvdFinalOffset, nil
 	}
+	// The stream ends before the offset passed to CancelReadAfter, so all data is delivered.
+	if s.cancelPending && s.stopSendingPending && s.finalOffset <= s.drainOffset {
+		s.cancelPending = false
+		s.stopSendingPending = false
+		if s.currentFrame != nil {
+			s.truncateCurrentFrame()
+		}
+	}
+	// Data beyond the drainOffset is not delivered to the application.
+	if s.cancelPending && (!s.stopSendingPending || frame.Offset >= s.drainOffset) {
+		if newlyRcvdFinalOffset {
+			s.signalPeerClosed()
+		}
+		frame.PutBack()
+		return false, nil
+	}
+	// The stream was already completed, this is a retransmission of data that was already read.
+	if s.frameQueue == nil {
+		frame.PutBack()
+		return false, nil
+	}
 	if err := s.frameQueue.Push(frame.Data, frame.Offset, frame.PutBack); err != nil {
 		return false, err
 	}
+	if s.reassemblyMemory.StreamLimitExceeded(s.frameQueue.Memory()) {
+		return s.cancelReadForReassemblyMemory(), nil
+	}
+	if err := s.reassemblyMemory.CheckConnectionLimit(); err != nil {
+		return false, err
+	}
+	if s.finalOffset != protocol.MaxByteCount && s.frameQueue.ContiguousOffset() >= s.finalOffset {
+		s.signalPeerClosed()
+	}
+	if s.cancelPending {
+		s.maybeQueueStopSending()
+	}
 	s.signalRead()
 	return false, nil
 }
 
+// cancelReadForReassemblyMemory cancels reading when the queued frames hold more memory than Config.MaxStreamReassemblyMemory.
+// The queued data is dropped, since it will never be read.
+// It must be called with the mutex held.
+func (s *receiveStream) cancelReadForReassemblyMemory() bool /* completed */ {
+	memory := s.frameQueue.Memory()
+	s.frameQueue.DiscardQueued()
+	completed := s.cancelReadImpl(s.reassemblyMemory.errorCode)
+	if s.canceledRead {
+		s.cancelReadErr = &StreamReassemblyMemoryError{
+			StreamError: StreamError{
+				StreamID:  s.streamID,
+				ErrorCode: s.reassemblyMemory.errorCode,
+				Err:       s.errorRegistry.Error(s.reassemblyMemory.errorCode),
+			},
+			Memory: uint64(memory),
+			Limit:  uint64(s.reassemblyMemory.maxStream),
+		}
+	}
+	return completed
+}
+
 func (s *receiveStream) handleResetStreamFrame(frame *wire.ResetStreamFrame) error {
 	s.mutex.Lock()
 	completed, err := s.handleResetStreamFrameImpl(frame)
+	var onReadable func()
+	if err == nil {
+		onReadable = s.readableCallback()
+	}
 	s.mutex.Unlock()
 
 	if completed {
 		s.flowController.Abandon()
+		s.releaseFrameQueue()
 		s.sender.onStreamCompleted(s.streamID)
 	}
+	if onReadable != nil {
+		onReadable()
+	}
 	return err
 }
 
@@ -281,13 +1004,66 @@ func (s *receiveStream) handleResetStreamFrameImpl(frame *wire.ResetStreamFrame)
 	if s.resetRemotely {
 		return false, nil
 	}
-	s.resetRemotely = true
+	wasPending := s.resetPending
+	reliableSize := frame.ReliableSize
+	// The reliable size can only be reduced by subsequent RESET_STREAM_AT frames.
+	if wasPending {
+		reliableSize = utils.MinByteCount(reliableSize, s.reliableSize)
+	}
 	s.resetRemotelyErr = &StreamError{
 		StreamID:  s.streamID,
 		ErrorCode: frame.ErrorCode,
+		Err:       s.errorRegistry.Error(frame.ErrorCode),
 	}
+	s.signalPeerClosed()
 	s.signalRead()
-	return newlyRcvdFinalOffset, nil
+	// If CancelReadAfter is waiting for data, this data might never arrive.
+	// Only the data up to the reliable size is delivered, followed by the reset error.
+	if s.cancelPending && s.stopSendingPending {
+		s.cancelPending = false
+		s.stopSendingPending = false
+		reliableSize = utils.MinByteCount(reliableSize, s.drainOffset)
+	}
+	// The data received before CancelReadAfterBuffered (or CancelReadAfter) was called is still delivered.
+	// The stream is completed once it was read.
+	if s.cancelPending {
+		return false, nil
+	}
+	if !s.canceledRead && reliableSize > s.readOffset() {
+		s.resetPending = true
+		s.reliableSize = reliableSize
+		s.truncateCurrentFrame()
+		return false, nil
+	}
+	s.resetPending = false
+	s.resetRemotely = true
+	return newlyRcvdFinalOffset || wasPending, nil
+}
+
+// releaseFrameQueue is called when the stream is completed.
+// Reading from the stream only returns errors (or io.EOF) from now on, so the frame queue isn't needed any more.
+func (s *receiveStream) releaseFrameQueue() {
+	s.mutex.Lock()
+	s.completed = true
+	if s.frameQueue != nil {
+		s.frameQueue.Release()
+		s.frameQueue = nil
+	}
+	s.mutex.Unlock()
+}
+
+func (s *receiveStream) PeerClosed() <-chan struct{} {
+	return s.peerClosedChan
+}
+
+// signalPeerClosed closes the peerClosedChan, if it wasn't closed yet.
+// It must be called with the mutex held.
+func (s *receiveStream) signalPeerClosed() {
+	if s.peerClosed {
+		return
+	}
+	s.peerClosed = true
+	close(s.peerClosedChan)
 }
 
 func (s *receiveStream) CloseRemote(offset protocol.ByteCount) {
@@ -309,8 +1085,13 @@ func (s *receiveStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	onReadable := s.readableCallback()
 	s.mutex.Unlock()
+	s.idleTimer.Stop()
 	s.signalRead()
+	if onReadable != nil {
+		onReadable()
+	}
 }
 
 func (s *receiveStream) getWindowUpdate() protocol.ByteCount {
@@ -1,12 +1,17 @@
 package quic
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"errors"
 	"io"
+	"math"
 	"runtime"
 	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -56,6 +61,119 @@ var _ = Describe("Receive Stream", func() {
 			Expect(b).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
 		})
 
+		It("reports the number of bytes that can be read", func() {
+			Expect(str.ReadableBytes()).To(BeZero())
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+			Expect(str.ReadableBytes()).To(Equal(uint64(4)))
+			// out-of-order data is not readable yet
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte{0xCA, 0xFE, 0xBA, 0xBE}})).To(Succeed())
+			Expect(str.ReadableBytes()).To(Equal(uint64(4)))
+			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+			n, err := strWithTimeout.Read(make([]byte, 3))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(Equal(3))
+			Expect(str.ReadableBytes()).To(Equal(uint64(1)))
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+			Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte{0x13, 0x37}})).To(Succeed())
+			Expect(str.ReadableBytes()).To(Equal(uint64(7)))
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			str.CancelRead(1234)
+			Expect(str.ReadableBytes()).To(BeZero())
+		})
+
+		Context("non-blocking reads", func() {
+			It("returns ErrWouldBlock when no data is available", func() {
+				n, err := str.TryRead(make([]byte, 4))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+			})
+
+			It("reads the available data", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+				b := make([]byte, 6)
+				n, err := str.TryRead(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(4))
+				Expect(b[:n]).To(Equal([]byte{0xDE, 0xAD, 0xBE, 0xEF}))
+				n, err = str.TryRead(b)
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+			})
+
+			It("returns EOF", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}, Fin: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				n, err := str.TryRead(make([]byte, 6))
+				Expect(err).To(MatchError(io.EOF))
+				Expect(n).To(Equal(4))
+			})
+
+			It("ignores the read deadline", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+				str.SetReadDeadline(time.Now().Add(-time.Second))
+				n, err := str.TryRead(make([]byte, 4))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(4))
+			})
+
+			It("returns an error when the read side was canceled", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(1234)
+				_, err := str.TryRead(make([]byte, 4))
+				Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
+			})
+
+			It("calls the OnReadable callback when data arrives", func() {
+				var called int
+				str.OnReadable(func() { called++ })
+				Expect(called).To(BeZero())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				// out-of-order data doesn't make the stream readable
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte{0xCA, 0xFE, 0xBA, 0xBE}})).To(Succeed())
+				Expect(called).To(BeZero())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+				Expect(called).To(Equal(1))
+			})
+
+			It("calls the OnReadable callback immediately if the stream is readable", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}})).To(Succeed())
+				var called bool
+				str.OnReadable(func() { called = true })
+				Expect(called).To(BeTrue())
+			})
+
+			It("calls the OnReadable callback when the stream is reset", func() {
+				var called bool
+				str.OnReadable(func() { called = true })
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 42,
+					ErrorCode: 1234,
+				})).To(Succeed())
+				Expect(called).To(BeTrue())
+			})
+
+			It("calls the OnReadable callback when the stream is closed for shutdown", func() {
+				var called bool
+				str.OnReadable(func() { called = true })
+				str.closeForShutdown(errors.New("shutdown"))
+				Expect(called).To(BeTrue())
+			})
+		})
+
 		It("reads a single STREAM frame in multiple goes", func() {
 			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
 			mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
@@ -324,6 +442,57 @@ var _ = Describe("Receive Stream", func() {
 			})
 		})
 
+		Context("contexts", func() {
+			It("returns an error when ReadContext is called with a canceled context", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				n, err := str.ReadContext(ctx, make([]byte, 6))
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(n).To(BeZero())
+			})
+
+			It("unblocks when the context is canceled", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					n, err := str.ReadContext(ctx, make([]byte, 6))
+					Expect(err).To(MatchError(context.Canceled))
+					Expect(n).To(BeZero())
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				cancel()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("unblocks when the context's deadline expires", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(50*time.Millisecond))
+				defer cancel()
+				deadline, _ := ctx.Deadline()
+				_, err := str.ReadContext(ctx, make([]byte, 6))
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+			})
+
+			It("reads data until the context is canceled", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				ctx, cancel := context.WithCancel(context.Background())
+				b := make([]byte, 6)
+				n, err := str.ReadContext(ctx, b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foobar")))
+				cancel()
+				n, err = str.ReadContext(ctx, b)
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(n).To(BeZero())
+			})
+		})
+
 		Context("closing", func() {
 			Context("with FIN bit", func() {
 				It("returns EOFs", func() {
@@ -345,6 +514,22 @@ var _ = Describe("Receive Stream", func() {
 					Expect(err).To(MatchError(io.EOF))
 				})
 
+				It("releases the frame queue when the stream is completed", func() {
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true).Times(2)
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+					str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}, Fin: true})
+					mockSender.EXPECT().onStreamCompleted(streamID)
+					n, err := strWithTimeout.Read(make([]byte, 4))
+					Expect(err).To(MatchError(io.EOF))
+					Expect(n).To(Equal(4))
+					Expect(str.frameQueue).To(BeNil())
+					// retransmissions are ignored
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xDE, 0xAD, 0xBE, 0xEF}, Fin: true})).To(Succeed())
+					n, err = strWithTimeout.Read(make([]byte, 4))
+					Expect(n).To(BeZero())
+					Expect(err).To(MatchError(io.EOF))
+				})
+
 				It("handles out-of-order frames", func() {
 					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
 					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
@@ -418,6 +603,480 @@ var _ = Describe("Receive Stream", func() {
 			})
 		})
 
+		Context("peeking", func() {
+			BeforeEach(func() {
+				mockFC.EXPECT().State().Return(flowcontrol.WindowState{ReceiveWindow: 1000, MaxReceiveWindowSize: 1000}).AnyTimes()
+			})
+
+			It("returns data without consuming it", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xbe, 0xef}})).To(Succeed())
+				b, err := str.Peek(3)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte{0xde, 0xad, 0xbe}))
+				// peeking doesn't consume the data
+				b, err = str.Peek(4)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1)).Times(2)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				data := make([]byte, 1)
+				_, err = strWithTimeout.Read(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xde}))
+				b, err = str.Peek(2)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte{0xad, 0xbe}))
+				data = make([]byte, 3)
+				_, err = strWithTimeout.Read(data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte{0xad, 0xbe, 0xef}))
+			})
+
+			It("waits until enough data is available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					b, err := str.Peek(4)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(b).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xbe, 0xef}})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("returns an io.EOF if the stream ends before n bytes", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}, Fin: true})).To(Succeed())
+				b, err := str.Peek(4)
+				Expect(err).To(MatchError(io.EOF))
+				Expect(b).To(Equal([]byte{0xde, 0xad}))
+				// the data can still be read
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data := make([]byte, 4)
+				n, err := strWithTimeout.Read(data)
+				Expect(err).To(MatchError(io.EOF))
+				Expect(data[:n]).To(Equal([]byte{0xde, 0xad}))
+				_, err = str.Peek(1)
+				Expect(err).To(MatchError(io.EOF))
+			})
+
+			It("unblocks when the stream is reset", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					_, err := str.Peek(4)
+					Expect(err).To(MatchError(&StreamError{
+						StreamID:  streamID,
+						ErrorCode: 1234,
+					}))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 42,
+					ErrorCode: 1234,
+				})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("unblocks after the deadline", func() {
+				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+				str.SetReadDeadline(deadline)
+				_, err := str.Peek(1)
+				Expect(err).To(MatchError(errDeadline))
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+			})
+
+			It("errors for a negative count", func() {
+				_, err := str.Peek(-1)
+				Expect(err).To(MatchError("negative count"))
+			})
+		})
+
+		Context("peeking beyond the receive window", func() {
+			It("errors if n is larger than the maximum receive window", func() {
+				mockFC.EXPECT().State().Return(flowcontrol.WindowState{ReceiveWindow: 100, MaxReceiveWindowSize: 200})
+				b, err := str.Peek(201)
+				Expect(err).To(MatchError(bufio.ErrBufferFull))
+				Expect(b).To(BeEmpty())
+			})
+
+			It("errors for a very large n", func() {
+				mockFC.EXPECT().State().Return(flowcontrol.WindowState{ReceiveWindow: 100, MaxReceiveWindowSize: 200})
+				_, err := str.Peek(int(^uint(0) >> 1)) // the largest int
+				Expect(err).To(MatchError(bufio.ErrBufferFull))
+			})
+
+			It("peeks beyond the maximum receive window, if the peer was granted more credit", func() {
+				mockFC.EXPECT().State().Return(flowcontrol.WindowState{ReceiveWindow: 6, MaxReceiveWindowSize: 4}).AnyTimes()
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				b, err := str.Peek(6)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte("foobar")))
+			})
+
+			It("errors when the peer used up its flow control credit", func() {
+				mockFC.EXPECT().State().Return(flowcontrol.WindowState{ReceiveWindow: 4, MaxReceiveWindowSize: 8}).AnyTimes()
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("fo")})).To(Succeed())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					b, err := str.Peek(6)
+					Expect(err).To(MatchError(bufio.ErrBufferFull))
+					Expect(b).To(Equal([]byte("foob")))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte("ob")})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+				// the data can still be read
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				data := make([]byte, 4)
+				_, err := io.ReadFull(strWithTimeout, data)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foob")))
+			})
+		})
+
+		Context("reading into borrowed buffers", func() {
+			It("returns all data available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(8), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xbe, 0xef}})).To(Succeed())
+				// not contiguous
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte{0xca, 0xfe}})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				bufs, release, err := str.ReadBuffers()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bufs).To(Equal([][]byte{{0xde, 0xad}, {0xbe, 0xef}}))
+				release()
+			})
+
+			It("returns the remainder of a partially read frame", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad, 0xbe, 0xef}})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1))
+				b := make([]byte, 1)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				bufs, release, err := str.ReadBuffers()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bufs).To(Equal([][]byte{{0xad, 0xbe, 0xef}}))
+				release()
+			})
+
+			It("releases the frames when release is called", func() {
+				var released int
+				Expect(str.frameQueue.Push([]byte{0xde, 0xad}, 0, func() { released++ })).To(Succeed())
+				Expect(str.frameQueue.Push([]byte{0xbe, 0xef}, 2, func() { released++ })).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				bufs, release, err := str.ReadBuffers()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bufs).To(HaveLen(2))
+				Expect(released).To(BeZero())
+				release()
+				Expect(released).To(Equal(2))
+			})
+
+			It("waits until data is available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					bufs, release, err := str.ReadBuffers()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(bufs).To(Equal([][]byte{{0xde, 0xad}}))
+					release()
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("returns io.EOF with the last buffers", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xbe, 0xef}, Fin: true})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				bufs, release, err := str.ReadBuffers()
+				Expect(err).To(MatchError(io.EOF))
+				Expect(bufs).To(Equal([][]byte{{0xde, 0xad}, {0xbe, 0xef}}))
+				release()
+				_, release, err = str.ReadBuffers()
+				Expect(err).To(MatchError(io.EOF))
+				release()
+			})
+
+			It("returns io.EOF for an immediate FIN", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+				str.CloseRemote(0)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				bufs, release, err := str.ReadBuffers()
+				Expect(err).To(MatchError(io.EOF))
+				Expect(bufs).To(BeEmpty())
+				release()
+			})
+
+			It("returns an error when the stream is reset", func() {
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 42,
+					ErrorCode: 1234,
+				})).To(Succeed())
+				_, release, err := str.ReadBuffers()
+				Expect(err).To(MatchError(&StreamError{
+					StreamID:  streamID,
+					ErrorCode: 1234,
+				}))
+				release()
+			})
+		})
+
+		Context("discarding", func() {
+			It("discards data across frames", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xbe, 0xef}})).To(Succeed())
+				gomock.InOrder(
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)),
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1)),
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(1)),
+				)
+				n, err := str.Discard(3)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(3))
+				b := make([]byte, 1)
+				_, err = strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte{0xef}))
+			})
+
+			It("waits until enough data is available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					n, err := str.Discard(4)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(BeEquivalentTo(4))
+				}()
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				Consistently(done).ShouldNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 2, Data: []byte{0xbe, 0xef}})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("completes the stream when discarding up to the FIN", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad, 0xbe, 0xef}, Fin: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				n, err := str.Discard(4)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(4))
+				_, err = strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(io.EOF))
+			})
+
+			It("returns an io.EOF if the stream ends before n bytes", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}, Fin: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				n, err := str.Discard(10)
+				Expect(err).To(MatchError(io.EOF))
+				Expect(n).To(BeEquivalentTo(2))
+				n, err = str.Discard(10)
+				Expect(err).To(MatchError(io.EOF))
+				Expect(n).To(BeZero())
+			})
+
+			It("returns an error when the stream is reset", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					_, err := str.Discard(4)
+					Expect(err).To(MatchError(&StreamError{
+						StreamID:  streamID,
+						ErrorCode: 1234,
+					}))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 42,
+					ErrorCode: 1234,
+				})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("errors for a negative count", func() {
+				_, err := str.Discard(-1)
+				Expect(err).To(MatchError("negative count"))
+			})
+		})
+
+		Context("writing to an io.Writer", func() {
+			It("writes all data until the FIN", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)).Times(2)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Offset: 2,
+					Data:   []byte{0xbe, 0xef},
+					Fin:    true,
+				})).To(Succeed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Offset: 0,
+					Data:   []byte{0xde, 0xad},
+				})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := &bytes.Buffer{}
+				n, err := str.WriteTo(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(4))
+				Expect(b.Bytes()).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+				// the stream is completed, calling WriteTo again is a no-op
+				n, err = str.WriteTo(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeZero())
+			})
+
+			It("waits until data is available", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(2), true)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(0))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				b := &bytes.Buffer{}
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					n, err := str.WriteTo(b)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(BeEquivalentTo(2))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad}})).To(Succeed())
+				Consistently(done).ShouldNot(BeClosed())
+				str.CloseRemote(2)
+				Eventually(done).Should(BeClosed())
+				Expect(b.Bytes()).To(Equal([]byte{0xde, 0xad}))
+			})
+
+			It("returns the error of the io.Writer", func() {
+				testErr := errors.New("test error")
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(0))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte{0xde, 0xad, 0xbe, 0xef}})).To(Succeed())
+				pr, pw := io.Pipe()
+				pr.CloseWithError(testErr)
+				n, err := str.WriteTo(pw)
+				Expect(err).To(MatchError(testErr))
+				Expect(n).To(BeZero())
+				// the data is still there
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				b := make([]byte, 4)
+				_, err = strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b).To(Equal([]byte{0xde, 0xad, 0xbe, 0xef}))
+			})
+
+			It("returns an error when the stream is reset", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					_, err := str.WriteTo(&bytes.Buffer{})
+					Expect(err).To(MatchError(&StreamError{
+						StreamID:  streamID,
+						ErrorCode: 1234,
+					}))
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 42,
+					ErrorCode: 1234,
+				})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("doesn't release the frame while writing it", func() {
+				var released bool
+				Expect(str.frameQueue.Push([]byte("foobar"), 0, func() { released = true })).To(Succeed())
+				Expect(str.frameQueue.Push([]byte("baz"), 6, nil)).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				str.SetReadDeadline(time.Now().Add(scaleDuration(20 * time.Millisecond)))
+				var written []byte
+				w := writerFunc(func(p []byte) (int, error) {
+					written = append(written, p...)
+					if len(written) == 6 {
+						// A concurrent Read consumes the frame that is being written.
+						b := make([]byte, 9)
+						n, err := str.Read(b)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(b[:n]).To(Equal([]byte("foobarbaz")))
+						Expect(released).To(BeFalse())
+						Expect(p).To(Equal([]byte("foobar")))
+					}
+					return len(p), nil
+				})
+				n, err := str.WriteTo(w)
+				Expect(err).To(MatchError(errDeadline))
+				Expect(n).To(BeEquivalentTo(6))
+				Expect(released).To(BeTrue())
+				Expect(written).To(Equal([]byte("foobar")))
+			})
+
+			It("unblocks after the deadline", func() {
+				deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+				str.SetReadDeadline(deadline)
+				_, err := str.WriteTo(&bytes.Buffer{})
+				Expect(err).To(MatchError(errDeadline))
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+			})
+		})
+
 		Context("closing for shutdown", func() {
 			testErr := errors.New("test error")
 
@@ -443,6 +1102,92 @@ var _ = Describe("Receive Stream", func() {
 				Expect(n).To(BeZero())
 				Expect(err).To(MatchError(testErr))
 			})
+
+			It("doesn't signal that the peer closed the stream", func() {
+				str.closeForShutdown(testErr)
+				Expect(str.PeerClosed()).ToNot(BeClosed())
+			})
+		})
+
+		Context("peer closing", func() {
+			It("signals when the FIN is received", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foob")})).To(Succeed())
+				Expect(str.PeerClosed()).ToNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Offset: 4,
+					Data:   []byte("ar"),
+					Fin:    true,
+				})).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+			})
+
+			It("signals for immediate FINs", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(0), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Fin: true})).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+			})
+
+			It("waits until all data has been received", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Offset: 4,
+					Data:   []byte("ar"),
+					Fin:    true,
+				})).To(Succeed())
+				Expect(str.PeerClosed()).ToNot(BeClosed())
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foob")})).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+			})
+
+			It("signals before the data is read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Data: []byte("foobar"),
+					Fin:  true,
+				})).To(Succeed())
+				Eventually(str.PeerClosed()).Should(BeClosed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data, err := io.ReadAll(strWithTimeout)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+			})
+
+			It("signals when the stream is reset", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 42,
+				})).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+			})
+
+			It("signals when the FIN is received after reading was canceled", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(1234)
+				Expect(str.PeerClosed()).ToNot(BeClosed())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(1000), true)
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{
+					Offset: 1000,
+					Fin:    true,
+				})).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+			})
+
+			It("handles duplicate FINs", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true).Times(2)
+				f := &wire.StreamFrame{Data: []byte("foobar"), Fin: true}
+				Expect(str.handleStreamFrame(f)).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+				Expect(str.handleStreamFrame(f)).To(Succeed())
+			})
 		})
 	})
 
@@ -469,6 +1214,17 @@ var _ = Describe("Receive Stream", func() {
 				Expect(err).To(MatchError("Read on stream 1337 canceled with error code 1234"))
 			})
 
+			It("wraps the error registered for the error code", func() {
+				errCanceled := errors.New("request canceled")
+				str.errorRegistry = newStreamErrorRegistry()
+				Expect(str.errorRegistry.Register(1234, errCanceled)).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelRead(1234)
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(errCanceled))
+				Expect(err.Error()).To(Equal("Read on stream 1337 canceled with error code 1234: request canceled"))
+			})
+
 			It("does nothing when CancelRead is called twice", func() {
 				mockSender.EXPECT().queueControlFrame(gomock.Any())
 				str.CancelRead(1234)
@@ -559,6 +1315,200 @@ var _ = Describe("Receive Stream", func() {
 			})
 		})
 
+		Context("canceling read after the buffered data", func() {
+			const errMsg = "Read on stream 1337 canceled with error code 1234"
+
+			It("delivers the data received in order, and then cancels reading", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(12), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				// out-of-order data is not delivered
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 8, Data: []byte("4242")})).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				str.CancelReadAfterBuffered(1234)
+				Expect(str.ReadableBytes()).To(BeEquivalentTo(6))
+				// data received after the cancellation is not delivered either
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(8), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("xx")})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				b := make([]byte, 4)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foob")))
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2))
+				n, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(errMsg))
+				Expect(b[:n]).To(Equal([]byte("ar")))
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(errMsg))
+			})
+
+			It("cancels reading right away if no data is buffered", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelReadAfterBuffered(1234)
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(errMsg))
+			})
+
+			It("completes the stream when the FIN is received after the data was read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelReadAfterBuffered(1234)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				data, err := io.ReadAll(str)
+				Expect(err).To(MatchError(errMsg))
+				Expect(data).To(Equal([]byte("foobar")))
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(100), true),
+					mockFC.EXPECT().Abandon(),
+				)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 100, Fin: true})).To(Succeed())
+			})
+
+			It("delivers the data if the peer resets the stream, and completes the stream once it was read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				str.CancelReadAfterBuffered(1234)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 42,
+					ErrorCode: 4321,
+				})).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+				gomock.InOrder(
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6)),
+					mockFC.EXPECT().Abandon(),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				data, err := io.ReadAll(str)
+				Expect(err).To(MatchError(errMsg))
+				Expect(data).To(Equal([]byte("foobar")))
+			})
+
+			It("discards the buffered data when CancelRead is called", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any()) // only a single STOP_SENDING frame is sent
+				str.CancelReadAfterBuffered(1234)
+				str.CancelReadAfterBuffered(1234)
+				str.CancelRead(1234)
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(errMsg))
+			})
+		})
+
+		Context("canceling read after an offset", func() {
+			const errMsg = "Read on stream 1337 canceled with error code 1234"
+
+			It("delivers the data up to the offset, and sends STOP_SENDING once it was received", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foob")})).To(Succeed())
+				str.CancelReadAfter(8, 1234)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				b := make([]byte, 4)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foob")))
+				// data beyond the offset is not delivered
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(14), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 10, Data: []byte("xxxx")})).To(Succeed())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("ar4242")})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				n, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(errMsg))
+				Expect(b[:n]).To(Equal([]byte("ar42")))
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(errMsg))
+			})
+
+			It("sends STOP_SENDING right away if all data up to the offset was already received", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				str.CancelReadAfter(3, 1234)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				data, err := io.ReadAll(str)
+				Expect(err).To(MatchError(errMsg))
+				Expect(data).To(Equal([]byte("foo")))
+			})
+
+			It("cancels reading right away if the data up to the offset was already read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				_, err := strWithTimeout.Read(make([]byte, 4))
+				Expect(err).ToNot(HaveOccurred())
+				mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				str.CancelReadAfter(2, 1234)
+				_, err = strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(errMsg))
+			})
+
+			It("delivers all data if the stream ends before the offset", func() {
+				str.CancelReadAfter(100, 1234)
+				gomock.InOrder(
+					mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true),
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6)),
+				)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), Fin: true})).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+			})
+
+			It("is a no-op if the final offset is smaller than the offset", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar"), Fin: true})).To(Succeed())
+				str.CancelReadAfter(10, 1234)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data, err := io.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(data).To(Equal([]byte("foobar")))
+			})
+
+			It("delivers the data up to the offset if the peer resets the stream before all data was received", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(4), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foob")})).To(Succeed())
+				str.CancelReadAfter(6, 1234)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					FinalSize:    42,
+					ReliableSize: 20,
+					ErrorCode:    4321,
+				})).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 4, Data: []byte("ar4242")})).To(Succeed())
+				gomock.InOrder(
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4)),
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)),
+					mockFC.EXPECT().Abandon(),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				data, err := io.ReadAll(str)
+				Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 4321}))
+				Expect(data).To(Equal([]byte("foobar")))
+			})
+
+			It("sends STOP_SENDING when CancelRead is called before all data was received", func() {
+				str.CancelReadAfter(6, 1234)
+				str.CancelReadAfter(3, 1234) // no-op
+				mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+				str.CancelRead(1234)
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(errMsg))
+			})
+		})
+
 		Context("receiving RESET_STREAM frames", func() {
 			rst := &wire.ResetStreamFrame{
 				StreamID:  streamID,
@@ -601,6 +1551,22 @@ var _ = Describe("Receive Stream", func() {
 				}))
 			})
 
+			It("wraps the error registered for the error code", func() {
+				errCanceled := errors.New("request canceled")
+				str.errorRegistry = newStreamErrorRegistry()
+				Expect(str.errorRegistry.Register(1234, errCanceled)).To(Succeed())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(errCanceled))
+				var streamErr *StreamError
+				Expect(errors.As(err, &streamErr)).To(BeTrue())
+				Expect(streamErr.ErrorCode).To(BeEquivalentTo(1234))
+				Expect(err.Error()).To(Equal("stream 1337 canceled with error code 1234: request canceled"))
+			})
+
 			It("errors when receiving a RESET_STREAM with an inconsistent offset", func() {
 				testErr := errors.New("already received a different final offset before")
 				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true).Return(testErr)
@@ -636,6 +1602,110 @@ var _ = Describe("Receive Stream", func() {
 				Expect(err).ToNot(HaveOccurred())
 			})
 		})
+
+		Context("receiving RESET_STREAM_AT frames", func() {
+			rst := &wire.ResetStreamFrame{
+				StreamID:     streamID,
+				FinalSize:    42,
+				ErrorCode:    1234,
+				ReliableSize: 6,
+			}
+
+			It("delivers the data up to the reliable size, and then resets the stream", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobarbaz!")})).To(Succeed())
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				Expect(str.PeerClosed()).To(BeClosed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(4))
+				b := make([]byte, 4)
+				n, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(b[:n]).To(Equal([]byte("foob")))
+				gomock.InOrder(
+					mockFC.EXPECT().AddBytesRead(protocol.ByteCount(2)),
+					mockFC.EXPECT().Abandon(),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				n, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+				Expect(b[:n]).To(Equal([]byte("ar")))
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+			})
+
+			It("waits for the data up to the reliable size", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					data, err := io.ReadAll(str)
+					Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+					Expect(data).To(Equal([]byte("foobar")))
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobarbaz!")})).To(Succeed())
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("resets the stream immediately, if the data up to the reliable size was already read", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(6), false)
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(6))
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobar")})).To(Succeed())
+				b := make([]byte, 6)
+				_, err := strWithTimeout.Read(b)
+				Expect(err).ToNot(HaveOccurred())
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				_, err = strWithTimeout.Read(b)
+				Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+			})
+
+			It("reduces the reliable size when receiving another RESET_STREAM_AT frame", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true).Times(3)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobarbaz!")})).To(Succeed())
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				// a larger reliable size is ignored
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{StreamID: streamID, FinalSize: 42, ErrorCode: 1234, ReliableSize: 8})).To(Succeed())
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{StreamID: streamID, FinalSize: 42, ErrorCode: 1234, ReliableSize: 3})).To(Succeed())
+				mockFC.EXPECT().AddBytesRead(protocol.ByteCount(3))
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				data, err := io.ReadAll(str)
+				Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+				Expect(data).To(Equal([]byte("foo")))
+			})
+
+			It("resets the stream when receiving a RESET_STREAM frame", func() {
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true).Times(2)
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				mockFC.EXPECT().Abandon()
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{StreamID: streamID, FinalSize: 42, ErrorCode: 1234})).To(Succeed())
+				_, err := strWithTimeout.Read([]byte{0})
+				Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+			})
+
+			It("doesn't peek beyond the reliable size", func() {
+				mockFC.EXPECT().State().Return(flowcontrol.WindowState{ReceiveWindow: 1000, MaxReceiveWindowSize: 1000}).AnyTimes()
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(10), false)
+				mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(42), true)
+				Expect(str.handleStreamFrame(&wire.StreamFrame{Data: []byte("foobarbaz!")})).To(Succeed())
+				Expect(str.handleResetStreamFrame(rst)).To(Succeed())
+				b, err := str.Peek(8)
+				Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+				Expect(b).To(Equal([]byte("foobar")))
+			})
+		})
 	})
 
 	Context("flow control", func() {
@@ -654,5 +1724,96 @@ var _ = Describe("Receive Stream", func() {
 			mockFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0x100))
 			Expect(str.getWindowUpdate()).To(Equal(protocol.ByteCount(0x100)))
 		})
+
+		It("sets the receive window", func() {
+			mockFC.EXPECT().SetMaxReceiveWindow(protocol.ByteCount(1 << 20))
+			str.SetReceiveWindow(1 << 20)
+			str.SetReceiveWindow(0) // ignored
+		})
+
+		It("grants receive credit", func() {
+			mockFC.EXPECT().GrantCredit(protocol.ByteCount(1337))
+			str.GrantReceiveCredit(1337)
+		})
+
+		It("limits the credit to the maximum byte count", func() {
+			mockFC.EXPECT().GrantCredit(protocol.MaxByteCount)
+			str.GrantReceiveCredit(math.MaxUint64)
+		})
+	})
+
+	Context("limiting the reassembly memory", func() {
+		// every frame holds 1000 bytes of memory, plus the frameSorterEntryOverhead
+		frameAt := func(offset protocol.ByteCount) *wire.StreamFrame {
+			return &wire.StreamFrame{
+				StreamID: streamID,
+				Offset:   offset,
+				Data:     make([]byte, 10, 1000),
+			}
+		}
+
+		It("cancels reading when the stream's limit is exceeded", func() {
+			str.setReassemblyMemory(newReassemblyMemory(2500, 0, 42))
+			mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false).Times(3)
+			Expect(str.handleStreamFrame(frameAt(100))).To(Succeed())
+			Expect(str.handleStreamFrame(frameAt(200))).To(Succeed())
+			mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{
+				StreamID:  streamID,
+				ErrorCode: 42,
+			})
+			Expect(str.handleStreamFrame(frameAt(300))).To(Succeed())
+			Expect(str.frameQueue.Memory()).To(BeZero())
+			_, err := strWithTimeout.Read(make([]byte, 10))
+			Expect(err).To(HaveOccurred())
+			var memErr *StreamReassemblyMemoryError
+			Expect(errors.As(err, &memErr)).To(BeTrue())
+			Expect(memErr.StreamID).To(Equal(streamID))
+			Expect(memErr.ErrorCode).To(BeEquivalentTo(42))
+			Expect(memErr.Memory).To(BeEquivalentTo(3000 + 3*frameSorterEntryOverhead))
+			Expect(memErr.Limit).To(BeEquivalentTo(2500))
+			var streamErr *StreamError
+			Expect(errors.As(err, &streamErr)).To(BeTrue())
+			// frames received after canceling are ignored
+			mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false)
+			Expect(str.handleStreamFrame(frameAt(400))).To(Succeed())
+		})
+
+		It("errors when the connection's limit is exceeded", func() {
+			m := newReassemblyMemory(0, 2500, 0)
+			str.setReassemblyMemory(m)
+			otherStr := newReceiveStream(streamID+4, mockSender, mockFC, protocol.VersionWhatever)
+			otherStr.setReassemblyMemory(m)
+			mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false).Times(3)
+			Expect(str.handleStreamFrame(frameAt(100))).To(Succeed())
+			Expect(otherStr.handleStreamFrame(frameAt(100))).To(Succeed())
+			err := str.handleStreamFrame(frameAt(200))
+			Expect(err).To(HaveOccurred())
+			var memErr *ConnectionReassemblyMemoryError
+			Expect(errors.As(err, &memErr)).To(BeTrue())
+			Expect(memErr.Memory).To(BeEquivalentTo(3000 + 3*frameSorterEntryOverhead))
+			Expect(memErr.Limit).To(BeEquivalentTo(2500))
+			var transportErr *TransportError
+			Expect(errors.As(err, &transportErr)).To(BeTrue())
+			Expect(transportErr.ErrorCode).To(Equal(InternalError))
+		})
+
+		It("releases the memory when the stream is completed", func() {
+			m := newReassemblyMemory(0, 1<<20, 0)
+			str.setReassemblyMemory(m)
+			mockFC.EXPECT().UpdateHighestReceived(gomock.Any(), false)
+			Expect(str.handleStreamFrame(frameAt(100))).To(Succeed())
+			Expect(m.Used()).ToNot(BeZero())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			str.CancelRead(1234)
+			mockFC.EXPECT().UpdateHighestReceived(protocol.ByteCount(1000), true)
+			mockFC.EXPECT().Abandon()
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			Expect(str.handleResetStreamFrame(&wire.ResetStreamFrame{StreamID: streamID, FinalSize: 1000})).To(Succeed())
+			Expect(m.Used()).To(BeZero())
+		})
 	})
 })
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
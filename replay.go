@@ -0,0 +1,290 @@
+package quic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// ReplayKeys are the TLS secrets of a connection, as written to the tls.Config.KeyLogWriter.
+type ReplayKeys = handshake.FixedKeys
+
+// A ReplayPacket is a UDP datagram received by the endpoint whose connection is replayed.
+type ReplayPacket struct {
+	Data    []byte
+	RcvTime time.Time
+}
+
+// A ReplayTrace is a recorded trace of the packets received by one endpoint of a QUIC connection.
+type ReplayTrace struct {
+	// Perspective is the perspective of the endpoint that received the packets.
+	Perspective logging.Perspective
+	Version     VersionNumber
+
+	// OriginalDestConnectionID is the destination connection ID of the client's first Initial packet.
+	OriginalDestConnectionID []byte
+	// ClientConnectionID is the source connection ID of the client's first Initial packet.
+	ClientConnectionID []byte
+	// ServerConnectionID is the source connection ID of the server's first Initial packet.
+	ServerConnectionID []byte
+	// RetrySourceConnectionID is the source connection ID of the Retry packet sent by the server.
+	// It is only needed when replaying a server that performed a Retry.
+	RetrySourceConnectionID []byte
+
+	// LocalAddr and RemoteAddr are the addresses of the endpoint and of its peer.
+	// They may be nil.
+	LocalAddr, RemoteAddr net.Addr
+
+	Keys ReplayKeys
+
+	Packets []ReplayPacket
+}
+
+// A ReplayError is returned by Replay when processing a packet closed the connection.
+type ReplayError struct {
+	// Packet is the index of the packet in ReplayTrace.Packets.
+	Packet int
+	// Err is the error that closed the connection.
+	Err error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("replaying packet %d closed the connection: %s", e.Packet, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error { return e.Err }
+
+// Replay feeds the packets of a recorded trace into the receive path of a new session.
+// It is intended for reproducing crashes and bugs in the connection state machine,
+// and MUST NOT be used on untrusted input in production.
+//
+// No TLS handshake is run. Instead, the session uses the fixed keys from the trace,
+// and installs them at the same point in the handshake where TLS would have derived them.
+// The packets are processed one after another, in a single goroutine, using their recorded receive times.
+// No packets are sent, and timers don't fire, so replaying the same trace always leads to the same result.
+// Since the packets sent by the endpoint are not replayed, the ACK frames received from the peer are ignored.
+//
+// If processing a packet closes the connection, Replay returns a ReplayError.
+// Panics are not recovered, unless the Config sets a PanicHandler.
+// The Config.Tracer can be used to obtain a qlog of the replayed connection.
+func Replay(trace *ReplayTrace, config *Config) error {
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+	if !protocol.IsValidVersion(trace.Version) {
+		return fmt.Errorf("replay: unsupported version %s", trace.Version)
+	}
+	var conf *Config
+	var localConnID protocol.ConnectionID
+	switch trace.Perspective {
+	case logging.PerspectiveServer:
+		conf = populateServerConfig(config)
+		localConnID = trace.ServerConnectionID
+	case logging.PerspectiveClient:
+		conf = populateClientConfig(config, false)
+		localConnID = trace.ClientConnectionID
+	default:
+		return errors.New("replay: invalid perspective")
+	}
+	// Only the length of the connection IDs issued by the session is relevant for parsing packets.
+	conf.ConnectionIDGenerator = nil
+	conf.ConnectionIDLength = localConnID.Len()
+
+	odcid := protocol.ConnectionID(trace.OriginalDestConnectionID)
+	var tracer logging.ConnectionTracer
+	if conf.Tracer != nil {
+		tracer = conf.Tracer.TracerForConnection(context.Background(), trace.Perspective, odcid)
+	}
+	logger := utils.DefaultLogger.WithPrefix("replay")
+	conn := &replayConn{localAddr: trace.LocalAddr, remoteAddr: trace.RemoteAddr}
+	if conn.localAddr == nil {
+		conn.localAddr = &net.UDPAddr{}
+	}
+	if conn.remoteAddr == nil {
+		conn.remoteAddr = &net.UDPAddr{}
+	}
+
+	var s *session
+	initialConnID := odcid
+	if trace.Perspective == logging.PerspectiveServer {
+		tokenGenerator, err := handshake.NewTokenGenerator(rand.Reader)
+		if err != nil {
+			return err
+		}
+		var origDestConnID protocol.ConnectionID
+		var retrySrcConnID *protocol.ConnectionID
+		if trace.RetrySourceConnectionID != nil {
+			rscid := protocol.ConnectionID(trace.RetrySourceConnectionID)
+			origDestConnID = odcid
+			retrySrcConnID = &rscid
+			initialConnID = rscid
+		}
+		s = newSession(
+			conn,
+			replaySessionRunner{},
+			origDestConnID,
+			retrySrcConnID,
+			initialConnID,
+			trace.ClientConnectionID,
+			localConnID,
+			protocol.StatelessResetToken{},
+			conf,
+			&tls.Config{},
+			tokenGenerator,
+			false,
+			tracer,
+			0,
+			logger,
+			trace.Version,
+		).(*session)
+	} else {
+		s = newClientSession(
+			conn,
+			replaySessionRunner{},
+			odcid,
+			localConnID,
+			conf,
+			&tls.Config{},
+			0,
+			false,
+			false,
+			tracer,
+			0,
+			logger,
+			trace.Version,
+		).(*session)
+	}
+	cs, err := handshake.NewFixedKeysCryptoSetup(
+		initialConnID,
+		&trace.Keys,
+		&handshakeRunner{
+			onReceivedParams:    s.handleTransportParameters,
+			onError:             s.closeLocal,
+			onKeysAvailable:     s.onKeysAvailable,
+			dropKeys:            s.dropEncryptionLevel,
+			onHandshakeComplete: func() { close(s.handshakeCompleteChan) },
+		},
+		s.rttStats,
+		tracer,
+		logger,
+		trace.Perspective,
+		trace.Version,
+	)
+	if err != nil {
+		return err
+	}
+	s.cryptoStreamHandler = cs
+	s.unpacker = newPacketUnpacker(cs, s.version)
+	oneRTTStream := s.oneRTTStream // only set for the server
+	if oneRTTStream == nil {
+		oneRTTStream = newCryptoStream(protocol.ByteCount(conf.MaxOneRTTCryptoData), conf.MaxCryptoFrameFragments)
+	}
+	s.cryptoStreamManager = newCryptoStreamManager(
+		cs,
+		newCryptoStream(protocol.ByteCount(conf.MaxInitialCryptoData), conf.MaxCryptoFrameFragments),
+		newHandshakeCryptoStream(protocol.ByteCount(conf.MaxHandshakeCryptoData), conf.MaxCryptoFrameFragments, conf.MaxCertificateChainSize),
+		oneRTTStream,
+		tracer,
+	)
+	s.sentPacketHandler = &replaySentPacketHandler{SentPacketHandler: s.sentPacketHandler}
+
+	err = s.replay(trace.Packets)
+	if tracer != nil {
+		tracer.Close()
+	}
+	return err
+}
+
+func (s *session) replay(packets []ReplayPacket) (err error) {
+	var i int
+	if s.config.PanicHandler != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &ReplayError{Packet: i, Err: s.handlePanic(r)}
+			}
+		}()
+	}
+	for ; i < len(packets); i++ {
+		p := packets[i]
+		buffer := getPacketBuffer()
+		buffer.Data = append(buffer.Data[:0], p.Data...)
+		queue := []*receivedPacket{{
+			buffer:     buffer,
+			remoteAddr: s.conn.RemoteAddr(),
+			rcvTime:    p.RcvTime,
+			data:       buffer.Data,
+		}}
+		// Process undecryptable packets as soon as the keys become available, like the run loop does.
+		for len(queue) > 0 {
+			for _, rp := range queue {
+				s.handlePacketImpl(rp)
+			}
+			select {
+			case <-s.handshakeCompleteChan:
+				s.handleHandshakeComplete()
+			default:
+			}
+			queue = s.undecryptablePacketsToProcess
+			s.undecryptablePacketsToProcess = nil
+		}
+		select {
+		case closeErr := <-s.closeChan:
+			if s.tracer != nil {
+				s.tracer.ClosedConnection(closeErr.err)
+			}
+			return &ReplayError{Packet: i, Err: closeErr.err}
+		default:
+		}
+	}
+	return nil
+}
+
+// replayConn is the sendConn used when replaying a trace. It discards all packets.
+type replayConn struct {
+	localAddr, remoteAddr net.Addr
+}
+
+var _ sendConn = &replayConn{}
+
+func (c *replayConn) Write([]byte) error                { return nil }
+func (c *replayConn) WriteWithDSCP([]byte, uint8) error { return nil }
+func (c *replayConn) Close() error                      { return nil }
+func (c *replayConn) LocalAddr() net.Addr               { return c.localAddr }
+func (c *replayConn) RemoteAddr() net.Addr              { return c.remoteAddr }
+func (c *replayConn) SetRemoteAddr(addr net.Addr)       { c.remoteAddr = addr }
+
+// replaySentPacketHandler is the SentPacketHandler used when replaying a trace.
+// The packets acknowledged by the peer were sent when the trace was recorded, but not when replaying it.
+// ACKs are therefore not passed to the loss detection logic, which would reject them as acknowledging unsent packets.
+type replaySentPacketHandler struct {
+	ackhandler.SentPacketHandler
+}
+
+func (h *replaySentPacketHandler) ReceivedAck(_ *wire.AckFrame, encLevel protocol.EncryptionLevel, _ time.Time) (bool, error) {
+	return encLevel == protocol.Encryption1RTT, nil
+}
+
+type replaySessionRunner struct{}
+
+var _ sessionRunner = replaySessionRunner{}
+
+func (replaySessionRunner) Add(protocol.ConnectionID, packetHandler) bool { return true }
+func (replaySessionRunner) GetStatelessResetToken(protocol.ConnectionID) protocol.StatelessResetToken {
+	return protocol.StatelessResetToken{}
+}
+func (replaySessionRunner) Retire(protocol.ConnectionID)                              {}
+func (replaySessionRunner) Remove(protocol.ConnectionID)                              {}
+func (replaySessionRunner) ReplaceWithClosed(protocol.ConnectionID, packetHandler)    {}
+func (replaySessionRunner) AddResetToken(protocol.StatelessResetToken, packetHandler) {}
+func (replaySessionRunner) RemoveResetToken(protocol.StatelessResetToken)             {}
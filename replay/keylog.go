@@ -0,0 +1,64 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// A KeyLog contains the TLS secrets of one or more connections, indexed by the client random.
+type KeyLog map[[32]byte]*quic.ReplayKeys
+
+// ReadKeyLog reads a key log in the NSS key log format, as written to the tls.Config.KeyLogWriter.
+// Labels other than the TLS 1.3 traffic secrets are ignored.
+func ReadKeyLog(r io.Reader) (KeyLog, error) {
+	keyLog := make(KeyLog)
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("replay: invalid key log line %d", lineNum)
+		}
+		random, err := hex.DecodeString(fields[1])
+		if err != nil || len(random) != 32 {
+			return nil, fmt.Errorf("replay: invalid client random in key log line %d", lineNum)
+		}
+		secret, err := hex.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("replay: invalid secret in key log line %d", lineNum)
+		}
+		var cr [32]byte
+		copy(cr[:], random)
+		keys, ok := keyLog[cr]
+		if !ok {
+			keys = &quic.ReplayKeys{}
+		}
+		switch fields[0] {
+		case "CLIENT_EARLY_TRAFFIC_SECRET":
+			keys.ClientEarlyTrafficSecret = secret
+		case "CLIENT_HANDSHAKE_TRAFFIC_SECRET":
+			keys.ClientHandshakeTrafficSecret = secret
+		case "SERVER_HANDSHAKE_TRAFFIC_SECRET":
+			keys.ServerHandshakeTrafficSecret = secret
+		case "CLIENT_TRAFFIC_SECRET_0":
+			keys.ClientTrafficSecret = secret
+		case "SERVER_TRAFFIC_SECRET_0":
+			keys.ServerTrafficSecret = secret
+		default:
+			continue
+		}
+		keyLog[cr] = keys
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return keyLog, nil
+}
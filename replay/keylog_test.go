@@ -0,0 +1,47 @@
+package replay
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Key Log", func() {
+	random1 := strings.Repeat("01", 32)
+	random2 := strings.Repeat("02", 32)
+
+	It("reads the secrets of multiple connections", func() {
+		keyLog, err := ReadKeyLog(strings.NewReader(strings.Join([]string{
+			"# a comment",
+			"CLIENT_HANDSHAKE_TRAFFIC_SECRET " + random1 + " 0a",
+			"SERVER_HANDSHAKE_TRAFFIC_SECRET " + random1 + " 0b",
+			"CLIENT_TRAFFIC_SECRET_0 " + random1 + " 0c",
+			"SERVER_TRAFFIC_SECRET_0 " + random1 + " 0d",
+			"",
+			"CLIENT_EARLY_TRAFFIC_SECRET " + random2 + " 0e",
+			"EXPORTER_SECRET " + random2 + " 0f",
+		}, "\n")))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(keyLog).To(HaveLen(2))
+		keys := keyLog[[32]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}]
+		Expect(keys).ToNot(BeNil())
+		Expect(keys.ClientHandshakeTrafficSecret).To(Equal([]byte{0xa}))
+		Expect(keys.ServerHandshakeTrafficSecret).To(Equal([]byte{0xb}))
+		Expect(keys.ClientTrafficSecret).To(Equal([]byte{0xc}))
+		Expect(keys.ServerTrafficSecret).To(Equal([]byte{0xd}))
+		Expect(keys.ClientEarlyTrafficSecret).To(BeEmpty())
+		keys = keyLog[[32]byte{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}]
+		Expect(keys).ToNot(BeNil())
+		Expect(keys.ClientEarlyTrafficSecret).To(Equal([]byte{0xe}))
+	})
+
+	It("errors on invalid lines", func() {
+		_, err := ReadKeyLog(strings.NewReader("CLIENT_TRAFFIC_SECRET_0 " + random1))
+		Expect(err).To(MatchError("replay: invalid key log line 1"))
+		_, err = ReadKeyLog(strings.NewReader("\nCLIENT_TRAFFIC_SECRET_0 0102 0a"))
+		Expect(err).To(MatchError("replay: invalid client random in key log line 2"))
+		_, err = ReadKeyLog(strings.NewReader("CLIENT_TRAFFIC_SECRET_0 " + random1 + " foobar"))
+		Expect(err).To(MatchError("replay: invalid secret in key log line 1"))
+	})
+})
@@ -0,0 +1,177 @@
+package replay
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// A Datagram is a UDP datagram read from a packet capture.
+type Datagram struct {
+	Time     time.Time
+	Src, Dst *net.UDPAddr
+	Data     []byte
+}
+
+// link types, see https://www.tcpdump.org/linktypes.html
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLinuxSLL = 113
+	linkTypeIPv4     = 228
+	linkTypeIPv6     = 229
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+
+	ipProtoUDP = 17
+)
+
+// ReadPcap reads all UDP datagrams from a packet capture in the pcap format, as written by tcpdump -w.
+// The pcapng format is not supported.
+// Supported link types are Ethernet, raw IP, BSD loopback and Linux cooked captures.
+// Packets that are not UDP, fragmented or truncated are skipped.
+func ReadPcap(r io.Reader) ([]Datagram, error) {
+	var hdr [24]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("replay: reading pcap header failed: %w", err)
+	}
+	var order binary.ByteOrder
+	var nanos bool
+	switch magic := binary.LittleEndian.Uint32(hdr[:4]); magic {
+	case 0xa1b2c3d4:
+		order = binary.LittleEndian
+	case 0xa1b23c4d:
+		order, nanos = binary.LittleEndian, true
+	case 0xd4c3b2a1:
+		order = binary.BigEndian
+	case 0x4d3cb2a1:
+		order, nanos = binary.BigEndian, true
+	default:
+		return nil, errors.New("replay: not a pcap file")
+	}
+	linkType := order.Uint32(hdr[20:24]) & 0xfffffff
+
+	var datagrams []Datagram
+	var recordHdr [16]byte
+	for {
+		if _, err := io.ReadFull(r, recordHdr[:]); err != nil {
+			if err == io.EOF {
+				return datagrams, nil
+			}
+			return nil, fmt.Errorf("replay: reading pcap record failed: %w", err)
+		}
+		sec := int64(order.Uint32(recordHdr[0:4]))
+		frac := int64(order.Uint32(recordHdr[4:8]))
+		if !nanos {
+			frac *= 1000
+		}
+		data := make([]byte, order.Uint32(recordHdr[8:12]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, fmt.Errorf("replay: reading pcap record failed: %w", err)
+		}
+		if order.Uint32(recordHdr[12:16]) != uint32(len(data)) { // truncated
+			continue
+		}
+		d, ok := parseLinkLayer(linkType, data)
+		if !ok {
+			continue
+		}
+		d.Time = time.Unix(sec, frac)
+		datagrams = append(datagrams, d)
+	}
+}
+
+func parseLinkLayer(linkType uint32, data []byte) (Datagram, bool) {
+	switch linkType {
+	case linkTypeNull:
+		// The address family is in host byte order.
+		// Just look at the IP version instead.
+		if len(data) < 4 {
+			return Datagram{}, false
+		}
+		return parseIP(data[4:])
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return Datagram{}, false
+		}
+		etherType := binary.BigEndian.Uint16(data[12:14])
+		data = data[14:]
+		if etherType == etherTypeVLAN {
+			if len(data) < 4 {
+				return Datagram{}, false
+			}
+			etherType = binary.BigEndian.Uint16(data[2:4])
+			data = data[4:]
+		}
+		if etherType != etherTypeIPv4 && etherType != etherTypeIPv6 {
+			return Datagram{}, false
+		}
+		return parseIP(data)
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return Datagram{}, false
+		}
+		return parseIP(data[16:])
+	case linkTypeRaw, linkTypeIPv4, linkTypeIPv6:
+		return parseIP(data)
+	default:
+		return Datagram{}, false
+	}
+}
+
+func parseIP(data []byte) (Datagram, bool) {
+	if len(data) == 0 {
+		return Datagram{}, false
+	}
+	var src, dst net.IP
+	switch data[0] >> 4 {
+	case 4:
+		if len(data) < 20 {
+			return Datagram{}, false
+		}
+		hdrLen := int(data[0]&0xf) * 4
+		totalLen := int(binary.BigEndian.Uint16(data[2:4]))
+		// skip fragments
+		if binary.BigEndian.Uint16(data[6:8])&0x3fff != 0 {
+			return Datagram{}, false
+		}
+		if data[9] != ipProtoUDP || hdrLen < 20 || totalLen < hdrLen || len(data) < totalLen {
+			return Datagram{}, false
+		}
+		src, dst = net.IP(data[12:16]), net.IP(data[16:20])
+		data = data[hdrLen:totalLen]
+	case 6:
+		if len(data) < 40 {
+			return Datagram{}, false
+		}
+		payloadLen := int(binary.BigEndian.Uint16(data[4:6]))
+		// extension headers are not supported
+		if data[6] != ipProtoUDP || len(data) < 40+payloadLen {
+			return Datagram{}, false
+		}
+		src, dst = net.IP(data[8:24]), net.IP(data[24:40])
+		data = data[40 : 40+payloadLen]
+	default:
+		return Datagram{}, false
+	}
+	if len(data) < 8 {
+		return Datagram{}, false
+	}
+	udpLen := int(binary.BigEndian.Uint16(data[4:6]))
+	if udpLen < 8 || len(data) < udpLen {
+		return Datagram{}, false
+	}
+	return Datagram{
+		Src:  &net.UDPAddr{IP: src, Port: int(binary.BigEndian.Uint16(data[0:2]))},
+		Dst:  &net.UDPAddr{IP: dst, Port: int(binary.BigEndian.Uint16(data[2:4]))},
+		Data: data[8:udpLen],
+	}, true
+}
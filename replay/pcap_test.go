@@ -0,0 +1,110 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pcap", func() {
+	udpPacket := func(payload []byte) []byte {
+		b := make([]byte, 8, 8+len(payload))
+		binary.BigEndian.PutUint16(b[0:2], 1234)
+		binary.BigEndian.PutUint16(b[2:4], 4321)
+		binary.BigEndian.PutUint16(b[4:6], uint16(8+len(payload)))
+		return append(b, payload...)
+	}
+
+	ipv4Packet := func(proto byte, payload []byte) []byte {
+		b := make([]byte, 20, 20+len(payload))
+		b[0] = 0x45
+		binary.BigEndian.PutUint16(b[2:4], uint16(20+len(payload)))
+		b[9] = proto
+		copy(b[12:16], net.IPv4(10, 0, 0, 1).To4())
+		copy(b[16:20], net.IPv4(10, 0, 0, 2).To4())
+		return append(b, payload...)
+	}
+
+	ethernetFrame := func(payload []byte) []byte {
+		b := make([]byte, 14, 14+len(payload))
+		binary.BigEndian.PutUint16(b[12:14], etherTypeIPv4)
+		return append(b, payload...)
+	}
+
+	writePcap := func(order binary.ByteOrder, linkType uint32, packets ...[]byte) []byte {
+		buf := &bytes.Buffer{}
+		hdr := make([]byte, 24)
+		order.PutUint32(hdr[0:4], 0xa1b2c3d4)
+		order.PutUint16(hdr[4:6], 2)
+		order.PutUint16(hdr[6:8], 4)
+		order.PutUint32(hdr[16:20], 65535)
+		order.PutUint32(hdr[20:24], linkType)
+		buf.Write(hdr)
+		for i, p := range packets {
+			rec := make([]byte, 16)
+			order.PutUint32(rec[0:4], uint32(1000+i))
+			order.PutUint32(rec[4:8], 42)
+			order.PutUint32(rec[8:12], uint32(len(p)))
+			order.PutUint32(rec[12:16], uint32(len(p)))
+			buf.Write(rec)
+			buf.Write(p)
+		}
+		return buf.Bytes()
+	}
+
+	for _, o := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		order := o
+
+		It("reads UDP datagrams from Ethernet frames, using "+order.String(), func() {
+			datagrams, err := ReadPcap(bytes.NewReader(writePcap(
+				order,
+				linkTypeEthernet,
+				ethernetFrame(ipv4Packet(ipProtoUDP, udpPacket([]byte("foo")))),
+				ethernetFrame(ipv4Packet(6, []byte("not UDP"))),
+				ethernetFrame(ipv4Packet(ipProtoUDP, udpPacket([]byte("bar")))),
+			)))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(datagrams).To(HaveLen(2))
+			Expect(datagrams[0].Data).To(Equal([]byte("foo")))
+			Expect(datagrams[0].Src.String()).To(Equal("10.0.0.1:1234"))
+			Expect(datagrams[0].Dst.String()).To(Equal("10.0.0.2:4321"))
+			Expect(datagrams[0].Time).To(Equal(time.Unix(1000, 42000)))
+			Expect(datagrams[1].Data).To(Equal([]byte("bar")))
+			Expect(datagrams[1].Time).To(Equal(time.Unix(1002, 42000)))
+		})
+	}
+
+	It("reads raw IP packets", func() {
+		datagrams, err := ReadPcap(bytes.NewReader(writePcap(
+			binary.LittleEndian,
+			linkTypeRaw,
+			ipv4Packet(ipProtoUDP, udpPacket([]byte("foobar"))),
+		)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(datagrams).To(HaveLen(1))
+		Expect(datagrams[0].Data).To(Equal([]byte("foobar")))
+	})
+
+	It("skips fragmented packets", func() {
+		p := ipv4Packet(ipProtoUDP, udpPacket([]byte("foobar")))
+		p[6] = 0x20 // more fragments
+		datagrams, err := ReadPcap(bytes.NewReader(writePcap(binary.LittleEndian, linkTypeRaw, p)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(datagrams).To(BeEmpty())
+	})
+
+	It("errors on files that are not pcap files", func() {
+		_, err := ReadPcap(bytes.NewReader(make([]byte, 24)))
+		Expect(err).To(MatchError("replay: not a pcap file"))
+	})
+
+	It("errors on truncated files", func() {
+		data := writePcap(binary.LittleEndian, linkTypeRaw, ipv4Packet(ipProtoUDP, udpPacket([]byte("foobar"))))
+		_, err := ReadPcap(bytes.NewReader(data[:len(data)-1]))
+		Expect(err).To(HaveOccurred())
+	})
+})
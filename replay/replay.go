@@ -0,0 +1,181 @@
+// Package replay converts packet captures into traces that can be replayed using quic.Replay.
+//
+// A trace is created from a packet capture (see ReadPcap) and the TLS secrets of the connection (see ReadKeyLog).
+// The secrets are written by crypto/tls if the tls.Config.KeyLogWriter is set,
+// and many other QUIC implementations support the SSLKEYLOGFILE environment variable.
+// Replaying a trace is deterministic, which makes it possible to reproduce crashes and
+// state machine bugs that were observed in production:
+//
+//	datagrams, _ := replay.ReadPcap(pcapFile)
+//	keyLog, _ := replay.ReadKeyLog(keyLogFile)
+//	trace, _ := replay.NewTrace(datagrams, keyLog, logging.PerspectiveServer)
+//	err := quic.Replay(trace, conf)
+package replay
+
+import (
+	"bytes"
+	"errors"
+	"net"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+)
+
+// NewTrace creates a trace of the first QUIC connection found in a list of datagrams.
+// The connection starts with the first Initial packet sent by the client.
+// If the server responds with a Version Negotiation packet, the connection starts with the client's next Initial.
+// The trace contains the datagrams received by the endpoint with the given perspective.
+//
+// The keys of the connection are selected from the key log using the client random of the ClientHello.
+// If the key log contains the keys of a single connection, these keys are used.
+func NewTrace(datagrams []Datagram, keyLog KeyLog, pers logging.Perspective) (*quic.ReplayTrace, error) {
+	if pers != logging.PerspectiveClient && pers != logging.PerspectiveServer {
+		return nil, errors.New("replay: invalid perspective")
+	}
+	var trace *quic.ReplayTrace
+	var client, server *net.UDPAddr
+	var clientInitial []byte
+	var start int
+	for i, d := range datagrams {
+		if trace != nil {
+			if equalAddr(d.Src, server) && equalAddr(d.Dst, client) && wire.IsVersionNegotiationPacket(d.Data) {
+				trace = nil
+			}
+			continue
+		}
+		hdr, _, _, err := wire.ParsePacket(d.Data, 0)
+		if err != nil || !hdr.IsLongHeader || hdr.Type != protocol.PacketTypeInitial {
+			continue
+		}
+		client, server = d.Src, d.Dst
+		clientInitial = d.Data
+		start = i
+		trace = &quic.ReplayTrace{
+			Perspective:              pers,
+			Version:                  hdr.Version,
+			OriginalDestConnectionID: hdr.DestConnectionID,
+			ClientConnectionID:       hdr.SrcConnectionID,
+		}
+	}
+	if trace == nil {
+		return nil, errors.New("replay: no Initial packet found")
+	}
+
+	// The server's connection ID is only contained in the packets sent by the server.
+	var retry int
+	for i, d := range datagrams[start:] {
+		if !equalAddr(d.Src, server) || !equalAddr(d.Dst, client) || wire.IsVersionNegotiationPacket(d.Data) {
+			continue
+		}
+		hdr, _, _, err := wire.ParsePacket(d.Data, 0)
+		if err != nil || !hdr.IsLongHeader {
+			continue
+		}
+		if hdr.Type == protocol.PacketTypeRetry {
+			if pers == logging.PerspectiveServer {
+				// The server only creates the session when receiving the client's second Initial.
+				trace.RetrySourceConnectionID = hdr.SrcConnectionID
+				retry = i
+			}
+			continue
+		}
+		trace.ServerConnectionID = hdr.SrcConnectionID
+		break
+	}
+	if trace.ServerConnectionID == nil {
+		return nil, errors.New("replay: no long header packet sent by the server")
+	}
+
+	local, remote := server, client
+	if pers == logging.PerspectiveClient {
+		local, remote = client, server
+	}
+	trace.LocalAddr, trace.RemoteAddr = local, remote
+	for _, d := range datagrams[start+retry:] {
+		if equalAddr(d.Src, remote) && equalAddr(d.Dst, local) {
+			trace.Packets = append(trace.Packets, quic.ReplayPacket{Data: d.Data, RcvTime: d.Time})
+		}
+	}
+
+	keys, err := selectKeys(keyLog, clientInitial, trace.Version)
+	if err != nil {
+		return nil, err
+	}
+	trace.Keys = *keys
+	return trace, nil
+}
+
+func selectKeys(keyLog KeyLog, clientInitial []byte, version protocol.VersionNumber) (*quic.ReplayKeys, error) {
+	if len(keyLog) == 0 {
+		return nil, errors.New("replay: empty key log")
+	}
+	if len(keyLog) == 1 {
+		for _, keys := range keyLog {
+			return keys, nil
+		}
+	}
+	random, err := clientRandom(clientInitial, version)
+	if err != nil {
+		return nil, err
+	}
+	keys, ok := keyLog[random]
+	if !ok {
+		return nil, errors.New("replay: key log doesn't contain the keys for the connection")
+	}
+	return keys, nil
+}
+
+// clientRandom decrypts the client's first Initial packet, and extracts the client random from the ClientHello.
+func clientRandom(data []byte, version protocol.VersionNumber) ([32]byte, error) {
+	var random [32]byte
+	hdr, packetData, _, err := wire.ParsePacket(data, 0)
+	if err != nil {
+		return random, err
+	}
+	data = make([]byte, len(packetData))
+	copy(data, packetData)
+	_, opener := handshake.NewInitialAEAD(hdr.DestConnectionID, protocol.PerspectiveServer, version)
+	hdrLen := int(hdr.ParsedLen())
+	if len(data) < hdrLen+4+16 {
+		return random, errors.New("replay: Initial packet too short")
+	}
+	origPNBytes := make([]byte, 4)
+	copy(origPNBytes, data[hdrLen:hdrLen+4])
+	opener.DecryptHeader(data[hdrLen+4:hdrLen+4+16], &data[0], data[hdrLen:hdrLen+4])
+	extHdr, err := hdr.ParseExtended(bytes.NewReader(data), version)
+	if err != nil && err != wire.ErrInvalidReservedBits {
+		return random, err
+	}
+	extHdrLen := int(extHdr.ParsedLen())
+	copy(data[extHdrLen:hdrLen+4], origPNBytes[int(extHdr.PacketNumberLen):])
+	payload, err := opener.Open(nil, data[extHdrLen:], extHdr.PacketNumber, data[:extHdrLen])
+	if err != nil {
+		return random, err
+	}
+	parser := wire.NewFrameParser(false, false, false, version)
+	r := bytes.NewReader(payload)
+	for r.Len() > 0 {
+		frame, err := parser.ParseNext(r, protocol.EncryptionInitial)
+		if err != nil {
+			return random, err
+		}
+		if frame == nil {
+			break
+		}
+		cf, ok := frame.(*wire.CryptoFrame)
+		// message type (1), length (3), legacy_version (2), random (32)
+		if !ok || cf.Offset != 0 || len(cf.Data) < 4+2+32 || cf.Data[0] != 1 {
+			continue
+		}
+		copy(random[:], cf.Data[4+2:])
+		return random, nil
+	}
+	return random, errors.New("replay: no ClientHello found in the client's first Initial packet")
+}
+
+func equalAddr(a, b *net.UDPAddr) bool {
+	return a.IP.Equal(b.IP) && a.Port == b.Port
+}
@@ -0,0 +1,191 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// recordingConn records all datagrams sent and received on a net.PacketConn.
+type recordingConn struct {
+	net.PacketConn
+
+	mutex     sync.Mutex
+	datagrams []Datagram
+}
+
+func (c *recordingConn) record(src, dst net.Addr, b []byte) {
+	data := make([]byte, len(b))
+	copy(data, b)
+	c.mutex.Lock()
+	c.datagrams = append(c.datagrams, Datagram{Time: time.Now(), Src: src.(*net.UDPAddr), Dst: dst.(*net.UDPAddr), Data: data})
+	c.mutex.Unlock()
+}
+
+func (c *recordingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	if err == nil {
+		c.record(addr, c.LocalAddr(), b[:n])
+	}
+	return n, addr, err
+}
+
+func (c *recordingConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.record(c.LocalAddr(), addr, b)
+	return c.PacketConn.WriteTo(b, addr)
+}
+
+func (c *recordingConn) Datagrams() []Datagram {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.datagrams
+}
+
+var _ = Describe("Replay", func() {
+	var (
+		conn   *recordingConn
+		ln     quic.Listener
+		keyLog *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).ToNot(HaveOccurred())
+		conn = &recordingConn{PacketConn: udpConn}
+		keyLog = &bytes.Buffer{}
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"replay"}
+		tlsConf.KeyLogWriter = keyLog
+		ln, err = quic.Listen(conn, tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		ln.Close()
+		conn.Close()
+	})
+
+	// runConnection transfers some data on a stream, and then closes the connection from the given side.
+	runConnection := func(closeBy logging.Perspective) {
+		serverSessChan := make(chan quic.Session, 1)
+		go func() {
+			defer GinkgoRecover()
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+			serverSessChan <- sess
+		}()
+		sess, err := quic.DialAddr(
+			ln.Addr().String(),
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"replay"}},
+			nil,
+		)
+		Expect(err).ToNot(HaveOccurred())
+		str, err := sess.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		var serverSess quic.Session
+		Eventually(serverSessChan).Should(Receive(&serverSess))
+		if closeBy == logging.PerspectiveClient {
+			Expect(sess.CloseWithError(0x42, "bye")).To(Succeed())
+			Eventually(serverSess.Context().Done()).Should(BeClosed())
+		} else {
+			Expect(serverSess.CloseWithError(0x42, "bye")).To(Succeed())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		}
+	}
+
+	readKeyLog := func() KeyLog {
+		if keyLog.Len() == 0 {
+			Skip("the TLS stack didn't write a key log")
+		}
+		keys, err := ReadKeyLog(bytes.NewReader(keyLog.Bytes()))
+		Expect(err).ToNot(HaveOccurred())
+		return keys
+	}
+
+	replay := func(pers logging.Perspective) (*quic.ReplayTrace, error) {
+		keys := readKeyLog()
+		trace, err := NewTrace(conn.Datagrams(), keys, pers)
+		Expect(err).ToNot(HaveOccurred())
+		return trace, quic.Replay(trace, nil)
+	}
+
+	expectClosedByPeer := func(trace *quic.ReplayTrace, err error) {
+		var replayErr *quic.ReplayError
+		Expect(errors.As(err, &replayErr)).To(BeTrue())
+		// The peer might have sent its CONNECTION_CLOSE multiple times.
+		// All packets before the first one are processed without closing the connection.
+		Expect(replayErr.Packet).To(BeNumerically("<", len(trace.Packets)))
+		truncated := *trace
+		truncated.Packets = trace.Packets[:replayErr.Packet]
+		Expect(quic.Replay(&truncated, nil)).To(Succeed())
+		var appErr *quic.ApplicationError
+		Expect(errors.As(err, &appErr)).To(BeTrue())
+		Expect(appErr.Remote).To(BeTrue())
+		Expect(appErr.ErrorCode).To(BeEquivalentTo(0x42))
+		Expect(appErr.ErrorMessage).To(Equal("bye"))
+	}
+
+	It("replays the packets received by the server", func() {
+		runConnection(logging.PerspectiveClient)
+		trace, err := replay(logging.PerspectiveServer)
+		Expect(trace.LocalAddr).To(Equal(ln.Addr()))
+		expectClosedByPeer(trace, err)
+	})
+
+	It("replays the packets received by the client", func() {
+		runConnection(logging.PerspectiveServer)
+		trace, err := replay(logging.PerspectiveClient)
+		Expect(trace.RemoteAddr).To(Equal(ln.Addr()))
+		expectClosedByPeer(trace, err)
+	})
+
+	It("replays deterministically", func() {
+		runConnection(logging.PerspectiveClient)
+		trace, err1 := replay(logging.PerspectiveServer)
+		err2 := quic.Replay(trace, nil)
+		Expect(err2).To(Equal(err1))
+	})
+
+	It("selects the keys of the first connection", func() {
+		runConnection(logging.PerspectiveClient)
+		runConnection(logging.PerspectiveClient)
+		Expect(readKeyLog()).To(HaveLen(2))
+		trace, err := replay(logging.PerspectiveServer)
+		expectClosedByPeer(trace, err)
+	})
+
+	It("errors when the key log doesn't contain the keys of the connection", func() {
+		runConnection(logging.PerspectiveClient)
+		keys := KeyLog{
+			[32]byte{1}: &quic.ReplayKeys{},
+			[32]byte{2}: &quic.ReplayKeys{},
+		}
+		_, err := NewTrace(conn.Datagrams(), keys, logging.PerspectiveServer)
+		Expect(err).To(MatchError("replay: key log doesn't contain the keys for the connection"))
+	})
+
+	It("errors when there's no connection", func() {
+		_, err := NewTrace(nil, KeyLog{}, logging.PerspectiveServer)
+		Expect(err).To(MatchError("replay: no Initial packet found"))
+	})
+})
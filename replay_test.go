@@ -0,0 +1,148 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/handshake"
+	mocklogging "github.com/lucas-clemente/quic-go/internal/mocks/logging"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/qerr"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/logging"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Replay", func() {
+	var (
+		odcid = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef, 0xde, 0xca, 0xfb, 0xad}
+		ccid  = protocol.ConnectionID{1, 2, 3, 4}
+		scid  = protocol.ConnectionID{5, 6, 7, 8}
+	)
+
+	// packInitial packs an Initial packet sent by the client
+	packInitial := func(pn protocol.PacketNumber, frames ...wire.Frame) []byte {
+		sealer, _ := handshake.NewInitialAEAD(odcid, protocol.PerspectiveClient, protocol.Version1)
+		payload := &bytes.Buffer{}
+		for _, f := range frames {
+			Expect(f.Write(payload, protocol.Version1)).To(Succeed())
+		}
+		payload.Write(make([]byte, 20)) // PADDING frames, for the header protection sample
+		hdr := &wire.ExtendedHeader{
+			Header: wire.Header{
+				IsLongHeader:     true,
+				Type:             protocol.PacketTypeInitial,
+				DestConnectionID: odcid,
+				SrcConnectionID:  ccid,
+				Length:           protocol.ByteCount(2 + payload.Len() + sealer.Overhead()),
+				Version:          protocol.Version1,
+			},
+			PacketNumber:    pn,
+			PacketNumberLen: protocol.PacketNumberLen2,
+		}
+		buf := &bytes.Buffer{}
+		Expect(hdr.Write(buf, protocol.Version1)).To(Succeed())
+		payloadOffset := buf.Len()
+		raw := make([]byte, payloadOffset, payloadOffset+payload.Len()+sealer.Overhead())
+		copy(raw, buf.Bytes())
+		raw = sealer.Seal(raw, payload.Bytes(), pn, raw[:payloadOffset])
+		pnOffset := payloadOffset - 2
+		sealer.EncryptHeader(raw[pnOffset+4:pnOffset+4+16], &raw[0], raw[pnOffset:payloadOffset])
+		return raw
+	}
+
+	newTrace := func(packets ...[]byte) *ReplayTrace {
+		trace := &ReplayTrace{
+			Perspective:              logging.PerspectiveServer,
+			Version:                  protocol.Version1,
+			OriginalDestConnectionID: odcid,
+			ClientConnectionID:       ccid,
+			ServerConnectionID:       scid,
+		}
+		for _, p := range packets {
+			trace.Packets = append(trace.Packets, ReplayPacket{Data: p, RcvTime: time.Now()})
+		}
+		return trace
+	}
+
+	It("replays a trace", func() {
+		Expect(Replay(newTrace(
+			packInitial(0, &wire.PingFrame{}, &wire.PingFrame{}),
+			packInitial(1, &wire.PingFrame{}),
+		), nil)).To(Succeed())
+	})
+
+	It("replays ACKs for the packets sent by the endpoint", func() {
+		// The packets acknowledged here were sent when the trace was recorded, but not when replaying it.
+		Expect(Replay(newTrace(
+			packInitial(0, &wire.PingFrame{}),
+			packInitial(1, &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 3}}}),
+			packInitial(2, &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 8}, {Smallest: 0, Largest: 3}}}, &wire.PingFrame{}),
+		), nil)).To(Succeed())
+	})
+
+	It("returns the error that closed the connection", func() {
+		err := Replay(newTrace(
+			packInitial(0, &wire.PingFrame{}),
+			[]byte("garbage"),
+			packInitial(1, &wire.ConnectionCloseFrame{ErrorCode: uint64(qerr.ProtocolViolation), ReasonPhrase: "foobar"}),
+			packInitial(2, &wire.PingFrame{}),
+		), nil)
+		Expect(err).To(HaveOccurred())
+		var replayErr *ReplayError
+		Expect(errors.As(err, &replayErr)).To(BeTrue())
+		Expect(replayErr.Packet).To(Equal(2))
+		var transportErr *qerr.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.Remote).To(BeTrue())
+		Expect(transportErr.ErrorCode).To(Equal(qerr.ProtocolViolation))
+		Expect(transportErr.ErrorMessage).To(Equal("foobar"))
+	})
+
+	It("returns protocol violations caused by a packet", func() {
+		err := Replay(newTrace(
+			packInitial(0, &wire.StreamFrame{StreamID: 0, Data: []byte("foobar")}),
+		), nil)
+		var replayErr *ReplayError
+		Expect(errors.As(err, &replayErr)).To(BeTrue())
+		Expect(replayErr.Packet).To(BeZero())
+		var transportErr *qerr.TransportError
+		Expect(errors.As(err, &transportErr)).To(BeTrue())
+		Expect(transportErr.Remote).To(BeFalse())
+		Expect(transportErr.ErrorCode).To(Equal(qerr.FrameEncodingError))
+	})
+
+	It("traces the replayed connection", func() {
+		tracer := mocklogging.NewMockTracer(mockCtrl)
+		connTracer := mocklogging.NewMockConnectionTracer(mockCtrl)
+		tracer.EXPECT().TracerForConnection(gomock.Any(), logging.PerspectiveServer, odcid).Return(connTracer)
+		connTracer.EXPECT().SentTransportParameters(gomock.Any()).AnyTimes()
+		connTracer.EXPECT().UpdatedKeyFromTLS(gomock.Any(), gomock.Any()).AnyTimes()
+		connTracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+		connTracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		connTracer.EXPECT().NegotiatedVersion(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		connTracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+		gomock.InOrder(
+			connTracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(hdr *logging.ExtendedHeader, _ logging.ByteCount, frames []logging.Frame) {
+				Expect(hdr.PacketNumber).To(Equal(protocol.PacketNumber(3)))
+				Expect(frames).To(Equal([]logging.Frame{&logging.PingFrame{}}))
+			}),
+			connTracer.EXPECT().Close(),
+		)
+		Expect(Replay(newTrace(packInitial(3, &wire.PingFrame{})), &Config{Tracer: tracer})).To(Succeed())
+	})
+
+	It("rejects invalid traces", func() {
+		trace := newTrace()
+		trace.Perspective = 42
+		Expect(Replay(trace, nil)).To(MatchError("replay: invalid perspective"))
+		trace = newTrace()
+		trace.Version = 0x1337
+		Expect(Replay(trace, nil)).To(MatchError("replay: unsupported version 0x1337"))
+	})
+})
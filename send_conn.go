@@ -2,42 +2,70 @@ package quic
 
 import (
 	"net"
+	"sync"
 )
 
 // A sendConn allows sending using a simple Write() on a non-connected packet conn.
 type sendConn interface {
 	Write([]byte) error
+	// WriteWithDSCP writes a packet that is marked with a DSCP, if the connection supports this.
+	WriteWithDSCP([]byte, uint8) error
 	Close() error
 	LocalAddr() net.Addr
 	RemoteAddr() net.Addr
+	// SetRemoteAddr changes the address that packets are sent to.
+	// It is used when the peer's address changes.
+	SetRemoteAddr(net.Addr)
+}
+
+// remoteAddress holds the address of the peer.
+// It can be changed while packets are being sent.
+type remoteAddress struct {
+	mutex sync.Mutex
+	addr  net.Addr
+}
+
+func (a *remoteAddress) RemoteAddr() net.Addr {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.addr
+}
+
+func (a *remoteAddress) SetRemoteAddr(addr net.Addr) {
+	a.mutex.Lock()
+	a.addr = addr
+	a.mutex.Unlock()
 }
 
 type sconn struct {
 	connection
+	remoteAddress
 
-	remoteAddr net.Addr
-	info       *packetInfo
-	oob        []byte
+	info *packetInfo
+	oob  []byte
 }
 
 var _ sendConn = &sconn{}
 
 func newSendConn(c connection, remote net.Addr, info *packetInfo) sendConn {
 	return &sconn{
-		connection: c,
-		remoteAddr: remote,
-		info:       info,
-		oob:        info.OOB(),
+		connection:    c,
+		remoteAddress: remoteAddress{addr: remote},
+		info:          info,
+		oob:           info.OOB(),
 	}
 }
 
 func (c *sconn) Write(p []byte) error {
-	_, err := c.WritePacket(p, c.remoteAddr, c.oob)
+	_, err := c.WritePacket(p, c.RemoteAddr(), c.oob)
 	return err
 }
 
-func (c *sconn) RemoteAddr() net.Addr {
-	return c.remoteAddr
+func (c *sconn) WriteWithDSCP(p []byte, dscp uint8) error {
+	addr := c.RemoteAddr()
+	oob := append(c.oob[:len(c.oob):len(c.oob)], dscpOOB(addr, dscp)...)
+	_, err := c.WritePacket(p, addr, oob)
+	return err
 }
 
 func (c *sconn) LocalAddr() net.Addr {
@@ -54,21 +82,21 @@ func (c *sconn) LocalAddr() net.Addr {
 
 type spconn struct {
 	net.PacketConn
-
-	remoteAddr net.Addr
+	remoteAddress
 }
 
 var _ sendConn = &spconn{}
 
 func newSendPconn(c net.PacketConn, remote net.Addr) sendConn {
-	return &spconn{PacketConn: c, remoteAddr: remote}
+	return &spconn{PacketConn: c, remoteAddress: remoteAddress{addr: remote}}
 }
 
 func (c *spconn) Write(p []byte) error {
-	_, err := c.WriteTo(p, c.remoteAddr)
+	_, err := c.WriteTo(p, c.RemoteAddr())
 	return err
 }
 
-func (c *spconn) RemoteAddr() net.Addr {
-	return c.remoteAddr
+// WriteWithDSCP writes the packet without marking it, since the net.PacketConn doesn't allow setting the DSCP.
+func (c *spconn) WriteWithDSCP(p []byte, _ uint8) error {
+	return c.Write(p)
 }
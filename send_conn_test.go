@@ -25,10 +25,23 @@ var _ = Describe("Connection (for sending packets)", func() {
 		Expect(c.Write([]byte("foobar"))).To(Succeed())
 	})
 
+	It("writes packets without marking them", func() {
+		packetConn.EXPECT().WriteTo([]byte("foobar"), addr)
+		Expect(c.WriteWithDSCP([]byte("foobar"), 46)).To(Succeed())
+	})
+
 	It("gets the remote address", func() {
 		Expect(c.RemoteAddr().String()).To(Equal("192.168.100.200:1337"))
 	})
 
+	It("changes the remote address", func() {
+		newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 100, 201), Port: 4242}
+		c.SetRemoteAddr(newAddr)
+		Expect(c.RemoteAddr()).To(Equal(newAddr))
+		packetConn.EXPECT().WriteTo([]byte("foobar"), newAddr)
+		Expect(c.Write([]byte("foobar"))).To(Succeed())
+	})
+
 	It("gets the local address", func() {
 		addr := &net.UDPAddr{
 			IP:   net.IPv4(192, 168, 0, 1),
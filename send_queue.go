@@ -1,10 +1,19 @@
 package quic
 
+import (
+	"sync/atomic"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
 type sender interface {
 	Send(p *packetBuffer)
 	Run() error
 	WouldBlock() bool
 	Available() <-chan struct{}
+	// QueuedBytes is the number of bytes that were queued, but not yet written to the connection.
+	// It is safe to call it concurrently with the other methods.
+	QueuedBytes() protocol.ByteCount
 	Close()
 }
 
@@ -14,6 +23,8 @@ type sendQueue struct {
 	runStopped  chan struct{} // runStopped when the run loop returns
 	available   chan struct{}
 	conn        sendConn
+
+	queuedBytes int64 // to be accessed atomically
 }
 
 var _ sender = &sendQueue{}
@@ -34,10 +45,14 @@ func newSendQueue(conn sendConn) sender {
 // Callers need to make sure that there's actually space in the send queue by calling WouldBlock.
 // Otherwise Send will panic.
 func (h *sendQueue) Send(p *packetBuffer) {
+	// Count the bytes before queueing the packet, so that the run loop never decrements the counter below 0.
+	atomic.AddInt64(&h.queuedBytes, int64(p.Len()))
 	select {
 	case h.queue <- p:
 	case <-h.runStopped:
+		atomic.AddInt64(&h.queuedBytes, -int64(p.Len()))
 	default:
+		atomic.AddInt64(&h.queuedBytes, -int64(p.Len()))
 		panic("sendQueue.Send would have blocked")
 	}
 }
@@ -50,6 +65,10 @@ func (h *sendQueue) Available() <-chan struct{} {
 	return h.available
 }
 
+func (h *sendQueue) QueuedBytes() protocol.ByteCount {
+	return protocol.ByteCount(atomic.LoadInt64(&h.queuedBytes))
+}
+
 func (h *sendQueue) Run() error {
 	defer close(h.runStopped)
 	var shouldClose bool
@@ -63,7 +82,14 @@ func (h *sendQueue) Run() error {
 			// make sure that all queued packets are actually sent out
 			shouldClose = true
 		case p := <-h.queue:
-			if err := h.conn.Write(p.Data); err != nil {
+			var err error
+			if p.dscp != 0 {
+				err = h.conn.WriteWithDSCP(p.Data, p.dscp)
+			} else {
+				err = h.conn.Write(p.Data)
+			}
+			atomic.AddInt64(&h.queuedBytes, -int64(p.Len()))
+			if err != nil {
 				return err
 			}
 			p.Release()
@@ -42,6 +42,44 @@ var _ = Describe("Send Queue", func() {
 		Eventually(done).Should(BeClosed())
 	})
 
+	It("sends a packet marked with a DSCP", func() {
+		p := getPacket([]byte("foobar"))
+		p.dscp = 46
+		q.Send(p)
+
+		written := make(chan struct{})
+		c.EXPECT().WriteWithDSCP([]byte("foobar"), uint8(46)).Do(func([]byte, uint8) { close(written) })
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+
+		Eventually(written).Should(BeClosed())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("counts the queued bytes", func() {
+		q.Send(getPacket([]byte("foo")))
+		q.Send(getPacket([]byte("foobar")))
+		Expect(q.QueuedBytes()).To(BeEquivalentTo(9))
+
+		written := make(chan struct{}, 2)
+		c.EXPECT().Write(gomock.Any()).Do(func([]byte) { written <- struct{}{} }).Times(2)
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			q.Run()
+			close(done)
+		}()
+		Eventually(written).Should(HaveLen(2))
+		Eventually(q.QueuedBytes).Should(BeZero())
+		q.Close()
+		Eventually(done).Should(BeClosed())
+	})
+
 	It("panics when Send() is called although there's no space in the queue", func() {
 		for i := 0; i < sendQueueCapacity; i++ {
 			Expect(q.WouldBlock()).To(BeFalse())
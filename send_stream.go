@@ -2,7 +2,10 @@ package quic
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -18,6 +21,7 @@ type sendStreamI interface {
 	SendStream
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	hasData() bool
+	pendingData() protocol.ByteCount
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	closeForShutdown(error)
 	updateSendWindow(protocol.ByteCount)
@@ -32,13 +36,35 @@ type sendStream struct {
 	ctx       context.Context
 	ctxCancel context.CancelFunc
 
+	// doneChan is closed when all data was acknowledged, the write-side was canceled, or the session was closed.
+	// doneErr is the reason why it was closed.
+	doneChan chan struct{}
+	doneErr  error
+	isDone   bool
+
 	streamID protocol.StreamID
 	sender   streamSender
 
 	writeOffset protocol.ByteCount
 
+	// deliveredOffset is the offset up to which all data was acknowledged by the peer.
+	// ackedRanges contains the (sorted, non-overlapping) ranges acknowledged beyond deliveredOffset.
+	deliveredOffset protocol.ByteCount
+	ackedRanges     []utils.ByteInterval
+	// deliveryWaiters are the channels returned by Delivered that are not closed yet.
+	deliveryWaiters []deliveryWaiter
+
+	// messages are the (sorted) ranges of the messages written by WriteMessage, that weren't sent completely yet.
+	messages []utils.ByteInterval
+	// deferredMessageEnd is the end offset of the last message that was deferred to the next packet.
+	// Every message is deferred at most once.
+	deferredMessageEnd protocol.ByteCount
+
 	cancelWriteErr      error
 	closeForShutdownErr error
+	// reliableSize is set by CancelWriteAt.
+	// The data up to this offset is still sent (and retransmitted) after the stream was canceled.
+	reliableSize protocol.ByteCount
 
 	closedForShutdown bool // set when CloseForShutdown() is called
 	finishedWriting   bool // set once Close() is called
@@ -47,19 +73,67 @@ type sendStream struct {
 	completed         bool // set when this stream has been reported to the streamSender as completed
 
 	dataForWriting []byte // during a Write() call, this slice is the part of p that still needs to be sent out
-	nextFrame      *wire.StreamFrame
+	// buffersForWriting are the buffers passed to WriteBuffers() that follow dataForWriting.
+	// If buffersForWriting is not empty, dataForWriting is not nil.
+	buffersForWriting [][]byte
+	nextFrame         *wire.StreamFrame
+	// queuedFrames are STREAM frames filled by ReadFrom, which are sent after nextFrame.
+	// If queuedFrames is not empty, nextFrame is not nil.
+	queuedFrames []*wire.StreamFrame
+	// queues holds the pooled queues, see sendStreamQueues. It is nil once the stream is completed.
+	queues *sendStreamQueues
 
-	writeChan chan struct{}
-	deadline  time.Time
+	writeChan    chan struct{}
+	deadline     time.Time
+	priority     StreamPriority
+	trafficClass TrafficClass
+	onWritable   func() // set by OnWritable
+	// discardOnWriteTimeout is set by SetDiscardOnWriteTimeout
+	discardOnWriteTimeout bool
 
 	flowController flowcontrol.StreamFlowController
+	// blockedByConnection is set while the stream has data to send, but is blocked by connection-level flow control
+	blockedByConnection    bool
+	connectionBlockedCount uint64
+	// idleTimer is nil if Config.StreamIdleTimeout is not set
+	idleTimer *streamIdleTimer
+	// errorRegistry is nil for streams that don't belong to a session
+	errorRegistry *StreamErrorRegistry
 
 	version protocol.VersionNumber
 }
 
+type deliveryWaiter struct {
+	offset protocol.ByteCount
+	ch     chan struct{}
+}
+
+// maxMessageSizeForBoundaryHint is the maximum size of a message written by WriteMessage,
+// that is sent in the next packet if it doesn't fit into the current packet.
+// Every packet can hold a STREAM frame containing a message of this size.
+const maxMessageSizeForBoundaryHint = 1000
+
+// maxQueuedStreamFrames is the number of STREAM frames that ReadFrom reads ahead
+const maxQueuedStreamFrames = 16
+
+// sendStreamQueues are the queues of a send stream.
+// They are detached from the stream when it is completed, and reused by the streams opened later,
+// since connections that use a lot of short-lived streams would otherwise grow new queues for every stream.
+// The stream itself is handed out to the application, and can't be reused.
+type sendStreamQueues struct {
+	retransmissionQueue []*wire.StreamFrame
+	queuedFrames        []*wire.StreamFrame
+	ackedRanges         []utils.ByteInterval
+}
+
+var sendStreamQueuesPool = sync.Pool{New: func() interface{} {
+	return &sendStreamQueues{queuedFrames: make([]*wire.StreamFrame, 0, maxQueuedStreamFrames)}
+}}
+
 var (
-	_ SendStream  = &sendStream{}
-	_ sendStreamI = &sendStream{}
+	_ SendStream    = &sendStream{}
+	_ sendStreamI   = &sendStream{}
+	_ io.ReaderFrom = &sendStream{}
 )
 
 func newSendStream(
@@ -73,41 +147,156 @@ func newSendStream(
 		sender:         sender,
 		flowController: flowController,
 		writeChan:      make(chan struct{}, 1),
+		doneChan:       make(chan struct{}),
+		priority:       defaultStreamPriority,
 		version:        version,
 	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	q := sendStreamQueuesPool.Get().(*sendStreamQueues)
+	s.retransmissionQueue = q.retransmissionQueue
+	s.queuedFrames = q.queuedFrames
+	s.ackedRanges = q.ackedRanges
+	*q = sendStreamQueues{}
+	s.queues = q
 	return s
 }
 
+// releaseQueues returns the queues to the pool. It is called when the stream is completed.
+// The queues are empty at this point: all frames were sent and acknowledged (or the stream was canceled).
+// must be called after locking the mutex
+func (s *sendStream) releaseQueues() {
+	q := s.queues
+	if q == nil {
+		return
+	}
+	s.queues = nil
+	// Don't hold on to STREAM frames that might still be in the queue after a cancelation.
+	for i := range s.queuedFrames {
+		s.queuedFrames[i] = nil
+	}
+	q.retransmissionQueue = s.retransmissionQueue[:0]
+	q.queuedFrames = s.queuedFrames[:0]
+	q.ackedRanges = s.ackedRanges[:0]
+	s.retransmissionQueue = nil
+	s.queuedFrames = nil
+	s.ackedRanges = nil
+	sendStreamQueuesPool.Put(q)
+}
+
+func (s *sendStream) FlowControlState() FlowControlState {
+	state := newFlowControlState(s.flowController.State())
+	s.mutex.Lock()
+	state.ConnectionBlockedCount = s.connectionBlockedCount
+	s.mutex.Unlock()
+	return state
+}
+
 func (s *sendStream) StreamID() protocol.StreamID {
 	return s.streamID // same for receiveStream and sendStream
 }
 
-func (s *sendStream) Write(p []byte) (int, error) {
+func (s *sendStream) SetPriority(prio StreamPriority) {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	changed := prio != s.priority
+	s.priority = prio
+	s.mutex.Unlock()
 
-	if s.finishedWriting {
-		return 0, fmt.Errorf("write on closed stream %d", s.streamID)
+	if changed {
+		s.sender.onStreamPriorityChanged(s.streamID, prio) // must be called without holding the mutex
 	}
-	if s.canceledWrite {
-		return 0, s.cancelWriteErr
+}
+
+func (s *sendStream) SetTrafficClass(class TrafficClass) {
+	s.mutex.Lock()
+	s.trafficClass = class
+	s.mutex.Unlock()
+
+	if prio, ok := s.sender.trafficClassPriority(class); ok {
+		s.SetPriority(prio)
 	}
+}
+
+func (s *sendStream) TrafficClass() TrafficClass {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.trafficClass
+}
+
+func (s *sendStream) SetDiscardOnWriteTimeout(discard bool) {
+	s.mutex.Lock()
+	s.discardOnWriteTimeout = discard
+	s.mutex.Unlock()
+}
+
+func (s *sendStream) Flush() error {
+	s.mutex.Lock()
 	if s.closeForShutdownErr != nil {
-		return 0, s.closeForShutdownErr
+		s.mutex.Unlock()
+		return s.closeForShutdownErr
 	}
-	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
-		return 0, errDeadline
+	if s.canceledWrite && s.reliableSize == 0 {
+		s.mutex.Unlock()
+		return s.cancelWriteErr
 	}
-	if len(p) == 0 {
+	hasStreamData := s.dataForWriting != nil || s.nextFrame != nil || len(s.retransmissionQueue) > 0 || (s.finishedWriting && !s.finSent)
+	s.mutex.Unlock()
+
+	if hasStreamData {
+		s.sender.onStreamFlushed(s.streamID) // must be called without holding the mutex
+	}
+	return nil
+}
+
+func (s *sendStream) Priority() StreamPriority {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.priority
+}
+
+func (s *sendStream) Write(p []byte) (int, error) {
+	return s.WriteContext(context.Background(), p)
+}
+
+func (s *sendStream) WriteContext(ctx context.Context, p []byte) (int, error) {
+	n, err := s.writeImpl(ctx, p, nil)
+	return int(n), err
+}
+
+// WriteBuffers writes the contents of bufs to the stream, as if they were concatenated.
+// The data is copied directly into the STREAM frames, without joining the buffers first.
+// It returns the same errors as Write.
+func (s *sendStream) WriteBuffers(bufs [][]byte) (int64, error) {
+	if len(bufs) == 0 {
+		return s.writeImpl(context.Background(), nil, nil)
+	}
+	return s.writeImpl(context.Background(), bufs[0], bufs[1:])
+}
+
+func (s *sendStream) writeImpl(ctx context.Context, p []byte, bufs [][]byte) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.writeError(); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	total := int64(len(p))
+	for _, b := range bufs {
+		total += int64(len(b))
+	}
+	if total == 0 {
 		return 0, nil
 	}
 
 	s.dataForWriting = p
+	s.buffersForWriting = bufs
+	s.skipEmptyBuffersForWriting()
 
 	var (
 		deadlineTimer  *utils.Timer
-		bytesWritten   int
+		bytesWritten   int64
 		notifiedSender bool
 	)
 	for {
@@ -118,29 +307,29 @@ func (s *sendStream) Write(p []byte) (int, error) {
 		// This allows us to return Write() when all data but x bytes have been sent out.
 		// When the user now calls Close(), this is much more likely to happen before we popped that last STREAM frame,
 		// allowing us to set the FIN bit on that frame (instead of sending an empty STREAM frame with FIN).
-		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 {
+		if s.canBufferStreamFrame() && len(s.dataForWriting) > 0 && !s.canceledWrite && !s.mustSendBeforeReturning(ctx) {
 			if s.nextFrame == nil {
 				f := wire.GetStreamFrame()
 				f.Offset = s.writeOffset
 				f.StreamID = s.streamID
 				f.DataLenPresent = true
-				f.Data = f.Data[:len(s.dataForWriting)]
-				copy(f.Data, s.dataForWriting)
+				f.Data = f.Data[:0]
 				s.nextFrame = f
-			} else {
-				l := len(s.nextFrame.Data)
-				s.nextFrame.Data = s.nextFrame.Data[:l+len(s.dataForWriting)]
-				copy(s.nextFrame.Data[l:], s.dataForWriting)
 			}
-			s.dataForWriting = nil
-			bytesWritten = len(p)
+			for s.dataForWriting != nil {
+				s.nextFrame.Data = append(s.nextFrame.Data, s.dataForWriting...)
+				s.dataForWriting = nil
+				s.skipEmptyBuffersForWriting()
+			}
+			bytesWritten = total
 			copied = true
 		} else {
-			bytesWritten = len(p) - len(s.dataForWriting)
+			bytesWritten = total - int64(s.dataForWritingLen())
 			deadline = s.deadline
 			if !deadline.IsZero() {
 				if !time.Now().Before(deadline) {
 					s.dataForWriting = nil
+					s.buffersForWriting = nil
 					return bytesWritten, errDeadline
 				}
 				if deadlineTimer == nil {
@@ -149,6 +338,11 @@ func (s *sendStream) Write(p []byte) (int, error) {
 				}
 				deadlineTimer.Reset(deadline)
 			}
+			if err := ctx.Err(); err != nil {
+				s.dataForWriting = nil
+				s.buffersForWriting = nil
+				return bytesWritten, err
+			}
 			if s.dataForWriting == nil || s.canceledWrite || s.closedForShutdown {
 				break
 			}
@@ -163,19 +357,20 @@ func (s *sendStream) Write(p []byte) (int, error) {
 			s.mutex.Lock()
 			break
 		}
-		if deadline.IsZero() {
-			<-s.writeChan
-		} else {
-			select {
-			case <-s.writeChan:
-			case <-deadlineTimer.Chan():
-				deadlineTimer.SetRead()
-			}
+		var deadlineChan <-chan time.Time
+		if !deadline.IsZero() {
+			deadlineChan = deadlineTimer.Chan()
+		}
+		select {
+		case <-s.writeChan:
+		case <-deadlineChan:
+			deadlineTimer.SetRead()
+		case <-ctx.Done():
 		}
 		s.mutex.Lock()
 	}
 
-	if bytesWritten == len(p) {
+	if bytesWritten == total {
 		return bytesWritten, nil
 	}
 	if s.closeForShutdownErr != nil {
@@ -186,32 +381,298 @@ func (s *sendStream) Write(p []byte) (int, error) {
 	return bytesWritten, nil
 }
 
+func (s *sendStream) TryWrite(p []byte) (int, error) {
+	s.mutex.Lock()
+	if err := s.writeError(); err != nil && err != errDeadline {
+		s.mutex.Unlock()
+		return 0, err
+	}
+	if len(p) == 0 {
+		s.mutex.Unlock()
+		return 0, nil
+	}
+	available := s.sendBufferSpace()
+	if available == 0 {
+		s.mutex.Unlock()
+		return 0, ErrWouldBlock
+	}
+	n := utils.Min(len(p), int(available))
+	if s.nextFrame == nil {
+		f := wire.GetStreamFrame()
+		f.Offset = s.writeOffset
+		f.StreamID = s.streamID
+		f.DataLenPresent = true
+		f.Data = f.Data[:0]
+		s.nextFrame = f
+	}
+	s.nextFrame.Data = append(s.nextFrame.Data, p[:n]...)
+	s.mutex.Unlock()
+
+	s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+	return n, nil
+}
+
+// sendBufferSpace returns the number of bytes that TryWrite can accept.
+// Data can only be buffered while no Write or ReadFrom call is in progress.
+// must be called with the mutex held
+func (s *sendStream) sendBufferSpace() protocol.ByteCount {
+	if s.dataForWriting != nil || len(s.queuedFrames) > 0 {
+		return 0
+	}
+	if s.nextFrame == nil {
+		return protocol.MaxPacketBufferSize
+	}
+	return utils.MaxByteCount(protocol.MaxPacketBufferSize-s.nextFrame.DataLen(), 0)
+}
+
+func (s *sendStream) OnWritable(cb func()) {
+	s.mutex.Lock()
+	s.onWritable = cb
+	writable := s.sendBufferSpace() > 0
+	if err := s.writeError(); err != nil && err != errDeadline {
+		writable = true
+	}
+	s.mutex.Unlock()
+
+	if cb != nil && writable {
+		cb()
+	}
+}
+
+func (s *sendStream) WriteMessage(p []byte) (int, error) {
+	if len(p) == 0 {
+		return s.Write(p)
+	}
+	s.mutex.Lock()
+	start := s.bufferedOffset()
+	s.messages = append(s.messages, utils.ByteInterval{Start: start, End: start + protocol.ByteCount(len(p))})
+	s.mutex.Unlock()
+
+	n, err := s.Write(p)
+	if n < len(p) {
+		// The rest of the message won't be sent.
+		s.mutex.Lock()
+		if l := len(s.messages); l > 0 && s.messages[l-1].Start == start {
+			if n == 0 {
+				s.messages = s.messages[:l-1]
+			} else {
+				s.messages[l-1].End = start + protocol.ByteCount(n)
+			}
+		}
+		s.mutex.Unlock()
+	}
+	return n, err
+}
+
+// mustSendBeforeReturning says if Write has to wait until all data was packed into packets,
+// instead of copying the remaining data into a STREAM frame and returning early.
+// must be called with the mutex held
+func (s *sendStream) mustSendBeforeReturning(ctx context.Context) bool {
+	return s.discardOnWriteTimeout && (!s.deadline.IsZero() || ctx.Done() != nil)
+}
+
+// writeError returns the error that a Write call would return before writing any data.
+// must be called with the mutex held
+func (s *sendStream) writeError() error {
+	if s.finishedWriting {
+		return fmt.Errorf("write on closed stream %d", s.streamID)
+	}
+	if s.canceledWrite {
+		return s.cancelWriteErr
+	}
+	if s.closeForShutdownErr != nil {
+		return s.closeForShutdownErr
+	}
+	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+		return errDeadline
+	}
+	return nil
+}
+
+// ReadFrom reads data from r until EOF or an error occurs, and sends it on the stream.
+// The data is read directly into the STREAM frames, without copying it through an intermediate buffer,
+// so that io.Copy(str, file) avoids double-buffering.
+// It returns the same errors as Write, and must not be called concurrently with Write.
+func (s *sendStream) ReadFrom(r io.Reader) (int64, error) {
+	var (
+		n             int64
+		deadlineTimer *utils.Timer
+	)
+	defer func() {
+		if deadlineTimer != nil {
+			deadlineTimer.Stop()
+		}
+	}()
+	for {
+		if err := s.waitForFrameQueue(&deadlineTimer); err != nil {
+			return n, err
+		}
+		f := wire.GetStreamFrame()
+		l, err := r.Read(f.Data[:cap(f.Data)])
+		if l > 0 {
+			f.Data = f.Data[:l]
+			if err := s.queueStreamFrame(f); err != nil {
+				return n, err
+			}
+			n += int64(l)
+			s.sender.onHasStreamData(s.streamID) // must be called without holding the mutex
+		} else {
+			f.PutBack()
+		}
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// waitForFrameQueue blocks until there's space to queue another STREAM frame.
+func (s *sendStream) waitForFrameQueue(deadlineTimer **utils.Timer) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for {
+		if err := s.writeError(); err != nil {
+			return err
+		}
+		if len(s.queuedFrames) < maxQueuedStreamFrames {
+			return nil
+		}
+		var deadlineChan <-chan time.Time
+		if deadline := s.deadline; !deadline.IsZero() {
+			if *deadlineTimer == nil {
+				*deadlineTimer = utils.NewTimer()
+			}
+			(*deadlineTimer).Reset(deadline)
+			deadlineChan = (*deadlineTimer).Chan()
+		}
+		s.mutex.Unlock()
+		select {
+		case <-s.writeChan:
+		case <-deadlineChan:
+			(*deadlineTimer).SetRead()
+		}
+		s.mutex.Lock()
+	}
+}
+
+func (s *sendStream) queueStreamFrame(f *wire.StreamFrame) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.writeError(); err != nil {
+		f.PutBack()
+		return err
+	}
+	f.StreamID = s.streamID
+	f.DataLenPresent = true
+	if s.nextFrame == nil {
+		f.Offset = s.writeOffset
+		s.nextFrame = f
+		return nil
+	}
+	// The offset is set when the frame becomes the nextFrame.
+	s.queuedFrames = append(s.queuedFrames, f)
+	return nil
+}
+
 func (s *sendStream) canBufferStreamFrame() bool {
+	if len(s.queuedFrames) > 0 {
+		return false
+	}
 	var l protocol.ByteCount
 	if s.nextFrame != nil {
 		l = s.nextFrame.DataLen()
 	}
-	return l+protocol.ByteCount(len(s.dataForWriting)) <= protocol.MaxPacketBufferSize
+	return l+s.dataForWritingLen() <= protocol.MaxPacketBufferSize
+}
+
+// dataForWritingLen returns the number of bytes of a Write() or WriteBuffers() call that still need to be sent out.
+func (s *sendStream) dataForWritingLen() protocol.ByteCount {
+	l := protocol.ByteCount(len(s.dataForWriting))
+	for _, b := range s.buffersForWriting {
+		l += protocol.ByteCount(len(b))
+	}
+	return l
+}
+
+// skipEmptyBuffersForWriting advances dataForWriting to the next non-empty buffer.
+// If no data is left, dataForWriting is set to nil.
+func (s *sendStream) skipEmptyBuffersForWriting() {
+	for len(s.dataForWriting) == 0 && len(s.buffersForWriting) > 0 {
+		s.dataForWriting = s.buffersForWriting[0]
+		s.buffersForWriting = s.buffersForWriting[1:]
+	}
+	if len(s.dataForWriting) == 0 {
+		s.dataForWriting = nil
+		s.buffersForWriting = nil
+	}
 }
 
 // popStreamFrame returns the next STREAM frame that is supposed to be sent on this stream
 // maxBytes is the maximum length this frame (including frame header) will have.
+// pendingData returns the number of bytes of new data waiting to be sent.
+// It returns protocol.MaxByteCount if data needs to be retransmitted, or the FIN needs to be sent.
+func (s *sendStream) pendingData() protocol.ByteCount {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closeForShutdownErr != nil || (s.canceledWrite && s.reliableSize == 0) {
+		return 0
+	}
+	if len(s.retransmissionQueue) > 0 || (s.finishedWriting && !s.finSent) {
+		return protocol.MaxByteCount
+	}
+	return s.bufferedOffset() - s.writeOffset + s.dataForWritingLen()
+}
+
+func (s *sendStream) isCompleted() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.completed
+}
+
+func (s *sendStream) BufferedAmount() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.closeForShutdownErr != nil || (s.canceledWrite && s.reliableSize == 0) {
+		return 0
+	}
+	l := s.bufferedOffset() - s.writeOffset + s.dataForWritingLen()
+	for _, f := range s.retransmissionQueue {
+		l += f.DataLen()
+	}
+	return uint64(l)
+}
+
 func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool /* has more data to send */) {
 	s.mutex.Lock()
+	hadBufferedData := s.nextFrame != nil
 	f, hasMoreData := s.popNewOrRetransmittedStreamFrame(maxBytes)
 	if f != nil {
 		s.numOutstandingFrames++
 	}
+	onWritable := s.onWritable
+	bufferEmptied := hadBufferedData && s.nextFrame == nil
 	s.mutex.Unlock()
 
+	// popStreamFrame is called while packing a packet, so the callback is only called once the packet was sent.
+	if onWritable != nil && bufferEmptied {
+		s.sender.onStreamWritable(onWritable)
+	}
+
 	if f == nil {
 		return nil, hasMoreData
 	}
+	s.idleTimer.Activity()
 	return &ackhandler.Frame{Frame: f, OnLost: s.queueRetransmission, OnAcked: s.frameAcked}, hasMoreData
 }
 
 func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCount) (*wire.StreamFrame, bool /* has more data to send */) {
-	if s.canceledWrite || s.closeForShutdownErr != nil {
+	if (s.canceledWrite && s.reliableSize == 0) || s.closeForShutdownErr != nil {
 		return nil, false
 	}
 
@@ -227,6 +688,11 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		}
 	}
 
+	// After CancelWriteAt, the data up to the reliable size was already copied into STREAM frames.
+	if s.canceledWrite && s.nextFrame == nil {
+		return nil, false
+	}
+
 	if len(s.dataForWriting) == 0 && s.nextFrame == nil {
 		if s.finishedWriting && !s.finSent {
 			s.finSent = true
@@ -247,16 +713,39 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 				StreamID:          s.streamID,
 				MaximumStreamData: offset,
 			})
+			s.sender.onStreamBlocked(s.streamID, false)
 			return nil, false
 		}
+		// If the stream-level limit wasn't reached, the stream is blocked by the connection-level limit.
+		if state := s.flowController.State(); !s.blockedByConnection && state.BytesSent < state.SendWindow {
+			s.blockedByConnection = true
+			s.connectionBlockedCount++
+			s.sender.onStreamBlocked(s.streamID, true)
+		}
 		return nil, true
 	}
+	s.blockedByConnection = false
+
+	if len(s.messages) > 0 {
+		var deferSending bool
+		sendWindow, deferSending = s.applyMessageBoundaries(maxBytes, sendWindow)
+		if deferSending {
+			return nil, true
+		}
+	}
 
 	f, hasMoreData := s.popNewStreamFrame(maxBytes, sendWindow)
 	if dataLen := f.DataLen(); dataLen > 0 {
 		s.writeOffset += f.DataLen()
 		s.flowController.AddBytesSent(f.DataLen())
 	}
+	// messages that were sent completely are not needed any more
+	for len(s.messages) > 0 && s.messages[0].End <= s.writeOffset {
+		s.messages = s.messages[1:]
+	}
+	if s.canceledWrite {
+		return f, s.nextFrame != nil
+	}
 	f.Fin = s.finishedWriting && s.dataForWriting == nil && s.nextFrame == nil && !s.finSent
 	if f.Fin {
 		s.finSent = true
@@ -264,6 +753,37 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 	return f, hasMoreData
 }
 
+// applyMessageBoundaries avoids splitting a message written by WriteMessage across two packets.
+// It returns the (reduced) number of bytes that can be sent in this packet,
+// and if sending should be deferred to the next packet.
+// When sending is deferred, popStreamFrame returns no frame, but says that there's more data to send.
+// The framer then doesn't ask the stream for more data until it packs the next packet.
+// must be called after locking the mutex
+func (s *sendStream) applyMessageBoundaries(maxBytes, maxDataLen protocol.ByteCount) (protocol.ByteCount, bool) {
+	f := &wire.StreamFrame{StreamID: s.streamID, Offset: s.writeOffset, DataLenPresent: true}
+	maxDataLen = utils.MinByteCount(maxDataLen, f.MaxDataLen(maxBytes, s.version))
+	split := utils.MinByteCount(s.writeOffset+maxDataLen, s.bufferedOffset()+s.dataForWritingLen())
+	for _, m := range s.messages {
+		if m.Start >= split {
+			break
+		}
+		// Only consider small messages that would be split at this point,
+		// and that weren't split already.
+		if m.End <= split || m.Start < s.writeOffset || m.End-m.Start > maxMessageSizeForBoundaryHint {
+			continue
+		}
+		if m.Start > s.writeOffset {
+			// send the data before the message
+			return m.Start - s.writeOffset, false
+		}
+		if s.deferredMessageEnd != m.End {
+			s.deferredMessageEnd = m.End
+			return 0, true
+		}
+	}
+	return maxDataLen, false
+}
+
 func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount) (*wire.StreamFrame, bool) {
 	if s.nextFrame != nil {
 		nextFrame := s.nextFrame
@@ -279,6 +799,14 @@ func (s *sendStream) popNewStreamFrame(maxBytes, sendWindow protocol.ByteCount)
 			copy(s.nextFrame.Data, nextFrame.Data[maxDataLen:])
 			nextFrame.Data = nextFrame.Data[:maxDataLen]
 		} else {
+			if len(s.queuedFrames) > 0 {
+				s.nextFrame = s.queuedFrames[0]
+				s.nextFrame.Offset = nextFrame.Offset + nextFrame.DataLen()
+				// Shift the queue instead of reslicing it, so it keeps its capacity.
+				n := copy(s.queuedFrames, s.queuedFrames[1:])
+				s.queuedFrames[n] = nil
+				s.queuedFrames = s.queuedFrames[:n]
+			}
 			s.signalWrite()
 		}
 		return nextFrame, s.nextFrame != nil || s.dataForWriting != nil
@@ -327,29 +855,32 @@ func (s *sendStream) hasData() bool {
 }
 
 func (s *sendStream) getDataForWriting(f *wire.StreamFrame, maxBytes protocol.ByteCount) {
-	if protocol.ByteCount(len(s.dataForWriting)) <= maxBytes {
-		f.Data = f.Data[:len(s.dataForWriting)]
-		copy(f.Data, s.dataForWriting)
-		s.dataForWriting = nil
-		s.signalWrite()
-		return
+	f.Data = f.Data[:0]
+	for s.dataForWriting != nil && protocol.ByteCount(len(f.Data)) < maxBytes {
+		n := utils.MinByteCount(maxBytes-protocol.ByteCount(len(f.Data)), protocol.ByteCount(len(s.dataForWriting)))
+		f.Data = append(f.Data, s.dataForWriting[:n]...)
+		s.dataForWriting = s.dataForWriting[n:]
+		s.skipEmptyBuffersForWriting()
 	}
-	f.Data = f.Data[:maxBytes]
-	copy(f.Data, s.dataForWriting)
-	s.dataForWriting = s.dataForWriting[maxBytes:]
-	if s.canBufferStreamFrame() {
+	if s.dataForWriting == nil || s.canBufferStreamFrame() {
 		s.signalWrite()
 	}
 }
 
 func (s *sendStream) frameAcked(f wire.Frame) {
-	f.(*wire.StreamFrame).PutBack()
+	sf := f.(*wire.StreamFrame)
+	start := sf.Offset
+	end := sf.Offset + sf.DataLen()
+	sf.PutBack()
 
 	s.mutex.Lock()
-	if s.canceledWrite {
+	if s.canceledWrite && s.reliableSize == 0 {
 		s.mutex.Unlock()
 		return
 	}
+	if end > start {
+		s.updateDeliveredOffset(start, end)
+	}
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
@@ -362,33 +893,165 @@ func (s *sendStream) frameAcked(f wire.Frame) {
 	}
 }
 
+// updateDeliveredOffset records that the range [start, end) was acknowledged.
+// must be called after locking the mutex
+func (s *sendStream) updateDeliveredOffset(start, end protocol.ByteCount) {
+	if start > s.deliveredOffset {
+		// Insert the range, merging it with the ranges it overlaps with.
+		i := sort.Search(len(s.ackedRanges), func(i int) bool { return s.ackedRanges[i].End >= start })
+		j := i
+		for j < len(s.ackedRanges) && s.ackedRanges[j].Start <= end {
+			start = utils.MinByteCount(start, s.ackedRanges[j].Start)
+			end = utils.MaxByteCount(end, s.ackedRanges[j].End)
+			j++
+		}
+		r := utils.ByteInterval{Start: start, End: end}
+		// Modify the ranges in place, so the (pooled) slice doesn't need to be reallocated.
+		if i == j {
+			s.ackedRanges = append(s.ackedRanges, utils.ByteInterval{})
+			copy(s.ackedRanges[i+1:], s.ackedRanges[i:])
+		} else {
+			s.ackedRanges = append(s.ackedRanges[:i+1], s.ackedRanges[j:]...)
+		}
+		s.ackedRanges[i] = r
+		return
+	}
+	s.deliveredOffset = utils.MaxByteCount(s.deliveredOffset, end)
+	var n int
+	for n < len(s.ackedRanges) && s.ackedRanges[n].Start <= s.deliveredOffset {
+		s.deliveredOffset = utils.MaxByteCount(s.deliveredOffset, s.ackedRanges[n].End)
+		n++
+	}
+	if n > 0 {
+		s.ackedRanges = s.ackedRanges[:copy(s.ackedRanges, s.ackedRanges[n:])]
+	}
+	s.notifyDeliveryWaiters(func(offset protocol.ByteCount) bool { return offset <= s.deliveredOffset })
+}
+
+func (s *sendStream) DeliveredOffset() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return uint64(s.deliveredOffset)
+}
+
+func (s *sendStream) Delivered(offset uint64) <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ch := make(chan struct{})
+	if protocol.ByteCount(offset) <= s.deliveredOffset || !s.canBeDelivered(protocol.ByteCount(offset)) {
+		close(ch)
+		return ch
+	}
+	s.deliveryWaiters = append(s.deliveryWaiters, deliveryWaiter{offset: protocol.ByteCount(offset), ch: ch})
+	return ch
+}
+
+// canBeDelivered says if the data up to offset can still be delivered to the peer.
+// must be called after locking the mutex
+func (s *sendStream) canBeDelivered(offset protocol.ByteCount) bool {
+	if s.closedForShutdown {
+		return false
+	}
+	return !s.canceledWrite || offset <= s.reliableSize
+}
+
+// notifyDeliveryWaiters closes the channels of all delivery waiters that cond returns true for.
+// must be called after locking the mutex
+func (s *sendStream) notifyDeliveryWaiters(cond func(offset protocol.ByteCount) bool) {
+	waiters := s.deliveryWaiters[:0]
+	for _, w := range s.deliveryWaiters {
+		if cond(w.offset) {
+			close(w.ch)
+			continue
+		}
+		waiters = append(waiters, w)
+	}
+	s.deliveryWaiters = waiters
+}
+
 func (s *sendStream) isNewlyCompleted() bool {
 	completed := (s.finSent || s.canceledWrite) && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0
+	// After CancelWriteAt, all data up to the reliable size needs to be sent.
+	if s.canceledWrite && s.writeOffset < s.reliableSize {
+		completed = false
+	}
 	if completed && !s.completed {
 		s.completed = true
+		s.releaseQueues()
+		if !s.canceledWrite {
+			// the FIN was acknowledged
+			s.markDone(nil)
+		}
 		return true
 	}
 	return false
 }
 
+// markDone closes the channel returned by Done.
+// must be called after locking the mutex
+func (s *sendStream) markDone(err error) {
+	if s.isDone {
+		return
+	}
+	s.isDone = true
+	s.doneErr = err
+	close(s.doneChan)
+}
+
+func (s *sendStream) Done() <-chan struct{} {
+	return s.doneChan
+}
+
+func (s *sendStream) Err() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.doneErr
+}
+
 func (s *sendStream) queueRetransmission(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
 	sf.DataLenPresent = true
 	s.mutex.Lock()
-	if s.canceledWrite {
+	if s.canceledWrite && s.reliableSize == 0 {
 		s.mutex.Unlock()
 		return
 	}
-	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.numOutstandingFrames--
 	if s.numOutstandingFrames < 0 {
 		panic("numOutStandingFrames negative")
 	}
+	if s.canceledWrite && !s.truncateToReliableSize(sf) {
+		// the frame only contained data beyond the reliable size
+		sf.PutBack()
+		newlyCompleted := s.isNewlyCompleted()
+		s.mutex.Unlock()
+
+		if newlyCompleted {
+			s.sender.onStreamCompleted(s.streamID)
+		}
+		return
+	}
+	s.retransmissionQueue = append(s.retransmissionQueue, sf)
 	s.mutex.Unlock()
 
 	s.sender.onHasStreamData(s.streamID)
 }
 
+// truncateToReliableSize removes the data beyond the reliable size from a STREAM frame.
+// It returns false if no data is left.
+// must be called after locking the mutex
+func (s *sendStream) truncateToReliableSize(f *wire.StreamFrame) bool {
+	if f.Offset >= s.reliableSize {
+		return false
+	}
+	if f.Offset+f.DataLen() > s.reliableSize {
+		f.Data = f.Data[:s.reliableSize-f.Offset]
+	}
+	f.Fin = false
+	return true
+}
+
 func (s *sendStream) Close() error {
 	s.mutex.Lock()
 	if s.closedForShutdown {
@@ -408,33 +1071,121 @@ func (s *sendStream) Close() error {
 }
 
 func (s *sendStream) CancelWrite(errorCode StreamErrorCode) {
-	s.cancelWriteImpl(errorCode, fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode))
+	s.cancelWriteImpl(errorCode, 0, s.newCancelWriteError(errorCode))
 }
 
-// must be called after locking the mutex
-func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, writeErr error) {
+func (s *sendStream) CancelWriteAt(errorCode StreamErrorCode, reliableSize uint64) error {
+	if !s.sender.supportsResetStreamAt() {
+		return errors.New("RESET_STREAM_AT not supported")
+	}
+	return s.cancelWriteImpl(errorCode, protocol.ByteCount(reliableSize), s.newCancelWriteError(errorCode))
+}
+
+// newCancelWriteError creates the error returned from Write after the stream was canceled locally.
+// It wraps the error registered for the error code, if any.
+func (s *sendStream) newCancelWriteError(errorCode StreamErrorCode) error {
+	if appErr := s.errorRegistry.Error(errorCode); appErr != nil {
+		return fmt.Errorf("Write on stream %d canceled with error code %d: %w", s.streamID, errorCode, appErr)
+	}
+	return fmt.Errorf("Write on stream %d canceled with error code %d", s.streamID, errorCode)
+}
+
+func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, reliableSize protocol.ByteCount, writeErr error) error {
 	s.mutex.Lock()
 	if s.canceledWrite {
 		s.mutex.Unlock()
-		return
+		return nil
+	}
+	if written := s.bufferedOffset(); reliableSize > written {
+		s.mutex.Unlock()
+		return fmt.Errorf("reliable size (%d) larger than the number of bytes written (%d)", reliableSize, written)
 	}
 	s.ctxCancel()
 	s.canceledWrite = true
 	s.cancelWriteErr = writeErr
-	s.numOutstandingFrames = 0
-	s.retransmissionQueue = nil
+	s.reliableSize = reliableSize
+	s.markDone(writeErr)
+	s.notifyDeliveryWaiters(func(offset protocol.ByteCount) bool { return !s.canBeDelivered(offset) })
+	if reliableSize == 0 {
+		s.numOutstandingFrames = 0
+		s.retransmissionQueue = nil
+	} else {
+		s.discardDataBeyondReliableSize()
+	}
+	hasStreamData := reliableSize > 0 && (s.nextFrame != nil || len(s.retransmissionQueue) > 0)
+	finalSize := utils.MaxByteCount(s.writeOffset, reliableSize)
 	newlyCompleted := s.isNewlyCompleted()
+	onWritable := s.onWritable
 	s.mutex.Unlock()
 
 	s.signalWrite()
+	// Writing now returns an error, so the callback needs to be called.
+	if onWritable != nil {
+		onWritable()
+	}
 	s.sender.queueControlFrame(&wire.ResetStreamFrame{
-		StreamID:  s.streamID,
-		FinalSize: s.writeOffset,
-		ErrorCode: errorCode,
+		StreamID:     s.streamID,
+		FinalSize:    finalSize,
+		ErrorCode:    errorCode,
+		ReliableSize: reliableSize,
 	})
+	if hasStreamData {
+		s.sender.onHasStreamData(s.streamID)
+	}
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
+	return nil
+}
+
+// bufferedOffset is the offset up to which data was copied into STREAM frames.
+// must be called after locking the mutex
+func (s *sendStream) bufferedOffset() protocol.ByteCount {
+	offset := s.writeOffset
+	if s.nextFrame != nil {
+		offset += s.nextFrame.DataLen()
+	}
+	for _, f := range s.queuedFrames {
+		offset += f.DataLen()
+	}
+	return offset
+}
+
+// discardDataBeyondReliableSize discards all data beyond the reliable size that wasn't sent yet,
+// and removes it from the retransmission queue.
+// must be called after locking the mutex
+func (s *sendStream) discardDataBeyondReliableSize() {
+	retransmissionQueue := s.retransmissionQueue[:0]
+	for _, f := range s.retransmissionQueue {
+		if s.truncateToReliableSize(f) {
+			retransmissionQueue = append(retransmissionQueue, f)
+		} else {
+			f.PutBack()
+		}
+	}
+	s.retransmissionQueue = retransmissionQueue
+
+	if s.nextFrame == nil {
+		return
+	}
+	frames := append([]*wire.StreamFrame{s.nextFrame}, s.queuedFrames...)
+	s.nextFrame = nil
+	s.queuedFrames = nil
+	offset := s.writeOffset
+	for _, f := range frames {
+		// The offset of queued frames is set when they become the nextFrame.
+		f.Offset = offset
+		offset += f.DataLen()
+		if !s.truncateToReliableSize(f) {
+			f.PutBack()
+			continue
+		}
+		if s.nextFrame == nil {
+			s.nextFrame = f
+		} else {
+			s.queuedFrames = append(s.queuedFrames, f)
+		}
+	}
 }
 
 func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
@@ -449,9 +1200,17 @@ func (s *sendStream) updateSendWindow(limit protocol.ByteCount) {
 }
 
 func (s *sendStream) handleStopSendingFrame(frame *wire.StopSendingFrame) {
-	s.cancelWriteImpl(frame.ErrorCode, &StreamError{
-		StreamID:  s.streamID,
-		ErrorCode: frame.ErrorCode,
+	s.mutex.Lock()
+	deliveredOffset := s.deliveredOffset
+	s.mutex.Unlock()
+
+	s.cancelWriteImpl(frame.ErrorCode, 0, &StopSendingError{
+		StreamError: StreamError{
+			StreamID:  s.streamID,
+			ErrorCode: frame.ErrorCode,
+			Err:       s.errorRegistry.Error(frame.ErrorCode),
+		},
+		DeliveredOffset: uint64(deliveredOffset),
 	})
 }
 
@@ -475,8 +1234,15 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.ctxCancel()
 	s.closedForShutdown = true
 	s.closeForShutdownErr = err
+	s.markDone(err)
+	s.notifyDeliveryWaiters(func(protocol.ByteCount) bool { return true })
+	onWritable := s.onWritable
 	s.mutex.Unlock()
+	s.idleTimer.Stop()
 	s.signalWrite()
+	if onWritable != nil {
+		onWritable()
+	}
 }
 
 // signalWrite performs a non-blocking send on the writeChan
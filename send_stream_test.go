@@ -2,16 +2,20 @@ package quic
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"io"
 	mrand "math/rand"
 	"runtime"
+	"testing/iotest"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo"
@@ -71,6 +75,45 @@ var _ = Describe("Send Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	Context("priorities", func() {
+		It("uses the default priority", func() {
+			Expect(str.Priority()).To(Equal(StreamPriority{Urgency: 3, Weight: 16}))
+		})
+
+		It("sets the priority", func() {
+			prio := StreamPriority{Urgency: 1, Incremental: true, Weight: 100}
+			mockSender.EXPECT().onStreamPriorityChanged(streamID, prio)
+			str.SetPriority(prio)
+			Expect(str.Priority()).To(Equal(prio))
+			// setting the same priority again is a no-op
+			str.SetPriority(prio)
+		})
+	})
+
+	Context("traffic classes", func() {
+		It("uses the default traffic class", func() {
+			Expect(str.TrafficClass()).To(Equal(TrafficClassDefault))
+		})
+
+		It("sets the priority configured for the traffic class", func() {
+			prio := StreamPriority{Urgency: 0, Weight: 200}
+			gomock.InOrder(
+				mockSender.EXPECT().trafficClassPriority(TrafficClass(1)).Return(prio, true),
+				mockSender.EXPECT().onStreamPriorityChanged(streamID, prio),
+			)
+			str.SetTrafficClass(1)
+			Expect(str.TrafficClass()).To(Equal(TrafficClass(1)))
+			Expect(str.Priority()).To(Equal(prio))
+		})
+
+		It("doesn't change the priority if none is configured for the traffic class", func() {
+			mockSender.EXPECT().trafficClassPriority(TrafficClass(2)).Return(StreamPriority{}, false)
+			str.SetTrafficClass(2)
+			Expect(str.TrafficClass()).To(Equal(TrafficClass(2)))
+			Expect(str.Priority()).To(Equal(defaultStreamPriority))
+		})
+	})
+
 	Context("writing", func() {
 		It("writes and gets all data at once", func() {
 			done := make(chan struct{})
@@ -148,6 +191,110 @@ var _ = Describe("Send Stream", func() {
 			Expect(f.Data).To(Equal([]byte("foobar")))
 		})
 
+		It("reports the amount of buffered data", func() {
+			Expect(str.BufferedAmount()).To(BeZero())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.BufferedAmount()).To(Equal(uint64(6)))
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			Expect(str.BufferedAmount()).To(BeZero())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			frame.OnLost(frame.Frame)
+			Expect(str.BufferedAmount()).To(Equal(uint64(6)))
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.BufferedAmount()).To(BeZero())
+		})
+
+		Context("non-blocking writes", func() {
+			It("buffers data until the send buffer is full", func() {
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				n, err := str.TryWrite([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(6))
+				n, err = str.TryWrite(make([]byte, protocol.MaxPacketBufferSize))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(protocol.MaxPacketBufferSize - 6))
+				n, err = str.TryWrite([]byte("foo"))
+				Expect(err).To(MatchError(ErrWouldBlock))
+				Expect(n).To(BeZero())
+				Expect(str.BufferedAmount()).To(BeEquivalentTo(protocol.MaxPacketBufferSize))
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(gomock.Any())
+				frame, _ := str.popStreamFrame(100)
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Data[:6]).To(Equal([]byte("foobar")))
+				// the data that was popped frees up space in the send buffer
+				mockSender.EXPECT().onHasStreamData(streamID)
+				n, err = str.TryWrite(make([]byte, 200))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(int(f.DataLen())))
+			})
+
+			It("returns an error when the write side was canceled", func() {
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				_, err := str.TryWrite([]byte("foobar"))
+				Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+			})
+
+			It("calls the OnWritable callback once the send buffer was sent", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.TryWrite(make([]byte, protocol.MaxPacketBufferSize))
+				Expect(err).ToNot(HaveOccurred())
+				var called bool
+				str.OnWritable(func() { called = true })
+				Expect(called).To(BeFalse())
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(2)
+				// the send buffer is only partially emptied
+				_, hasMoreData := str.popStreamFrame(1000)
+				Expect(hasMoreData).To(BeTrue())
+				var notify func()
+				mockSender.EXPECT().onStreamWritable(gomock.Any()).Do(func(n func()) { notify = n })
+				_, hasMoreData = str.popStreamFrame(protocol.MaxByteCount)
+				Expect(hasMoreData).To(BeFalse())
+				Expect(called).To(BeFalse())
+				notify()
+				Expect(called).To(BeTrue())
+			})
+
+			It("calls the OnWritable callback immediately if the stream is writable", func() {
+				var called bool
+				str.OnWritable(func() { called = true })
+				Expect(called).To(BeTrue())
+			})
+
+			It("calls the OnWritable callback when the write side is canceled", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.TryWrite(make([]byte, protocol.MaxPacketBufferSize))
+				Expect(err).ToNot(HaveOccurred())
+				var called bool
+				str.OnWritable(func() { called = true })
+				Expect(called).To(BeFalse())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				Expect(called).To(BeTrue())
+			})
+
+			It("calls the OnWritable callback when the stream is closed for shutdown", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.TryWrite(make([]byte, protocol.MaxPacketBufferSize))
+				Expect(err).ToNot(HaveOccurred())
+				var called bool
+				str.OnWritable(func() { called = true })
+				str.closeForShutdown(errors.New("shutdown"))
+				Expect(called).To(BeTrue())
+			})
+		})
+
 		It("writes and gets data in multiple turns, for large writes", func() {
 			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(5)
 			var totalBytesSent protocol.ByteCount
@@ -314,6 +461,7 @@ var _ = Describe("Send Stream", func() {
 					StreamID:          streamID,
 					MaximumStreamData: 12,
 				})
+				mockSender.EXPECT().onStreamBlocked(streamID, false)
 				done := make(chan struct{})
 				go func() {
 					defer GinkgoRecover()
@@ -357,6 +505,7 @@ var _ = Describe("Send Stream", func() {
 					StreamID:          streamID,
 					MaximumStreamData: 10,
 				})
+				mockSender.EXPECT().onStreamBlocked(streamID, false)
 				f, hasMoreData = str.popStreamFrame(1000)
 				Expect(f).To(BeNil())
 				Expect(hasMoreData).To(BeFalse())
@@ -364,6 +513,46 @@ var _ = Describe("Send Stream", func() {
 				str.closeForShutdown(nil)
 				Eventually(done).Should(BeClosed())
 			})
+
+			It("counts how often it is blocked by connection-level flow control", func() {
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					_, err := str.Write([]byte("foobar"))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				waitForWrite()
+
+				// the stream-level limit is not reached
+				windowState := flowcontrol.WindowState{BytesSent: 0, SendWindow: 100}
+				mockFC.EXPECT().State().Return(windowState).AnyTimes()
+				mockFC.EXPECT().SendWindowSize().Times(2)
+				mockFC.EXPECT().IsNewlyBlocked().Times(2)
+				mockSender.EXPECT().onStreamBlocked(streamID, true) // only reported once
+				for i := 0; i < 2; i++ {
+					f, hasMoreData := str.popStreamFrame(1000)
+					Expect(f).To(BeNil())
+					Expect(hasMoreData).To(BeTrue())
+				}
+				Expect(str.FlowControlState().ConnectionBlockedCount).To(BeEquivalentTo(1))
+				// the connection-level window is opened
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(3))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+				f, _ := str.popStreamFrame(expectedFrameHeaderLen(0) + 3)
+				Expect(f).ToNot(BeNil())
+				// blocked again
+				mockFC.EXPECT().SendWindowSize()
+				mockFC.EXPECT().IsNewlyBlocked()
+				mockSender.EXPECT().onStreamBlocked(streamID, true)
+				f, _ = str.popStreamFrame(1000)
+				Expect(f).To(BeNil())
+				Expect(str.FlowControlState().ConnectionBlockedCount).To(BeEquivalentTo(2))
+				// make the Write go routine return
+				str.closeForShutdown(nil)
+				Eventually(done).Should(BeClosed())
+			})
 		})
 
 		Context("deadlines", func() {
@@ -446,6 +635,91 @@ var _ = Describe("Send Stream", func() {
 				Expect(hasMoreData).To(BeFalse())
 			})
 
+			It("buffers the remaining data, if it fits into a single STREAM frame", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				str.SetWriteDeadline(time.Now().Add(time.Hour))
+				n, err := strWithTimeout.Write(getData(100))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(100))
+				Expect(str.nextFrame).ToNot(BeNil())
+			})
+
+			Context("discarding data on timeout", func() {
+				BeforeEach(func() {
+					str.SetDiscardOnWriteTimeout(true)
+				})
+
+				It("doesn't buffer data if a deadline is set, and discards it when the deadline expires", func() {
+					mockSender.EXPECT().onHasStreamData(streamID)
+					deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+					str.SetWriteDeadline(deadline)
+					n, err := strWithTimeout.Write(getData(100))
+					Expect(err).To(MatchError(errDeadline))
+					Expect(n).To(BeZero())
+					Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+					frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).To(BeNil())
+					Expect(hasMoreData).To(BeFalse())
+				})
+
+				It("returns the number of bytes sent", func() {
+					mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+					mockFC.EXPECT().AddBytesSent(gomock.Any())
+					mockSender.EXPECT().onHasStreamData(streamID)
+					str.SetWriteDeadline(time.Now().Add(time.Hour))
+					var n int
+					writeReturned := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						defer close(writeReturned)
+						var err error
+						n, err = str.Write(getData(100))
+						Expect(err).To(MatchError(errDeadline))
+					}()
+					waitForWrite()
+					frame, _ := str.popStreamFrame(50)
+					Expect(frame).ToNot(BeNil())
+					Consistently(writeReturned).ShouldNot(BeClosed())
+					str.SetWriteDeadline(time.Now().Add(-time.Second))
+					Eventually(writeReturned).Should(BeClosed())
+					Expect(n).To(BeEquivalentTo(frame.Frame.(*wire.StreamFrame).DataLen()))
+					frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).To(BeNil())
+					Expect(hasMoreData).To(BeFalse())
+				})
+
+				It("returns once all data was sent", func() {
+					mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+					mockFC.EXPECT().AddBytesSent(protocol.ByteCount(100))
+					mockSender.EXPECT().onHasStreamData(streamID)
+					ctx, cancel := context.WithCancel(context.Background())
+					defer cancel()
+					writeReturned := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						defer close(writeReturned)
+						n, err := str.WriteContext(ctx, getData(100))
+						Expect(err).ToNot(HaveOccurred())
+						Expect(n).To(Equal(100))
+					}()
+					waitForWrite()
+					Consistently(writeReturned).ShouldNot(BeClosed())
+					frame, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+					Expect(frame).ToNot(BeNil())
+					Expect(frame.Frame.(*wire.StreamFrame).Data).To(Equal(getData(100)))
+					Expect(hasMoreData).To(BeFalse())
+					Eventually(writeReturned).Should(BeClosed())
+				})
+
+				It("buffers data if neither a deadline nor a context is used", func() {
+					mockSender.EXPECT().onHasStreamData(streamID)
+					n, err := strWithTimeout.Write(getData(100))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(n).To(Equal(100))
+					Expect(str.nextFrame).ToNot(BeNil())
+				})
+			})
+
 			It("doesn't unblock if the deadline is changed before the first one expires", func() {
 				mockSender.EXPECT().onHasStreamData(streamID)
 				deadline1 := time.Now().Add(scaleDuration(50 * time.Millisecond))
@@ -518,6 +792,58 @@ var _ = Describe("Send Stream", func() {
 			})
 		})
 
+		Context("contexts", func() {
+			It("returns an error when WriteContext is called with a canceled context", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				n, err := str.WriteContext(ctx, []byte("foobar"))
+				Expect(err).To(MatchError(context.Canceled))
+				Expect(n).To(BeZero())
+			})
+
+			It("unblocks when the context is canceled", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					n, err := str.WriteContext(ctx, getData(5000))
+					Expect(err).To(MatchError(context.Canceled))
+					Expect(n).To(BeZero())
+					close(done)
+				}()
+				Consistently(done).ShouldNot(BeClosed())
+				cancel()
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("returns the number of bytes written, when the context is canceled", func() {
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any())
+				ctx, cancel := context.WithCancel(context.Background())
+				var n int
+				writeReturned := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(writeReturned)
+					mockSender.EXPECT().onHasStreamData(streamID)
+					var err error
+					n, err = str.WriteContext(ctx, getData(5000))
+					Expect(err).To(MatchError(context.Canceled))
+				}()
+				waitForWrite()
+				frame, hasMoreData := str.popStreamFrame(50)
+				Expect(frame).ToNot(BeNil())
+				Expect(hasMoreData).To(BeTrue())
+				cancel()
+				Eventually(writeReturned).Should(BeClosed())
+				Expect(n).To(BeEquivalentTo(frame.Frame.(*wire.StreamFrame).DataLen()))
+				frame, hasMoreData = str.popStreamFrame(50)
+				Expect(frame).To(BeNil())
+				Expect(hasMoreData).To(BeFalse())
+			})
+		})
+
 		Context("closing", func() {
 			It("doesn't allow writes after it has been closed", func() {
 				mockSender.EXPECT().onHasStreamData(streamID)
@@ -649,6 +975,387 @@ var _ = Describe("Send Stream", func() {
 		})
 	})
 
+	Context("reading from an io.Reader", func() {
+		BeforeEach(func() {
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		})
+
+		// popData pops all STREAM frames, and checks that their offsets are continuous, starting at offset
+		popData := func(offset int) []byte {
+			var data []byte
+			for {
+				frame, _ := str.popStreamFrame(protocol.MaxPacketBufferSize)
+				if frame == nil {
+					return data
+				}
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Offset).To(BeEquivalentTo(offset + len(data)))
+				Expect(f.DataLenPresent).To(BeTrue())
+				data = append(data, f.Data...)
+			}
+		}
+
+		It("reads all data", func() {
+			data := make([]byte, 5*protocol.MaxPacketBufferSize+42)
+			mrand.Read(data)
+			n, err := str.ReadFrom(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(len(data)))
+			Expect(popData(0)).To(Equal(data))
+			Expect(str.Close()).To(Succeed())
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Fin).To(BeTrue())
+		})
+
+		It("is used by io.Copy", func() {
+			// io.LimitReader hides the io.WriterTo implementation of the bytes.Reader
+			n, err := io.Copy(str, io.LimitReader(bytes.NewReader([]byte("foobar")), 6))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(6))
+			Expect(str.nextFrame).ToNot(BeNil())
+			Expect(str.dataForWriting).To(BeNil())
+			Expect(popData(0)).To(Equal([]byte("foobar")))
+		})
+
+		It("splits frames that don't fit into a packet", func() {
+			data := make([]byte, 3*protocol.MaxPacketBufferSize)
+			mrand.Read(data)
+			_, err := str.ReadFrom(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			var popped []byte
+			for {
+				frame, _ := str.popStreamFrame(500)
+				if frame == nil {
+					break
+				}
+				f := frame.Frame.(*wire.StreamFrame)
+				Expect(f.Length(protocol.VersionWhatever)).To(BeNumerically("<=", 500))
+				Expect(f.Offset).To(BeEquivalentTo(len(popped)))
+				popped = append(popped, f.Data...)
+			}
+			Expect(popped).To(Equal(data))
+		})
+
+		It("blocks until the queued frames are sent", func() {
+			data := make([]byte, (maxQueuedStreamFrames+5)*protocol.MaxPacketBufferSize)
+			mrand.Read(data)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				n, err := str.ReadFrom(bytes.NewReader(data))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(len(data)))
+			}()
+			Eventually(func() int {
+				str.mutex.Lock()
+				defer str.mutex.Unlock()
+				return len(str.queuedFrames)
+			}).Should(Equal(maxQueuedStreamFrames))
+			Consistently(done).ShouldNot(BeClosed())
+			var popped []byte
+			Eventually(func() []byte {
+				popped = append(popped, popData(len(popped))...)
+				return popped
+			}).Should(Equal(data))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("keeps the order of data written after ReadFrom", func() {
+			data := make([]byte, 2*protocol.MaxPacketBufferSize)
+			mrand.Read(data)
+			_, err := str.ReadFrom(bytes.NewReader(data))
+			Expect(err).ToNot(HaveOccurred())
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			var popped []byte
+			Eventually(func() []byte {
+				popped = append(popped, popData(len(popped))...)
+				return popped
+			}).Should(Equal(append(data, []byte("foobar")...)))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns the error returned by the reader", func() {
+			testErr := errors.New("test error")
+			r := io.MultiReader(bytes.NewReader([]byte("foobar")), iotest.ErrReader(testErr))
+			n, err := str.ReadFrom(r)
+			Expect(err).To(MatchError(testErr))
+			Expect(n).To(BeEquivalentTo(6))
+			Expect(popData(0)).To(Equal([]byte("foobar")))
+		})
+
+		It("errors when the stream was closed", func() {
+			Expect(str.Close()).To(Succeed())
+			_, err := str.ReadFrom(bytes.NewReader([]byte("foobar")))
+			Expect(err).To(MatchError("write on closed stream 1337"))
+		})
+
+		It("unblocks when the write is canceled", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID).MaxTimes(1)
+			data := make([]byte, (maxQueuedStreamFrames+5)*protocol.MaxPacketBufferSize)
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := str.ReadFrom(bytes.NewReader(data))
+				errChan <- err
+			}()
+			Consistently(errChan).ShouldNot(Receive())
+			str.CancelWrite(1234)
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			Expect(err).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+		})
+
+		It("respects the write deadline", func() {
+			deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+			str.SetWriteDeadline(deadline)
+			data := make([]byte, (maxQueuedStreamFrames+5)*protocol.MaxPacketBufferSize)
+			errChan := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				_, err := str.ReadFrom(bytes.NewReader(data))
+				errChan <- err
+			}()
+			var err error
+			Eventually(errChan).Should(Receive(&err))
+			Expect(err).To(MatchError(errDeadline))
+			Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+		})
+	})
+
+	Context("writing buffers", func() {
+		BeforeEach(func() {
+			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		})
+
+		It("bundles small buffers into a single STREAM frame", func() {
+			n, err := str.WriteBuffers([][]byte{[]byte("foo"), nil, []byte("bar")})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeEquivalentTo(6))
+			Expect(str.dataForWriting).To(BeNil())
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			f := frame.Frame.(*wire.StreamFrame)
+			Expect(f.Data).To(Equal([]byte("foobar")))
+			Expect(f.Offset).To(BeZero())
+			Expect(str.popStreamFrame(protocol.MaxByteCount)).To(BeNil())
+		})
+
+		It("packs large buffers into STREAM frames", func() {
+			bufs := [][]byte{make([]byte, 3000), {}, make([]byte, 1), make([]byte, 2500)}
+			var data []byte
+			for _, b := range bufs {
+				mrand.Read(b)
+				data = append(data, b...)
+			}
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				n, err := str.WriteBuffers(bufs)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(BeEquivalentTo(len(data)))
+			}()
+			waitForWrite()
+			var popped []byte
+			Eventually(func() []byte {
+				frame, _ := str.popStreamFrame(1000)
+				if frame != nil {
+					f := frame.Frame.(*wire.StreamFrame)
+					Expect(f.Offset).To(BeEquivalentTo(len(popped)))
+					Expect(f.DataLen()).To(BeNumerically("<=", 1000))
+					popped = append(popped, f.Data...)
+				}
+				return popped
+			}).Should(Equal(data))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("returns when given no data", func() {
+			n, err := str.WriteBuffers(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeZero())
+			n, err = str.WriteBuffers([][]byte{{}, {}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(n).To(BeZero())
+			Expect(str.popStreamFrame(protocol.MaxByteCount)).To(BeNil())
+		})
+
+		It("returns the number of bytes written, when the deadline expires", func() {
+			deadline := time.Now().Add(scaleDuration(50 * time.Millisecond))
+			str.SetWriteDeadline(deadline)
+			var n int64
+			writeReturned := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(writeReturned)
+				var err error
+				n, err = str.WriteBuffers([][]byte{getData(3000), getData(2000)})
+				Expect(err).To(MatchError(errDeadline))
+				Expect(time.Now()).To(BeTemporally("~", deadline, scaleDuration(20*time.Millisecond)))
+			}()
+			waitForWrite()
+			frame, _ := str.popStreamFrame(1000)
+			Expect(frame).ToNot(BeNil())
+			Eventually(writeReturned).Should(BeClosed())
+			Expect(n).To(BeEquivalentTo(frame.Frame.(*wire.StreamFrame).DataLen()))
+			Expect(str.buffersForWriting).To(BeEmpty())
+		})
+
+		It("errors when the stream was closed", func() {
+			Expect(str.Close()).To(Succeed())
+			_, err := str.WriteBuffers([][]byte{[]byte("foobar")})
+			Expect(err).To(MatchError("write on closed stream 1337"))
+		})
+	})
+
+	Context("writing messages", func() {
+		BeforeEach(func() {
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+			mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+		})
+
+		popFrame := func(maxBytes protocol.ByteCount) *wire.StreamFrame {
+			frame, _ := str.popStreamFrame(maxBytes)
+			if frame == nil {
+				return nil
+			}
+			return frame.Frame.(*wire.StreamFrame)
+		}
+
+		It("doesn't split a message that fits into the next packet", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := str.WriteMessage(getDataAtOffset(0, 10))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = str.WriteMessage(getDataAtOffset(10, 50))
+			Expect(err).ToNot(HaveOccurred())
+			// the first message and a part of the second message would fit
+			f := popFrame(expectedFrameHeaderLen(0) + 30)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Data).To(Equal(getDataAtOffset(0, 10)))
+			// the second message doesn't fit, and is deferred to the next packet
+			frame, hasMoreData := str.popStreamFrame(expectedFrameHeaderLen(10) + 30)
+			Expect(frame).To(BeNil())
+			Expect(hasMoreData).To(BeTrue())
+			f = popFrame(expectedFrameHeaderLen(10) + 100)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Offset).To(BeEquivalentTo(10))
+			Expect(f.Data).To(Equal(getDataAtOffset(10, 50)))
+			Expect(str.messages).To(BeEmpty())
+		})
+
+		It("defers every message only once", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := str.WriteMessage(getData(50))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(popFrame(expectedFrameHeaderLen(0) + 30)).To(BeNil())
+			f := popFrame(expectedFrameHeaderLen(0) + 30)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Data).To(Equal(getData(30)))
+			f = popFrame(protocol.MaxByteCount)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Data).To(Equal(getDataAtOffset(30, 20)))
+		})
+
+		It("splits large messages", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := str.WriteMessage(getData(maxMessageSizeForBoundaryHint + 1))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			f := popFrame(expectedFrameHeaderLen(0) + 50)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Data).To(Equal(getData(50)))
+			f = popFrame(protocol.MaxByteCount)
+			Expect(f).ToNot(BeNil())
+			Expect(f.Offset + f.DataLen()).To(BeEquivalentTo(maxMessageSizeForBoundaryHint + 1))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("doesn't record messages that weren't written", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			_, err := str.WriteMessage([]byte("foobar"))
+			Expect(err).To(HaveOccurred())
+			Expect(str.messages).To(BeEmpty())
+		})
+	})
+
+	Context("flushing", func() {
+		It("flushes the stream, if it has data to send", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().onStreamFlushed(streamID)
+			Expect(str.Flush()).To(Succeed())
+		})
+
+		It("flushes the stream, if it needs to send the FIN", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			mockSender.EXPECT().onStreamFlushed(streamID)
+			Expect(str.Flush()).To(Succeed())
+		})
+
+		It("doesn't flush the stream, if it doesn't have any data to send", func() {
+			Expect(str.Flush()).To(Succeed())
+		})
+
+		It("errors after the stream was canceled", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.Flush()).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+		})
+
+		It("errors after the stream was closed for shutdown", func() {
+			testErr := errors.New("test error")
+			str.closeForShutdown(testErr)
+			Expect(str.Flush()).To(MatchError(testErr))
+		})
+	})
+
+	Context("pending data", func() {
+		It("says how much data is waiting to be sent", func() {
+			Expect(str.pendingData()).To(BeZero())
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.pendingData()).To(Equal(protocol.ByteCount(6)))
+		})
+
+		It("doesn't allow delaying the FIN", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			Expect(str.pendingData()).To(Equal(protocol.MaxByteCount))
+		})
+
+		It("doesn't have any pending data after the stream was canceled", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.pendingData()).To(BeZero())
+		})
+	})
+
 	Context("handling MAX_STREAM_DATA frames", func() {
 		It("informs the flow controller", func() {
 			mockFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1337))
@@ -840,6 +1547,100 @@ var _ = Describe("Send Stream", func() {
 			})
 		})
 
+		Context("canceling writing at a reliable size", func() {
+			It("errors if the peer doesn't support RESET_STREAM_AT", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(false)
+				Expect(str.CancelWriteAt(1234, 0)).To(MatchError("RESET_STREAM_AT not supported"))
+			})
+
+			It("errors if the reliable size is larger than the number of bytes written", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := strWithTimeout.Write(getData(100))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.CancelWriteAt(1234, 101)).To(MatchError("reliable size (101) larger than the number of bytes written (100)"))
+			})
+
+			It("sends the data up to the reliable size", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(2)
+				_, err := strWithTimeout.Write(getData(100))
+				Expect(err).ToNot(HaveOccurred())
+				frame1, _ := str.popStreamFrame(50)
+				Expect(frame1).ToNot(BeNil())
+				f1 := frame1.Frame.(*wire.StreamFrame)
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					FinalSize:    80,
+					ErrorCode:    1234,
+					ReliableSize: 80,
+				})
+				Expect(str.CancelWriteAt(1234, 80)).To(Succeed())
+				frame2, hasMoreData := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame2).ToNot(BeNil())
+				Expect(hasMoreData).To(BeFalse())
+				f2 := frame2.Frame.(*wire.StreamFrame)
+				Expect(f2.Offset).To(Equal(f1.DataLen()))
+				Expect(f2.Offset + f2.DataLen()).To(BeEquivalentTo(80))
+				Expect(f2.Data).To(Equal(getDataAtOffset(f2.Offset, f2.DataLen())))
+				Expect(f2.Fin).To(BeFalse())
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+				// the stream is completed once all data up to the reliable size was acknowledged
+				frame1.OnAcked(f1)
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				frame2.OnAcked(f2)
+			})
+
+			It("retransmits lost data up to the reliable size", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(gomock.Any())
+				_, err := strWithTimeout.Write(getData(100))
+				Expect(err).ToNot(HaveOccurred())
+				frame1, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame1).ToNot(BeNil())
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:     streamID,
+					FinalSize:    100,
+					ErrorCode:    1234,
+					ReliableSize: 20,
+				})
+				Expect(str.CancelWriteAt(1234, 20)).To(Succeed())
+				frame1.OnLost(frame1.Frame)
+				frame2, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame2).ToNot(BeNil())
+				f2 := frame2.Frame.(*wire.StreamFrame)
+				Expect(f2.Offset).To(BeZero())
+				Expect(f2.Data).To(Equal(getData(20)))
+				Expect(f2.Fin).To(BeFalse())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				frame2.OnAcked(f2)
+			})
+
+			It("doesn't retransmit data beyond the reliable size", func() {
+				mockSender.EXPECT().supportsResetStreamAt().Return(true)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).Times(2)
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).Times(2)
+				_, err := strWithTimeout.Write(getData(100))
+				Expect(err).ToNot(HaveOccurred())
+				frame1, _ := str.popStreamFrame(50)
+				frame2, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame2).ToNot(BeNil())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				Expect(str.CancelWriteAt(1234, 20)).To(Succeed())
+				frame2.OnLost(frame2.Frame)
+				frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+				Expect(frame).To(BeNil())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				frame1.OnAcked(frame1.Frame)
+			})
+		})
+
 		Context("receiving STOP_SENDING frames", func() {
 			It("queues a RESET_STREAM frames, and copies the error code from the STOP_SENDING frame", func() {
 				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
@@ -889,6 +1690,199 @@ var _ = Describe("Send Stream", func() {
 					ErrorCode: 1234,
 				}))
 			})
+
+			It("reports the error code and the delivered offset", func() {
+				str.numOutstandingFrames = 3
+				str.frameAcked(&wire.StreamFrame{StreamID: streamID, Offset: 10, Data: make([]byte, 10)})
+				str.frameAcked(&wire.StreamFrame{StreamID: streamID, Data: make([]byte, 5)})
+				str.frameAcked(&wire.StreamFrame{StreamID: streamID, Offset: 5, Data: make([]byte, 5)})
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(gomock.Any())
+				str.handleStopSendingFrame(&wire.StopSendingFrame{
+					StreamID:  streamID,
+					ErrorCode: 123,
+				})
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).To(MatchError(&StopSendingError{
+					StreamError:     StreamError{StreamID: streamID, ErrorCode: 123},
+					DeliveredOffset: 20,
+				}))
+				var streamErr *StreamError
+				Expect(errors.As(err, &streamErr)).To(BeTrue())
+				Expect(streamErr.ErrorCode).To(BeEquivalentTo(123))
+				Expect(err.Error()).To(ContainSubstring("data delivered up to offset 20"))
+			})
+
+			It("wraps the error registered for the error code", func() {
+				errRejected := errors.New("request rejected")
+				str.errorRegistry = newStreamErrorRegistry()
+				Expect(str.errorRegistry.Register(123, errRejected)).To(Succeed())
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(gomock.Any())
+				str.handleStopSendingFrame(&wire.StopSendingFrame{
+					StreamID:  streamID,
+					ErrorCode: 123,
+				})
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).To(MatchError(errRejected))
+				var streamErr *StreamError
+				Expect(errors.As(err, &streamErr)).To(BeTrue())
+				Expect(streamErr.Err).To(Equal(errRejected))
+				Expect(err.Error()).To(Equal("stream 1337: peer stopped reading with error code 123 (data delivered up to offset 0): request rejected"))
+			})
+		})
+	})
+
+	Context("tracking delivered data", func() {
+		It("tracks data acknowledged in order", func() {
+			str.updateDeliveredOffset(0, 10)
+			str.updateDeliveredOffset(10, 15)
+			Expect(str.deliveredOffset).To(Equal(protocol.ByteCount(15)))
+			Expect(str.ackedRanges).To(BeEmpty())
+		})
+
+		It("tracks data acknowledged out of order", func() {
+			str.updateDeliveredOffset(20, 30)
+			str.updateDeliveredOffset(40, 50)
+			str.updateDeliveredOffset(10, 15)
+			Expect(str.deliveredOffset).To(BeZero())
+			Expect(str.ackedRanges).To(Equal([]utils.ByteInterval{{Start: 10, End: 15}, {Start: 20, End: 30}, {Start: 40, End: 50}}))
+			str.updateDeliveredOffset(32, 35)
+			Expect(str.ackedRanges).To(Equal([]utils.ByteInterval{{Start: 10, End: 15}, {Start: 20, End: 30}, {Start: 32, End: 35}, {Start: 40, End: 50}}))
+			// merge overlapping and adjacent ranges
+			str.updateDeliveredOffset(15, 45)
+			Expect(str.ackedRanges).To(Equal([]utils.ByteInterval{{Start: 10, End: 50}}))
+			str.updateDeliveredOffset(0, 10)
+			Expect(str.deliveredOffset).To(Equal(protocol.ByteCount(50)))
+			Expect(str.ackedRanges).To(BeEmpty())
+		})
+
+		It("handles duplicate acknowledgements", func() {
+			str.updateDeliveredOffset(0, 10)
+			str.updateDeliveredOffset(20, 30)
+			str.updateDeliveredOffset(20, 30)
+			str.updateDeliveredOffset(5, 8)
+			Expect(str.deliveredOffset).To(Equal(protocol.ByteCount(10)))
+			Expect(str.ackedRanges).To(Equal([]utils.ByteInterval{{Start: 20, End: 30}}))
+		})
+
+		It("notifies when data was delivered", func() {
+			delivered10 := str.Delivered(10)
+			delivered20 := str.Delivered(20)
+			Expect(str.Delivered(0)).To(BeClosed())
+			str.updateDeliveredOffset(10, 20)
+			Expect(delivered10).ToNot(BeClosed())
+			str.updateDeliveredOffset(0, 5)
+			Expect(delivered10).ToNot(BeClosed())
+			Expect(str.DeliveredOffset()).To(BeEquivalentTo(5))
+			str.updateDeliveredOffset(5, 10)
+			Expect(delivered10).To(BeClosed())
+			Expect(delivered20).To(BeClosed())
+			Expect(str.DeliveredOffset()).To(BeEquivalentTo(20))
+			Expect(str.deliveryWaiters).To(BeEmpty())
+			Expect(str.Delivered(15)).To(BeClosed())
+		})
+
+		It("notifies when data can't be delivered, because the stream was canceled", func() {
+			delivered := str.Delivered(10)
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(delivered).To(BeClosed())
+			Expect(str.DeliveredOffset()).To(BeZero())
+			Expect(str.Delivered(5)).To(BeClosed())
+		})
+
+		It("notifies when data beyond the reliable size can't be delivered", func() {
+			mockSender.EXPECT().supportsResetStreamAt().Return(true)
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := strWithTimeout.Write(getData(100))
+			Expect(err).ToNot(HaveOccurred())
+			delivered50 := str.Delivered(50)
+			delivered100 := str.Delivered(100)
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			Expect(str.CancelWriteAt(1234, 50)).To(Succeed())
+			Expect(delivered100).To(BeClosed())
+			Expect(delivered50).ToNot(BeClosed())
+			str.mutex.Lock()
+			str.updateDeliveredOffset(0, 50)
+			str.mutex.Unlock()
+			Expect(delivered50).To(BeClosed())
+		})
+
+		It("notifies when the stream is closed for shutdown", func() {
+			delivered := str.Delivered(10)
+			str.closeForShutdown(errors.New("shutdown"))
+			Expect(delivered).To(BeClosed())
+			Expect(str.Delivered(5)).To(BeClosed())
+		})
+	})
+
+	Context("signaling when the stream is done", func() {
+		It("is done when the FIN is acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			_, err := strWithTimeout.Write([]byte("foobar"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.Close()).To(Succeed())
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame.Frame.(*wire.StreamFrame).Fin).To(BeTrue())
+			Expect(str.Done()).ToNot(BeClosed())
+			Expect(str.Err()).ToNot(HaveOccurred())
+			// the frame is lost and retransmitted
+			mockSender.EXPECT().onHasStreamData(streamID)
+			frame.OnLost(frame.Frame)
+			frame, _ = str.popStreamFrame(protocol.MaxByteCount)
+			Expect(str.Done()).ToNot(BeClosed())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frame.OnAcked(frame.Frame)
+			Expect(str.Done()).To(BeClosed())
+			Expect(str.Err()).ToNot(HaveOccurred())
+		})
+
+		It("is done when the write-side is canceled", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.Done()).To(BeClosed())
+			Expect(str.Err()).To(MatchError("Write on stream 1337 canceled with error code 1234"))
+		})
+
+		It("wraps the error registered for the error code when the write-side is canceled", func() {
+			errCanceled := errors.New("request canceled")
+			str.errorRegistry = newStreamErrorRegistry()
+			Expect(str.errorRegistry.Register(1234, errCanceled)).To(Succeed())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			Expect(str.Err()).To(MatchError(errCanceled))
+			Expect(str.Err().Error()).To(Equal("Write on stream 1337 canceled with error code 1234: request canceled"))
+		})
+
+		It("is done when a STOP_SENDING frame is received", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.handleStopSendingFrame(&wire.StopSendingFrame{StreamID: streamID, ErrorCode: 1234})
+			Expect(str.Done()).To(BeClosed())
+			var stopSendingErr *StopSendingError
+			Expect(errors.As(str.Err(), &stopSendingErr)).To(BeTrue())
+			Expect(stopSendingErr.ErrorCode).To(BeEquivalentTo(1234))
+		})
+
+		It("is done when the stream is closed for shutdown", func() {
+			testErr := errors.New("shutdown")
+			str.closeForShutdown(testErr)
+			Expect(str.Done()).To(BeClosed())
+			Expect(str.Err()).To(MatchError(testErr))
+		})
+
+		It("keeps the first reason", func() {
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			str.closeForShutdown(errors.New("shutdown"))
+			Expect(str.Err()).To(MatchError("Write on stream 1337 canceled with error code 1234"))
 		})
 	})
 
@@ -1044,6 +2038,26 @@ var _ = Describe("Send Stream", func() {
 			frame.OnAcked(frame.Frame)
 		})
 
+		It("releases the queues when the stream is completed", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.queues).ToNot(BeNil())
+			Expect(str.Close()).To(Succeed())
+			frame, _ := str.popStreamFrame(protocol.MaxByteCount)
+			Expect(frame).ToNot(BeNil())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frame.OnAcked(frame.Frame)
+			Expect(str.queues).To(BeNil())
+			Expect(str.queuedFrames).To(BeNil())
+			Expect(str.retransmissionQueue).To(BeNil())
+			Expect(str.ackedRanges).To(BeNil())
+			// The pool might or might not return the queues that were just released.
+			q := sendStreamQueuesPool.Get().(*sendStreamQueues)
+			Expect(q.queuedFrames).To(BeEmpty())
+			Expect(q.retransmissionQueue).To(BeEmpty())
+			Expect(q.ackedRanges).To(BeEmpty())
+			sendStreamQueuesPool.Put(q)
+		})
+
 		It("says when a stream is completed, if Close() is called before popping the frame", func() {
 			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
 			done := make(chan struct{})
@@ -153,11 +153,12 @@ func listenAddr(addr string, tlsConf *tls.Config, config *Config, acceptEarly bo
 // PacketConn satisfies the OOBCapablePacketConn interface (as a net.UDPConn
 // does), ECN and packet info support will be enabled. In this case, ReadMsgUDP
 // and WriteMsgUDP will be used instead of ReadFrom and WriteTo to read/write
-// packets. A single net.PacketConn only be used for a single call to Listen.
+// packets. To use a PacketConn that is not a UDP socket, see NewGenericPacketConn.
+// A single net.PacketConn only be used for a single call to Listen.
 // The PacketConn can be used for simultaneous calls to Dial. QUIC connection
 // IDs are used for demultiplexing the different connections. The tls.Config
-// must not be nil and must contain a certificate configuration. The
-// tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites. Furthermore,
+// must not be nil and must contain a certificate configuration, unless
+// Config.Certificates is set. The tls.Config.CipherSuites allows setting of TLS 1.3 cipher suites. Furthermore,
 // it must define an application control (using NextProtos). The quic.Config may
 // be nil, in that case the default values will be used.
 func Listen(conn net.PacketConn, tlsConf *tls.Config, config *Config) (Listener, error) {
@@ -181,6 +182,12 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		return nil, err
 	}
 	config = populateServerConfig(config)
+	if config.Certificates != nil {
+		tlsConf = config.Certificates.configureTLS(tlsConf)
+	}
+	if isGenericPacketConn(conn) {
+		config.DisablePathMTUDiscovery = true
+	}
 	for _, v := range config.Versions {
 		if !protocol.IsValidVersion(v) {
 			return nil, fmt.Errorf("%s is not a valid QUIC version", v)
@@ -208,7 +215,7 @@ func listen(conn net.PacketConn, tlsConf *tls.Config, config *Config, acceptEarl
 		sessionQueue:        make(chan quicSession),
 		errorChan:           make(chan struct{}),
 		running:             make(chan struct{}),
-		receivedPackets:     make(chan *receivedPacket, protocol.MaxServerUnprocessedPackets),
+		receivedPackets:     make(chan *receivedPacket, config.MaxUnprocessedPackets),
 		newSession:          newSession,
 		logger:              utils.DefaultLogger.WithPrefix("server"),
 		acceptEarlySessions: acceptEarly,
@@ -251,6 +258,10 @@ var defaultAcceptToken = func(clientAddr net.Addr, token *Token) bool {
 	}
 	var sourceAddr string
 	if udpAddr, ok := clientAddr.(*net.UDPAddr); ok {
+		// tokens bound to a network prefix, see Config.TokenIPv4PrefixLength and Config.TokenIPv6PrefixLength
+		if _, prefix, err := net.ParseCIDR(token.RemoteAddr); err == nil {
+			return prefix.Contains(udpAddr.IP)
+		}
 		sourceAddr = udpAddr.IP.String()
 	} else {
 		sourceAddr = clientAddr.String()
@@ -320,17 +331,42 @@ func (s *baseServer) Addr() net.Addr {
 func (s *baseServer) handlePacket(p *receivedPacket) {
 	select {
 	case s.receivedPackets <- p:
+		return
 	default:
-		s.logger.Debugf("Dropping packet from %s (%d bytes). Server receive queue full.", p.remoteAddr, p.Size())
-		if s.config.Tracer != nil {
-			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
+	}
+	if s.config.UnprocessedPacketsEvictionPolicy == EvictOldest {
+		select {
+		case oldest := <-s.receivedPackets:
+			s.dropQueuedPacket(oldest)
+		default:
+		}
+		select {
+		case s.receivedPackets <- p:
+			return
+		default:
 		}
 	}
+	s.dropQueuedPacket(p)
+}
+
+func (s *baseServer) dropQueuedPacket(p *receivedPacket) {
+	s.logger.Debugf("Dropping packet from %s (%d bytes). Server receive queue full.", p.remoteAddr, p.Size())
+	if s.config.Tracer != nil {
+		s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeNotDetermined, p.Size(), logging.PacketDropDOSPrevention)
+	}
+	p.buffer.Release()
 }
 
 func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer still in use? */ {
 	if wire.IsVersionNegotiationPacket(p.data) {
 		s.logger.Debugf("Dropping Version Negotiation packet.")
+		if s.config.InspectLongHeaderPacket != nil {
+			if hdr, supportedVersions, err := wire.ParseVersionNegotiationPacket(bytes.NewReader(p.data)); err == nil {
+				info := newLongHeaderPacketInfo(p, hdr)
+				info.SupportedVersions = supportedVersions
+				s.config.InspectLongHeaderPacket(info)
+			}
+		}
 		if s.config.Tracer != nil {
 			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeVersionNegotiation, p.Size(), logging.PacketDropUnexpectedPacket)
 		}
@@ -352,6 +388,7 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer s
 	}
 	if hdr.Type == protocol.PacketTypeInitial && p.Size() < protocol.MinInitialPacketSize {
 		s.logger.Debugf("Dropping a packet that is too small to be a valid Initial (%d bytes)", p.Size())
+		s.inspectLongHeaderPacket(p, hdr)
 		if s.config.Tracer != nil {
 			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropUnexpectedPacket)
 		}
@@ -359,6 +396,7 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer s
 	}
 	// send a Version Negotiation Packet if the client is speaking a different protocol version
 	if !protocol.IsSupportedVersion(s.config.Versions, hdr.Version) {
+		s.inspectLongHeaderPacket(p, hdr)
 		if p.Size() < protocol.MinUnknownVersionPacketSize {
 			s.logger.Debugf("Dropping a packet with an unknown version that is too small (%d bytes)", p.Size())
 			if s.config.Tracer != nil {
@@ -376,6 +414,7 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer s
 		// There's little point in sending a Stateless Reset, since the client
 		// might not have received the token yet.
 		s.logger.Debugf("Dropping long header packet of type %s (%d bytes)", hdr.Type, len(p.data))
+		s.inspectLongHeaderPacket(p, hdr)
 		if s.config.Tracer != nil {
 			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeFromHeader(hdr), p.Size(), logging.PacketDropUnexpectedPacket)
 		}
@@ -392,8 +431,34 @@ func (s *baseServer) handlePacketImpl(p *receivedPacket) bool /* is the buffer s
 	return true
 }
 
+// inspectLongHeaderPacket passes the header of a packet that is not handled by a session
+// to the Config.InspectLongHeaderPacket callback.
+// Packets handled by a session are reported by the session.
+func (s *baseServer) inspectLongHeaderPacket(p *receivedPacket, hdr *wire.Header) {
+	if s.config.InspectLongHeaderPacket != nil {
+		s.config.InspectLongHeaderPacket(newLongHeaderPacketInfo(p, hdr))
+	}
+}
+
+// newLongHeaderPacketInfo copies the header of a received long header packet.
+func newLongHeaderPacketInfo(p *receivedPacket, hdr *wire.Header) *LongHeaderPacketInfo {
+	info := &LongHeaderPacketInfo{
+		RemoteAddr:       p.remoteAddr,
+		Type:             logging.PacketTypeFromHeader(hdr),
+		Version:          hdr.Version,
+		DestConnectionID: append([]byte{}, hdr.DestConnectionID...),
+		SrcConnectionID:  append([]byte{}, hdr.SrcConnectionID...),
+		Size:             int(p.Size()),
+	}
+	if len(hdr.Token) > 0 {
+		info.Token = append([]byte{}, hdr.Token...)
+	}
+	return info
+}
+
 func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) error {
 	if len(hdr.Token) == 0 && hdr.DestConnectionID.Len() < protocol.MinConnectionIDLenInitial {
+		s.inspectLongHeaderPacket(p, hdr)
 		p.buffer.Release()
 		if s.config.Tracer != nil {
 			s.config.Tracer.DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropUnexpectedPacket)
@@ -421,6 +486,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		}
 	}
 	if !s.config.AcceptToken(p.remoteAddr, token) {
+		s.inspectLongHeaderPacket(p, hdr)
 		go func() {
 			defer p.buffer.Release()
 			if token != nil && token.IsRetryToken {
@@ -438,6 +504,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 
 	if queueLen := atomic.LoadInt32(&s.sessionQueueLen); queueLen >= protocol.MaxAcceptQueueSize {
 		s.logger.Debugf("Rejecting new connection. Server currently busy. Accept queue length: %d (max %d)", queueLen, protocol.MaxAcceptQueueSize)
+		s.inspectLongHeaderPacket(p, hdr)
 		go func() {
 			defer p.buffer.Release()
 			if err := s.sendConnectionRefused(p.remoteAddr, hdr, p.info); err != nil {
@@ -447,7 +514,7 @@ func (s *baseServer) handleInitialImpl(p *receivedPacket, hdr *wire.Header) erro
 		return nil
 	}
 
-	connID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	connID, err := s.config.newConnectionID()
 	if err != nil {
 		return err
 	}
@@ -532,7 +599,7 @@ func (s *baseServer) sendRetry(remoteAddr net.Addr, hdr *wire.Header, info *pack
 	// Log the Initial packet now.
 	// If no Retry is sent, the packet will be logged by the session.
 	(&wire.ExtendedHeader{Header: *hdr}).Log(s.logger)
-	srcConnID, err := protocol.GenerateConnectionID(s.config.ConnectionIDLength)
+	srcConnID, err := s.config.newConnectionID()
 	if err != nil {
 		return err
 	}
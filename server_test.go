@@ -136,12 +136,13 @@ var _ = Describe("Server", func() {
 		supportedVersions := []protocol.VersionNumber{protocol.VersionTLS}
 		acceptToken := func(_ net.Addr, _ *Token) bool { return true }
 		config := Config{
-			Versions:             supportedVersions,
-			AcceptToken:          acceptToken,
-			HandshakeIdleTimeout: 1337 * time.Hour,
-			MaxIdleTimeout:       42 * time.Minute,
-			KeepAlive:            true,
-			StatelessResetKey:    []byte("foobar"),
+			Versions:              supportedVersions,
+			AcceptToken:           acceptToken,
+			HandshakeIdleTimeout:  1337 * time.Hour,
+			MaxIdleTimeout:        42 * time.Minute,
+			KeepAlive:             true,
+			StatelessResetKey:     []byte("foobar"),
+			MaxUnprocessedPackets: 42,
 		}
 		ln, err := Listen(conn, tlsConf, &config)
 		Expect(err).ToNot(HaveOccurred())
@@ -153,6 +154,22 @@ var _ = Describe("Server", func() {
 		Expect(reflect.ValueOf(server.config.AcceptToken)).To(Equal(reflect.ValueOf(acceptToken)))
 		Expect(server.config.KeepAlive).To(BeTrue())
 		Expect(server.config.StatelessResetKey).To(Equal([]byte("foobar")))
+		Expect(cap(server.receivedPackets)).To(Equal(42))
+		// stop the listener
+		Expect(ln.Close()).To(Succeed())
+	})
+
+	It("uses the certificates from the Config", func() {
+		set := &CertificateSet{}
+		ln, err := Listen(conn, tlsConf, &Config{Certificates: set})
+		Expect(err).ToNot(HaveOccurred())
+		server := ln.(*baseServer)
+		Expect(server.config.Certificates).To(Equal(set))
+		Expect(server.tlsConf.Certificates).To(BeEmpty())
+		Expect(server.tlsConf.GetCertificate).ToNot(BeNil())
+		Expect(server.tlsConf.NextProtos).To(Equal([]string{"proto1"}))
+		// the tls.Config passed to Listen is not modified
+		Expect(tlsConf.Certificates).ToNot(BeEmpty())
 		// stop the listener
 		Expect(ln.Close()).To(Succeed())
 	})
@@ -239,6 +256,29 @@ var _ = Describe("Server", func() {
 				time.Sleep(50 * time.Millisecond)
 			})
 
+			It("passes dropped packets to the InspectLongHeaderPacket callback", func() {
+				infoChan := make(chan *LongHeaderPacketInfo, 1)
+				serv.config.InspectLongHeaderPacket = func(info *LongHeaderPacketInfo) { infoChan <- info }
+				p := getPacket(&wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: protocol.ConnectionID{1, 2, 3, 4},
+					SrcConnectionID:  protocol.ConnectionID{5, 6, 7, 8},
+					Version:          serv.config.Versions[0],
+				}, []byte("invalid"))
+				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketTypeHandshake, p.Size(), logging.PacketDropUnexpectedPacket)
+				serv.handlePacket(p)
+				var info *LongHeaderPacketInfo
+				Eventually(infoChan).Should(Receive(&info))
+				Expect(info.RemoteAddr).To(Equal(p.remoteAddr))
+				Expect(info.Type).To(Equal(logging.PacketTypeHandshake))
+				Expect(info.Version).To(Equal(serv.config.Versions[0]))
+				Expect(info.DestConnectionID).To(Equal([]byte{1, 2, 3, 4}))
+				Expect(info.SrcConnectionID).To(Equal([]byte{5, 6, 7, 8}))
+				Expect(info.Size).To(BeEquivalentTo(p.Size()))
+				Expect(info.Decrypted).To(BeFalse())
+			})
+
 			It("decodes the token from the Token field", func() {
 				raddr := &net.UDPAddr{
 					IP:   net.IPv4(192, 168, 13, 37),
@@ -447,6 +487,30 @@ var _ = Describe("Server", func() {
 				time.Sleep(scaleDuration(20 * time.Millisecond))
 			})
 
+			It("passes Version Negotiation packets to the InspectLongHeaderPacket callback", func() {
+				infoChan := make(chan *LongHeaderPacketInfo, 1)
+				serv.config.InspectLongHeaderPacket = func(info *LongHeaderPacketInfo) { infoChan <- info }
+				data, err := wire.ComposeVersionNegotiation(
+					protocol.ConnectionID{1, 2, 3, 4},
+					protocol.ConnectionID{4, 3, 2, 1},
+					[]protocol.VersionNumber{1, 2, 3},
+				)
+				Expect(err).ToNot(HaveOccurred())
+				raddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}
+				tracer.EXPECT().DroppedPacket(raddr, logging.PacketTypeVersionNegotiation, protocol.ByteCount(len(data)), logging.PacketDropUnexpectedPacket)
+				serv.handlePacket(&receivedPacket{
+					remoteAddr: raddr,
+					data:       data,
+					buffer:     getPacketBuffer(),
+				})
+				var info *LongHeaderPacketInfo
+				Eventually(infoChan).Should(Receive(&info))
+				Expect(info.Type).To(Equal(logging.PacketTypeVersionNegotiation))
+				Expect(info.DestConnectionID).To(Equal([]byte{1, 2, 3, 4}))
+				Expect(info.SrcConnectionID).To(Equal([]byte{4, 3, 2, 1}))
+				Expect(info.SupportedVersions).To(ContainElements(protocol.VersionNumber(1), protocol.VersionNumber(2), protocol.VersionNumber(3)))
+			})
+
 			It("doesn't send a Version Negotiation Packet for unsupported versions, if the packet is too small", func() {
 				srcConnID := protocol.ConnectionID{1, 2, 3, 4, 5}
 				destConnID := protocol.ConnectionID{1, 2, 3, 4, 5, 6}
@@ -541,7 +605,7 @@ var _ = Describe("Server", func() {
 					Expect(err).ToNot(HaveOccurred())
 					data, err := opener.Open(nil, b[extHdr.ParsedLen():], extHdr.PacketNumber, b[:extHdr.ParsedLen()])
 					Expect(err).ToNot(HaveOccurred())
-					f, err := wire.NewFrameParser(false, hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
+					f, err := wire.NewFrameParser(false, false, false, hdr.Version).ParseNext(bytes.NewReader(data), protocol.EncryptionInitial)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(f).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
 					ccf := f.(*wire.ConnectionCloseFrame)
@@ -713,6 +777,28 @@ var _ = Describe("Server", func() {
 				Consistently(func() uint32 { return atomic.LoadUint32(&counter) }).Should(BeEquivalentTo(protocol.MaxServerUnprocessedPackets + 1))
 			})
 
+			It("evicts the oldest packets if the receive queue is full", func() {
+				// use a server that doesn't process any packets
+				s := &baseServer{
+					config: populateServerConfig(&Config{
+						Tracer:                           tracer,
+						UnprocessedPacketsEvictionPolicy: EvictOldest,
+					}),
+					receivedPackets: make(chan *receivedPacket, 2),
+					logger:          utils.DefaultLogger,
+				}
+				p1 := getInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8})
+				p2 := getInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8})
+				p3 := getInitial(protocol.ConnectionID{1, 2, 3, 4, 5, 6, 7, 8})
+				s.handlePacket(p1)
+				s.handlePacket(p2)
+				tracer.EXPECT().DroppedPacket(p1.remoteAddr, logging.PacketTypeNotDetermined, p1.Size(), logging.PacketDropDOSPrevention)
+				s.handlePacket(p3)
+				Expect(s.receivedPackets).To(Receive(BeIdenticalTo(p2)))
+				Expect(s.receivedPackets).To(Receive(BeIdenticalTo(p3)))
+				Expect(s.receivedPackets).ToNot(Receive())
+			})
+
 			It("only creates a single session for a duplicate Initial", func() {
 				serv.config.AcceptToken = func(_ net.Addr, _ *Token) bool { return true }
 				var createdSession bool
@@ -1179,6 +1265,18 @@ var _ = Describe("default source address verification", func() {
 		Expect(defaultAcceptToken(remoteAddr, token)).To(BeTrue())
 	})
 
+	It("accepts a token bound to a network prefix, if the address lies within the prefix", func() {
+		token := &Token{
+			RemoteAddr: "192.168.0.0/24",
+			SentTime:   time.Now(),
+		}
+		Expect(defaultAcceptToken(&net.UDPAddr{IP: net.IPv4(192, 168, 0, 42)}, token)).To(BeTrue())
+		Expect(defaultAcceptToken(&net.UDPAddr{IP: net.IPv4(192, 168, 1, 1)}, token)).To(BeFalse())
+		token.RemoteAddr = "2001:db8:1234:5600::/56"
+		Expect(defaultAcceptToken(&net.UDPAddr{IP: net.ParseIP("2001:db8:1234:56ff::1")}, token)).To(BeTrue())
+		Expect(defaultAcceptToken(&net.UDPAddr{IP: net.ParseIP("2001:db8:1234:5700::1")}, token)).To(BeFalse())
+	})
+
 	It("requests verification if no token is provided", func() {
 		remoteAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1)}
 		Expect(defaultAcceptToken(remoteAddr, nil)).To(BeFalse())
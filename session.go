@@ -3,12 +3,14 @@ package quic
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"reflect"
+	"runtime/debug"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,6 +24,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/quicvarint"
 )
 
 type unpacker interface {
@@ -45,6 +48,8 @@ type streamManager interface {
 	DeleteStream(protocol.StreamID) error
 	UpdateLimits(*wire.TransportParameters)
 	HandleMaxStreamsFrame(*wire.MaxStreamsFrame)
+	SetMaxIncomingStreams(uint64)
+	SetMaxIncomingUniStreams(uint64)
 	CloseWithError(error)
 	ResetFor0RTT()
 	UseResetMaps()
@@ -58,6 +63,7 @@ type cryptoStreamHandler interface {
 	GetSessionTicket() ([]byte, error)
 	io.Closer
 	ConnectionState() handshake.ConnectionState
+	TicketAge() time.Duration
 }
 
 type packetInfo struct {
@@ -75,18 +81,22 @@ type receivedPacket struct {
 	ecn protocol.ECN
 
 	info *packetInfo
+
+	// faultsInjected is set once the faultInjector was consulted for this packet
+	faultsInjected bool
 }
 
 func (p *receivedPacket) Size() protocol.ByteCount { return protocol.ByteCount(len(p.data)) }
 
 func (p *receivedPacket) Clone() *receivedPacket {
 	return &receivedPacket{
-		remoteAddr: p.remoteAddr,
-		rcvTime:    p.rcvTime,
-		data:       p.data,
-		buffer:     p.buffer,
-		ecn:        p.ecn,
-		info:       p.info,
+		remoteAddr:     p.remoteAddr,
+		rcvTime:        p.rcvTime,
+		data:           p.data,
+		buffer:         p.buffer,
+		ecn:            p.ecn,
+		info:           p.info,
+		faultsInjected: p.faultsInjected,
 	}
 }
 
@@ -101,16 +111,22 @@ type sessionRunner interface {
 }
 
 type handshakeRunner struct {
-	onReceivedParams    func(*wire.TransportParameters)
-	onError             func(error)
-	dropKeys            func(protocol.EncryptionLevel)
-	onHandshakeComplete func()
+	onReceivedParams      func(*wire.TransportParameters)
+	onError               func(error)
+	onKeysAvailable       func(protocol.EncryptionLevel, bool)
+	dropKeys              func(protocol.EncryptionLevel)
+	onHandshakeComplete   func()
+	onSessionTicketStored func()
 }
 
 func (r *handshakeRunner) OnReceivedParams(tp *wire.TransportParameters) { r.onReceivedParams(tp) }
 func (r *handshakeRunner) OnError(e error)                               { r.onError(e) }
 func (r *handshakeRunner) DropKeys(el protocol.EncryptionLevel)          { r.dropKeys(el) }
 func (r *handshakeRunner) OnHandshakeComplete()                          { r.onHandshakeComplete() }
+func (r *handshakeRunner) OnSessionTicketStored()                        { r.onSessionTicketStored() }
+func (r *handshakeRunner) OnKeysAvailable(el protocol.EncryptionLevel, forSending bool) {
+	r.onKeysAvailable(el, forSending)
+}
 
 type closeError struct {
 	err       error
@@ -118,6 +134,67 @@ type closeError struct {
 	immediate bool
 }
 
+// peerAddrValidation is the validation of a new peer address
+type peerAddrValidation struct {
+	oldAddr, newAddr net.Addr
+	start, deadline  time.Time
+
+	// A new PATH_CHALLENGE is sent every time the previous one is declared lost.
+	// A PATH_RESPONSE to any of them validates the address.
+	challenges    [][8]byte
+	sendChallenge bool // true if the last challenge still needs to be sent
+
+	// Until the new address is validated, the server sends at most 3 times the number of bytes it received from it,
+	// see section 9.3 of RFC 9000.
+	bytesReceived, bytesSent protocol.ByteCount
+}
+
+func (v *peerAddrValidation) isAmplificationLimited() bool {
+	return v != nil && v.bytesSent >= 3*v.bytesReceived
+}
+
+func (v *peerAddrValidation) addChallenge() error {
+	var challenge [8]byte
+	if _, err := rand.Read(challenge[:]); err != nil {
+		return err
+	}
+	v.challenges = append(v.challenges, challenge)
+	v.sendChallenge = true
+	return nil
+}
+
+func (v *peerAddrValidation) isChallenge(data [8]byte) bool {
+	for _, c := range v.challenges {
+		if c == data {
+			return true
+		}
+	}
+	return false
+}
+
+// isPortOnlyChange says if only the port differs between the two addresses.
+func isPortOnlyChange(oldAddr, newAddr net.Addr) bool {
+	oldUDPAddr, ok := oldAddr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	newUDPAddr, ok := newAddr.(*net.UDPAddr)
+	if !ok {
+		return false
+	}
+	return oldUDPAddr.IP.Equal(newUDPAddr.IP)
+}
+
+// isProbingFrame says if a frame is a probing frame, see section 9.1 of RFC 9000.
+// Packets that only contain probing frames don't cause a switch to a new peer address.
+func isProbingFrame(f wire.Frame) bool {
+	switch f.(type) {
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame, *wire.NewConnectionIDFrame:
+		return true
+	}
+	return false
+}
+
 type errCloseForRecreating struct {
 	nextPacketNumber protocol.PacketNumber
 	nextVersion      protocol.VersionNumber
@@ -161,6 +238,8 @@ type session struct {
 	framer                framer
 	windowUpdateQueue     *windowUpdateQueue
 	connFlowController    flowcontrol.ConnectionFlowController
+	memoryAccount         *memoryAccount            // nil if no MemoryBudget is configured
+	memoryUsage           int64                     // accessed atomically, the memory usage last sampled on the run loop
 	tokenStoreKey         string                    // only set for the client
 	tokenGenerator        *handshake.TokenGenerator // only set for the server
 
@@ -174,6 +253,8 @@ type session struct {
 
 	receivedPackets  chan *receivedPacket
 	sendingScheduled chan struct{}
+	// inFlightPacketsRequests is only set if Config.EnablePacketHistorySnapshots is set
+	inFlightPacketsRequests chan chan<- []InFlightPacket
 
 	closeOnce sync.Once
 	// closeChan is used to notify the run loop that it should terminate
@@ -181,11 +262,20 @@ type session struct {
 
 	ctx                context.Context
 	ctxCancel          context.CancelFunc
+	closeCause         *closeCause
 	handshakeCtx       context.Context
 	handshakeCtxCancel context.CancelFunc
 
 	undecryptablePackets          []*receivedPacket // undecryptable packets, waiting for a change in encryption level
+	undecryptableBytes            protocol.ByteCount
 	undecryptablePacketsToProcess []*receivedPacket
+	// packets delayed or duplicated by the faultInjector
+	delayedPackets []delayedPacket
+
+	// callbacks of streams that became writable while packing the last packets
+	streamWritableCallbacks []func()
+	streamGroups            *streamGroupRegistry
+	streamErrors            *StreamErrorRegistry
 
 	clientHelloWritten    <-chan *wire.TransportParameters
 	earlySessionReadyChan chan struct{}
@@ -207,14 +297,44 @@ type session struct {
 	pacingDeadline time.Time
 
 	peerParams *wire.TransportParameters
+	// the transport parameters restored for 0-RTT (client only), nil if 0-RTT wasn't used
+	zeroRTTParams *wire.TransportParameters
 
 	timer *utils.Timer
 	// keepAlivePingSent stores whether a keep alive PING is in flight.
 	// It is reset as soon as we receive a packet from the peer.
 	keepAlivePingSent bool
 	keepAliveInterval time.Duration
-
-	datagramQueue *datagramQueue
+	// undecryptableShortHeaderPackets is the number of consecutive 1-RTT packets that couldn't be decrypted.
+	undecryptableShortHeaderPackets int
+	// duplicatePackets is the number of duplicate packets received.
+	duplicatePackets int
+
+	datagramQueue          *datagramQueue
+	maxDatagramPayloadSize int64 // to be accessed atomically
+	effectiveIdleTimeout   int64 // to be accessed atomically
+
+	peerSupportsResetStreamAt int32 // to be accessed atomically, 1 if the peer supports RESET_STREAM_AT frames
+	flushRequested            int32 // to be accessed atomically, 1 if Flush was called on a stream
+	// coalescingStart is the time when sending of a small amount of stream data was first delayed, see Config.CoalescingDelay
+	coalescingStart time.Time
+
+	// largestRcvdAppDataPacketNumber is used to detect if a packet from a new peer address was reordered
+	largestRcvdAppDataPacketNumber protocol.PacketNumber
+	// peerAddrValidation is the ongoing validation of a new peer address, nil if no validation is in progress
+	peerAddrValidation *peerAddrValidation
+	sentPathChallenge  bool
+
+	// The processing times are measured on the run loop,
+	// and added to the stats once per iteration of the run loop (see addProcessingTimes).
+	receiveProcessingTime time.Duration
+	sendProcessingTime    time.Duration
+
+	statsMutex sync.Mutex
+	stats      ConnectionStats
+	// usedRetry and handshakeDuration are protected by the statsMutex
+	usedRetry         bool
+	handshakeDuration time.Duration // set when the handshake completes
 
 	logID  string
 	tracer logging.ConnectionTracer
@@ -228,6 +348,10 @@ var (
 	deadlineSendImmediately              = time.Time{}.Add(42 * time.Millisecond) // any value > time.Time{} and before time.Now() is fine
 )
 
+// If the peer's max_idle_timeout reduces the idle timeout by more than this factor, we log a message,
+// since applications often assume that their own Config.MaxIdleTimeout applies.
+const idleTimeoutReductionWarningFactor = 4
+
 var newSession = func(
 	conn sendConn,
 	runner sessionRunner,
@@ -252,9 +376,10 @@ var newSession = func(
 		handshakeDestConnID:   destConnID,
 		srcConnIDLen:          srcConnID.Len(),
 		tokenGenerator:        tokenGenerator,
-		oneRTTStream:          newCryptoStream(),
+		oneRTTStream:          newCryptoStream(protocol.ByteCount(conf.MaxOneRTTCryptoData), conf.MaxCryptoFrameFragments),
 		perspective:           protocol.PerspectiveServer,
 		handshakeCompleteChan: make(chan struct{}),
+		usedRetry:             retrySrcConnID != nil,
 		tracer:                tracer,
 		logger:                logger,
 		version:               v,
@@ -266,6 +391,7 @@ var newSession = func(
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
+		s.config.ConnectionIDRotation,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		s.queueControlFrame,
@@ -273,6 +399,7 @@ var newSession = func(
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		clientDestConnID,
+		s.config.newConnectionID,
 		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
 		runner.GetStatelessResetToken,
 		runner.Remove,
@@ -282,18 +409,21 @@ var newSession = func(
 		s.version,
 	)
 	s.preSetup()
-	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), SessionTracingKey, tracingID))
+	s.initContext(tracingID)
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		0,
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		s.perspective,
+		s.config.PersistentCongestionThreshold,
+		s.config.AdaptiveReorderingThreshold,
+		s.ackRangeFilter(),
 		s.tracer,
 		s.logger,
 		s.version,
 	)
-	initialStream := newCryptoStream()
-	handshakeStream := newCryptoStream()
+	initialStream := newCryptoStream(protocol.ByteCount(s.config.MaxInitialCryptoData), s.config.MaxCryptoFrameFragments)
+	handshakeStream := newHandshakeCryptoStream(protocol.ByteCount(s.config.MaxHandshakeCryptoData), s.config.MaxCryptoFrameFragments, s.config.MaxCertificateChainSize)
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiLocal:   protocol.ByteCount(s.config.InitialStreamReceiveWindow),
 		InitialMaxStreamDataBidiRemote:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
@@ -314,6 +444,8 @@ var newSession = func(
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
+	params.SupportsBDPFrames = s.config.EnableBDPFrames
+	params.SupportsResetStreamAt = s.config.EnableResetStreamAt
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -327,6 +459,7 @@ var newSession = func(
 		&handshakeRunner{
 			onReceivedParams: s.handleTransportParameters,
 			onError:          s.closeLocal,
+			onKeysAvailable:  s.onKeysAvailable,
 			dropKeys:         s.dropEncryptionLevel,
 			onHandshakeComplete: func() {
 				runner.Retire(clientDestConnID)
@@ -357,7 +490,7 @@ var newSession = func(
 		s.version,
 	)
 	s.unpacker = newPacketUnpacker(cs, s.version)
-	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, s.oneRTTStream)
+	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, s.oneRTTStream, s.tracer)
 	return s
 }
 
@@ -393,6 +526,7 @@ var newClientSession = func(
 	}
 	s.connIDManager = newConnIDManager(
 		destConnID,
+		s.config.ConnectionIDRotation,
 		func(token protocol.StatelessResetToken) { runner.AddResetToken(token, s) },
 		runner.RemoveResetToken,
 		s.queueControlFrame,
@@ -400,6 +534,7 @@ var newClientSession = func(
 	s.connIDGenerator = newConnIDGenerator(
 		srcConnID,
 		nil,
+		s.config.newConnectionID,
 		func(connID protocol.ConnectionID) { runner.Add(connID, s) },
 		runner.GetStatelessResetToken,
 		runner.Remove,
@@ -409,18 +544,21 @@ var newClientSession = func(
 		s.version,
 	)
 	s.preSetup()
-	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(context.Background(), SessionTracingKey, tracingID))
+	s.initContext(tracingID)
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		initialPacketNumber,
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		s.perspective,
+		s.config.PersistentCongestionThreshold,
+		s.config.AdaptiveReorderingThreshold,
+		s.ackRangeFilter(),
 		s.tracer,
 		s.logger,
 		s.version,
 	)
-	initialStream := newCryptoStream()
-	handshakeStream := newCryptoStream()
+	initialStream := newCryptoStream(protocol.ByteCount(s.config.MaxInitialCryptoData), s.config.MaxCryptoFrameFragments)
+	handshakeStream := newHandshakeCryptoStream(protocol.ByteCount(s.config.MaxHandshakeCryptoData), s.config.MaxCryptoFrameFragments, s.config.MaxCertificateChainSize)
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiRemote: protocol.ByteCount(s.config.InitialStreamReceiveWindow),
 		InitialMaxStreamDataBidiLocal:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
@@ -438,6 +576,8 @@ var newClientSession = func(
 	if s.config.EnableDatagrams {
 		params.MaxDatagramFrameSize = protocol.MaxDatagramFrameSize
 	}
+	params.SupportsBDPFrames = s.config.EnableBDPFrames
+	params.SupportsResetStreamAt = s.config.EnableResetStreamAt
 	if s.tracer != nil {
 		s.tracer.SentTransportParameters(params)
 	}
@@ -451,8 +591,14 @@ var newClientSession = func(
 		&handshakeRunner{
 			onReceivedParams:    s.handleTransportParameters,
 			onError:             s.closeLocal,
+			onKeysAvailable:     s.onKeysAvailable,
 			dropKeys:            s.dropEncryptionLevel,
 			onHandshakeComplete: func() { close(s.handshakeCompleteChan) },
+			onSessionTicketStored: func() {
+				if s.config.SessionTicketStored != nil {
+					s.config.SessionTicketStored(s)
+				}
+			},
 		},
 		tlsConf,
 		enable0RTT,
@@ -463,7 +609,21 @@ var newClientSession = func(
 	)
 	s.clientHelloWritten = clientHelloWritten
 	s.cryptoStreamHandler = cs
-	s.cryptoStreamManager = newCryptoStreamManager(cs, initialStream, handshakeStream, newCryptoStream())
+	s.cryptoStreamManager = newCryptoStreamManager(
+		&sessionTicketFilter{
+			cryptoDataHandler: cs,
+			discard:           s.config.DiscardSessionTickets,
+			onSessionTicket: func() {
+				s.statsMutex.Lock()
+				s.stats.SessionTicketsReceived++
+				s.statsMutex.Unlock()
+			},
+		},
+		initialStream,
+		handshakeStream,
+		newCryptoStream(protocol.ByteCount(s.config.MaxOneRTTCryptoData), s.config.MaxCryptoFrameFragments),
+		s.tracer,
+	)
 	s.unpacker = newPacketUnpacker(cs, s.version)
 	s.packer = newPacketPacker(
 		srcConnID,
@@ -495,29 +655,55 @@ var newClientSession = func(
 
 func (s *session) preSetup() {
 	s.sendQueue = newSendQueue(s.conn)
+	s.streamGroups = newStreamGroupRegistry()
+	s.streamErrors = newStreamErrorRegistry()
 	s.retransmissionQueue = newRetransmissionQueue(s.version)
-	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.version)
+	s.frameParser = wire.NewFrameParser(s.config.EnableDatagrams, s.config.EnableBDPFrames, s.config.EnableResetStreamAt, s.version)
 	s.rttStats = &utils.RTTStats{}
 	s.connFlowController = flowcontrol.NewConnectionFlowController(
 		protocol.ByteCount(s.config.InitialConnectionReceiveWindow),
 		protocol.ByteCount(s.config.MaxConnectionReceiveWindow),
 		s.onHasConnectionWindowUpdate,
+		s.windowUpdateFunc(true, 0),
 		s.rttStats,
 		s.logger,
 	)
+	if s.config.MemoryBudget != nil {
+		s.memoryAccount = s.config.MemoryBudget.newAccount(
+			s.onHasConnectionWindowUpdate,
+			func() {
+				s.closeLocal(&qerr.TransportError{
+					ErrorCode:    qerr.InternalError,
+					ErrorMessage: "memory budget exceeded",
+				})
+			},
+		)
+	}
 	s.earlySessionReadyChan = make(chan struct{})
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
+		s.config.StreamIdleTimeout,
+		s.config.StreamIdleTimeoutErrorCode,
+		s.newStreamAdmission(),
+		newReassemblyMemory(s.config.MaxStreamReassemblyMemory, s.config.MaxConnectionReassemblyMemory, s.config.ReassemblyMemoryErrorCode),
+		s.streamErrors,
 		s.perspective,
 		s.version,
 	)
-	s.framer = newFramer(s.streamsMap, s.version)
+	var onSentStreamFrame func(*wire.StreamFrame)
+	if s.config.StreamTap != nil {
+		onSentStreamFrame = func(f *wire.StreamFrame) { s.tapStreamFrame(StreamDataSent, f) }
+	}
+	s.framer = newFramer(s.streamsMap, s.config.StreamScheduler, s.version, onSentStreamFrame)
 	s.receivedPackets = make(chan *receivedPacket, protocol.MaxSessionUnprocessedPackets)
 	s.closeChan = make(chan closeError, 1)
 	s.sendingScheduled = make(chan struct{}, 1)
+	if s.config.EnablePacketHistorySnapshots {
+		s.inFlightPacketsRequests = make(chan chan<- []InFlightPacket)
+	}
 	s.handshakeCtx, s.handshakeCtxCancel = context.WithCancel(context.Background())
 
 	now := time.Now()
@@ -531,21 +717,39 @@ func (s *session) preSetup() {
 }
 
 // run the session main loop
-func (s *session) run() error {
+func (s *session) run() (err error) {
 	defer s.ctxCancel()
+	if s.config.PanicHandler != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				err = s.closeAfterPanic(s.handlePanic(r))
+			}
+		}()
+	}
 
 	s.timer = utils.NewTimer()
 
-	go s.cryptoStreamHandler.RunHandshake()
 	go func() {
+		if s.config.PanicHandler != nil {
+			defer s.recoverPanic()
+		}
+		s.cryptoStreamHandler.RunHandshake()
+	}()
+	go func() {
+		if s.config.PanicHandler != nil {
+			defer s.recoverPanic()
+		}
 		if err := s.sendQueue.Run(); err != nil {
 			s.destroyImpl(err)
 		}
 	}()
 
 	if s.perspective == protocol.PerspectiveClient {
+		start := time.Now()
 		select {
 		case zeroRTTParams := <-s.clientHelloWritten:
+			// Account for the time TLS spent on creating the ClientHello.
+			s.measureProcessingTime(&s.sendProcessingTime, start)
 			s.scheduleSending()
 			if zeroRTTParams != nil {
 				s.restoreTransportParameters(zeroRTTParams)
@@ -564,12 +768,17 @@ func (s *session) run() error {
 
 runLoop:
 	for {
+		s.addProcessingTimes()
+
 		// Close immediately if requested
 		select {
 		case closeErr = <-s.closeChan:
 			break runLoop
 		case <-s.handshakeCompleteChan:
+			// For the server, this includes the time TLS spends on creating the session tickets.
+			start := time.Now()
 			s.handleHandshakeComplete()
+			s.measureProcessingTime(&s.receiveProcessingTime, start)
 		default:
 		}
 
@@ -577,6 +786,7 @@ runLoop:
 
 		var processedUndecryptablePacket bool
 		if len(s.undecryptablePacketsToProcess) > 0 {
+			start := time.Now()
 			queue := s.undecryptablePacketsToProcess
 			s.undecryptablePacketsToProcess = nil
 			for _, p := range queue {
@@ -590,6 +800,7 @@ runLoop:
 				default:
 				}
 			}
+			s.measureProcessingTime(&s.receiveProcessingTime, start)
 		} else if !processedUndecryptablePacket {
 			select {
 			case closeErr = <-s.closeChan:
@@ -602,7 +813,11 @@ runLoop:
 				// We do all the interesting stuff after the switch statement, so
 				// nothing to see here.
 			case <-sendQueueAvailable:
+			case reply := <-s.inFlightPacketsRequests:
+				reply <- newInFlightPackets(s.sentPacketHandler.InFlightPackets())
+				continue
 			case firstPacket := <-s.receivedPackets:
+				start := time.Now()
 				wasProcessed := s.handlePacketImpl(firstPacket)
 				// Don't set timers and send packets if the packet made us close the session.
 				select {
@@ -632,6 +847,7 @@ runLoop:
 						}
 					}
 				}
+				s.measureProcessingTime(&s.receiveProcessingTime, start)
 				// Only reset the timers if this packet was actually processed.
 				// This avoids modifying any state when handling undecryptable packets,
 				// which could be injected by an attacker.
@@ -639,11 +855,16 @@ runLoop:
 					continue
 				}
 			case <-s.handshakeCompleteChan:
+				start := time.Now()
 				s.handleHandshakeComplete()
+				s.measureProcessingTime(&s.receiveProcessingTime, start)
 			}
 		}
 
+		s.updateMemoryUsage()
+
 		now := time.Now()
+		s.handleDelayedPackets(now)
 		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
@@ -651,6 +872,7 @@ runLoop:
 				s.closeLocal(err)
 			}
 		}
+		s.maybeAbandonPeerAddressValidation(now)
 
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
 			// send a PING frame since there is no activity in the session
@@ -675,9 +897,12 @@ runLoop:
 			sendQueueAvailable = s.sendQueue.Available()
 			continue
 		}
+		start := time.Now()
 		if err := s.sendPackets(); err != nil {
 			s.closeLocal(err)
 		}
+		s.measureProcessingTime(&s.sendProcessingTime, start)
+		s.notifyWritableStreams()
 		if s.sendQueue.WouldBlock() {
 			sendQueueAvailable = s.sendQueue.Available()
 		} else {
@@ -685,17 +910,65 @@ runLoop:
 		}
 	}
 
-	s.handleCloseError(&closeErr)
+	s.addProcessingTimes()
+	return s.closeRunLoop(closeErr)
+}
+
+func (s *session) closeRunLoop(closeErr closeError) error {
+	s.handleCloseErrorRecovering(&closeErr)
+	if s.memoryAccount != nil {
+		s.memoryAccount.Close()
+	}
 	if e := (&errCloseForRecreating{}); !errors.As(closeErr.err, &e) && s.tracer != nil {
 		s.tracer.Close()
 	}
 	s.logger.Infof("Connection %s closed.", s.logID)
 	s.cryptoStreamHandler.Close()
 	s.sendQueue.Close()
+	s.discardDelayedPackets()
 	s.timer.Stop()
 	return closeErr.err
 }
 
+// handleCloseErrorRecovering handles the close error.
+// After a panic, the session's state might be inconsistent, so sending the CONNECTION_CLOSE might panic as well.
+// If a PanicHandler is set, such a panic is passed to the PanicHandler, and the session is closed nevertheless.
+func (s *session) handleCloseErrorRecovering(closeErr *closeError) {
+	if s.config.PanicHandler != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				s.handlePanic(r)
+				// We might not have replaced the session with a closed session.
+				s.connIDGenerator.RemoveAll()
+			}
+		}()
+	}
+	s.handleCloseError(closeErr)
+}
+
+// handlePanic passes a value recovered from a panic to the PanicHandler.
+// It returns the error that the session is closed with.
+func (s *session) handlePanic(r interface{}) error {
+	s.logger.Errorf("Recovered from panic: %v", r)
+	s.config.PanicHandler(s, r, debug.Stack())
+	return &qerr.TransportError{ErrorCode: qerr.InternalError}
+}
+
+// recoverPanic recovers a panic in one of the goroutines started by the run loop.
+// It must be deferred.
+func (s *session) recoverPanic() {
+	if r := recover(); r != nil {
+		s.closeLocal(s.handlePanic(r))
+	}
+}
+
+// closeAfterPanic closes the session after the run loop panicked.
+func (s *session) closeAfterPanic(e error) error {
+	// the run loop won't read from the closeChan any more
+	s.closeOnce.Do(func() {})
+	return s.closeRunLoop(closeError{err: e})
+}
+
 // blocks until the early session can be used
 func (s *session) earlySessionReady() <-chan struct{} {
 	return s.earlySessionReadyChan
@@ -709,15 +982,223 @@ func (s *session) Context() context.Context {
 	return s.ctx
 }
 
+type closeCauseCtxKey struct{}
+
+// closeCause records why a session was closed.
+// It is stored on the session's context, such that it can be retrieved from all contexts derived from it.
+type closeCause struct {
+	done <-chan struct{} // the Done channel of the session's context
+	err  error           // only written before done is closed
+}
+
+func (s *session) initContext(tracingID uint64) {
+	s.closeCause = &closeCause{}
+	ctx := context.WithValue(context.Background(), SessionTracingKey, tracingID)
+	s.ctx, s.ctxCancel = context.WithCancel(context.WithValue(ctx, closeCauseCtxKey{}, s.closeCause))
+	s.closeCause.done = s.ctx.Done()
+}
+
+// SessionCloseCause returns the reason why a session was closed,
+// if ctx is the context returned by Session.Context (or a context derived from it),
+// and that context was canceled because the session was closed.
+// This is the same error that is returned by all pending and future calls on the session and its streams.
+// In all other cases, it returns ctx.Err().
+func SessionCloseCause(ctx context.Context) error {
+	if c, ok := ctx.Value(closeCauseCtxKey{}).(*closeCause); ok {
+		select {
+		case <-c.done:
+			return c.err
+		default:
+		}
+	}
+	return ctx.Err()
+}
+
 func (s *session) supportsDatagrams() bool {
 	return s.peerParams.MaxDatagramFrameSize != protocol.InvalidByteCount
 }
 
+// updateMaxDatagramPayloadSize calculates the maximum size of a message that can be sent in a single packet,
+// and calls the DatagramPayloadSizeChanged callback if it changed.
+// It must be called whenever the packet size, the peer's transport parameters or the connection ID change.
+func (s *session) updateMaxDatagramPayloadSize() {
+	if !s.config.EnableDatagrams || s.peerParams == nil || !s.supportsDatagrams() {
+		return
+	}
+	// short header: first byte, connection ID, packet number (at most 4 bytes), and the AEAD tag
+	overhead := 1 + protocol.ByteCount(s.connIDManager.ActiveConnectionIDLen()) + 4 + 16
+	f := &wire.DatagramFrame{DataLenPresent: true}
+	var size protocol.ByteCount
+	if maxPacketSize := s.packer.MaxPacketSize(); maxPacketSize > overhead {
+		size = f.MaxDataLen(maxPacketSize-overhead, s.version)
+	}
+	size = utils.MinByteCount(size, f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version))
+	if old := atomic.SwapInt64(&s.maxDatagramPayloadSize, int64(size)); old != int64(size) && s.config.DatagramPayloadSizeChanged != nil {
+		s.config.DatagramPayloadSizeChanged(s, int(size))
+	}
+}
+
+func (s *session) countSentPacket(p *packetContents) {
+	s.statsMutex.Lock()
+	s.stats.PacketsSent++
+	if !p.IsAckEliciting() && p.ack != nil {
+		s.stats.AckOnlyPacketsSent++
+	}
+	s.stats.BytesSent += uint64(p.length)
+	if p.ack != nil {
+		s.stats.AckFrameBytesSent += uint64(p.ack.Length(s.version))
+	}
+	for _, f := range p.frames {
+		s.countSentFrame(f.Frame)
+	}
+	s.statsMutex.Unlock()
+}
+
+// countSentFrame must be called with the statsMutex held.
+func (s *session) countSentFrame(f wire.Frame) {
+	switch frame := f.(type) {
+	case *wire.StreamFrame:
+		s.stats.PayloadBytesSent += uint64(frame.DataLen())
+	case *wire.DatagramFrame:
+		s.stats.PayloadBytesSent += uint64(len(frame.Data))
+	case *wire.MaxDataFrame, *wire.MaxStreamDataFrame, *wire.MaxStreamsFrame,
+		*wire.DataBlockedFrame, *wire.StreamDataBlockedFrame, *wire.StreamsBlockedFrame:
+		s.stats.FlowControlFrameBytesSent += uint64(f.Length(s.version))
+	case *wire.PingFrame:
+		s.stats.PingFrameBytesSent += uint64(f.Length(s.version))
+	case *wire.PathChallengeFrame, *wire.PathResponseFrame:
+		s.stats.PathValidationFrameBytesSent += uint64(f.Length(s.version))
+	default:
+		s.stats.OtherControlFrameBytesSent += uint64(f.Length(s.version))
+	}
+}
+
+func (s *session) countProbePacket(ping bool) {
+	s.statsMutex.Lock()
+	s.stats.ProbePacketsSent++
+	if ping {
+		s.stats.PingProbePacketsSent++
+	}
+	s.statsMutex.Unlock()
+}
+
+// measureProcessingTime adds the time passed since start to one of the processing time counters.
+// It must only be called from the run loop.
+func (s *session) measureProcessingTime(counter *time.Duration, start time.Time) {
+	*counter += time.Since(start)
+}
+
+// addProcessingTimes adds the processing times measured since the last call to the stats.
+func (s *session) addProcessingTimes() {
+	if s.receiveProcessingTime == 0 && s.sendProcessingTime == 0 {
+		return
+	}
+	s.statsMutex.Lock()
+	s.stats.ReceiveProcessingTime += s.receiveProcessingTime
+	s.stats.SendProcessingTime += s.sendProcessingTime
+	s.statsMutex.Unlock()
+	s.receiveProcessingTime = 0
+	s.sendProcessingTime = 0
+}
+
+func (s *session) ConnectionStats() ConnectionStats {
+	s.statsMutex.Lock()
+	stats := s.stats
+	s.statsMutex.Unlock()
+	stats.SpuriousLosses = s.sentPacketHandler.SpuriousLosses()
+	sentStats := s.sentPacketHandler.Stats()
+	stats.SmoothedRTT = sentStats.SmoothedRTT
+	stats.RTTVariance = sentStats.RTTVariance
+	stats.MinRTT = sentStats.MinRTT
+	stats.LatestRTT = sentStats.LatestRTT
+	stats.CongestionWindow = uint64(sentStats.CongestionWindow)
+	stats.BytesInFlight = uint64(sentStats.BytesInFlight)
+	for _, space := range []struct {
+		stats     *PacketNumberSpaceStats
+		sentStats ackhandler.PacketNumberSpaceStats
+	}{
+		{&stats.Initial, sentStats.Initial},
+		{&stats.Handshake, sentStats.Handshake},
+		{&stats.ApplicationData, sentStats.AppData},
+	} {
+		space.stats.PacketsSent = space.sentStats.PacketsSent
+		space.stats.PacketsAcked = space.sentStats.PacketsAcked
+		space.stats.PacketsLost = space.sentStats.PacketsLost
+		space.stats.BytesSent = space.sentStats.BytesSent
+		stats.PacketsAcked += space.sentStats.PacketsAcked
+		stats.PacketsLost += space.sentStats.PacketsLost
+	}
+	return stats
+}
+
+func (s *session) FlowControlState() FlowControlState {
+	return newFlowControlState(s.connFlowController.State())
+}
+
+func (s *session) PacingState() PacingState {
+	return newPacingState(s.sentPacketHandler.PacerState(), uint64(s.sendQueue.QueuedBytes()))
+}
+
+func (s *session) NewStreamGroup() *StreamGroup {
+	return newStreamGroup(s.streamGroups)
+}
+
+func (s *session) StreamErrors() *StreamErrorRegistry {
+	return s.streamErrors
+}
+
+func (s *session) ackRangeFilter() ackhandler.AckRangeFilter {
+	if s.config.AckRangeFilter == nil {
+		return nil
+	}
+	return func(received []wire.AckRange) []wire.AckRange {
+		return s.config.AckRangeFilter(s, received)
+	}
+}
+
+func (s *session) InFlightPackets(ctx context.Context) ([]InFlightPacket, error) {
+	if !s.config.EnablePacketHistorySnapshots {
+		return nil, errors.New("packet history snapshots not enabled")
+	}
+	reply := make(chan []InFlightPacket, 1)
+	select {
+	case s.inFlightPacketsRequests <- reply:
+	case <-s.ctx.Done():
+		return nil, SessionCloseCause(s.ctx)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return <-reply, nil
+}
+
 func (s *session) ConnectionState() ConnectionState {
-	return ConnectionState{
-		TLS:               s.cryptoStreamHandler.ConnectionState(),
-		SupportsDatagrams: s.supportsDatagrams(),
+	tlsState := s.cryptoStreamHandler.ConnectionState()
+	s.statsMutex.Lock()
+	usedRetry := s.usedRetry
+	handshakeDuration := s.handshakeDuration
+	s.statsMutex.Unlock()
+	state := ConnectionState{
+		TLS:                    tlsState,
+		SupportsDatagrams:      s.supportsDatagrams(),
+		MaxDatagramPayloadSize: int(atomic.LoadInt64(&s.maxDatagramPayloadSize)),
+		UsedRetry:              usedRetry,
+		Used0RTT:               tlsState.Used0RTT,
+		DidResume:              tlsState.DidResume,
+		HandshakeDuration:      handshakeDuration,
+		IdleTimeout:            time.Duration(atomic.LoadInt64(&s.effectiveIdleTimeout)),
 	}
+	if handshakeDuration > 0 {
+		if !state.Used0RTT {
+			state.HandshakeRTTs++
+		}
+		if usedRetry {
+			state.HandshakeRTTs++
+		}
+		if s.versionNegotiated {
+			state.HandshakeRTTs++
+		}
+	}
+	return state
 }
 
 // Time when the next keep-alive packet should be sent.
@@ -758,6 +1239,12 @@ func (s *session) maybeResetTimer() {
 	if !s.pacingDeadline.IsZero() {
 		deadline = utils.MinTime(deadline, s.pacingDeadline)
 	}
+	if s.peerAddrValidation != nil {
+		deadline = utils.MinTime(deadline, s.peerAddrValidation.deadline)
+	}
+	if delayedPacketTime := s.nextDelayedPacketTime(); !delayedPacketTime.IsZero() {
+		deadline = utils.MinTime(deadline, delayedPacketTime)
+	}
 
 	s.timer.Reset(deadline)
 }
@@ -772,29 +1259,53 @@ func (s *session) handleHandshakeComplete() {
 	defer s.handshakeCtxCancel()
 	// Once the handshake completes, we have derived 1-RTT keys.
 	// There's no point in queueing undecryptable packets for later decryption any more.
-	s.undecryptablePackets = nil
+	s.clearUndecryptablePackets()
 
 	s.connIDManager.SetHandshakeComplete()
 	s.connIDGenerator.SetHandshakeComplete()
 
+	s.statsMutex.Lock()
+	s.handshakeDuration = time.Since(s.sessionCreationTime)
+	s.statsMutex.Unlock()
+
 	if s.perspective == protocol.PerspectiveClient {
+		if err := s.check0RTTTransportParameters(); err != nil {
+			s.closeLocal(err)
+			return
+		}
 		s.applyTransportParameters()
 		return
 	}
 
 	s.handleHandshakeConfirmed()
 
-	ticket, err := s.cryptoStreamHandler.GetSessionTicket()
-	if err != nil {
-		s.closeLocal(err)
+	if s.config.SessionResumed != nil {
+		if state := s.cryptoStreamHandler.ConnectionState(); state.DidResume {
+			s.config.SessionResumed(s, ResumptionInfo{
+				TicketAge: s.cryptoStreamHandler.TicketAge(),
+				Used0RTT:  state.Used0RTT,
+			})
+		}
 	}
-	if ticket != nil {
-		s.oneRTTStream.Write(ticket)
-		for s.oneRTTStream.HasData() {
-			s.queueControlFrame(s.oneRTTStream.PopCryptoFrame(protocol.MaxPostHandshakeCryptoFrameSize))
+
+	for i := 0; i < s.config.NumSessionTickets; i++ {
+		ticket, err := s.cryptoStreamHandler.GetSessionTicket()
+		if err != nil {
+			s.closeLocal(err)
+			break
 		}
+		if ticket == nil { // session tickets are disabled
+			break
+		}
+		s.oneRTTStream.Write(ticket)
+		s.statsMutex.Lock()
+		s.stats.SessionTicketsSent++
+		s.statsMutex.Unlock()
 	}
-	token, err := s.tokenGenerator.NewToken(s.conn.RemoteAddr())
+	for s.oneRTTStream.HasData() {
+		s.queueControlFrame(s.oneRTTStream.PopCryptoFrame(protocol.MaxPostHandshakeCryptoFrameSize))
+	}
+	token, err := s.tokenGenerator.NewPrefixToken(s.conn.RemoteAddr(), s.config.TokenIPv4PrefixLength, s.config.TokenIPv6PrefixLength)
 	if err != nil {
 		s.closeLocal(err)
 	}
@@ -820,6 +1331,7 @@ func (s *session) handleHandshakeConfirmed() {
 			func(size protocol.ByteCount) {
 				s.sentPacketHandler.SetMaxDatagramSize(size)
 				s.packer.SetMaxPacketSize(size)
+				s.updateMaxDatagramPayloadSize()
 			},
 		)
 	}
@@ -836,6 +1348,8 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 	var counter uint8
 	var lastConnID protocol.ConnectionID
 	var processed bool
+	// Packets delayed or duplicated by the faultInjector (and packets queued for later decryption) were already inspected.
+	injectFaults := s.config.faultInjector != nil && !rp.faultsInjected
 	data := rp.data
 	p := rp
 	for len(data) > 0 {
@@ -884,6 +1398,14 @@ func (s *session) handlePacketImpl(rp *receivedPacket) bool {
 			s.logger.Debugf("Parsed a coalesced packet. Part %d: %d bytes. Remaining: %d bytes.", counter, len(packetData), len(rest))
 		}
 		p.data = packetData
+		if injectFaults {
+			p.faultsInjected = true
+			if !s.injectReceiveFaults(p, hdr) {
+				p.buffer.Decrement()
+				data = rest
+				continue
+			}
+		}
 		if wasProcessed := s.handleSinglePacket(p, hdr); wasProcessed {
 			processed = true
 		}
@@ -904,6 +1426,7 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 	}()
 
 	if hdr.Type == protocol.PacketTypeRetry {
+		s.inspectLongHeaderPacket(p, hdr, nil)
 		return s.handleRetryPacket(hdr, p.data)
 	}
 
@@ -914,6 +1437,7 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 			s.tracer.DroppedPacket(logging.PacketTypeInitial, p.Size(), logging.PacketDropUnknownConnectionID)
 		}
 		s.logger.Debugf("Dropping Initial packet (%d bytes) with unexpected source connection ID: %s (expected %s)", p.Size(), hdr.SrcConnectionID, s.handshakeDestConnID)
+		s.inspectLongHeaderPacket(p, hdr, nil)
 		return false
 	}
 	// drop 0-RTT packets, if we are a client
@@ -921,11 +1445,16 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(logging.PacketType0RTT, p.Size(), logging.PacketDropKeyUnavailable)
 		}
+		s.inspectLongHeaderPacket(p, hdr, nil)
 		return false
 	}
 
 	packet, err := s.unpacker.Unpack(hdr, p.rcvTime, p.data)
 	if err != nil {
+		// Packets that are queued are reported once they can be decrypted.
+		if err != handshake.ErrKeysNotYetAvailable {
+			s.inspectLongHeaderPacket(p, hdr, nil)
+		}
 		switch err {
 		case handshake.ErrKeysDropped:
 			if s.tracer != nil {
@@ -948,6 +1477,9 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 				s.tracer.DroppedPacket(logging.PacketTypeFromHeader(hdr), p.Size(), logging.PacketDropPayloadDecryptError)
 			}
 			s.logger.Debugf("Dropping %s packet (%d bytes) that could not be unpacked. Error: %s", hdr.PacketType(), p.Size(), err)
+			if !hdr.IsLongHeader {
+				s.handleUndecryptableShortHeaderPacket(p.data)
+			}
 		default:
 			var headerErr *headerParseError
 			if errors.As(err, &headerErr) {
@@ -965,6 +1497,11 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 		return false
 	}
 
+	if !hdr.IsLongHeader {
+		s.undecryptableShortHeaderPackets = 0
+	}
+	s.inspectLongHeaderPacket(p, hdr, packet)
+
 	if s.logger.Debug() {
 		s.logger.Debugf("<- Reading packet %d (%d bytes) for connection %s, %s", packet.packetNumber, p.Size(), hdr.DestConnectionID, packet.encryptionLevel)
 		packet.hdr.Log(s.logger)
@@ -972,19 +1509,63 @@ func (s *session) handleSinglePacket(p *receivedPacket, hdr *wire.Header) bool /
 
 	if s.receivedPacketHandler.IsPotentiallyDuplicate(packet.packetNumber, packet.encryptionLevel) {
 		s.logger.Debugf("Dropping (potentially) duplicate packet.")
+		s.duplicatePackets++
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(logging.PacketTypeFromHeader(hdr), p.Size(), logging.PacketDropDuplicate)
+			if s.duplicatePackets%protocol.DuplicatePacketAnomalyThreshold == 0 {
+				s.tracer.DetectedPeerAnomaly(logging.PeerAnomalyDuplicatePackets, fmt.Sprintf("received %d duplicate packets", s.duplicatePackets))
+			}
 		}
 		return false
 	}
 
-	if err := s.handleUnpackedPacket(packet, p.ecn, p.rcvTime, p.Size()); err != nil {
+	if err := s.handleUnpackedPacket(packet, p.ecn, p.rcvTime, p.remoteAddr, p.Size()); err != nil {
 		s.closeLocal(err)
 		return false
 	}
 	return true
 }
 
+// inspectLongHeaderPacket passes the header of a long header packet to the Config.InspectLongHeaderPacket callback.
+// The unpacked packet is nil if the packet wasn't decrypted.
+func (s *session) inspectLongHeaderPacket(p *receivedPacket, hdr *wire.Header, packet *unpackedPacket) {
+	if s.config.InspectLongHeaderPacket == nil || !hdr.IsLongHeader {
+		return
+	}
+	info := newLongHeaderPacketInfo(p, hdr)
+	if packet != nil {
+		info.Decrypted = true
+		info.PacketNumber = packet.packetNumber
+	}
+	s.config.InspectLongHeaderPacket(info)
+}
+
+// handleUndecryptableShortHeaderPacket counts consecutive 1-RTT packets that couldn't be decrypted.
+// A stateless reset looks like a short header packet that fails to decrypt.
+// If the peer uses a stateless reset token that we don't know (e.g. because its load balancer
+// is configured with a different StatelessResetKey), the stateless reset is not recognized,
+// and we would otherwise keep the connection open until it times out.
+// Once enough packets failed to decrypt, we send a PING to check if the peer is still alive.
+func (s *session) handleUndecryptableShortHeaderPacket(data []byte) {
+	if len(data) < 17 /* type byte + 16 bytes for the reset token */ {
+		return
+	}
+	s.undecryptableShortHeaderPackets++
+	if s.undecryptableShortHeaderPackets != protocol.StatelessResetSuspicionThreshold {
+		return
+	}
+	var token protocol.StatelessResetToken
+	copy(token[:], data[len(data)-16:])
+	s.logger.Debugf("Received %d undecryptable 1-RTT packets in a row. This might be a stateless reset with an unknown token (%#x). Sending a PING.", s.undecryptableShortHeaderPackets, token)
+	if s.tracer != nil {
+		s.tracer.SuspectedStatelessReset(token, s.undecryptableShortHeaderPackets)
+	}
+	s.statsMutex.Lock()
+	s.stats.SuspectedStatelessResets++
+	s.statsMutex.Unlock()
+	s.framer.QueueControlFrame(&wire.PingFrame{})
+}
+
 func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was this a valid Retry */ {
 	if s.perspective == protocol.PerspectiveServer {
 		if s.tracer != nil {
@@ -1034,6 +1615,9 @@ func (s *session) handleRetryPacket(hdr *wire.Header, data []byte) bool /* was t
 	}
 	newDestConnID := hdr.SrcConnectionID
 	s.receivedRetry = true
+	s.statsMutex.Lock()
+	s.usedRetry = true
+	s.statsMutex.Unlock()
 	if err := s.sentPacketHandler.ResetForRetry(); err != nil {
 		s.closeLocal(err)
 		return false
@@ -1064,6 +1648,11 @@ func (s *session) handleVersionNegotiationPacket(p *receivedPacket) {
 		s.logger.Debugf("Error parsing Version Negotiation packet: %s", err)
 		return
 	}
+	if s.config.InspectLongHeaderPacket != nil {
+		info := newLongHeaderPacketInfo(p, hdr)
+		info.SupportedVersions = supportedVersions
+		s.config.InspectLongHeaderPacket(info)
+	}
 
 	for _, v := range supportedVersions {
 		if v == s.version {
@@ -1105,7 +1694,8 @@ func (s *session) handleUnpackedPacket(
 	packet *unpackedPacket,
 	ecn protocol.ECN,
 	rcvTime time.Time,
-	packetSize protocol.ByteCount, // only for logging
+	remoteAddr net.Addr,
+	packetSize protocol.ByteCount, // for logging, and for limiting the data sent to an unvalidated peer address
 ) error {
 	if len(packet.data) == 0 {
 		return &qerr.TransportError{
@@ -1114,6 +1704,12 @@ func (s *session) handleUnpackedPacket(
 		}
 	}
 
+	s.statsMutex.Lock()
+	pnSpaceStats := s.stats.packetNumberSpace(packet.encryptionLevel)
+	pnSpaceStats.PacketsReceived++
+	pnSpaceStats.BytesReceived += uint64(packetSize)
+	s.statsMutex.Unlock()
+
 	if !s.receivedFirstPacket {
 		s.receivedFirstPacket = true
 		if !s.versionNegotiated && s.tracer != nil {
@@ -1162,7 +1758,7 @@ func (s *session) handleUnpackedPacket(
 	// If we're not tracing, this slice will always remain empty.
 	var frames []wire.Frame
 	r := bytes.NewReader(packet.data)
-	var isAckEliciting bool
+	var isAckEliciting, isNonProbing, isConnectionClose bool
 	for {
 		frame, err := s.frameParser.ParseNext(r, packet.encryptionLevel)
 		if err != nil {
@@ -1174,6 +1770,12 @@ func (s *session) handleUnpackedPacket(
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
+		if !isProbingFrame(frame) {
+			isNonProbing = true
+		}
+		if _, ok := frame.(*wire.ConnectionCloseFrame); ok {
+			isConnectionClose = true
+		}
 		// Only process frames now if we're not logging.
 		// If we're logging, we need to make sure that the packet_received event is logged first.
 		if s.tracer == nil {
@@ -1198,6 +1800,18 @@ func (s *session) handleUnpackedPacket(
 		}
 	}
 
+	if packet.encryptionLevel == protocol.Encryption1RTT && packet.packetNumber >= s.largestRcvdAppDataPacketNumber {
+		s.largestRcvdAppDataPacketNumber = packet.packetNumber
+		// Only a non-probing packet with the largest packet number received so far indicates a change of the peer's address.
+		// Reordered packets might still arrive from the old address.
+		// There's no need to switch addresses if the peer closed the connection.
+		if isNonProbing && !isConnectionClose {
+			s.maybeHandlePeerAddressChange(remoteAddr, rcvTime)
+		}
+	}
+	if v := s.peerAddrValidation; v != nil && remoteAddr != nil && remoteAddr.String() == v.newAddr.String() {
+		v.bytesReceived += packetSize
+	}
 	return s.receivedPacketHandler.ReceivedPacket(packet.packetNumber, ecn, packet.encryptionLevel, rcvTime, isAckEliciting)
 }
 
@@ -1230,8 +1844,7 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		err = s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1242,6 +1855,8 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 		err = s.handleHandshakeDoneFrame()
 	case *wire.DatagramFrame:
 		err = s.handleDatagramFrame(frame)
+	case *wire.BDPFrame:
+		s.handleBDPFrame(frame)
 	default:
 		err = fmt.Errorf("unexpected frame type: %s", reflect.ValueOf(&frame).Elem().Type().Name())
 	}
@@ -1250,6 +1865,10 @@ func (s *session) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel, d
 
 // handlePacket is called by the server with a new packet
 func (s *session) handlePacket(p *receivedPacket) {
+	s.statsMutex.Lock()
+	s.stats.DatagramsReceived++
+	s.stats.BytesReceived += uint64(p.Size())
+	s.statsMutex.Unlock()
 	// Discard packets once the amount of queued packets is larger than
 	// the channel size, protocol.MaxSessionUnprocessedPackets
 	select {
@@ -1263,10 +1882,12 @@ func (s *session) handlePacket(p *receivedPacket) {
 
 func (s *session) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame) {
 	if frame.IsApplicationError {
+		msg, payload := qerr.DecodeReasonPhrase(frame.ReasonPhrase)
 		s.closeRemote(&qerr.ApplicationError{
 			Remote:       true,
 			ErrorCode:    qerr.ApplicationErrorCode(frame.ErrorCode),
-			ErrorMessage: frame.ReasonPhrase,
+			ErrorMessage: msg,
+			Payload:      payload,
 		})
 		return
 	}
@@ -1286,7 +1907,7 @@ func (s *session) handleCryptoFrame(frame *wire.CryptoFrame, encLevel protocol.E
 	if encLevelChanged {
 		// Queue all packets for decryption that have been undecryptable so far.
 		s.undecryptablePacketsToProcess = s.undecryptablePackets
-		s.undecryptablePackets = nil
+		s.clearUndecryptablePackets()
 	}
 	return nil
 }
@@ -1301,10 +1922,24 @@ func (s *session) handleStreamFrame(frame *wire.StreamFrame) error {
 		// ignore this StreamFrame
 		return nil
 	}
+	// The tap has to be called before the frame is handed to the stream,
+	// since the stream might release the frame once it was read.
+	if s.config.StreamTap != nil {
+		s.tapStreamFrame(StreamDataReceived, frame)
+	}
 	return str.handleStreamFrame(frame)
 }
 
+func (s *session) tapStreamFrame(dir StreamDataDirection, frame *wire.StreamFrame) {
+	s.config.StreamTap(s, dir, frame.StreamID, uint64(frame.Offset), frame.Data, frame.Fin)
+}
+
 func (s *session) handleMaxDataFrame(frame *wire.MaxDataFrame) {
+	if s.tracer != nil {
+		if limit := s.connFlowController.State().SendWindow; frame.MaximumData < limit {
+			s.tracer.DetectedPeerAnomaly(logging.PeerAnomalyFlowControlLimitDecreased, fmt.Sprintf("MAX_DATA: %d (current limit: %d)", frame.MaximumData, limit))
+		}
+	}
 	s.connFlowController.UpdateSendWindow(frame.MaximumData)
 }
 
@@ -1317,6 +1952,11 @@ func (s *session) handleMaxStreamDataFrame(frame *wire.MaxStreamDataFrame) error
 		// stream is closed and already garbage collected
 		return nil
 	}
+	if s.tracer != nil {
+		if limit := str.FlowControlState().SendLimit; uint64(frame.MaximumStreamData) < limit {
+			s.tracer.DetectedPeerAnomaly(logging.PeerAnomalyFlowControlLimitDecreased, fmt.Sprintf("MAX_STREAM_DATA for stream %d: %d (current limit: %d)", frame.StreamID, frame.MaximumStreamData, limit))
+		}
+	}
 	str.updateSendWindow(frame.MaximumStreamData)
 	return nil
 }
@@ -1351,9 +1991,153 @@ func (s *session) handleStopSendingFrame(frame *wire.StopSendingFrame) error {
 }
 
 func (s *session) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
+	s.statsMutex.Lock()
+	s.stats.PathChallengesReceived++
+	s.statsMutex.Unlock()
+	if s.tracer != nil {
+		s.tracer.ReceivedPathChallenge(frame.Data)
+	}
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *session) handlePathResponseFrame(frame *wire.PathResponseFrame) error {
+	// we only send PATH_CHALLENGEs when validating a new peer address
+	if !s.sentPathChallenge {
+		return errors.New("unexpected PATH_RESPONSE frame")
+	}
+	// This might be a response to a PATH_CHALLENGE sent for an earlier address change.
+	if s.peerAddrValidation == nil || !s.peerAddrValidation.isChallenge(frame.Data) {
+		return nil
+	}
+	v := s.peerAddrValidation
+	s.peerAddrValidation = nil
+	duration := time.Since(v.start)
+	s.logger.Debugf("Validated new peer address %s in %s", v.newAddr, duration)
+	// The RTT and the congestion window of the old path don't apply to the new path,
+	// unless the peer's port changed (e.g. due to a NAT rebinding), see section 9.4 of RFC 9000.
+	if !isPortOnlyChange(v.oldAddr, v.newAddr) {
+		s.sentPacketHandler.MigratedPath()
+	}
+	s.statsMutex.Lock()
+	s.stats.PathValidationsSucceeded++
+	s.stats.LastPathValidationTime = duration
+	s.statsMutex.Unlock()
+	if s.tracer != nil {
+		s.tracer.FinishedPathValidation(v.newAddr, true, duration)
+	}
+	s.notifyPeerAddressChange(v.oldAddr, v.newAddr, AddressValidationSucceeded)
+	return nil
+}
+
+// maybeHandlePeerAddressChange switches to a new peer address, and starts validating it.
+// It is called when a non-probing packet with the largest packet number received so far
+// was received. Only the server handles address changes, and only after the handshake was confirmed.
+func (s *session) maybeHandlePeerAddressChange(addr net.Addr, now time.Time) {
+	if s.perspective != protocol.PerspectiveServer || !s.handshakeConfirmed || addr == nil {
+		return
+	}
+	if addr.String() == s.conn.RemoteAddr().String() {
+		return
+	}
+	// If the previous address change wasn't validated yet, we don't know if the peer was ever reachable at that address.
+	oldAddr := s.conn.RemoteAddr()
+	if s.peerAddrValidation != nil {
+		oldAddr = s.peerAddrValidation.oldAddr
+	}
+	decision := PeerAddressChangeAccept
+	if s.config.CheckPeerAddressChange != nil {
+		decision = s.config.CheckPeerAddressChange(s, AddressChange{OldAddr: oldAddr, NewAddr: addr, State: AddressValidationPending})
+	}
+	if decision == PeerAddressChangeRefuse {
+		s.logger.Debugf("Refusing change of the peer address from %s to %s", oldAddr, addr)
+		s.closeLocal(&qerr.TransportError{
+			ErrorCode:    qerr.NoViablePathError,
+			ErrorMessage: "peer address change refused",
+		})
+		return
+	}
+	v := &peerAddrValidation{
+		oldAddr:  oldAddr,
+		newAddr:  addr,
+		start:    now,
+		deadline: now.Add(3 * utils.MaxDuration(s.rttStats.PTO(true), protocol.MinPathValidationPTO)),
+	}
+	if err := v.addChallenge(); err != nil {
+		s.logger.Errorf("Failed to generate PATH_CHALLENGE: %s", err)
+		return
+	}
+	s.logger.Debugf("Peer address changed from %s to %s", oldAddr, addr)
+	s.conn.SetRemoteAddr(addr)
+	s.peerAddrValidation = v
+	if s.tracer != nil {
+		s.tracer.StartedPathValidation(addr, v.challenges[0])
+	}
+	s.notifyPeerAddressChange(oldAddr, addr, AddressValidationPending)
+	s.scheduleSending()
+}
+
+// maybeSendPathChallenge sends a PATH_CHALLENGE to the new peer address, if the address is being validated.
+// The PATH_CHALLENGE is sent in its own packet, padded to 1200 bytes,
+// unless this would exceed the amplification limit, see section 8.2.1 of RFC 9000.
+func (s *session) maybeSendPathChallenge(now time.Time) (bool, error) {
+	v := s.peerAddrValidation
+	if v == nil || !v.sendChallenge {
+		return false, nil
+	}
+	size := protocol.ByteCount(protocol.MinInitialPacketSize)
+	if budget := 3*v.bytesReceived - v.bytesSent; budget < size {
+		size = budget
+	}
+	challenge := ackhandler.Frame{
+		Frame: &wire.PathChallengeFrame{Data: v.challenges[len(v.challenges)-1]},
+		// Don't retransmit the PATH_CHALLENGE, but send a new one with new data, see section 8.2.1 of RFC 9000.
+		OnLost: func(wire.Frame) {
+			if s.peerAddrValidation != v {
+				return
+			}
+			if err := v.addChallenge(); err != nil {
+				s.logger.Errorf("Failed to generate PATH_CHALLENGE: %s", err)
+			}
+		},
+	}
+	packet, err := s.packer.PackPathProbePacket(challenge, size)
+	if err != nil {
+		return false, err
+	}
+	v.sendChallenge = false
+	s.sentPathChallenge = true
+	s.statsMutex.Lock()
+	s.stats.PathChallengesSent++
+	s.statsMutex.Unlock()
+	s.sendPackedPacket(packet, now)
+	return true, nil
+}
+
+// maybeAbandonPeerAddressValidation reverts to the old peer address if the new address couldn't be validated in time.
+func (s *session) maybeAbandonPeerAddressValidation(now time.Time) {
+	v := s.peerAddrValidation
+	if v == nil || now.Before(v.deadline) {
+		return
+	}
+	s.peerAddrValidation = nil
+	s.logger.Debugf("Failed to validate new peer address %s. Reverting to %s.", v.newAddr, v.oldAddr)
+	s.conn.SetRemoteAddr(v.oldAddr)
+	s.statsMutex.Lock()
+	s.stats.PathValidationsFailed++
+	s.statsMutex.Unlock()
+	if s.tracer != nil {
+		s.tracer.FinishedPathValidation(v.newAddr, false, now.Sub(v.start))
+	}
+	s.notifyPeerAddressChange(v.oldAddr, v.newAddr, AddressValidationFailed)
+}
+
+func (s *session) notifyPeerAddressChange(oldAddr, newAddr net.Addr, state AddressValidationState) {
+	if s.config.PeerAddressChanged == nil {
+		return
+	}
+	s.config.PeerAddressChanged(s, AddressChange{OldAddr: oldAddr, NewAddr: newAddr, State: state})
+}
+
 func (s *session) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return &qerr.TransportError{
@@ -1413,6 +2197,18 @@ func (s *session) handleDatagramFrame(f *wire.DatagramFrame) error {
 	return nil
 }
 
+func (s *session) handleBDPFrame(f *wire.BDPFrame) {
+	if s.config.BDPFrameReceived == nil {
+		return
+	}
+	s.config.BDPFrameReceived(s, BDPInfo{
+		Lifetime:      f.Lifetime,
+		SavedCapacity: f.SavedCapacity,
+		SavedRTT:      f.SavedRTT,
+		EndpointToken: f.EndpointToken,
+	})
+}
+
 // closeLocal closes the session and send a CONNECTION_CLOSE containing the error
 func (s *session) closeLocal(e error) {
 	s.closeOnce.Do(func() {
@@ -1465,6 +2261,19 @@ func (s *session) CloseWithError(code ApplicationErrorCode, desc string) error {
 	return nil
 }
 
+func (s *session) CloseWithPayload(code ApplicationErrorCode, desc string, payload []byte) error {
+	if len(payload) > protocol.MaxClosePayloadSize {
+		return fmt.Errorf("close payload too large: %d bytes (maximum %d)", len(payload), protocol.MaxClosePayloadSize)
+	}
+	s.closeLocal(&qerr.ApplicationError{
+		ErrorCode:    code,
+		ErrorMessage: desc,
+		Payload:      payload,
+	})
+	<-s.ctx.Done()
+	return nil
+}
+
 func (s *session) handleCloseError(closeErr *closeError) {
 	e := closeErr.err
 	if e == nil {
@@ -1497,6 +2306,8 @@ func (s *session) handleCloseError(closeErr *closeError) {
 		}
 	}
 
+	// The session's context is canceled after the streams and datagrams have been closed.
+	s.closeCause.err = e
 	s.streamsMap.CloseWithError(e)
 	s.connIDManager.Close()
 	if s.datagramQueue != nil {
@@ -1504,6 +2315,7 @@ func (s *session) handleCloseError(closeErr *closeError) {
 	}
 
 	if s.tracer != nil && !errors.As(e, &recreateErr) {
+		s.cryptoStreamManager.Close()
 		s.tracer.ClosedConnection(e)
 	}
 
@@ -1524,6 +2336,12 @@ func (s *session) handleCloseError(closeErr *closeError) {
 	s.connIDGenerator.ReplaceWithClosed(cs)
 }
 
+func (s *session) onKeysAvailable(encLevel protocol.EncryptionLevel, forSending bool) {
+	if s.config.KeysAvailable != nil {
+		s.config.KeysAvailable(s, encLevel, forSending)
+	}
+}
+
 func (s *session) dropEncryptionLevel(encLevel protocol.EncryptionLevel) {
 	s.sentPacketHandler.DropPackets(encLevel)
 	s.receivedPacketHandler.DropPackets(encLevel)
@@ -1532,6 +2350,7 @@ func (s *session) dropEncryptionLevel(encLevel protocol.EncryptionLevel) {
 	}
 	if encLevel == protocol.Encryption0RTT {
 		s.streamsMap.ResetFor0RTT()
+		s.streamGroups.reset()
 		if err := s.connFlowController.Reset(); err != nil {
 			s.closeLocal(err)
 		}
@@ -1548,11 +2367,32 @@ func (s *session) restoreTransportParameters(params *wire.TransportParameters) {
 	}
 
 	s.peerParams = params
+	s.zeroRTTParams = params
+	s.storePeerSupportsResetStreamAt(params)
 	s.connIDGenerator.SetMaxActiveConnIDs(params.ActiveConnectionIDLimit)
 	s.connFlowController.UpdateSendWindow(params.InitialMaxData)
 	s.streamsMap.UpdateLimits(params)
 }
 
+// check0RTTTransportParameters is called for the client when the handshake completes.
+// If the server accepted 0-RTT, it must not have reduced any of the limits that the 0-RTT data relied on.
+func (s *session) check0RTTTransportParameters() error {
+	if s.zeroRTTParams == nil || !s.cryptoStreamHandler.ConnectionState().Used0RTT {
+		return nil
+	}
+	param := s.peerParams.Mismatched0RTTParameter(s.zeroRTTParams)
+	if param == "" {
+		return nil
+	}
+	return &ZeroRTTParameterError{
+		TransportError: qerr.TransportError{
+			ErrorCode:    qerr.ProtocolViolation,
+			ErrorMessage: fmt.Sprintf("server accepted 0-RTT, but reduced the %s transport parameter", param),
+		},
+		Parameter: param,
+	}
+}
+
 func (s *session) handleTransportParameters(params *wire.TransportParameters) {
 	if err := s.checkTransportParameters(params); err != nil {
 		s.closeLocal(&qerr.TransportError{
@@ -1561,6 +2401,7 @@ func (s *session) handleTransportParameters(params *wire.TransportParameters) {
 		})
 	}
 	s.peerParams = params
+	s.storePeerSupportsResetStreamAt(params)
 	// On the client side we have to wait for handshake completion.
 	// During a 0-RTT connection, we are only allowed to use the new transport parameters for 1-RTT packets.
 	if s.perspective == protocol.PerspectiveServer {
@@ -1571,6 +2412,14 @@ func (s *session) handleTransportParameters(params *wire.TransportParameters) {
 	}
 }
 
+func (s *session) storePeerSupportsResetStreamAt(params *wire.TransportParameters) {
+	var supports int32
+	if params.SupportsResetStreamAt {
+		supports = 1
+	}
+	atomic.StoreInt32(&s.peerSupportsResetStreamAt, supports)
+}
+
 func (s *session) checkTransportParameters(params *wire.TransportParameters) error {
 	if s.logger.Debug() {
 		s.logger.Debugf("Processed Transport Parameters: %s", params)
@@ -1608,6 +2457,13 @@ func (s *session) applyTransportParameters() {
 	params := s.peerParams
 	// Our local idle timeout will always be > 0.
 	s.idleTimeout = utils.MinNonZeroDuration(s.config.MaxIdleTimeout, params.MaxIdleTimeout)
+	atomic.StoreInt64(&s.effectiveIdleTimeout, int64(s.idleTimeout))
+	if s.idleTimeout < s.config.MaxIdleTimeout/idleTimeoutReductionWarningFactor {
+		s.logger.Infof("Peer reduced the idle timeout from %s to %s.", s.config.MaxIdleTimeout, s.idleTimeout)
+	}
+	if s.tracer != nil {
+		s.tracer.NegotiatedIdleTimeout(s.config.MaxIdleTimeout, params.MaxIdleTimeout, s.idleTimeout)
+	}
 	s.keepAliveInterval = utils.MinDuration(s.idleTimeout/2, protocol.MaxKeepAliveInterval)
 	s.streamsMap.UpdateLimits(params)
 	s.packer.HandleTransportParameters(params)
@@ -1623,15 +2479,26 @@ func (s *session) applyTransportParameters() {
 		// Retire the connection ID.
 		s.connIDManager.AddFromPreferredAddress(params.PreferredAddress.ConnectionID, params.PreferredAddress.StatelessResetToken)
 	}
+	s.updateMaxDatagramPayloadSize()
 }
 
 func (s *session) sendPackets() error {
 	s.pacingDeadline = time.Time{}
 
+	// The flush request is kept until a packet was sent.
+	flush := atomic.LoadInt32(&s.flushRequested) == 1
 	var sentPacket bool // only used in for packets sent in send mode SendAny
 	for {
+		if s.peerAddrValidation.isAmplificationLimited() {
+			return nil
+		}
 		sendMode := s.sentPacketHandler.SendMode()
-		if sendMode == ackhandler.SendAny && s.handshakeComplete && !s.sentPacketHandler.HasPacingBudget() {
+		pacingLimited := sendMode == ackhandler.SendAny && s.handshakeComplete && !s.sentPacketHandler.HasPacingBudget()
+		// After Flush was called on a stream, send the first packet right away, without waiting for the pacer.
+		if pacingLimited && flush && !sentPacket && s.hasAppDataToSend() {
+			pacingLimited = false
+		}
+		if pacingLimited {
 			deadline := s.sentPacketHandler.TimeUntilSend()
 			if deadline.IsZero() {
 				deadline = deadlineSendImmediately
@@ -1643,6 +2510,11 @@ func (s *session) sendPackets() error {
 			if sentPacket {
 				return nil
 			}
+			// If we have data to send, and the pacer will allow us to send soon,
+			// don't send an ACK-only packet. The ACK will be sent together with the data.
+			if s.hasAppDataToSend() && time.Until(deadline) <= protocol.AckPiggybackWindow {
+				return nil
+			}
 			sendMode = ackhandler.SendAck
 		}
 		switch sendMode {
@@ -1672,10 +2544,25 @@ func (s *session) sendPackets() error {
 				return err
 			}
 		case ackhandler.SendAny:
+			if !flush && s.delaySending(time.Now()) {
+				return nil
+			}
+			// Clear the flush request before packing the packet,
+			// so that a stream flushed while packing isn't missed.
+			clearFlush := flush && !sentPacket
+			if clearFlush {
+				atomic.StoreInt32(&s.flushRequested, 0)
+			}
 			sent, err := s.sendPacket()
-			if err != nil || !sent {
+			if err != nil {
 				return err
 			}
+			if !sent {
+				if clearFlush {
+					atomic.StoreInt32(&s.flushRequested, 1)
+				}
+				return nil
+			}
 			sentPacket = true
 		default:
 			return fmt.Errorf("BUG: invalid send mode %d", sendMode)
@@ -1691,6 +2578,38 @@ func (s *session) sendPackets() error {
 	}
 }
 
+// delaySending says if sending should be delayed, because only a small amount of stream data is waiting to be sent.
+// In that case, it sets the pacingDeadline to the time when the data has to be sent, see Config.CoalescingDelay.
+func (s *session) delaySending(now time.Time) bool {
+	if s.config.CoalescingDelay == 0 || !s.handshakeComplete || s.retransmissionQueue.HasAppData() {
+		s.coalescingStart = time.Time{}
+		return false
+	}
+	if ackAlarm := s.receivedPacketHandler.GetAlarmTimeout(); !ackAlarm.IsZero() && !now.Before(ackAlarm) {
+		s.coalescingStart = time.Time{}
+		return false
+	}
+	minFill := protocol.ByteCount(s.config.CoalescingMinFill * float64(s.packer.MaxPacketSize()))
+	if pending := s.framer.PendingStreamData(minFill); pending == 0 || pending >= minFill {
+		s.coalescingStart = time.Time{}
+		return false
+	}
+	if s.coalescingStart.IsZero() {
+		s.coalescingStart = now
+	}
+	deadline := s.coalescingStart.Add(s.config.CoalescingDelay)
+	if !now.Before(deadline) {
+		s.coalescingStart = time.Time{}
+		return false
+	}
+	s.pacingDeadline = deadline
+	return true
+}
+
+func (s *session) hasAppDataToSend() bool {
+	return s.framer.HasData() || s.retransmissionQueue.HasAppData()
+}
+
 func (s *session) maybeSendAckOnlyPacket() error {
 	packet, err := s.packer.MaybePackAckPacket(s.handshakeConfirmed)
 	if err != nil {
@@ -1704,33 +2623,32 @@ func (s *session) maybeSendAckOnlyPacket() error {
 }
 
 func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
-	// Queue probe packets until we actually send out a packet,
-	// or until there are no more packets to queue.
 	var packet *packedPacket
-	for {
-		if wasQueued := s.sentPacketHandler.QueueProbePacket(encLevel); !wasQueued {
-			break
-		}
+	//nolint:exhaustive // ProbeRetransmitOldest is handled below.
+	switch s.config.ProbePolicy {
+	case ProbeNewData:
+		// Try to send new data first, without retransmitting anything.
 		var err error
 		packet, err = s.packer.MaybePackProbePacket(encLevel)
 		if err != nil {
 			return err
 		}
-		if packet != nil {
-			break
+		// If there's no new data, the packet might only contain an ACK.
+		// Send it, but don't count it as a probe packet, since it doesn't elicit an ACK.
+		if packet != nil && packet.packetContents != nil && !packet.IsAckEliciting() {
+			s.sendPackedPacket(packet, time.Now())
+			packet = nil
+		}
+	case ProbePing:
+		if encLevel == protocol.Encryption1RTT {
+			return s.sendPingProbePacket(encLevel)
 		}
 	}
-	if packet == nil {
-		//nolint:exhaustive // Cannot send probe packets for 0-RTT.
-		switch encLevel {
-		case protocol.EncryptionInitial:
-			s.retransmissionQueue.AddInitial(&wire.PingFrame{})
-		case protocol.EncryptionHandshake:
-			s.retransmissionQueue.AddHandshake(&wire.PingFrame{})
-		case protocol.Encryption1RTT:
-			s.retransmissionQueue.AddAppData(&wire.PingFrame{})
-		default:
-			panic("unexpected encryption level")
+	// Queue probe packets until we actually send out a packet,
+	// or until there are no more packets to queue.
+	for packet == nil {
+		if wasQueued := s.sentPacketHandler.QueueProbePacket(encLevel); !wasQueued {
+			break
 		}
 		var err error
 		packet, err = s.packer.MaybePackProbePacket(encLevel)
@@ -1738,9 +2656,37 @@ func (s *session) sendProbePacket(encLevel protocol.EncryptionLevel) error {
 			return err
 		}
 	}
+	if packet == nil {
+		return s.sendPingProbePacket(encLevel)
+	}
+	if packet.packetContents == nil {
+		return fmt.Errorf("session BUG: couldn't pack %s probe packet", encLevel)
+	}
+	s.countProbePacket(false)
+	s.sendPackedPacket(packet, time.Now())
+	return nil
+}
+
+func (s *session) sendPingProbePacket(encLevel protocol.EncryptionLevel) error {
+	//nolint:exhaustive // Cannot send probe packets for 0-RTT.
+	switch encLevel {
+	case protocol.EncryptionInitial:
+		s.retransmissionQueue.AddInitial(&wire.PingFrame{})
+	case protocol.EncryptionHandshake:
+		s.retransmissionQueue.AddHandshake(&wire.PingFrame{})
+	case protocol.Encryption1RTT:
+		s.retransmissionQueue.AddAppData(&wire.PingFrame{})
+	default:
+		panic("unexpected encryption level")
+	}
+	packet, err := s.packer.MaybePackProbePacket(encLevel)
+	if err != nil {
+		return err
+	}
 	if packet == nil || packet.packetContents == nil {
 		return fmt.Errorf("session BUG: couldn't pack %s probe packet", encLevel)
 	}
+	s.countProbePacket(true)
 	s.sendPackedPacket(packet, time.Now())
 	return nil
 }
@@ -1762,10 +2708,11 @@ func (s *session) sendPacket() (bool, error) {
 			if s.firstAckElicitingPacketAfterIdleSentTime.IsZero() && p.IsAckEliciting() {
 				s.firstAckElicitingPacketAfterIdleSentTime = now
 			}
+			s.countSentPacket(p)
 			s.sentPacketHandler.SentPacket(p.ToAckHandlerPacket(now, s.retransmissionQueue))
 		}
-		s.connIDManager.SentPacket()
-		s.sendQueue.Send(packet.buffer)
+		s.connIDManager.SentPacket(now, packet.buffer.Len())
+		s.sendDatagram(packet.buffer, packet.packets)
 		return true, nil
 	}
 	if !s.config.DisablePathMTUDiscovery && s.mtuDiscoverer.ShouldSendProbe(now) {
@@ -1776,6 +2723,9 @@ func (s *session) sendPacket() (bool, error) {
 		s.sendPackedPacket(packet, now)
 		return true, nil
 	}
+	if sent, err := s.maybeSendPathChallenge(now); sent || err != nil {
+		return sent, err
+	}
 	packet, err := s.packer.PackPacket()
 	if err != nil || packet == nil {
 		return false, err
@@ -1789,9 +2739,40 @@ func (s *session) sendPackedPacket(packet *packedPacket, now time.Time) {
 		s.firstAckElicitingPacketAfterIdleSentTime = now
 	}
 	s.logPacket(packet)
+	s.countSentPacket(packet.packetContents)
 	s.sentPacketHandler.SentPacket(packet.ToAckHandlerPacket(now, s.retransmissionQueue))
-	s.connIDManager.SentPacket()
-	s.sendQueue.Send(packet.buffer)
+	s.connIDManager.SentPacket(now, packet.buffer.Len())
+	if s.peerAddrValidation != nil {
+		s.peerAddrValidation.bytesSent += packet.buffer.Len()
+	}
+	packet.buffer.dscp = s.packetDSCP(packet.packetContents)
+	s.sendDatagram(packet.buffer, []*packetContents{packet.packetContents})
+}
+
+// packetDSCP returns the DSCP that a packet is marked with, see TrafficClassConfig.DSCP.
+// Packets are only marked if all the frames they contain (except for the ACK frame)
+// are STREAM frames of streams of the same traffic class.
+func (s *session) packetDSCP(p *packetContents) uint8 {
+	if len(s.config.TrafficClasses) == 0 || len(p.frames) == 0 {
+		return 0
+	}
+	var class TrafficClass
+	for i, f := range p.frames {
+		frame, ok := f.Frame.(*wire.StreamFrame)
+		if !ok {
+			return 0
+		}
+		str, err := s.streamsMap.GetOrOpenSendStream(frame.StreamID)
+		if err != nil || str == nil {
+			return 0
+		}
+		c := str.TrafficClass()
+		if i > 0 && c != class {
+			return 0
+		}
+		class = c
+	}
+	return s.config.TrafficClasses[class].DSCP
 }
 
 func (s *session) sendConnectionClose(e error) ([]byte, error) {
@@ -1870,19 +2851,73 @@ func (s *session) AcceptUniStream(ctx context.Context) (ReceiveStream, error) {
 
 // OpenStream opens a stream
 func (s *session) OpenStream() (Stream, error) {
-	return s.streamsMap.OpenStream()
+	str, err := s.streamsMap.OpenStream()
+	if err == nil && s.config.StreamObserver != nil {
+		s.config.StreamObserver.OpenedStream(s, str.StreamID())
+	}
+	return str, err
 }
 
 func (s *session) OpenStreamSync(ctx context.Context) (Stream, error) {
-	return s.streamsMap.OpenStreamSync(ctx)
+	str, err := s.streamsMap.OpenStreamSync(ctx)
+	if err == nil && s.config.StreamObserver != nil {
+		s.config.StreamObserver.OpenedStream(s, str.StreamID())
+	}
+	return str, err
+}
+
+// OpenStreamSyncWithOptions notifies the StreamObserver after the options were applied,
+// so that the observer sees the stream's traffic class and priority.
+func (s *session) OpenStreamSyncWithOptions(ctx context.Context, opts StreamOptions) (Stream, error) {
+	str, err := s.streamsMap.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.applyStreamOptions(str, opts)
+	if s.config.StreamObserver != nil {
+		s.config.StreamObserver.OpenedStream(s, str.StreamID())
+	}
+	return str, nil
+}
+
+func (s *session) OpenUniStreamSyncWithOptions(ctx context.Context, opts StreamOptions) (SendStream, error) {
+	str, err := s.streamsMap.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.applyStreamOptions(str, opts)
+	if s.config.StreamObserver != nil {
+		s.config.StreamObserver.OpenedStream(s, str.StreamID())
+	}
+	return str, nil
+}
+
+func (s *session) applyStreamOptions(str SendStream, opts StreamOptions) {
+	if opts.TrafficClass != TrafficClassDefault {
+		str.SetTrafficClass(opts.TrafficClass)
+	}
+	if opts.Priority != nil {
+		str.SetPriority(*opts.Priority)
+	}
+	if opts.Label != "" && s.tracer != nil {
+		s.tracer.LabeledStream(str.StreamID(), opts.Label)
+	}
 }
 
 func (s *session) OpenUniStream() (SendStream, error) {
-	return s.streamsMap.OpenUniStream()
+	str, err := s.streamsMap.OpenUniStream()
+	if err == nil && s.config.StreamObserver != nil {
+		s.config.StreamObserver.OpenedStream(s, str.StreamID())
+	}
+	return str, err
 }
 
 func (s *session) OpenUniStreamSync(ctx context.Context) (SendStream, error) {
-	return s.streamsMap.OpenUniStreamSync(ctx)
+	str, err := s.streamsMap.OpenUniStreamSync(ctx)
+	if err == nil && s.config.StreamObserver != nil {
+		s.config.StreamObserver.OpenedStream(s, str.StreamID())
+	}
+	return str, err
 }
 
 func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlowController {
@@ -1894,16 +2929,40 @@ func (s *session) newFlowController(id protocol.StreamID) flowcontrol.StreamFlow
 			initialSendWindow = s.peerParams.InitialMaxStreamDataBidiLocal
 		}
 	}
-	return flowcontrol.NewStreamFlowController(
+	fc := flowcontrol.NewStreamFlowController(
 		id,
 		s.connFlowController,
 		protocol.ByteCount(s.config.InitialStreamReceiveWindow),
 		protocol.ByteCount(s.config.MaxStreamReceiveWindow),
 		initialSendWindow,
 		s.onHasStreamWindowUpdate,
+		s.windowUpdateFunc(false, id),
 		s.rttStats,
 		s.logger,
 	)
+	if s.config.ManualStreamReceiveCredit {
+		fc.GrantCredit(0)
+	}
+	return fc
+}
+
+// windowUpdateFunc returns nil if no WindowUpdateStrategy is configured.
+func (s *session) windowUpdateFunc(isConnection bool, id protocol.StreamID) flowcontrol.WindowUpdateFunc {
+	strategy := s.config.WindowUpdateStrategy
+	if strategy == nil {
+		return nil
+	}
+	return func(w flowcontrol.ReceiveWindow) bool {
+		return strategy.ShouldSendWindowUpdate(FlowControlWindow{
+			IsConnection:    isConnection,
+			StreamID:        id,
+			BytesRead:       uint64(w.BytesRead),
+			Offset:          uint64(w.Offset),
+			Size:            uint64(w.Size),
+			SmoothedRTT:     w.SmoothedRTT,
+			SinceLastUpdate: w.SinceLastUpdate,
+		})
+	}
 }
 
 // scheduleSending signals that we have data for sending
@@ -1918,11 +2977,15 @@ func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket, hdr *wire.He
 	if s.handshakeComplete {
 		panic("shouldn't queue undecryptable packets after handshake completion")
 	}
-	if len(s.undecryptablePackets)+1 > protocol.MaxUndecryptablePackets {
+	if len(s.undecryptablePackets)+1 > s.config.MaxUndecryptablePackets || uint64(s.undecryptableBytes+p.Size()) > s.config.MaxUndecryptableBytes {
 		if s.tracer != nil {
 			s.tracer.DroppedPacket(logging.PacketTypeFromHeader(hdr), p.Size(), logging.PacketDropDOSPrevention)
+			s.tracer.UndecryptablePacketQueueFull(logging.PacketTypeFromHeader(hdr), p.Size(), len(s.undecryptablePackets), s.undecryptableBytes)
 		}
-		s.logger.Infof("Dropping undecryptable packet (%d bytes). Undecryptable packet queue full.", p.Size())
+		s.logger.Infof("Dropping undecryptable packet (%d bytes). Undecryptable packet queue full (%d packets, %d bytes).", p.Size(), len(s.undecryptablePackets), s.undecryptableBytes)
+		s.statsMutex.Lock()
+		s.stats.UndecryptablePacketsDropped++
+		s.statsMutex.Unlock()
 		return
 	}
 	s.logger.Infof("Queueing packet (%d bytes) for later decryption", p.Size())
@@ -1930,6 +2993,21 @@ func (s *session) tryQueueingUndecryptablePacket(p *receivedPacket, hdr *wire.He
 		s.tracer.BufferedPacket(logging.PacketTypeFromHeader(hdr))
 	}
 	s.undecryptablePackets = append(s.undecryptablePackets, p)
+	s.undecryptableBytes += p.Size()
+	s.updateUndecryptableStats()
+}
+
+func (s *session) clearUndecryptablePackets() {
+	s.undecryptablePackets = nil
+	s.undecryptableBytes = 0
+	s.updateUndecryptableStats()
+}
+
+func (s *session) updateUndecryptableStats() {
+	s.statsMutex.Lock()
+	s.stats.UndecryptablePacketsQueued = uint64(len(s.undecryptablePackets))
+	s.stats.UndecryptableBytesQueued = uint64(s.undecryptableBytes)
+	s.statsMutex.Unlock()
 }
 
 func (s *session) queueControlFrame(f wire.Frame) {
@@ -1942,17 +3020,104 @@ func (s *session) onHasStreamWindowUpdate(id protocol.StreamID) {
 	s.scheduleSending()
 }
 
+// onHasConnectionWindowUpdate is called from the application's Read,
+// and from the memory budget when the memory pressure is relieved (potentially by another session).
 func (s *session) onHasConnectionWindowUpdate() {
+	if s.memoryAccount != nil {
+		// The memory usage can only be sampled on the run loop.
+		s.memoryAccount.Update(atomic.LoadInt64(&s.memoryUsage))
+		// Under memory pressure, don't allow the peer to send more data.
+		// The memory account calls this function again once the pressure is relieved.
+		if s.memoryAccount.WithholdWindowUpdate() {
+			// The application might just have read data. Make the run loop sample the memory usage.
+			s.scheduleSending()
+			return
+		}
+	}
 	s.windowUpdateQueue.AddConnection()
 	s.scheduleSending()
 }
 
+// updateMemoryUsage must only be called from the run loop.
+func (s *session) updateMemoryUsage() {
+	if s.memoryAccount == nil {
+		return
+	}
+	used := s.connFlowController.BufferedBytes() + s.cryptoStreamManager.BufferedBytes() + s.undecryptableBytes
+	if s.datagramQueue != nil {
+		used += s.datagramQueue.ReceivedBytes()
+	}
+	atomic.StoreInt64(&s.memoryUsage, int64(used))
+	s.memoryAccount.Update(int64(used))
+}
+
 func (s *session) onHasStreamData(id protocol.StreamID) {
 	s.framer.AddActiveStream(id)
 	s.scheduleSending()
 }
 
+func (s *session) onStreamPriorityChanged(id protocol.StreamID, prio StreamPriority) {
+	s.framer.SetStreamPriority(id, prio)
+	s.scheduleSending()
+}
+
+func (s *session) trafficClassPriority(class TrafficClass) (StreamPriority, bool) {
+	c, ok := s.config.TrafficClasses[class]
+	if !ok || c.Priority == nil {
+		return StreamPriority{}, false
+	}
+	return *c.Priority, true
+}
+
+func (s *session) onStreamFlushed(id protocol.StreamID) {
+	s.framer.AddActiveStream(id)
+	atomic.StoreInt32(&s.flushRequested, 1)
+	s.scheduleSending()
+}
+
+func (s *session) onStreamBlocked(id protocol.StreamID, connectionLevel bool) {
+	if s.tracer != nil {
+		s.tracer.BlockedByFlowControl(id, connectionLevel)
+	}
+}
+
+func (s *session) supportsResetStreamAt() bool {
+	return s.config.EnableResetStreamAt && atomic.LoadInt32(&s.peerSupportsResetStreamAt) == 1
+}
+
+func (s *session) onStreamWritable(notify func()) {
+	s.streamWritableCallbacks = append(s.streamWritableCallbacks, notify)
+}
+
+// notifyWritableStreams calls the callbacks of streams that became writable.
+// It must be called after sending, since the callbacks might write to the stream,
+// which would deadlock if called while packing a packet.
+func (s *session) notifyWritableStreams() {
+	callbacks := s.streamWritableCallbacks
+	s.streamWritableCallbacks = nil
+	for _, notify := range callbacks {
+		notify()
+	}
+}
+
+func (s *session) newStreamAdmission() streamAdmission {
+	a := streamAdmission{
+		maxBidiAcceptBacklog: s.config.MaxStreamAcceptBacklog,
+		maxUniAcceptBacklog:  s.config.MaxUniStreamAcceptBacklog,
+		backlogErrorCode:     s.config.RejectedStreamErrorCode,
+	}
+	if s.config.AllowIncomingStream != nil {
+		a.allow = func(id protocol.StreamID) (bool, StreamErrorCode) { return s.config.AllowIncomingStream(s, id) }
+	}
+	if s.config.StreamObserver != nil {
+		a.opened = func(id protocol.StreamID) { s.config.StreamObserver.AcceptedStream(s, id) }
+	}
+	return a
+}
+
 func (s *session) onStreamCompleted(id protocol.StreamID) {
+	s.framer.RemoveStream(id)
+	s.streamGroups.onStreamCompleted(id)
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
 	}
@@ -1963,11 +3128,55 @@ func (s *session) SendMessage(p []byte) error {
 	if protocol.ByteCount(len(p)) > f.MaxDataLen(s.peerParams.MaxDatagramFrameSize, s.version) {
 		return errors.New("message too large")
 	}
+	if maxSize := atomic.LoadInt64(&s.maxDatagramPayloadSize); maxSize > 0 && int64(len(p)) > maxSize {
+		return errors.New("message too large")
+	}
 	f.Data = make([]byte, len(p))
 	copy(f.Data, p)
 	return s.datagramQueue.AddAndWait(f)
 }
 
+func (s *session) SendBDPFrame(info BDPInfo) error {
+	if !s.config.EnableBDPFrames {
+		return errors.New("BDP_FRAME extension not enabled")
+	}
+	if s.peerParams == nil || !s.peerParams.SupportsBDPFrames {
+		return errors.New("peer doesn't support the BDP_FRAME extension")
+	}
+	if len(info.EndpointToken) > protocol.MaxBDPEndpointTokenLength {
+		return fmt.Errorf("endpoint token too long: %d bytes (maximum %d)", len(info.EndpointToken), protocol.MaxBDPEndpointTokenLength)
+	}
+	if info.SavedCapacity > quicvarint.Max {
+		return errors.New("invalid saved capacity")
+	}
+	token := make([]byte, len(info.EndpointToken))
+	copy(token, info.EndpointToken)
+	s.framer.QueueControlFrame(&wire.BDPFrame{
+		Lifetime:      info.Lifetime,
+		SavedCapacity: info.SavedCapacity,
+		SavedRTT:      info.SavedRTT,
+		EndpointToken: token,
+	})
+	s.scheduleSending()
+	return nil
+}
+
+func (s *session) SetMaxIncomingStreams(n uint64) error {
+	if n > uint64(protocol.MaxStreamCount) {
+		return errors.New("invalid maximum number of incoming streams")
+	}
+	s.streamsMap.SetMaxIncomingStreams(n)
+	return nil
+}
+
+func (s *session) SetMaxIncomingUniStreams(n uint64) error {
+	if n > uint64(protocol.MaxStreamCount) {
+		return errors.New("invalid maximum number of incoming unidirectional streams")
+	}
+	s.streamsMap.SetMaxIncomingUniStreams(n)
+	return nil
+}
+
 func (s *session) ReceiveMessage() ([]byte, error) {
 	return s.datagramQueue.Receive()
 }
@@ -11,9 +11,12 @@ import (
 	"net"
 	"runtime/pprof"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/ackhandler"
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/handshake"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	mockackhandler "github.com/lucas-clemente/quic-go/internal/mocks/ackhandler"
@@ -24,6 +27,7 @@ import (
 	"github.com/lucas-clemente/quic-go/internal/utils"
 	"github.com/lucas-clemente/quic-go/internal/wire"
 	"github.com/lucas-clemente/quic-go/logging"
+	"github.com/lucas-clemente/quic-go/quicvarint"
 
 	"github.com/golang/mock/gomock"
 
@@ -43,6 +47,24 @@ func areClosedSessionsRunning() bool {
 	return strings.Contains(b.String(), "quic-go.(*closedLocalSession).run")
 }
 
+type faultInjectorFuncs struct {
+	sending, received func(faultInjectionPacket) packetFault
+}
+
+func (f *faultInjectorFuncs) SendingPacket(p faultInjectionPacket) packetFault {
+	if f.sending == nil {
+		return packetFault{}
+	}
+	return f.sending(p)
+}
+
+func (f *faultInjectorFuncs) ReceivedPacket(p faultInjectionPacket) packetFault {
+	if f.received == nil {
+		return packetFault{}
+	}
+	return f.received(p)
+}
+
 var _ = Describe("Session", func() {
 	var (
 		sess          *session
@@ -139,6 +161,32 @@ var _ = Describe("Session", func() {
 				Expect(sess.handleStreamFrame(f)).To(Succeed())
 			})
 
+			It("reports STREAM frames to the stream tap", func() {
+				type tapped struct {
+					dir    StreamDataDirection
+					id     StreamID
+					offset uint64
+					data   []byte
+					fin    bool
+				}
+				var taps []tapped
+				sess.config.StreamTap = func(s Session, dir StreamDataDirection, id StreamID, offset uint64, data []byte, fin bool) {
+					Expect(s).To(Equal(sess))
+					taps = append(taps, tapped{dir: dir, id: id, offset: offset, data: append([]byte{}, data...), fin: fin})
+				}
+				f := &wire.StreamFrame{
+					StreamID: 5,
+					Offset:   10,
+					Data:     []byte{0xde, 0xca, 0xfb, 0xad},
+					Fin:      true,
+				}
+				str := NewMockReceiveStreamI(mockCtrl)
+				str.EXPECT().handleStreamFrame(f)
+				streamManager.EXPECT().GetOrOpenReceiveStream(protocol.StreamID(5)).Return(str, nil)
+				Expect(sess.handleStreamFrame(f)).To(Succeed())
+				Expect(taps).To(Equal([]tapped{{dir: StreamDataReceived, id: 5, offset: 10, data: []byte{0xde, 0xca, 0xfb, 0xad}, fin: true}}))
+			})
+
 			It("returns errors", func() {
 				testErr := errors.New("test err")
 				f := &wire.StreamFrame{
@@ -207,6 +255,55 @@ var _ = Describe("Session", func() {
 			})
 		})
 
+		Context("memory budget", func() {
+			It("withholds connection-level window updates under memory pressure", func() {
+				budget := NewMemoryBudget(1000)
+				sess.memoryAccount = budget.newAccount(sess.onHasConnectionWindowUpdate, func() {})
+				connFC := mocks.NewMockConnectionFlowController(mockCtrl)
+				sess.connFlowController = connFC
+				sess.windowUpdateQueue = newWindowUpdateQueue(sess.streamsMap, connFC, sess.framer.QueueControlFrame)
+				connFC.EXPECT().BufferedBytes().Return(protocol.ByteCount(900))
+				sess.updateMemoryUsage()
+				Expect(budget.Stats().Used).To(BeEquivalentTo(900))
+				// the memory usage is not sampled when called from the application's Read
+				sess.onHasConnectionWindowUpdate()
+				Expect(budget.Stats().WithheldWindowUpdates).To(BeEquivalentTo(1))
+				Expect(sess.sendingScheduled).To(Receive())
+				sess.windowUpdateQueue.QueueAll()
+				Expect(sess.framer.HasData()).To(BeFalse())
+				// the application read data, the run loop samples the memory usage, relieving the pressure
+				connFC.EXPECT().BufferedBytes().Return(protocol.ByteCount(100))
+				connFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0x1337))
+				sess.updateMemoryUsage()
+				Expect(budget.Stats().Used).To(BeEquivalentTo(100))
+				sess.windowUpdateQueue.QueueAll()
+				frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.MaxDataFrame{MaximumData: 0x1337}}}))
+			})
+
+			It("accounts for CRYPTO data, undecryptable packets and DATAGRAM frames", func() {
+				budget := NewMemoryBudget(1 << 20)
+				sess.memoryAccount = budget.newAccount(sess.onHasConnectionWindowUpdate, func() {})
+				connFC := mocks.NewMockConnectionFlowController(mockCtrl)
+				sess.connFlowController = connFC
+				connFC.EXPECT().BufferedBytes().Return(protocol.ByteCount(1000)).AnyTimes()
+				initialStream := NewMockCryptoStream(mockCtrl)
+				handshakeStream := NewMockCryptoStream(mockCtrl)
+				sess.cryptoStreamManager = newCryptoStreamManager(nil, initialStream, handshakeStream, nil, nil)
+				initialStream.EXPECT().BufferedBytes().Return(protocol.ByteCount(200)).AnyTimes()
+				handshakeStream.EXPECT().BufferedBytes().Return(protocol.ByteCount(30)).AnyTimes()
+				sess.updateMemoryUsage()
+				Expect(budget.Stats().Used).To(BeEquivalentTo(1230))
+				sess.undecryptableBytes = 4000
+				sess.updateMemoryUsage()
+				Expect(budget.Stats().Used).To(BeEquivalentTo(5230))
+				sess.datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger)
+				sess.datagramQueue.HandleDatagramFrame(&wire.DatagramFrame{Data: make([]byte, 50000)})
+				sess.updateMemoryUsage()
+				Expect(budget.Stats().Used).To(BeEquivalentTo(55230))
+			})
+		})
+
 		Context("handling MAX_DATA and MAX_STREAM_DATA frames", func() {
 			var connFC *mocks.MockConnectionFlowController
 
@@ -222,16 +319,38 @@ var _ = Describe("Session", func() {
 				}
 				str := NewMockSendStreamI(mockCtrl)
 				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(12345)).Return(str, nil)
+				str.EXPECT().FlowControlState().Return(FlowControlState{SendLimit: 0x1000})
 				str.EXPECT().updateSendWindow(protocol.ByteCount(0x1337))
 				Expect(sess.handleMaxStreamDataFrame(f)).To(Succeed())
 			})
 
+			It("reports MAX_STREAM_DATA frames that would decrease the limit", func() {
+				f := &wire.MaxStreamDataFrame{
+					StreamID:          12345,
+					MaximumStreamData: 0x1000,
+				}
+				str := NewMockSendStreamI(mockCtrl)
+				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(12345)).Return(str, nil)
+				str.EXPECT().FlowControlState().Return(FlowControlState{SendLimit: 0x1337})
+				tracer.EXPECT().DetectedPeerAnomaly(logging.PeerAnomalyFlowControlLimitDecreased, "MAX_STREAM_DATA for stream 12345: 4096 (current limit: 4919)")
+				str.EXPECT().updateSendWindow(protocol.ByteCount(0x1000))
+				Expect(sess.handleMaxStreamDataFrame(f)).To(Succeed())
+			})
+
 			It("updates the flow control window of the connection", func() {
 				offset := protocol.ByteCount(0x800000)
+				connFC.EXPECT().State().Return(flowcontrol.WindowState{SendWindow: 0x1000})
 				connFC.EXPECT().UpdateSendWindow(offset)
 				sess.handleMaxDataFrame(&wire.MaxDataFrame{MaximumData: offset})
 			})
 
+			It("reports MAX_DATA frames that would decrease the limit", func() {
+				connFC.EXPECT().State().Return(flowcontrol.WindowState{SendWindow: 0x1337})
+				tracer.EXPECT().DetectedPeerAnomaly(logging.PeerAnomalyFlowControlLimitDecreased, "MAX_DATA: 4096 (current limit: 4919)")
+				connFC.EXPECT().UpdateSendWindow(protocol.ByteCount(0x1000))
+				sess.handleMaxDataFrame(&wire.MaxDataFrame{MaximumData: 0x1000})
+			})
+
 			It("ignores MAX_STREAM_DATA frames for a closed stream", func() {
 				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(10)).Return(nil, nil)
 				Expect(sess.handleFrame(&wire.MaxStreamDataFrame{
@@ -252,6 +371,15 @@ var _ = Describe("Session", func() {
 			})
 		})
 
+		It("changes the stream limits", func() {
+			streamManager.EXPECT().SetMaxIncomingStreams(uint64(42))
+			Expect(sess.SetMaxIncomingStreams(42)).To(Succeed())
+			streamManager.EXPECT().SetMaxIncomingUniStreams(uint64(1337))
+			Expect(sess.SetMaxIncomingUniStreams(1337)).To(Succeed())
+			Expect(sess.SetMaxIncomingStreams(1<<60 + 1)).To(MatchError("invalid maximum number of incoming streams"))
+			Expect(sess.SetMaxIncomingUniStreams(1<<60 + 1)).To(MatchError("invalid maximum number of incoming unidirectional streams"))
+		})
+
 		Context("handling STOP_SENDING frames", func() {
 			It("passes the frame to the stream", func() {
 				f := &wire.StopSendingFrame{
@@ -294,10 +422,236 @@ var _ = Describe("Session", func() {
 
 		It("handles PATH_CHALLENGE frames", func() {
 			data := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+			tracer.EXPECT().ReceivedPathChallenge(data)
 			err := sess.handleFrame(&wire.PathChallengeFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).ToNot(HaveOccurred())
 			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
 			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PathResponseFrame{Data: data}}}))
+			Expect(sess.ConnectionStats().PathChallengesReceived).To(BeEquivalentTo(1))
+		})
+
+		Context("peer address changes", func() {
+			newAddr := &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1), Port: 4242}
+			var changes []AddressChange
+			var pn protocol.PacketNumber // the packet number of the last packet sent
+
+			BeforeEach(func() {
+				changes = nil
+				pn = 0
+				sess.config.PeerAddressChanged = func(_ Session, c AddressChange) { changes = append(changes, c) }
+				sess.handshakeConfirmed = true
+				sess.receivedFirstPacket = true
+				tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			})
+
+			receivePacket := func(pn protocol.PacketNumber, frame wire.Frame, addr net.Addr) {
+				buf := &bytes.Buffer{}
+				Expect(frame.Write(buf, sess.version)).To(Succeed())
+				Expect(sess.handleUnpackedPacket(&unpackedPacket{
+					packetNumber:    pn,
+					encryptionLevel: protocol.Encryption1RTT,
+					hdr:             &wire.ExtendedHeader{PacketNumber: pn},
+					data:            buf.Bytes(),
+				}, protocol.ECNNon, time.Now(), addr, protocol.ByteCount(buf.Len()))).To(Succeed())
+			}
+
+			// sendPathChallenge sends the packet containing the PATH_CHALLENGE, and returns the PATH_CHALLENGE.
+			sendPathChallenge := func(expectedSize protocol.ByteCount) ackhandler.Frame {
+				sender := NewMockSender(mockCtrl)
+				sess.sendQueue = sender
+				sender.EXPECT().Send(gomock.Any())
+				tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				var challenge ackhandler.Frame
+				packer.EXPECT().PackPathProbePacket(gomock.Any(), expectedSize).DoAndReturn(func(f ackhandler.Frame, _ protocol.ByteCount) (*packedPacket, error) {
+					challenge = f
+					pn++
+					return getPacket(pn), nil
+				})
+				sent, err := sess.maybeSendPathChallenge(time.Now())
+				ExpectWithOffset(1, err).ToNot(HaveOccurred())
+				ExpectWithOffset(1, sent).To(BeTrue())
+				ExpectWithOffset(1, challenge.Frame).To(BeAssignableToTypeOf(&wire.PathChallengeFrame{}))
+				return challenge
+			}
+
+			getPathChallenge := func() [8]byte {
+				// make sure that the packet isn't limited by the amplification limit
+				sess.peerAddrValidation.bytesReceived = protocol.MinInitialPacketSize
+				return sendPathChallenge(protocol.MinInitialPacketSize).Frame.(*wire.PathChallengeFrame).Data
+			}
+
+			It("switches to the new address, and validates it", func() {
+				sess.rttStats.UpdateRTT(time.Second, 0, time.Now())
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				var challenge [8]byte
+				tracer.EXPECT().StartedPathValidation(newAddr, gomock.Any()).Do(func(_ net.Addr, c [8]byte) { challenge = c })
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				Expect(changes).To(Equal([]AddressChange{{OldAddr: remoteAddr, NewAddr: newAddr, State: AddressValidationPending}}))
+				data := getPathChallenge()
+				Expect(data).To(Equal(challenge))
+				Expect(sess.ConnectionStats().PathChallengesSent).To(BeEquivalentTo(1))
+				time.Sleep(scaleDuration(5 * time.Millisecond))
+				var duration time.Duration
+				tracer.EXPECT().FinishedPathValidation(newAddr, true, gomock.Any()).Do(func(_ net.Addr, _ bool, d time.Duration) { duration = d })
+				// a PATH_RESPONSE with the wrong data is ignored
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(changes).To(HaveLen(1))
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(changes).To(HaveLen(2))
+				Expect(changes[1]).To(Equal(AddressChange{OldAddr: remoteAddr, NewAddr: newAddr, State: AddressValidationSucceeded}))
+				Expect(duration).To(BeNumerically(">=", scaleDuration(5*time.Millisecond)))
+				stats := sess.ConnectionStats()
+				Expect(stats.PathValidationsSucceeded).To(BeEquivalentTo(1))
+				Expect(stats.PathValidationsFailed).To(BeZero())
+				Expect(stats.LastPathValidationTime).To(Equal(duration))
+				// the RTT estimate of the old path was discarded
+				Expect(sess.rttStats.SmoothedRTT()).To(BeZero())
+				// the validation was completed, so it doesn't time out
+				sess.maybeAbandonPeerAddressValidation(time.Now().Add(time.Hour))
+				Expect(changes).To(HaveLen(2))
+			})
+
+			It("pads the packet containing the PATH_CHALLENGE, without exceeding the amplification limit", func() {
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				tracer.EXPECT().StartedPathValidation(newAddr, gomock.Any())
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				v := sess.peerAddrValidation
+				v.bytesReceived = 100
+				v.bytesSent = 42
+				sendPathChallenge(3*100 - 42)
+				Expect(v.bytesSent).To(Equal(protocol.ByteCount(42 + 6))) // the packet returned by the mock packer has a length of 6
+				// the PATH_CHALLENGE is only sent once
+				sent, err := sess.maybeSendPathChallenge(time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sent).To(BeFalse())
+				Expect(sess.ConnectionStats().PathChallengesSent).To(BeEquivalentTo(1))
+			})
+
+			It("sends a PATH_CHALLENGE with new data when the PATH_CHALLENGE is lost", func() {
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				var firstChallenge [8]byte
+				tracer.EXPECT().StartedPathValidation(newAddr, gomock.Any()).Do(func(_ net.Addr, c [8]byte) { firstChallenge = c })
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				sess.peerAddrValidation.bytesReceived = protocol.MinInitialPacketSize
+				challenge := sendPathChallenge(protocol.MinInitialPacketSize)
+				Expect(challenge.Frame.(*wire.PathChallengeFrame).Data).To(Equal(firstChallenge))
+				Expect(challenge.OnLost).ToNot(BeNil())
+				challenge.OnLost(challenge.Frame)
+				challenge = sendPathChallenge(protocol.MinInitialPacketSize)
+				Expect(challenge.Frame.(*wire.PathChallengeFrame).Data).ToNot(Equal(firstChallenge))
+				Expect(sess.ConnectionStats().PathChallengesSent).To(BeEquivalentTo(2))
+				// a response to the first PATH_CHALLENGE validates the address as well
+				tracer.EXPECT().FinishedPathValidation(newAddr, true, gomock.Any())
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: firstChallenge}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(changes).To(HaveLen(2))
+				Expect(changes[1].State).To(Equal(AddressValidationSucceeded))
+				// once the address is validated, lost PATH_CHALLENGEs are not sent again
+				challenge.OnLost(challenge.Frame)
+				sent, err := sess.maybeSendPathChallenge(time.Now())
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sent).To(BeFalse())
+			})
+
+			It("keeps the RTT estimate, if only the peer's port changed", func() {
+				sess.rttStats.UpdateRTT(time.Second, 0, time.Now())
+				newPort := &net.UDPAddr{IP: remoteAddr.IP, Port: remoteAddr.Port + 1}
+				mconn.EXPECT().SetRemoteAddr(newPort)
+				tracer.EXPECT().StartedPathValidation(newPort, gomock.Any())
+				receivePacket(10, &wire.PingFrame{}, newPort)
+				data := getPathChallenge()
+				tracer.EXPECT().FinishedPathValidation(newPort, true, gomock.Any())
+				Expect(sess.handleFrame(&wire.PathResponseFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(changes).To(HaveLen(2))
+				Expect(sess.rttStats.SmoothedRTT()).To(Equal(time.Second))
+			})
+
+			It("reverts to the old address, if the validation times out", func() {
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				tracer.EXPECT().StartedPathValidation(newAddr, gomock.Any())
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				Expect(changes).To(HaveLen(1))
+				sess.maybeAbandonPeerAddressValidation(time.Now())
+				Expect(changes).To(HaveLen(1))
+				mconn.EXPECT().SetRemoteAddr(remoteAddr)
+				var duration time.Duration
+				tracer.EXPECT().FinishedPathValidation(newAddr, false, gomock.Any()).Do(func(_ net.Addr, _ bool, d time.Duration) { duration = d })
+				sess.maybeAbandonPeerAddressValidation(time.Now().Add(time.Hour))
+				Expect(changes).To(HaveLen(2))
+				Expect(changes[1]).To(Equal(AddressChange{OldAddr: remoteAddr, NewAddr: newAddr, State: AddressValidationFailed}))
+				Expect(duration).To(BeNumerically("~", time.Hour, time.Second))
+				stats := sess.ConnectionStats()
+				Expect(stats.PathValidationsFailed).To(BeEquivalentTo(1))
+				Expect(stats.PathValidationsSucceeded).To(BeZero())
+			})
+
+			It("doesn't switch addresses for reordered packets", func() {
+				receivePacket(10, &wire.PingFrame{}, remoteAddr)
+				receivePacket(9, &wire.PingFrame{}, newAddr)
+				Expect(changes).To(BeEmpty())
+				Expect(sess.framer.HasData()).To(BeFalse())
+			})
+
+			It("doesn't switch addresses for packets that only contain probing frames", func() {
+				tracer.EXPECT().ReceivedPathChallenge(gomock.Any())
+				receivePacket(10, &wire.PathChallengeFrame{}, newAddr)
+				Expect(changes).To(BeEmpty())
+				frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PathResponseFrame{}}}))
+			})
+
+			It("doesn't switch addresses before the handshake is confirmed", func() {
+				sess.handshakeConfirmed = false
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				Expect(changes).To(BeEmpty())
+			})
+
+			It("asks the application if the address change is allowed", func() {
+				var checked []AddressChange
+				sess.config.CheckPeerAddressChange = func(_ Session, c AddressChange) PeerAddressChangeDecision {
+					checked = append(checked, c)
+					return PeerAddressChangeAccept
+				}
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				tracer.EXPECT().StartedPathValidation(newAddr, gomock.Any())
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				Expect(checked).To(Equal([]AddressChange{{OldAddr: remoteAddr, NewAddr: newAddr, State: AddressValidationPending}}))
+				Expect(changes).To(HaveLen(1))
+				// the amplification limit applies to accepted changes as well
+				sess.peerAddrValidation.bytesSent = 3 * sess.peerAddrValidation.bytesReceived
+				Expect(sess.peerAddrValidation.isAmplificationLimited()).To(BeTrue())
+			})
+
+			It("closes the connection if the application refuses the address change", func() {
+				sess.config.CheckPeerAddressChange = func(Session, AddressChange) PeerAddressChangeDecision { return PeerAddressChangeRefuse }
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				Expect(changes).To(BeEmpty())
+				Expect(sess.peerAddrValidation).To(BeNil())
+				Expect(sess.framer.HasData()).To(BeFalse())
+				var closeErr closeError
+				Expect(sess.closeChan).To(Receive(&closeErr))
+				Expect(closeErr.err).To(MatchError(&qerr.TransportError{ErrorCode: qerr.NoViablePathError, ErrorMessage: "peer address change refused"}))
+			})
+
+			It("limits the amount of data sent to the new address until it is validated", func() {
+				mconn.EXPECT().SetRemoteAddr(newAddr)
+				tracer.EXPECT().StartedPathValidation(newAddr, gomock.Any())
+				tracer.EXPECT().ReceivedPathChallenge(gomock.Any())
+				receivePacket(10, &wire.PingFrame{}, newAddr)
+				v := sess.peerAddrValidation
+				Expect(v.bytesReceived).To(Equal(protocol.ByteCount(1)))
+				Expect(v.isAmplificationLimited()).To(BeFalse())
+				v.bytesSent = 3
+				Expect(v.isAmplificationLimited()).To(BeTrue())
+				// the sent packet handler isn't even asked for the send mode
+				sess.sentPacketHandler = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+				Expect(sess.sendPackets()).To(Succeed())
+				// packets received from the old address don't increase the budget
+				receivePacket(11, &wire.PingFrame{}, remoteAddr)
+				Expect(v.isAmplificationLimited()).To(BeTrue())
+				receivePacket(12, &wire.PathChallengeFrame{}, newAddr)
+				Expect(v.bytesReceived).To(Equal(protocol.ByteCount(1 + 9)))
+				Expect(v.isAmplificationLimited()).To(BeFalse())
+			})
 		})
 
 		It("rejects NEW_TOKEN frames", func() {
@@ -322,6 +676,32 @@ var _ = Describe("Session", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("handles BDP_FRAMEs", func() {
+			var info BDPInfo
+			sess.config.BDPFrameReceived = func(s Session, i BDPInfo) {
+				Expect(s).To(Equal(sess))
+				info = i
+			}
+			err := sess.handleFrame(&wire.BDPFrame{
+				Lifetime:      time.Hour,
+				SavedCapacity: 1337,
+				SavedRTT:      42 * time.Millisecond,
+				EndpointToken: []byte("token"),
+			}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info).To(Equal(BDPInfo{
+				Lifetime:      time.Hour,
+				SavedCapacity: 1337,
+				SavedRTT:      42 * time.Millisecond,
+				EndpointToken: []byte("token"),
+			}))
+		})
+
+		It("ignores BDP_FRAMEs, if no callback is set", func() {
+			err := sess.handleFrame(&wire.BDPFrame{SavedCapacity: 1337}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
 		It("handles CONNECTION_CLOSE frames, with a transport error code", func() {
 			expectedErr := &qerr.TransportError{
 				Remote:       true,
@@ -386,6 +766,35 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
+		It("handles CONNECTION_CLOSE frames, with a payload", func() {
+			testErr := &qerr.ApplicationError{
+				Remote:       true,
+				ErrorCode:    0x1337,
+				ErrorMessage: "foobar",
+				Payload:      []byte("key=value"),
+			}
+			streamManager.EXPECT().CloseWithError(testErr)
+			sessionRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(testErr),
+				tracer.EXPECT().Close(),
+			)
+
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				Expect(sess.run()).To(MatchError(testErr))
+			}()
+			ccf := &wire.ConnectionCloseFrame{
+				ErrorCode:          0x1337,
+				ReasonPhrase:       qerr.EncodeReasonPhrase("foobar", []byte("key=value")),
+				IsApplicationError: true,
+			}
+			Expect(sess.handleFrame(ccf, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
 		It("errors on HANDSHAKE_DONE frames", func() {
 			Expect(sess.handleHandshakeDoneFrame()).To(MatchError(&qerr.TransportError{
 				ErrorCode:    qerr.ProtocolViolation,
@@ -399,6 +808,257 @@ var _ = Describe("Session", func() {
 		Expect(sess.GetVersion()).To(Equal(protocol.VersionNumber(4242)))
 	})
 
+	It("reports the number of spurious losses", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sph.EXPECT().SpuriousLosses().Return(uint64(7))
+		sph.EXPECT().Stats()
+		sess.sentPacketHandler = sph
+		Expect(sess.ConnectionStats().SpuriousLosses).To(BeEquivalentTo(7))
+	})
+
+	It("reports the RTT, the congestion controller state and the packets sent", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		sph.EXPECT().SpuriousLosses()
+		sph.EXPECT().Stats().Return(ackhandler.Stats{
+			SmoothedRTT:      100 * time.Millisecond,
+			RTTVariance:      10 * time.Millisecond,
+			MinRTT:           80 * time.Millisecond,
+			LatestRTT:        90 * time.Millisecond,
+			CongestionWindow: 12345,
+			BytesInFlight:    1234,
+			Initial:          ackhandler.PacketNumberSpaceStats{PacketsSent: 2, PacketsAcked: 1, PacketsLost: 1, BytesSent: 2400},
+			Handshake:        ackhandler.PacketNumberSpaceStats{PacketsSent: 3, PacketsAcked: 3, BytesSent: 3000},
+			AppData:          ackhandler.PacketNumberSpaceStats{PacketsSent: 100, PacketsAcked: 90, PacketsLost: 5, BytesSent: 100000},
+		})
+		sess.sentPacketHandler = sph
+		stats := sess.ConnectionStats()
+		Expect(stats.SmoothedRTT).To(Equal(100 * time.Millisecond))
+		Expect(stats.RTTVariance).To(Equal(10 * time.Millisecond))
+		Expect(stats.MinRTT).To(Equal(80 * time.Millisecond))
+		Expect(stats.LatestRTT).To(Equal(90 * time.Millisecond))
+		Expect(stats.CongestionWindow).To(BeEquivalentTo(12345))
+		Expect(stats.BytesInFlight).To(BeEquivalentTo(1234))
+		Expect(stats.PacketsAcked).To(BeEquivalentTo(94))
+		Expect(stats.PacketsLost).To(BeEquivalentTo(6))
+		Expect(stats.Initial).To(Equal(PacketNumberSpaceStats{PacketsSent: 2, PacketsAcked: 1, PacketsLost: 1, BytesSent: 2400}))
+		Expect(stats.Handshake).To(Equal(PacketNumberSpaceStats{PacketsSent: 3, PacketsAcked: 3, BytesSent: 3000}))
+		Expect(stats.ApplicationData).To(Equal(PacketNumberSpaceStats{PacketsSent: 100, PacketsAcked: 90, PacketsLost: 5, BytesSent: 100000}))
+	})
+
+	It("reports the pacing state", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		next := time.Now().Add(time.Millisecond)
+		sph.EXPECT().PacerState().Return(congestion.PacerState{Budget: 1337, NextSendTime: next})
+		sess.sentPacketHandler = sph
+		sender := NewMockSender(mockCtrl)
+		sender.EXPECT().QueuedBytes().Return(protocol.ByteCount(4242))
+		sess.sendQueue = sender
+		Expect(sess.PacingState()).To(Equal(PacingState{
+			Budget:       1337,
+			NextSendTime: next,
+			QueuedBytes:  4242,
+		}))
+	})
+
+	It("passes the flow control window to the WindowUpdateStrategy", func() {
+		Expect(sess.windowUpdateFunc(false, 4)).To(BeNil())
+		var windows []FlowControlWindow
+		sess.config.WindowUpdateStrategy = WindowUpdateStrategyFunc(func(w FlowControlWindow) bool {
+			windows = append(windows, w)
+			return true
+		})
+		Expect(sess.windowUpdateFunc(false, 4)(flowcontrol.ReceiveWindow{BytesRead: 10, Offset: 100, Size: 50})).To(BeTrue())
+		Expect(sess.windowUpdateFunc(true, 0)(flowcontrol.ReceiveWindow{
+			BytesRead:       20,
+			Offset:          200,
+			Size:            150,
+			SmoothedRTT:     time.Second,
+			SinceLastUpdate: time.Minute,
+		})).To(BeTrue())
+		Expect(windows).To(Equal([]FlowControlWindow{
+			{StreamID: 4, BytesRead: 10, Offset: 100, Size: 50},
+			{IsConnection: true, BytesRead: 20, Offset: 200, Size: 150, SmoothedRTT: time.Second, SinceLastUpdate: time.Minute},
+		}))
+	})
+
+	It("only extends the stream receive window when credit is granted, if configured", func() {
+		sess.config.ManualStreamReceiveCredit = true
+		sess.peerParams = &wire.TransportParameters{}
+		window := protocol.ByteCount(sess.config.InitialStreamReceiveWindow)
+		fc := sess.newFlowController(3)
+		Expect(fc.UpdateHighestReceived(window, false)).To(Succeed())
+		fc.AddBytesRead(window)
+		Expect(fc.GetWindowUpdate()).To(BeZero())
+		fc.GrantCredit(1000)
+		Expect(fc.GetWindowUpdate()).To(Equal(window + 1000))
+	})
+
+	It("traces streams that are blocked by flow control", func() {
+		tracer.EXPECT().BlockedByFlowControl(protocol.StreamID(4), true)
+		sess.onStreamBlocked(4, true)
+	})
+
+	It("refuses to close with a too large payload", func() {
+		err := sess.CloseWithPayload(0x1337, "test error", make([]byte, protocol.MaxClosePayloadSize+1))
+		Expect(err).To(MatchError(ContainSubstring("close payload too large")))
+		Expect(sess.Context().Done()).ToNot(BeClosed())
+	})
+
+	Context("sending BDP_FRAMEs", func() {
+		info := BDPInfo{
+			Lifetime:      time.Hour,
+			SavedCapacity: 1337,
+			SavedRTT:      42 * time.Millisecond,
+			EndpointToken: []byte("token"),
+		}
+
+		It("refuses to send, if the extension is not enabled", func() {
+			sess.peerParams = &wire.TransportParameters{SupportsBDPFrames: true}
+			Expect(sess.SendBDPFrame(info)).To(MatchError("BDP_FRAME extension not enabled"))
+			Expect(sess.framer.HasData()).To(BeFalse())
+		})
+
+		It("refuses to send, if the peer doesn't support the extension", func() {
+			sess.config.EnableBDPFrames = true
+			Expect(sess.SendBDPFrame(info)).To(MatchError("peer doesn't support the BDP_FRAME extension"))
+			sess.peerParams = &wire.TransportParameters{}
+			Expect(sess.SendBDPFrame(info)).To(MatchError("peer doesn't support the BDP_FRAME extension"))
+			Expect(sess.framer.HasData()).To(BeFalse())
+		})
+
+		It("refuses to send too long endpoint tokens", func() {
+			sess.config.EnableBDPFrames = true
+			sess.peerParams = &wire.TransportParameters{SupportsBDPFrames: true}
+			i := info
+			i.EndpointToken = make([]byte, protocol.MaxBDPEndpointTokenLength+1)
+			Expect(sess.SendBDPFrame(i)).To(MatchError("endpoint token too long: 256 bytes (maximum 255)"))
+			Expect(sess.framer.HasData()).To(BeFalse())
+		})
+
+		It("refuses to send saved capacities that can't be encoded", func() {
+			sess.config.EnableBDPFrames = true
+			sess.peerParams = &wire.TransportParameters{SupportsBDPFrames: true}
+			i := info
+			i.SavedCapacity = quicvarint.Max + 1
+			Expect(sess.SendBDPFrame(i)).To(MatchError("invalid saved capacity"))
+			Expect(sess.framer.HasData()).To(BeFalse())
+		})
+
+		It("queues a BDP_FRAME", func() {
+			sess.config.EnableBDPFrames = true
+			sess.peerParams = &wire.TransportParameters{SupportsBDPFrames: true}
+			Expect(sess.SendBDPFrame(info)).To(Succeed())
+			frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+			Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.BDPFrame{
+				Lifetime:      time.Hour,
+				SavedCapacity: 1337,
+				SavedRTT:      42 * time.Millisecond,
+				EndpointToken: []byte("token"),
+			}}}))
+		})
+	})
+
+	Context("RESET_STREAM_AT", func() {
+		It("supports RESET_STREAM_AT, if it was enabled and the peer supports it", func() {
+			Expect(sess.supportsResetStreamAt()).To(BeFalse())
+			sess.storePeerSupportsResetStreamAt(&wire.TransportParameters{SupportsResetStreamAt: true})
+			Expect(sess.supportsResetStreamAt()).To(BeFalse())
+			sess.config.EnableResetStreamAt = true
+			Expect(sess.supportsResetStreamAt()).To(BeTrue())
+			sess.storePeerSupportsResetStreamAt(&wire.TransportParameters{})
+			Expect(sess.supportsResetStreamAt()).To(BeFalse())
+		})
+	})
+
+	It("removes completed streams from their stream group", func() {
+		group := sess.NewStreamGroup()
+		str := &groupableMockStream{MockStreamI: NewMockStreamI(mockCtrl)}
+		str.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+		Expect(group.AddStream(str)).To(Succeed())
+		Expect(group.Len()).To(Equal(1))
+		streamManager.EXPECT().DeleteStream(protocol.StreamID(4))
+		sess.onStreamCompleted(4)
+		Expect(group.Len()).To(BeZero())
+	})
+
+	It("passes the session to the ACK range filter", func() {
+		Expect(sess.ackRangeFilter()).To(BeNil())
+		var s Session
+		sess.config.AckRangeFilter = func(sess Session, r []AckRange) []AckRange {
+			s = sess
+			return r[1:]
+		}
+		Expect(sess.ackRangeFilter()([]wire.AckRange{{Smallest: 5, Largest: 6}, {Smallest: 1, Largest: 2}})).To(Equal([]wire.AckRange{{Smallest: 1, Largest: 2}}))
+		Expect(s).To(Equal(sess))
+	})
+
+	Context("in-flight packet snapshots", func() {
+		It("errors if snapshots are not enabled", func() {
+			_, err := sess.InFlightPackets(context.Background())
+			Expect(err).To(MatchError("packet history snapshots not enabled"))
+		})
+
+		It("returns a snapshot taken by the run loop", func() {
+			sess.config.EnablePacketHistorySnapshots = true
+			sess.inFlightPacketsRequests = make(chan chan<- []InFlightPacket)
+			sendTime := time.Now().Add(-time.Second)
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().InFlightPackets().Return([]ackhandler.Packet{{
+				PacketNumber:    1337,
+				EncryptionLevel: protocol.Encryption1RTT,
+				Length:          1200,
+				SendTime:        sendTime,
+				Frames: []ackhandler.Frame{
+					{Frame: &wire.PingFrame{}},
+					{Frame: &wire.StreamFrame{StreamID: 4, Offset: 10, Data: []byte("foobar")}},
+				},
+			}})
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendNone).AnyTimes()
+			sess.sentPacketHandler = sph
+			runErr := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				runErr <- sess.run()
+			}()
+			packets, err := sess.InFlightPackets(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(packets).To(Equal([]InFlightPacket{{
+				PacketNumber:    1337,
+				EncryptionLevel: Encryption1RTT,
+				Size:            1200,
+				SendTime:        sendTime,
+				Frames: []logging.Frame{
+					&logging.PingFrame{},
+					&logging.StreamFrame{StreamID: 4, Offset: 10, Length: 6},
+				},
+			}}))
+			// make the go routine return
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			sess.shutdown()
+			Eventually(runErr).Should(Receive())
+			_, err = sess.InFlightPackets(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns when the context is canceled", func() {
+			sess.config.EnablePacketHistorySnapshots = true
+			sess.inFlightPacketsRequests = make(chan chan<- []InFlightPacket)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := sess.InFlightPackets(ctx)
+			Expect(err).To(MatchError(context.Canceled))
+		})
+	})
+
 	Context("closing", func() {
 		var (
 			runErr         chan error
@@ -490,6 +1150,27 @@ var _ = Describe("Session", func() {
 			Expect(sess.Context().Done()).To(BeClosed())
 		})
 
+		It("closes with an error and a payload", func() {
+			runSession()
+			expectedErr := &qerr.ApplicationError{
+				ErrorCode:    0x1337,
+				ErrorMessage: "test error",
+				Payload:      []byte("key=value"),
+			}
+			streamManager.EXPECT().CloseWithError(expectedErr)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackApplicationClose(expectedErr).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(expectedErr),
+				tracer.EXPECT().Close(),
+			)
+			Expect(sess.CloseWithPayload(0x1337, "test error", []byte("key=value"))).To(Succeed())
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(sess.Context().Done()).To(BeClosed())
+		})
+
 		It("includes the frame type in transport-level close frames", func() {
 			runSession()
 			expectedErr := &qerr.TransportError{
@@ -557,6 +1238,35 @@ var _ = Describe("Session", func() {
 			Eventually(returned).Should(BeClosed())
 		})
 
+		It("records why the session was closed on the context", func() {
+			runSession()
+			expectedErr := &qerr.ApplicationError{
+				ErrorCode:    0x1337,
+				ErrorMessage: "test error",
+			}
+			ctx, cancel := context.WithCancel(sess.Context())
+			defer cancel()
+			Expect(SessionCloseCause(ctx)).To(Succeed())
+			canceledCtx, cancel2 := context.WithCancel(sess.Context())
+			cancel2()
+			Expect(SessionCloseCause(canceledCtx)).To(MatchError(context.Canceled))
+			Expect(SessionCloseCause(context.Background())).To(Succeed())
+			streamManager.EXPECT().CloseWithError(expectedErr).Do(func(error) {
+				// the context is only canceled after the streams were closed
+				Expect(sess.Context().Done()).ToNot(BeClosed())
+			})
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackApplicationClose(expectedErr).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			tracer.EXPECT().ClosedConnection(expectedErr)
+			tracer.EXPECT().Close()
+			sess.CloseWithError(0x1337, "test error")
+			Eventually(areSessionsRunning).Should(BeFalse())
+			Expect(SessionCloseCause(sess.Context())).To(Equal(expectedErr))
+			Expect(SessionCloseCause(ctx)).To(Equal(expectedErr))
+		})
+
 		It("doesn't send any more packets after receiving a CONNECTION_CLOSE", func() {
 			unpacker := NewMockUnpacker(mockCtrl)
 			sess.handshakeConfirmed = true
@@ -597,38 +1307,140 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
-		It("closes when the sendQueue encounters an error", func() {
+		It("recovers panics in the run loop, if a PanicHandler is set", func() {
+			var (
+				recovered interface{}
+				stack     []byte
+			)
+			handlerCalled := make(chan struct{})
+			sess.config.PanicHandler = func(s Session, r interface{}, st []byte) {
+				Expect(s).To(Equal(sess))
+				recovered = r
+				stack = st
+				close(handlerCalled)
+			}
+			unpacker := NewMockUnpacker(mockCtrl)
 			sess.handshakeConfirmed = true
-			conn := NewMockSendConn(mockCtrl)
-			conn.EXPECT().Write(gomock.Any()).Return(io.ErrClosedPipe).AnyTimes()
-			sess.sendQueue = newSendQueue(conn)
-			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
-			sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
-			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
-			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
-			// only expect a single SentPacket() call
-			sph.EXPECT().SentPacket(gomock.Any())
-			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
-			tracer.EXPECT().ClosedConnection(gomock.Any())
-			tracer.EXPECT().Close()
-			streamManager.EXPECT().CloseWithError(gomock.Any())
-			sessionRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
-			cryptoSetup.EXPECT().Close()
-			sess.sentPacketHandler = sph
-			p := getPacket(1)
-			packer.EXPECT().PackPacket().Return(p, nil)
-			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
-			runSession()
-			sess.queueControlFrame(&wire.PingFrame{})
-			sess.scheduleSending()
-			Eventually(sess.Context().Done()).Should(BeClosed())
-		})
-
-		It("closes due to a stateless reset", func() {
-			token := protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+			sess.unpacker = unpacker
 			runSession()
-			gomock.InOrder(
-				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
+			expectedErr := &qerr.TransportError{ErrorCode: qerr.InternalError}
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(*wire.Header, time.Time, []byte) {
+				panic("foobar")
+			})
+			streamManager.EXPECT().CloseWithError(expectedErr)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(expectedErr).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(expectedErr),
+				tracer.EXPECT().Close(),
+			)
+			buf := &bytes.Buffer{}
+			Expect((&wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}).Write(buf, sess.version)).To(Succeed())
+			sess.handlePacket(&receivedPacket{
+				rcvTime:    time.Now(),
+				remoteAddr: &net.UDPAddr{},
+				buffer:     getPacketBuffer(),
+				data:       buf.Bytes(),
+			})
+			Eventually(handlerCalled).Should(BeClosed())
+			Expect(recovered).To(Equal("foobar"))
+			Expect(string(stack)).To(ContainSubstring("handlePacketImpl"))
+			Eventually(sess.Context().Done()).Should(BeClosed())
+			expectedRunErr = expectedErr
+		})
+
+		It("recovers panics while closing the session after a panic", func() {
+			var recovered []interface{}
+			handlerCalled := make(chan struct{}, 2)
+			sess.config.PanicHandler = func(_ Session, r interface{}, _ []byte) {
+				recovered = append(recovered, r)
+				handlerCalled <- struct{}{}
+			}
+			expectedErr := &qerr.TransportError{ErrorCode: qerr.InternalError}
+			streamManager.EXPECT().CloseWithError(expectedErr)
+			packer.EXPECT().PackConnectionClose(expectedErr).Do(func(error) { panic("close panic") })
+			// the session is not replaced with a closed session, but its connection IDs are removed
+			sessionRunner.EXPECT().Remove(srcConnID)
+			sessionRunner.EXPECT().Remove(clientDestConnID).MaxTimes(1)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(expectedErr),
+				tracer.EXPECT().Close(),
+			)
+			cryptoSetup.EXPECT().RunHandshake().Do(func() { panic("foobar") })
+			go func() {
+				defer GinkgoRecover()
+				runErr <- sess.run()
+			}()
+			Eventually(sess.Context().Done()).Should(BeClosed())
+			Eventually(handlerCalled).Should(HaveLen(2))
+			Expect(recovered).To(Equal([]interface{}{"foobar", "close panic"}))
+			expectedRunErr = expectedErr
+		})
+
+		It("recovers panics during the handshake, if a PanicHandler is set", func() {
+			handlerCalled := make(chan struct{})
+			sess.config.PanicHandler = func(_ Session, r interface{}, _ []byte) {
+				Expect(r).To(Equal("foobar"))
+				close(handlerCalled)
+			}
+			expectedErr := &qerr.TransportError{ErrorCode: qerr.InternalError}
+			streamManager.EXPECT().CloseWithError(expectedErr)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(expectedErr).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any())
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(expectedErr),
+				tracer.EXPECT().Close(),
+			)
+			cryptoSetup.EXPECT().RunHandshake().Do(func() { panic("foobar") })
+			go func() {
+				defer GinkgoRecover()
+				runErr <- sess.run()
+			}()
+			Eventually(handlerCalled).Should(BeClosed())
+			Eventually(sess.Context().Done()).Should(BeClosed())
+			expectedRunErr = expectedErr
+		})
+
+		It("closes when the sendQueue encounters an error", func() {
+			sess.handshakeConfirmed = true
+			conn := NewMockSendConn(mockCtrl)
+			conn.EXPECT().Write(gomock.Any()).Return(io.ErrClosedPipe).AnyTimes()
+			sess.sendQueue = newSendQueue(conn)
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().GetLossDetectionTimeout().Return(time.Now().Add(time.Hour)).AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			// only expect a single SentPacket() call
+			sph.EXPECT().SentPacket(gomock.Any())
+			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			sessionRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+			cryptoSetup.EXPECT().Close()
+			sess.sentPacketHandler = sph
+			p := getPacket(1)
+			packer.EXPECT().PackPacket().Return(p, nil)
+			packer.EXPECT().PackPacket().Return(nil, nil).AnyTimes()
+			runSession()
+			sess.queueControlFrame(&wire.PingFrame{})
+			sess.scheduleSending()
+			Eventually(sess.Context().Done()).Should(BeClosed())
+		})
+
+		It("closes due to a stateless reset", func() {
+			token := protocol.StatelessResetToken{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+			runSession()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
 					var srErr *StatelessResetError
 					Expect(errors.As(e, &srErr)).To(BeTrue())
 					Expect(srErr.Token).To(Equal(token))
@@ -697,6 +1509,43 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(p)).To(BeFalse())
 		})
 
+		It("accounts for received datagrams", func() {
+			p := getPacket(&wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader: true,
+					Type:         protocol.PacketTypeHandshake,
+					Version:      sess.version,
+				},
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}, nil)
+			p.data[0] ^= 0x40 // unset the QUIC bit
+			sess.handlePacket(p)
+			stats := sess.ConnectionStats()
+			Expect(stats.DatagramsReceived).To(BeEquivalentTo(1))
+			Expect(stats.BytesReceived).To(BeEquivalentTo(p.Size()))
+			Expect(stats.ProcessingTimePerDatagram()).To(BeZero())
+		})
+
+		It("counts the packets received per packet number space", func() {
+			sess.receivedFirstPacket = true
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			for i, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionHandshake, protocol.Encryption1RTT, protocol.Encryption1RTT} {
+				pn := protocol.PacketNumber(i)
+				Expect(sess.handleUnpackedPacket(&unpackedPacket{
+					packetNumber:    pn,
+					encryptionLevel: encLevel,
+					hdr:             &wire.ExtendedHeader{Header: wire.Header{IsLongHeader: encLevel == protocol.EncryptionHandshake, Type: protocol.PacketTypeHandshake}, PacketNumber: pn},
+					data:            []byte{0x1}, // PING frame
+				}, protocol.ECNNon, time.Now(), remoteAddr, 100)).To(Succeed())
+			}
+			stats := sess.ConnectionStats()
+			Expect(stats.Initial.PacketsReceived).To(BeZero())
+			Expect(stats.Handshake.PacketsReceived).To(BeEquivalentTo(1))
+			Expect(stats.Handshake.BytesReceived).To(BeEquivalentTo(100))
+			Expect(stats.ApplicationData.PacketsReceived).To(BeEquivalentTo(2))
+			Expect(stats.ApplicationData.BytesReceived).To(BeEquivalentTo(200))
+		})
+
 		It("drops packets for which the version is unsupported", func() {
 			p := getPacket(&wire.ExtendedHeader{
 				Header: wire.Header{
@@ -808,6 +1657,102 @@ var _ = Describe("Session", func() {
 			Expect(sess.handlePacketImpl(packet)).To(BeFalse())
 		})
 
+		It("reports frequent duplicate packets", func() {
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.Encryption1RTT,
+				hdr:             hdr,
+				data:            []byte("foobar"),
+			}, nil).Times(protocol.DuplicatePacketAnomalyThreshold)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.Encryption1RTT).Return(true).Times(protocol.DuplicatePacketAnomalyThreshold)
+			sess.receivedPacketHandler = rph
+			tracer.EXPECT().DroppedPacket(logging.PacketType1RTT, gomock.Any(), logging.PacketDropDuplicate).Times(protocol.DuplicatePacketAnomalyThreshold)
+			for i := 0; i < protocol.DuplicatePacketAnomalyThreshold-1; i++ {
+				Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeFalse())
+			}
+			tracer.EXPECT().DetectedPeerAnomaly(logging.PeerAnomalyDuplicatePackets, fmt.Sprintf("received %d duplicate packets", protocol.DuplicatePacketAnomalyThreshold))
+			Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeFalse())
+		})
+
+		It("passes decrypted long header packets to the InspectLongHeaderPacket callback", func() {
+			var info *LongHeaderPacketInfo
+			sess.config.InspectLongHeaderPacket = func(i *LongHeaderPacketInfo) { info = i }
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: srcConnID,
+					SrcConnectionID:  destConnID,
+					Version:          sess.version,
+					Length:           2 + 6,
+				},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}
+			packet := getPacket(hdr, []byte("foobar"))
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+				packetNumber:    0x1337,
+				encryptionLevel: protocol.EncryptionHandshake,
+				hdr:             hdr,
+				data:            []byte{0}, // one PADDING frame
+			}, nil)
+			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+			rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x1337), protocol.EncryptionHandshake)
+			rph.EXPECT().ReceivedPacket(protocol.PacketNumber(0x1337), gomock.Any(), protocol.EncryptionHandshake, gomock.Any(), false)
+			sess.receivedPacketHandler = rph
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			Expect(sess.handlePacketImpl(packet)).To(BeTrue())
+			Expect(info).ToNot(BeNil())
+			Expect(info.Type).To(Equal(logging.PacketTypeHandshake))
+			Expect(info.DestConnectionID).To(Equal([]byte(srcConnID)))
+			Expect(info.SrcConnectionID).To(Equal([]byte(destConnID)))
+			Expect(info.Size).To(BeEquivalentTo(packet.Size()))
+			Expect(info.Decrypted).To(BeTrue())
+			Expect(info.PacketNumber).To(Equal(protocol.PacketNumber(0x1337)))
+		})
+
+		It("passes long header packets that can't be decrypted to the InspectLongHeaderPacket callback", func() {
+			var info *LongHeaderPacketInfo
+			sess.config.InspectLongHeaderPacket = func(i *LongHeaderPacketInfo) { info = i }
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+			p := getPacket(&wire.ExtendedHeader{
+				Header: wire.Header{
+					IsLongHeader:     true,
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: srcConnID,
+					Version:          sess.version,
+					Length:           2 + 6,
+				},
+				PacketNumber:    0x1337,
+				PacketNumberLen: protocol.PacketNumberLen2,
+			}, []byte("foobar"))
+			tracer.EXPECT().DroppedPacket(logging.PacketTypeHandshake, p.Size(), logging.PacketDropPayloadDecryptError)
+			Expect(sess.handlePacketImpl(p)).To(BeFalse())
+			Expect(info).ToNot(BeNil())
+			Expect(info.Type).To(Equal(logging.PacketTypeHandshake))
+			Expect(info.Decrypted).To(BeFalse())
+			Expect(info.PacketNumber).To(BeZero())
+		})
+
+		It("doesn't pass short header packets to the InspectLongHeaderPacket callback", func() {
+			sess.config.InspectLongHeaderPacket = func(*LongHeaderPacketInfo) { Fail("unexpected call") }
+			hdr := &wire.ExtendedHeader{
+				Header:          wire.Header{DestConnectionID: srcConnID},
+				PacketNumber:    0x37,
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+			tracer.EXPECT().DroppedPacket(logging.PacketType1RTT, gomock.Any(), logging.PacketDropPayloadDecryptError)
+			Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeFalse())
+		})
+
 		It("drops a packet when unpacking fails", func() {
 			unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -841,6 +1786,68 @@ var _ = Describe("Session", func() {
 			Eventually(sess.Context().Done()).Should(BeClosed())
 		})
 
+		Context("suspecting stateless resets", func() {
+			getUndecryptablePacket := func() *receivedPacket {
+				return getPacket(&wire.ExtendedHeader{
+					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumber:    0x42,
+					PacketNumberLen: protocol.PacketNumberLen2,
+				}, []byte("0123456789abcdefghij"))
+			}
+
+			BeforeEach(func() {
+				tracer.EXPECT().DroppedPacket(logging.PacketType1RTT, gomock.Any(), logging.PacketDropPayloadDecryptError).AnyTimes()
+			})
+
+			It("sends a PING when multiple 1-RTT packets in a row can't be decrypted", func() {
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed).Times(protocol.StatelessResetSuspicionThreshold + 1)
+				for i := 0; i < protocol.StatelessResetSuspicionThreshold-1; i++ {
+					Expect(sess.handlePacketImpl(getUndecryptablePacket())).To(BeFalse())
+				}
+				Expect(sess.framer.HasData()).To(BeFalse())
+				p := getUndecryptablePacket()
+				var token protocol.StatelessResetToken
+				copy(token[:], p.data[len(p.data)-16:])
+				tracer.EXPECT().SuspectedStatelessReset(token, protocol.StatelessResetSuspicionThreshold)
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+				frames, _ := sess.framer.AppendControlFrames(nil, 1000)
+				Expect(frames).To(Equal([]ackhandler.Frame{{Frame: &wire.PingFrame{}}}))
+				Expect(sess.stats.SuspectedStatelessResets).To(BeEquivalentTo(1))
+				// only send a single PING
+				Expect(sess.handlePacketImpl(getUndecryptablePacket())).To(BeFalse())
+				Expect(sess.framer.HasData()).To(BeFalse())
+				Expect(sess.stats.SuspectedStatelessResets).To(BeEquivalentTo(1))
+			})
+
+			It("resets the counter when a 1-RTT packet is decrypted", func() {
+				for i := 0; i < protocol.StatelessResetSuspicionThreshold-1; i++ {
+					unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+					Expect(sess.handlePacketImpl(getUndecryptablePacket())).To(BeFalse())
+				}
+				hdr := &wire.ExtendedHeader{
+					Header:          wire.Header{DestConnectionID: srcConnID},
+					PacketNumber:    0x37,
+					PacketNumberLen: protocol.PacketNumberLen1,
+				}
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(&unpackedPacket{
+					packetNumber:    0x37,
+					encryptionLevel: protocol.Encryption1RTT,
+					hdr:             hdr,
+					data:            []byte("foobar"),
+				}, nil)
+				rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
+				rph.EXPECT().IsPotentiallyDuplicate(protocol.PacketNumber(0x37), protocol.Encryption1RTT).Return(true)
+				sess.receivedPacketHandler = rph
+				tracer.EXPECT().DroppedPacket(logging.PacketType1RTT, gomock.Any(), logging.PacketDropDuplicate)
+				Expect(sess.handlePacketImpl(getPacket(hdr, nil))).To(BeFalse())
+				for i := 0; i < protocol.StatelessResetSuspicionThreshold-1; i++ {
+					unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+					Expect(sess.handlePacketImpl(getUndecryptablePacket())).To(BeFalse())
+				}
+				Expect(sess.framer.HasData()).To(BeFalse())
+			})
+		})
+
 		It("processes multiple received packets before sending one", func() {
 			sess.sessionCreationTime = time.Now()
 			var pn protocol.PacketNumber
@@ -872,6 +1879,10 @@ var _ = Describe("Session", func() {
 				sess.run()
 			}()
 			Consistently(sess.Context().Done()).ShouldNot(BeClosed())
+			// The time spent processing the packets is measured on the run loop.
+			stats := sess.ConnectionStats()
+			Expect(stats.ReceiveProcessingTime).ToNot(BeZero())
+			Expect(stats.ProcessingTimePerDatagram()).To(Equal(stats.ProcessingTime() / 3))
 
 			// make the go routine return
 			streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -1103,6 +2114,73 @@ var _ = Describe("Session", func() {
 			tracer.EXPECT().BufferedPacket(logging.PacketTypeHandshake)
 			Expect(sess.handlePacketImpl(packet)).To(BeFalse())
 			Expect(sess.undecryptablePackets).To(Equal([]*receivedPacket{packet}))
+			stats := sess.ConnectionStats()
+			Expect(stats.UndecryptablePacketsQueued).To(BeEquivalentTo(1))
+			Expect(stats.UndecryptableBytesQueued).To(BeEquivalentTo(packet.Size()))
+			Expect(stats.UndecryptablePacketsDropped).To(BeZero())
+		})
+
+		Context("limiting the number of undecryptable packets", func() {
+			getUndecryptablePacket := func(pn protocol.PacketNumber) *receivedPacket {
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrKeysNotYetAvailable)
+				return getPacket(&wire.ExtendedHeader{
+					Header: wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeHandshake,
+						DestConnectionID: destConnID,
+						SrcConnectionID:  srcConnID,
+						Length:           1,
+						Version:          sess.version,
+					},
+					PacketNumberLen: protocol.PacketNumberLen1,
+					PacketNumber:    pn,
+				}, nil)
+			}
+
+			BeforeEach(func() {
+				sess.handshakeComplete = false
+			})
+
+			It("drops packets when the maximum number of packets is queued", func() {
+				sess.config.MaxUndecryptablePackets = 2
+				tracer.EXPECT().BufferedPacket(logging.PacketTypeHandshake).Times(2)
+				p1 := getUndecryptablePacket(1)
+				Expect(sess.handlePacketImpl(p1)).To(BeFalse())
+				p2 := getUndecryptablePacket(2)
+				Expect(sess.handlePacketImpl(p2)).To(BeFalse())
+				p3 := getUndecryptablePacket(3)
+				tracer.EXPECT().DroppedPacket(logging.PacketTypeHandshake, p3.Size(), logging.PacketDropDOSPrevention)
+				tracer.EXPECT().UndecryptablePacketQueueFull(logging.PacketTypeHandshake, p3.Size(), 2, p1.Size()+p2.Size())
+				Expect(sess.handlePacketImpl(p3)).To(BeFalse())
+				Expect(sess.undecryptablePackets).To(Equal([]*receivedPacket{p1, p2}))
+				stats := sess.ConnectionStats()
+				Expect(stats.UndecryptablePacketsQueued).To(BeEquivalentTo(2))
+				Expect(stats.UndecryptableBytesQueued).To(BeEquivalentTo(p1.Size() + p2.Size()))
+				Expect(stats.UndecryptablePacketsDropped).To(BeEquivalentTo(1))
+			})
+
+			It("drops packets when the maximum number of bytes is queued", func() {
+				p1 := getUndecryptablePacket(1)
+				sess.config.MaxUndecryptableBytes = uint64(p1.Size()) + 1
+				tracer.EXPECT().BufferedPacket(logging.PacketTypeHandshake)
+				Expect(sess.handlePacketImpl(p1)).To(BeFalse())
+				p2 := getUndecryptablePacket(2)
+				tracer.EXPECT().DroppedPacket(logging.PacketTypeHandshake, p2.Size(), logging.PacketDropDOSPrevention)
+				tracer.EXPECT().UndecryptablePacketQueueFull(logging.PacketTypeHandshake, p2.Size(), 1, p1.Size())
+				Expect(sess.handlePacketImpl(p2)).To(BeFalse())
+				Expect(sess.undecryptablePackets).To(Equal([]*receivedPacket{p1}))
+				Expect(sess.ConnectionStats().UndecryptablePacketsDropped).To(BeEquivalentTo(1))
+			})
+
+			It("resets the queue statistics when the queue is cleared", func() {
+				tracer.EXPECT().BufferedPacket(logging.PacketTypeHandshake)
+				Expect(sess.handlePacketImpl(getUndecryptablePacket(1))).To(BeFalse())
+				Expect(sess.ConnectionStats().UndecryptablePacketsQueued).To(BeEquivalentTo(1))
+				sess.clearUndecryptablePackets()
+				stats := sess.ConnectionStats()
+				Expect(stats.UndecryptablePacketsQueued).To(BeZero())
+				Expect(stats.UndecryptableBytesQueued).To(BeZero())
+			})
 		})
 
 		Context("updating the remote address", func() {
@@ -1239,6 +2317,214 @@ var _ = Describe("Session", func() {
 		})
 	})
 
+	Context("injecting faults", func() {
+		var (
+			sender   *MockSender
+			injected []faultInjectionPacket
+		)
+
+		getDatagram := func() (*packetBuffer, []*packetContents) {
+			buffer := getPacketBuffer()
+			buffer.Data = append(buffer.Data, bytes.Repeat([]byte{'a'}, 10)...)
+			buffer.Data = append(buffer.Data, bytes.Repeat([]byte{'b'}, 20)...)
+			return buffer, []*packetContents{
+				{
+					header: &wire.ExtendedHeader{Header: wire.Header{IsLongHeader: true, Type: protocol.PacketTypeInitial, Version: protocol.VersionTLS}, PacketNumber: 3},
+					ack:    &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}},
+					length: 10,
+				},
+				{
+					header: &wire.ExtendedHeader{Header: wire.Header{IsLongHeader: true, Type: protocol.PacketTypeHandshake, Version: protocol.VersionTLS}, PacketNumber: 7},
+					frames: []ackhandler.Frame{{Frame: &wire.PingFrame{}}},
+					length: 20,
+				},
+			}
+		}
+
+		injectFaults := func(f func(faultInjectionPacket) packetFault) {
+			sess.config.faultInjector = faultInjectorFunc(func(p faultInjectionPacket) packetFault {
+				injected = append(injected, p)
+				return f(p)
+			})
+		}
+
+		BeforeEach(func() {
+			injected = nil
+			sender = NewMockSender(mockCtrl)
+			sess.sendQueue = sender
+		})
+
+		It("sends datagrams unmodified if no fault injector is set", func() {
+			buffer, packets := getDatagram()
+			sender.EXPECT().Send(buffer)
+			sess.sendDatagram(buffer, packets)
+			Expect(buffer.Data).To(HaveLen(30))
+		})
+
+		It("drops packets from coalesced datagrams", func() {
+			injectFaults(func(p faultInjectionPacket) packetFault {
+				return packetFault{Drop: p.Type == logging.PacketTypeInitial}
+			})
+			buffer, packets := getDatagram()
+			sender.EXPECT().Send(buffer)
+			sess.sendDatagram(buffer, packets)
+			Expect(buffer.Data).To(Equal(bytes.Repeat([]byte{'b'}, 20)))
+			Expect(injected).To(Equal([]faultInjectionPacket{
+				{Type: logging.PacketTypeInitial, PacketNumber: 3, Size: 10, HasAck: true},
+				{Type: logging.PacketTypeHandshake, PacketNumber: 7, Size: 20, IsAckEliciting: true},
+			}))
+		})
+
+		It("doesn't send anything if all packets are dropped", func() {
+			injectFaults(func(faultInjectionPacket) packetFault { return packetFault{Drop: true} })
+			buffer, packets := getDatagram()
+			sess.sendDatagram(buffer, packets)
+			Expect(injected).To(HaveLen(2))
+		})
+
+		It("corrupts packets", func() {
+			injectFaults(func(p faultInjectionPacket) packetFault {
+				return packetFault{Corrupt: p.Type == logging.PacketTypeInitial}
+			})
+			buffer, packets := getDatagram()
+			sender.EXPECT().Send(buffer)
+			sess.sendDatagram(buffer, packets)
+			Expect(buffer.Data).To(HaveLen(30))
+			Expect(buffer.Data[:9]).To(Equal(bytes.Repeat([]byte{'a'}, 9)))
+			Expect(buffer.Data[9]).To(Equal(byte('a') ^ 0xff))
+			Expect(buffer.Data[10:]).To(Equal(bytes.Repeat([]byte{'b'}, 20)))
+		})
+
+		It("duplicates packets", func() {
+			injectFaults(func(p faultInjectionPacket) packetFault {
+				return packetFault{Duplicate: p.Type == logging.PacketTypeHandshake}
+			})
+			buffer, packets := getDatagram()
+			buffer.dscp = 42
+			sender.EXPECT().Send(buffer)
+			sess.sendDatagram(buffer, packets)
+			Expect(buffer.Data).To(HaveLen(30))
+			// the duplicate is sent by the run loop, via the send queue
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any()).Do(func(b *packetBuffer) {
+				Expect(b.Data).To(Equal(bytes.Repeat([]byte{'b'}, 20)))
+				Expect(b.dscp).To(BeEquivalentTo(42))
+			})
+			sess.handleDelayedPackets(time.Now())
+			Expect(sess.delayedPackets).To(BeEmpty())
+		})
+
+		It("delays packets", func() {
+			injectFaults(func(p faultInjectionPacket) packetFault {
+				if p.Type == logging.PacketTypeHandshake {
+					return packetFault{Delay: time.Hour}
+				}
+				return packetFault{}
+			})
+			buffer, packets := getDatagram()
+			sender.EXPECT().Send(buffer)
+			sess.sendDatagram(buffer, packets)
+			Expect(buffer.Data).To(Equal(bytes.Repeat([]byte{'a'}, 10)))
+			sender.EXPECT().WouldBlock().AnyTimes()
+			Expect(sess.nextDelayedPacketTime()).To(BeTemporally("~", time.Now().Add(time.Hour), time.Second))
+			sess.handleDelayedPackets(time.Now())
+			Expect(sess.delayedPackets).To(HaveLen(1))
+			sender.EXPECT().Send(gomock.Any()).Do(func(b *packetBuffer) {
+				Expect(b.Data).To(Equal(bytes.Repeat([]byte{'b'}, 20)))
+			})
+			sess.handleDelayedPackets(time.Now().Add(time.Hour))
+			Expect(sess.delayedPackets).To(BeEmpty())
+			Expect(sess.nextDelayedPacketTime()).To(BeZero())
+		})
+
+		It("doesn't send delayed packets while the send queue is full", func() {
+			injectFaults(func(p faultInjectionPacket) packetFault { return packetFault{Delay: time.Millisecond} })
+			buffer, packets := getDatagram()
+			sess.sendDatagram(buffer, packets)
+			Expect(sess.delayedPackets).To(HaveLen(2))
+			sender.EXPECT().WouldBlock().Return(true).AnyTimes()
+			Expect(sess.nextDelayedPacketTime()).To(BeZero())
+			sess.handleDelayedPackets(time.Now().Add(time.Second))
+			Expect(sess.delayedPackets).To(HaveLen(2))
+			sess.discardDelayedPackets()
+			Expect(sess.delayedPackets).To(BeEmpty())
+		})
+
+		Context("for received packets", func() {
+			var unpacker *MockUnpacker
+
+			BeforeEach(func() {
+				unpacker = NewMockUnpacker(mockCtrl)
+				sess.unpacker = unpacker
+				tracer.EXPECT().DroppedPacket(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			})
+
+			getPacket := func() *receivedPacket {
+				buf := &bytes.Buffer{}
+				Expect((&wire.ExtendedHeader{
+					Header: wire.Header{
+						IsLongHeader:     true,
+						Type:             protocol.PacketTypeHandshake,
+						DestConnectionID: srcConnID,
+						Version:          sess.version,
+						Length:           2 + 6,
+					},
+					PacketNumber:    0x1337,
+					PacketNumberLen: protocol.PacketNumberLen2,
+				}).Write(buf, sess.version)).To(Succeed())
+				buf.WriteString("foobar")
+				b := getPacketBuffer()
+				b.Data = append(b.Data, buf.Bytes()...)
+				return &receivedPacket{data: b.Data, buffer: b, rcvTime: time.Now()}
+			}
+
+			injectReceiveFaults := func(f func(faultInjectionPacket) packetFault) {
+				sess.config.faultInjector = &faultInjectorFuncs{
+					received: func(p faultInjectionPacket) packetFault {
+						injected = append(injected, p)
+						return f(p)
+					},
+				}
+			}
+
+			It("drops packets", func() {
+				injectReceiveFaults(func(faultInjectionPacket) packetFault { return packetFault{Drop: true} })
+				p := getPacket()
+				Expect(sess.handlePacketImpl(p)).To(BeFalse())
+				Expect(injected).To(Equal([]faultInjectionPacket{{
+					Type:         logging.PacketTypeHandshake,
+					PacketNumber: protocol.InvalidPacketNumber,
+					Size:         p.Size(),
+				}}))
+			})
+
+			It("corrupts packets", func() {
+				injectReceiveFaults(func(faultInjectionPacket) packetFault { return packetFault{Corrupt: true} })
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
+					Expect(data[len(data)-1]).To(Equal(byte('r') ^ 0xff))
+					return nil, handshake.ErrDecryptionFailed
+				})
+				Expect(sess.handlePacketImpl(getPacket())).To(BeFalse())
+			})
+
+			It("delays and duplicates packets", func() {
+				injectReceiveFaults(func(faultInjectionPacket) packetFault { return packetFault{Duplicate: true, Delay: time.Hour} })
+				Expect(sess.handlePacketImpl(getPacket())).To(BeFalse())
+				Expect(sess.delayedPackets).To(HaveLen(2))
+				sess.handleDelayedPackets(time.Now())
+				Expect(sess.delayedPackets).To(HaveLen(2))
+				// the faultInjector is only consulted once per packet
+				unpacker.EXPECT().Unpack(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ *wire.Header, _ time.Time, data []byte) (*unpackedPacket, error) {
+					Expect(data).To(HaveSuffix("foobar"))
+					return nil, handshake.ErrKeysDropped
+				}).Times(2)
+				sess.handleDelayedPackets(time.Now().Add(time.Hour))
+				Expect(sess.delayedPackets).To(BeEmpty())
+				Expect(injected).To(HaveLen(1))
+			})
+		})
+	})
+
 	Context("sending packets", func() {
 		var (
 			sessionDone chan struct{}
@@ -1402,6 +2688,8 @@ var _ = Describe("Session", func() {
 				It("sends a PING as a probe packet", func() {
 					sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
 					sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+					sph.EXPECT().SpuriousLosses().AnyTimes()
+					sph.EXPECT().Stats().AnyTimes()
 					sph.EXPECT().TimeUntilSend().AnyTimes()
 					sph.EXPECT().SendMode().Return(sendMode)
 					sph.EXPECT().SendMode().Return(ackhandler.SendNone)
@@ -1421,7 +2709,121 @@ var _ = Describe("Session", func() {
 					// We're using a mock packet packer in this test.
 					// We therefore need to test separately that the PING was actually queued.
 					Expect(getFrame(1000)).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+					Expect(sess.ConnectionStats().ProbePacketsSent).To(BeEquivalentTo(1))
+					Expect(sess.ConnectionStats().PingProbePacketsSent).To(BeEquivalentTo(1))
+				})
+
+				It("sends new data as a probe packet, if configured", func() {
+					sess.config.ProbePolicy = ProbeNewData
+					sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+					sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+					sph.EXPECT().SpuriousLosses().AnyTimes()
+					sph.EXPECT().Stats().AnyTimes()
+					sph.EXPECT().TimeUntilSend().AnyTimes()
+					sph.EXPECT().SendMode().Return(sendMode)
+					sph.EXPECT().SendMode().Return(ackhandler.SendNone)
+					p := getPacket(123)
+					p.frames = []ackhandler.Frame{{Frame: &wire.MaxDataFrame{MaximumData: 1337}}}
+					packer.EXPECT().MaybePackProbePacket(encLevel).Return(p, nil)
+					sph.EXPECT().SentPacket(gomock.Any())
+					sess.sentPacketHandler = sph
+					runSession()
+					sent := make(chan struct{})
+					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
+					sess.scheduleSending()
+					Eventually(sent).Should(BeClosed())
+					Expect(sess.ConnectionStats().ProbePacketsSent).To(BeEquivalentTo(1))
+					Expect(sess.ConnectionStats().PingProbePacketsSent).To(BeZero())
 				})
+
+				It("sends a PING as a probe packet, if configured to send new data, but the packet only contains an ACK", func() {
+					sess.config.ProbePolicy = ProbeNewData
+					sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+					sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+					sph.EXPECT().SpuriousLosses().AnyTimes()
+					sph.EXPECT().Stats().AnyTimes()
+					sph.EXPECT().TimeUntilSend().AnyTimes()
+					sph.EXPECT().SendMode().Return(sendMode)
+					sph.EXPECT().SendMode().Return(ackhandler.SendNone)
+					ackPacket := getPacket(123)
+					ackPacket.ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+					p := getPacket(124)
+					queuedFrame := make(chan wire.Frame, 1)
+					gomock.InOrder(
+						packer.EXPECT().MaybePackProbePacket(encLevel).Return(ackPacket, nil),
+						sph.EXPECT().QueueProbePacket(encLevel).Return(false),
+						// We're using a mock packet packer in this test.
+						// Check that the PING was queued when the probe packet is packed.
+						packer.EXPECT().MaybePackProbePacket(encLevel).DoAndReturn(func(protocol.EncryptionLevel) (*packedPacket, error) {
+							queuedFrame <- getFrame(1000)
+							return p, nil
+						}),
+					)
+					sph.EXPECT().SentPacket(gomock.Any()).Times(2)
+					sess.sentPacketHandler = sph
+					runSession()
+					sent := make(chan struct{}, 2)
+					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { sent <- struct{}{} }).Times(2)
+					tracer.EXPECT().SentPacket(ackPacket.header, ackPacket.length, ackPacket.ack, gomock.Any())
+					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
+					sess.scheduleSending()
+					Eventually(sent).Should(Receive())
+					Eventually(sent).Should(Receive())
+					var frame wire.Frame
+					Expect(queuedFrame).To(Receive(&frame))
+					Expect(frame).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+					Expect(sess.ConnectionStats().ProbePacketsSent).To(BeEquivalentTo(1))
+					Expect(sess.ConnectionStats().PingProbePacketsSent).To(BeEquivalentTo(1))
+				})
+
+				It("retransmits data in a probe packet, if configured to send new data, but there's no new data", func() {
+					sess.config.ProbePolicy = ProbeNewData
+					sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+					sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+					sph.EXPECT().TimeUntilSend().AnyTimes()
+					sph.EXPECT().SendMode().Return(sendMode)
+					sph.EXPECT().SendMode().Return(ackhandler.SendNone)
+					p := getPacket(123)
+					gomock.InOrder(
+						packer.EXPECT().MaybePackProbePacket(encLevel),
+						sph.EXPECT().QueueProbePacket(encLevel).Return(true),
+						packer.EXPECT().MaybePackProbePacket(encLevel).Return(p, nil),
+					)
+					sph.EXPECT().SentPacket(gomock.Any())
+					sess.sentPacketHandler = sph
+					runSession()
+					sent := make(chan struct{})
+					sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+					tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
+					sess.scheduleSending()
+					Eventually(sent).Should(BeClosed())
+				})
+
+				if encLevel == protocol.Encryption1RTT {
+					It("sends a PING as a probe packet, if configured", func() {
+						sess.config.ProbePolicy = ProbePing
+						sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+						sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+						sph.EXPECT().SpuriousLosses().AnyTimes()
+						sph.EXPECT().Stats().AnyTimes()
+						sph.EXPECT().TimeUntilSend().AnyTimes()
+						sph.EXPECT().SendMode().Return(sendMode)
+						sph.EXPECT().SendMode().Return(ackhandler.SendNone)
+						p := getPacket(123)
+						packer.EXPECT().MaybePackProbePacket(encLevel).Return(p, nil)
+						sph.EXPECT().SentPacket(gomock.Any())
+						sess.sentPacketHandler = sph
+						runSession()
+						sent := make(chan struct{})
+						sender.EXPECT().Send(gomock.Any()).Do(func(packet *packetBuffer) { close(sent) })
+						tracer.EXPECT().SentPacket(p.header, p.length, gomock.Any(), gomock.Any())
+						sess.scheduleSending()
+						Eventually(sent).Should(BeClosed())
+						Expect(getFrame(1000)).To(BeAssignableToTypeOf(&wire.PingFrame{}))
+						Expect(sess.ConnectionStats().PingProbePacketsSent).To(BeEquivalentTo(1))
+					})
+				}
 			})
 		}
 	})
@@ -1432,10 +2834,20 @@ var _ = Describe("Session", func() {
 			sender *MockSender
 		)
 
+		// connectionStats returns the stats without the processing times, which can't be predicted
+		connectionStats := func() ConnectionStats {
+			stats := sess.ConnectionStats()
+			stats.ReceiveProcessingTime = 0
+			stats.SendProcessingTime = 0
+			return stats
+		}
+
 		BeforeEach(func() {
 			tracer.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
 			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SpuriousLosses().AnyTimes()
+			sph.EXPECT().Stats().AnyTimes()
 			sess.handshakeConfirmed = true
 			sess.handshakeComplete = true
 			sess.sentPacketHandler = sph
@@ -1463,26 +2875,237 @@ var _ = Describe("Session", func() {
 			sph.EXPECT().HasPacingBudget().Return(true).Times(2)
 			sph.EXPECT().HasPacingBudget()
 			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
-			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(3)
-			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
-			packer.EXPECT().PackPacket().Return(getPacket(11), nil)
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(3)
+			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
+			packer.EXPECT().PackPacket().Return(getPacket(11), nil)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any()).Times(2)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.scheduleSending()
+			time.Sleep(50 * time.Millisecond) // make sure that only 2 packets are sent
+		})
+
+		It("sends multiple packets, when the pacer allows immediate sending", func() {
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(2)
+			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
+			packer.EXPECT().PackPacket().Return(nil, nil)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any())
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.scheduleSending()
+			time.Sleep(50 * time.Millisecond) // make sure that only 1 packet is sent
+		})
+
+		It("allows an ACK to be sent when pacing limited", func() {
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget()
+			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
+			packer.EXPECT().MaybePackAckPacket(gomock.Any()).Return(getPacket(10), nil)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any())
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.scheduleSending()
+			time.Sleep(50 * time.Millisecond) // make sure that only 1 packet is sent
+		})
+
+		It("sends a packet when pacing limited, after a stream was flushed", func() {
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget().Times(2)
+			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(2)
+			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any())
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.onStreamFlushed(4)
+			time.Sleep(50 * time.Millisecond) // make sure that only 1 packet is sent
+		})
+
+		It("keeps the flush request until a packet was sent", func() {
+			sendModeCalled := make(chan struct{})
+			gomock.InOrder(
+				sph.EXPECT().SendMode().DoAndReturn(func() ackhandler.SendMode {
+					close(sendModeCalled)
+					return ackhandler.SendNone
+				}),
+				sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(2),
+			)
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget().Times(2)
+			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
+			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any())
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.onStreamFlushed(4)
+			Eventually(sendModeCalled).Should(BeClosed())
+			// The first call to sendPackets didn't send anything.
+			// The flush request still allows sending a packet when pacing limited.
+			sess.scheduleSending()
+			time.Sleep(50 * time.Millisecond) // make sure that only 1 packet is sent
+			Expect(atomic.LoadInt32(&sess.flushRequested)).To(BeZero())
+		})
+
+		It("notifies writable streams after sending the packet", func() {
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			var sent bool
+			notified := make(chan struct{})
+			packer.EXPECT().PackPacket().DoAndReturn(func() (*packedPacket, error) {
+				// this is what the send stream does when its send buffer was emptied
+				sess.onStreamWritable(func() {
+					defer GinkgoRecover()
+					Expect(sent).To(BeTrue())
+					close(notified)
+				})
+				return getPacket(10), nil
+			})
+			packer.EXPECT().PackPacket().AnyTimes()
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any()).Do(func(*packetBuffer) { sent = true })
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.scheduleSending()
+			Eventually(notified).Should(BeClosed())
+		})
+
+		It("delays sending when only a small amount of stream data is pending", func() {
+			sess.config.CoalescingDelay = 100 * time.Millisecond
+			sess.config.CoalescingMinFill = 0.5
+			str := NewMockSendStreamI(mockCtrl)
+			str.EXPECT().pendingData().Return(protocol.ByteCount(100)).AnyTimes()
+			sess.framer = newFramer(streamManager, StreamSchedulerStrict, sess.version, nil)
+			streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(4)).Return(str, nil).AnyTimes()
+			packer.EXPECT().MaxPacketSize().Return(protocol.ByteCount(1200)).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sent := make(chan struct{})
+			packer.EXPECT().PackPacket().DoAndReturn(func() (*packedPacket, error) {
+				close(sent)
+				return getPacket(10), nil
+			})
+			packer.EXPECT().PackPacket().AnyTimes()
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any())
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			start := time.Now()
+			sess.framer.AddActiveStream(4)
+			sess.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+			Expect(time.Since(start)).To(BeNumerically(">=", 100*time.Millisecond))
+		})
+
+		It("doesn't delay sending when enough stream data is pending", func() {
+			sess.config.CoalescingDelay = time.Hour
+			sess.config.CoalescingMinFill = 0.5
+			str := NewMockSendStreamI(mockCtrl)
+			str.EXPECT().pendingData().Return(protocol.ByteCount(600)).AnyTimes()
+			sess.framer = newFramer(streamManager, StreamSchedulerStrict, sess.version, nil)
+			streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(4)).Return(str, nil).AnyTimes()
+			packer.EXPECT().MaxPacketSize().Return(protocol.ByteCount(1200)).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			sent := make(chan struct{})
+			packer.EXPECT().PackPacket().DoAndReturn(func() (*packedPacket, error) {
+				close(sent)
+				return getPacket(10), nil
+			})
+			packer.EXPECT().PackPacket().AnyTimes()
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any())
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
+				sess.run()
+			}()
+			sess.framer.AddActiveStream(4)
+			sess.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+		})
+
+		It("counts ACK-only packets", func() {
+			sph.EXPECT().SentPacket(gomock.Any())
+			sph.EXPECT().HasPacingBudget()
+			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
+			p := getPacket(10)
+			p.ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+			packer.EXPECT().MaybePackAckPacket(gomock.Any()).Return(p, nil)
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sender.EXPECT().Send(gomock.Any()).Times(2)
+			sender.EXPECT().Send(gomock.Any())
 			go func() {
 				defer GinkgoRecover()
 				cryptoSetup.EXPECT().RunHandshake().MaxTimes(1)
 				sess.run()
 			}()
 			sess.scheduleSending()
-			time.Sleep(50 * time.Millisecond) // make sure that only 2 packets are sent
+			ackLen := p.ack.Length(sess.version)
+			Eventually(connectionStats).Should(Equal(ConnectionStats{
+				PacketsSent:        1,
+				AckOnlyPacketsSent: 1,
+				BytesSent:          6,
+				AckFrameBytesSent:  uint64(ackLen),
+			}))
+			Expect(sess.ConnectionStats().AckOnlyPacketRatio()).To(Equal(1.0))
+			Eventually(func() time.Duration { return sess.ConnectionStats().SendProcessingTime }).ShouldNot(BeZero())
+			Expect(sess.ConnectionStats().GoodputEfficiency()).To(BeZero())
 		})
 
-		It("sends multiple packets, when the pacer allows immediate sending", func() {
+		It("counts the bytes sent, by frame type", func() {
 			sph.EXPECT().SentPacket(gomock.Any())
 			sph.EXPECT().HasPacingBudget().Return(true).AnyTimes()
-			sph.EXPECT().SendMode().Return(ackhandler.SendAny).Times(2)
-			packer.EXPECT().PackPacket().Return(getPacket(10), nil)
-			packer.EXPECT().PackPacket().Return(nil, nil)
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			p := getPacket(10)
+			p.length = 1000
+			p.ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
+			frames := []wire.Frame{
+				&wire.StreamFrame{StreamID: 4, Data: make([]byte, 500)},
+				&wire.DatagramFrame{Data: make([]byte, 200)},
+				&wire.MaxDataFrame{MaximumData: 1337},
+				&wire.MaxStreamDataFrame{StreamID: 4, MaximumStreamData: 1337},
+				&wire.PingFrame{},
+				&wire.PathResponseFrame{},
+				&wire.ResetStreamFrame{StreamID: 8, FinalSize: 42},
+				&wire.NewConnectionIDFrame{SequenceNumber: 1, ConnectionID: protocol.ConnectionID{1, 2, 3, 4}},
+			}
+			for _, f := range frames {
+				p.frames = append(p.frames, ackhandler.Frame{Frame: f})
+			}
+			packer.EXPECT().PackPacket().Return(p, nil)
+			packer.EXPECT().PackPacket().AnyTimes()
 			sender.EXPECT().WouldBlock().AnyTimes()
 			sender.EXPECT().Send(gomock.Any())
 			go func() {
@@ -1491,15 +3114,32 @@ var _ = Describe("Session", func() {
 				sess.run()
 			}()
 			sess.scheduleSending()
-			time.Sleep(50 * time.Millisecond) // make sure that only 1 packet is sent
+			v := sess.version
+			Eventually(connectionStats).Should(Equal(ConnectionStats{
+				PacketsSent:                  1,
+				BytesSent:                    1000,
+				PayloadBytesSent:             700,
+				AckFrameBytesSent:            uint64(p.ack.Length(v)),
+				FlowControlFrameBytesSent:    uint64(frames[2].Length(v) + frames[3].Length(v)),
+				PingFrameBytesSent:           1,
+				PathValidationFrameBytesSent: uint64(frames[5].Length(v)),
+				OtherControlFrameBytesSent:   uint64(frames[6].Length(v) + frames[7].Length(v)),
+			}))
+			Expect(sess.ConnectionStats().GoodputEfficiency()).To(Equal(0.7))
 		})
 
-		It("allows an ACK to be sent when pacing limited", func() {
-			sph.EXPECT().SentPacket(gomock.Any())
-			sph.EXPECT().HasPacingBudget()
-			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
-			sph.EXPECT().SendMode().Return(ackhandler.SendAny)
-			packer.EXPECT().MaybePackAckPacket(gomock.Any()).Return(getPacket(10), nil)
+		It("doesn't send an ACK-only packet when pacing limited, if data can be sent soon", func() {
+			sess.framer.QueueControlFrame(&wire.PingFrame{})
+			sph.EXPECT().SendMode().Return(ackhandler.SendAny).AnyTimes()
+			gomock.InOrder(
+				sph.EXPECT().HasPacingBudget(),
+				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(protocol.AckPiggybackWindow/2)),
+				sph.EXPECT().HasPacingBudget().Return(true),
+				packer.EXPECT().PackPacket().Return(getPacket(10), nil),
+				sph.EXPECT().SentPacket(gomock.Any()),
+				sph.EXPECT().HasPacingBudget().Return(true),
+				packer.EXPECT().PackPacket(),
+			)
 			sender.EXPECT().WouldBlock().AnyTimes()
 			sender.EXPECT().Send(gomock.Any())
 			go func() {
@@ -1508,7 +3148,9 @@ var _ = Describe("Session", func() {
 				sess.run()
 			}()
 			sess.scheduleSending()
-			time.Sleep(50 * time.Millisecond) // make sure that only 1 packet is sent
+			Eventually(connectionStats).Should(Equal(ConnectionStats{PacketsSent: 1, BytesSent: 6}))
+			// make sure that no ACK-only packet is sent
+			Consistently(connectionStats, scaleDuration(50*time.Millisecond)).Should(Equal(ConnectionStats{PacketsSent: 1, BytesSent: 6}))
 		})
 
 		// when becoming congestion limited, at some point the SendMode will change from SendAny to SendAck
@@ -1952,6 +3594,168 @@ var _ = Describe("Session", func() {
 		Eventually(sess.Context().Done()).Should(BeClosed())
 	})
 
+	It("sends multiple session tickets", func() {
+		sess.config.NumSessionTickets = 3
+		packer.EXPECT().PackCoalescedPacket().AnyTimes()
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().RunHandshake()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().GetSessionTicket().Return([]byte("ticket"), nil).Times(3)
+		close(sess.handshakeCompleteChan)
+		go func() {
+			defer GinkgoRecover()
+			sess.run()
+		}()
+		Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+		Eventually(func() uint64 { return sess.ConnectionStats().SessionTicketsSent }).Should(BeEquivalentTo(3))
+		frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+		var data []byte
+		for _, f := range frames {
+			if cf, ok := f.Frame.(*wire.CryptoFrame); ok {
+				data = append(data, cf.Data...)
+			}
+		}
+		Expect(data).To(Equal([]byte("ticketticketticket")))
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		tracer.EXPECT().ClosedConnection(gomock.Any())
+		tracer.EXPECT().Close()
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("doesn't send session tickets if disabled", func() {
+		sess.config.NumSessionTickets = -1
+		packer.EXPECT().PackCoalescedPacket().AnyTimes()
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().RunHandshake()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		close(sess.handshakeCompleteChan)
+		go func() {
+			defer GinkgoRecover()
+			sess.run()
+		}()
+		Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+		Eventually(func() []ackhandler.Frame {
+			frames, _ := sess.framer.AppendControlFrames(nil, protocol.MaxByteCount)
+			return frames
+		}).ShouldNot(BeEmpty())
+		Expect(sess.ConnectionStats().SessionTicketsSent).To(BeZero())
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		tracer.EXPECT().ClosedConnection(gomock.Any())
+		tracer.EXPECT().Close()
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("calls the SessionResumed callback when the handshake of a resumed session completes", func() {
+		infoChan := make(chan ResumptionInfo, 1)
+		sess.config.SessionResumed = func(s Session, info ResumptionInfo) {
+			Expect(s).To(Equal(sess))
+			infoChan <- info
+		}
+		var state handshake.ConnectionState
+		state.DidResume = true
+		state.Used0RTT = true
+		packer.EXPECT().PackCoalescedPacket().AnyTimes()
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().RunHandshake()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().ConnectionState().Return(state)
+		cryptoSetup.EXPECT().TicketAge().Return(time.Hour)
+		cryptoSetup.EXPECT().GetSessionTicket()
+		close(sess.handshakeCompleteChan)
+		go func() {
+			defer GinkgoRecover()
+			sess.run()
+		}()
+		Eventually(infoChan).Should(Receive(Equal(ResumptionInfo{TicketAge: time.Hour, Used0RTT: true})))
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		tracer.EXPECT().ClosedConnection(gomock.Any())
+		tracer.EXPECT().Close()
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("reports how the connection was set up in the ConnectionState", func() {
+		var tlsState handshake.ConnectionState
+		tlsState.DidResume = true
+		cryptoSetup.EXPECT().ConnectionState().Return(tlsState).AnyTimes()
+		sess.peerParams = &wire.TransportParameters{}
+		state := sess.ConnectionState()
+		Expect(state.DidResume).To(BeTrue())
+		Expect(state.Used0RTT).To(BeFalse())
+		Expect(state.UsedRetry).To(BeFalse())
+		Expect(state.HandshakeDuration).To(BeZero())
+		Expect(state.HandshakeRTTs).To(BeZero())
+
+		packer.EXPECT().PackCoalescedPacket().AnyTimes()
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().RunHandshake()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().GetSessionTicket()
+		time.Sleep(scaleDuration(10 * time.Millisecond))
+		close(sess.handshakeCompleteChan)
+		go func() {
+			defer GinkgoRecover()
+			sess.run()
+		}()
+		Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+		state = sess.ConnectionState()
+		Expect(state.HandshakeDuration).To(BeNumerically(">=", scaleDuration(10*time.Millisecond)))
+		Expect(state.HandshakeRTTs).To(Equal(1))
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		tracer.EXPECT().ClosedConnection(gomock.Any())
+		tracer.EXPECT().Close()
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
+	It("doesn't call the SessionResumed callback if the session wasn't resumed", func() {
+		sess.config.SessionResumed = func(Session, ResumptionInfo) { Fail("didn't expect the SessionResumed callback") }
+		packer.EXPECT().PackCoalescedPacket().AnyTimes()
+		sessionRunner.EXPECT().Retire(clientDestConnID)
+		cryptoSetup.EXPECT().RunHandshake()
+		cryptoSetup.EXPECT().SetHandshakeConfirmed()
+		cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{})
+		cryptoSetup.EXPECT().GetSessionTicket()
+		close(sess.handshakeCompleteChan)
+		go func() {
+			defer GinkgoRecover()
+			sess.run()
+		}()
+		Eventually(sess.HandshakeComplete().Done()).Should(BeClosed())
+		// make sure the go routine returns
+		streamManager.EXPECT().CloseWithError(gomock.Any())
+		expectReplaceWithClosed()
+		packer.EXPECT().PackApplicationClose(gomock.Any()).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+		cryptoSetup.EXPECT().Close()
+		mconn.EXPECT().Write(gomock.Any())
+		tracer.EXPECT().ClosedConnection(gomock.Any())
+		tracer.EXPECT().Close()
+		sess.shutdown()
+		Eventually(sess.Context().Done()).Should(BeClosed())
+	})
+
 	It("doesn't cancel the HandshakeComplete context when the handshake fails", func() {
 		packer.EXPECT().PackCoalescedPacket().AnyTimes()
 		streamManager.EXPECT().CloseWithError(gomock.Any())
@@ -2081,9 +3885,87 @@ var _ = Describe("Session", func() {
 			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).Times(2)
 			sessionRunner.EXPECT().Add(gomock.Any(), sess).Times(2)
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedIdleTimeout(sess.config.MaxIdleTimeout, 90*time.Second, sess.config.MaxIdleTimeout)
 			sess.handleTransportParameters(params)
 			Expect(sess.earlySessionReady()).To(BeClosed())
 		})
+
+		It("reports the maximum datagram payload size", func() {
+			var sizes []int
+			sess.config.EnableDatagrams = true
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).AnyTimes()
+			sess.config.DatagramPayloadSizeChanged = func(s Session, size int) {
+				Expect(s).To(Equal(sess))
+				sizes = append(sizes, size)
+			}
+			params := &wire.TransportParameters{
+				MaxDatagramFrameSize:      10000,
+				InitialSourceConnectionID: destConnID,
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().MaxPacketSize().Return(protocol.ByteCount(1252))
+			packer.EXPECT().PackCoalescedPacket().MaxTimes(3)
+			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).AnyTimes()
+			sessionRunner.EXPECT().Add(gomock.Any(), sess).AnyTimes()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedIdleTimeout(gomock.Any(), gomock.Any(), gomock.Any())
+			sess.handleTransportParameters(params)
+			overhead := 1 + protocol.ByteCount(sess.connIDManager.ActiveConnectionIDLen()) + 4 + 16
+			f := &wire.DatagramFrame{DataLenPresent: true}
+			size := int(f.MaxDataLen(1252-overhead, sess.version))
+			Expect(sizes).To(Equal([]int{size}))
+			Expect(sess.ConnectionState().MaxDatagramPayloadSize).To(Equal(size))
+			Expect(sess.SendMessage(make([]byte, size+1))).To(MatchError("message too large"))
+			// the packet size is increased by Path MTU Discovery
+			packer.EXPECT().MaxPacketSize().Return(protocol.ByteCount(1400))
+			sess.updateMaxDatagramPayloadSize()
+			newSize := int(f.MaxDataLen(1400-overhead, sess.version))
+			Expect(sizes).To(Equal([]int{size, newSize}))
+			Expect(sess.ConnectionState().MaxDatagramPayloadSize).To(Equal(newSize))
+			// the callback is not called if the size didn't change
+			packer.EXPECT().MaxPacketSize().Return(protocol.ByteCount(1400))
+			sess.updateMaxDatagramPayloadSize()
+			Expect(sizes).To(HaveLen(2))
+		})
+
+		It("limits the datagram payload size to the peer's max_datagram_frame_size", func() {
+			sess.config.EnableDatagrams = true
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{})
+			params := &wire.TransportParameters{
+				MaxDatagramFrameSize:      100,
+				InitialSourceConnectionID: destConnID,
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().MaxPacketSize().Return(protocol.ByteCount(1252))
+			packer.EXPECT().PackCoalescedPacket().MaxTimes(3)
+			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).AnyTimes()
+			sessionRunner.EXPECT().Add(gomock.Any(), sess).AnyTimes()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedIdleTimeout(gomock.Any(), gomock.Any(), gomock.Any())
+			sess.handleTransportParameters(params)
+			f := &wire.DatagramFrame{DataLenPresent: true}
+			Expect(sess.ConnectionState().MaxDatagramPayloadSize).To(Equal(int(f.MaxDataLen(100, sess.version))))
+		})
+
+		It("reports the negotiated idle timeout", func() {
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{}).AnyTimes()
+			sess.config.MaxIdleTimeout = time.Minute
+			params := &wire.TransportParameters{
+				MaxIdleTimeout:            10 * time.Second,
+				InitialSourceConnectionID: destConnID,
+			}
+			streamManager.EXPECT().UpdateLimits(params)
+			packer.EXPECT().HandleTransportParameters(params)
+			packer.EXPECT().PackCoalescedPacket().MaxTimes(3)
+			sessionRunner.EXPECT().GetStatelessResetToken(gomock.Any()).AnyTimes()
+			sessionRunner.EXPECT().Add(gomock.Any(), sess).AnyTimes()
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedIdleTimeout(time.Minute, 10*time.Second, 10*time.Second)
+			sess.handleTransportParameters(params)
+			Expect(sess.ConnectionState().IdleTimeout).To(Equal(10 * time.Second))
+		})
 	})
 
 	Context("keep-alives", func() {
@@ -2091,6 +3973,7 @@ var _ = Describe("Session", func() {
 			streamManager.EXPECT().UpdateLimits(gomock.Any())
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			tracer.EXPECT().ReceivedTransportParameters(gomock.Any())
+			tracer.EXPECT().NegotiatedIdleTimeout(gomock.Any(), gomock.Any(), gomock.Any())
 			sess.handleTransportParameters(&wire.TransportParameters{
 				MaxIdleTimeout:            t,
 				InitialSourceConnectionID: destConnID,
@@ -2383,6 +4266,119 @@ var _ = Describe("Session", func() {
 			Expect(str).To(Equal(mstr))
 		})
 
+		It("opens streams with options", func() {
+			prio := StreamPriority{Urgency: 1, Incremental: true}
+			mstr := NewMockStreamI(mockCtrl)
+			mstr.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+			streamManager.EXPECT().OpenStreamSync(context.Background()).Return(mstr, nil)
+			gomock.InOrder(
+				mstr.EXPECT().SetPriority(prio),
+				tracer.EXPECT().LabeledStream(protocol.StreamID(4), "request"),
+			)
+			str, err := sess.OpenStreamSyncWithOptions(context.Background(), StreamOptions{Priority: &prio, Label: "request"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
+		It("applies the traffic class before the priority", func() {
+			prio := StreamPriority{Urgency: 1}
+			mstr := NewMockStreamI(mockCtrl)
+			streamManager.EXPECT().OpenStreamSync(context.Background()).Return(mstr, nil)
+			gomock.InOrder(
+				mstr.EXPECT().SetTrafficClass(TrafficClass(2)),
+				mstr.EXPECT().SetPriority(prio),
+			)
+			str, err := sess.OpenStreamSyncWithOptions(context.Background(), StreamOptions{Priority: &prio, TrafficClass: 2})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
+		It("returns the priority configured for a traffic class", func() {
+			prio := StreamPriority{Urgency: 0, Weight: 100}
+			sess.config.TrafficClasses = map[TrafficClass]TrafficClassConfig{
+				1: {Priority: &prio},
+				2: {DSCP: 46},
+			}
+			p, ok := sess.trafficClassPriority(1)
+			Expect(ok).To(BeTrue())
+			Expect(p).To(Equal(prio))
+			_, ok = sess.trafficClassPriority(2)
+			Expect(ok).To(BeFalse())
+			_, ok = sess.trafficClassPriority(3)
+			Expect(ok).To(BeFalse())
+		})
+
+		Context("marking packets with a DSCP", func() {
+			BeforeEach(func() {
+				sess.config.TrafficClasses = map[TrafficClass]TrafficClassConfig{
+					1: {DSCP: 46},
+					2: {DSCP: 10},
+				}
+			})
+
+			streamWithTrafficClass := func(id protocol.StreamID, class TrafficClass) {
+				str := NewMockSendStreamI(mockCtrl)
+				str.EXPECT().TrafficClass().Return(class).AnyTimes()
+				streamManager.EXPECT().GetOrOpenSendStream(id).Return(str, nil).AnyTimes()
+			}
+
+			streamFrames := func(ids ...protocol.StreamID) []ackhandler.Frame {
+				frames := make([]ackhandler.Frame, 0, len(ids))
+				for _, id := range ids {
+					frames = append(frames, ackhandler.Frame{Frame: &wire.StreamFrame{StreamID: id, Data: []byte("foobar")}})
+				}
+				return frames
+			}
+
+			It("marks packets that only contain STREAM frames of one traffic class", func() {
+				streamWithTrafficClass(4, 1)
+				streamWithTrafficClass(8, 1)
+				Expect(sess.packetDSCP(&packetContents{frames: streamFrames(4, 8)})).To(BeEquivalentTo(46))
+				// ACK frames don't prevent marking
+				Expect(sess.packetDSCP(&packetContents{frames: streamFrames(4), ack: &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}})).To(BeEquivalentTo(46))
+			})
+
+			It("doesn't mark packets containing STREAM frames of different traffic classes", func() {
+				streamWithTrafficClass(4, 1)
+				streamWithTrafficClass(8, 2)
+				Expect(sess.packetDSCP(&packetContents{frames: streamFrames(8)})).To(BeEquivalentTo(10))
+				Expect(sess.packetDSCP(&packetContents{frames: streamFrames(4, 8)})).To(BeZero())
+			})
+
+			It("doesn't mark packets containing other frames", func() {
+				streamWithTrafficClass(4, 1)
+				frames := append(streamFrames(4), ackhandler.Frame{Frame: &wire.PingFrame{}})
+				Expect(sess.packetDSCP(&packetContents{frames: frames})).To(BeZero())
+				Expect(sess.packetDSCP(&packetContents{ack: &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}})).To(BeZero())
+			})
+
+			It("doesn't mark packets of streams in the default traffic class", func() {
+				streamWithTrafficClass(4, TrafficClassDefault)
+				Expect(sess.packetDSCP(&packetContents{frames: streamFrames(4)})).To(BeZero())
+			})
+
+			It("doesn't mark packets of streams that were already closed", func() {
+				streamManager.EXPECT().GetOrOpenSendStream(protocol.StreamID(4)).Return(nil, nil)
+				Expect(sess.packetDSCP(&packetContents{frames: streamFrames(4)})).To(BeZero())
+			})
+		})
+
+		It("opens unidirectional streams with options", func() {
+			mstr := NewMockSendStreamI(mockCtrl)
+			streamManager.EXPECT().OpenUniStreamSync(context.Background()).Return(mstr, nil)
+			// neither the priority nor the label is set
+			str, err := sess.OpenUniStreamSyncWithOptions(context.Background(), StreamOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(Equal(mstr))
+		})
+
+		It("returns the error when opening a stream with options fails", func() {
+			testErr := errors.New("test error")
+			streamManager.EXPECT().OpenStreamSync(context.Background()).Return(nil, testErr)
+			_, err := sess.OpenStreamSyncWithOptions(context.Background(), StreamOptions{Label: "request"})
+			Expect(err).To(MatchError(testErr))
+		})
+
 		It("accepts streams", func() {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 			defer cancel()
@@ -2402,6 +4398,72 @@ var _ = Describe("Session", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(str).To(Equal(mstr))
 		})
+
+		It("notifies the StreamObserver", func() {
+			observer := &recordingStreamObserver{}
+			sess.config.StreamObserver = observer
+			bidiStr := NewMockStreamI(mockCtrl)
+			bidiStr.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+			uniStr := NewMockSendStreamI(mockCtrl)
+			uniStr.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
+			streamManager.EXPECT().OpenStream().Return(bidiStr, nil)
+			streamManager.EXPECT().OpenStreamSync(context.Background()).Return(bidiStr, nil)
+			streamManager.EXPECT().OpenUniStream().Return(uniStr, nil)
+			streamManager.EXPECT().OpenUniStreamSync(context.Background()).Return(uniStr, nil)
+			_, err := sess.OpenStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = sess.OpenStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = sess.OpenUniStream()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = sess.OpenUniStreamSync(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(observer.opened).To(Equal([]StreamID{4, 4, 6, 6}))
+		})
+
+		It("notifies the StreamObserver after applying the stream options", func() {
+			var events []string
+			sess.config.StreamObserver = &streamObserverFuncs{
+				opened: func(id StreamID) { events = append(events, fmt.Sprintf("opened %d", id)) },
+			}
+			bidiStr := NewMockStreamI(mockCtrl)
+			bidiStr.EXPECT().StreamID().Return(protocol.StreamID(4)).AnyTimes()
+			bidiStr.EXPECT().SetPriority(gomock.Any()).Do(func(StreamPriority) { events = append(events, "priority 4") })
+			uniStr := NewMockSendStreamI(mockCtrl)
+			uniStr.EXPECT().StreamID().Return(protocol.StreamID(6)).AnyTimes()
+			uniStr.EXPECT().SetPriority(gomock.Any()).Do(func(StreamPriority) { events = append(events, "priority 6") })
+			streamManager.EXPECT().OpenStreamSync(context.Background()).Return(bidiStr, nil)
+			streamManager.EXPECT().OpenUniStreamSync(context.Background()).Return(uniStr, nil)
+			opts := StreamOptions{Priority: &StreamPriority{Urgency: 1}}
+			_, err := sess.OpenStreamSyncWithOptions(context.Background(), opts)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = sess.OpenUniStreamSyncWithOptions(context.Background(), opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(Equal([]string{"priority 4", "opened 4", "priority 6", "opened 6"}))
+		})
+
+		It("doesn't notify the StreamObserver when opening a stream fails", func() {
+			observer := &recordingStreamObserver{}
+			sess.config.StreamObserver = observer
+			testErr := errors.New("test error")
+			streamManager.EXPECT().OpenStream().Return(nil, testErr)
+			streamManager.EXPECT().OpenUniStreamSync(context.Background()).Return(nil, testErr)
+			_, err := sess.OpenStream()
+			Expect(err).To(MatchError(testErr))
+			_, err = sess.OpenUniStreamSyncWithOptions(context.Background(), StreamOptions{})
+			Expect(err).To(MatchError(testErr))
+			Expect(observer.opened).To(BeEmpty())
+		})
+	})
+
+	It("returns the connection-level flow control state", func() {
+		sess.connFlowController.UpdateSendWindow(1000)
+		sess.connFlowController.AddBytesSent(300)
+		state := sess.FlowControlState()
+		Expect(state.BytesSent).To(BeEquivalentTo(300))
+		Expect(state.SendLimit).To(BeEquivalentTo(1000))
+		Expect(state.SendBlocked()).To(BeFalse())
+		Expect(state.ReceiveLimit).To(BeEquivalentTo(sess.config.InitialConnectionReceiveWindow))
 	})
 
 	It("returns the local address", func() {
@@ -2629,6 +4691,7 @@ var _ = Describe("Client Session", func() {
 			recreateErr := err.(*errCloseForRecreating)
 			Expect(recreateErr.nextVersion).To(Equal(protocol.VersionNumber(4321)))
 			Expect(recreateErr.nextPacketNumber).To(Equal(protocol.PacketNumber(128)))
+			Expect(SessionCloseCause(sess.Context())).To(Equal(recreateErr))
 		})
 
 		It("it closes when no matching version is found", func() {
@@ -2708,6 +4771,9 @@ var _ = Describe("Client Session", func() {
 				Expect(hdr.Token).To(Equal(retryHdr.Token))
 			})
 			Expect(sess.handlePacketImpl(getPacket(retryHdr, getRetryTag(retryHdr)))).To(BeTrue())
+			sess.peerParams = &wire.TransportParameters{}
+			cryptoSetup.EXPECT().ConnectionState()
+			Expect(sess.ConnectionState().UsedRetry).To(BeTrue())
 		})
 
 		It("ignores Retry packets after receiving a regular packet", func() {
@@ -2791,6 +4857,7 @@ var _ = Describe("Client Session", func() {
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			packer.EXPECT().PackCoalescedPacket().MaxTimes(1)
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedIdleTimeout(gomock.Any(), gomock.Any(), gomock.Any())
 			sess.handleTransportParameters(params)
 			sess.handleHandshakeComplete()
 			// make sure the connection ID is not retired
@@ -2812,12 +4879,50 @@ var _ = Describe("Client Session", func() {
 			}
 			packer.EXPECT().HandleTransportParameters(gomock.Any())
 			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedIdleTimeout(gomock.Any(), gomock.Any(), gomock.Any())
 			sess.handleTransportParameters(params)
 			sess.handleHandshakeComplete()
 			Expect(sess.idleTimeout).To(Equal(18 * time.Second))
 			expectClose(true)
 		})
 
+		It("errors if the server accepted 0-RTT, but reduced the transport parameters", func() {
+			sess.zeroRTTParams = &wire.TransportParameters{InitialMaxData: 1000}
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				InitialMaxData:                  999,
+			}
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			sess.handleTransportParameters(params)
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{Used0RTT: true})
+			expectClose(false)
+			sess.handleHandshakeComplete()
+			Eventually(errChan).Should(Receive(MatchError(&ZeroRTTParameterError{
+				TransportError: qerr.TransportError{
+					ErrorCode:    qerr.ProtocolViolation,
+					ErrorMessage: "server accepted 0-RTT, but reduced the initial_max_data transport parameter",
+				},
+				Parameter: "initial_max_data",
+			})))
+		})
+
+		It("doesn't check the 0-RTT transport parameters if 0-RTT was rejected", func() {
+			sess.zeroRTTParams = &wire.TransportParameters{InitialMaxData: 1000}
+			params := &wire.TransportParameters{
+				OriginalDestinationConnectionID: destConnID,
+				InitialSourceConnectionID:       destConnID,
+				InitialMaxData:                  999,
+			}
+			packer.EXPECT().HandleTransportParameters(gomock.Any())
+			tracer.EXPECT().ReceivedTransportParameters(params)
+			tracer.EXPECT().NegotiatedIdleTimeout(gomock.Any(), gomock.Any(), gomock.Any())
+			sess.handleTransportParameters(params)
+			cryptoSetup.EXPECT().ConnectionState().Return(handshake.ConnectionState{Used0RTT: false})
+			sess.handleHandshakeComplete()
+			expectClose(true)
+		})
+
 		It("errors if the transport parameters contain a wrong initial_source_connection_id", func() {
 			sess.handshakeDestConnID = protocol.ConnectionID{0xde, 0xad, 0xbe, 0xef}
 			params := &wire.TransportParameters{
@@ -2988,6 +5093,7 @@ var _ = Describe("Client Session", func() {
 			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
 			initialPacket := testutils.ComposeInitialPacket(destConnID, srcConnID, sess.version, destConnID, []wire.Frame{ack})
 			tracer.EXPECT().ReceivedPacket(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().DetectedPeerAnomaly(logging.PeerAnomalyAckForUnsentPacket, gomock.Any())
 			Expect(sess.handlePacketImpl(wrapPacket(initialPacket))).To(BeFalse())
 		})
 
@@ -0,0 +1,50 @@
+package quic
+
+import (
+	"errors"
+	"io"
+)
+
+// Splice copies the data received on src to dst until the end of src, and then closes dst.
+// It is the inner loop of a proxy that forwards data between QUIC streams:
+// The data is passed to dst.Write directly from the frames received on src, without an intermediate buffer.
+// Note that this is not zero-copy: dst copies the data into the STREAM frames it sends.
+// Flow control is coupled: Data is only consumed from src (granting flow control credit to the peer sending on src)
+// once it was accepted by dst. A peer that is slow to receive on dst therefore also slows down the peer sending on src.
+// Cancellations are propagated: If the peer resets src, dst is reset with the same error code,
+// and if the peer stops dst (using STOP_SENDING), reading from src is canceled with the same error code.
+// Splice returns the number of bytes copied, and the first error that occurred.
+// The error is nil if all data up to the end of src was copied, and dst was closed.
+func Splice(dst SendStream, src ReceiveStream) (int64, error) {
+	w := &spliceWriter{str: dst}
+	// The streams returned by a session implement io.WriterTo, which is used by io.Copy.
+	n, err := io.Copy(w, src)
+	if err == nil {
+		return n, dst.Close()
+	}
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		return n, err
+	}
+	if w.err != nil {
+		src.CancelRead(streamErr.ErrorCode)
+	} else {
+		dst.CancelWrite(streamErr.ErrorCode)
+	}
+	return n, err
+}
+
+// The spliceWriter records if an error occurred when writing to the destination stream.
+// It hides the io.ReaderFrom implementation of the stream, since io.Copy wouldn't tell which stream an error came from.
+type spliceWriter struct {
+	str SendStream
+	err error
+}
+
+func (w *spliceWriter) Write(p []byte) (int, error) {
+	n, err := w.str.Write(p)
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
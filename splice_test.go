@@ -0,0 +1,99 @@
+package quic
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/golang/mock/gomock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// A writerToReceiveStream is a receive stream that implements io.WriterTo, like the streams returned by a session.
+type writerToReceiveStream struct {
+	*MockReceiveStreamI
+	data []byte
+	err  error
+}
+
+func (s *writerToReceiveStream) WriteTo(w io.Writer) (int64, error) {
+	if len(s.data) == 0 {
+		return 0, s.err
+	}
+	n, err := w.Write(s.data)
+	if err != nil {
+		return int64(n), err
+	}
+	return int64(n), s.err
+}
+
+var _ = Describe("Splice", func() {
+	var (
+		dst *MockSendStreamI
+		src *writerToReceiveStream
+	)
+
+	BeforeEach(func() {
+		dst = NewMockSendStreamI(mockCtrl)
+		src = &writerToReceiveStream{MockReceiveStreamI: NewMockReceiveStreamI(mockCtrl)}
+	})
+
+	It("copies the data using WriteTo, and closes the destination stream", func() {
+		src.data = []byte("foobar")
+		gomock.InOrder(
+			dst.EXPECT().Write([]byte("foobar")).Return(6, nil),
+			dst.EXPECT().Close(),
+		)
+		n, err := Splice(dst, src)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(6))
+	})
+
+	It("copies from streams that don't implement io.WriterTo", func() {
+		r := bytes.NewReader([]byte("foobar"))
+		str := NewMockReceiveStreamI(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(r.Read).AnyTimes()
+		dst.EXPECT().Write([]byte("foobar")).Return(6, nil)
+		dst.EXPECT().Close()
+		n, err := Splice(dst, str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(n).To(BeEquivalentTo(6))
+	})
+
+	It("resets the destination stream when the source stream is reset", func() {
+		src.data = []byte("foo")
+		src.err = &StreamError{StreamID: 4, ErrorCode: 1337}
+		dst.EXPECT().Write([]byte("foo")).Return(3, nil)
+		dst.EXPECT().CancelWrite(StreamErrorCode(1337))
+		n, err := Splice(dst, src)
+		Expect(err).To(MatchError(src.err))
+		Expect(n).To(BeEquivalentTo(3))
+	})
+
+	It("cancels reading from the source stream when the destination stream is stopped", func() {
+		src.data = []byte("foobar")
+		stopErr := &StopSendingError{StreamError: StreamError{StreamID: 8, ErrorCode: 42}}
+		dst.EXPECT().Write([]byte("foobar")).Return(2, stopErr)
+		src.EXPECT().CancelRead(StreamErrorCode(42))
+		n, err := Splice(dst, src)
+		Expect(err).To(MatchError(stopErr))
+		Expect(n).To(BeEquivalentTo(2))
+	})
+
+	It("doesn't cancel any stream on other errors", func() {
+		testErr := errors.New("test error")
+		src.err = testErr
+		n, err := Splice(dst, src)
+		Expect(err).To(MatchError(testErr))
+		Expect(n).To(BeZero())
+	})
+
+	It("returns the error when closing the destination stream fails", func() {
+		testErr := errors.New("test error")
+		dst.EXPECT().Close().Return(testErr)
+		_, err := Splice(dst, src)
+		Expect(err).To(MatchError(testErr))
+	})
+})
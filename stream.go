@@ -25,6 +25,19 @@ var errDeadline net.Error = &deadlineError{}
 type streamSender interface {
 	queueControlFrame(wire.Frame)
 	onHasStreamData(protocol.StreamID)
+	onStreamPriorityChanged(protocol.StreamID, StreamPriority)
+	// trafficClassPriority returns the priority configured for a traffic class, if any
+	trafficClassPriority(TrafficClass) (StreamPriority, bool)
+	// onStreamFlushed is called when Flush is called on a stream that has data to send
+	onStreamFlushed(protocol.StreamID)
+	// onStreamBlocked is called when a stream that has data to send is blocked by flow control,
+	// either by the stream-level or by the connection-level limit
+	onStreamBlocked(id protocol.StreamID, connectionLevel bool)
+	// supportsResetStreamAt says if RESET_STREAM_AT frames can be sent
+	supportsResetStreamAt() bool
+	// onStreamWritable is called when the send buffer of a stream was emptied while packing a packet.
+	// notify is called once the packet was sent.
+	onStreamWritable(notify func())
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
 }
@@ -44,6 +57,30 @@ func (s *uniStreamSender) onHasStreamData(id protocol.StreamID) {
 	s.streamSender.onHasStreamData(id)
 }
 
+func (s *uniStreamSender) onStreamPriorityChanged(id protocol.StreamID, prio StreamPriority) {
+	s.streamSender.onStreamPriorityChanged(id, prio)
+}
+
+func (s *uniStreamSender) trafficClassPriority(class TrafficClass) (StreamPriority, bool) {
+	return s.streamSender.trafficClassPriority(class)
+}
+
+func (s *uniStreamSender) onStreamFlushed(id protocol.StreamID) {
+	s.streamSender.onStreamFlushed(id)
+}
+
+func (s *uniStreamSender) onStreamBlocked(id protocol.StreamID, connectionLevel bool) {
+	s.streamSender.onStreamBlocked(id, connectionLevel)
+}
+
+func (s *uniStreamSender) supportsResetStreamAt() bool {
+	return s.streamSender.supportsResetStreamAt()
+}
+
+func (s *uniStreamSender) onStreamWritable(notify func()) {
+	s.streamSender.onStreamWritable(notify)
+}
+
 func (s *uniStreamSender) onStreamCompleted(protocol.StreamID) {
 	s.onStreamCompletedImpl()
 }
@@ -57,8 +94,10 @@ type streamI interface {
 	handleStreamFrame(*wire.StreamFrame) error
 	handleResetStreamFrame(*wire.ResetStreamFrame) error
 	getWindowUpdate() protocol.ByteCount
+	setFlowControlGroup(*flowcontrol.GroupFlowController)
 	// for sending
 	hasData() bool
+	pendingData() protocol.ByteCount
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount) (*ackhandler.Frame, bool)
 	updateSendWindow(protocol.ByteCount)
@@ -98,8 +137,11 @@ func newStream(streamID protocol.StreamID,
 		onStreamCompletedImpl: func() {
 			s.completedMutex.Lock()
 			s.sendStreamCompleted = true
-			s.checkIfCompleted()
+			completed := s.checkIfCompleted()
 			s.completedMutex.Unlock()
+			if completed {
+				s.sender.onStreamCompleted(s.StreamID())
+			}
 		},
 	}
 	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, version)
@@ -108,8 +150,11 @@ func newStream(streamID protocol.StreamID,
 		onStreamCompletedImpl: func() {
 			s.completedMutex.Lock()
 			s.receiveStreamCompleted = true
-			s.checkIfCompleted()
+			completed := s.checkIfCompleted()
 			s.completedMutex.Unlock()
+			if completed {
+				s.sender.onStreamCompleted(s.StreamID())
+			}
 		},
 	}
 	s.receiveStream = *newReceiveStream(streamID, senderForReceiveStream, flowController, version)
@@ -122,6 +167,12 @@ func (s *stream) StreamID() protocol.StreamID {
 	return s.sendStream.StreamID()
 }
 
+// need to define FlowControlState() here, since both receiveStream and sendStream have a FlowControlState()
+func (s *stream) FlowControlState() FlowControlState {
+	// the result is same for receiveStream and sendStream, since they share the flow controller
+	return s.sendStream.FlowControlState()
+}
+
 func (s *stream) Close() error {
 	return s.sendStream.Close()
 }
@@ -140,10 +191,21 @@ func (s *stream) closeForShutdown(err error) {
 	s.receiveStream.closeForShutdown(err)
 }
 
+func (s *stream) isCompleted() bool {
+	s.completedMutex.Lock()
+	defer s.completedMutex.Unlock()
+	return s.sendStreamCompleted && s.receiveStreamCompleted
+}
+
 // checkIfCompleted is called from the uniStreamSender, when one of the stream halves is completed.
 // It makes sure that the onStreamCompleted callback is only called if both receive and send side have completed.
-func (s *stream) checkIfCompleted() {
+// The callback must be called after releasing the completedMutex, since the session acquires locks
+// that are held while calling isCompleted.
+func (s *stream) checkIfCompleted() bool {
 	if s.sendStreamCompleted && s.receiveStreamCompleted {
-		s.sender.onStreamCompleted(s.StreamID())
+		// the idle timer is shared by both stream halves
+		s.sendStream.idleTimer.Stop()
+		return true
 	}
+	return false
 }
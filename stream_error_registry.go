@@ -0,0 +1,104 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type registeredStreamError struct {
+	code StreamErrorCode
+	err  error
+}
+
+// A StreamErrorRegistry maps stream error codes to application-defined errors, and vice versa.
+// It allows protocols layered on top of QUIC (like HTTP/3 or WebTransport) to define their error codes once,
+// instead of translating the errors returned from the streams ad hoc.
+//
+// When a stream is canceled with a registered error code, either locally or by the peer,
+// the errors returned from Read and Write wrap the registered error, so they can be matched using errors.Is.
+// Errors caused by the peer canceling the stream can still be matched with errors.As using a *StreamError,
+// and the registered error is available in the StreamError's Err field.
+//
+// Every session has its own registry, see Session.StreamErrors. It is safe for concurrent use.
+// A nil registry behaves like an empty one, except that no errors can be registered.
+type StreamErrorRegistry struct {
+	mutex  sync.RWMutex
+	byCode map[StreamErrorCode]error
+	// the registered errors in the order of registration, used by Code
+	errs []registeredStreamError
+}
+
+func newStreamErrorRegistry() *StreamErrorRegistry {
+	return &StreamErrorRegistry{byCode: make(map[StreamErrorCode]error)}
+}
+
+// Register associates an error code with an application-defined error.
+// Every error code can only be registered once.
+// Registering the same error for multiple error codes is allowed, in which case Code returns the code that was registered first.
+// Streams that were already canceled are not affected.
+func (r *StreamErrorRegistry) Register(code StreamErrorCode, err error) error {
+	if err == nil {
+		return errors.New("cannot register a nil error")
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.byCode[code]; ok {
+		return fmt.Errorf("stream error code %d is already registered", code)
+	}
+	r.byCode[code] = err
+	r.errs = append(r.errs, registeredStreamError{code: code, err: err})
+	return nil
+}
+
+// Error returns the error registered for an error code.
+// It returns nil if no error was registered for the code.
+func (r *StreamErrorRegistry) Error(code StreamErrorCode) error {
+	if r == nil {
+		return nil
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.byCode[code]
+}
+
+// Code returns the error code for an error.
+// The error matches a registered error if errors.Is(err, registered) is true,
+// so errors wrapping a registered error are translated as well.
+func (r *StreamErrorRegistry) Code(err error) (StreamErrorCode, bool) {
+	if r == nil {
+		return 0, false
+	}
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for _, e := range r.errs {
+		if errors.Is(err, e.err) {
+			return e.code, true
+		}
+	}
+	return 0, false
+}
+
+// CancelRead cancels reading from a stream with the error code registered for err, see ReceiveStream.CancelRead.
+// It returns false, without canceling the stream, if err doesn't match any registered error.
+func (r *StreamErrorRegistry) CancelRead(str ReceiveStream, err error) bool {
+	code, ok := r.Code(err)
+	if !ok {
+		return false
+	}
+	str.CancelRead(code)
+	return true
+}
+
+// CancelWrite cancels writing to a stream with the error code registered for err, see SendStream.CancelWrite.
+// It returns false, without canceling the stream, if err doesn't match any registered error.
+func (r *StreamErrorRegistry) CancelWrite(str SendStream, err error) bool {
+	code, ok := r.Code(err)
+	if !ok {
+		return false
+	}
+	str.CancelWrite(code)
+	return true
+}
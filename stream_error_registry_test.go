@@ -0,0 +1,83 @@
+package quic
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Error Registry", func() {
+	var r *StreamErrorRegistry
+
+	errRequestCanceled := errors.New("request canceled")
+	errRequestRejected := errors.New("request rejected")
+
+	BeforeEach(func() {
+		r = newStreamErrorRegistry()
+	})
+
+	It("translates error codes to errors", func() {
+		Expect(r.Register(0x10c, errRequestCanceled)).To(Succeed())
+		Expect(r.Register(0x10b, errRequestRejected)).To(Succeed())
+		Expect(r.Error(0x10c)).To(Equal(errRequestCanceled))
+		Expect(r.Error(0x10b)).To(Equal(errRequestRejected))
+		Expect(r.Error(0x100)).To(BeNil())
+	})
+
+	It("translates errors to error codes", func() {
+		Expect(r.Register(0x10c, errRequestCanceled)).To(Succeed())
+		code, ok := r.Code(errRequestCanceled)
+		Expect(ok).To(BeTrue())
+		Expect(code).To(BeEquivalentTo(0x10c))
+		code, ok = r.Code(fmt.Errorf("wrapped: %w", errRequestCanceled))
+		Expect(ok).To(BeTrue())
+		Expect(code).To(BeEquivalentTo(0x10c))
+		_, ok = r.Code(errRequestRejected)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the first code registered for an error", func() {
+		Expect(r.Register(0x10c, errRequestCanceled)).To(Succeed())
+		Expect(r.Register(0x10d, errRequestCanceled)).To(Succeed())
+		code, ok := r.Code(errRequestCanceled)
+		Expect(ok).To(BeTrue())
+		Expect(code).To(BeEquivalentTo(0x10c))
+		Expect(r.Error(0x10d)).To(Equal(errRequestCanceled))
+	})
+
+	It("refuses to register an error code twice", func() {
+		Expect(r.Register(0x10c, errRequestCanceled)).To(Succeed())
+		Expect(r.Register(0x10c, errRequestRejected)).To(MatchError("stream error code 268 is already registered"))
+		Expect(r.Error(0x10c)).To(Equal(errRequestCanceled))
+	})
+
+	It("refuses to register nil errors", func() {
+		Expect(r.Register(0x10c, nil)).To(MatchError("cannot register a nil error"))
+	})
+
+	It("doesn't return errors or error codes on a nil registry", func() {
+		var nilRegistry *StreamErrorRegistry
+		Expect(nilRegistry.Error(0x10c)).To(BeNil())
+		_, ok := nilRegistry.Code(errRequestCanceled)
+		Expect(ok).To(BeFalse())
+		Expect(nilRegistry.CancelRead(NewMockReceiveStreamI(mockCtrl), errRequestCanceled)).To(BeFalse())
+	})
+
+	It("cancels reading", func() {
+		Expect(r.Register(0x10c, errRequestCanceled)).To(Succeed())
+		str := NewMockReceiveStreamI(mockCtrl)
+		str.EXPECT().CancelRead(StreamErrorCode(0x10c))
+		Expect(r.CancelRead(str, errRequestCanceled)).To(BeTrue())
+		Expect(r.CancelRead(str, errRequestRejected)).To(BeFalse())
+	})
+
+	It("cancels writing", func() {
+		Expect(r.Register(0x10c, errRequestCanceled)).To(Succeed())
+		str := NewMockSendStreamI(mockCtrl)
+		str.EXPECT().CancelWrite(StreamErrorCode(0x10c))
+		Expect(r.CancelWrite(str, errRequestCanceled)).To(BeTrue())
+		Expect(r.CancelWrite(str, errRequestRejected)).To(BeFalse())
+	})
+})
@@ -0,0 +1,256 @@
+package quic
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A groupableStream is a stream that can be added to a StreamGroup.
+// It is implemented by all streams returned by a session.
+type groupableStream interface {
+	StreamID() StreamID
+	// isCompleted says if the stream was reported to the session as completed
+	isCompleted() bool
+}
+
+var (
+	_ groupableStream = &stream{}
+	_ groupableStream = &sendStream{}
+	_ groupableStream = &receiveStream{}
+)
+
+// A StreamGroup associates a set of streams, e.g. the streams belonging to one WebTransport session,
+// so that they can be canceled, prioritized and flow-controlled as a unit.
+// Streams are removed from the group once they are completed,
+// i.e. when both the send and the receive side have finished (successfully or not).
+// A stream can only be a member of a single group.
+// A StreamGroup is created by Session.NewStreamGroup. It is safe for concurrent use.
+type StreamGroup struct {
+	registry *streamGroupRegistry
+
+	mutex          sync.Mutex
+	members        map[protocol.StreamID]groupMember
+	closed         bool
+	closeCode      StreamErrorCode
+	priority       *StreamPriority
+	flowController *flowcontrol.GroupFlowController // nil until SetReceiveWindow is called
+}
+
+// A groupMember is a stream in a group.
+// The send or the receive side is nil for unidirectional streams.
+type groupMember struct {
+	send    SendStream
+	receive ReceiveStream
+}
+
+func (m groupMember) cancel(errorCode StreamErrorCode) {
+	if m.send != nil {
+		m.send.CancelWrite(errorCode)
+	}
+	if m.receive != nil {
+		m.receive.CancelRead(errorCode)
+	}
+}
+
+func (m groupMember) apply(prio *StreamPriority, fc *flowcontrol.GroupFlowController) {
+	if m.send != nil && prio != nil {
+		m.send.SetPriority(*prio)
+	}
+	if fc == nil {
+		return
+	}
+	if str, ok := m.receive.(interface {
+		setFlowControlGroup(*flowcontrol.GroupFlowController)
+	}); ok {
+		str.setFlowControlGroup(fc)
+	}
+}
+
+func newStreamGroup(registry *streamGroupRegistry) *StreamGroup {
+	return &StreamGroup{
+		registry: registry,
+		members:  make(map[protocol.StreamID]groupMember),
+	}
+}
+
+// AddStream adds a bidirectional stream to the group.
+// The stream must have been opened or accepted on the session that created the group.
+// The group's priority is applied to the stream, and its receive window is limited by the group's receive window.
+// If the group was already closed, the stream is canceled with the group's error code.
+// It is a no-op if the stream was already completed.
+func (g *StreamGroup) AddStream(str Stream) error {
+	return g.add(str, groupMember{send: str, receive: str})
+}
+
+// AddSendStream adds a unidirectional send stream to the group, see AddStream.
+func (g *StreamGroup) AddSendStream(str SendStream) error {
+	return g.add(str, groupMember{send: str})
+}
+
+// AddReceiveStream adds a unidirectional receive stream to the group, see AddStream.
+func (g *StreamGroup) AddReceiveStream(str ReceiveStream) error {
+	return g.add(str, groupMember{receive: str})
+}
+
+func (g *StreamGroup) add(str interface{}, m groupMember) error {
+	s, ok := str.(groupableStream)
+	if !ok {
+		return errors.New("not a QUIC stream")
+	}
+	added, err := g.registry.add(g, s, m)
+	if err != nil || !added {
+		return err
+	}
+	g.mutex.Lock()
+	closed := g.closed
+	closeCode := g.closeCode
+	prio := g.priority
+	fc := g.flowController
+	g.mutex.Unlock()
+	// Canceling a stream might complete it, which removes it from the group.
+	// This must therefore happen without holding the mutex.
+	if closed {
+		m.cancel(closeCode)
+		return nil
+	}
+	m.apply(prio, fc)
+	return nil
+}
+
+// Len returns the number of streams in the group.
+func (g *StreamGroup) Len() int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return len(g.members)
+}
+
+// SetPriority sets the priority of the send side of all streams in the group,
+// as well as of the streams added to the group later.
+func (g *StreamGroup) SetPriority(prio StreamPriority) {
+	g.mutex.Lock()
+	g.priority = &prio
+	members := g.snapshot()
+	g.mutex.Unlock()
+	for _, m := range members {
+		m.apply(&prio, nil)
+	}
+}
+
+// SetReceiveWindow sets the receive window of the group, which is shared by the receive side of all streams in the group.
+// Flow control credit is only granted on a stream as long as the credit outstanding on all streams in the group
+// (the data that the peer is allowed to send, but which wasn't read yet) doesn't exceed the group's window.
+// Credit that was granted before a stream was added to the group is not revoked.
+// A size of 0 is ignored.
+func (g *StreamGroup) SetReceiveWindow(size uint64) {
+	if size == 0 {
+		return
+	}
+	g.mutex.Lock()
+	if fc := g.flowController; fc != nil {
+		g.mutex.Unlock()
+		fc.SetWindow(protocol.ByteCount(size))
+		return
+	}
+	g.flowController = flowcontrol.NewGroupFlowController(protocol.ByteCount(size))
+	fc := g.flowController
+	members := g.snapshot()
+	g.mutex.Unlock()
+	for _, m := range members {
+		m.apply(nil, fc)
+	}
+}
+
+// Close cancels all streams in the group: the send side is canceled using a RESET_STREAM frame,
+// and the receive side using a STOP_SENDING frame, both with the given error code.
+// Streams added to the group after it was closed are canceled right away.
+// When called multiple times, it is a no-op.
+func (g *StreamGroup) Close(errorCode StreamErrorCode) {
+	g.mutex.Lock()
+	if g.closed {
+		g.mutex.Unlock()
+		return
+	}
+	g.closed = true
+	g.closeCode = errorCode
+	members := g.snapshot()
+	g.mutex.Unlock()
+	for _, m := range members {
+		m.cancel(errorCode)
+	}
+}
+
+// snapshot returns the current members. It must be called with the mutex held.
+func (g *StreamGroup) snapshot() []groupMember {
+	members := make([]groupMember, 0, len(g.members))
+	for _, m := range g.members {
+		members = append(members, m)
+	}
+	return members
+}
+
+// The streamGroupRegistry keeps track of the group membership of all streams of a session.
+// Its mutex is acquired before the mutex of a StreamGroup.
+type streamGroupRegistry struct {
+	mutex  sync.Mutex
+	groups map[protocol.StreamID]*StreamGroup
+}
+
+func newStreamGroupRegistry() *streamGroupRegistry {
+	return &streamGroupRegistry{groups: make(map[protocol.StreamID]*StreamGroup)}
+}
+
+// add adds a stream to a group.
+// Streams set their completed flag before reporting their completion to the session,
+// which calls onStreamCompleted. Since both add and onStreamCompleted hold the registry's mutex,
+// a stream is either not added at all, or removed from the group once it completes.
+func (r *streamGroupRegistry) add(g *StreamGroup, str groupableStream, m groupMember) (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	id := str.StreamID()
+	if other, ok := r.groups[id]; ok {
+		if other == g {
+			return false, nil
+		}
+		return false, errors.New("stream already is a member of a different group")
+	}
+	if str.isCompleted() {
+		return false, nil
+	}
+	r.groups[id] = g
+	g.mutex.Lock()
+	g.members[id] = m
+	g.mutex.Unlock()
+	return true, nil
+}
+
+// reset removes all streams from their groups.
+// It is called when 0-RTT is rejected, since all streams opened so far are closed.
+func (r *streamGroupRegistry) reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for id, g := range r.groups {
+		g.mutex.Lock()
+		delete(g.members, id)
+		g.mutex.Unlock()
+	}
+	r.groups = make(map[protocol.StreamID]*StreamGroup)
+}
+
+func (r *streamGroupRegistry) onStreamCompleted(id protocol.StreamID) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	g, ok := r.groups[id]
+	if !ok {
+		return
+	}
+	delete(r.groups, id)
+	g.mutex.Lock()
+	delete(g.members, id)
+	g.mutex.Unlock()
+}
@@ -0,0 +1,168 @@
+package quic
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type groupableMockStream struct {
+	*MockStreamI
+	completed bool
+}
+
+func (s *groupableMockStream) isCompleted() bool { return s.completed }
+
+type groupableMockSendStream struct {
+	*MockSendStreamI
+	completed bool
+}
+
+func (s *groupableMockSendStream) isCompleted() bool { return s.completed }
+
+type groupableMockReceiveStream struct {
+	*MockReceiveStreamI
+	completed bool
+}
+
+func (s *groupableMockReceiveStream) isCompleted() bool { return s.completed }
+
+var _ = Describe("Stream Group", func() {
+	var (
+		registry *streamGroupRegistry
+		group    *StreamGroup
+	)
+
+	BeforeEach(func() {
+		registry = newStreamGroupRegistry()
+		group = newStreamGroup(registry)
+	})
+
+	newStream := func(id StreamID) *groupableMockStream {
+		str := &groupableMockStream{MockStreamI: NewMockStreamI(mockCtrl)}
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		return str
+	}
+
+	newSendStream := func(id StreamID) *groupableMockSendStream {
+		str := &groupableMockSendStream{MockSendStreamI: NewMockSendStreamI(mockCtrl)}
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		return str
+	}
+
+	newReceiveStream := func(id StreamID) *groupableMockReceiveStream {
+		str := &groupableMockReceiveStream{MockReceiveStreamI: NewMockReceiveStreamI(mockCtrl)}
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		return str
+	}
+
+	It("adds streams, and removes them when they are completed", func() {
+		Expect(group.AddStream(newStream(0))).To(Succeed())
+		Expect(group.AddSendStream(newSendStream(2))).To(Succeed())
+		Expect(group.AddReceiveStream(newReceiveStream(3))).To(Succeed())
+		Expect(group.Len()).To(Equal(3))
+		registry.onStreamCompleted(2)
+		Expect(group.Len()).To(Equal(2))
+		// streams that are not in a group are ignored
+		registry.onStreamCompleted(4)
+		Expect(group.Len()).To(Equal(2))
+	})
+
+	It("doesn't add streams that were already completed", func() {
+		str := newStream(0)
+		str.completed = true
+		Expect(group.AddStream(str)).To(Succeed())
+		Expect(group.Len()).To(BeZero())
+		Expect(registry.groups).To(BeEmpty())
+	})
+
+	It("only allows a stream to be a member of a single group", func() {
+		str := newStream(4)
+		Expect(group.AddStream(str)).To(Succeed())
+		Expect(group.AddStream(str)).To(Succeed())
+		Expect(group.Len()).To(Equal(1))
+		other := newStreamGroup(registry)
+		Expect(other.AddStream(str)).To(MatchError("stream already is a member of a different group"))
+		Expect(other.Len()).To(BeZero())
+		// once the stream is completed, the ID could be reused by a stream from a different group
+		registry.onStreamCompleted(4)
+		Expect(other.AddStream(newStream(4))).To(Succeed())
+	})
+
+	It("rejects streams that were not returned by a session", func() {
+		Expect(group.AddStream(NewMockStreamI(mockCtrl))).ToNot(Succeed())
+		Expect(group.Len()).To(BeZero())
+	})
+
+	It("sets the priority", func() {
+		str := newStream(0)
+		recvStr := newReceiveStream(3)
+		Expect(group.AddStream(str)).To(Succeed())
+		Expect(group.AddReceiveStream(recvStr)).To(Succeed())
+		prio := StreamPriority{Urgency: 2, Incremental: true}
+		str.EXPECT().SetPriority(prio)
+		group.SetPriority(prio)
+		// the priority is applied to streams added later
+		sendStr := newSendStream(2)
+		sendStr.EXPECT().SetPriority(prio)
+		Expect(group.AddSendStream(sendStr)).To(Succeed())
+	})
+
+	It("sets the receive window", func() {
+		str := newStream(0)
+		sendStr := newSendStream(2)
+		Expect(group.AddStream(str)).To(Succeed())
+		Expect(group.AddSendStream(sendStr)).To(Succeed())
+		var fc *flowcontrol.GroupFlowController
+		str.EXPECT().setFlowControlGroup(gomock.Any()).Do(func(g *flowcontrol.GroupFlowController) { fc = g })
+		group.SetReceiveWindow(1 << 20)
+		Expect(fc).ToNot(BeNil())
+		group.SetReceiveWindow(0) // ignored
+		// changing the window doesn't create a new group flow controller
+		group.SetReceiveWindow(1 << 10)
+		// the group flow controller is used by streams added later
+		recvStr := newReceiveStream(3)
+		recvStr.EXPECT().setFlowControlGroup(fc)
+		Expect(group.AddReceiveStream(recvStr)).To(Succeed())
+	})
+
+	It("cancels all streams when closed", func() {
+		str := newStream(0)
+		sendStr := newSendStream(2)
+		recvStr := newReceiveStream(3)
+		Expect(group.AddStream(str)).To(Succeed())
+		Expect(group.AddSendStream(sendStr)).To(Succeed())
+		Expect(group.AddReceiveStream(recvStr)).To(Succeed())
+		str.EXPECT().CancelWrite(StreamErrorCode(1337))
+		str.EXPECT().CancelRead(StreamErrorCode(1337))
+		sendStr.EXPECT().CancelWrite(StreamErrorCode(1337))
+		recvStr.EXPECT().CancelRead(StreamErrorCode(1337)).Do(func(StreamErrorCode) {
+			// canceling might complete the stream, which removes it from the group
+			registry.onStreamCompleted(3)
+		})
+		group.Close(1337)
+		Expect(group.Len()).To(Equal(2))
+		// closing a second time is a no-op
+		group.Close(42)
+	})
+
+	It("cancels streams added after the group was closed", func() {
+		group.Close(1337)
+		str := newStream(0)
+		str.EXPECT().CancelWrite(StreamErrorCode(1337))
+		str.EXPECT().CancelRead(StreamErrorCode(1337))
+		Expect(group.AddStream(str)).To(Succeed())
+	})
+
+	It("removes all streams when the registry is reset", func() {
+		Expect(group.AddStream(newStream(0))).To(Succeed())
+		other := newStreamGroup(registry)
+		Expect(other.AddSendStream(newSendStream(2))).To(Succeed())
+		registry.reset()
+		Expect(group.Len()).To(BeZero())
+		Expect(other.Len()).To(BeZero())
+		Expect(other.AddStream(newStream(0))).To(Succeed())
+	})
+})
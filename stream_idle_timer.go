@@ -0,0 +1,78 @@
+package quic
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A streamIdleTimer cancels a stream if no STREAM frames were sent or received for a certain time,
+// see Config.StreamIdleTimeout.
+// To avoid resetting a timer for every frame, the time of the last activity is stored,
+// and the timer is rearmed when it fires before the stream was idle for long enough.
+// All methods can be called on a nil streamIdleTimer, which is used when the idle timeout is disabled.
+type streamIdleTimer struct {
+	timeout      time.Duration
+	lastActivity int64 // in nanoseconds since the Unix epoch, to be accessed atomically
+	onIdle       func()
+
+	mutex   sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+func newStreamIdleTimer(timeout time.Duration, onIdle func()) *streamIdleTimer {
+	return &streamIdleTimer{
+		timeout: timeout,
+		onIdle:  onIdle,
+	}
+}
+
+// Start arms the timer.
+// It must be called after the stream was set up, since onIdle is called on a separate goroutine.
+func (t *streamIdleTimer) Start() {
+	atomic.StoreInt64(&t.lastActivity, time.Now().UnixNano())
+	t.mutex.Lock()
+	if !t.stopped {
+		t.timer = time.AfterFunc(t.timeout, t.fire)
+	}
+	t.mutex.Unlock()
+}
+
+// Activity is called when a STREAM frame is sent or received.
+func (t *streamIdleTimer) Activity() {
+	if t == nil {
+		return
+	}
+	atomic.StoreInt64(&t.lastActivity, time.Now().UnixNano())
+}
+
+func (t *streamIdleTimer) fire() {
+	t.mutex.Lock()
+	if t.stopped {
+		t.mutex.Unlock()
+		return
+	}
+	idle := time.Since(time.Unix(0, atomic.LoadInt64(&t.lastActivity)))
+	if idle < t.timeout {
+		t.timer.Reset(t.timeout - idle)
+		t.mutex.Unlock()
+		return
+	}
+	t.stopped = true
+	t.mutex.Unlock()
+	t.onIdle()
+}
+
+// Stop is called when the stream is completed, or closed for shutdown.
+func (t *streamIdleTimer) Stop() {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	t.stopped = true
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.mutex.Unlock()
+}
@@ -0,0 +1,59 @@
+package quic
+
+import (
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Idle Timer", func() {
+	It("calls the callback when the stream is idle", func() {
+		start := time.Now()
+		done := make(chan struct{})
+		newStreamIdleTimer(scaleDuration(20*time.Millisecond), func() { close(done) }).Start()
+		Eventually(done).Should(BeClosed())
+		Expect(time.Since(start)).To(BeNumerically(">=", scaleDuration(20*time.Millisecond)))
+	})
+
+	It("postpones the callback when there's activity on the stream", func() {
+		var called int32
+		t := newStreamIdleTimer(scaleDuration(40*time.Millisecond), func() { atomic.StoreInt32(&called, 1) })
+		t.Start()
+		for i := 0; i < 6; i++ {
+			time.Sleep(scaleDuration(10 * time.Millisecond))
+			t.Activity()
+		}
+		Expect(atomic.LoadInt32(&called)).To(BeZero())
+		Eventually(func() int32 { return atomic.LoadInt32(&called) }).Should(BeEquivalentTo(1))
+	})
+
+	It("doesn't call the callback after it was stopped", func() {
+		var called int32
+		t := newStreamIdleTimer(scaleDuration(10*time.Millisecond), func() { atomic.StoreInt32(&called, 1) })
+		t.Start()
+		t.Stop()
+		Consistently(func() int32 { return atomic.LoadInt32(&called) }, scaleDuration(50*time.Millisecond)).Should(BeZero())
+	})
+
+	It("doesn't call the callback before it was started", func() {
+		var called int32
+		newStreamIdleTimer(scaleDuration(10*time.Millisecond), func() { atomic.StoreInt32(&called, 1) })
+		Consistently(func() int32 { return atomic.LoadInt32(&called) }, scaleDuration(50*time.Millisecond)).Should(BeZero())
+	})
+
+	It("can be stopped before it was started", func() {
+		var called int32
+		t := newStreamIdleTimer(scaleDuration(10*time.Millisecond), func() { atomic.StoreInt32(&called, 1) })
+		t.Stop()
+		t.Start()
+		Consistently(func() int32 { return atomic.LoadInt32(&called) }, scaleDuration(50*time.Millisecond)).Should(BeZero())
+	})
+
+	It("can be used when the idle timeout is disabled", func() {
+		var t *streamIdleTimer
+		t.Activity()
+		t.Stop()
+	})
+})
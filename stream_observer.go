@@ -0,0 +1,70 @@
+package quic
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+// A StreamObserver is notified when streams are opened.
+// This is useful for protocols that assign semantic meaning to stream IDs,
+// e.g. to reserve the first streams for control channels (see OpenControlStreams).
+// The methods must not block.
+type StreamObserver interface {
+	// OpenedStream is called when a stream ID is allocated for a stream opened by this endpoint,
+	// i.e. when a call to Open{Uni}Stream{Sync} succeeds.
+	// It is called on the goroutine that opened the stream, before the stream is returned to the application.
+	OpenedStream(sess Session, id StreamID)
+	// AcceptedStream is called when the peer opens a stream, i.e. when the first frame for that stream is received.
+	// Opening a stream implicitly opens all streams of the same type with lower stream IDs, and it is called for each of them.
+	// This happens before the stream can be accepted, and also for streams that are rejected
+	// (see Config.AllowIncomingStream and Config.MaxStreamAcceptBacklog).
+	// It is called on the goroutine that processes the frames received on the session.
+	AcceptedStream(sess Session, id StreamID)
+}
+
+// OpenControlStreams opens the streams that an application protocol uses as control channels.
+// It opens numBidi bidirectional and numUni unidirectional streams, in that order.
+//
+// Stream IDs are allocated in the order in which streams are opened.
+// Protocols can therefore reserve the lowest stream IDs for their control channels,
+// by calling OpenControlStreams before any other stream is opened on the session.
+// The peer can then identify a control stream by its stream ID alone.
+// If any other stream was opened before, an error is returned,
+// and the application should close the session.
+// This also applies to streams opened concurrently, from a different goroutine.
+func OpenControlStreams(ctx context.Context, sess Session, numBidi, numUni int) ([]Stream, []SendStream, error) {
+	bidiStreams := make([]Stream, 0, numBidi)
+	for i := 0; i < numBidi; i++ {
+		str, err := sess.OpenStreamSync(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		bidiStreams = append(bidiStreams, str)
+		if err := checkControlStreamID(str.StreamID(), i); err != nil {
+			return nil, nil, err
+		}
+	}
+	uniStreams := make([]SendStream, 0, numUni)
+	for i := 0; i < numUni; i++ {
+		str, err := sess.OpenUniStreamSync(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		uniStreams = append(uniStreams, str)
+		if err := checkControlStreamID(str.StreamID(), i); err != nil {
+			return nil, nil, err
+		}
+	}
+	return bidiStreams, uniStreams, nil
+}
+
+// checkControlStreamID checks that the i-th control stream of a stream type has the i-th stream ID of that type.
+func checkControlStreamID(id StreamID, i int) error {
+	expected := protocol.StreamNum(i+1).StreamID(id.Type(), id.InitiatedBy())
+	if id != expected {
+		return fmt.Errorf("control stream opened with stream ID %d, expected %d", id, expected)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package quic
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type recordingStreamObserver struct {
+	mutex    sync.Mutex
+	opened   []StreamID
+	accepted []StreamID
+}
+
+func (o *recordingStreamObserver) OpenedStream(_ Session, id StreamID) {
+	o.mutex.Lock()
+	o.opened = append(o.opened, id)
+	o.mutex.Unlock()
+}
+
+func (o *recordingStreamObserver) AcceptedStream(_ Session, id StreamID) {
+	o.mutex.Lock()
+	o.accepted = append(o.accepted, id)
+	o.mutex.Unlock()
+}
+
+type streamObserverFuncs struct {
+	opened, accepted func(StreamID)
+}
+
+func (o *streamObserverFuncs) OpenedStream(_ Session, id StreamID) {
+	if o.opened != nil {
+		o.opened(id)
+	}
+}
+
+func (o *streamObserverFuncs) AcceptedStream(_ Session, id StreamID) {
+	if o.accepted != nil {
+		o.accepted(id)
+	}
+}
+
+var _ = Describe("Control Streams", func() {
+	var sess *MockQuicSession
+
+	BeforeEach(func() {
+		sess = NewMockQuicSession(mockCtrl)
+	})
+
+	mockStream := func(id protocol.StreamID) *MockStreamI {
+		str := NewMockStreamI(mockCtrl)
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		return str
+	}
+
+	mockSendStream := func(id protocol.StreamID) *MockSendStreamI {
+		str := NewMockSendStreamI(mockCtrl)
+		str.EXPECT().StreamID().Return(id).AnyTimes()
+		return str
+	}
+
+	It("opens the control streams, for the client", func() {
+		ctx := context.Background()
+		sess.EXPECT().OpenStreamSync(ctx).Return(mockStream(0), nil)
+		sess.EXPECT().OpenStreamSync(ctx).Return(mockStream(4), nil)
+		sess.EXPECT().OpenUniStreamSync(ctx).Return(mockSendStream(2), nil)
+		bidiStreams, uniStreams, err := OpenControlStreams(ctx, sess, 2, 1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bidiStreams).To(HaveLen(2))
+		Expect(bidiStreams[0].StreamID()).To(Equal(protocol.StreamID(0)))
+		Expect(bidiStreams[1].StreamID()).To(Equal(protocol.StreamID(4)))
+		Expect(uniStreams).To(HaveLen(1))
+		Expect(uniStreams[0].StreamID()).To(Equal(protocol.StreamID(2)))
+	})
+
+	It("opens the control streams, for the server", func() {
+		ctx := context.Background()
+		sess.EXPECT().OpenUniStreamSync(ctx).Return(mockSendStream(3), nil)
+		sess.EXPECT().OpenUniStreamSync(ctx).Return(mockSendStream(7), nil)
+		bidiStreams, uniStreams, err := OpenControlStreams(ctx, sess, 0, 2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(bidiStreams).To(BeEmpty())
+		Expect(uniStreams).To(HaveLen(2))
+	})
+
+	It("errors if another stream was opened before", func() {
+		ctx := context.Background()
+		sess.EXPECT().OpenStreamSync(ctx).Return(mockStream(0), nil)
+		sess.EXPECT().OpenUniStreamSync(ctx).Return(mockSendStream(6), nil)
+		_, _, err := OpenControlStreams(ctx, sess, 1, 1)
+		Expect(err).To(MatchError("control stream opened with stream ID 6, expected 2"))
+	})
+
+	It("returns errors that occur when opening a stream", func() {
+		ctx := context.Background()
+		testErr := errors.New("test error")
+		sess.EXPECT().OpenStreamSync(ctx).Return(nil, testErr)
+		_, _, err := OpenControlStreams(ctx, sess, 1, 1)
+		Expect(err).To(MatchError(testErr))
+	})
+})
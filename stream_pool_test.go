@@ -0,0 +1,97 @@
+package quic
+
+import (
+	"io"
+	"testing"
+
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/utils"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// benchmarkStreamSender is a streamSender that ignores all calls.
+type benchmarkStreamSender struct{ streamSender }
+
+func (benchmarkStreamSender) onHasStreamData(protocol.StreamID)       {}
+func (benchmarkStreamSender) onStreamCompleted(protocol.StreamID)     {}
+func (benchmarkStreamSender) onStreamWritable(func())                 {}
+func (benchmarkStreamSender) onStreamBlocked(protocol.StreamID, bool) {}
+func (benchmarkStreamSender) queueControlFrame(wire.Frame)            {}
+func (benchmarkStreamSender) onStreamFlushed(protocol.StreamID)       {}
+func (benchmarkStreamSender) trafficClassPriority(TrafficClass) (StreamPriority, bool) {
+	return StreamPriority{}, false
+}
+
+func newBenchmarkStreamFlowController(id protocol.StreamID) flowcontrol.StreamFlowController {
+	rttStats := &utils.RTTStats{}
+	cfc := flowcontrol.NewConnectionFlowController(protocol.MaxByteCount, protocol.MaxByteCount, func() {}, nil, rttStats, utils.DefaultLogger)
+	cfc.UpdateSendWindow(protocol.MaxByteCount)
+	return flowcontrol.NewStreamFlowController(id, cfc, protocol.MaxByteCount, protocol.MaxByteCount, protocol.MaxByteCount, func(protocol.StreamID) {}, nil, rttStats, utils.DefaultLogger)
+}
+
+// BenchmarkShortLivedStreams measures the allocations of a short-lived stream.
+// The frame sorter of the receive stream and the queues of the send stream are reused once the stream is completed.
+func BenchmarkShortLivedStreams(b *testing.B) {
+	const numFrames = maxQueuedStreamFrames
+	data := make([]byte, 100)
+	buf := make([]byte, len(data))
+	var frames []*wire.StreamFrame
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		id := protocol.StreamID(4 * i)
+
+		// send numFrames STREAM frames, as ReadFrom does, and acknowledge them in reverse order
+		sstr := newSendStream(id, benchmarkStreamSender{}, newBenchmarkStreamFlowController(id), protocol.VersionTLS)
+		for j := 0; j < numFrames; j++ {
+			f := wire.GetStreamFrame()
+			f.Data = append(f.Data[:0], data...)
+			if err := sstr.queueStreamFrame(f); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err := sstr.Close(); err != nil {
+			b.Fatal(err)
+		}
+		frames = frames[:0]
+		for {
+			f, hasMore := sstr.popStreamFrame(protocol.MaxByteCount)
+			if f != nil {
+				frames = append(frames, f.Frame.(*wire.StreamFrame))
+			}
+			if !hasMore {
+				break
+			}
+		}
+		for j := len(frames) - 1; j >= 0; j-- {
+			sstr.frameAcked(frames[j])
+		}
+		if !sstr.completed {
+			b.Fatal("send stream not completed")
+		}
+
+		// receive numFrames STREAM frames in reverse order, and read them
+		rstr := newReceiveStream(id, benchmarkStreamSender{}, newBenchmarkStreamFlowController(id), protocol.VersionTLS)
+		for j := numFrames - 1; j >= 0; j-- {
+			f := wire.GetStreamFrame()
+			f.Offset = protocol.ByteCount(j * len(data))
+			f.Data = append(f.Data[:0], data...)
+			f.Fin = j == numFrames-1
+			if err := rstr.handleStreamFrame(f); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for {
+			_, err := rstr.Read(buf)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+		if !rstr.isCompleted() {
+			b.Fatal("receive stream not completed")
+		}
+	}
+}
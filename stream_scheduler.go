@@ -0,0 +1,139 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+)
+
+var defaultStreamPriority = StreamPriority{Urgency: 3, Weight: 16}
+
+// The streamScheduler decides which of the active streams is allowed to send next.
+// It is not safe for concurrent use, the framer only uses it while holding its mutex.
+type streamScheduler struct {
+	scheduler StreamScheduler
+
+	// priorities contains the priorities of all streams that don't use the default priority
+	priorities map[protocol.StreamID]StreamPriority
+
+	// For the StreamSchedulerWeighted.
+	// finishTimes contains the number of bytes sent by each stream, divided by its weight.
+	// The virtualTime is the finish time of the stream that was scheduled last.
+	// A stream that becomes active starts at the virtualTime, so that it can't build up credit while being idle.
+	finishTimes map[protocol.StreamID]uint64
+	virtualTime uint64
+}
+
+func newStreamScheduler(scheduler StreamScheduler) *streamScheduler {
+	return &streamScheduler{
+		scheduler:   scheduler,
+		priorities:  make(map[protocol.StreamID]StreamPriority),
+		finishTimes: make(map[protocol.StreamID]uint64),
+	}
+}
+
+func (s *streamScheduler) SetPriority(id protocol.StreamID, prio StreamPriority) {
+	if prio == defaultStreamPriority {
+		delete(s.priorities, id)
+		return
+	}
+	s.priorities[id] = prio
+}
+
+func (s *streamScheduler) priority(id protocol.StreamID) StreamPriority {
+	if prio, ok := s.priorities[id]; ok {
+		return prio
+	}
+	return defaultStreamPriority
+}
+
+// Next returns the index of the stream in the queue that is allowed to send next.
+// The queue contains the active streams, in the order in which they were last served.
+// The queue must not be empty.
+func (s *streamScheduler) Next(queue []protocol.StreamID) int {
+	switch s.scheduler {
+	case StreamSchedulerWeighted:
+		return s.nextWeighted(queue)
+	case StreamSchedulerIncremental:
+		return s.nextIncremental(queue)
+	default:
+		return s.nextStrict(queue)
+	}
+}
+
+func (s *streamScheduler) nextStrict(queue []protocol.StreamID) int {
+	if len(s.priorities) == 0 {
+		return 0
+	}
+	var next int
+	urgency := s.priority(queue[0]).Urgency
+	for i, id := range queue[1:] {
+		if u := s.priority(id).Urgency; u < urgency {
+			next = i + 1
+			urgency = u
+		}
+	}
+	return next
+}
+
+func (s *streamScheduler) nextIncremental(queue []protocol.StreamID) int {
+	next := -1
+	var nextPrio StreamPriority
+	for i, id := range queue {
+		prio := s.priority(id)
+		if next == -1 || prio.Urgency < nextPrio.Urgency {
+			next = i
+			nextPrio = prio
+			continue
+		}
+		if prio.Urgency > nextPrio.Urgency || prio.Incremental {
+			continue
+		}
+		// Non-incremental streams are preferred over incremental streams,
+		// and are served in the order of their stream IDs.
+		if nextPrio.Incremental || id < queue[next] {
+			next = i
+			nextPrio = prio
+		}
+	}
+	return next
+}
+
+func (s *streamScheduler) nextWeighted(queue []protocol.StreamID) int {
+	var next int
+	var nextFinishTime uint64
+	for i, id := range queue {
+		finishTime, ok := s.finishTimes[id]
+		if !ok || finishTime < s.virtualTime {
+			finishTime = s.virtualTime
+			s.finishTimes[id] = finishTime
+		}
+		if i == 0 || finishTime < nextFinishTime {
+			next = i
+			nextFinishTime = finishTime
+		}
+	}
+	s.virtualTime = nextFinishTime
+	return next
+}
+
+// Sent is called after a STREAM frame of length l was sent on the stream.
+func (s *streamScheduler) Sent(id protocol.StreamID, l protocol.ByteCount) {
+	if s.scheduler != StreamSchedulerWeighted {
+		return
+	}
+	weight := uint64(s.priority(id).Weight)
+	if weight == 0 {
+		weight = 1
+	}
+	s.finishTimes[id] += uint64(l) * 256 / weight
+}
+
+// Deactivated is called when a stream doesn't have any more data to send.
+func (s *streamScheduler) Deactivated(id protocol.StreamID) {
+	delete(s.finishTimes, id)
+}
+
+// Remove is called when a stream is completed.
+func (s *streamScheduler) Remove(id protocol.StreamID) {
+	delete(s.priorities, id)
+	delete(s.finishTimes, id)
+}
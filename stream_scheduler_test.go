@@ -0,0 +1,112 @@
+package quic
+
+import (
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream Scheduler", func() {
+	// schedule simulates a framer that sends a frame of size l for every stream that is scheduled,
+	// and returns the order in which the streams were scheduled
+	schedule := func(s *streamScheduler, queue []protocol.StreamID, n int, l protocol.ByteCount) []protocol.StreamID {
+		queue = append([]protocol.StreamID{}, queue...)
+		var order []protocol.StreamID
+		for i := 0; i < n; i++ {
+			next := s.Next(queue)
+			id := queue[next]
+			order = append(order, id)
+			s.Sent(id, l)
+			queue = append(append(queue[:next], queue[next+1:]...), id)
+		}
+		return order
+	}
+
+	Context("strict", func() {
+		It("serves streams round-robin, if they all use the default priority", func() {
+			s := newStreamScheduler(StreamSchedulerStrict)
+			Expect(schedule(s, []protocol.StreamID{4, 0, 8}, 6, 100)).To(Equal([]protocol.StreamID{4, 0, 8, 4, 0, 8}))
+		})
+
+		It("serves more urgent streams first", func() {
+			s := newStreamScheduler(StreamSchedulerStrict)
+			s.SetPriority(8, StreamPriority{Urgency: 1})
+			s.SetPriority(12, StreamPriority{Urgency: 1})
+			s.SetPriority(0, StreamPriority{Urgency: 5})
+			Expect(schedule(s, []protocol.StreamID{0, 4, 8, 12}, 4, 100)).To(Equal([]protocol.StreamID{8, 12, 8, 12}))
+		})
+
+		It("forgets the priority of removed streams", func() {
+			s := newStreamScheduler(StreamSchedulerStrict)
+			s.SetPriority(4, StreamPriority{Urgency: 0})
+			Expect(s.Next([]protocol.StreamID{0, 4})).To(Equal(1))
+			s.Remove(4)
+			Expect(s.priorities).To(BeEmpty())
+			Expect(s.Next([]protocol.StreamID{0, 4})).To(BeZero())
+		})
+
+		It("doesn't store the default priority", func() {
+			s := newStreamScheduler(StreamSchedulerStrict)
+			s.SetPriority(4, StreamPriority{Urgency: 0})
+			s.SetPriority(4, defaultStreamPriority)
+			Expect(s.priorities).To(BeEmpty())
+		})
+	})
+
+	Context("incremental", func() {
+		It("serves non-incremental streams one after the other", func() {
+			s := newStreamScheduler(StreamSchedulerIncremental)
+			Expect(schedule(s, []protocol.StreamID{8, 0, 4}, 3, 100)).To(Equal([]protocol.StreamID{0, 0, 0}))
+		})
+
+		It("serves incremental streams round-robin, after non-incremental streams", func() {
+			s := newStreamScheduler(StreamSchedulerIncremental)
+			s.SetPriority(0, StreamPriority{Urgency: 3, Incremental: true})
+			s.SetPriority(4, StreamPriority{Urgency: 3, Incremental: true})
+			Expect(schedule(s, []protocol.StreamID{0, 4, 8}, 2, 100)).To(Equal([]protocol.StreamID{8, 8}))
+			Expect(schedule(s, []protocol.StreamID{0, 4}, 4, 100)).To(Equal([]protocol.StreamID{0, 4, 0, 4}))
+		})
+
+		It("serves more urgent streams first", func() {
+			s := newStreamScheduler(StreamSchedulerIncremental)
+			s.SetPriority(8, StreamPriority{Urgency: 0, Incremental: true})
+			Expect(schedule(s, []protocol.StreamID{0, 4, 8}, 2, 100)).To(Equal([]protocol.StreamID{8, 8}))
+		})
+	})
+
+	Context("weighted", func() {
+		It("shares the bandwidth according to the weights", func() {
+			s := newStreamScheduler(StreamSchedulerWeighted)
+			s.SetPriority(0, StreamPriority{Weight: 30})
+			s.SetPriority(4, StreamPriority{Weight: 10})
+			counts := make(map[protocol.StreamID]int)
+			for _, id := range schedule(s, []protocol.StreamID{0, 4}, 400, 1000) {
+				counts[id]++
+			}
+			Expect(counts[0]).To(BeNumerically("~", 300, 2))
+			Expect(counts[4]).To(BeNumerically("~", 100, 2))
+		})
+
+		It("ignores the urgency", func() {
+			s := newStreamScheduler(StreamSchedulerWeighted)
+			s.SetPriority(4, StreamPriority{Urgency: 0, Weight: 16})
+			Expect(schedule(s, []protocol.StreamID{0, 4}, 4, 100)).To(Equal([]protocol.StreamID{0, 4, 0, 4}))
+		})
+
+		It("treats a weight of 0 as 1", func() {
+			s := newStreamScheduler(StreamSchedulerWeighted)
+			s.SetPriority(0, StreamPriority{Weight: 0})
+			s.SetPriority(4, StreamPriority{Weight: 1})
+			Expect(schedule(s, []protocol.StreamID{0, 4}, 4, 100)).To(Equal([]protocol.StreamID{0, 4, 0, 4}))
+		})
+
+		It("doesn't let streams build up credit while they are inactive", func() {
+			s := newStreamScheduler(StreamSchedulerWeighted)
+			Expect(schedule(s, []protocol.StreamID{0}, 10, 100)).To(HaveLen(10))
+			s.Deactivated(0)
+			// stream 4 becomes active, and isn't allowed to send 10 frames in a row
+			Expect(schedule(s, []protocol.StreamID{0, 4}, 4, 100)).To(Equal([]protocol.StreamID{0, 4, 0, 4}))
+		})
+	})
+})
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/mocks"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
 	"github.com/lucas-clemente/quic-go/internal/wire"
@@ -56,6 +57,25 @@ var _ = Describe("Stream", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("returns the flow control state", func() {
+		mockFC.EXPECT().State().Return(flowcontrol.WindowState{
+			BytesSent:       100,
+			SendWindow:      100,
+			BytesRead:       10,
+			HighestReceived: 20,
+			ReceiveWindow:   30,
+		})
+		state := str.FlowControlState()
+		Expect(state).To(Equal(FlowControlState{
+			BytesSent:     100,
+			SendLimit:     100,
+			BytesReceived: 20,
+			BytesRead:     10,
+			ReceiveLimit:  30,
+		}))
+		Expect(state.SendBlocked()).To(BeTrue())
+	})
+
 	Context("deadlines", func() {
 		It("sets a write deadline, when SetDeadline is called", func() {
 			str.SetDeadline(time.Now().Add(-time.Second))
@@ -89,8 +109,12 @@ var _ = Describe("Stream", func() {
 		})
 
 		It("is completed when both sides are completed", func() {
-			mockSender.EXPECT().onStreamCompleted(streamID)
+			// the stream is reported as completed before the sender is notified
+			mockSender.EXPECT().onStreamCompleted(streamID).Do(func(protocol.StreamID) {
+				Expect(str.isCompleted()).To(BeTrue())
+			})
 			str.sendStream.sender.onStreamCompleted(streamID)
+			Expect(str.isCompleted()).To(BeFalse())
 			str.receiveStream.sender.onStreamCompleted(streamID)
 		})
 	})
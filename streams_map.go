@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/lucas-clemente/quic-go/internal/flowcontrol"
 	"github.com/lucas-clemente/quic-go/internal/protocol"
@@ -44,6 +45,42 @@ func (streamOpenErr) Timeout() bool     { return false }
 // errTooManyOpenStreams is used internally by the outgoing streams maps.
 var errTooManyOpenStreams = errors.New("too many open streams")
 
+// streamAdmission decides which streams opened by the peer are admitted,
+// see Config.AllowIncomingStream and Config.MaxStreamAcceptBacklog.
+// It also notifies the StreamObserver about streams opened by the peer.
+type streamAdmission struct {
+	allow                                     func(protocol.StreamID) (bool, StreamErrorCode) // nil if all streams are allowed
+	opened                                    func(protocol.StreamID)                         // nil if no StreamObserver is set
+	maxBidiAcceptBacklog, maxUniAcceptBacklog int                                             // 0 if the backlog is not limited
+	backlogErrorCode                          StreamErrorCode
+}
+
+// rejectBacklog rejects streams if the accept backlog is full.
+func (a *streamAdmission) rejectBacklog(maxBacklog, numPending int) (bool, StreamErrorCode) {
+	if maxBacklog > 0 && numPending >= maxBacklog {
+		return true, a.backlogErrorCode
+	}
+	return false, 0
+}
+
+// allowStream returns nil if all streams are allowed.
+func (a *streamAdmission) allowStream(streamType protocol.StreamType, pers protocol.Perspective) func(protocol.StreamNum) (bool, StreamErrorCode) {
+	if a.allow == nil {
+		return nil
+	}
+	return func(num protocol.StreamNum) (bool, StreamErrorCode) {
+		return a.allow(num.StreamID(streamType, pers))
+	}
+}
+
+// streamOpened returns nil if no notifications are needed.
+func (a *streamAdmission) streamOpened(streamType protocol.StreamType, pers protocol.Perspective) func(protocol.StreamNum) {
+	if a.opened == nil {
+		return nil
+	}
+	return func(num protocol.StreamNum) { a.opened(num.StreamID(streamType, pers)) }
+}
+
 type streamsMap struct {
 	perspective protocol.Perspective
 	version     protocol.VersionNumber
@@ -54,6 +91,15 @@ type streamsMap struct {
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
 
+	// streams are canceled with the streamIdleErrorCode if no STREAM frames are sent or received for the streamIdleTimeout
+	streamIdleTimeout   time.Duration
+	streamIdleErrorCode StreamErrorCode
+
+	admission streamAdmission
+	// reassemblyMemory is nil if the memory held by out-of-order stream data is not limited
+	reassemblyMemory *reassemblyMemory
+	errorRegistry    *StreamErrorRegistry
+
 	mutex               sync.Mutex
 	outgoingBidiStreams *outgoingBidiStreamsMap
 	outgoingUniStreams  *outgoingUniStreamsMap
@@ -69,6 +115,11 @@ func newStreamsMap(
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
+	streamIdleTimeout time.Duration,
+	streamIdleErrorCode StreamErrorCode,
+	admission streamAdmission,
+	reassemblyMemory *reassemblyMemory,
+	errorRegistry *StreamErrorRegistry,
 	perspective protocol.Perspective,
 	version protocol.VersionNumber,
 ) streamManager {
@@ -77,6 +128,11 @@ func newStreamsMap(
 		newFlowController:      newFlowController,
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
+		streamIdleTimeout:      streamIdleTimeout,
+		streamIdleErrorCode:    streamIdleErrorCode,
+		admission:              admission,
+		reassemblyMemory:       reassemblyMemory,
+		errorRegistry:          errorRegistry,
 		sender:                 sender,
 		version:                version,
 	}
@@ -87,36 +143,110 @@ func newStreamsMap(
 func (m *streamsMap) initMaps() {
 	m.outgoingBidiStreams = newOutgoingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
-			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
+			return m.newBidiStream(num.StreamID(protocol.StreamTypeBidi, m.perspective))
 		},
 		m.sender.queueControlFrame,
 	)
 	m.incomingBidiStreams = newIncomingBidiStreamsMap(
 		func(num protocol.StreamNum) streamI {
-			id := num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite())
-			return newStream(id, m.sender, m.newFlowController(id), m.version)
+			return m.newBidiStream(num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite()))
 		},
 		m.maxIncomingBidiStreams,
 		m.sender.queueControlFrame,
+		func(_ protocol.StreamNum, numPending int) (bool, StreamErrorCode) {
+			return m.admission.rejectBacklog(m.admission.maxBidiAcceptBacklog, numPending)
+		},
+		m.admission.allowStream(protocol.StreamTypeBidi, m.perspective.Opposite()),
+		func(str streamI, errorCode StreamErrorCode) {
+			str.CancelRead(errorCode)
+			str.CancelWrite(errorCode)
+		},
+		m.admission.streamOpened(protocol.StreamTypeBidi, m.perspective.Opposite()),
 	)
 	m.outgoingUniStreams = newOutgoingUniStreamsMap(
 		func(num protocol.StreamNum) sendStreamI {
-			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
-			return newSendStream(id, m.sender, m.newFlowController(id), m.version)
+			return m.newSendStream(num.StreamID(protocol.StreamTypeUni, m.perspective))
 		},
 		m.sender.queueControlFrame,
 	)
 	m.incomingUniStreams = newIncomingUniStreamsMap(
 		func(num protocol.StreamNum) receiveStreamI {
-			id := num.StreamID(protocol.StreamTypeUni, m.perspective.Opposite())
-			return newReceiveStream(id, m.sender, m.newFlowController(id), m.version)
+			return m.newReceiveStream(num.StreamID(protocol.StreamTypeUni, m.perspective.Opposite()))
 		},
 		m.maxIncomingUniStreams,
 		m.sender.queueControlFrame,
+		func(_ protocol.StreamNum, numPending int) (bool, StreamErrorCode) {
+			return m.admission.rejectBacklog(m.admission.maxUniAcceptBacklog, numPending)
+		},
+		m.admission.allowStream(protocol.StreamTypeUni, m.perspective.Opposite()),
+		func(str receiveStreamI, errorCode StreamErrorCode) { str.CancelRead(errorCode) },
+		m.admission.streamOpened(protocol.StreamTypeUni, m.perspective.Opposite()),
 	)
 }
 
+// newBidiStream creates a new bidirectional stream.
+// If the idle timeout is enabled, both stream halves share one idle timer.
+func (m *streamsMap) newBidiStream(id protocol.StreamID) streamI {
+	str := newStream(id, m.sender, m.newFlowController(id), m.version)
+	str.receiveStream.setReassemblyMemory(m.reassemblyMemory)
+	str.receiveStream.errorRegistry = m.errorRegistry
+	str.sendStream.errorRegistry = m.errorRegistry
+	if m.streamIdleTimeout > 0 {
+		t := newStreamIdleTimer(m.streamIdleTimeout, func() {
+			str.CancelRead(m.streamIdleErrorCode)
+			str.CancelWrite(m.streamIdleErrorCode)
+		})
+		str.sendStream.idleTimer = t
+		str.receiveStream.idleTimer = t
+		t.Start()
+	}
+	return str
+}
+
+// newSendStream creates a new unidirectional send stream.
+// If the idle timeout is enabled, the idle timer is stopped when the stream is completed.
+func (m *streamsMap) newSendStream(id protocol.StreamID) sendStreamI {
+	if m.streamIdleTimeout == 0 {
+		str := newSendStream(id, m.sender, m.newFlowController(id), m.version)
+		str.errorRegistry = m.errorRegistry
+		return str
+	}
+	var t *streamIdleTimer
+	sender := &uniStreamSender{
+		streamSender:          m.sender,
+		onStreamCompletedImpl: func() { t.Stop(); m.sender.onStreamCompleted(id) },
+	}
+	str := newSendStream(id, sender, m.newFlowController(id), m.version)
+	str.errorRegistry = m.errorRegistry
+	t = newStreamIdleTimer(m.streamIdleTimeout, func() { str.CancelWrite(m.streamIdleErrorCode) })
+	str.idleTimer = t
+	t.Start()
+	return str
+}
+
+// newReceiveStream creates a new unidirectional receive stream.
+// If the idle timeout is enabled, the idle timer is stopped when the stream is completed.
+func (m *streamsMap) newReceiveStream(id protocol.StreamID) receiveStreamI {
+	if m.streamIdleTimeout == 0 {
+		str := newReceiveStream(id, m.sender, m.newFlowController(id), m.version)
+		str.setReassemblyMemory(m.reassemblyMemory)
+		str.errorRegistry = m.errorRegistry
+		return str
+	}
+	var t *streamIdleTimer
+	sender := &uniStreamSender{
+		streamSender:          m.sender,
+		onStreamCompletedImpl: func() { t.Stop(); m.sender.onStreamCompleted(id) },
+	}
+	str := newReceiveStream(id, sender, m.newFlowController(id), m.version)
+	str.setReassemblyMemory(m.reassemblyMemory)
+	str.errorRegistry = m.errorRegistry
+	t = newStreamIdleTimer(m.streamIdleTimeout, func() { str.CancelRead(m.streamIdleErrorCode) })
+	str.idleTimer = t
+	t.Start()
+	return str
+}
+
 func (m *streamsMap) OpenStream() (Stream, error) {
 	m.mutex.Lock()
 	reset := m.reset
@@ -290,6 +420,22 @@ func (m *streamsMap) UpdateLimits(p *wire.TransportParameters) {
 	m.outgoingUniStreams.SetMaxStream(p.MaxUniStreamNum)
 }
 
+// SetMaxIncomingStreams changes the number of bidirectional streams that the peer is allowed to open.
+func (m *streamsMap) SetMaxIncomingStreams(n uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.maxIncomingBidiStreams = n
+	m.incomingBidiStreams.SetMaxNumStreams(n)
+}
+
+// SetMaxIncomingUniStreams changes the number of unidirectional streams that the peer is allowed to open.
+func (m *streamsMap) SetMaxIncomingUniStreams(n uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.maxIncomingUniStreams = n
+	m.incomingUniStreams.SetMaxNumStreams(n)
+}
+
 func (m *streamsMap) CloseWithError(err error) {
 	m.outgoingBidiStreams.CloseWithError(err)
 	m.outgoingUniStreams.CloseWithError(err)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -38,18 +39,28 @@ var _ = Describe("Streams Map (incoming)", func() {
 		newItemCounter int
 		mockSender     *MockStreamSender
 		maxNumStreams  uint64
+		rejectStream   func(protocol.StreamNum, int) (bool, StreamErrorCode)
+		allowStream    func(protocol.StreamNum) (bool, StreamErrorCode)
+		cancelStream   func(item, StreamErrorCode)
+		streamOpened   func(protocol.StreamNum)
 	)
 
 	// check that the frame can be serialized and deserialized
 	checkFrameSerialization := func(f wire.Frame) {
 		b := &bytes.Buffer{}
 		ExpectWithOffset(1, f.Write(b, protocol.VersionTLS)).To(Succeed())
-		frame, err := wire.NewFrameParser(false, protocol.VersionTLS).ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
+		frame, err := wire.NewFrameParser(false, false, false, protocol.VersionTLS).ParseNext(bytes.NewReader(b.Bytes()), protocol.Encryption1RTT)
 		ExpectWithOffset(1, err).ToNot(HaveOccurred())
 		Expect(f).To(Equal(frame))
 	}
 
-	BeforeEach(func() { maxNumStreams = 5 })
+	BeforeEach(func() {
+		maxNumStreams = 5
+		rejectStream = nil
+		allowStream = nil
+		cancelStream = nil
+		streamOpened = nil
+	})
 
 	JustBeforeEach(func() {
 		newItemCounter = 0
@@ -61,6 +72,10 @@ var _ = Describe("Streams Map (incoming)", func() {
 			},
 			maxNumStreams,
 			mockSender.queueControlFrame,
+			rejectStream,
+			allowStream,
+			cancelStream,
+			streamOpened,
 		)
 	})
 
@@ -237,6 +252,261 @@ var _ = Describe("Streams Map (incoming)", func() {
 		Expect(m.DeleteStream(4)).To(Succeed())
 	})
 
+	It("sends a MAX_STREAMS frame when the limit is raised", func() {
+		mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: streamTypeGeneric, MaxStreamNum: protocol.StreamNum(maxNumStreams + 2)})
+		m.SetMaxNumStreams(maxNumStreams + 2)
+		_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams + 2))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("only grants new streams after enough streams were closed, when the limit is lowered", func() {
+		_, err := m.GetOrOpenStream(protocol.StreamNum(maxNumStreams))
+		Expect(err).ToNot(HaveOccurred())
+		for i := 0; i < int(maxNumStreams); i++ {
+			_, err := m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+		}
+		m.SetMaxNumStreams(maxNumStreams - 2)
+		Expect(m.DeleteStream(1)).To(Succeed())
+		Expect(m.DeleteStream(2)).To(Succeed())
+		mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: streamTypeGeneric, MaxStreamNum: protocol.StreamNum(maxNumStreams + 1)})
+		Expect(m.DeleteStream(3)).To(Succeed())
+	})
+
+	Context("rejecting streams", func() {
+		type canceledStream struct {
+			num       protocol.StreamNum
+			errorCode StreamErrorCode
+		}
+		var canceled []canceledStream
+
+		BeforeEach(func() {
+			canceled = nil
+			rejectStream = func(num protocol.StreamNum, _ int) (bool, StreamErrorCode) {
+				return num%2 == 0, StreamErrorCode(num)
+			}
+			cancelStream = func(str item, errorCode StreamErrorCode) {
+				canceled = append(canceled, canceledStream{num: str.(*mockGenericStream).num, errorCode: errorCode})
+			}
+		})
+
+		It("cancels rejected streams", func() {
+			str, err := m.GetOrOpenStream(4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(4)))
+			Expect(canceled).To(Equal([]canceledStream{{num: 2, errorCode: 2}, {num: 4, errorCode: 4}}))
+		})
+
+		It("doesn't hold the mutex when canceling streams", func() {
+			m.cancelStream = func(str item, _ StreamErrorCode) {
+				Expect(m.DeleteStream(str.(*mockGenericStream).num)).To(Succeed())
+			}
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("doesn't accept rejected streams", func() {
+			_, err := m.GetOrOpenStream(4)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Millisecond))
+			defer cancel()
+			_, err = m.AcceptStream(ctx)
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+		})
+
+		It("skips rejected streams that were already deleted", func() {
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			// rejected streams are deleted right away, even though they were never accepted
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			Expect(m.DeleteStream(2)).To(Succeed())
+			str, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(BeNil())
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+		})
+
+		It("tells the callback how many streams are waiting to be accepted", func() {
+			var pending []int
+			m.rejectStream = func(_ protocol.StreamNum, numPending int) (bool, StreamErrorCode) {
+				pending = append(pending, numPending)
+				return numPending >= 2, 0
+			}
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pending).To(Equal([]int{0, 1, 2}))
+			_, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.GetOrOpenStream(4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pending).To(Equal([]int{0, 1, 2, 1}))
+		})
+	})
+
+	Context("notifying about new streams", func() {
+		var opened []protocol.StreamNum
+
+		BeforeEach(func() {
+			opened = nil
+			streamOpened = func(num protocol.StreamNum) { opened = append(opened, num) }
+		})
+
+		It("notifies when the peer opens streams", func() {
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]protocol.StreamNum{1, 2}))
+			_, err = m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]protocol.StreamNum{1, 2, 3}))
+		})
+
+		It("notifies before the application accepts the stream", func() {
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]protocol.StreamNum{1}))
+			_, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(opened).To(Equal([]protocol.StreamNum{1}))
+		})
+
+		It("notifies about rejected streams, before asking the application", func() {
+			var events []string
+			m.streamOpened = func(num protocol.StreamNum) { events = append(events, fmt.Sprintf("opened %d", num)) }
+			m.rejectStream = func(num protocol.StreamNum, _ int) (bool, StreamErrorCode) { return num == 1, 0 }
+			m.allowStream = func(num protocol.StreamNum) (bool, StreamErrorCode) {
+				events = append(events, fmt.Sprintf("allow %d", num))
+				return true, 0
+			}
+			m.cancelStream = func(item, StreamErrorCode) {}
+			_, err := m.GetOrOpenStream(2)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(events).To(Equal([]string{"opened 1", "opened 2", "allow 2"}))
+		})
+
+		It("doesn't hold the mutex when notifying", func() {
+			m.streamOpened = func(protocol.StreamNum) {
+				_, err := m.GetOrOpenStream(1)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("asking the application if streams are allowed", func() {
+		type canceledStream struct {
+			num       protocol.StreamNum
+			errorCode StreamErrorCode
+		}
+		var canceled []canceledStream
+
+		BeforeEach(func() {
+			canceled = nil
+			allowStream = func(num protocol.StreamNum) (bool, StreamErrorCode) {
+				return num%2 == 1, StreamErrorCode(num)
+			}
+			cancelStream = func(str item, errorCode StreamErrorCode) {
+				canceled = append(canceled, canceledStream{num: str.(*mockGenericStream).num, errorCode: errorCode})
+			}
+		})
+
+		It("cancels streams that are not allowed", func() {
+			str, err := m.GetOrOpenStream(4)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(4)))
+			Expect(canceled).To(Equal([]canceledStream{{num: 2, errorCode: 2}, {num: 4, errorCode: 4}}))
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+			str, err = m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(3)))
+		})
+
+		It("doesn't ask the application about streams rejected because of the backlog", func() {
+			var asked []protocol.StreamNum
+			m.rejectStream = func(num protocol.StreamNum, _ int) (bool, StreamErrorCode) { return num == 2, 0 }
+			m.allowStream = func(num protocol.StreamNum) (bool, StreamErrorCode) {
+				asked = append(asked, num)
+				return true, 0
+			}
+			_, err := m.GetOrOpenStream(3)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(asked).To(Equal([]protocol.StreamNum{1, 3}))
+		})
+
+		It("doesn't hold the mutex when asking the application", func() {
+			m.allowStream = func(num protocol.StreamNum) (bool, StreamErrorCode) {
+				// stream 1 is not returned by AcceptStream until the application allowed it
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Millisecond))
+				defer cancel()
+				_, err := m.AcceptStream(ctx)
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+				str, err := m.GetOrOpenStream(num)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.(*mockGenericStream).num).To(Equal(num))
+				return true, 0
+			}
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := m.AcceptStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+		})
+
+		It("accepts a stream once the application allowed it", func() {
+			allowed := make(chan struct{})
+			m.allowStream = func(protocol.StreamNum) (bool, StreamErrorCode) {
+				<-allowed
+				return true, 0
+			}
+			go func() {
+				defer GinkgoRecover()
+				_, err := m.GetOrOpenStream(1)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			accepted := make(chan item)
+			go func() {
+				defer GinkgoRecover()
+				str, err := m.AcceptStream(context.Background())
+				Expect(err).ToNot(HaveOccurred())
+				accepted <- str
+			}()
+			Consistently(accepted, scaleDuration(20*time.Millisecond)).ShouldNot(Receive())
+			close(allowed)
+			var str item
+			Eventually(accepted).Should(Receive(&str))
+			Expect(str.(*mockGenericStream).num).To(Equal(protocol.StreamNum(1)))
+		})
+
+		It("deletes streams that were completed before the application rejected them", func() {
+			m.allowStream = func(num protocol.StreamNum) (bool, StreamErrorCode) {
+				Expect(m.DeleteStream(num)).To(Succeed())
+				return false, 0
+			}
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			_, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			str, err := m.GetOrOpenStream(1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(str).To(BeNil())
+		})
+	})
+
 	Context("using high stream limits", func() {
 		BeforeEach(func() { maxNumStreams = uint64(protocol.MaxStreamCount) - 2 })
 
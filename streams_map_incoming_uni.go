@@ -17,6 +17,11 @@ import (
 type receiveStreamIEntry struct {
 	stream       receiveStreamI
 	shouldDelete bool
+	// rejected streams are never returned by AcceptStream
+	rejected bool
+	// undecided is set while the application decides if the stream is allowed.
+	// AcceptStream waits until the decision was made.
+	undecided bool
 }
 
 type incomingUniStreamsMap struct {
@@ -29,9 +34,21 @@ type incomingUniStreamsMap struct {
 	nextStreamToOpen   protocol.StreamNum // the highest stream that the peer opened
 	maxStream          protocol.StreamNum // the highest stream that the peer is allowed to open
 	maxNumStreams      uint64             // maximum number of streams
+	numPending         int                // number of streams opened by the peer that were not yet accepted (excluding rejected streams)
 
 	newStream        func(protocol.StreamNum) receiveStreamI
 	queueMaxStreamID func(*wire.MaxStreamsFrame)
+	// rejectStream decides if a stream opened by the peer is rejected, and which error code is used to cancel it.
+	// It is called while holding the mutex. It is nil if all streams are admitted.
+	rejectStream func(num protocol.StreamNum, numPending int) (bool, StreamErrorCode)
+	// allowStream is called for the streams that were not rejected by rejectStream.
+	// It calls into the application, and is therefore called without holding the mutex.
+	// It is nil if all streams are allowed.
+	allowStream  func(protocol.StreamNum) (bool, StreamErrorCode)
+	cancelStream func(receiveStreamI, StreamErrorCode)
+	// streamOpened is called for every stream opened by the peer, including rejected streams.
+	// It is called without holding the mutex, before allowStream. It is nil if no notifications are needed.
+	streamOpened func(protocol.StreamNum)
 
 	closeErr error
 }
@@ -40,6 +57,10 @@ func newIncomingUniStreamsMap(
 	newStream func(protocol.StreamNum) receiveStreamI,
 	maxStreams uint64,
 	queueControlFrame func(wire.Frame),
+	rejectStream func(protocol.StreamNum, int) (bool, StreamErrorCode),
+	allowStream func(protocol.StreamNum) (bool, StreamErrorCode),
+	cancelStream func(receiveStreamI, StreamErrorCode),
+	streamOpened func(protocol.StreamNum),
 ) *incomingUniStreamsMap {
 	return &incomingUniStreamsMap{
 		newStreamChan:      make(chan struct{}, 1),
@@ -50,6 +71,10 @@ func newIncomingUniStreamsMap(
 		nextStreamToOpen:   1,
 		nextStreamToAccept: 1,
 		queueMaxStreamID:   func(f *wire.MaxStreamsFrame) { queueControlFrame(f) },
+		rejectStream:       rejectStream,
+		allowStream:        allowStream,
+		cancelStream:       cancelStream,
+		streamOpened:       streamOpened,
 	}
 }
 
@@ -72,9 +97,15 @@ func (m *incomingUniStreamsMap) AcceptStream(ctx context.Context) (receiveStream
 		}
 		var ok bool
 		entry, ok = m.streams[num]
-		if ok {
+		if ok && !entry.rejected && !entry.undecided {
 			break
 		}
+		// Skip rejected streams. They might already have been deleted.
+		// For undecided streams, wait until the application decided if they are allowed.
+		if !entry.undecided && (ok || num < m.nextStreamToOpen) {
+			m.nextStreamToAccept++
+			continue
+		}
 		m.mutex.Unlock()
 		select {
 		case <-ctx.Done():
@@ -84,6 +115,7 @@ func (m *incomingUniStreamsMap) AcceptStream(ctx context.Context) (receiveStream
 		m.mutex.Lock()
 	}
 	m.nextStreamToAccept++
+	m.numPending--
 	// If this stream was completed before being accepted, we can delete it now.
 	if entry.shouldDelete {
 		if err := m.deleteStream(num); err != nil {
@@ -122,19 +154,88 @@ func (m *incomingUniStreamsMap) GetOrOpenStream(num protocol.StreamNum) (receive
 	// no need to check the two error conditions from above again
 	// * maxStream can only increase, so if the id was valid before, it definitely is valid now
 	// * highestStream is only modified by this function
+	type rejectedStream struct {
+		stream    receiveStreamI
+		errorCode StreamErrorCode
+	}
+	var rejected []rejectedStream
+	var undecided []protocol.StreamNum
+	firstNewNum := m.nextStreamToOpen
 	for newNum := m.nextStreamToOpen; newNum <= num; newNum++ {
-		m.streams[newNum] = receiveStreamIEntry{stream: m.newStream(newNum)}
-		select {
-		case m.newStreamChan <- struct{}{}:
-		default:
+		entry := receiveStreamIEntry{stream: m.newStream(newNum)}
+		if m.rejectStream != nil {
+			if reject, errorCode := m.rejectStream(newNum, m.numPending); reject {
+				entry.rejected = true
+				rejected = append(rejected, rejectedStream{stream: entry.stream, errorCode: errorCode})
+			}
+		}
+		if !entry.rejected && m.allowStream != nil {
+			entry.undecided = true
+			undecided = append(undecided, newNum)
+		}
+		m.streams[newNum] = entry
+		if entry.rejected {
+			continue
+		}
+		m.numPending++
+		if !entry.undecided {
+			m.signalNewStream()
 		}
 	}
 	m.nextStreamToOpen = num + 1
 	entry := m.streams[num]
 	m.mutex.Unlock()
+
+	if m.streamOpened != nil {
+		for n := firstNewNum; n <= num; n++ {
+			m.streamOpened(n)
+		}
+	}
+	// The application might call into the session (e.g. to accept or open a stream),
+	// so it must be called without holding the mutex.
+	for _, n := range undecided {
+		allowed, errorCode := m.allowStream(n)
+		m.mutex.Lock()
+		e, ok := m.streams[n]
+		if !ok || !e.undecided || m.closeErr != nil {
+			m.mutex.Unlock()
+			continue
+		}
+		e.undecided = false
+		if allowed {
+			m.streams[n] = e
+			m.signalNewStream()
+			m.mutex.Unlock()
+			continue
+		}
+		e.rejected = true
+		m.numPending--
+		rejected = append(rejected, rejectedStream{stream: e.stream, errorCode: errorCode})
+		if e.shouldDelete { // the stream was completed while the application was deciding
+			delete(m.streams, n)
+			m.maybeQueueMaxStreams()
+		} else {
+			m.streams[n] = e
+		}
+		m.mutex.Unlock()
+	}
+	// Canceling a stream might complete it, which deletes it from the map.
+	// We therefore need to release the mutex first.
+	// Rejected streams are still returned, such that they can process frames sent by the peer.
+	for _, r := range rejected {
+		m.cancelStream(r.stream, r.errorCode)
+	}
 	return entry.stream, nil
 }
 
+// signalNewStream wakes up AcceptStream. It must be called with the mutex held.
+func (m *incomingUniStreamsMap) signalNewStream() {
+	select {
+	case m.newStreamChan <- struct{}{}:
+	default:
+	}
+}
+
 func (m *incomingUniStreamsMap) DeleteStream(num protocol.StreamNum) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -143,7 +244,8 @@ func (m *incomingUniStreamsMap) DeleteStream(num protocol.StreamNum) error {
 }
 
 func (m *incomingUniStreamsMap) deleteStream(num protocol.StreamNum) error {
-	if _, ok := m.streams[num]; !ok {
+	entry, ok := m.streams[num]
+	if !ok {
 		return streamError{
 			message: "tried to delete unknown incoming stream %d",
 			nums:    []protocol.StreamNum{num},
@@ -152,9 +254,9 @@ func (m *incomingUniStreamsMap) deleteStream(num protocol.StreamNum) error {
 
 	// Don't delete this stream yet, if it was not yet accepted.
 	// Just save it to streamsToDelete map, to make sure it is deleted as soon as it gets accepted.
-	if num >= m.nextStreamToAccept {
-		entry, ok := m.streams[num]
-		if ok && entry.shouldDelete {
+	// Rejected streams are never accepted, so they can be deleted right away.
+	if num >= m.nextStreamToAccept && !entry.rejected {
+		if entry.shouldDelete {
 			return streamError{
 				message: "tried to delete incoming stream %d multiple times",
 				nums:    []protocol.StreamNum{num},
@@ -167,20 +269,37 @@ func (m *incomingUniStreamsMap) deleteStream(num protocol.StreamNum) error {
 
 	delete(m.streams, num)
 	// queue a MAX_STREAM_ID frame, giving the peer the option to open a new stream
-	if m.maxNumStreams > uint64(len(m.streams)) {
-		maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
-		// Never send a value larger than protocol.MaxStreamCount.
-		if maxStream <= protocol.MaxStreamCount {
-			m.maxStream = maxStream
-			m.queueMaxStreamID(&wire.MaxStreamsFrame{
-				Type:         protocol.StreamTypeUni,
-				MaxStreamNum: m.maxStream,
-			})
-		}
-	}
+	m.maybeQueueMaxStreams()
 	return nil
 }
 
+// SetMaxNumStreams changes the maximum number of streams.
+// The limit of a MAX_STREAMS frame can't be decreased, so a lower limit only takes effect
+// once enough streams have been closed.
+func (m *incomingUniStreamsMap) SetMaxNumStreams(n uint64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.maxNumStreams = n
+	m.maybeQueueMaxStreams()
+}
+
+func (m *incomingUniStreamsMap) maybeQueueMaxStreams() {
+	if m.maxNumStreams <= uint64(len(m.streams)) {
+		return
+	}
+	maxStream := m.nextStreamToOpen + protocol.StreamNum(m.maxNumStreams-uint64(len(m.streams))) - 1
+	// Never send a value larger than protocol.MaxStreamCount.
+	if maxStream <= m.maxStream || maxStream > protocol.MaxStreamCount {
+		return
+	}
+	m.maxStream = maxStream
+	m.queueMaxStreamID(&wire.MaxStreamsFrame{
+		Type:         protocol.StreamTypeUni,
+		MaxStreamNum: m.maxStream,
+	})
+}
+
 func (m *incomingUniStreamsMap) CloseWithError(err error) {
 	m.mutex.Lock()
 	m.closeErr = err
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/golang/mock/gomock"
 
@@ -89,7 +90,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective, protocol.VersionWhatever).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, 0, 0, streamAdmission{}, nil, nil, perspective, protocol.VersionWhatever).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -457,6 +458,136 @@ var _ = Describe("Streams Map", func() {
 				})
 			})
 
+			Context("changing the stream limits", func() {
+				It("changes the limit for bidirectional streams", func() {
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeBidi, MaxStreamNum: MaxBidiStreamNum + 10})
+					m.SetMaxIncomingStreams(MaxBidiStreamNum + 10)
+					Expect(m.maxIncomingBidiStreams).To(BeEquivalentTo(MaxBidiStreamNum + 10))
+				})
+
+				It("changes the limit for unidirectional streams", func() {
+					mockSender.EXPECT().queueControlFrame(&wire.MaxStreamsFrame{Type: protocol.StreamTypeUni, MaxStreamNum: MaxUniStreamNum + 10})
+					m.SetMaxIncomingUniStreams(MaxUniStreamNum + 10)
+					Expect(m.maxIncomingUniStreams).To(BeEquivalentTo(MaxUniStreamNum + 10))
+				})
+			})
+
+			Context("idle timeout", func() {
+				BeforeEach(func() {
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, scaleDuration(10*time.Millisecond), 1337, streamAdmission{}, nil, nil, perspective, protocol.VersionWhatever).(*streamsMap)
+					allowUnlimitedStreams()
+				})
+
+				// The expectations are set before opening the stream, since the idle timer fires on a separate goroutine.
+				It("cancels idle bidirectional streams", func() {
+					done := make(chan struct{})
+					mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: ids.firstOutgoingBidiStream, ErrorCode: 1337})
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: ids.firstOutgoingBidiStream, ErrorCode: 1337}).Do(func(wire.Frame) { close(done) })
+					str, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream))
+					Eventually(done).Should(BeClosed())
+				})
+
+				It("cancels idle unidirectional streams", func() {
+					done := make(chan struct{})
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: ids.firstOutgoingUniStream, ErrorCode: 1337})
+					mockSender.EXPECT().onStreamCompleted(ids.firstOutgoingUniStream).Do(func(protocol.StreamID) { close(done) })
+					str, err := m.OpenUniStream()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstOutgoingUniStream))
+					Eventually(done).Should(BeClosed())
+				})
+			})
+
+			Context("admission", func() {
+				It("rejects streams that exceed the accept backlog", func() {
+					admission := streamAdmission{maxBidiAcceptBacklog: 1, maxUniAcceptBacklog: 2, backlogErrorCode: 42}
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, 0, 0, admission, nil, nil, perspective, protocol.VersionWhatever).(*streamsMap)
+					rejectedBidi := ids.firstIncomingBidiStream + 4
+					rejectedUni := ids.firstIncomingUniStream + 8
+					mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: rejectedBidi, ErrorCode: 42})
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{StreamID: rejectedBidi, ErrorCode: 42})
+					_, err := m.GetOrOpenReceiveStream(rejectedBidi)
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: rejectedUni, ErrorCode: 42})
+					_, err = m.GetOrOpenReceiveStream(rejectedUni)
+					Expect(err).ToNot(HaveOccurred())
+					str, err := m.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstIncomingBidiStream))
+					// now that a stream was accepted, the next stream is admitted again
+					_, err = m.GetOrOpenReceiveStream(rejectedBidi + 4)
+					Expect(err).ToNot(HaveOccurred())
+					str, err = m.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(rejectedBidi + 4))
+					for _, id := range []protocol.StreamID{ids.firstIncomingUniStream, ids.firstIncomingUniStream + 4} {
+						ustr, err := m.AcceptUniStream(context.Background())
+						Expect(err).ToNot(HaveOccurred())
+						Expect(ustr.StreamID()).To(Equal(id))
+					}
+				})
+
+				It("asks the callback if streams are allowed", func() {
+					var asked []protocol.StreamID
+					admission := streamAdmission{allow: func(id protocol.StreamID) (bool, StreamErrorCode) {
+						asked = append(asked, id)
+						return id != ids.firstIncomingUniStream, 1337
+					}}
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, 0, 0, admission, nil, nil, perspective, protocol.VersionWhatever).(*streamsMap)
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().queueControlFrame(&wire.StopSendingFrame{StreamID: ids.firstIncomingUniStream, ErrorCode: 1337})
+					_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream + 4)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(asked).To(Equal([]protocol.StreamID{ids.firstIncomingBidiStream, ids.firstIncomingUniStream, ids.firstIncomingUniStream + 4}))
+					str, err := m.AcceptUniStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstIncomingUniStream + 4))
+				})
+
+				It("notifies about streams opened by the peer", func() {
+					var opened []protocol.StreamID
+					admission := streamAdmission{opened: func(id protocol.StreamID) { opened = append(opened, id) }}
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, 0, 0, admission, nil, nil, perspective, protocol.VersionWhatever).(*streamsMap)
+					_, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream + 4)
+					Expect(err).ToNot(HaveOccurred())
+					_, err = m.GetOrOpenReceiveStream(ids.firstIncomingUniStream)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(opened).To(Equal([]protocol.StreamID{ids.firstIncomingBidiStream, ids.firstIncomingBidiStream + 4, ids.firstIncomingUniStream}))
+				})
+
+				It("allows the callback to use the streams map", func() {
+					admission := streamAdmission{allow: func(id protocol.StreamID) (bool, StreamErrorCode) {
+						str, err := m.OpenStream()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream))
+						rstr, err := m.GetOrOpenReceiveStream(id)
+						Expect(err).ToNot(HaveOccurred())
+						Expect(rstr.StreamID()).To(Equal(id))
+						ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(10*time.Millisecond))
+						defer cancel()
+						_, err = m.AcceptStream(ctx)
+						Expect(err).To(MatchError(context.DeadlineExceeded))
+						return true, 0
+					}}
+					m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, 0, 0, admission, nil, nil, perspective, protocol.VersionWhatever).(*streamsMap)
+					m.UpdateLimits(&wire.TransportParameters{MaxBidiStreamNum: 1})
+					done := make(chan struct{})
+					go func() {
+						defer GinkgoRecover()
+						defer close(done)
+						_, err := m.GetOrOpenReceiveStream(ids.firstIncomingBidiStream)
+						Expect(err).ToNot(HaveOccurred())
+					}()
+					Eventually(done).Should(BeClosed())
+					str, err := m.AcceptStream(context.Background())
+					Expect(err).ToNot(HaveOccurred())
+					Expect(str.StreamID()).To(Equal(ids.firstIncomingBidiStream))
+				})
+			})
+
 			It("closes", func() {
 				testErr := errors.New("test error")
 				m.CloseWithError(testErr)
@@ -0,0 +1,82 @@
+package unistream
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+// A Handler handles a unidirectional stream.
+// The stream type was already read from the stream.
+type Handler func(sess quic.Session, str quic.ReceiveStream)
+
+// A Dispatcher accepts unidirectional streams on a session, reads their stream types
+// and dispatches them to the handlers registered for these stream types.
+// The zero value is a Dispatcher without any handlers.
+//
+// Handlers can be registered while the Dispatcher is serving sessions.
+// A single Dispatcher can serve many sessions.
+type Dispatcher struct {
+	// UnknownStreamType is called for streams with a stream type that no handler is registered for.
+	// If nil, reading from the stream is canceled with the UnknownStreamTypeErrorCode.
+	UnknownStreamType func(sess quic.Session, str quic.ReceiveStream, streamType uint64)
+	// UnknownStreamTypeErrorCode is the error code used to cancel streams with an unknown stream type.
+	// HTTP/3 uses H3_STREAM_CREATION_ERROR (0x103).
+	UnknownStreamTypeErrorCode quic.StreamErrorCode
+
+	mutex    sync.RWMutex
+	handlers map[uint64]Handler
+}
+
+// Handle registers the handler for the given stream type.
+// If a handler already exists for this stream type, Handle panics.
+func (d *Dispatcher) Handle(streamType uint64, handler Handler) {
+	if handler == nil {
+		panic("unistream: nil handler")
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if _, ok := d.handlers[streamType]; ok {
+		panic(fmt.Sprintf("unistream: multiple registrations for stream type %#x", streamType))
+	}
+	if d.handlers == nil {
+		d.handlers = make(map[uint64]Handler)
+	}
+	d.handlers[streamType] = handler
+}
+
+// Serve accepts unidirectional streams on the session and dispatches them.
+// Every stream is handled in its own goroutine.
+// It returns when the session is closed.
+func (d *Dispatcher) Serve(sess quic.Session) error {
+	ctx := sess.Context()
+	for {
+		str, err := sess.AcceptUniStream(ctx)
+		if err != nil {
+			return err
+		}
+		go d.dispatch(sess, str)
+	}
+}
+
+func (d *Dispatcher) dispatch(sess quic.Session, str quic.ReceiveStream) {
+	streamType, err := ReadStreamType(str)
+	if err != nil {
+		// The peer reset the stream, or the session was closed.
+		return
+	}
+	d.mutex.RLock()
+	handler, ok := d.handlers[streamType]
+	d.mutex.RUnlock()
+	if ok {
+		handler(sess, str)
+		return
+	}
+	if d.UnknownStreamType != nil {
+		d.UnknownStreamType(sess, str, streamType)
+		return
+	}
+	str.CancelRead(d.UnknownStreamTypeErrorCode)
+}
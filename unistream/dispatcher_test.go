@@ -0,0 +1,126 @@
+package unistream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+
+	"github.com/golang/mock/gomock"
+	"github.com/lucas-clemente/quic-go"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dispatcher", func() {
+	var (
+		sess   *mockquic.MockEarlySession
+		closed chan struct{}
+		done   chan struct{}
+	)
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+		closed = make(chan struct{})
+		done = make(chan struct{})
+		sess.EXPECT().Context().Return(context.Background()).AnyTimes()
+	})
+
+	AfterEach(func() {
+		close(closed)
+		Eventually(done).Should(BeClosed())
+	})
+
+	newStream := func(streamType uint64, data []byte) *mockquic.MockStream {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, streamType)
+		b.Write(data)
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(b.Read).AnyTimes()
+		return str
+	}
+
+	serve := func(d *Dispatcher, streams ...quic.ReceiveStream) {
+		var calls []*gomock.Call
+		for _, str := range streams {
+			calls = append(calls, sess.EXPECT().AcceptUniStream(gomock.Any()).Return(str, nil))
+		}
+		calls = append(calls, sess.EXPECT().AcceptUniStream(gomock.Any()).DoAndReturn(func(context.Context) (quic.ReceiveStream, error) {
+			<-closed
+			return nil, errors.New("session closed")
+		}))
+		gomock.InOrder(calls...)
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(d.Serve(sess)).To(MatchError("session closed"))
+		}()
+	}
+
+	It("dispatches streams by stream type", func() {
+		received := make(chan string, 2)
+		d := &Dispatcher{}
+		handler := func(prefix string) Handler {
+			return func(s quic.Session, str quic.ReceiveStream) {
+				defer GinkgoRecover()
+				Expect(s).To(Equal(sess))
+				data, err := ioutil.ReadAll(str)
+				Expect(err).ToNot(HaveOccurred())
+				received <- prefix + string(data)
+			}
+		}
+		d.Handle(0x0, handler("control: "))
+		d.Handle(0x54, handler("webtransport: "))
+		serve(d, newStream(0x54, []byte("foo")), newStream(0x0, []byte("bar")))
+		var messages []string
+		for i := 0; i < 2; i++ {
+			var msg string
+			Eventually(received).Should(Receive(&msg))
+			messages = append(messages, msg)
+		}
+		Expect(messages).To(ConsistOf("webtransport: foo", "control: bar"))
+	})
+
+	It("dispatches streams to the handler registered for their stream type", func() {
+		received := make(chan []byte, 1)
+		d := &Dispatcher{}
+		d.Handle(0x0, func(quic.Session, quic.ReceiveStream) { Fail("wrong handler called") })
+		d.Handle(0x1337, func(_ quic.Session, str quic.ReceiveStream) {
+			data, _ := ioutil.ReadAll(str)
+			received <- data
+		})
+		serve(d, newStream(0x1337, []byte("foobar")))
+		Eventually(received).Should(Receive(Equal([]byte("foobar"))))
+	})
+
+	It("cancels streams with unknown stream types", func() {
+		canceled := make(chan struct{})
+		str := newStream(0x21, nil)
+		str.EXPECT().CancelRead(quic.StreamErrorCode(0x103)).Do(func(quic.StreamErrorCode) { close(canceled) })
+		serve(&Dispatcher{UnknownStreamTypeErrorCode: 0x103}, str)
+		Eventually(canceled).Should(BeClosed())
+	})
+
+	It("passes streams with unknown stream types to the UnknownStreamType callback", func() {
+		streamTypes := make(chan uint64, 1)
+		d := &Dispatcher{
+			UnknownStreamType: func(s quic.Session, _ quic.ReceiveStream, streamType uint64) {
+				streamTypes <- streamType
+			},
+		}
+		serve(d, newStream(0x21, nil))
+		Eventually(streamTypes).Should(Receive(BeEquivalentTo(0x21)))
+	})
+
+	It("panics when a handler is registered twice for the same stream type", func() {
+		d := &Dispatcher{}
+		d.Handle(0x2, func(quic.Session, quic.ReceiveStream) {})
+		Expect(func() {
+			d.Handle(0x2, func(quic.Session, quic.ReceiveStream) {})
+		}).To(PanicWith("unistream: multiple registrations for stream type 0x2"))
+		close(done)
+	})
+})
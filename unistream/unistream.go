@@ -0,0 +1,51 @@
+// Package unistream implements unidirectional streams that start with a stream type.
+//
+// Many application protocols on top of QUIC use unidirectional streams for different purposes,
+// e.g. HTTP/3 uses them for the control stream, for server push and for the QPACK encoder and decoder streams.
+// The purpose of a stream is identified by its stream type, which is sent
+// as a variable-length integer at the beginning of the stream (see section 6.2 of RFC 9114).
+//
+// Open writes the stream type when opening a stream.
+// On the receiving side, a Dispatcher reads the stream type and dispatches the stream
+// to the Handler registered for this stream type.
+package unistream
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+)
+
+// Open opens a new unidirectional stream, and writes the stream type.
+func Open(sess quic.Session, streamType uint64) (quic.SendStream, error) {
+	str, err := sess.OpenUniStream()
+	if err != nil {
+		return nil, err
+	}
+	return str, writeStreamType(str, streamType)
+}
+
+// OpenSync opens a new unidirectional stream, and writes the stream type.
+// It blocks until the stream can be opened, see quic.Session.OpenUniStreamSync.
+func OpenSync(ctx context.Context, sess quic.Session, streamType uint64) (quic.SendStream, error) {
+	str, err := sess.OpenUniStreamSync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return str, writeStreamType(str, streamType)
+}
+
+func writeStreamType(str quic.SendStream, streamType uint64) error {
+	b := &bytes.Buffer{}
+	quicvarint.Write(b, streamType)
+	_, err := str.Write(b.Bytes())
+	return err
+}
+
+// ReadStreamType reads the stream type from a unidirectional stream.
+// It doesn't read any data beyond the stream type.
+func ReadStreamType(str quic.ReceiveStream) (uint64, error) {
+	return quicvarint.Read(quicvarint.NewReader(str))
+}
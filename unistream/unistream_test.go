@@ -0,0 +1,81 @@
+package unistream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/golang/mock/gomock"
+	mockquic "github.com/lucas-clemente/quic-go/internal/mocks/quic"
+	"github.com/lucas-clemente/quic-go/quicvarint"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unidirectional Streams", func() {
+	var sess *mockquic.MockEarlySession
+
+	BeforeEach(func() {
+		sess = mockquic.NewMockEarlySession(mockCtrl)
+	})
+
+	It("opens a stream and writes the stream type", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		sess.EXPECT().OpenUniStream().Return(str, nil)
+		str.EXPECT().Write([]byte{0x40, 0x54}).Return(2, nil)
+		s, err := Open(sess, 0x54)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+	})
+
+	It("opens a stream synchronously and writes the stream type", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		str := mockquic.NewMockStream(mockCtrl)
+		sess.EXPECT().OpenUniStreamSync(ctx).Return(str, nil)
+		str.EXPECT().Write([]byte{0x2}).Return(1, nil)
+		s, err := OpenSync(ctx, sess, 2)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(s).To(Equal(str))
+	})
+
+	It("returns errors that occur when opening a stream", func() {
+		testErr := errors.New("test error")
+		sess.EXPECT().OpenUniStream().Return(nil, testErr)
+		_, err := Open(sess, 0x54)
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("returns errors that occur when writing the stream type", func() {
+		testErr := errors.New("test error")
+		str := mockquic.NewMockStream(mockCtrl)
+		sess.EXPECT().OpenUniStream().Return(str, nil)
+		str.EXPECT().Write(gomock.Any()).Return(0, testErr)
+		_, err := Open(sess, 0x54)
+		Expect(err).To(MatchError(testErr))
+	})
+
+	It("reads the stream type, and nothing else", func() {
+		b := &bytes.Buffer{}
+		quicvarint.Write(b, 0x1337)
+		b.Write([]byte("foobar"))
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).DoAndReturn(b.Read).AnyTimes()
+		streamType, err := ReadStreamType(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(streamType).To(BeEquivalentTo(0x1337))
+		data, err := ioutil.ReadAll(str)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal([]byte("foobar")))
+	})
+
+	It("errors when the stream ends before the stream type", func() {
+		str := mockquic.NewMockStream(mockCtrl)
+		str.EXPECT().Read(gomock.Any()).Return(0, io.EOF)
+		_, err := ReadStreamType(str)
+		Expect(err).To(MatchError(io.EOF))
+	})
+})
@@ -0,0 +1,128 @@
+//go:build linux
+// +build linux
+
+package vsock
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// A Conn is a VM datagram socket.
+type Conn struct {
+	file      *os.File
+	rawConn   syscall.RawConn
+	localAddr *Addr
+}
+
+var _ net.PacketConn = &Conn{}
+
+// ListenPacket creates a datagram socket bound to the given CID and port.
+// Use CIDAny and PortAny to bind to any CID and port.
+func ListenPacket(cid, port uint32) (*Conn, error) {
+	fd, err := unix.Socket(unix.AF_VSOCK, unix.SOCK_DGRAM|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, listenError(os.NewSyscallError("socket", err))
+	}
+	if err := unix.Bind(fd, &unix.SockaddrVM{CID: cid, Port: port}); err != nil {
+		unix.Close(fd)
+		return nil, listenError(os.NewSyscallError("bind", err))
+	}
+	sa, err := unix.Getsockname(fd)
+	if err != nil {
+		unix.Close(fd)
+		return nil, listenError(os.NewSyscallError("getsockname", err))
+	}
+	localAddr := fromSockaddr(sa)
+	if localAddr == nil {
+		unix.Close(fd)
+		return nil, listenError(errors.New("unexpected socket address type"))
+	}
+	// Since the socket is non-blocking, the os.File uses the runtime's network poller.
+	// This makes deadlines work.
+	file := os.NewFile(uintptr(fd), "vsock:"+localAddr.String())
+	rawConn, err := file.SyscallConn()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Conn{file: file, rawConn: rawConn, localAddr: localAddr}, nil
+}
+
+// ReadFrom reads a datagram.
+func (c *Conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	var n int
+	var from unix.Sockaddr
+	var opErr error
+	err := c.rawConn.Read(func(fd uintptr) bool {
+		n, from, opErr = unix.Recvfrom(int(fd), b, 0)
+		return opErr != unix.EAGAIN
+	})
+	if err == nil {
+		err = opErr
+	}
+	if err != nil {
+		return 0, nil, c.opError("read", nil, err)
+	}
+	addr := fromSockaddr(from)
+	if addr == nil {
+		return 0, nil, c.opError("read", nil, errors.New("unexpected socket address type"))
+	}
+	return n, addr, nil
+}
+
+// WriteTo sends a datagram to the given address, which must be an *Addr.
+func (c *Conn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	a, ok := addr.(*Addr)
+	if !ok {
+		return 0, c.opError("write", addr, syscall.EINVAL)
+	}
+	var opErr error
+	err := c.rawConn.Write(func(fd uintptr) bool {
+		opErr = unix.Sendto(int(fd), b, 0, &unix.SockaddrVM{CID: a.CID, Port: a.Port})
+		return opErr != unix.EAGAIN
+	})
+	if err == nil {
+		err = opErr
+	}
+	if err != nil {
+		return 0, c.opError("write", addr, err)
+	}
+	return len(b), nil
+}
+
+// Close closes the socket.
+func (c *Conn) Close() error { return c.file.Close() }
+
+// LocalAddr returns the address the socket is bound to.
+func (c *Conn) LocalAddr() net.Addr { return c.localAddr }
+
+// SetDeadline sets the read and write deadlines.
+func (c *Conn) SetDeadline(t time.Time) error { return c.file.SetDeadline(t) }
+
+// SetReadDeadline sets the read deadline.
+func (c *Conn) SetReadDeadline(t time.Time) error { return c.file.SetReadDeadline(t) }
+
+// SetWriteDeadline sets the write deadline.
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.file.SetWriteDeadline(t) }
+
+func (c *Conn) opError(op string, addr net.Addr, err error) error {
+	return &net.OpError{Op: op, Net: "vsock", Source: c.localAddr, Addr: addr, Err: err}
+}
+
+func fromSockaddr(sa unix.Sockaddr) *Addr {
+	vm, ok := sa.(*unix.SockaddrVM)
+	if !ok {
+		return nil
+	}
+	return &Addr{CID: vm.CID, Port: vm.Port}
+}
+
+func listenError(err error) error {
+	return &net.OpError{Op: "listen", Net: "vsock", Err: err}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package vsock
+
+import (
+	"errors"
+	"net"
+)
+
+// A Conn is a VM datagram socket.
+// VM sockets are only supported on Linux.
+type Conn struct {
+	net.PacketConn
+}
+
+// ListenPacket creates a datagram socket bound to the given CID and port.
+// VM sockets are only supported on Linux.
+func ListenPacket(cid, port uint32) (*Conn, error) {
+	return nil, &net.OpError{Op: "listen", Net: "vsock", Err: errors.New("VM sockets are not supported on this platform")}
+}
@@ -0,0 +1,106 @@
+// Package vsock runs QUIC over datagram sockets of the Linux VM sockets address family (AF_VSOCK).
+//
+// VM sockets allow communication between a virtual machine and its host, without configuring a network.
+// Using QUIC over a VM socket provides the same API (multiplexed streams, datagrams) as QUIC over UDP.
+// A VM socket is addressed by a context ID (CID) and a port.
+// The host always uses the CID CIDHost, every virtual machine has its own CID.
+//
+// Only Linux is supported, and the VM sockets transport must support datagram sockets (SOCK_DGRAM).
+// On other platforms, and if the transport doesn't support datagram sockets, ListenPacket returns an error.
+package vsock
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/lucas-clemente/quic-go"
+)
+
+const (
+	// CIDAny is used to bind to any CID.
+	CIDAny uint32 = 0xffffffff
+	// CIDHypervisor is the CID of the hypervisor.
+	CIDHypervisor uint32 = 0
+	// CIDLocal is the CID used for local communication, i.e. within the same virtual machine or host.
+	CIDLocal uint32 = 1
+	// CIDHost is the CID of the host.
+	CIDHost uint32 = 2
+
+	// PortAny is used to bind to any available port.
+	PortAny uint32 = 0xffffffff
+)
+
+// MaxDatagramSize is the maximum size of a datagram sent over a VM socket.
+// VM sockets don't fragment datagrams, and transmit datagrams up to 64 kB.
+// QUIC packets are limited to 1452 bytes.
+const MaxDatagramSize = 1 << 16
+
+// An Addr is the address of a VM socket.
+type Addr struct {
+	CID  uint32
+	Port uint32
+}
+
+var _ net.Addr = &Addr{}
+
+// Network returns the address's network name, "vsock".
+func (a *Addr) Network() string { return "vsock" }
+
+func (a *Addr) String() string { return fmt.Sprintf("%d:%d", a.CID, a.Port) }
+
+// MaxDatagramSize returns the maximum size of a datagram sent to this address.
+// It is used by quic-go to size the QUIC packets, since Path MTU Discovery is not used for generic PacketConns.
+func (a *Addr) MaxDatagramSize() int { return MaxDatagramSize }
+
+// Listen listens for QUIC connections on the given CID and port.
+// The tls.Config and the quic.Config are used as for quic.Listen.
+// The socket is closed when the listener is closed.
+func Listen(cid, port uint32, tlsConf *tls.Config, config *quic.Config) (quic.Listener, error) {
+	conn, err := ListenPacket(cid, port)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := quic.Listen(quic.NewGenericPacketConn(conn), tlsConf, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &listener{Listener: ln, conn: conn}, nil
+}
+
+type listener struct {
+	quic.Listener
+	conn net.PacketConn
+}
+
+func (l *listener) Close() error {
+	err := l.Listener.Close()
+	l.conn.Close()
+	return err
+}
+
+// Dial establishes a new QUIC connection to the given CID and port.
+// It binds a new socket to any port, which is closed when the session is closed.
+// Since VM sockets don't use host names, the ServerName of the tls.Config should be set.
+func Dial(ctx context.Context, cid, port uint32, tlsConf *tls.Config, config *quic.Config) (quic.Session, error) {
+	var host string
+	if tlsConf != nil {
+		host = tlsConf.ServerName
+	}
+	conn, err := ListenPacket(CIDAny, PortAny)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := quic.DialContext(ctx, quic.NewGenericPacketConn(conn), &Addr{CID: cid, Port: port}, host, tlsConf, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go func() {
+		<-sess.Context().Done()
+		conn.Close()
+	}()
+	return sess, nil
+}
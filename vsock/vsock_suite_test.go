@@ -0,0 +1,13 @@
+package vsock
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestVsock(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "vsock Suite")
+}
@@ -0,0 +1,67 @@
+package vsock
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"time"
+
+	"github.com/lucas-clemente/quic-go"
+	"github.com/lucas-clemente/quic-go/internal/testdata"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VM sockets", func() {
+	It("has a string representation for addresses", func() {
+		addr := &Addr{CID: CIDHost, Port: 1337}
+		Expect(addr.Network()).To(Equal("vsock"))
+		Expect(addr.String()).To(Equal("2:1337"))
+		Expect(addr.MaxDatagramSize()).To(Equal(MaxDatagramSize))
+	})
+
+	It("runs QUIC over a local VM socket", func() {
+		conn, err := ListenPacket(CIDLocal, PortAny)
+		if err != nil {
+			Skip("VM datagram sockets not available: " + err.Error())
+		}
+		conn.Close()
+
+		tlsConf := testdata.GetTLSConfig()
+		tlsConf.NextProtos = []string{"vsock"}
+		ln, err := Listen(CIDLocal, 1337, tlsConf, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			sess, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := sess.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			data, err := ioutil.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(data).To(Equal([]byte("foobar")))
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		sess, err := Dial(
+			ctx,
+			CIDLocal,
+			1337,
+			&tls.Config{RootCAs: testdata.GetRootCA(), ServerName: "localhost", NextProtos: []string{"vsock"}},
+			&quic.Config{},
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer sess.CloseWithError(0, "")
+		str, err := sess.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write([]byte("foobar"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+	})
+})
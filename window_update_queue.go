@@ -48,7 +48,10 @@ func (q *windowUpdateQueue) QueueAll() {
 	q.mutex.Lock()
 	// queue a connection-level window update
 	if q.queuedConn {
-		q.callback(&wire.MaxDataFrame{MaximumData: q.connFlowController.GetWindowUpdate()})
+		// The offset is 0 if the WindowUpdateStrategy decided not to send a window update.
+		if offset := q.connFlowController.GetWindowUpdate(); offset > 0 {
+			q.callback(&wire.MaxDataFrame{MaximumData: offset})
+		}
 		q.queuedConn = false
 	}
 	// queue all stream-level window updates
@@ -98,6 +98,15 @@ var _ = Describe("Window Update Queue", func() {
 		}))
 	})
 
+	It("doesn't queue MAX_DATA frames if the flow controller returns an offset of 0", func() {
+		connFC.EXPECT().GetWindowUpdate()
+		q.AddConnection()
+		q.QueueAll()
+		Expect(queuedFrames).To(BeEmpty())
+		// don't EXPECT any further calls to GetWindowUpdate
+		q.QueueAll()
+	})
+
 	It("deduplicates", func() {
 		stream10 := NewMockStreamI(mockCtrl)
 		stream10.EXPECT().getWindowUpdate().Return(protocol.ByteCount(200))
@@ -0,0 +1,60 @@
+package quic
+
+import "time"
+
+// FlowControlWindow is the state of a receive flow control window.
+type FlowControlWindow struct {
+	// IsConnection says if this is the connection-level flow control window.
+	// If false, StreamID is the ID of the stream.
+	IsConnection bool
+	StreamID     StreamID
+	// BytesRead is the number of bytes that the application has read.
+	BytesRead uint64
+	// Offset is the flow control offset that was last advertised to the peer.
+	Offset uint64
+	// Size is the current size of the window.
+	// It is increased by flow control auto-tuning, if the peer consumes the window quickly.
+	Size uint64
+	// SmoothedRTT is the smoothed RTT of the connection, 0 if no RTT sample was taken yet.
+	SmoothedRTT time.Duration
+	// SinceLastUpdate is the time since the last window update was sent (or since the first byte was read).
+	// Together with SmoothedRTT, it allows sending window updates at most once per RTT.
+	SinceLastUpdate time.Duration
+}
+
+// A WindowUpdateStrategy decides when flow control window updates are sent.
+// When a window update is sent, the window is moved forward, such that the peer is allowed to send Size bytes
+// beyond BytesRead.
+// It is called on the session's run loop after the application read data from a stream, so it must not block.
+// It may be called concurrently for streams of different sessions.
+type WindowUpdateStrategy interface {
+	ShouldSendWindowUpdate(FlowControlWindow) bool
+}
+
+// The WindowUpdateStrategyFunc type is an adapter to allow the use of ordinary functions as a WindowUpdateStrategy.
+type WindowUpdateStrategyFunc func(FlowControlWindow) bool
+
+// ShouldSendWindowUpdate calls f(w).
+func (f WindowUpdateStrategyFunc) ShouldSendWindowUpdate(w FlowControlWindow) bool {
+	return f(w)
+}
+
+type windowUpdateThreshold float64
+
+// WindowUpdateThreshold returns a WindowUpdateStrategy that sends a window update
+// once more than the fraction of the window was consumed.
+// The fraction must be larger than 0, and at most 1, otherwise the Config is rejected.
+// Small values lead to frequent window updates, which is useful for interactive applications,
+// since the peer is rarely blocked by flow control.
+// Large values reduce the number of window updates for bulk transfers.
+func WindowUpdateThreshold(fraction float64) WindowUpdateStrategy {
+	return windowUpdateThreshold(fraction)
+}
+
+func (t windowUpdateThreshold) isValid() bool {
+	return t > 0 && t <= 1 // false for NaN
+}
+
+func (t windowUpdateThreshold) ShouldSendWindowUpdate(w FlowControlWindow) bool {
+	return w.Offset-w.BytesRead <= uint64(float64(w.Size)*(1-float64(t)))
+}
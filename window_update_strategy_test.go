@@ -0,0 +1,41 @@
+package quic
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Window Update Strategy", func() {
+	It("sends window updates when the threshold is exceeded", func() {
+		s := WindowUpdateThreshold(0.5)
+		Expect(s.ShouldSendWindowUpdate(FlowControlWindow{BytesRead: 1049, Offset: 1100, Size: 100})).To(BeFalse())
+		Expect(s.ShouldSendWindowUpdate(FlowControlWindow{BytesRead: 1050, Offset: 1100, Size: 100})).To(BeTrue())
+		Expect(s.ShouldSendWindowUpdate(FlowControlWindow{BytesRead: 1100, Offset: 1100, Size: 100})).To(BeTrue())
+	})
+
+	It("sends a window update once the window is consumed when the threshold is 1", func() {
+		Expect(WindowUpdateThreshold(1).ShouldSendWindowUpdate(FlowControlWindow{BytesRead: 99, Offset: 100, Size: 100})).To(BeFalse())
+		Expect(WindowUpdateThreshold(1).ShouldSendWindowUpdate(FlowControlWindow{BytesRead: 100, Offset: 100, Size: 100})).To(BeTrue())
+	})
+
+	It("rejects invalid thresholds", func() {
+		Expect(WindowUpdateThreshold(0.1).(windowUpdateThreshold).isValid()).To(BeTrue())
+		Expect(WindowUpdateThreshold(1).(windowUpdateThreshold).isValid()).To(BeTrue())
+		for _, f := range []float64{-0.1, 0, 1.1, math.NaN()} {
+			Expect(validateConfig(&Config{WindowUpdateStrategy: WindowUpdateThreshold(f)})).To(MatchError("invalid window update threshold for Config.WindowUpdateStrategy"))
+		}
+		Expect(validateConfig(&Config{WindowUpdateStrategy: WindowUpdateThreshold(0.5)})).To(Succeed())
+	})
+
+	It("uses functions as strategies", func() {
+		var called FlowControlWindow
+		s := WindowUpdateStrategyFunc(func(w FlowControlWindow) bool {
+			called = w
+			return true
+		})
+		Expect(s.ShouldSendWindowUpdate(FlowControlWindow{StreamID: 4, BytesRead: 10})).To(BeTrue())
+		Expect(called).To(Equal(FlowControlWindow{StreamID: 4, BytesRead: 10}))
+	})
+})